@@ -0,0 +1,165 @@
+package mockllm
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+//go:embed ui/index.html
+var dashboardHTML []byte
+
+// MockSummary describes a configured mock and how often it has been matched
+type MockSummary struct {
+	Provider string `json:"provider"`
+	// Tenant is the virtual tenant the mock belongs to, empty for the default mocks
+	Tenant string `json:"tenant,omitempty"`
+	// Organization and Project are the OpenAI organization and project the mock belongs to, if any
+	Organization string    `json:"organization,omitempty"`
+	Project      string    `json:"project,omitempty"`
+	Name         string    `json:"name"`
+	MatchType    MatchType `json:"match_type"`
+	Tags         []string  `json:"tags,omitempty"`
+	// Disabled reports whether the mock was disabled, or the tag selection keeps it from being served
+	Disabled bool `json:"disabled,omitempty"`
+	Hits     int  `json:"hits"`
+	// MaxConcurrency is the most requests the mock has served at once
+	MaxConcurrency int `json:"max_concurrency"`
+}
+
+// Mocks returns a summary of every configured mock along with its hit count
+func (s *Server) Mocks() []MockSummary {
+	summaries := s.providerMockSummaries("", s.config.OpenAI, s.config.Anthropic)
+	summaries = append(summaries, s.organizationMockSummaries()...)
+
+	httpHits := s.requestLog.Hits(providerHTTP)
+	httpConcurrency := s.requestLog.TenantMaxConcurrency("", providerHTTP)
+	for _, mock := range s.config.HTTP {
+		summary := MockSummary{Provider: providerHTTP, Name: mock.Name, Hits: httpHits[mock.Name],
+			MaxConcurrency: httpConcurrency[mock.Name]}
+		if mock.Body != nil {
+			summary.MatchType = mock.Body.MatchType
+		}
+		summaries = append(summaries, summary)
+	}
+
+	geminiHits := s.requestLog.Hits(providerGemini)
+	geminiConcurrency := s.requestLog.TenantMaxConcurrency("", providerGemini)
+	for _, mock := range s.config.Gemini {
+		summaries = append(summaries, MockSummary{Provider: providerGemini, Name: mock.Name,
+			MatchType: mock.Match.MatchType, Hits: geminiHits[mock.Name], MaxConcurrency: geminiConcurrency[mock.Name]})
+	}
+
+	// Responses API mocks are logged as OpenAI requests
+	openaiHits := s.requestLog.Hits(providerOpenAI)
+	openaiConcurrency := s.requestLog.TenantMaxConcurrency("", providerOpenAI)
+	for _, mock := range s.config.OpenAIResponses {
+		summaries = append(summaries, MockSummary{Provider: providerOpenAI, Name: mock.Name,
+			MatchType: mock.Match.MatchType, Hits: openaiHits[mock.Name], MaxConcurrency: openaiConcurrency[mock.Name]})
+	}
+
+	for _, tenant := range s.config.Tenants {
+		summaries = append(summaries, s.providerMockSummaries(tenant.Name, tenant.OpenAI, tenant.Anthropic)...)
+	}
+	return summaries
+}
+
+// MockCoverage reports how many of the configured mocks have been matched
+type MockCoverage struct {
+	Total  int           `json:"total"`
+	Used   int           `json:"used"`
+	Unused []MockSummary `json:"unused"`
+}
+
+// Coverage reports which configured mocks have been matched so far, to find dead mocks and agent
+// paths a test run never reached
+func (s *Server) Coverage() MockCoverage {
+	mocks := s.Mocks()
+	unused := s.UnusedMocks()
+	return MockCoverage{Total: len(mocks), Used: len(mocks) - len(unused), Unused: unused}
+}
+
+// UnusedMocks returns the configured mocks that have not been matched so far
+func (s *Server) UnusedMocks() []MockSummary {
+	unused := []MockSummary{}
+	for _, mock := range s.Mocks() {
+		if mock.Hits == 0 {
+			unused = append(unused, mock)
+		}
+	}
+	return unused
+}
+
+// MaxConcurrency returns the most requests the named mock has served at once, e.g. to assert that
+// an agent never issues more than two parallel calls. Mocks of the same name in several providers
+// or tenants report the highest of their counts.
+func (s *Server) MaxConcurrency(mockName string) int {
+	concurrency := 0
+	for _, mock := range s.Mocks() {
+		if mock.Name == mockName {
+			concurrency = max(concurrency, mock.MaxConcurrency)
+		}
+	}
+	return concurrency
+}
+
+// providerMockSummaries summarizes the OpenAI and Anthropic mocks of a tenant
+func (s *Server) providerMockSummaries(tenant string, openaiMocks []OpenAIMock,
+	anthropicMocks []AnthropicMock) []MockSummary {
+	openaiHits := s.requestLog.TenantHits(tenant, providerOpenAI)
+	anthropicHits := s.requestLog.TenantHits(tenant, providerAnthropic)
+	openaiConcurrency := s.requestLog.TenantMaxConcurrency(tenant, providerOpenAI)
+	anthropicConcurrency := s.requestLog.TenantMaxConcurrency(tenant, providerAnthropic)
+
+	summaries := make([]MockSummary, 0, len(openaiMocks)+len(anthropicMocks))
+	for _, mock := range openaiMocks {
+		summaries = append(summaries, MockSummary{
+			Provider:       providerOpenAI,
+			Tenant:         tenant,
+			Name:           mock.Name,
+			MatchType:      mock.Match.MatchType,
+			Tags:           mock.Tags,
+			Disabled:       s.switches.disabledReason(mock.Name, mock.Tags) != "",
+			Hits:           openaiHits[mock.Name],
+			MaxConcurrency: openaiConcurrency[mock.Name],
+		})
+	}
+	for _, mock := range anthropicMocks {
+		summaries = append(summaries, MockSummary{
+			Provider:       providerAnthropic,
+			Tenant:         tenant,
+			Name:           mock.Name,
+			MatchType:      mock.Match.MatchType,
+			Tags:           mock.Tags,
+			Disabled:       s.switches.disabledReason(mock.Name, mock.Tags) != "",
+			Hits:           anthropicHits[mock.Name],
+			MaxConcurrency: anthropicConcurrency[mock.Name],
+		})
+	}
+	return summaries
+}
+
+func (s *Server) handleUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(dashboardHTML) //nolint:errcheck
+}
+
+func (s *Server) handleAdminMocks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Mocks())
+}
+
+func (s *Server) handleAdminCoverage(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Coverage())
+}
+
+// writeJSON encodes v as the JSON body of the response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
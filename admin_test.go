@@ -0,0 +1,265 @@
+package mockllm_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDashboard(t *testing.T) {
+	config := mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{
+			{
+				Name: "greeting",
+				Match: mockllm.OpenAIRequestMatch{
+					MatchType: mockllm.MatchTypeContains,
+					Message:   openaiUserMessage("Hello"),
+				},
+				Response: openai.ChatCompletion{ID: "chatcmpl-1"},
+			},
+		},
+	}
+
+	server := mockllm.NewServer(mockllm.WithConfig(config))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	headers := map[string]string{"Authorization": "Bearer test-key"}
+	for _, content := range []string{"Hello there", "Goodbye"} {
+		postJSON(t, baseURL+"/v1/chat/completions", openai.ChatCompletionNewParams{
+			Model:    "gpt-4o-mini",
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage(content)},
+		}, headers)
+	}
+
+	resp, err := http.Get(baseURL + "/ui")
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/html")
+	page, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	// The admin API is fetched relative to the dashboard, to work under a path prefix
+	assert.Contains(t, string(page), `fetch("admin/requests")`)
+	assert.NotContains(t, string(page), `fetch("/`)
+
+	resp, err = http.Get(baseURL + "/admin/mocks")
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	var mocks []mockllm.MockSummary
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&mocks))
+	require.Len(t, mocks, 1)
+	assert.Equal(t, 1, mocks[0].Hits)
+	assert.Empty(t, server.UnusedMocks())
+
+	resp, err = http.Get(baseURL + "/admin/requests")
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	var requests []mockllm.RequestRecord
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&requests))
+	require.Len(t, requests, 2)
+
+	assert.True(t, requests[0].Matched)
+	assert.Equal(t, "greeting", requests[0].MockName)
+
+	assert.False(t, requests[1].Matched)
+	assert.Equal(t, http.StatusNotFound, requests[1].Status)
+	require.Len(t, requests[1].Diffs, 1)
+	assert.Contains(t, requests[1].Diffs[0].Diff, `-   "content": "Hello"`)
+	assert.Contains(t, requests[1].Diffs[0].Diff, `+   "content": "Goodbye"`)
+}
+
+func TestAdminRequestsRedactAPIKeys(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{OpenAI: []mockllm.OpenAIMock{replyMock("hello", "Hi")}}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	for _, key := range []string{"sk-proj-0123456789abcd", "test-key"} {
+		postJSON(t, baseURL+"/v1/chat/completions", openai.ChatCompletionNewParams{
+			Model:    "gpt-4o-mini",
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+		}, map[string]string{"Authorization": "Bearer " + key})
+	}
+
+	resp, err := http.Get(baseURL + "/admin/requests")
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "sk-proj-0123456789abcd")
+	assert.NotContains(t, string(body), "test-key")
+	var requests []mockllm.RequestRecord
+	require.NoError(t, json.Unmarshal(body, &requests))
+	require.Len(t, requests, 2)
+	assert.Equal(t, "sk-...abcd", requests[0].APIKey)
+	assert.Equal(t, "...-key", requests[1].APIKey)
+}
+
+func TestAdminCoverage(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{
+			{Name: "greeting", Match: mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")}},
+			{Name: "farewell", Match: mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Bye")}},
+		},
+		Tenants: []mockllm.TenantConfig{{
+			Name:    "team-a",
+			APIKeys: []string{"team-a-key"},
+			OpenAI: []mockllm.OpenAIMock{
+				{Name: "greeting", Match: mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")}},
+			},
+		}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	postJSON(t, baseURL+"/v1/chat/completions", openai.ChatCompletionNewParams{
+		Model:    "gpt-4o-mini",
+		Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello there")},
+	}, map[string]string{"Authorization": "Bearer test-key"})
+
+	resp, err := http.Get(baseURL + "/admin/coverage")
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	var coverage mockllm.MockCoverage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&coverage))
+	assert.Equal(t, 3, coverage.Total)
+	assert.Equal(t, 1, coverage.Used)
+	require.Len(t, coverage.Unused, 2)
+	assert.Equal(t, "farewell", coverage.Unused[0].Name)
+	assert.Equal(t, "team-a", coverage.Unused[1].Tenant)
+	assert.Equal(t, coverage.Unused, server.UnusedMocks())
+}
+
+func TestAdminRequestQuery(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{OpenAI: []mockllm.OpenAIMock{{
+		Name:     "hello",
+		Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+		Response: openai.ChatCompletion{ID: "chatcmpl-1"},
+	}}}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	headers := map[string]string{"Authorization": "Bearer test-key"}
+	for _, request := range []struct{ model, content string }{
+		{"gpt-4o", "Hello"}, {"gpt-4o-mini", "Goodbye"}, {"gpt-4o", "Hello again"}, {"gpt-4o-mini", "Hello"},
+	} {
+		resp := postJSON(t, baseURL+"/v1/chat/completions", openai.ChatCompletionNewParams{
+			Model:    request.model,
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage(request.content)},
+		}, headers)
+		resp.Body.Close() //nolint:errcheck
+	}
+	postJSON(t, baseURL+"/v1/messages", anthropicHelloRequest, anthropicHeaders("2023-06-01")).Body.Close() //nolint:errcheck
+
+	query := func(t *testing.T, params string) ([]mockllm.RequestRecord, string) {
+		resp, err := http.Get(baseURL + "/admin/requests?" + params)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint:errcheck
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var records []mockllm.RequestRecord
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&records))
+		return records, resp.Header.Get("X-Total-Count")
+	}
+	ids := func(records []mockllm.RequestRecord) []int {
+		ids := make([]int, len(records))
+		for i, record := range records {
+			ids[i] = record.ID
+		}
+		return ids
+	}
+
+	records, total := query(t, "")
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, ids(records))
+	assert.Equal(t, "5", total)
+	records, _ = query(t, "provider=openai&matched=false")
+	assert.Equal(t, []int{2}, ids(records))
+	records, _ = query(t, "model=gpt-4o-mini")
+	assert.Equal(t, []int{2, 4}, ids(records), "unmatched requests are filtered by their requested model")
+	records, _ = query(t, "mock=hello&model=gpt-4o")
+	assert.Equal(t, []int{1, 3}, ids(records))
+
+	records, total = query(t, "provider=openai&offset=1&limit=2&bodies=false")
+	assert.Equal(t, []int{2, 3}, ids(records))
+	assert.Equal(t, "4", total)
+	for _, record := range records {
+		assert.Nil(t, record.Body)
+	}
+
+	first, last := server.Requests()[0].Time, server.Requests()[4].Time
+	records, _ = query(t, "since="+first.Add(-time.Second).Format(time.RFC3339)+"&until="+last.Add(time.Second).Format(time.RFC3339))
+	assert.Len(t, records, 5)
+	records, _ = query(t, "until="+first.Add(-time.Second).Format(time.RFC3339))
+	assert.Empty(t, records)
+
+	resp, err := http.Get(baseURL + "/admin/requests?limit=-1")
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "limit must be a non-negative integer")
+}
+
+func TestAdminEvents(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	resp, err := http.Get(baseURL + "/admin/events")
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	postJSON(t, baseURL+"/v1/chat/completions", openai.ChatCompletionNewParams{
+		Model:    "gpt-4o-mini",
+		Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+	}, map[string]string{"Authorization": "Bearer test-key"})
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event: unmatch\n", line)
+
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	var event mockllm.Event
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event))
+	assert.Equal(t, mockllm.EventUnmatch, event.Type)
+	assert.Equal(t, "/v1/chat/completions", event.Request.Path)
+}
+
+func TestStopEndsAdminEvents(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+
+	resp, err := http.Get(baseURL + "/admin/events")
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+
+	stopped := make(chan error)
+	go func() { stopped <- server.Stop(context.Background()) }()
+	select {
+	case err := <-stopped:
+		require.NoError(t, err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("Stop is blocked by an events subscriber")
+	}
+	_, err = io.ReadAll(resp.Body)
+	assert.NoError(t, err, "the event stream ends")
+}
@@ -2,110 +2,484 @@ package mockllm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/kagent-dev/mockllm/tokenizer"
 )
 
+// DefaultAnthropicVersions are the anthropic-version header values accepted unless
+// Config.AnthropicVersions overrides them
+var DefaultAnthropicVersions = []string{"2023-01-01", "2023-06-01"}
+
 // AnthropicProvider handles Anthropic request/response mocking
 type AnthropicProvider struct {
-	mocks []AnthropicMock
+	mocks    []AnthropicMock
+	versions []string
+	cache    *promptCache
+	log      *RequestLog
+	// batches holds the message batches created through the provider, which end batchDelay
+	// after they were created
+	batches    *anthropicBatchStore
+	batchDelay time.Duration
+	// tenant is the name of the virtual tenant the provider serves, empty for the default mocks
+	tenant string
+	// tokenizer paces streams, and with estimateUsage and enforceMaxTokens also fills in missing
+	// usage and truncates responses to the requested max tokens
+	tokenizer        tokenizer.Tokenizer
+	estimateUsage    bool
+	enforceMaxTokens bool
+	// grammar, or else echo, answers requests that match no mock when set, the grammar only
+	// those one of its rules matches
+	grammar *Grammar
+	echo    *EchoConfig
+	// plugins runs the WebAssembly plugins of mocks
+	plugins *pluginHost
+	// clock times simulated delays and timestamps responses
+	clock Clock
+	// toolValidation checks the tools declared in requests when set
+	toolValidation *ToolValidationConfig
+	// fuzz counts the requests of fuzzed mocks
+	fuzz *fuzzIterations
+	// draws count the draws of mocks matching with a probability
+	draws *matchDraws
+	// memories are the facts persisted by mocks with a memory
+	memories *memories
+	// modelAliases map requested models to the canonical names mocks are matched with
+	modelAliases map[string]string
+	// knownModels are the models served, any model when empty
+	knownModels []string
+	// profiles give models a latency, context window, streaming speed and error rate
+	profiles modelProfiles
+	// heartbeat keeps streams alive while they wait
+	heartbeat *HeartbeatConfig
+	// streamAttempts counts the cut attempts of requests for stream retry mocks
+	streamAttempts *streamAttempts
+	// switches enable and disable mocks by name and by tag, all of them being served when nil
+	switches *mockSwitches
+	// patches change the responses of mocks at runtime
+	patches *responsePatches
+	// ids generates the IDs of the objects created through the stateful emulations
+	ids *idSource
+	// files holds the files uploaded through the Files API
+	files *anthropicFileStore
 }
 
 // NewAnthropicProvider creates a new Anthropic AnthropicProvider with the given mocks
 func NewAnthropicProvider(mocks []AnthropicMock) *AnthropicProvider {
-	return &AnthropicProvider{mocks: mocks}
+	return &AnthropicProvider{
+		mocks:          mocks,
+		versions:       DefaultAnthropicVersions,
+		cache:          newPromptCache(),
+		batches:        newAnthropicBatchStore(),
+		tokenizer:      tokenizer.Default,
+		plugins:        newPluginHost(),
+		clock:          systemClock{},
+		fuzz:           newFuzzIterations(),
+		draws:          newMatchDraws(),
+		memories:       newMemories(),
+		streamAttempts: newStreamAttempts(),
+		ids:            newIDSource(nil),
+		files:          newAnthropicFileStore(),
+	}
+}
+
+// Routes returns the Anthropic endpoints served by the provider
+func (p *AnthropicProvider) Routes() []Route {
+	return append([]Route{
+		{Method: http.MethodPost, Path: "/v1/messages"},
+		{Method: http.MethodPost, Path: anthropicBatchesPath},
+		{Method: http.MethodGet, Path: anthropicBatchesPath + "/{id}"},
+		{Method: http.MethodGet, Path: anthropicBatchesPath + "/{id}/results"},
+	}, anthropicFileRoutes...)
 }
 
-// Handle processes an Anthropic messages request
+// Handle processes an Anthropic messages, message batches or files request
 func (p *AnthropicProvider) Handle(w http.ResponseWriter, r *http.Request) {
-	// Check for required headers
-	if r.Header.Get("x-api-key") == "" {
-		http.Error(w, "Missing x-api-key header", http.StatusUnauthorized)
+	if strings.HasPrefix(r.URL.Path, anthropicBatchesPath) {
+		p.handleBatches(w, r)
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, anthropicFilesPath) {
+		p.handleFiles(w, r)
+		return
+	}
+	p.handleMessages(w, r)
+}
+
+// handleMessages processes an Anthropic messages request
+func (p *AnthropicProvider) handleMessages(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, fmt.Sprintf("Failed to read request body: %v", err))
+		return
+	}
+
+	record := p.log.newRecord(r, providerAnthropic, body)
+	record.Tenant = p.tenant
+	defer p.log.Add(&record)
+
+	version := r.Header.Get("anthropic-version")
+	if status, reason, message := p.checkHeaders(r); status != 0 {
+		record.Status, record.Error = status, reason
+		writeAnthropicError(w, status, message)
 		return
 	}
 
-	if r.Header.Get("anthropic-version") == "" {
-		http.Error(w, "Missing anthropic-version header", http.StatusBadRequest)
+	if body, err = p.resolveFileReferences(body); err != nil {
+		record.Status, record.Error = http.StatusNotFound, err.Error()
+		writeAnthropicError(w, http.StatusNotFound, err.Error())
 		return
 	}
 
 	// Parse the incoming request into SDK type
 	var requestBody anthropic.MessageNewParams
-	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+	if err := json.Unmarshal(body, &requestBody); err != nil {
+		record.Status, record.Error = http.StatusBadRequest, err.Error()
+		writeAnthropicError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+
+	if !knownModel(p.knownModels, p.modelAliases, requestBody.Model) {
+		record.Status, record.Error = http.StatusNotFound, "unknown model "+string(requestBody.Model)
+		writeAnthropicError(w, http.StatusNotFound, "model: "+string(requestBody.Model))
+		return
+	}
+
+	forcedStatus, err := forcedError(r.Header)
+	if err != nil {
+		record.Status, record.Error = http.StatusBadRequest, err.Error()
+		writeAnthropicError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if forcedStatus != 0 {
+		_, _, message := p.profileError(nil, requestBody, forcedStatus)
+		record.Status, record.Error = forcedStatus, "forced error"
+		writeAnthropicError(w, forcedStatus, message)
+		return
+	}
+
+	profile := lookupModelProfile(p.profiles, p.modelAliases, requestBody.Model)
+	latency, failure := profile.draw(529)
+	if latency > 0 && !wait(r.Context(), p.clock, latency) {
+		// No status was sent before the client went away
+		record.Status, record.Error = 0, "client disconnected"
+		return
+	}
+	if status, reason, message := p.profileError(profile, requestBody, failure); status != 0 {
+		record.Status, record.Error = status, reason
+		writeAnthropicError(w, status, message)
+		return
+	}
+
+	if violation := checkTools(p.toolValidation, &record, anthropicToolViolations, body); violation != nil {
+		writeAnthropicError(w, http.StatusBadRequest, violation.Message)
 		return
 	}
 
 	// Find a matching mock
-	mock := p.findMatchingMock(requestBody)
+	mock := p.findMatchingMock(r.Context(), requestBody, r.Header)
+	if name := r.Header.Get(ForceMockHeader); mock == nil && name != "" {
+		record.Status, record.Error = http.StatusNotFound, "no mock named "+name
+		writeAnthropicError(w, http.StatusNotFound, fmt.Sprintf("No mock named %q to force.", name))
+		return
+	}
 	if mock == nil {
+		record.Status, record.Diffs = http.StatusNotFound, p.diffs(requestBody)
 		requestBodyBytes, err := json.MarshalIndent(requestBody, "", "  ")
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to encode request body: %v", err),
-				http.StatusInternalServerError)
+			record.Status, record.Error = http.StatusInternalServerError, err.Error()
+			writeAnthropicError(w, http.StatusInternalServerError,
+				fmt.Sprintf("Failed to encode request body: %v", err))
 			return
 		}
 
-		http.Error(w, fmt.Sprintf("No matching mock found. Request: %s",
-			string(requestBodyBytes)), http.StatusNotFound)
+		writeAnthropicError(w, http.StatusNotFound, fmt.Sprintf("No matching mock found. Request: %s",
+			string(requestBodyBytes)))
 		return
 	}
 
-	p.handleNonStreamingResponse(w, mock.Response)
+	if missing := missingBetas(r.Header, anthropicBetaHeader, mock.Betas); len(missing) > 0 {
+		record.Status, record.MockName = http.StatusBadRequest, mock.Name
+		record.Error = "missing anthropic-beta " + strings.Join(missing, ",")
+		writeAnthropicError(w, http.StatusBadRequest, fmt.Sprintf(
+			"This feature requires the %s header to include: %s", anthropicBetaHeader, strings.Join(missing, ", ")))
+		return
+	}
+
+	record.Status, record.Matched, record.MockName = http.StatusOK, true, mock.Name
+	defer p.log.serving(providerAnthropic, p.tenant, mock.Name)()
+	record.ExpectationFailures = anthropicExpectationFailures(mock.Expect, requestBody)
+	if status, ok := applyFault(w, r, p.clock, mock.Fault, "application/json"); !ok {
+		// The mock matched, but the connection was dropped instead of serving it
+		record.Status, record.Error = status, "simulated fault: "+string(mock.Fault.Type)
+		return
+	}
+	w = dripResponse(w, r, p.clock, mock.Fault)
+	if mock.Raw != nil {
+		record.Status = writeHTTPResponse(w, *mock.Raw)
+		return
+	}
+	if mock.Script != "" {
+		if mock, err = scriptedAnthropicMock(mock, body); err != nil {
+			record.Status, record.Error = http.StatusInternalServerError, err.Error()
+			writeAnthropicError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if mock.Plugin != "" {
+		if mock, err = p.plugins.pluginAnthropicMock(r.Context(), mock, body); err != nil {
+			record.Status, record.Error = http.StatusInternalServerError, err.Error()
+			writeAnthropicError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	response := p.buildResponse(mock, requestBody, version)
+	record.Fuzz = p.fuzzResponse(mock, requestBody, &response)
+	record.Model, record.Usage = string(requestBody.Model), anthropicTokenUsage(response)
+	record.Response, _ = json.Marshal(anthropicMessageJSON(response))
+	if isStreamingRequest(body) {
+		events := mock.StreamError.anthropicEvents(malformEvents(mock.Malformed, anthropicStreamEvents(response, p.tokenizer)))
+		events, cut, attempt := p.streamAttempts.attempt(mock.StreamRetry, mock.Name, body, events)
+		record.Stream = writeSSE(w, r, p.clock, events, ssePacing{
+			fault:             mock.Fault,
+			interval:          profile.streamInterval(),
+			heartbeat:         p.heartbeat.heartbeatEvent(&anthropicPingEvent),
+			heartbeatInterval: p.heartbeat.interval(),
+		}, anthropicInterruptedEvent)
+		record.Stream.Attempt = attempt
+		switch {
+		case cut:
+			closeConnection(w)
+			record.Error = fmt.Sprintf("simulated disconnect of attempt %d", attempt)
+		case mock.StreamError != nil:
+			record.Error = mock.StreamError.reason()
+		}
+		return
+	}
+	if mock.Fault.stallsBefore(0) && !wait(r.Context(), p.clock, time.Duration(mock.Fault.Duration)) {
+		return
+	}
+	if mock.Malformed != "" {
+		writeMalformedResponse(w, mock.Malformed, anthropicMessageJSON(response))
+		return
+	}
+	p.handleNonStreamingResponse(w, anthropicMessageJSON(response))
 }
 
-// findMatchingMock finds the first mock that matches the request
-func (p *AnthropicProvider) findMatchingMock(request anthropic.MessageNewParams) *AnthropicMock {
+// checkHeaders validates the authentication and version headers required by every Anthropic
+// endpoint. It returns the status, log reason and client message of the first failed check.
+func (p *AnthropicProvider) checkHeaders(r *http.Request) (status int, reason, message string) {
+	if r.Header.Get("x-api-key") == "" {
+		return http.StatusUnauthorized, "missing x-api-key header", "x-api-key header is required"
+	}
+
+	version := r.Header.Get("anthropic-version")
+	if version == "" {
+		return http.StatusBadRequest, "missing anthropic-version header", "anthropic-version header is required"
+	}
+	if !slices.Contains(p.versions, version) {
+		return http.StatusBadRequest, "unsupported anthropic-version " + version, fmt.Sprintf(
+			"anthropic-version: %q is not a valid version. Supported versions: %s", version, strings.Join(p.versions, ", "))
+	}
+	return 0, "", ""
+}
+
+// buildResponse derives the response to a request from the matched mock
+func (p *AnthropicProvider) buildResponse(mock *AnthropicMock, requestBody anthropic.MessageNewParams,
+	version string) anthropic.Message {
+	response := mock.Response
+	if versionResponse, ok := mock.VersionResponses[version]; ok {
+		response = versionResponse
+	}
+	matched := requestBody
+	matched.Model = canonicalModel(p.modelAliases, matched.Model)
+	substituteCaptures(&response, fieldCaptures(matched, mock.Match.Fields))
+	if mock.Echo != nil {
+		setAnthropicContent(&response, mock.Echo.echo(anthropicLastMessageText(requestBody)))
+	}
+	if mock.Generate != nil && mock.Generate.ToolCall != "" {
+		mock.Generate.generateAnthropicToolUse(mock.Name, requestBody, &response)
+	} else if mock.Generate != nil {
+		setAnthropicContent(&response, mock.Generate.generate(p.tokenizer))
+	}
+	if mock.Grammar != nil {
+		reply, _ := mock.Grammar.reply(anthropicLastMessageText(requestBody))
+		setAnthropicContent(&response, reply)
+	}
+	if mock.Memory != nil {
+		if reply, ok := mock.Memory.reply(p.memories, mock.Name, anthropicMessageTexts(requestBody)); ok {
+			setAnthropicContent(&response, reply)
+		}
+	}
+	forceAnthropicToolCall(mock.Name, requestBody, &response)
+	fillAnthropicDefaults(mock.Name, requestBody, &response)
+	if mock.SimulatePromptCache {
+		p.cache.simulateAnthropicPromptCache(requestBody, &response)
+	}
+	if p.enforceMaxTokens && requestBody.MaxTokens > 0 {
+		truncateAnthropicContent(p.tokenizer, &response, int(requestBody.MaxTokens))
+	}
+	if p.estimateUsage {
+		estimateAnthropicUsage(p.tokenizer, requestBody, &response)
+	}
+	return response
+}
+
+// findMatchingMock finds the first mock that matches the request, falling back to the grammar
+// and echo mode. A request forcing a tool call prefers the first matching mock calling the tool,
+// and one naming a mock in the ForceMockHeader gets that mock, if any.
+func (p *AnthropicProvider) findMatchingMock(ctx context.Context, request anthropic.MessageNewParams, header http.Header) *AnthropicMock {
+	if name := header.Get(ForceMockHeader); name != "" {
+		for _, mock := range p.mocks {
+			if mock.Name == name {
+				applyResponsePatches(p.patches, mock.Name, &mock.Response)
+				return &mock
+			}
+		}
+		return nil
+	}
+	request.Model = canonicalModel(p.modelAliases, request.Model)
+	forced := anthropicForcedTool(request)
+	var first *AnthropicMock
 	for _, mock := range p.mocks {
-		if p.requestsMatch(mock.Match, request) {
+		if !p.mockMatches(ctx, mock, request, header) || !p.draws.draw(mock.Name, mock.Probability) {
+			continue
+		}
+		applyResponsePatches(p.patches, mock.Name, &mock.Response)
+		if forced == nil || anthropicCallsTool(mock.Response, forced) {
 			return &mock
 		}
+		if first == nil {
+			first = &mock
+		}
+	}
+	if first != nil {
+		return first
+	}
+	if p.grammar.answers(anthropicLastMessageText(request)) {
+		return &AnthropicMock{Name: grammarMockName, Grammar: p.grammar}
+	}
+	if p.echo != nil {
+		return &AnthropicMock{Name: echoMockName, Echo: p.echo}
 	}
 	return nil
 }
 
+// mockMatches asks the match hook of the mock's plugin, or else checks the mock's match criteria
+func (p *AnthropicProvider) mockMatches(ctx context.Context, mock AnthropicMock, request anthropic.MessageNewParams, header http.Header) bool {
+	matched, _ := p.explainMockMatch(ctx, mock, request, header)
+	return matched
+}
+
+// explainMockMatch is mockMatches, also returning the reason the mock matches or not
+func (p *AnthropicProvider) explainMockMatch(ctx context.Context, mock AnthropicMock, request anthropic.MessageNewParams, header http.Header) (bool, string) {
+	if reason := p.switches.disabledReason(mock.Name, mock.Tags); reason != "" {
+		return false, reason
+	}
+	if reason := mismatchedHeader(header, mock.Match.Headers); reason != "" {
+		return false, reason
+	}
+	if mock.Plugin != "" {
+		body, err := json.Marshal(request)
+		if err != nil {
+			return false, fmt.Sprintf("failed to encode request: %v", err)
+		}
+		// A failing plugin never matches, the request then shows up as unmatched
+		matched, ok, err := p.plugins.matches(ctx, mock.Plugin, body)
+		if err != nil {
+			return false, fmt.Sprintf("plugin failed: %v", err)
+		}
+		if ok {
+			return matched, pluginMatchReason(matched)
+		}
+	}
+	return p.explainRequestMatch(mock.Match, request)
+}
+
+// diffs compares the last message of an unmatched request against every configured mock
+func (p *AnthropicProvider) diffs(request anthropic.MessageNewParams) []MockDiff {
+	if len(request.Messages) == 0 {
+		return nil
+	}
+	lastMessage := request.Messages[len(request.Messages)-1]
+
+	diffs := make([]MockDiff, 0, len(p.mocks))
+	for _, mock := range p.mocks {
+		diffs = append(diffs, MockDiff{
+			MockName:  mock.Name,
+			MatchType: mock.Match.MatchType,
+			Diff:      jsonDiff(mock.Match.Message, lastMessage),
+		})
+	}
+	return diffs
+}
+
 // requestsMatch checks if two requests are equivalent.
 //
 // Note: For MatchTypeContains, this function only supports a single content part
 // in the expected message, and that part must be of type OfText. If this constraint
 // is not met, the function will return false.
 func (p *AnthropicProvider) requestsMatch(expected AnthropicRequestMatch, actual anthropic.MessageNewParams) bool {
-	// Simple deep equal comparison for now
-	// In the future, we could add more sophisticated matching
+	matched, _ := p.explainRequestMatch(expected, actual)
+	return matched
+}
+
+// explainRequestMatch is requestsMatch, also returning the reason the request matches or not
+func (p *AnthropicProvider) explainRequestMatch(expected AnthropicRequestMatch, actual anthropic.MessageNewParams) (bool, string) {
+	if tool := anthropicMissingTool(expected.ToolsCalled, actual); tool != "" {
+		return false, fmt.Sprintf("tool %q has not been called", tool)
+	}
+	if reason := mismatchedField(actual, expected.Fields); reason != "" {
+		return false, reason
+	}
+	if reason := expected.Document.mismatch(anthropicDocuments(actual)); reason != "" {
+		return false, reason
+	}
+	if reason := expected.ComputerUse.mismatch(actual); reason != "" {
+		return false, reason
+	}
 	switch expected.MatchType {
 	case MatchTypeExact:
 		// get Last message from actual
 		if len(actual.Messages) == 0 {
-			return false
+			return false, "request has no messages"
 		}
 		lastMessage := actual.Messages[len(actual.Messages)-1]
 		// Check json is equal
 		jsonExpected, err := json.Marshal(expected.Message)
 		if err != nil {
-			return false
+			return false, fmt.Sprintf("failed to encode expected message: %v", err)
 		}
 		jsonActual, err := json.Marshal(lastMessage)
 		if err != nil {
-			return false
+			return false, fmt.Sprintf("failed to encode last message: %v", err)
+		}
+		if !bytes.Equal(jsonExpected, jsonActual) {
+			return false, "last message differs from the expected message"
 		}
-		return bytes.Equal(jsonExpected, jsonActual)
+		return true, "last message equals the expected message"
 	case MatchTypeContains:
 		if len(actual.Messages) == 0 {
-			return false
+			return false, "request has no messages"
 		}
 
 		// For simplicity, only support single content part in expected.
 		if len(expected.Message.Content) != 1 || expected.Message.Content[0].OfText == nil {
-			return false
+			return false, "expected message must have a single text content part"
 		}
 
 		lastMessage := actual.Messages[len(actual.Messages)-1]
 		if lastMessage.Role != expected.Message.Role {
-			return false
+			return false, fmt.Sprintf("last message has role %q, expected %q", lastMessage.Role, expected.Message.Role)
 		}
 
 		for _, part := range lastMessage.Content {
@@ -114,11 +488,48 @@ func (p *AnthropicProvider) requestsMatch(expected AnthropicRequestMatch, actual
 			}
 
 			if strings.Contains(part.OfText.Text, expected.Message.Content[0].OfText.Text) {
-				return true
+				return true, fmt.Sprintf("last message contains %q", expected.Message.Content[0].OfText.Text)
+			}
+		}
+		return false, fmt.Sprintf("no text part of the last message contains %q", expected.Message.Content[0].OfText.Text)
+	case MatchTypeUserContains:
+		// User messages carrying only tool results are part of the tool loop, not something the user said
+		for i := len(actual.Messages) - 1; i >= 0; i-- {
+			message := actual.Messages[i]
+			if message.Role == anthropic.MessageParamRoleUser && slices.ContainsFunc(message.Content,
+				func(block anthropic.ContentBlockParamUnion) bool { return block.OfText != nil }) {
+				return containsReason("latest user message", anthropicMessageText(message),
+					anthropicMessageText(expected.Message))
+			}
+		}
+		return false, "request has no user message with text"
+	case MatchTypeFields:
+		return true, "fields match"
+	default:
+		return false, fmt.Sprintf("unknown match type %q", expected.MatchType)
+	}
+}
+
+// anthropicMissingTool returns the first named tool the assistant did not call in the request's
+// conversation, or "" when it called them all
+func anthropicMissingTool(tools []string, request anthropic.MessageNewParams) string {
+	var called []string
+	for _, message := range request.Messages {
+		if message.Role != anthropic.MessageParamRoleAssistant {
+			continue
+		}
+		for _, block := range message.Content {
+			if block.OfToolUse != nil {
+				called = append(called, block.OfToolUse.Name)
 			}
 		}
 	}
-	return false
+	for _, tool := range tools {
+		if !slices.Contains(called, tool) {
+			return tool
+		}
+	}
+	return ""
 }
 
 // handleNonStreamingResponse sends a JSON response
@@ -0,0 +1,231 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// anthropicBatchesPath is the root of the Message Batches API
+const (
+	anthropicBatchesPath = "/v1/messages/batches"
+	// batchNoMatchError is the error of batch entries matching no mock
+	batchNoMatchError = "No matching mock found"
+)
+
+// BatchConfig controls the batch API emulations
+type BatchConfig struct {
+	// ProcessingDelay is how long a batch stays in progress after it was created
+	ProcessingDelay Duration `json:"processing_delay,omitempty"`
+}
+
+// anthropicBatch is a message batch whose entries were resolved when it was created
+type anthropicBatch struct {
+	id        string
+	createdAt time.Time
+	endsAt    time.Time
+	results   []map[string]any
+	succeeded int
+	errored   int
+}
+
+// anthropicBatchStore holds the batches created through a provider
+type anthropicBatchStore struct {
+	mu      sync.Mutex
+	batches map[string]*anthropicBatch
+}
+
+func newAnthropicBatchStore() *anthropicBatchStore {
+	return &anthropicBatchStore{batches: map[string]*anthropicBatch{}}
+}
+
+func (s *anthropicBatchStore) add(batch *anthropicBatch) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches[batch.id] = batch
+}
+
+func (s *anthropicBatchStore) get(id string) *anthropicBatch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.batches[id]
+}
+
+// handleBatches serves the create, retrieve and results endpoints of the Message Batches API
+func (p *AnthropicProvider) handleBatches(w http.ResponseWriter, r *http.Request) {
+	if status, _, message := p.checkHeaders(r); status != 0 {
+		writeAnthropicError(w, status, message)
+		return
+	}
+
+	id, results := strings.CutSuffix(strings.Trim(strings.TrimPrefix(r.URL.Path, anthropicBatchesPath), "/"), "/results")
+	switch {
+	case id == "" && r.Method == http.MethodPost:
+		p.createBatch(w, r)
+	case id != "" && !strings.Contains(id, "/") && r.Method == http.MethodGet:
+		batch := p.batches.get(id)
+		if batch == nil {
+			writeAnthropicError(w, http.StatusNotFound, fmt.Sprintf("Message batch %s not found", id))
+			return
+		}
+		if results {
+			p.writeBatchResults(w, batch)
+			return
+		}
+		writeJSON(w, http.StatusOK, batch.object(r, p.clock.Now()))
+	default:
+		writeAnthropicError(w, http.StatusNotFound, fmt.Sprintf("Unsupported batches request %s %s", r.Method, r.URL.Path))
+	}
+}
+
+// createBatch resolves every entry of a new batch through the mocks
+func (p *AnthropicProvider) createBatch(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, fmt.Sprintf("Failed to read request body: %v", err))
+		return
+	}
+	var request struct {
+		Requests []struct {
+			CustomID string          `json:"custom_id"`
+			Params   json.RawMessage `json:"params"`
+		} `json:"requests"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+	if len(request.Requests) == 0 {
+		writeAnthropicError(w, http.StatusBadRequest, "requests: at least one request is required")
+		return
+	}
+
+	now := p.clock.Now()
+	batch := &anthropicBatch{id: p.ids.next("message_batch", "msgbatch_", p.tenant), createdAt: now, endsAt: now.Add(p.batchDelay)}
+	version := r.Header.Get("anthropic-version")
+	for _, entry := range request.Requests {
+		result := p.resolveBatchEntry(r, entry.Params, version)
+		if result["type"] == "succeeded" {
+			batch.succeeded++
+		} else {
+			batch.errored++
+		}
+		batch.results = append(batch.results, map[string]any{"custom_id": entry.CustomID, "result": result})
+	}
+	p.batches.add(batch)
+
+	writeJSON(w, http.StatusOK, batch.object(r, now))
+}
+
+// resolveBatchEntry matches the params of a batch entry against the mocks and returns its result
+func (p *AnthropicProvider) resolveBatchEntry(r *http.Request, params json.RawMessage, version string) map[string]any {
+	record := p.log.newRecord(r, providerAnthropic, params)
+	record.Tenant = p.tenant
+	defer p.log.Add(&record)
+
+	errored := func(status int, message string) map[string]any {
+		record.Status, record.Error = status, message
+		return map[string]any{
+			"type": "errored",
+			"error": map[string]any{
+				"type":  "error",
+				"error": map[string]any{"type": anthropicErrorType(status), "message": message},
+			},
+		}
+	}
+
+	params, err := p.resolveFileReferences(params)
+	if err != nil {
+		return errored(http.StatusNotFound, err.Error())
+	}
+	var requestBody anthropic.MessageNewParams
+	if err := json.Unmarshal(params, &requestBody); err != nil {
+		return errored(http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+	}
+	if !knownModel(p.knownModels, p.modelAliases, requestBody.Model) {
+		return errored(http.StatusNotFound, "model: "+string(requestBody.Model))
+	}
+	profile := lookupModelProfile(p.profiles, p.modelAliases, requestBody.Model)
+	_, failure := profile.draw(529)
+	if status, _, message := p.profileError(profile, requestBody, failure); status != 0 {
+		return errored(status, message)
+	}
+	mock := p.findMatchingMock(r.Context(), requestBody, r.Header)
+	if mock == nil {
+		record.Diffs = p.diffs(requestBody)
+		return errored(http.StatusNotFound, batchNoMatchError)
+	}
+	record.MockName = mock.Name
+	if mock.Raw != nil {
+		return errored(http.StatusInternalServerError, "Raw responses are not supported in batches")
+	}
+	if mock.Script != "" {
+		if mock, err = scriptedAnthropicMock(mock, params); err != nil {
+			return errored(http.StatusInternalServerError, err.Error())
+		}
+	}
+	if mock.Plugin != "" {
+		if mock, err = p.plugins.pluginAnthropicMock(r.Context(), mock, params); err != nil {
+			return errored(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	response := p.buildResponse(mock, requestBody, version)
+	record.Status, record.Matched = http.StatusOK, true
+	record.Fuzz = p.fuzzResponse(mock, requestBody, &response)
+	record.Model, record.Usage = string(requestBody.Model), anthropicTokenUsage(response)
+	message := anthropicMessageJSON(response)
+	record.Response, _ = json.Marshal(message)
+	return map[string]any{"type": "succeeded", "message": message}
+}
+
+// writeBatchResults writes the results of an ended batch as JSON lines
+func (p *AnthropicProvider) writeBatchResults(w http.ResponseWriter, batch *anthropicBatch) {
+	if p.clock.Now().Before(batch.endsAt) {
+		writeAnthropicError(w, http.StatusBadRequest, fmt.Sprintf("Message batch %s is still in progress", batch.id))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-jsonl")
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	for _, result := range batch.results {
+		if err := encoder.Encode(result); err != nil {
+			return
+		}
+	}
+}
+
+// object returns the MessageBatch representation of the batch at the given time
+func (b *anthropicBatch) object(r *http.Request, now time.Time) map[string]any {
+	ended := !now.Before(b.endsAt)
+	counts := map[string]int{"processing": 0, "succeeded": 0, "errored": 0, "canceled": 0, "expired": 0}
+	object := map[string]any{
+		"id":                  b.id,
+		"type":                "message_batch",
+		"processing_status":   "in_progress",
+		"request_counts":      counts,
+		"created_at":          b.createdAt.UTC().Format(time.RFC3339),
+		"expires_at":          b.createdAt.Add(24 * time.Hour).UTC().Format(time.RFC3339),
+		"ended_at":            nil,
+		"cancel_initiated_at": nil,
+		"archived_at":         nil,
+		"results_url":         nil,
+	}
+	if !ended {
+		counts["processing"] = len(b.results)
+		return object
+	}
+
+	counts["succeeded"], counts["errored"] = b.succeeded, b.errored
+	object["processing_status"] = "ended"
+	object["ended_at"] = b.endsAt.UTC().Format(time.RFC3339)
+	object["results_url"] = fmt.Sprintf("http://%s%s/%s/results", r.Host, anthropicBatchesPath, b.id)
+	return object
+}
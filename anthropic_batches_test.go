@@ -0,0 +1,85 @@
+package mockllm_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/kagent-dev/mockllm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnthropicMessageBatches(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		Anthropic: []mockllm.AnthropicMock{{
+			Name: "hello",
+			Match: mockllm.AnthropicRequestMatch{
+				MatchType: mockllm.MatchTypeExact,
+				Message:   anthropicHelloRequest.Messages[0],
+			},
+			Response: anthropic.Message{
+				ID:      "msg_batch",
+				Role:    "assistant",
+				Content: []anthropic.ContentBlockUnion{{Type: "text", Text: "Hi!"}},
+			},
+		}},
+		Batches: &mockllm.BatchConfig{ProcessingDelay: mockllm.Duration(200 * time.Millisecond)},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	client := anthropic.NewClient(
+		anthropicoption.WithBaseURL(baseURL),
+		anthropicoption.WithAPIKey("test-key"),
+		anthropicoption.WithMaxRetries(0),
+	)
+	batch, err := client.Messages.Batches.New(t.Context(), anthropic.MessageBatchNewParams{
+		Requests: []anthropic.MessageBatchNewParamsRequest{
+			{
+				CustomID: "hello",
+				Params: anthropic.MessageBatchNewParamsRequestParams{
+					Model:     anthropicHelloRequest.Model,
+					MaxTokens: anthropicHelloRequest.MaxTokens,
+					Messages:  anthropicHelloRequest.Messages,
+				},
+			},
+			{
+				CustomID: "unmatched",
+				Params: anthropic.MessageBatchNewParamsRequestParams{
+					Model:     anthropicHelloRequest.Model,
+					MaxTokens: anthropicHelloRequest.MaxTokens,
+					Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("Bye"))},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, anthropic.MessageBatchProcessingStatusInProgress, batch.ProcessingStatus)
+	assert.Equal(t, int64(2), batch.RequestCounts.Processing)
+
+	require.Eventually(t, func() bool {
+		batch, err = client.Messages.Batches.Get(t.Context(), batch.ID)
+		return err == nil && batch.ProcessingStatus == anthropic.MessageBatchProcessingStatusEnded
+	}, 2*time.Second, 50*time.Millisecond)
+	assert.Equal(t, int64(1), batch.RequestCounts.Succeeded)
+	assert.Equal(t, int64(1), batch.RequestCounts.Errored)
+	assert.NotEmpty(t, batch.ResultsURL)
+
+	stream := client.Messages.Batches.ResultsStreaming(t.Context(), batch.ID)
+	results := map[string]anthropic.MessageBatchIndividualResponse{}
+	for stream.Next() {
+		results[stream.Current().CustomID] = stream.Current()
+	}
+	require.NoError(t, stream.Err())
+	require.Len(t, results, 2)
+	assert.Equal(t, "succeeded", results["hello"].Result.Type)
+	assert.Equal(t, "Hi!", results["hello"].Result.Message.Content[0].Text)
+	assert.Equal(t, "errored", results["unmatched"].Result.Type)
+	assert.Equal(t, "not_found_error", string(results["unmatched"].Result.Error.Error.Type))
+
+	assert.Equal(t, 1, server.Mocks()[0].Hits)
+}
@@ -0,0 +1,108 @@
+package mockllm
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// anthropicMessageJSON returns a message as the Messages API encodes it. The SDK's content block
+// union would otherwise be encoded with the fields of every block type, and the results of server
+// tools such as web search in a shape clients can't decode.
+func anthropicMessageJSON(message anthropic.Message) any {
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		return message
+	}
+	var object map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	if err := decoder.Decode(&object); err != nil {
+		return message
+	}
+	content := make([]any, len(message.Content))
+	for i, block := range message.Content {
+		content[i] = anthropicBlockJSON(block)
+	}
+	object["content"] = content
+	return object
+}
+
+// anthropicBlockJSON returns the fields of a content block's type. Citations and web search
+// results decoded from a config are kept as configured.
+func anthropicBlockJSON(block anthropic.ContentBlockUnion) any {
+	switch block.Type {
+	case "text":
+		object := map[string]any{"type": "text", "text": block.Text}
+		if len(block.Citations) > 0 {
+			citations := make([]any, len(block.Citations))
+			for i, citation := range block.Citations {
+				citations[i] = anthropicCitationJSON(citation)
+			}
+			object["citations"] = citations
+		}
+		return object
+	case "tool_use", "server_tool_use":
+		var input any = json.RawMessage(block.Input)
+		if len(block.Input) == 0 {
+			input = map[string]any{}
+		}
+		return map[string]any{"type": block.Type, "id": block.ID, "name": block.Name, "input": input}
+	case "thinking":
+		return map[string]any{"type": "thinking", "thinking": block.Thinking, "signature": block.Signature}
+	case "redacted_thinking":
+		return map[string]any{"type": "redacted_thinking", "data": block.Data}
+	case "web_search_tool_result":
+		var content any
+		switch {
+		case block.Content.RawJSON() != "":
+			content = json.RawMessage(block.Content.RawJSON())
+		case block.Content.ErrorCode != "":
+			content = map[string]any{"type": "web_search_tool_result_error", "error_code": block.Content.ErrorCode}
+		default:
+			results := make([]any, len(block.Content.OfWebSearchResultBlockArray))
+			for i, result := range block.Content.OfWebSearchResultBlockArray {
+				results[i] = map[string]any{
+					"type":              "web_search_result",
+					"url":               result.URL,
+					"title":             result.Title,
+					"encrypted_content": result.EncryptedContent,
+					"page_age":          result.PageAge,
+				}
+			}
+			content = results
+		}
+		return map[string]any{"type": "web_search_tool_result", "tool_use_id": block.ToolUseID, "content": content}
+	default:
+		if raw := block.RawJSON(); raw != "" {
+			return json.RawMessage(raw)
+		}
+		return block
+	}
+}
+
+// anthropicCitationJSON returns the fields of a citation's location type
+func anthropicCitationJSON(citation anthropic.TextCitationUnion) any {
+	if raw := citation.RawJSON(); raw != "" {
+		return json.RawMessage(raw)
+	}
+	object := map[string]any{"type": citation.Type, "cited_text": citation.CitedText}
+	switch citation.Type {
+	case "char_location":
+		object["document_index"], object["document_title"] = citation.DocumentIndex, citation.DocumentTitle
+		object["start_char_index"], object["end_char_index"] = citation.StartCharIndex, citation.EndCharIndex
+	case "page_location":
+		object["document_index"], object["document_title"] = citation.DocumentIndex, citation.DocumentTitle
+		object["start_page_number"], object["end_page_number"] = citation.StartPageNumber, citation.EndPageNumber
+	case "content_block_location":
+		object["document_index"], object["document_title"] = citation.DocumentIndex, citation.DocumentTitle
+		object["start_block_index"], object["end_block_index"] = citation.StartBlockIndex, citation.EndBlockIndex
+	case "web_search_result_location":
+		object["url"], object["title"], object["encrypted_index"] = citation.URL, citation.Title, citation.EncryptedIndex
+	case "search_result_location":
+		object["source"], object["title"], object["search_result_index"] = citation.Source, citation.Title, citation.SearchResultIndex
+		object["start_block_index"], object["end_block_index"] = citation.StartBlockIndex, citation.EndBlockIndex
+	}
+	return object
+}
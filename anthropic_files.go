@@ -0,0 +1,297 @@
+package mockllm
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	anthropicFilesPath = "/v1/files"
+	// anthropicFilesBeta is the beta the Files API requires in the anthropic-beta header
+	anthropicFilesBeta = "files-api-2025-04-14"
+)
+
+// anthropicFileRoutes are the endpoints of the Files API, shared with the OpenAI Files API
+var anthropicFileRoutes = []Route{
+	{Method: http.MethodPost, Path: anthropicFilesPath},
+	{Method: http.MethodGet, Path: anthropicFilesPath},
+	{Method: http.MethodGet, Path: anthropicFilesPath + "/{id}"},
+	{Method: http.MethodDelete, Path: anthropicFilesPath + "/{id}"},
+	{Method: http.MethodGet, Path: anthropicFilesPath + "/{id}/content"},
+}
+
+// anthropicFile is the metadata of a file uploaded to the Files API emulation, along with its content
+type anthropicFile struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Filename  string    `json:"filename"`
+	MimeType  string    `json:"mime_type"`
+	SizeBytes int       `json:"size_bytes"`
+	CreatedAt time.Time `json:"created_at"`
+	// Downloadable is false for uploaded files, only files created by tools can be downloaded
+	Downloadable bool `json:"downloadable"`
+	content      []byte
+}
+
+// anthropicFileStore keeps uploaded files in memory, in upload order
+type anthropicFileStore struct {
+	mu    sync.Mutex
+	files []*anthropicFile
+}
+
+func newAnthropicFileStore() *anthropicFileStore {
+	return &anthropicFileStore{}
+}
+
+func (s *anthropicFileStore) add(file *anthropicFile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files = append(s.files, file)
+}
+
+// get returns the file with the given ID, or nil
+func (s *anthropicFileStore) get(id string) *anthropicFile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, file := range s.files {
+		if file.ID == id {
+			return file
+		}
+	}
+	return nil
+}
+
+// list returns the files, newest first
+func (s *anthropicFileStore) list() []*anthropicFile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files := slices.Clone(s.files)
+	slices.Reverse(files)
+	return files
+}
+
+// remove deletes the file with the given ID and reports whether it existed
+func (s *anthropicFileStore) remove(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, file := range s.files {
+		if file.ID == id {
+			s.files = slices.Delete(s.files, i, i+1)
+			return true
+		}
+	}
+	return false
+}
+
+// claims takes the requests to the routes of the Files API shared with the OpenAI provider that
+// carry Anthropic headers
+func (p *AnthropicProvider) claims(r *http.Request) bool {
+	return r.Header.Get("anthropic-version") != "" || r.Header.Get("x-api-key") != ""
+}
+
+// handleFiles serves the upload, list, metadata, delete and download endpoints of the Files API
+func (p *AnthropicProvider) handleFiles(w http.ResponseWriter, r *http.Request) {
+	if status, _, message := p.checkHeaders(r); status != 0 {
+		writeAnthropicError(w, status, message)
+		return
+	}
+	if missing := missingBetas(r.Header, anthropicBetaHeader, []string{anthropicFilesBeta}); len(missing) > 0 {
+		writeAnthropicError(w, http.StatusBadRequest,
+			fmt.Sprintf("The Files API requires the %s header to include %s", anthropicBetaHeader, anthropicFilesBeta))
+		return
+	}
+
+	id, content := strings.CutSuffix(strings.Trim(strings.TrimPrefix(r.URL.Path, anthropicFilesPath), "/"), "/content")
+	if id == "" {
+		switch r.Method {
+		case http.MethodPost:
+			p.uploadFile(w, r)
+		default:
+			p.listFiles(w, r)
+		}
+		return
+	}
+
+	file := p.files.get(id)
+	if file == nil {
+		writeAnthropicError(w, http.StatusNotFound, "File not found: "+id)
+		return
+	}
+	switch {
+	case content && !file.Downloadable:
+		writeAnthropicError(w, http.StatusBadRequest, fmt.Sprintf("File %s is not downloadable", id))
+	case content:
+		w.Header().Set("Content-Type", file.MimeType)
+		w.WriteHeader(http.StatusOK)
+		w.Write(file.content) //nolint:errcheck
+	case r.Method == http.MethodDelete:
+		p.files.remove(id)
+		writeJSON(w, http.StatusOK, map[string]any{"id": id, "type": "file_deleted"})
+	default:
+		writeJSON(w, http.StatusOK, file)
+	}
+}
+
+// uploadFile stores the file of a multipart upload, typed by its part's content type or else by
+// its extension
+func (p *AnthropicProvider) uploadFile(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, fmt.Sprintf("Failed to parse multipart form: %v", err))
+		return
+	}
+	upload, header, err := r.FormFile("file")
+	if err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, "file: Field required")
+		return
+	}
+	defer upload.Close() //nolint:errcheck
+
+	content, err := io.ReadAll(upload)
+	if err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, fmt.Sprintf("Failed to read file: %v", err))
+		return
+	}
+	mimeType, _, _ := strings.Cut(header.Header.Get("Content-Type"), ";")
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		if byExtension, _, _ := strings.Cut(mime.TypeByExtension(path.Ext(header.Filename)), ";"); byExtension != "" {
+			mimeType = byExtension
+		}
+	}
+	file := &anthropicFile{
+		ID:        p.ids.next("file", "file_", p.tenant),
+		Type:      "file",
+		Filename:  header.Filename,
+		MimeType:  cmp.Or(mimeType, "application/octet-stream"),
+		SizeBytes: len(content),
+		CreatedAt: p.clock.Now().UTC(),
+		content:   content,
+	}
+	p.files.add(file)
+	writeJSON(w, http.StatusOK, file)
+}
+
+// listFiles lists the uploaded files newest first, a page at a time
+func (p *AnthropicProvider) listFiles(w http.ResponseWriter, r *http.Request) {
+	files := p.files.list()
+	// The SDKs append the list parameters to the beta=true query of the path with another "?"
+	query, _ := url.ParseQuery(strings.ReplaceAll(r.URL.RawQuery, "?", "&"))
+	limit := 20
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 1000 {
+			writeAnthropicError(w, http.StatusBadRequest, "limit: Input should be between 1 and 1000")
+			return
+		}
+		limit = parsed
+	}
+	position := func(id string) int {
+		return slices.IndexFunc(files, func(file *anthropicFile) bool { return file.ID == id })
+	}
+	if after := query.Get("after_id"); after != "" {
+		files = files[position(after)+1:]
+	}
+	if before := query.Get("before_id"); before != "" {
+		if i := position(before); i >= 0 {
+			files = files[max(i-limit, 0):i]
+		}
+	}
+	hasMore := len(files) > limit
+	files = files[:min(len(files), limit)]
+
+	page := map[string]any{"data": files, "has_more": hasMore, "first_id": nil, "last_id": nil}
+	if len(files) > 0 {
+		page["first_id"], page["last_id"] = files[0].ID, files[len(files)-1].ID
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// resolveFileReferences rewrites the content blocks of a messages request referring to uploaded
+// files into blocks carrying their content, which the SDK request types can decode: document and
+// image sources of the file type, and container_upload blocks, which become documents with the
+// container_upload context. Requests without file references are returned as they are.
+func (p *AnthropicProvider) resolveFileReferences(body []byte) ([]byte, error) {
+	if !bytes.Contains(body, []byte(`"file_id"`)) {
+		return body, nil
+	}
+	var request map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&request); err != nil {
+		// Left for decoding the request to report
+		return body, nil
+	}
+
+	var resolve func(blocks []any) error
+	resolve = func(blocks []any) error {
+		for i, value := range blocks {
+			block, ok := value.(map[string]any)
+			if !ok {
+				continue
+			}
+			if content, ok := block["content"].([]any); ok {
+				if err := resolve(content); err != nil {
+					return err
+				}
+			}
+
+			source, _ := block["source"].(map[string]any)
+			var fileID any
+			switch {
+			case block["type"] == "container_upload":
+				fileID = block["file_id"]
+			case source != nil && source["type"] == "file":
+				fileID = source["file_id"]
+			default:
+				continue
+			}
+			id, _ := fileID.(string)
+			file := p.files.get(id)
+			if file == nil {
+				return fmt.Errorf("file not found: %v", fileID)
+			}
+
+			switch {
+			case block["type"] == "image":
+				block["source"] = map[string]any{"type": "base64", "media_type": file.MimeType,
+					"data": base64.StdEncoding.EncodeToString(file.content)}
+				continue
+			case block["type"] == "container_upload":
+				block = map[string]any{"type": "document", "context": "container_upload"}
+				blocks[i] = block
+			}
+			if _, ok := block["title"]; !ok {
+				block["title"] = file.Filename
+			}
+			block["source"] = map[string]any{"type": "base64", "media_type": "application/pdf",
+				"data": base64.StdEncoding.EncodeToString(file.content)}
+			if strings.HasPrefix(file.MimeType, "text/") {
+				block["source"] = map[string]any{"type": "text", "media_type": "text/plain", "data": string(file.content)}
+			}
+		}
+		return nil
+	}
+
+	messages, _ := request["messages"].([]any)
+	for _, value := range messages {
+		message, _ := value.(map[string]any)
+		if content, ok := message["content"].([]any); ok {
+			if err := resolve(content); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return json.Marshal(request)
+}
@@ -0,0 +1,141 @@
+package mockllm_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	openaioption "github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnthropicFiles(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		Anthropic: []mockllm.AnthropicMock{
+			{
+				Name: "report",
+				Match: mockllm.AnthropicRequestMatch{
+					MatchType: mockllm.MatchTypeFields,
+					Document:  &mockllm.DocumentMatch{Filename: "report.pdf", MediaType: "application/pdf"},
+				},
+				Response: anthropic.Message{Content: []anthropic.ContentBlockUnion{{Type: "text", Text: "Revenue grew."}}},
+			},
+			{
+				Name: "notes",
+				Match: mockllm.AnthropicRequestMatch{
+					MatchType: mockllm.MatchTypeFields,
+					Document:  &mockllm.DocumentMatch{Filename: "notes.txt", MediaType: "text/plain", MinBytes: 5},
+				},
+				Response: anthropic.Message{Content: []anthropic.ContentBlockUnion{{Type: "text", Text: "Noted."}}},
+			},
+		},
+		IDs: &mockllm.IDConfig{Seed: 7},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+
+	client := anthropic.NewClient(
+		anthropicoption.WithBaseURL(baseURL),
+		anthropicoption.WithAPIKey("test-key"),
+		anthropicoption.WithMaxRetries(0),
+	)
+	report, err := client.Beta.Files.Upload(t.Context(), anthropic.BetaFileUploadParams{
+		File: anthropic.File(strings.NewReader("%PDF-1.7 quarterly report"), "report.pdf", "application/pdf"),
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(report.ID, "file_"))
+	assert.Equal(t, "report.pdf", report.Filename)
+	assert.Equal(t, "application/pdf", report.MimeType)
+	assert.Equal(t, int64(25), report.SizeBytes)
+	assert.False(t, report.Downloadable)
+	assert.Contains(t, server.IDs(), mockllm.IssuedID{ID: report.ID, Kind: "file"})
+
+	// The type of files uploaded without one comes from their extension
+	notes, err := client.Beta.Files.Upload(t.Context(), anthropic.BetaFileUploadParams{
+		File: anthropic.File(strings.NewReader("Ship on Friday"), "notes.txt", ""),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain", notes.MimeType)
+
+	metadata, err := client.Beta.Files.GetMetadata(t.Context(), report.ID, anthropic.BetaFileGetMetadataParams{})
+	require.NoError(t, err)
+	assert.Equal(t, report.Filename, metadata.Filename)
+
+	page, err := client.Beta.Files.List(t.Context(), anthropic.BetaFileListParams{Limit: anthropic.Int(1)})
+	require.NoError(t, err)
+	require.Len(t, page.Data, 1)
+	assert.Equal(t, notes.ID, page.Data[0].ID)
+	assert.True(t, page.HasMore)
+	page, err = client.Beta.Files.List(t.Context(), anthropic.BetaFileListParams{AfterID: anthropic.String(notes.ID)})
+	require.NoError(t, err)
+	require.Len(t, page.Data, 1)
+	assert.Equal(t, report.ID, page.Data[0].ID)
+	assert.False(t, page.HasMore)
+
+	_, err = client.Beta.Files.Download(t.Context(), report.ID, anthropic.BetaFileDownloadParams{})
+	var apiErr *anthropic.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+
+	// Messages refer to uploaded files by ID, as document sources or container uploads
+	message := func(block map[string]any) map[string]any {
+		return map[string]any{
+			"model":      "claude-sonnet-4-5",
+			"max_tokens": 100,
+			"messages":   []any{map[string]any{"role": "user", "content": []any{block, map[string]any{"type": "text", "text": "Summarize"}}}},
+		}
+	}
+	reply := func(body map[string]any) anthropic.Message {
+		t.Helper()
+		resp := postJSON(t, baseURL+"/v1/messages", body, anthropicHeaders("2023-06-01"))
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var message anthropic.Message
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&message))
+		return message
+	}
+	document := reply(message(map[string]any{"type": "document", "source": map[string]any{"type": "file", "file_id": report.ID}}))
+	assert.Equal(t, "Revenue grew.", document.Content[0].Text)
+	upload := reply(message(map[string]any{"type": "container_upload", "file_id": notes.ID}))
+	assert.Equal(t, "Noted.", upload.Content[0].Text)
+
+	resp := postJSON(t, baseURL+"/v1/messages",
+		message(map[string]any{"type": "container_upload", "file_id": "file_missing"}), anthropicHeaders("2023-06-01"))
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	deleted, err := client.Beta.Files.Delete(t.Context(), report.ID, anthropic.BetaFileDeleteParams{})
+	require.NoError(t, err)
+	assert.Equal(t, report.ID, deleted.ID)
+	_, err = client.Beta.Files.GetMetadata(t.Context(), report.ID, anthropic.BetaFileGetMetadataParams{})
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+
+	// Requests without Anthropic headers still reach the OpenAI Files API
+	openaiClient := openai.NewClient(openaioption.WithBaseURL(baseURL+"/v1"), openaioption.WithAPIKey("test-key"))
+	openaiPage, err := openaiClient.Files.List(t.Context(), openai.FileListParams{})
+	require.NoError(t, err)
+	assert.Empty(t, openaiPage.Data)
+}
+
+func TestAnthropicFilesRequireBeta(t *testing.T) {
+	server := mockllm.NewServer()
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+
+	req, err := http.NewRequest(http.MethodGet, baseURL+"/v1/files", nil)
+	require.NoError(t, err)
+	for name, value := range anthropicHeaders("2023-06-01") {
+		req.Header.Set(name, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,113 @@
+package mockllm
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/kagent-dev/mockllm/tokenizer"
+)
+
+// anthropicInterruptedEvent ends an Anthropic stream cut short by the server stopping, with the
+// error event the API sends when it fails mid-stream
+var anthropicInterruptedEvent = newSSEEvent("error", map[string]any{
+	"type": "error",
+	"error": map[string]any{
+		"type":    "overloaded_error",
+		"message": "The server is shutting down",
+	},
+})
+
+// anthropicStreamEvents converts a message into the events the Messages API streams for it:
+// message_start, a start/delta/stop sequence per content block, message_delta and message_stop
+func anthropicStreamEvents(response anthropic.Message, tok tokenizer.Tokenizer) []sseEvent {
+	event := func(name string, data map[string]any) sseEvent {
+		data["type"] = name
+		return newSSEEvent(name, data)
+	}
+
+	events := []sseEvent{event("message_start", map[string]any{
+		"message": map[string]any{
+			"id":            response.ID,
+			"type":          "message",
+			"role":          "assistant",
+			"model":         response.Model,
+			"content":       []any{},
+			"stop_reason":   nil,
+			"stop_sequence": nil,
+			"usage": map[string]any{
+				"input_tokens":                response.Usage.InputTokens,
+				"output_tokens":               1,
+				"cache_creation_input_tokens": response.Usage.CacheCreationInputTokens,
+				"cache_read_input_tokens":     response.Usage.CacheReadInputTokens,
+			},
+		},
+	})}
+
+	for i, block := range response.Content {
+		start, deltas := anthropicBlockEvents(block, tok)
+		events = append(events, event("content_block_start", map[string]any{"index": i, "content_block": start}))
+		for _, delta := range deltas {
+			events = append(events, event("content_block_delta", map[string]any{"index": i, "delta": delta}))
+		}
+		events = append(events, event("content_block_stop", map[string]any{"index": i}))
+	}
+
+	stopReason := string(response.StopReason)
+	if stopReason == "" {
+		stopReason = string(anthropic.StopReasonEndTurn)
+	}
+	var stopSequence any
+	if response.StopSequence != "" {
+		stopSequence = response.StopSequence
+	}
+	usage := map[string]any{"output_tokens": response.Usage.OutputTokens}
+	if requests := response.Usage.ServerToolUse.WebSearchRequests; requests > 0 {
+		usage["server_tool_use"] = map[string]any{"web_search_requests": requests}
+	}
+	events = append(events,
+		event("message_delta", map[string]any{
+			"delta": map[string]any{"stop_reason": stopReason, "stop_sequence": stopSequence},
+			"usage": usage,
+		}),
+		event("message_stop", map[string]any{}),
+	)
+	return events
+}
+
+// anthropicBlockEvents returns the content_block_start payload of a content block and the
+// single token deltas that build up its content
+func anthropicBlockEvents(block anthropic.ContentBlockUnion, tok tokenizer.Tokenizer) (any, []any) {
+	var deltas []any
+	switch block.Type {
+	case "text":
+		// Citations precede the text they are attached to
+		for _, citation := range block.Citations {
+			deltas = append(deltas, map[string]any{"type": "citations_delta", "citation": anthropicCitationJSON(citation)})
+		}
+		for _, piece := range tok.Tokenize(block.Text) {
+			deltas = append(deltas, map[string]any{"type": "text_delta", "text": piece})
+		}
+		return map[string]any{"type": "text", "text": ""}, deltas
+	case "tool_use", "server_tool_use":
+		var input bytes.Buffer
+		if err := json.Compact(&input, block.Input); err == nil {
+			for _, piece := range tok.Tokenize(input.String()) {
+				deltas = append(deltas, map[string]any{"type": "input_json_delta", "partial_json": piece})
+			}
+		}
+		return map[string]any{"type": block.Type, "id": block.ID, "name": block.Name, "input": map[string]any{}}, deltas
+	case "thinking":
+		for _, piece := range tok.Tokenize(block.Thinking) {
+			deltas = append(deltas, map[string]any{"type": "thinking_delta", "thinking": piece})
+		}
+		if block.Signature != "" {
+			deltas = append(deltas, map[string]any{"type": "signature_delta", "signature": block.Signature})
+		}
+		return map[string]any{"type": "thinking", "thinking": ""}, deltas
+	default:
+		// Blocks without incremental deltas, such as redacted thinking and web search results, are
+		// sent whole
+		return anthropicBlockJSON(block), nil
+	}
+}
@@ -0,0 +1,222 @@
+package mockllm_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/kagent-dev/mockllm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// anthropicHelloRequest is a minimal Anthropic request whose last message says "Hello"
+var anthropicHelloRequest = anthropic.MessageNewParams{
+	Model:     "claude-3-5-sonnet-20240620",
+	MaxTokens: 1000,
+	Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("Hello"))},
+}
+
+// anthropicHeaders returns the headers required by the Anthropic handler for the given version
+func anthropicHeaders(version string) map[string]string {
+	return map[string]string{"x-api-key": "test-key", "anthropic-version": version}
+}
+
+func TestAnthropicVersions(t *testing.T) {
+	config := mockllm.Config{
+		Anthropic: []mockllm.AnthropicMock{
+			{
+				Name: "hello",
+				Match: mockllm.AnthropicRequestMatch{
+					MatchType: mockllm.MatchTypeContains,
+					Message:   anthropic.NewUserMessage(anthropic.NewTextBlock("Hello")),
+				},
+				Response: anthropic.Message{ID: "msg_current"},
+				VersionResponses: map[string]anthropic.Message{
+					"2023-01-01": {ID: "msg_legacy"},
+				},
+			},
+		},
+	}
+
+	server := mockllm.NewServer(mockllm.WithConfig(config))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	for version, expectedID := range map[string]string{"2023-06-01": "msg_current", "2023-01-01": "msg_legacy"} {
+		resp := postJSON(t, baseURL+"/v1/messages", anthropicHelloRequest, anthropicHeaders(version))
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var message map[string]any
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&message))
+		assert.Equal(t, expectedID, message["id"], version)
+	}
+
+	resp := postJSON(t, baseURL+"/v1/messages", anthropicHelloRequest, anthropicHeaders("2099-01-01"))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	var body struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "invalid_request_error", body.Error.Type)
+	assert.Contains(t, body.Error.Message, "2099-01-01")
+}
+
+func TestAnthropicBetaHeaders(t *testing.T) {
+	config := mockllm.Config{
+		Anthropic: []mockllm.AnthropicMock{
+			{
+				Name: "cached",
+				Match: mockllm.AnthropicRequestMatch{
+					MatchType: mockllm.MatchTypeContains,
+					Message:   anthropic.NewUserMessage(anthropic.NewTextBlock("Hello")),
+				},
+				Response: anthropic.Message{ID: "msg_cached"},
+				Betas:    []string{"prompt-caching-2024-07-31"},
+			},
+		},
+	}
+
+	server := mockllm.NewServer(mockllm.WithConfig(config))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	resp := postJSON(t, baseURL+"/v1/messages", anthropicHelloRequest, anthropicHeaders("2023-06-01"))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	headers := anthropicHeaders("2023-06-01")
+	headers["anthropic-beta"] = "token-counting-2024-11-01, prompt-caching-2024-07-31"
+	resp = postJSON(t, baseURL+"/v1/messages", anthropicHelloRequest, headers)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestAnthropicPromptCacheSimulation(t *testing.T) {
+	config := mockllm.Config{
+		Anthropic: []mockllm.AnthropicMock{
+			{
+				Name: "cached",
+				Match: mockllm.AnthropicRequestMatch{
+					MatchType: mockllm.MatchTypeContains,
+					Message:   anthropic.NewUserMessage(anthropic.NewTextBlock("Hello")),
+				},
+				Response:            anthropic.Message{ID: "msg_cached"},
+				SimulatePromptCache: true,
+			},
+		},
+	}
+
+	server := mockllm.NewServer(mockllm.WithConfig(config))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	request := anthropicHelloRequest
+	request.System = []anthropic.TextBlockParam{
+		{
+			Text:         strings.Repeat("You are a helpful assistant. ", 100),
+			CacheControl: anthropic.NewCacheControlEphemeralParam(),
+		},
+	}
+
+	usage := func() anthropic.Usage {
+		resp := postJSON(t, baseURL+"/v1/messages", request, anthropicHeaders("2023-06-01"))
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var message anthropic.Message
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&message))
+		return message.Usage
+	}
+
+	first := usage()
+	assert.Positive(t, first.CacheCreationInputTokens)
+	assert.Zero(t, first.CacheReadInputTokens)
+	assert.Positive(t, first.InputTokens)
+
+	second := usage()
+	assert.Zero(t, second.CacheCreationInputTokens)
+	assert.Equal(t, first.CacheCreationInputTokens, second.CacheReadInputTokens)
+	assert.Equal(t, first.InputTokens, second.InputTokens)
+}
+
+func TestAnthropicPartialResponseDefaults(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{Anthropic: []mockllm.AnthropicMock{{
+		Name:     "partial",
+		Match:    mockllm.AnthropicRequestMatch{MatchType: mockllm.MatchTypeExact, Message: anthropicHelloRequest.Messages[0]},
+		Response: anthropic.Message{Content: []anthropic.ContentBlockUnion{{Type: "text", Text: "Hi"}}},
+	}}}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	resp := postJSON(t, baseURL+"/v1/messages", anthropicHelloRequest, anthropicHeaders("2023-06-01"))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var message anthropic.Message
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&message))
+	assert.True(t, strings.HasPrefix(message.ID, "msg_"))
+	assert.Equal(t, "message", string(message.Type))
+	assert.Equal(t, "assistant", string(message.Role))
+	assert.Equal(t, anthropicHelloRequest.Model, message.Model)
+	assert.Equal(t, anthropic.StopReasonEndTurn, message.StopReason)
+}
+
+func TestAnthropicWebSearchAndCitations(t *testing.T) {
+	config, err := mockllm.LoadConfigFromFile("mocks.json", fstest.MapFS{"mocks.json": {Data: []byte(`{
+  "anthropic": [{
+    "name": "search",
+    "match": {"match_type": "contains", "message": {"role": "user", "content": [{"type": "text", "text": "Hello"}]}},
+    "response": {"content": [
+      {"type": "server_tool_use", "id": "srvtoolu_1", "name": "web_search", "input": {"query": "kagent"}},
+      {"type": "web_search_tool_result", "tool_use_id": "srvtoolu_1", "content": [
+        {"type": "web_search_result", "url": "https://kagent.dev", "title": "kagent", "encrypted_content": "EqgfCioIARgB", "page_age": "2 days ago"}
+      ]},
+      {"type": "text", "text": "kagent runs agents on Kubernetes.", "citations": [
+        {"type": "web_search_result_location", "url": "https://kagent.dev", "title": "kagent", "encrypted_index": "Eo8BCioIAhgB", "cited_text": "Bringing agentic AI to cloud native"}
+      ]}
+    ]}
+  }]
+}`)}})
+	require.NoError(t, err)
+	server := mockllm.NewServer(mockllm.WithConfig(config))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+	client := anthropic.NewClient(anthropicoption.WithBaseURL(baseURL), anthropicoption.WithAPIKey("test-key"))
+
+	check := func(message anthropic.Message) {
+		t.Helper()
+		require.Len(t, message.Content, 3)
+		assert.Equal(t, "server_tool_use", message.Content[0].Type)
+		assert.JSONEq(t, `{"query": "kagent"}`, string(message.Content[0].Input))
+		results := message.Content[1].Content.AsWebSearchResultBlockArray()
+		require.Len(t, results, 1)
+		assert.Equal(t, "https://kagent.dev", results[0].URL)
+		assert.Equal(t, "srvtoolu_1", message.Content[1].ToolUseID)
+		assert.Equal(t, "kagent runs agents on Kubernetes.", message.Content[2].Text)
+		require.Len(t, message.Content[2].Citations, 1)
+		citation := message.Content[2].Citations[0].AsWebSearchResultLocation()
+		assert.Equal(t, "Bringing agentic AI to cloud native", citation.CitedText)
+		assert.Equal(t, "https://kagent.dev", citation.URL)
+		assert.Equal(t, anthropic.StopReasonEndTurn, message.StopReason)
+	}
+
+	message, err := client.Messages.New(t.Context(), anthropicHelloRequest)
+	require.NoError(t, err)
+	check(*message)
+	assert.Equal(t, int64(1), message.Usage.ServerToolUse.WebSearchRequests)
+
+	stream := client.Messages.NewStreaming(t.Context(), anthropicHelloRequest)
+	var streamed anthropic.Message
+	for stream.Next() {
+		require.NoError(t, streamed.Accumulate(stream.Current()))
+	}
+	require.NoError(t, stream.Err())
+	check(streamed)
+}
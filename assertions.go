@@ -0,0 +1,161 @@
+package mockllm
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Assertion is a check on the calls of a mock, or on the unmatched requests, declared in the
+// config so harnesses not written in Go can verify a run: assertions are evaluated when the server
+// stops, failing Stop and Run, and by Server.Verify and GET /admin/verify, e.g.
+// {"mock": "search", "min_calls": 1} or {"unmatched": true, "max_calls": 0}
+type Assertion struct {
+	// Mock is the name of the mock whose calls are counted, across providers and tenants
+	Mock string `json:"mock,omitempty"`
+	// Unmatched counts the OpenAI and Anthropic requests no mock matched instead
+	Unmatched bool `json:"unmatched,omitempty"`
+	// MinCalls is the fewest calls expected
+	MinCalls int `json:"min_calls,omitempty"`
+	// MaxCalls is the most calls expected, unbounded when unset, so 0 expects no call at all
+	MaxCalls *int `json:"max_calls,omitempty"`
+}
+
+// String describes what the assertion expects
+func (a Assertion) String() string {
+	subject := fmt.Sprintf("mock %q", a.Mock)
+	if a.Unmatched {
+		subject = "unmatched requests"
+	}
+	switch {
+	case a.MaxCalls != nil && *a.MaxCalls == 0:
+		return subject + " never called"
+	case a.MaxCalls != nil && a.MinCalls == *a.MaxCalls:
+		return fmt.Sprintf("%s called %s", subject, times(a.MinCalls))
+	case a.MaxCalls != nil:
+		return fmt.Sprintf("%s called %d to %d times", subject, a.MinCalls, *a.MaxCalls)
+	default:
+		return fmt.Sprintf("%s called at least %s", subject, times(a.MinCalls))
+	}
+}
+
+// times writes a number of calls, e.g. once or 3 times
+func times(n int) string {
+	if n == 1 {
+		return "once"
+	}
+	return fmt.Sprintf("%d times", n)
+}
+
+// AssertionResult is the outcome of an assertion
+type AssertionResult struct {
+	Assertion
+	// Description is the assertion as String writes it
+	Description string `json:"description"`
+	Calls       int    `json:"calls"`
+	Passed      bool   `json:"passed"`
+}
+
+// unmatched reports whether no mock matched the request, as opposed to it failing before matching
+func (r RequestRecord) unmatched() bool {
+	return (r.Provider == providerOpenAI || r.Provider == providerAnthropic) && !r.Matched &&
+		r.Status == http.StatusNotFound && (r.Error == "" || r.Error == batchNoMatchError)
+}
+
+// Assertions evaluates the assertions of the config against the requests served so far
+func (s *Server) Assertions() []AssertionResult {
+	results := make([]AssertionResult, 0, len(s.config.Assertions))
+	records := s.Requests()
+	for _, assertion := range s.config.Assertions {
+		calls := 0
+		for _, record := range records {
+			if assertion.Unmatched && record.unmatched() || !assertion.Unmatched && record.Matched && record.MockName == assertion.Mock {
+				calls++
+			}
+		}
+		results = append(results, AssertionResult{
+			Assertion:   assertion,
+			Description: assertion.String(),
+			Calls:       calls,
+			Passed:      calls >= assertion.MinCalls && (assertion.MaxCalls == nil || calls <= *assertion.MaxCalls),
+		})
+	}
+	return results
+}
+
+// assertionFailures returns an error for every failed assertion
+func (s *Server) assertionFailures() []error {
+	var errs []error
+	for _, result := range s.Assertions() {
+		if !result.Passed {
+			errs = append(errs, fmt.Errorf("assertion failed: %s, was called %s", result.Description, times(result.Calls)))
+		}
+	}
+	return errs
+}
+
+// assertionIssues reports assertions counting neither a mock nor the unmatched requests, naming
+// mocks the config lacks, or with bounds no count meets
+func assertionIssues(config Config) []configIssue {
+	names := map[string]bool{}
+	for _, mock := range config.HTTP {
+		names[mock.Name] = true
+	}
+	addMocks := func(openaiMocks []OpenAIMock, anthropicMocks []AnthropicMock) {
+		for _, mock := range openaiMocks {
+			names[mock.Name] = true
+		}
+		for _, mock := range anthropicMocks {
+			names[mock.Name] = true
+		}
+	}
+	addMocks(config.OpenAI, config.Anthropic)
+	for _, tenant := range config.Tenants {
+		addMocks(tenant.OpenAI, tenant.Anthropic)
+	}
+	for _, organization := range config.OpenAIOrganizations {
+		addMocks(organization.OpenAI, nil)
+		for _, project := range organization.Projects {
+			addMocks(project.OpenAI, nil)
+		}
+	}
+
+	var issues []configIssue
+	for i, assertion := range config.Assertions {
+		path := fmt.Sprintf("assertions[%d]", i)
+		switch {
+		case (assertion.Mock == "") == !assertion.Unmatched:
+			issues = append(issues, configIssue{path, "must set either mock or unmatched"})
+		case assertion.Mock != "" && !names[assertion.Mock]:
+			issues = append(issues, configIssue{path + ".mock", fmt.Sprintf("no mock named %q", assertion.Mock)})
+		}
+		if assertion.MinCalls < 0 || assertion.MaxCalls != nil && *assertion.MaxCalls < assertion.MinCalls {
+			issues = append(issues, configIssue{path, "min_calls must be between 0 and max_calls"})
+		}
+	}
+	return issues
+}
+
+// handleAdminVerify reports the assertions of the config and the failures of Verify, with a 417
+// status when any failed
+func (s *Server) handleAdminVerify(w http.ResponseWriter, r *http.Request) {
+	failures := []string{}
+	for _, err := range unwrapJoined(s.Verify()) {
+		failures = append(failures, err.Error())
+	}
+	status := http.StatusOK
+	if len(failures) > 0 {
+		status = http.StatusExpectationFailed
+	}
+	writeJSON(w, status, map[string]any{"passed": len(failures) == 0, "assertions": s.Assertions(), "failures": failures})
+}
+
+// unwrapJoined returns the errors joined by errors.Join, nil for a nil error
+func unwrapJoined(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
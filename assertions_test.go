@@ -0,0 +1,99 @@
+package mockllm_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssertions(t *testing.T) {
+	never := 0
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{
+			{
+				Name:     "hello",
+				Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+				Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "Hi"}}}},
+			},
+			{
+				Name:     "refund",
+				Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("refund")},
+				Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "No"}}}},
+			},
+		},
+		Assertions: []mockllm.Assertion{
+			{Mock: "hello", MinCalls: 1},
+			{Mock: "refund", MaxCalls: &never},
+			{Unmatched: true, MaxCalls: &never},
+		},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+
+	verify := func() (int, []mockllm.AssertionResult, []string) {
+		t.Helper()
+		resp, err := http.Get(baseURL + "/admin/verify")
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint:errcheck
+		var body struct {
+			Assertions []mockllm.AssertionResult `json:"assertions"`
+			Failures   []string                  `json:"failures"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return resp.StatusCode, body.Assertions, body.Failures
+	}
+
+	status, results, failures := verify()
+	assert.Equal(t, http.StatusExpectationFailed, status)
+	assert.Equal(t, []string{`assertion failed: mock "hello" called at least once, was called 0 times`}, failures)
+	require.Len(t, results, 3)
+	assert.Equal(t, "unmatched requests never called", results[2].Description)
+
+	postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+		Model:    "gpt-4o",
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Hello")},
+	})
+	status, results, _ = verify()
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 1, results[0].Calls)
+	require.NoError(t, server.Verify())
+
+	resp := postJSON(t, baseURL+"/v1/chat/completions", openai.ChatCompletionNewParams{
+		Model:    "gpt-4o",
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Goodbye")},
+	}, openaiHeaders)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Stopping evaluates the assertions too
+	err = server.Stop(t.Context())
+	require.Error(t, err)
+	assert.Equal(t, "assertion failed: unmatched requests never called, was called once", err.Error())
+}
+
+func TestAssertionValidation(t *testing.T) {
+	filesys := fstest.MapFS{"config.json": {Data: []byte(`{
+  "openai": [{
+    "name": "hello",
+    "match": {"match_type": "contains", "message": {"role": "user", "content": "Hello"}},
+    "response": {"choices": [{"index": 0, "message": {"role": "assistant", "content": "Hi"}, "finish_reason": "stop"}]}
+  }],
+  "assertions": [
+    {"mock": "hello", "min_calls": 1},
+    {"mock": "helo", "min_calls": 1},
+    {"min_calls": 1},
+    {"mock": "hello", "min_calls": 2, "max_calls": 1}
+  ]
+}`)}}
+	_, err := mockllm.LoadConfigFromFile("config.json", filesys)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `assertions[1].mock: no mock named "helo"`)
+	assert.Contains(t, err.Error(), "assertions[2]: must set either mock or unmatched")
+	assert.Contains(t, err.Error(), "assertions[3]: min_calls must be between 0 and max_calls")
+	assert.NotContains(t, err.Error(), "assertions[0]")
+}
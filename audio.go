@@ -0,0 +1,183 @@
+package mockllm
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+const (
+	// speechSampleRate is the sample rate of generated speech, that of the pcm16 format of the
+	// OpenAI API
+	speechSampleRate = 24000
+	// speechWordDuration is how long generated speech takes per word of its transcript
+	speechWordDuration = 250 * time.Millisecond
+	// audioLifetime is how long generated audio can be referred to in later turns
+	audioLifetime = time.Hour
+)
+
+// AudioMatch matches an input_audio part of the conversation, as sent to audio models such as
+// gpt-4o-audio-preview. Any audio part of the conversation that meets every criterion matches.
+type AudioMatch struct {
+	// Format is a wildcard pattern, or a regular expression between slashes, the format of the
+	// audio must match, e.g. "wav"
+	Format string `json:"format,omitempty"`
+	// Transcript is a pattern the transcript of the audio must match, e.g. "*weather*". Only WAV
+	// audio carrying its transcript has one, such as the audio of mock responses or SpeechWAV.
+	Transcript string `json:"transcript,omitempty"`
+}
+
+// inputAudio is an input_audio part of a request
+type inputAudio struct {
+	format     string
+	transcript string
+}
+
+// mismatch returns why none of the audio parts matches, or an empty string
+func (m *AudioMatch) mismatch(parts []inputAudio) string {
+	if m == nil {
+		return ""
+	}
+	if len(parts) == 0 {
+		return "request has no input audio"
+	}
+	format, err := compileFieldPattern(cmp.Or(m.Format, "*"))
+	if err != nil {
+		return fmt.Sprintf("invalid audio format pattern: %v", err)
+	}
+	transcript, err := compileFieldPattern(cmp.Or(m.Transcript, "*"))
+	if err != nil {
+		return fmt.Sprintf("invalid audio transcript pattern: %v", err)
+	}
+	for _, part := range parts {
+		if format.MatchString(part.format) && transcript.MatchString(part.transcript) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("none of the %d input audio parts matches", len(parts))
+}
+
+// openaiInputAudio returns the input_audio parts of the request's user messages
+func openaiInputAudio(request openai.ChatCompletionNewParams) []inputAudio {
+	var parts []inputAudio
+	for _, message := range request.Messages {
+		if message.OfUser == nil {
+			continue
+		}
+		for _, part := range message.OfUser.Content.OfArrayOfContentParts {
+			if part.OfInputAudio == nil {
+				continue
+			}
+			audio := part.OfInputAudio.InputAudio
+			data, _ := base64.StdEncoding.DecodeString(audio.Data)
+			parts = append(parts, inputAudio{format: audio.Format, transcript: wavTranscript(data)})
+		}
+	}
+	return parts
+}
+
+// fillOpenAIAudio completes the audio of a message, for requests asking for the audio modality:
+// the text content becomes the transcript of audio that is generated unless the mock configures
+// its data. Audio is WAV, or raw samples for the pcm16 format; other formats get WAV too.
+func fillOpenAIAudio(mockName string, request openai.ChatCompletionNewParams, message *openai.ChatCompletionMessage,
+	now time.Time) {
+	audio := &message.Audio
+	if audio.Transcript == "" && audio.Data == "" {
+		if !slices.Contains(request.Modalities, "audio") || message.Content == "" {
+			return
+		}
+		audio.Transcript, message.Content = message.Content, ""
+	}
+	if audio.Data == "" {
+		speech := SpeechWAV(audio.Transcript)
+		if request.Audio.Format == openai.ChatCompletionAudioParamFormatPcm16 {
+			speech = speech[len(speech)-speechSamples(audio.Transcript)*2:]
+		}
+		audio.Data = base64.StdEncoding.EncodeToString(speech)
+	}
+	if audio.ID == "" {
+		audio.ID = mockResponseID("audio_", mockName)
+	}
+	if audio.ExpiresAt == 0 {
+		audio.ExpiresAt = now.Add(audioLifetime).Unix()
+	}
+}
+
+// speechSamples is the number of samples of the speech of a transcript
+func speechSamples(transcript string) int {
+	words := max(len(strings.Fields(transcript)), 1)
+	return int(time.Duration(words) * speechWordDuration * speechSampleRate / time.Second)
+}
+
+// SpeechWAV returns a WAV file of silence lasting as long as saying the transcript takes, which
+// carries the transcript in its INFO comment. Tests can send it as input audio that mocks match by
+// transcript, see AudioMatch.
+func SpeechWAV(transcript string) []byte {
+	samples := speechSamples(transcript)
+
+	comment := append([]byte(transcript), 0)
+	if len(comment)%2 == 1 {
+		comment = append(comment, 0)
+	}
+	var info bytes.Buffer
+	info.WriteString("INFO")
+	writeWAVChunk(&info, "ICMT", comment)
+
+	var body bytes.Buffer
+	body.WriteString("WAVE")
+	format := make([]byte, 16)
+	binary.LittleEndian.PutUint16(format[0:], 1) // PCM
+	binary.LittleEndian.PutUint16(format[2:], 1) // mono
+	binary.LittleEndian.PutUint32(format[4:], speechSampleRate)
+	binary.LittleEndian.PutUint32(format[8:], speechSampleRate*2)
+	binary.LittleEndian.PutUint16(format[12:], 2)
+	binary.LittleEndian.PutUint16(format[14:], 16)
+	writeWAVChunk(&body, "fmt ", format)
+	writeWAVChunk(&body, "LIST", info.Bytes())
+	writeWAVChunk(&body, "data", make([]byte, samples*2))
+
+	var wav bytes.Buffer
+	writeWAVChunk(&wav, "RIFF", body.Bytes())
+	return wav.Bytes()
+}
+
+func writeWAVChunk(w *bytes.Buffer, id string, data []byte) {
+	w.WriteString(id)
+	binary.Write(w, binary.LittleEndian, uint32(len(data))) //nolint:errcheck // writes to a buffer don't fail
+	w.Write(data)
+}
+
+// wavTranscript returns the INFO comment of a WAV file, empty when it has none or is no WAV file
+func wavTranscript(data []byte) string {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return ""
+	}
+	for chunks := data[12:]; len(chunks) >= 8; {
+		id, size := string(chunks[0:4]), int(binary.LittleEndian.Uint32(chunks[4:8]))
+		if size > len(chunks)-8 {
+			return ""
+		}
+		chunk := chunks[8 : 8+size]
+		if id == "LIST" && len(chunk) >= 4 && string(chunk[0:4]) == "INFO" {
+			for fields := chunk[4:]; len(fields) >= 8; {
+				fieldID, fieldSize := string(fields[0:4]), int(binary.LittleEndian.Uint32(fields[4:8]))
+				if fieldSize > len(fields)-8 {
+					break
+				}
+				if fieldID == "ICMT" {
+					return strings.TrimRight(string(fields[8:8+fieldSize]), "\x00")
+				}
+				fields = fields[8+fieldSize+fieldSize%2:]
+			}
+		}
+		chunks = chunks[min(8+size+size%2, len(chunks)):]
+	}
+	return ""
+}
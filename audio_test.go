@@ -0,0 +1,76 @@
+package mockllm_test
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIAudio(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{
+			{
+				Name: "weather",
+				Match: mockllm.OpenAIRequestMatch{
+					MatchType: mockllm.MatchTypeFields,
+					Audio:     &mockllm.AudioMatch{Format: "wav", Transcript: "*weather*"},
+				},
+				Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{
+					Message: openai.ChatCompletionMessage{Content: "It is sunny in Paris."},
+				}}},
+			},
+		},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	question := func(transcript string) openai.ChatCompletionNewParams {
+		return openai.ChatCompletionNewParams{
+			Model:      "gpt-4o-audio-preview",
+			Modalities: []string{"text", "audio"},
+			Audio:      openai.ChatCompletionAudioParam{Format: openai.ChatCompletionAudioParamFormatWAV, Voice: "alloy"},
+			Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage([]openai.ChatCompletionContentPartUnionParam{
+				openai.InputAudioContentPart(openai.ChatCompletionContentPartInputAudioInputAudioParam{
+					Data:   base64.StdEncoding.EncodeToString(mockllm.SpeechWAV(transcript)),
+					Format: "wav",
+				}),
+			})},
+		}
+	}
+	client := openai.NewClient(option.WithBaseURL(baseURL+"/v1"), option.WithAPIKey("test-key"), option.WithMaxRetries(0))
+	completion, err := client.Chat.Completions.New(t.Context(), question("What is the weather in Paris?"))
+	require.NoError(t, err)
+	audio := completion.Choices[0].Message.Audio
+	assert.Equal(t, "It is sunny in Paris.", audio.Transcript, "the reply is spoken")
+	assert.Empty(t, completion.Choices[0].Message.Content)
+	assert.NotEmpty(t, audio.ID)
+	assert.NotZero(t, audio.ExpiresAt)
+	data, err := base64.StdEncoding.DecodeString(audio.Data)
+	require.NoError(t, err)
+	assert.Equal(t, mockllm.SpeechWAV("It is sunny in Paris."), data)
+
+	_, err = client.Chat.Completions.New(t.Context(), question("Tell me a joke"))
+	var apiErr *openai.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode, "the transcript must match")
+
+	stream := question("How is the weather in Paris?")
+	resp := postJSON(t, baseURL+"/v1/chat/completions", map[string]any{
+		"model": stream.Model, "modalities": stream.Modalities, "audio": stream.Audio, "messages": stream.Messages, "stream": true,
+	}, map[string]string{"Authorization": "Bearer test-key"})
+	defer resp.Body.Close() //nolint:errcheck
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"audio":{"id":"audio_`)
+	assert.Contains(t, string(body), `"audio":{"transcript":"It"}`)
+	assert.Contains(t, string(body), `"audio":{"data":"UklGR`)
+}
@@ -0,0 +1,48 @@
+package mockllm
+
+import (
+	"math/rand/v2"
+	"sync"
+)
+
+// MatchProbability makes a mock match only a share of the requests it would otherwise match,
+// like a canary backend serving part of the traffic. The other requests fall through to the next
+// matching mock, so clients can be tested against varying responses.
+type MatchProbability struct {
+	// Rate is the share of the requests the mock matches, between 0 and 1
+	Rate float64 `json:"rate"`
+	// Seed picks the sequence of draws, which is the same from run to run for a seed
+	Seed uint64 `json:"seed,omitempty"`
+}
+
+// matchDraws counts the draws of the mocks matching with a probability
+type matchDraws struct {
+	mu   sync.Mutex
+	next map[string]int
+}
+
+func newMatchDraws() *matchDraws {
+	return &matchDraws{next: map[string]int{}}
+}
+
+// draw reports whether the named mock matches this time, always when it has no probability
+func (d *matchDraws) draw(mockName string, probability *MatchProbability) bool {
+	if probability == nil {
+		return true
+	}
+	d.mu.Lock()
+	iteration := d.next[mockName]
+	d.next[mockName]++
+	d.mu.Unlock()
+
+	rng := rand.New(rand.NewPCG(probability.Seed, uint64(iteration)))
+	return rng.Float64() < probability.Rate
+}
+
+// probabilityIssues reports rates outside of [0, 1]
+func probabilityIssues(probability *MatchProbability, path string) []configIssue {
+	if probability == nil || (probability.Rate >= 0 && probability.Rate <= 1) {
+		return nil
+	}
+	return []configIssue{{path + ".rate", "must be between 0 and 1"}}
+}
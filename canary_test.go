@@ -0,0 +1,95 @@
+package mockllm_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchProbability(t *testing.T) {
+	reply := func(name, content string, probability *mockllm.MatchProbability) mockllm.OpenAIMock {
+		return mockllm.OpenAIMock{
+			Name:        name,
+			Match:       mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+			Response:    openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: content}}}},
+			Probability: probability,
+		}
+	}
+	config := mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{
+			reply("never", "Never", &mockllm.MatchProbability{Rate: 0}),
+			reply("canary", "Canary", &mockllm.MatchProbability{Rate: 0.3, Seed: 42}),
+			reply("stable", "Stable", nil),
+		},
+		Anthropic: []mockllm.AnthropicMock{{
+			Name:        "canary",
+			Match:       mockllm.AnthropicRequestMatch{MatchType: mockllm.MatchTypeContains, Message: anthropicHelloRequest.Messages[0]},
+			Response:    anthropic.Message{Content: []anthropic.ContentBlockUnion{{Type: "text", Text: "Canary"}}},
+			Probability: &mockllm.MatchProbability{Rate: 0.5},
+		}},
+	}
+
+	serve := func() ([]string, int) {
+		server := mockllm.NewServer(mockllm.WithConfig(config))
+		baseURL, err := server.Start(t.Context())
+		require.NoError(t, err)
+		t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+
+		var contents []string
+		for range 50 {
+			completion := postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+				Model:    "gpt-4o",
+				Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Hello")},
+			})
+			contents = append(contents, completion.Choices[0].Message.Content)
+		}
+
+		client := anthropic.NewClient(anthropicoption.WithBaseURL(baseURL), anthropicoption.WithAPIKey("test-key"),
+			anthropicoption.WithMaxRetries(0))
+		unmatched := 0
+		for range 20 {
+			if _, err := client.Messages.New(t.Context(), anthropicHelloRequest); err != nil {
+				unmatched++
+			}
+		}
+		return contents, unmatched
+	}
+
+	contents, unmatched := serve()
+	assert.NotContains(t, contents, "Never")
+	canaries := 0
+	for _, content := range contents {
+		if content == "Canary" {
+			canaries++
+		}
+	}
+	assert.Greater(t, canaries, 5)
+	assert.Less(t, canaries, 30)
+	// Requests losing the draw of the only matching mock go unmatched
+	assert.Greater(t, unmatched, 0)
+	assert.Less(t, unmatched, 20)
+
+	// The draws of a seed are the same from run to run
+	again, _ := serve()
+	assert.Equal(t, contents, again)
+}
+
+func TestMatchProbabilityValidation(t *testing.T) {
+	filesys := fstest.MapFS{"config.json": {Data: []byte(`{
+  "openai": [{
+    "name": "canary",
+    "match": {"match_type": "contains", "message": {"role": "user", "content": "Hello"}},
+    "response": {"choices": [{"index": 0, "message": {"role": "assistant", "content": "Hi"}, "finish_reason": "stop"}]},
+    "probability": {"rate": 1.5}
+  }]
+}`)}}
+	_, err := mockllm.LoadConfigFromFile("config.json", filesys)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "openai[0].probability.rate")
+}
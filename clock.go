@@ -0,0 +1,128 @@
+package mockllm
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and waits for simulated delays. Config.Clock injects a FakeClock into a
+// server, so simulated latency, rate limit windows and batch and fine-tuning timers elapse under
+// the test's control instead of in real time.
+type Clock interface {
+	Now() time.Time
+	// After returns a channel that receives the time once d has elapsed
+	After(d time.Duration) <-chan time.Time
+	// Sleep blocks until d has elapsed or ctx is done, and reports whether d elapsed
+	Sleep(ctx context.Context, d time.Duration) bool
+}
+
+// systemClock is the real time
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (systemClock) Sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// FakeClock is a Clock that only moves when advanced
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+// fakeWaiter is a channel of FakeClock.After waiting for its deadline
+type fakeWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// NewFakeClock returns a fake clock starting at start
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+// Now returns the fake time
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel that receives the fake time once the clock has been advanced by d
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{deadline: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Sleep blocks until the clock has been advanced by d or ctx is done, and reports whether d
+// elapsed. Unlike a wait on After, a sleep cut short by ctx no longer counts as a waiter.
+func (c *FakeClock) Sleep(ctx context.Context, d time.Duration) bool {
+	ch := c.After(d)
+	select {
+	case <-ch:
+		return true
+	case <-ctx.Done():
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waiters = slices.DeleteFunc(c.waiters, func(waiter fakeWaiter) bool { return waiter.ch == ch })
+	return false
+}
+
+// Advance moves the clock forward by d, releasing the waits that end in the meantime
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	waiting := c.waiters[:0]
+	for _, waiter := range c.waiters {
+		if waiter.deadline.After(c.now) {
+			waiting = append(waiting, waiter)
+			continue
+		}
+		waiter.ch <- c.now
+	}
+	c.waiters = waiting
+}
+
+// Waiters returns the number of pending waits, so tests can advance the clock once a request is
+// blocked on a simulated delay. Sleeps cut short by their context, such as those of requests whose client
+// disconnected, are not counted.
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// AdvanceTime moves the server's fake clock forward by d. It panics unless Config.Clock is a
+// FakeClock.
+func (s *Server) AdvanceTime(d time.Duration) {
+	clock, ok := s.config.Clock.(*FakeClock)
+	if !ok {
+		panic("mockllm: AdvanceTime requires Config.Clock to be a *FakeClock")
+	}
+	clock.Advance(d)
+}
@@ -0,0 +1,127 @@
+package mockllm_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeClock(t *testing.T) {
+	clock := mockllm.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		Clock: clock,
+		OpenAI: []mockllm.OpenAIMock{{
+			Name:  "slow",
+			Match: mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+			Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{
+				Message: openai.ChatCompletionMessage{Content: "Hi"},
+			}}},
+			Fault: &mockllm.Fault{Type: mockllm.FaultStall, Duration: mockllm.Duration(time.Hour)},
+		}},
+		Anthropic: []mockllm.AnthropicMock{{
+			Name:     "hello",
+			Match:    mockllm.AnthropicRequestMatch{MatchType: mockllm.MatchTypeExact, Message: anthropicHelloRequest.Messages[0]},
+			Response: anthropic.Message{Content: []anthropic.ContentBlockUnion{{Type: "text", Text: "Hi!"}}},
+		}},
+		Batches: &mockllm.BatchConfig{ProcessingDelay: mockllm.Duration(24 * time.Hour)},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	done := make(chan openai.ChatCompletion)
+	go func() {
+		done <- postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+			Model:    "gpt-4o-mini",
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+		})
+	}()
+	require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, 5*time.Millisecond)
+	server.AdvanceTime(30 * time.Minute)
+	assert.Equal(t, 1, clock.Waiters(), "the stall lasts an hour")
+	server.AdvanceTime(30 * time.Minute)
+	completion := <-done
+	assert.Equal(t, "Hi", completion.Choices[0].Message.Content)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).Unix(), completion.Created)
+	requests := server.Requests()
+	require.Len(t, requests, 1)
+	assert.True(t, requests[0].Time.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)), "requests are logged on the same clock")
+
+	client := anthropic.NewClient(
+		anthropicoption.WithBaseURL(baseURL),
+		anthropicoption.WithAPIKey("test-key"),
+		anthropicoption.WithMaxRetries(0),
+	)
+	batch, err := client.Messages.Batches.New(t.Context(), anthropic.MessageBatchNewParams{
+		Requests: []anthropic.MessageBatchNewParamsRequest{{
+			CustomID: "hello",
+			Params: anthropic.MessageBatchNewParamsRequestParams{
+				Model:     anthropicHelloRequest.Model,
+				MaxTokens: anthropicHelloRequest.MaxTokens,
+				Messages:  anthropicHelloRequest.Messages,
+			},
+		}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, anthropic.MessageBatchProcessingStatusInProgress, batch.ProcessingStatus)
+
+	server.AdvanceTime(24 * time.Hour)
+	batch, err = client.Messages.Batches.Get(t.Context(), batch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, anthropic.MessageBatchProcessingStatusEnded, batch.ProcessingStatus)
+}
+
+func TestFakeClockRateLimit(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		Clock: mockllm.NewFakeClock(time.Now()),
+		Tenants: []mockllm.TenantConfig{{
+			Name:      "team-a",
+			APIKeys:   []string{"team-a-key"},
+			RateLimit: &mockllm.RateLimitConfig{Requests: 1},
+		}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	post := func() int {
+		resp := postJSON(t, baseURL+"/v1/chat/completions", openai.ChatCompletionNewParams{
+			Model:    "gpt-4o-mini",
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+		}, map[string]string{"Authorization": "Bearer team-a-key"})
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusNotFound, post())
+	assert.Equal(t, http.StatusTooManyRequests, post())
+	server.AdvanceTime(time.Minute)
+	assert.Equal(t, http.StatusNotFound, post())
+}
+
+func TestFakeClockDropsAbandonedSleeps(t *testing.T) {
+	clock := mockllm.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan bool)
+	go func() { done <- clock.Sleep(ctx, time.Hour) }()
+	require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	assert.False(t, <-done)
+	assert.Zero(t, clock.Waiters(), "an abandoned sleep is no longer pending")
+
+	go func() { done <- clock.Sleep(t.Context(), time.Hour) }()
+	require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, 5*time.Millisecond)
+	clock.Advance(time.Hour)
+	assert.True(t, <-done)
+}
+
+func TestAdvanceTimeRequiresFakeClock(t *testing.T) {
+	assert.Panics(t, func() { mockllm.NewServer(mockllm.WithConfig(mockllm.Config{})).AdvanceTime(time.Second) })
+}
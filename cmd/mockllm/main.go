@@ -0,0 +1,559 @@
+// Command mockllm provides tooling around mockllm configs.
+//
+//	mockllm serve --config mocks.json [--addr 0.0.0.0:8090] [--report report.xml] [--golden transcript.json] [--metrics-baseline metrics.json]
+//	mockllm match --config mocks.json --request req.json [--header "Name: value"] [--target openai] [--diff]
+//	mockllm record --target openai --request req.json --response resp.json [--name name]
+//	mockllm skeleton --spec openapi.yaml --schema CreateChatCompletionResponse
+//	mockllm import-har --har capture.har
+//	mockllm import-stubs --from wiremock|mockoon --stubs mappings
+//	mockllm migrate --config mocks.json [--write]
+//	mockllm drift --config mocks.json [--openai-spec openai.yaml] [--anthropic-spec anthropic.yaml]
+//	mockllm export (--url http://localhost:8090 [--query mock=name] | --log requests.json) --out dir [--format json|go] [--package fixtures]
+//	mockllm conformance [--url http://localhost:8090]
+//
+// serve runs the mock server with a config file until interrupted, then writes the report of
+// the expectations and assertions of the config, and compares the run against a golden transcript
+// and the baseline of its prompt metrics.
+// match explains which mock of a config would answer a request, and why the others would not.
+// record prints a mock config entry for a real request and response captured from a provider.
+// skeleton prints the smallest valid response of a schema in a provider's OpenAPI spec, given
+// as a path or URL.
+// import-har prints a config of recorded mocks for the LLM calls of a captured HTTP archive.
+// import-stubs prints a config converting the WireMock mappings, a file or a directory of them,
+// or the Mockoon environment stubbing LLM endpoints.
+// migrate upgrades a config file to the current version of the config format.
+// drift flags the mock responses of a config using fields or values that the providers' current
+// OpenAPI specs, given as paths or URLs, deprecate or no longer define.
+// export writes the requests a running server, or a saved dump of its /admin/requests, served
+// as fixture files for the test suites of other repos.
+// conformance drives the official OpenAI and Anthropic SDKs against a mock server serving
+// conformance.Config, by default one it starts, and reports where the mock diverges from them.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/kagent-dev/mockllm/conformance"
+	"github.com/kagent-dev/mockllm/openapi"
+)
+
+const usage = `Usage: mockllm <command> [flags]
+
+Commands:
+  serve       run the mock server with a config file
+  match       explain which mock of a config matches a request
+  record      print a mock config entry for a captured request and response
+  skeleton    print a skeleton response for a schema of an OpenAPI spec
+  import-har  print a config of recorded mocks for the LLM calls of a HAR file
+  import-stubs
+              print a config converting WireMock or Mockoon stubs of LLM endpoints
+  migrate     upgrade a config file to the current config format version
+  drift       flag mock responses drifting from the providers' OpenAPI specs
+  export      write the recorded requests as JSON or Go test fixtures
+  conformance check the mock against the official provider SDKs
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "serve":
+		err = serve(os.Args[2:])
+	case "match":
+		err = match(os.Args[2:])
+	case "record":
+		err = record(os.Args[2:])
+	case "skeleton":
+		err = skeleton(os.Args[2:])
+	case "import-har":
+		err = importHAR(os.Args[2:])
+	case "import-stubs":
+		err = importStubs(os.Args[2:])
+	case "migrate":
+		err = migrate(os.Args[2:])
+	case "drift":
+		err = drift(os.Args[2:])
+	case "export":
+		err = export(os.Args[2:])
+	case "conformance":
+		err = runConformance(os.Args[2:])
+	case "-h", "--help", "help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mockllm %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func serve(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	configPath := flags.String("config", "", "path of the JSON config file")
+	addr := flags.String("addr", "", "address to listen on, overrides listen_addr of the config")
+	reportPath := flags.String("report", "", "file to write a verification report to on exit, JUnit XML for .xml files and JSON otherwise")
+	goldenPath := flags.String("golden", "", "golden transcript to compare the requests of the run against on exit, written when missing")
+	baselinePath := flags.String("metrics-baseline", "", "prompt metrics baseline to compare the run against on exit, written when missing")
+	flags.Parse(args) //nolint:errcheck
+
+	if *configPath == "" {
+		return fmt.Errorf("--config is required")
+	}
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if *addr != "" {
+		config.ListenAddr = *addr
+	}
+	if *reportPath != "" {
+		config.Report = &mockllm.ReportConfig{Path: *reportPath}
+	}
+	if *goldenPath != "" {
+		if config.Golden == nil {
+			config.Golden = &mockllm.GoldenConfig{}
+		}
+		config.Golden.Path = *goldenPath
+	}
+	if *baselinePath != "" {
+		if config.Metrics == nil {
+			config.Metrics = &mockllm.MetricsConfig{}
+		}
+		config.Metrics.Baseline = *baselinePath
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	server := mockllm.NewServer(mockllm.WithConfig(config), mockllm.WithLogger(logger))
+	if _, err := server.Start(context.Background()); err != nil {
+		return err
+	}
+	return server.Run(context.Background())
+}
+
+func match(args []string) error {
+	flags := flag.NewFlagSet("match", flag.ExitOnError)
+	configPath := flags.String("config", "", "path of the config file")
+	requestPath := flags.String("request", "", "path of the request body JSON")
+	target := flags.String("target", "", "only explain the mocks of a provider: openai or anthropic")
+	showDiff := flags.Bool("diff", false, "print the diff of every mock that does not match")
+	header := http.Header{}
+	flags.Func("header", "request header as \"Name: value\", can be repeated", func(value string) error {
+		name, value, ok := strings.Cut(value, ":")
+		if !ok {
+			return fmt.Errorf("header %q is not of the form \"Name: value\"", name)
+		}
+		header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+		return nil
+	})
+	flags.Parse(args) //nolint:errcheck
+
+	if *configPath == "" || *requestPath == "" {
+		return fmt.Errorf("--config and --request are required")
+	}
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	request, err := os.ReadFile(*requestPath)
+	if err != nil {
+		return err
+	}
+	explanations, err := mockllm.NewServer(mockllm.WithConfig(config)).WhichMockMatches(request, header)
+	if err != nil {
+		return err
+	}
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(out, "PROVIDER\tMOCK\tRESULT\tREASON")
+	selected := false
+	for _, explanation := range explanations {
+		if *target != "" && explanation.Provider != *target {
+			continue
+		}
+		result := "no match"
+		switch {
+		case explanation.Selected:
+			result, selected = "selected", true
+		case explanation.Matched:
+			result = "match"
+		}
+		fmt.Fprintf(out, "%s\t%s\t%s\t%s\n", explanation.Provider, explanation.MockName, result, explanation.Reason)
+		if *showDiff && explanation.Diff != "" {
+			out.Flush() //nolint:errcheck
+			fmt.Print(indent(explanation.Diff))
+		}
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	if !selected {
+		return fmt.Errorf("no mock matches the request")
+	}
+	return nil
+}
+
+func record(args []string) error {
+	flags := flag.NewFlagSet("record", flag.ExitOnError)
+	target := flags.String("target", "openai", "provider the request was sent to: openai or anthropic")
+	requestPath := flags.String("request", "", "path of the request body JSON")
+	responsePath := flags.String("response", "", "path of the response body JSON")
+	name := flags.String("name", "recorded", "name of the mock")
+	flags.Parse(args) //nolint:errcheck
+
+	if *requestPath == "" || *responsePath == "" {
+		return fmt.Errorf("--request and --response are required")
+	}
+	request, err := os.ReadFile(*requestPath)
+	if err != nil {
+		return err
+	}
+	response, err := os.ReadFile(*responsePath)
+	if err != nil {
+		return err
+	}
+
+	var entry []byte
+	switch *target {
+	case "openai":
+		entry, err = mockllm.RecordOpenAIMock(*name, request, response)
+	case "anthropic":
+		entry, err = mockllm.RecordAnthropicMock(*name, request, response)
+	default:
+		return fmt.Errorf("unknown target %q, expected openai or anthropic", *target)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Println(string(entry))
+	return err
+}
+
+func skeleton(args []string) error {
+	flags := flag.NewFlagSet("skeleton", flag.ExitOnError)
+	specLocation := flags.String("spec", "", "path or URL of the OpenAPI spec, in YAML or JSON")
+	schema := flags.String("schema", "", "name of the component schema, e.g. CreateChatCompletionResponse")
+	flags.Parse(args) //nolint:errcheck
+
+	if *specLocation == "" || *schema == "" {
+		return fmt.Errorf("--spec and --schema are required")
+	}
+	data, err := readLocation(*specLocation)
+	if err != nil {
+		return err
+	}
+	spec, err := openapi.Parse(data)
+	if err != nil {
+		return err
+	}
+	value, err := spec.Skeleton(*schema)
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Println(string(out))
+	return err
+}
+
+func importHAR(args []string) error {
+	flags := flag.NewFlagSet("import-har", flag.ExitOnError)
+	harPath := flags.String("har", "", "path or URL of the HAR file")
+	flags.Parse(args) //nolint:errcheck
+
+	if *harPath == "" {
+		return fmt.Errorf("--har is required")
+	}
+	data, err := readLocation(*harPath)
+	if err != nil {
+		return err
+	}
+	config, skipped, err := mockllm.ImportHAR(data)
+	if err != nil {
+		return err
+	}
+	for _, reason := range skipped {
+		fmt.Fprintf(os.Stderr, "skipped %s\n", reason)
+	}
+	_, err = fmt.Println(string(config))
+	return err
+}
+
+func importStubs(args []string) error {
+	flags := flag.NewFlagSet("import-stubs", flag.ExitOnError)
+	from := flags.String("from", "", "tool the stubs were written for: wiremock or mockoon")
+	stubsPath := flags.String("stubs", "", "path of the WireMock mappings file or directory, or of the Mockoon environment")
+	flags.Parse(args) //nolint:errcheck
+
+	if *from == "" || *stubsPath == "" {
+		return fmt.Errorf("--from and --stubs are required")
+	}
+	var config []byte
+	var skipped []string
+	switch *from {
+	case "wiremock":
+		documents, err := readStubFiles(*stubsPath)
+		if err != nil {
+			return err
+		}
+		config, skipped, err = mockllm.ImportWireMock(documents...)
+		if err != nil {
+			return err
+		}
+	case "mockoon":
+		data, err := os.ReadFile(*stubsPath)
+		if err != nil {
+			return err
+		}
+		config, skipped, err = mockllm.ImportMockoon(data)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown stub format %q, expected wiremock or mockoon", *from)
+	}
+	for _, reason := range skipped {
+		fmt.Fprintf(os.Stderr, "skipped %s\n", reason)
+	}
+	_, err := fmt.Println(string(config))
+	return err
+}
+
+// readStubFiles reads a stub file, or the JSON files of a directory in name order
+func readStubFiles(stubsPath string) ([][]byte, error) {
+	info, err := os.Stat(stubsPath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		data, err := os.ReadFile(stubsPath)
+		return [][]byte{data}, err
+	}
+	paths, err := filepath.Glob(filepath.Join(stubsPath, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	documents := make([][]byte, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, data)
+	}
+	return documents, nil
+}
+
+func migrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	configPath := flags.String("config", "", "path of the JSON config file")
+	write := flags.Bool("write", false, "rewrite the file in place instead of printing the upgraded config")
+	flags.Parse(args) //nolint:errcheck
+
+	if *configPath == "" {
+		return fmt.Errorf("--config is required")
+	}
+	data, err := os.ReadFile(*configPath)
+	if err != nil {
+		return err
+	}
+	migrated, applied, err := mockllm.MigrateConfig(data)
+	if err != nil {
+		return err
+	}
+	for _, migration := range applied {
+		fmt.Fprintf(os.Stderr, "migrated %s\n", migration)
+	}
+	if *write {
+		return os.WriteFile(*configPath, migrated, 0o644)
+	}
+	_, err = os.Stdout.Write(migrated)
+	return err
+}
+
+func drift(args []string) error {
+	flags := flag.NewFlagSet("drift", flag.ExitOnError)
+	configPath := flags.String("config", "", "path of the config file")
+	openaiSpec := flags.String("openai-spec", "", "path or URL of OpenAI's OpenAPI spec")
+	openaiSchema := flags.String("openai-schema", mockllm.OpenAIResponseSchema, "schema of the OpenAI mock responses")
+	anthropicSpec := flags.String("anthropic-spec", "", "path or URL of Anthropic's OpenAPI spec")
+	anthropicSchema := flags.String("anthropic-schema", mockllm.AnthropicResponseSchema, "schema of the Anthropic mock responses")
+	flags.Parse(args) //nolint:errcheck
+
+	if *configPath == "" || (*openaiSpec == "" && *anthropicSpec == "") {
+		return fmt.Errorf("--config and at least one of --openai-spec or --anthropic-spec are required")
+	}
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	specs := mockllm.DriftSpecs{OpenAISchema: *openaiSchema, AnthropicSchema: *anthropicSchema}
+	for _, spec := range []struct {
+		location string
+		parsed   **openapi.Spec
+	}{{*openaiSpec, &specs.OpenAI}, {*anthropicSpec, &specs.Anthropic}} {
+		if spec.location == "" {
+			continue
+		}
+		data, err := readLocation(spec.location)
+		if err != nil {
+			return err
+		}
+		if *spec.parsed, err = openapi.Parse(data); err != nil {
+			return fmt.Errorf("%s: %w", spec.location, err)
+		}
+	}
+	drifts, err := mockllm.CheckSpecDrift(config, specs)
+	if err != nil {
+		return err
+	}
+	if len(drifts) == 0 {
+		fmt.Println("no mock response drifted from the specs")
+		return nil
+	}
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(out, "PROVIDER\tMOCK\tPATH\tKIND\tMESSAGE")
+	for _, drift := range drifts {
+		fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%s\n", drift.Provider, drift.MockName, drift.Path, drift.Kind, drift.Message)
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	return fmt.Errorf("%d mock response fields drifted from the specs", len(drifts))
+}
+
+func export(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	baseURL := flags.String("url", "", "base URL of a running mockllm server to export the request log of")
+	query := flags.String("query", "", "query parameters selecting the requests exported with --url, e.g. mock=name")
+	logPath := flags.String("log", "", "path of a saved /admin/requests response to export instead")
+	fixtureFormat := flags.String("format", string(mockllm.FixtureFormatJSON), "fixture format: json or go")
+	outDir := flags.String("out", "", "directory the fixture files are written to")
+	goPackage := flags.String("package", "fixtures", "package of the Go fixtures")
+	flags.Parse(args) //nolint:errcheck
+
+	if (*baseURL == "") == (*logPath == "") || *outDir == "" {
+		return fmt.Errorf("--out and one of --url or --log are required")
+	}
+	location := *logPath
+	if *baseURL != "" {
+		location = strings.TrimSuffix(*baseURL, "/") + "/admin/requests"
+		if *query != "" {
+			location += "?" + *query
+		}
+	}
+	data, err := readLocation(location)
+	if err != nil {
+		return err
+	}
+	var records []mockllm.RequestRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return fmt.Errorf("failed to parse request log: %w", err)
+	}
+	files, err := mockllm.ExportFixtures(records, mockllm.FixtureFormat(*fixtureFormat), *goPackage)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		return err
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(*outDir, name), content, 0o644); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("wrote %d fixture files to %s\n", len(files), *outDir)
+	return nil
+}
+
+// loadConfig loads a config file, resolving its includes within the file's directory
+func loadConfig(configPath string) (mockllm.Config, error) {
+	absPath, err := filepath.Abs(configPath)
+	if err != nil {
+		return mockllm.Config{}, err
+	}
+	return mockllm.LoadConfigFromFile(filepath.Base(absPath), os.DirFS(filepath.Dir(absPath)).(fs.ReadFileFS))
+}
+
+// indent prefixes every line of text with four spaces
+func indent(text string) string {
+	lines := strings.SplitAfter(strings.TrimSuffix(text, "\n"), "\n")
+	return "    " + strings.Join(lines, "    ") + "\n"
+}
+
+// readLocation reads a local file, or fetches an http(s) URL
+func readLocation(location string) ([]byte, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return os.ReadFile(location)
+	}
+	resp, err := http.Get(location) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", location, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func runConformance(args []string) error {
+	flags := flag.NewFlagSet("conformance", flag.ExitOnError)
+	baseURL := flags.String("url", "", "base URL of a running mockllm server serving the conformance mocks, by default one is started")
+	flags.Parse(args) //nolint:errcheck
+
+	ctx := context.Background()
+	if *baseURL == "" {
+		config := conformance.Config()
+		config.ListenAddr = "127.0.0.1:0"
+		server := mockllm.NewServer(mockllm.WithConfig(config))
+		url, err := server.Start(ctx)
+		if err != nil {
+			return err
+		}
+		defer server.Stop(ctx) //nolint:errcheck
+		*baseURL = url
+	}
+
+	results := conformance.Run(ctx, strings.TrimSuffix(*baseURL, "/"))
+	out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(out, "PROVIDER\tCHECK\tRESULT\tDIVERGENCE")
+	divergences := 0
+	for _, result := range results {
+		status := "pass"
+		if !result.Passed() {
+			status = "FAIL"
+			divergences++
+		}
+		fmt.Fprintf(out, "%s\t%s\t%s\t%s\n", result.Provider, result.Check, status, result.Divergence)
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	if divergences > 0 {
+		return fmt.Errorf("%d of %d checks diverged from the SDKs", divergences, len(results))
+	}
+	return nil
+}
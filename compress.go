@@ -0,0 +1,123 @@
+package mockllm
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+)
+
+// CompressionConfig controls compression of responses
+type CompressionConfig struct {
+	// Enabled compresses responses with gzip or deflate when the client's Accept-Encoding allows it
+	Enabled bool `json:"enabled,omitempty"`
+	// Force compresses every response with the given encoding (gzip or deflate), regardless of
+	// Accept-Encoding, to reproduce clients that mishandle compressed bodies or SSE streams
+	Force string `json:"force,omitempty"`
+}
+
+// compressionMiddleware compresses the responses of next according to the config
+func compressionMiddleware(config CompressionConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := config.Force
+		if encoding == "" && config.Enabled {
+			encoding = negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		}
+		if encoding != encodingGzip && encoding != encodingDeflate {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.Close() //nolint:errcheck
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header, preferring gzip
+func negotiateEncoding(acceptEncoding string) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.ReplaceAll(strings.TrimSpace(params), " ", "") == "q=0" {
+			continue
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	switch {
+	case accepted[encodingGzip]:
+		return encodingGzip
+	case accepted[encodingDeflate]:
+		return encodingDeflate
+	default:
+		return ""
+	}
+}
+
+// compressWriter compresses everything written to the underlying response writer. Responses
+// that already carry a Content-Encoding, such as pre-compressed raw mock bodies, pass through.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	writer      io.WriteCloser
+	wroteHeader bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+
+	header := cw.Header()
+	if header.Get("Content-Encoding") == "" && status != http.StatusNoContent && status != http.StatusNotModified {
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		header.Add("Vary", "Accept-Encoding")
+		if cw.encoding == encodingGzip {
+			cw.writer = gzip.NewWriter(cw.ResponseWriter)
+		} else {
+			cw.writer = zlib.NewWriter(cw.ResponseWriter)
+		}
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.writer == nil {
+		return cw.ResponseWriter.Write(b)
+	}
+	return cw.writer.Write(b)
+}
+
+// Flush flushes the compressor before the connection so streamed events reach the client
+func (cw *compressWriter) Flush() {
+	if flusher, ok := cw.writer.(interface{ Flush() error }); ok {
+		flusher.Flush() //nolint:errcheck
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the underlying response writer to http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close writes the compression trailer
+func (cw *compressWriter) Close() error {
+	if cw.writer == nil {
+		return nil
+	}
+	return cw.writer.Close()
+}
@@ -0,0 +1,60 @@
+package mockllm_test
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompression(t *testing.T) {
+	tests := []struct {
+		name             string
+		compression      mockllm.CompressionConfig
+		acceptEncoding   string
+		expectedEncoding string
+	}{
+		{name: "negotiated gzip", compression: mockllm.CompressionConfig{Enabled: true}, acceptEncoding: "deflate, gzip", expectedEncoding: "gzip"},
+		{name: "negotiated deflate", compression: mockllm.CompressionConfig{Enabled: true}, acceptEncoding: "gzip;q=0, deflate", expectedEncoding: "deflate"},
+		{name: "not accepted", compression: mockllm.CompressionConfig{Enabled: true}, acceptEncoding: "identity", expectedEncoding: ""},
+		{name: "forced", compression: mockllm.CompressionConfig{Force: "deflate"}, acceptEncoding: "identity", expectedEncoding: "deflate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{Compression: &tt.compression}))
+			baseURL, err := server.Start(t.Context())
+			require.NoError(t, err)
+			defer server.Stop(context.Background()) //nolint:errcheck
+
+			req, err := http.NewRequest(http.MethodGet, baseURL+"/health", nil)
+			require.NoError(t, err)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close() //nolint:errcheck
+
+			assert.Equal(t, tt.expectedEncoding, resp.Header.Get("Content-Encoding"))
+			var body io.Reader = resp.Body
+			switch tt.expectedEncoding {
+			case "gzip":
+				body, err = gzip.NewReader(resp.Body)
+				require.NoError(t, err)
+			case "deflate":
+				body, err = zlib.NewReader(resp.Body)
+				require.NoError(t, err)
+			}
+
+			var health map[string]any
+			require.NoError(t, json.NewDecoder(body).Decode(&health))
+			assert.Equal(t, "healthy", health["status"])
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package mockllm
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// defaultComputerTool is the name Anthropic's computer use tools are declared with
+const defaultComputerTool = "computer"
+
+// ComputerUseMatch matches a turn of a computer use agent loop, where the assistant calls the
+// computer tool with an action and the client answers with a tool result, often a screenshot
+type ComputerUseMatch struct {
+	// Tool is the name of the computer use tool. Defaults to "computer".
+	Tool string `json:"tool,omitempty"`
+	// Action requires the latest tool call of the conversation to be a call of the computer use
+	// tool with this action, e.g. "screenshot" or "left_click"
+	Action string `json:"action,omitempty"`
+	// Screenshot requires the last message to return a screenshot, i.e. a tool result with an image
+	Screenshot bool `json:"screenshot,omitempty"`
+}
+
+// mismatch returns why a request is not the computer use turn, or an empty string
+func (m *ComputerUseMatch) mismatch(request anthropic.MessageNewParams) string {
+	if m == nil {
+		return ""
+	}
+	if m.Action != "" {
+		tool := cmp.Or(m.Tool, defaultComputerTool)
+		call := anthropicLatestToolUse(request)
+		if call == nil || call.Name != tool {
+			return fmt.Sprintf("latest tool call is not a call of %q", tool)
+		}
+		var input struct {
+			Action string `json:"action"`
+		}
+		encoded, _ := json.Marshal(call.Input)
+		json.Unmarshal(encoded, &input) //nolint:errcheck
+		if input.Action != m.Action {
+			return fmt.Sprintf("latest %s action is %q, want %q", tool, input.Action, m.Action)
+		}
+	}
+	if m.Screenshot && !anthropicReturnsScreenshot(request) {
+		return "last message returns no screenshot"
+	}
+	return ""
+}
+
+// anthropicLatestToolUse returns the last tool call of the assistant in the request's
+// conversation, or nil when it called no tool
+func anthropicLatestToolUse(request anthropic.MessageNewParams) *anthropic.ToolUseBlockParam {
+	for i := len(request.Messages) - 1; i >= 0; i-- {
+		message := request.Messages[i]
+		if message.Role != anthropic.MessageParamRoleAssistant {
+			continue
+		}
+		for j := len(message.Content) - 1; j >= 0; j-- {
+			if block := message.Content[j]; block.OfToolUse != nil {
+				return block.OfToolUse
+			}
+		}
+	}
+	return nil
+}
+
+// anthropicReturnsScreenshot reports whether the last message of the request has a tool result
+// with an image
+func anthropicReturnsScreenshot(request anthropic.MessageNewParams) bool {
+	if len(request.Messages) == 0 {
+		return false
+	}
+	for _, block := range request.Messages[len(request.Messages)-1].Content {
+		if block.OfToolResult == nil {
+			continue
+		}
+		for _, content := range block.OfToolResult.Content {
+			if content.OfImage != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
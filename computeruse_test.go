@@ -0,0 +1,108 @@
+package mockllm_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/kagent-dev/mockllm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputerUseLoop(t *testing.T) {
+	computerAction := func(id, input string) anthropic.Message {
+		return anthropic.Message{Content: []anthropic.ContentBlockUnion{
+			{Type: "tool_use", ID: id, Name: "computer", Input: json.RawMessage(input)},
+		}}
+	}
+	anyUserMessage := anthropic.NewUserMessage(anthropic.NewTextBlock(""))
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{Anthropic: []mockllm.AnthropicMock{
+		{
+			Name: "click",
+			Match: mockllm.AnthropicRequestMatch{
+				MatchType:   mockllm.MatchTypeFields,
+				ComputerUse: &mockllm.ComputerUseMatch{Action: "screenshot", Screenshot: true},
+			},
+			Response: computerAction("toolu_2", `{"action": "left_click", "coordinate": [512, 384]}`),
+		},
+		{
+			Name: "done",
+			Match: mockllm.AnthropicRequestMatch{
+				MatchType:   mockllm.MatchTypeFields,
+				ComputerUse: &mockllm.ComputerUseMatch{Action: "left_click"},
+			},
+			Response: anthropic.Message{Content: []anthropic.ContentBlockUnion{{Type: "text", Text: "The browser is open."}}},
+		},
+		{
+			Name:     "screenshot",
+			Match:    mockllm.AnthropicRequestMatch{MatchType: mockllm.MatchTypeUserContains, Message: anyUserMessage},
+			Response: computerAction("toolu_1", `{"action": "screenshot"}`),
+		},
+	}}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+	client := anthropic.NewClient(anthropicoption.WithBaseURL(baseURL), anthropicoption.WithAPIKey("test-key"))
+
+	screenshot := anthropic.NewImageBlockBase64("image/png", "iVBORw0KGgo=")
+	request := anthropic.MessageNewParams{
+		Model:     "claude-sonnet-4-0",
+		MaxTokens: 1024,
+		Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("Open the browser"))},
+	}
+	var actions []string
+	for range 5 {
+		message, err := client.Messages.New(t.Context(), request)
+		require.NoError(t, err)
+		if message.StopReason != anthropic.StopReasonToolUse {
+			assert.Equal(t, "The browser is open.", message.Content[0].Text)
+			break
+		}
+		call := message.Content[0]
+		var input struct {
+			Action string `json:"action"`
+		}
+		require.NoError(t, json.Unmarshal(call.Input, &input))
+		actions = append(actions, input.Action)
+
+		result := anthropic.ToolResultBlockParam{ToolUseID: call.ID}
+		if input.Action == "screenshot" {
+			result.Content = []anthropic.ToolResultBlockParamContentUnion{{OfImage: screenshot.OfImage}}
+		} else {
+			result.Content = []anthropic.ToolResultBlockParamContentUnion{{OfText: &anthropic.TextBlockParam{Text: "clicked"}}}
+		}
+		request.Messages = append(request.Messages, message.ToParam(),
+			anthropic.NewUserMessage(anthropic.ContentBlockParamUnion{OfToolResult: &result}))
+	}
+	assert.Equal(t, []string{"screenshot", "left_click"}, actions)
+
+	var names []string
+	for _, record := range server.Requests() {
+		names = append(names, record.MockName)
+	}
+	assert.Equal(t, []string{"screenshot", "click", "done"}, names)
+
+	// A screenshot action answered without an image is not the click turn
+	body, err := json.Marshal(map[string]any{
+		"model":      "claude-sonnet-4-0",
+		"max_tokens": 1024,
+		"messages": []any{
+			map[string]any{"role": "user", "content": "Open the browser"},
+			map[string]any{"role": "assistant", "content": []any{map[string]any{
+				"type": "tool_use", "id": "toolu_1", "name": "computer", "input": map[string]any{"action": "screenshot"},
+			}}},
+			map[string]any{"role": "user", "content": []any{map[string]any{
+				"type": "tool_result", "tool_use_id": "toolu_1", "content": "no display",
+			}}},
+		},
+	})
+	require.NoError(t, err)
+	explanations, err := server.WhichMockMatches(body, nil)
+	require.NoError(t, err)
+	require.Len(t, explanations, 3)
+	assert.Equal(t, "last message returns no screenshot", explanations[0].Reason)
+	assert.Equal(t, `latest computer action is "screenshot", want "left_click"`, explanations[1].Reason)
+}
@@ -0,0 +1,104 @@
+package mockllm
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// ConcurrencyConfig limits how many provider requests are handled at once, to emulate provider
+// capacity limits when testing client side concurrency gates
+type ConcurrencyConfig struct {
+	// MaxConcurrent is the number of requests handled at once
+	MaxConcurrent int `json:"max_concurrent"`
+	// MaxQueued is the number of requests that wait for a free slot once MaxConcurrent is reached,
+	// further requests are rejected immediately
+	MaxQueued int `json:"max_queued,omitempty"`
+	// QueueTimeout is how long a queued request waits before it is rejected, queued requests wait
+	// until their client gives up when unset
+	QueueTimeout Duration `json:"queue_timeout,omitempty"`
+	// Status is the status code of rejected requests, 503 when unset
+	Status int `json:"status,omitempty"`
+}
+
+// concurrencyLimiter hands out a fixed number of slots and queues a bounded number of waiters
+type concurrencyLimiter struct {
+	config  ConcurrencyConfig
+	clock   Clock
+	log     *RequestLog
+	slots   chan struct{}
+	waiting chan struct{}
+}
+
+func newConcurrencyLimiter(config ConcurrencyConfig, clock Clock, log *RequestLog) *concurrencyLimiter {
+	return &concurrencyLimiter{
+		config:  config,
+		clock:   clock,
+		log:     log,
+		slots:   make(chan struct{}, config.MaxConcurrent),
+		waiting: make(chan struct{}, config.MaxQueued),
+	}
+}
+
+// acquire takes a slot, queueing for one if allowed, and reports whether a slot was taken
+func (l *concurrencyLimiter) acquire(ctx context.Context) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	select {
+	case l.waiting <- struct{}{}:
+		defer func() { <-l.waiting }()
+	default:
+		return false
+	}
+
+	// The queue timeout sleeps on a context of its own, so it stops counting as a waiter of a
+	// fake clock once the request leaves the queue
+	var timeout chan struct{}
+	if l.config.QueueTimeout > 0 {
+		timeoutCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		timeout = make(chan struct{})
+		go func() {
+			if l.clock.Sleep(timeoutCtx, time.Duration(l.config.QueueTimeout)) {
+				close(timeout)
+			}
+		}()
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timeout:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release frees a slot taken by acquire
+func (l *concurrencyLimiter) release() {
+	<-l.slots
+}
+
+// limit wraps the handler of a provider so it only runs once a slot is available, rejecting the
+// request with the provider's error envelope otherwise. Rejected requests are logged as overloaded.
+func (l *concurrencyLimiter) limit(provider Provider, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !l.acquire(r.Context()) {
+			status := l.config.Status
+			if status == 0 {
+				status = http.StatusServiceUnavailable
+			}
+			record := l.log.newRecord(r, builtinProviderName(provider), nil)
+			record.Status, record.Error = status, "overloaded"
+			l.log.Add(&record)
+			writeProviderError(w, provider, status, "The server is currently overloaded, please try again later.")
+			return
+		}
+		defer l.release()
+		next(w, r)
+	}
+}
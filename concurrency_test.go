@@ -0,0 +1,131 @@
+package mockllm_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{{
+			Name: "slow",
+			Match: mockllm.OpenAIRequestMatch{
+				MatchType: mockllm.MatchTypeContains,
+				Message:   openaiUserMessage("Hello"),
+			},
+			Response: helloCompletion,
+			Fault:    &mockllm.Fault{Type: mockllm.FaultStall, Duration: mockllm.Duration(300 * time.Millisecond)},
+		}},
+		Concurrency: &mockllm.ConcurrencyConfig{MaxConcurrent: 1, MaxQueued: 1},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	// The first request takes the only slot, the second waits in the queue and the third is rejected
+	statuses := make([]int, 3)
+	var wg sync.WaitGroup
+	for i := range statuses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := postJSON(t, baseURL+"/v1/chat/completions", helloParams, openaiHeaders)
+			statuses[i] = resp.StatusCode
+			if resp.StatusCode == http.StatusServiceUnavailable {
+				var body struct {
+					Error struct {
+						Type string `json:"type"`
+					} `json:"error"`
+				}
+				assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+				assert.Equal(t, "server_error", body.Error.Type)
+			}
+		}()
+		time.Sleep(50 * time.Millisecond)
+	}
+	wg.Wait()
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusServiceUnavailable}, statuses)
+}
+
+func TestConcurrencyQueueTimeout(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{{
+			Name: "slow",
+			Match: mockllm.OpenAIRequestMatch{
+				MatchType: mockllm.MatchTypeContains,
+				Message:   openaiUserMessage("Hello"),
+			},
+			Response: helloCompletion,
+			Fault:    &mockllm.Fault{Type: mockllm.FaultStall, Duration: mockllm.Duration(500 * time.Millisecond)},
+		}},
+		Concurrency: &mockllm.ConcurrencyConfig{
+			MaxConcurrent: 1,
+			MaxQueued:     1,
+			QueueTimeout:  mockllm.Duration(100 * time.Millisecond),
+			Status:        http.StatusTooManyRequests,
+		},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		postJSON(t, baseURL+"/v1/chat/completions", helloParams, openaiHeaders)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	resp := postJSON(t, baseURL+"/v1/chat/completions", helloParams, openaiHeaders)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	<-done
+
+	// The rejected request is logged, ahead of the one that held the slot
+	records := server.Requests()
+	require.Len(t, records, 2)
+	assert.Equal(t, "openai", records[0].Provider)
+	assert.Equal(t, http.StatusTooManyRequests, records[0].Status)
+	assert.Equal(t, "overloaded", records[0].Error)
+	assert.Equal(t, http.StatusOK, records[1].Status)
+}
+
+func TestMaxConcurrencyPerMock(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{{
+			Name: "slow",
+			Match: mockllm.OpenAIRequestMatch{
+				MatchType: mockllm.MatchTypeContains,
+				Message:   openaiUserMessage("Hello"),
+			},
+			Response: helloCompletion,
+			Fault:    &mockllm.Fault{Type: mockllm.FaultStall, Duration: mockllm.Duration(200 * time.Millisecond)},
+		}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			postJSON(t, baseURL+"/v1/chat/completions", helloParams, openaiHeaders)
+		}()
+	}
+	wg.Wait()
+	postJSON(t, baseURL+"/v1/chat/completions", helloParams, openaiHeaders)
+
+	assert.Equal(t, 3, server.MaxConcurrency("slow"))
+	assert.Equal(t, 0, server.MaxConcurrency("other"))
+	assert.Equal(t, 3, server.Mocks()[0].MaxConcurrency)
+}
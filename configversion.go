@@ -0,0 +1,94 @@
+package mockllm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// CurrentConfigVersion is the version of the config format this release reads and writes
+const CurrentConfigVersion = 1
+
+// configMigration upgrades config documents of a version to the next one
+type configMigration struct {
+	// Description tells users what the migration changes
+	Description string
+	Migrate     func(document map[string]any) error
+}
+
+// configMigrations upgrade config documents from version 1, the migration at index i upgrading
+// version i+1. A breaking change to the format of matchers or responses bumps
+// CurrentConfigVersion and appends the migration upgrading the previous version, so that older
+// configs keep loading as they were meant to instead of being silently misread.
+var configMigrations []configMigration
+
+// migrateConfigDocument upgrades a decoded config document to CurrentConfigVersion in place, and
+// returns the descriptions of the migrations applied. Documents of versions newer than this
+// release understands are rejected.
+func migrateConfigDocument(document map[string]any) ([]string, error) {
+	version := 1
+	if value, ok := document["version"]; ok {
+		number, ok := value.(json.Number)
+		parsed, err := number.Int64()
+		if !ok || err != nil || parsed < 1 {
+			return nil, fmt.Errorf("config version must be a positive integer, got %v", value)
+		}
+		version = int(parsed)
+	}
+	if version > CurrentConfigVersion {
+		return nil, fmt.Errorf("config version %d is newer than the latest version this mockllm supports, %d: upgrade mockllm",
+			version, CurrentConfigVersion)
+	}
+
+	var applied []string
+	for ; version < CurrentConfigVersion; version++ {
+		migration := configMigrations[version-1]
+		if err := migration.Migrate(document); err != nil {
+			return nil, fmt.Errorf("failed to migrate config from version %d: %w", version, err)
+		}
+		applied = append(applied, fmt.Sprintf("version %d to %d: %s", version, version+1, migration.Description))
+	}
+	document["version"] = json.Number(fmt.Sprint(CurrentConfigVersion))
+	return applied, nil
+}
+
+// MigrateConfig upgrades a JSON config file to CurrentConfigVersion, returning the upgraded file
+// and the descriptions of the migrations applied. Included files are migrated on their own. A file
+// that only lacks its version gets it added, and is otherwise kept as written; files that migrations
+// changed are re-encoded, with their members sorted.
+func MigrateConfig(data []byte) ([]byte, []string, error) {
+	var document map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse config JSON: %w", err)
+	}
+	_, versioned := document["version"]
+	applied, err := migrateConfigDocument(document)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case len(applied) > 0:
+		out, err := json.MarshalIndent(document, "", "  ")
+		if err != nil {
+			return nil, nil, err
+		}
+		return append(out, '\n'), applied, nil
+	case versioned:
+		return data, nil, nil
+	}
+	// Add the version as the first member, indented like the next one
+	open := bytes.IndexByte(data, '{')
+	rest := data[open+1:]
+	indent := rest[:len(rest)-len(bytes.TrimLeft(rest, " \t\r\n"))]
+	if len(bytes.TrimSpace(rest)) == 1 {
+		indent = []byte("")
+	}
+	versionMember := fmt.Sprintf(`%s"version": %d`, indent, CurrentConfigVersion)
+	if len(bytes.TrimSpace(rest)) > 1 {
+		versionMember += ","
+	}
+	return append(append(append([]byte{}, data[:open+1]...), versionMember...), rest...), nil, nil
+}
@@ -0,0 +1,52 @@
+package mockllm_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigVersion(t *testing.T) {
+	load := func(files fstest.MapFS) (mockllm.Config, error) {
+		return mockllm.LoadConfigFromFile("mocks.json", files)
+	}
+
+	config, err := load(fstest.MapFS{"mocks.json": {Data: []byte(`{"listen_addr": "127.0.0.1:0"}`)}})
+	require.NoError(t, err)
+	assert.Equal(t, mockllm.CurrentConfigVersion, config.Version, "unversioned configs are of version 1")
+
+	_, err = load(fstest.MapFS{"mocks.json": {Data: []byte(`{"version": 99}`)}})
+	assert.ErrorContains(t, err, "mocks.json: config version 99 is newer than the latest version this mockllm supports")
+
+	_, err = load(fstest.MapFS{"mocks.json": {Data: []byte(`{"version": "1"}`)}})
+	assert.ErrorContains(t, err, "config version must be a positive integer")
+
+	_, err = load(fstest.MapFS{
+		"mocks.json":     {Data: []byte(`{"version": 1, "include": ["more/*.json"]}`)},
+		"more/next.json": {Data: []byte(`{"version": 2}`)},
+	})
+	assert.ErrorContains(t, err, "more/next.json: config version 2 is newer", "included files are checked too")
+}
+
+func TestMigrateConfig(t *testing.T) {
+	migrated, applied, err := mockllm.MigrateConfig([]byte("{\n  \"openai\": [],\n  \"listen_addr\": \"127.0.0.1:0\"\n}\n"))
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+	assert.Equal(t, "{\n  \"version\": 1,\n  \"openai\": [],\n  \"listen_addr\": \"127.0.0.1:0\"\n}\n", string(migrated),
+		"the version is added, the rest kept as written")
+
+	migrated, _, err = mockllm.MigrateConfig([]byte(`{}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"version": 1}`, string(migrated))
+
+	current := []byte(`{"version": 1, "openai": []}`)
+	migrated, _, err = mockllm.MigrateConfig(current)
+	require.NoError(t, err)
+	assert.Equal(t, current, migrated)
+
+	_, _, err = mockllm.MigrateConfig([]byte(`{"version": 3}`))
+	assert.ErrorContains(t, err, "config version 3 is newer")
+}
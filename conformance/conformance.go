@@ -0,0 +1,432 @@
+// Package conformance runs the official OpenAI and Anthropic Go SDKs against a mock server for a
+// battery of operations, non-streaming, streaming, tool calls and errors, and reports where the
+// mock diverges from what the SDKs expect of the real APIs. The server must serve the mocks of
+// Config.
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	openaioption "github.com/openai/openai-go/option"
+)
+
+const (
+	helloPrompt   = "conformance: say hello"
+	toolPrompt    = "conformance: what is the weather in Paris?"
+	unknownPrompt = "conformance: nothing matches this"
+	helloReply    = "Hello from mockllm, the conformance suite is running."
+	toolName      = "get_weather"
+	toolArguments = `{"city":"Paris"}`
+	openaiModel   = "gpt-4o"
+	claudeModel   = "claude-sonnet-4-5"
+
+	providerOpenAI    = "openai"
+	providerAnthropic = "anthropic"
+)
+
+// Result is the outcome of a check, with the divergence it found if any
+type Result struct {
+	Provider string `json:"provider"`
+	Check    string `json:"check"`
+	// Divergence describes how the response differs from what the SDK expects, empty when it conforms
+	Divergence string `json:"divergence,omitempty"`
+}
+
+// Passed reports whether the mock conformed
+func (r Result) Passed() bool {
+	return r.Divergence == ""
+}
+
+// Config returns the mocks the checks expect the server to serve
+func Config() mockllm.Config {
+	weatherTool := openai.ChatCompletionMessageToolCall{
+		ID:       "call_conformance",
+		Type:     "function",
+		Function: openai.ChatCompletionMessageToolCallFunction{Name: toolName, Arguments: toolArguments},
+	}
+	return mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{
+			{
+				Name: "conformance-hello",
+				Match: mockllm.OpenAIRequestMatch{
+					MatchType: mockllm.MatchTypeContains,
+					Message:   userMessage(helloPrompt),
+				},
+				Response: openai.ChatCompletion{
+					Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: helloReply}}},
+					Usage:   openai.CompletionUsage{PromptTokens: 6, CompletionTokens: 10, TotalTokens: 16},
+				},
+			},
+			{
+				Name: "conformance-tool",
+				Match: mockllm.OpenAIRequestMatch{
+					MatchType: mockllm.MatchTypeContains,
+					Message:   userMessage(toolPrompt),
+				},
+				Response: openai.ChatCompletion{
+					Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{
+						ToolCalls: []openai.ChatCompletionMessageToolCall{weatherTool},
+					}}},
+					Usage: openai.CompletionUsage{PromptTokens: 20, CompletionTokens: 8, TotalTokens: 28},
+				},
+			},
+		},
+		Anthropic: []mockllm.AnthropicMock{
+			{
+				Name: "conformance-hello",
+				Match: mockllm.AnthropicRequestMatch{
+					MatchType: mockllm.MatchTypeContains,
+					Message:   anthropic.NewUserMessage(anthropic.NewTextBlock(helloPrompt)),
+				},
+				Response: anthropic.Message{
+					Content: []anthropic.ContentBlockUnion{{Type: "text", Text: helloReply}},
+					Usage:   anthropic.Usage{InputTokens: 6, OutputTokens: 10},
+				},
+			},
+			{
+				Name: "conformance-tool",
+				Match: mockllm.AnthropicRequestMatch{
+					MatchType: mockllm.MatchTypeContains,
+					Message:   anthropic.NewUserMessage(anthropic.NewTextBlock(toolPrompt)),
+				},
+				Response: anthropic.Message{
+					Content: []anthropic.ContentBlockUnion{{
+						Type:  "tool_use",
+						ID:    "toolu_conformance",
+						Name:  toolName,
+						Input: json.RawMessage(toolArguments),
+					}},
+					Usage: anthropic.Usage{InputTokens: 20, OutputTokens: 8},
+				},
+			},
+		},
+	}
+}
+
+// userMessage is a user message as mocks match it, with its role set
+func userMessage(content string) openai.ChatCompletionMessageParamUnion {
+	return openai.ChatCompletionMessageParamUnion{OfUser: &openai.ChatCompletionUserMessageParam{
+		Role:    "user",
+		Content: openai.ChatCompletionUserMessageParamContentUnion{OfString: openai.String(content)},
+	}}
+}
+
+// check is a conformance check, returning the divergence it found
+type check struct {
+	provider string
+	name     string
+	run      func(ctx context.Context) error
+}
+
+// Run runs every check against the server at baseURL, e.g. http://localhost:8090, in order
+func Run(ctx context.Context, baseURL string) []Result {
+	openaiClient := openai.NewClient(
+		openaioption.WithBaseURL(baseURL+"/v1"),
+		openaioption.WithAPIKey("conformance"),
+		openaioption.WithMaxRetries(0),
+	)
+	anthropicClient := anthropic.NewClient(
+		anthropicoption.WithBaseURL(baseURL),
+		anthropicoption.WithAPIKey("conformance"),
+		anthropicoption.WithMaxRetries(0),
+	)
+
+	var results []Result
+	for _, check := range append(openaiChecks(openaiClient), anthropicChecks(anthropicClient)...) {
+		result := Result{Provider: check.provider, Check: check.name}
+		if err := check.run(ctx); err != nil {
+			result.Divergence = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func openaiChecks(client openai.Client) []check {
+	request := func(prompt string) openai.ChatCompletionNewParams {
+		return openai.ChatCompletionNewParams{
+			Model:    openaiModel,
+			Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage(prompt)},
+			Tools: []openai.ChatCompletionToolParam{{Function: openai.FunctionDefinitionParam{
+				Name:       toolName,
+				Parameters: openai.FunctionParameters{"type": "object", "properties": map[string]any{"city": map[string]any{"type": "string"}}},
+			}}},
+		}
+	}
+	streamed := func(ctx context.Context, prompt string) (openai.ChatCompletion, error) {
+		params := request(prompt)
+		params.StreamOptions = openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)}
+		stream := client.Chat.Completions.NewStreaming(ctx, params)
+		defer stream.Close() //nolint:errcheck
+		var accumulator openai.ChatCompletionAccumulator
+		for stream.Next() {
+			if !accumulator.AddChunk(stream.Current()) {
+				return openai.ChatCompletion{}, fmt.Errorf("chunk %q does not continue the stream", stream.Current().RawJSON())
+			}
+		}
+		return accumulator.ChatCompletion, stream.Err()
+	}
+
+	return []check{
+		{providerOpenAI, "chat completion", func(ctx context.Context) error {
+			completion, err := client.Chat.Completions.New(ctx, request(helloPrompt))
+			if err != nil {
+				return err
+			}
+			return checkOpenAIReply(*completion)
+		}},
+		{providerOpenAI, "streamed chat completion", func(ctx context.Context) error {
+			completion, err := streamed(ctx, helloPrompt)
+			if err != nil {
+				return err
+			}
+			return checkOpenAIReply(completion)
+		}},
+		{providerOpenAI, "tool call", func(ctx context.Context) error {
+			completion, err := client.Chat.Completions.New(ctx, request(toolPrompt))
+			if err != nil {
+				return err
+			}
+			return checkOpenAIToolCall(*completion)
+		}},
+		{providerOpenAI, "streamed tool call", func(ctx context.Context) error {
+			completion, err := streamed(ctx, toolPrompt)
+			if err != nil {
+				return err
+			}
+			return checkOpenAIToolCall(completion)
+		}},
+		{providerOpenAI, "unmatched request error", func(ctx context.Context) error {
+			_, err := client.Chat.Completions.New(ctx, request(unknownPrompt))
+			return checkOpenAIError(err, http.StatusNotFound)
+		}},
+		{providerOpenAI, "rate limit error", func(ctx context.Context) error {
+			_, err := client.Chat.Completions.New(ctx, request(helloPrompt),
+				openaioption.WithHeader(mockllm.ForceErrorHeader, "429"))
+			return checkOpenAIError(err, http.StatusTooManyRequests)
+		}},
+		{providerOpenAI, "authentication error", func(ctx context.Context) error {
+			_, err := client.Chat.Completions.New(ctx, request(helloPrompt), openaioption.WithHeaderDel("Authorization"))
+			return checkOpenAIError(err, http.StatusUnauthorized)
+		}},
+	}
+}
+
+func checkOpenAIReply(completion openai.ChatCompletion) error {
+	switch {
+	case completion.ID == "":
+		return errors.New("the completion has no id")
+	case string(completion.Object) != "chat.completion":
+		return fmt.Errorf("the object is %q, want chat.completion", completion.Object)
+	case completion.Model != openaiModel:
+		return fmt.Errorf("the model is %q, want %q", completion.Model, openaiModel)
+	case len(completion.Choices) != 1:
+		return fmt.Errorf("got %d choices, want 1", len(completion.Choices))
+	case completion.Choices[0].Message.Role != "assistant":
+		return fmt.Errorf("the role is %q, want assistant", completion.Choices[0].Message.Role)
+	case completion.Choices[0].Message.Content != helloReply:
+		return fmt.Errorf("the content is %q, want %q", completion.Choices[0].Message.Content, helloReply)
+	case completion.Choices[0].FinishReason != "stop":
+		return fmt.Errorf("the finish reason is %q, want stop", completion.Choices[0].FinishReason)
+	case completion.Usage.TotalTokens != completion.Usage.PromptTokens+completion.Usage.CompletionTokens ||
+		completion.Usage.TotalTokens == 0:
+		return fmt.Errorf("the usage %s doesn't add up", completion.Usage.RawJSON())
+	}
+	return nil
+}
+
+func checkOpenAIToolCall(completion openai.ChatCompletion) error {
+	if len(completion.Choices) != 1 {
+		return fmt.Errorf("got %d choices, want 1", len(completion.Choices))
+	}
+	choice := completion.Choices[0]
+	if choice.FinishReason != "tool_calls" {
+		return fmt.Errorf("the finish reason is %q, want tool_calls", choice.FinishReason)
+	}
+	if len(choice.Message.ToolCalls) != 1 {
+		return fmt.Errorf("got %d tool calls, want 1", len(choice.Message.ToolCalls))
+	}
+	toolCall := choice.Message.ToolCalls[0]
+	switch {
+	case toolCall.ID == "":
+		return errors.New("the tool call has no id")
+	case toolCall.Function.Name != toolName:
+		return fmt.Errorf("the tool called is %q, want %q", toolCall.Function.Name, toolName)
+	}
+	return checkArguments(toolCall.Function.Arguments)
+}
+
+func checkOpenAIError(err error, status int) error {
+	var apiErr *openai.Error
+	switch {
+	case err == nil:
+		return fmt.Errorf("the request succeeded, want a %d error", status)
+	case !errors.As(err, &apiErr):
+		return fmt.Errorf("the SDK did not decode an API error: %w", err)
+	case apiErr.StatusCode != status:
+		return fmt.Errorf("the status is %d, want %d", apiErr.StatusCode, status)
+	case apiErr.Message == "":
+		return errors.New("the error has no message")
+	}
+	return nil
+}
+
+func anthropicChecks(client anthropic.Client) []check {
+	request := func(prompt string) anthropic.MessageNewParams {
+		return anthropic.MessageNewParams{
+			Model:     claudeModel,
+			MaxTokens: 1024,
+			Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock(prompt))},
+			Tools: []anthropic.ToolUnionParam{{OfTool: &anthropic.ToolParam{
+				Name:        toolName,
+				InputSchema: anthropic.ToolInputSchemaParam{Properties: map[string]any{"city": map[string]any{"type": "string"}}},
+			}}},
+		}
+	}
+	streamed := func(ctx context.Context, prompt string) (anthropic.Message, error) {
+		stream := client.Messages.NewStreaming(ctx, request(prompt))
+		defer stream.Close() //nolint:errcheck
+		var message anthropic.Message
+		for stream.Next() {
+			if err := message.Accumulate(stream.Current()); err != nil {
+				return message, err
+			}
+		}
+		return message, stream.Err()
+	}
+
+	return []check{
+		{providerAnthropic, "message", func(ctx context.Context) error {
+			message, err := client.Messages.New(ctx, request(helloPrompt))
+			if err != nil {
+				return err
+			}
+			return checkAnthropicReply(*message)
+		}},
+		{providerAnthropic, "streamed message", func(ctx context.Context) error {
+			message, err := streamed(ctx, helloPrompt)
+			if err != nil {
+				return err
+			}
+			return checkAnthropicReply(message)
+		}},
+		{providerAnthropic, "tool use", func(ctx context.Context) error {
+			message, err := client.Messages.New(ctx, request(toolPrompt))
+			if err != nil {
+				return err
+			}
+			return checkAnthropicToolUse(*message)
+		}},
+		{providerAnthropic, "streamed tool use", func(ctx context.Context) error {
+			message, err := streamed(ctx, toolPrompt)
+			if err != nil {
+				return err
+			}
+			return checkAnthropicToolUse(message)
+		}},
+		{providerAnthropic, "unmatched request error", func(ctx context.Context) error {
+			_, err := client.Messages.New(ctx, request(unknownPrompt))
+			return checkAnthropicError(err, http.StatusNotFound, "not_found_error")
+		}},
+		{providerAnthropic, "overloaded error", func(ctx context.Context) error {
+			_, err := client.Messages.New(ctx, request(helloPrompt), anthropicoption.WithHeader(mockllm.ForceErrorHeader, "529"))
+			return checkAnthropicError(err, 529, "overloaded_error")
+		}},
+		{providerAnthropic, "authentication error", func(ctx context.Context) error {
+			_, err := client.Messages.New(ctx, request(helloPrompt), anthropicoption.WithHeaderDel("x-api-key"))
+			return checkAnthropicError(err, http.StatusUnauthorized, "authentication_error")
+		}},
+	}
+}
+
+func checkAnthropicReply(message anthropic.Message) error {
+	switch {
+	case message.ID == "":
+		return errors.New("the message has no id")
+	case message.Type != "message":
+		return fmt.Errorf("the type is %q, want message", message.Type)
+	case message.Role != "assistant":
+		return fmt.Errorf("the role is %q, want assistant", message.Role)
+	case string(message.Model) != claudeModel:
+		return fmt.Errorf("the model is %q, want %q", message.Model, claudeModel)
+	case len(message.Content) != 1 || message.Content[0].Type != "text":
+		return fmt.Errorf("the content %s is not a single text block", message.RawJSON())
+	case message.Content[0].Text != helloReply:
+		return fmt.Errorf("the text is %q, want %q", message.Content[0].Text, helloReply)
+	case message.StopReason != anthropic.StopReasonEndTurn:
+		return fmt.Errorf("the stop reason is %q, want end_turn", message.StopReason)
+	case message.Usage.InputTokens == 0 || message.Usage.OutputTokens == 0:
+		return fmt.Errorf("the usage %s is missing tokens", message.Usage.RawJSON())
+	}
+	return nil
+}
+
+func checkAnthropicToolUse(message anthropic.Message) error {
+	if message.StopReason != anthropic.StopReasonToolUse {
+		return fmt.Errorf("the stop reason is %q, want tool_use", message.StopReason)
+	}
+	if len(message.Content) != 1 || message.Content[0].Type != "tool_use" {
+		return fmt.Errorf("the content %s is not a single tool_use block", message.RawJSON())
+	}
+	block := message.Content[0]
+	switch {
+	case block.ID == "":
+		return errors.New("the tool use has no id")
+	case block.Name != toolName:
+		return fmt.Errorf("the tool used is %q, want %q", block.Name, toolName)
+	}
+	return checkArguments(string(block.Input))
+}
+
+func checkAnthropicError(err error, status int, errorType string) error {
+	var apiErr *anthropic.Error
+	switch {
+	case err == nil:
+		return fmt.Errorf("the request succeeded, want a %d error", status)
+	case !errors.As(err, &apiErr):
+		return fmt.Errorf("the SDK did not decode an API error: %w", err)
+	case apiErr.StatusCode != status:
+		return fmt.Errorf("the status is %d, want %d", apiErr.StatusCode, status)
+	}
+	var body struct {
+		Type  string `json:"type"`
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(apiErr.RawJSON()), &body); err != nil {
+		return fmt.Errorf("the error body is not JSON: %w", err)
+	}
+	switch {
+	case body.Type != "error":
+		return fmt.Errorf("the type of the error body is %q, want error", body.Type)
+	case body.Error.Type != errorType:
+		return fmt.Errorf("the error type is %q, want %s", body.Error.Type, errorType)
+	case body.Error.Message == "":
+		return errors.New("the error has no message")
+	}
+	return nil
+}
+
+// checkArguments checks that tool call arguments are the JSON object the mock sent
+func checkArguments(arguments string) error {
+	var decoded, want map[string]any
+	if err := json.Unmarshal([]byte(arguments), &decoded); err != nil {
+		return fmt.Errorf("the arguments %q are not a JSON object: %w", arguments, err)
+	}
+	json.Unmarshal([]byte(toolArguments), &want) //nolint:errcheck
+	if !reflect.DeepEqual(decoded, want) {
+		return fmt.Errorf("the arguments are %s, want %s", arguments, toolArguments)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package conformance_test
+
+import (
+	"testing"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/kagent-dev/mockllm/conformance"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockConforms(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(conformance.Config()))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+
+	results := conformance.Run(t.Context(), baseURL)
+	require.Len(t, results, 14)
+	for _, result := range results {
+		assert.True(t, result.Passed(), "%s %s: %s", result.Provider, result.Check, result.Divergence)
+	}
+}
+
+func TestDivergencesAreReported(t *testing.T) {
+	// A server without the conformance mocks answers every check with a not found error
+	server := mockllm.NewServer()
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+
+	results := conformance.Run(t.Context(), baseURL)
+	require.NotEmpty(t, results)
+	assert.False(t, results[0].Passed())
+	assert.Contains(t, results[0].Divergence, "404")
+}
@@ -0,0 +1,49 @@
+package mockllm
+
+import (
+	"cmp"
+	"fmt"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/kagent-dev/mockllm/tokenizer"
+	"github.com/openai/openai-go"
+)
+
+// openaiContextOverflow returns the log reason and message of the context_length_exceeded error an
+// OpenAI request gets when its estimated prompt tokens, plus the completion tokens it asks for,
+// exceed the context window, or empty strings when the request fits
+func openaiContextOverflow(tok tokenizer.Tokenizer, limit int64, request openai.ChatCompletionNewParams) (reason, message string) {
+	prompt := promptTokens(tok, []any{request.Tools, request.Messages}, len(request.Messages))
+	completion := cmp.Or(request.MaxCompletionTokens.Value, request.MaxTokens.Value)
+	switch {
+	case prompt > limit:
+		return fmt.Sprintf("context length exceeded: %d tokens > %d", prompt, limit),
+			fmt.Sprintf("This model's maximum context length is %d tokens. However, your messages resulted in %d tokens. "+
+				"Please reduce the length of the messages.", limit, prompt)
+	case prompt+completion > limit:
+		return fmt.Sprintf("context length exceeded: %d + %d tokens > %d", prompt, completion, limit),
+			fmt.Sprintf("This model's maximum context length is %d tokens. However, you requested %d tokens (%d in the "+
+				"messages, %d in the completion). Please reduce the length of the messages or completion.",
+				limit, prompt+completion, prompt, completion)
+	default:
+		return "", ""
+	}
+}
+
+// anthropicContextOverflow returns the log reason and message of the invalid_request_error an
+// Anthropic request gets when its estimated input tokens, plus its max_tokens, exceed the context
+// window, or empty strings when the request fits
+func anthropicContextOverflow(tok tokenizer.Tokenizer, limit int64, request anthropic.MessageNewParams) (reason, message string) {
+	input := promptTokens(tok, []any{request.System, request.Tools, request.Messages}, len(request.Messages))
+	switch {
+	case input > limit:
+		return fmt.Sprintf("context length exceeded: %d tokens > %d", input, limit),
+			fmt.Sprintf("prompt is too long: %d tokens > %d maximum", input, limit)
+	case input+request.MaxTokens > limit:
+		return fmt.Sprintf("context length exceeded: %d + %d tokens > %d", input, request.MaxTokens, limit),
+			fmt.Sprintf("input length and `max_tokens` exceed context limit: %d + %d > %d, decrease input length or "+
+				"`max_tokens` and try again", input, request.MaxTokens, limit)
+	default:
+		return "", ""
+	}
+}
@@ -0,0 +1,92 @@
+package mockllm_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	openaioption "github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextWindowOverflow(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		ModelProfiles: map[string]mockllm.ModelProfile{"*": {MaxContextTokens: 1100}},
+		OpenAI: []mockllm.OpenAIMock{{
+			Name:     "hello",
+			Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+			Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "Hi"}}}},
+		}},
+		Anthropic: []mockllm.AnthropicMock{{
+			Name:     "hello",
+			Match:    mockllm.AnthropicRequestMatch{MatchType: mockllm.MatchTypeContains, Message: anthropic.NewUserMessage(anthropic.NewTextBlock("Hello"))},
+			Response: anthropic.Message{Content: []anthropic.ContentBlockUnion{{Type: "text", Text: "Hi"}}},
+		}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+	long := "Hello" + strings.Repeat(" again", 1200)
+
+	t.Run("openai", func(t *testing.T) {
+		client := openai.NewClient(openaioption.WithBaseURL(baseURL+"/v1"), openaioption.WithAPIKey("test-key"),
+			openaioption.WithMaxRetries(0))
+		request := func(content string, maxTokens int64) openai.ChatCompletionNewParams {
+			return openai.ChatCompletionNewParams{
+				Model:               "gpt-4o",
+				Messages:            []openai.ChatCompletionMessageParamUnion{openaiUserMessage(content)},
+				MaxCompletionTokens: openai.Int(maxTokens),
+			}
+		}
+
+		_, err := client.Chat.Completions.New(t.Context(), request("Hello", 1000))
+		require.NoError(t, err)
+
+		var apiErr *openai.Error
+		_, err = client.Chat.Completions.New(t.Context(), request("Hello", 2000))
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, 400, apiErr.StatusCode)
+		assert.Equal(t, "context_length_exceeded", apiErr.Code)
+		assert.Contains(t, apiErr.Message, "However, you requested")
+		assert.Contains(t, apiErr.Message, "2000 in the completion")
+
+		_, err = client.Chat.Completions.New(t.Context(), request(long, 10))
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, "context_length_exceeded", apiErr.Code)
+		assert.Contains(t, apiErr.Message, "This model's maximum context length is 1100 tokens. However, your messages resulted in")
+	})
+
+	t.Run("anthropic", func(t *testing.T) {
+		client := anthropic.NewClient(anthropicoption.WithBaseURL(baseURL), anthropicoption.WithAPIKey("test-key"),
+			anthropicoption.WithMaxRetries(0))
+
+		_, err := client.Messages.New(t.Context(), anthropicHelloRequest)
+		require.NoError(t, err)
+
+		var apiErr *anthropic.Error
+		request := anthropicHelloRequest
+		request.MaxTokens = 2000
+		_, err = client.Messages.New(t.Context(), request)
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, 400, apiErr.StatusCode)
+		assert.Contains(t, apiErr.Error(), "input length and `max_tokens` exceed context limit")
+		assert.Contains(t, apiErr.Error(), "+ 2000")
+
+		request.MaxTokens = 10
+		request.Messages = []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock(long))}
+		_, err = client.Messages.New(t.Context(), request)
+		require.ErrorAs(t, err, &apiErr)
+		assert.Contains(t, apiErr.Error(), "prompt is too long")
+		assert.Contains(t, apiErr.Error(), "1100 maximum")
+	})
+
+	for _, record := range server.Requests() {
+		if record.Status == 400 {
+			assert.Contains(t, record.Error, "context length exceeded")
+		}
+	}
+}
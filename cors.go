@@ -0,0 +1,75 @@
+package mockllm
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig lets browser based clients call the server from other origins
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to call the server, as exact origins or path.Match
+	// patterns such as "http://localhost:*". Defaults to any origin.
+	AllowedOrigins []string `json:"allowed_origins,omitempty"`
+	// AllowedHeaders are the request headers browsers may send. Defaults to whatever headers the
+	// preflight request asks for, covering authorization, x-api-key, anthropic-version and the
+	// headers the SDKs add.
+	AllowedHeaders []string `json:"allowed_headers,omitempty"`
+	// MaxAge is how long browsers may cache the result of a preflight request
+	MaxAge Duration `json:"max_age,omitempty"`
+}
+
+// allowsOrigin reports whether the config allows requests from the origin
+func (c CORSConfig) allowsOrigin(origin string) bool {
+	if len(c.AllowedOrigins) == 0 {
+		return true
+	}
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		if ok, err := path.Match(allowed, origin); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// corsMiddleware adds CORS headers to the responses of next for allowed origins, and answers
+// preflight requests itself, before any authentication, allowing the given methods
+func corsMiddleware(config CORSConfig, methods []string, next http.Handler) http.Handler {
+	allowedMethods := strings.Join(methods, ", ")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !config.allowsOrigin(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header := w.Header()
+		header.Set("Access-Control-Allow-Origin", origin)
+		header.Add("Vary", "Origin")
+		// Let browsers read request IDs and rate limit headers
+		header.Set("Access-Control-Expose-Headers", "*")
+		if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header.Set("Access-Control-Allow-Methods", allowedMethods)
+		allowedHeaders := strings.Join(config.AllowedHeaders, ", ")
+		if len(config.AllowedHeaders) == 0 {
+			allowedHeaders = r.Header.Get("Access-Control-Request-Headers")
+			header.Add("Vary", "Access-Control-Request-Headers")
+		}
+		if allowedHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", allowedHeaders)
+		}
+		if config.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", strconv.Itoa(int(time.Duration(config.MaxAge).Seconds())))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
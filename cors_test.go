@@ -0,0 +1,58 @@
+package mockllm_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORS(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		CORS: &mockllm.CORSConfig{
+			AllowedOrigins: []string{"http://localhost:*"},
+			MaxAge:         mockllm.Duration(10 * time.Minute),
+		},
+		HTTP: []mockllm.HTTPMock{{Name: "purge", Method: "purge", Path: "/cache"}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	preflight := func(origin string) *http.Response {
+		req, err := http.NewRequest(http.MethodOptions, baseURL+"/v1/messages", nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "content-type, x-api-key, anthropic-version")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close() //nolint:errcheck
+		return resp
+	}
+
+	resp := preflight("http://localhost:5173")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "http://localhost:5173", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "content-type, x-api-key, anthropic-version", resp.Header.Get("Access-Control-Allow-Headers"))
+	// The methods of every route and HTTP mock are allowed
+	assert.Equal(t, "DELETE, GET, OPTIONS, PATCH, POST, PURGE, PUT", resp.Header.Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "600", resp.Header.Get("Access-Control-Max-Age"))
+
+	resp = preflight("https://example.com")
+	assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+
+	// Actual requests carry the CORS headers too, errors included
+	resp = postJSON(t, baseURL+"/v1/chat/completions", openai.ChatCompletionNewParams{
+		Model:    "gpt-4o-mini",
+		Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+	}, map[string]string{"Origin": "http://localhost:5173", "Authorization": "Bearer test-key"})
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "http://localhost:5173", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "*", resp.Header.Get("Access-Control-Expose-Headers"))
+}
@@ -0,0 +1,92 @@
+package mockllm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+)
+
+// mockResponseID derives a stable response ID for a mock, so responses that leave out their ID
+// still look like the provider's
+func mockResponseID(prefix, mockName string) string {
+	sum := sha256.Sum256([]byte(mockName))
+	return prefix + hex.EncodeToString(sum[:12])
+}
+
+// fillOpenAIDefaults completes a partial chat completion, so mocks only need to configure the
+// fields a test cares about. The model is echoed from the request.
+func fillOpenAIDefaults(mockName string, request openai.ChatCompletionNewParams, response *openai.ChatCompletion,
+	now time.Time) {
+	if response.ID == "" {
+		response.ID = mockResponseID("chatcmpl-", mockName)
+	}
+	if response.Object == "" {
+		response.Object = "chat.completion"
+	}
+	if response.Created == 0 {
+		response.Created = now.Unix()
+	}
+	if response.Model == "" {
+		response.Model = request.Model
+	}
+
+	response.Choices = slices.Clone(response.Choices)
+	sequential := len(response.Choices) > 1 && !slices.ContainsFunc(response.Choices, func(choice openai.ChatCompletionChoice) bool {
+		return choice.Index != 0
+	})
+	for i := range response.Choices {
+		choice := &response.Choices[i]
+		if sequential {
+			choice.Index = int64(i)
+		}
+		if choice.Message.Role == "" {
+			choice.Message.Role = "assistant"
+		}
+		fillOpenAIAudio(mockName, request, &choice.Message, now)
+		if choice.FinishReason == "" {
+			choice.FinishReason = "stop"
+			if len(choice.Message.ToolCalls) > 0 {
+				choice.FinishReason = "tool_calls"
+			}
+		}
+	}
+}
+
+// fillAnthropicDefaults completes a partial message, so mocks only need to configure the fields a
+// test cares about. The model is echoed from the request.
+func fillAnthropicDefaults(mockName string, request anthropic.MessageNewParams, response *anthropic.Message) {
+	if response.ID == "" {
+		response.ID = mockResponseID("msg_", mockName)
+	}
+	if response.Type == "" {
+		response.Type = "message"
+	}
+	if response.Role == "" {
+		response.Role = "assistant"
+	}
+	if response.Model == "" {
+		response.Model = request.Model
+	}
+	if response.Content == nil {
+		response.Content = []anthropic.ContentBlockUnion{}
+	}
+	if response.StopReason == "" {
+		response.StopReason = anthropic.StopReasonEndTurn
+		if slices.ContainsFunc(response.Content, func(block anthropic.ContentBlockUnion) bool {
+			return block.Type == "tool_use"
+		}) {
+			response.StopReason = anthropic.StopReasonToolUse
+		}
+	}
+	if response.Usage.ServerToolUse.WebSearchRequests == 0 {
+		for _, block := range response.Content {
+			if block.Type == "server_tool_use" && block.Name == "web_search" {
+				response.Usage.ServerToolUse.WebSearchRequests++
+			}
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines hunkDiff prints around changes
+const diffContext = 3
+
+// maxLCSCells bounds the table lineEdits diffs the changed lines with. Larger changes are
+// reported as all their expected lines removed and all their actual lines added.
+const maxLCSCells = 1 << 22
+
+// lineEdit is a line of a diff, kind being ' ' for common lines, '-' for lines only in the
+// expected text and '+' for lines only in the actual one
+type lineEdit struct {
+	kind byte
+	line string
+}
+
+// lineEdits returns the edits turning the lines of a into those of b. The common prefix and
+// suffix are skipped first, so the memory used stays bounded by the size of the changes.
+func lineEdits(a, b []string) []lineEdit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]lineEdit, 0, len(a)+len(b)-prefix-suffix)
+	for _, line := range a[:prefix] {
+		edits = append(edits, lineEdit{' ', line})
+	}
+	edits = append(edits, changedEdits(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, lineEdit{' ', line})
+	}
+	return edits
+}
+
+// changedEdits returns a minimal diff of the changed lines, or when they are too many to diff
+// within maxLCSCells, all of a removed followed by all of b added
+func changedEdits(a, b []string) []lineEdit {
+	var edits []lineEdit
+	if (len(a)+1)*(len(b)+1) > maxLCSCells {
+		for _, line := range a {
+			edits = append(edits, lineEdit{'-', line})
+		}
+		for _, line := range b {
+			edits = append(edits, lineEdit{'+', line})
+		}
+		return edits
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, lineEdit{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, lineEdit{'-', a[i]})
+			i++
+		default:
+			edits = append(edits, lineEdit{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		edits = append(edits, lineEdit{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		edits = append(edits, lineEdit{'+', b[j]})
+	}
+	return edits
+}
+
+// lineDiff returns a line based diff between expected and actual. Lines only in expected are
+// prefixed with "- ", lines only in actual with "+ " and common lines with two spaces.
+func lineDiff(expected, actual string) string {
+	var sb strings.Builder
+	for _, edit := range lineEdits(strings.Split(expected, "\n"), strings.Split(actual, "\n")) {
+		sb.WriteString(string(edit.kind) + " " + edit.line + "\n")
+	}
+	return sb.String()
+}
+
+// hunkDiff returns the changed hunks of a line diff between expected and actual, each with
+// diffContext common lines around its changes and an "@@ -line,count +line,count @@" header
+// giving its position, for diffs of long texts where most lines are the same
+func hunkDiff(expected, actual string) string {
+	edits := lineEdits(strings.Split(expected, "\n"), strings.Split(actual, "\n"))
+	// lines[i] are the numbers of the expected and actual lines edit i starts at
+	lines := make([][2]int, len(edits)+1)
+	lines[0] = [2]int{1, 1}
+	for i, edit := range edits {
+		lines[i+1] = lines[i]
+		if edit.kind != '+' {
+			lines[i+1][0]++
+		}
+		if edit.kind != '-' {
+			lines[i+1][1]++
+		}
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(edits); {
+		if edits[i].kind == ' ' {
+			i++
+			continue
+		}
+		// Hunks extend over changes separated by less than twice the context
+		last := i
+		for j := i; j < len(edits) && j-last <= 2*diffContext; j++ {
+			if edits[j].kind != ' ' {
+				last = j
+			}
+		}
+		start, end := max(0, i-diffContext), min(len(edits), last+diffContext+1)
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", lines[start][0], lines[end][0]-lines[start][0],
+			lines[start][1], lines[end][1]-lines[start][1])
+		for _, edit := range edits[start:end] {
+			sb.WriteString(string(edit.kind) + " " + edit.line + "\n")
+		}
+		i = end
+	}
+	return sb.String()
+}
+
+// jsonDiff indents both values as JSON and diffs them line by line
+func jsonDiff(expected, actual any) string {
+	expectedJSON, err := json.MarshalIndent(expected, "", "  ")
+	if err != nil {
+		return err.Error()
+	}
+	actualJSON, err := json.MarshalIndent(actual, "", "  ")
+	if err != nil {
+		return err.Error()
+	}
+	return lineDiff(string(expectedJSON), string(actualJSON))
+}
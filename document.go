@@ -0,0 +1,159 @@
+package mockllm
+
+import (
+	"cmp"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+)
+
+// DocumentMatch matches a document attached to a request, such as the PDF a document QA agent
+// answers questions about: Anthropic document blocks and OpenAI file parts. Any document of the
+// conversation that meets every criterion matches.
+type DocumentMatch struct {
+	// Filename is a wildcard pattern such as "*.pdf", or a regular expression between slashes,
+	// the name of the document must match. Anthropic documents are named by their title, or else
+	// the last element of their URL.
+	Filename string `json:"filename,omitempty"`
+	// MediaType is a pattern the media type of the document must match, e.g. "application/pdf"
+	MediaType string `json:"media_type,omitempty"`
+	// MinBytes and MaxBytes bound the size of the document's content, unbounded when zero.
+	// Documents of unknown size, such as those given by URL, only match without bounds.
+	MinBytes int64 `json:"min_bytes,omitempty"`
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+}
+
+// attachedDocument is a document attached to a request, with a negative size when it is unknown
+type attachedDocument struct {
+	filename  string
+	mediaType string
+	size      int64
+}
+
+// mismatch returns why none of the documents matches, or an empty string
+func (m *DocumentMatch) mismatch(documents []attachedDocument) string {
+	if m == nil {
+		return ""
+	}
+	if len(documents) == 0 {
+		return "request has no documents"
+	}
+	filename, err := compileFieldPattern(cmp.Or(m.Filename, "*"))
+	if err != nil {
+		return fmt.Sprintf("invalid document filename pattern: %v", err)
+	}
+	mediaType, err := compileFieldPattern(cmp.Or(m.MediaType, "*"))
+	if err != nil {
+		return fmt.Sprintf("invalid document media type pattern: %v", err)
+	}
+	for _, document := range documents {
+		if !filename.MatchString(document.filename) || !mediaType.MatchString(document.mediaType) {
+			continue
+		}
+		if (m.MinBytes > 0 || m.MaxBytes > 0) && document.size < 0 {
+			continue
+		}
+		if document.size < m.MinBytes || (m.MaxBytes > 0 && document.size > m.MaxBytes) {
+			continue
+		}
+		return ""
+	}
+	return fmt.Sprintf("none of the %d documents matches", len(documents))
+}
+
+// openaiDocuments returns the file parts of the request's messages. Files given by the ID of an
+// upload to the Files API emulation are described by the upload.
+func openaiDocuments(request openai.ChatCompletionNewParams, files *fileStore) []attachedDocument {
+	var documents []attachedDocument
+	for _, message := range request.Messages {
+		if message.OfUser == nil {
+			continue
+		}
+		for _, part := range message.OfUser.Content.OfArrayOfContentParts {
+			if part.OfFile == nil {
+				continue
+			}
+			file := part.OfFile.File
+			document := attachedDocument{filename: file.Filename.Value, size: -1}
+			if data := file.FileData.Value; data != "" {
+				// File data is a data URL, or else plain base64
+				if header, encoded, ok := strings.Cut(data, ","); ok && strings.HasPrefix(header, "data:") {
+					document.mediaType, _, _ = strings.Cut(strings.TrimPrefix(header, "data:"), ";")
+					data = encoded
+				}
+				document.size = base64Size(data)
+			} else if upload := files.get(file.FileID.Value); upload != nil {
+				document.filename = upload.Filename
+				document.size = int64(upload.Bytes)
+			}
+			if document.mediaType == "" {
+				document.mediaType, _, _ = strings.Cut(mime.TypeByExtension(path.Ext(document.filename)), ";")
+			}
+			documents = append(documents, document)
+		}
+	}
+	return documents
+}
+
+// anthropicDocuments returns the document blocks of the request's messages, those of tool results
+// included
+func anthropicDocuments(request anthropic.MessageNewParams) []attachedDocument {
+	var blocks []*anthropic.DocumentBlockParam
+	for _, message := range request.Messages {
+		for _, block := range message.Content {
+			if block.OfDocument != nil {
+				blocks = append(blocks, block.OfDocument)
+			}
+			if block.OfToolResult == nil {
+				continue
+			}
+			for _, content := range block.OfToolResult.Content {
+				if content.OfDocument != nil {
+					blocks = append(blocks, content.OfDocument)
+				}
+			}
+		}
+	}
+
+	documents := make([]attachedDocument, 0, len(blocks))
+	for _, block := range blocks {
+		document := attachedDocument{filename: block.Title.Value, size: -1}
+		source := block.Source
+		switch {
+		case source.OfBase64 != nil:
+			document.mediaType, document.size = "application/pdf", base64Size(source.OfBase64.Data)
+		case source.OfText != nil:
+			document.mediaType, document.size = "text/plain", int64(len(source.OfText.Data))
+		case source.OfContent != nil:
+			document.mediaType, document.size = "text/plain", 0
+			for _, content := range source.OfContent.Content.OfContentBlockSourceContent {
+				if content.OfText != nil {
+					document.size += int64(len(content.OfText.Text))
+				}
+			}
+			document.size += int64(len(source.OfContent.Content.OfString.Value))
+		case source.OfURL != nil:
+			document.mediaType = "application/pdf"
+			if document.filename == "" {
+				document.filename = path.Base(source.OfURL.URL)
+			}
+		}
+		documents = append(documents, document)
+	}
+	return documents
+}
+
+// base64Size returns the size of base64 encoded data, estimated from its length when it does not
+// decode
+func base64Size(data string) int64 {
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return int64(base64.StdEncoding.DecodedLen(len(data)))
+	}
+	return int64(len(decoded))
+}
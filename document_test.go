@@ -0,0 +1,110 @@
+package mockllm_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	openaioption "github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentMatching(t *testing.T) {
+	pdf := base64.StdEncoding.EncodeToString([]byte("%PDF-1.7 quarterly report"))
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{{
+			Name: "small-pdf",
+			Match: mockllm.OpenAIRequestMatch{
+				MatchType: mockllm.MatchTypeFields,
+				Document:  &mockllm.DocumentMatch{Filename: "*.pdf", MediaType: "application/pdf", MaxBytes: 100},
+			},
+			Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "Revenue grew."}}}},
+		}},
+		Anthropic: []mockllm.AnthropicMock{{
+			Name: "report",
+			Match: mockllm.AnthropicRequestMatch{
+				MatchType: mockllm.MatchTypeFields,
+				Document:  &mockllm.DocumentMatch{Filename: "/(?i)report/", MinBytes: 10},
+			},
+		}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+
+	// Bodies are explained against the mocks of both providers, the mock of the request's one tells
+	explain := func(body any) mockllm.MatchExplanation {
+		t.Helper()
+		encoded, err := json.Marshal(body)
+		require.NoError(t, err)
+		explanations, err := server.WhichMockMatches(encoded, nil)
+		require.NoError(t, err)
+		provider := "openai"
+		if _, ok := body.(anthropic.MessageNewParams); ok {
+			provider = "anthropic"
+		}
+		for _, explanation := range explanations {
+			if explanation.Provider == provider {
+				return explanation
+			}
+		}
+		require.Fail(t, "no explanation for "+provider)
+		return mockllm.MatchExplanation{}
+	}
+	openaiFile := func(file openai.ChatCompletionContentPartFileFileParam) openai.ChatCompletionNewParams {
+		return openai.ChatCompletionNewParams{Model: "gpt-4o", Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.UserMessage([]openai.ChatCompletionContentPartUnionParam{
+				openai.TextContentPart("Summarize the report"),
+				openai.FileContentPart(file),
+			}),
+		}}
+	}
+
+	request := openaiFile(openai.ChatCompletionContentPartFileFileParam{
+		Filename: openai.String("q3.pdf"),
+		FileData: openai.String("data:application/pdf;base64," + pdf),
+	})
+	completion := postChatCompletion(t, baseURL, request)
+	require.Len(t, completion.Choices, 1)
+	assert.Equal(t, "Revenue grew.", completion.Choices[0].Message.Content)
+
+	explanation := explain(openaiFile(openai.ChatCompletionContentPartFileFileParam{
+		Filename: openai.String("q3.pdf"),
+		FileData: openai.String("data:application/pdf;base64," + strings.Repeat("A", 200)),
+	}))
+	assert.Equal(t, "none of the 1 documents matches", explanation.Reason, "the document is too large")
+
+	// Uploaded files are described by the upload
+	client := openai.NewClient(openaioption.WithBaseURL(baseURL+"/v1"), openaioption.WithAPIKey("test-key"))
+	upload, err := client.Files.New(t.Context(), openai.FileNewParams{
+		File:    openai.File(strings.NewReader("%PDF-1.7"), "q4.pdf", "application/pdf"),
+		Purpose: openai.FilePurposeUserData,
+	})
+	require.NoError(t, err)
+	explanation = explain(openaiFile(openai.ChatCompletionContentPartFileFileParam{FileID: openai.String(upload.ID)}))
+	assert.True(t, explanation.Matched, explanation.Reason)
+	assert.Equal(t, "request has no documents", explain(openai.ChatCompletionNewParams{
+		Model:    "gpt-4o",
+		Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Summarize the report")},
+	}).Reason)
+
+	anthropicDocument := func(block anthropic.DocumentBlockParam) anthropic.MessageNewParams {
+		return anthropic.MessageNewParams{Model: "claude-sonnet-4-0", MaxTokens: 100, Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(anthropic.ContentBlockParamUnion{OfDocument: &block}, anthropic.NewTextBlock("Summarize it")),
+		}}
+	}
+	explanation = explain(anthropicDocument(anthropic.DocumentBlockParam{
+		Title:  anthropic.String("Quarterly Report"),
+		Source: anthropic.DocumentBlockParamSourceUnion{OfBase64: &anthropic.Base64PDFSourceParam{Data: pdf}},
+	}))
+	assert.True(t, explanation.Matched, explanation.Reason)
+	explanation = explain(anthropicDocument(anthropic.DocumentBlockParam{
+		Source: anthropic.DocumentBlockParamSourceUnion{OfURL: &anthropic.URLPDFSourceParam{URL: "https://example.com/report.pdf"}},
+	}))
+	assert.False(t, explanation.Matched, "documents of unknown size don't match size bounds")
+}
@@ -0,0 +1,96 @@
+package mockllm
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+)
+
+// DefaultDuplicateWindow is how close identical requests must be to be reported as duplicates,
+// unless Config.DuplicateWindow says otherwise
+const DefaultDuplicateWindow = 10 * time.Second
+
+// DuplicateSummary describes identical requests sent within the duplicate window of each other,
+// e.g. by a retry loop that retries successful calls
+type DuplicateSummary struct {
+	// FirstID is the ID of the first of the identical requests, and DuplicateIDs those of the others
+	FirstID      int    `json:"first_id"`
+	DuplicateIDs []int  `json:"duplicate_ids"`
+	Provider     string `json:"provider"`
+	Tenant       string `json:"tenant,omitempty"`
+	APIKey       string `json:"api_key,omitempty"`
+	Path         string `json:"path"`
+	MockName     string `json:"mock_name,omitempty"`
+	// WastedTokens are the input and output tokens reported to the client for the duplicates
+	WastedTokens int64 `json:"wasted_tokens"`
+}
+
+// duplicateOf returns the ID of the first of the logged requests identical to the record, when the
+// latest of them was received within the window before it, and 0 otherwise. Idempotent replays
+// cost no tokens and are never duplicates. l.mu must be held.
+func (l *RequestLog) duplicateOf(record *RequestRecord) int {
+	if l.duplicateWindow < 0 || record.Body == nil || record.ReplayOf != 0 {
+		return 0
+	}
+	window := l.duplicateWindow
+	if window == 0 {
+		window = DefaultDuplicateWindow
+	}
+
+	for i := len(l.records) - 1; i >= 0; i-- {
+		previous := &l.records[i]
+		if elapsed := record.Time.Sub(previous.Time); elapsed < 0 || elapsed > window {
+			continue
+		}
+		if previous.Provider != record.Provider || previous.Tenant != record.Tenant || previous.apiKey != record.apiKey ||
+			previous.Method != record.Method || previous.Path != record.Path || !bytes.Equal(previous.Body, record.Body) {
+			continue
+		}
+		if previous.DuplicateOf != 0 {
+			return previous.DuplicateOf
+		}
+		return previous.ID
+	}
+	return 0
+}
+
+// Duplicates returns the identical requests the log has received, in the order of their first request
+func (l *RequestLog) Duplicates() []DuplicateSummary {
+	records := l.Records()
+	byID := map[int]*DuplicateSummary{}
+	var order []int
+	for _, record := range records {
+		if record.DuplicateOf == 0 {
+			continue
+		}
+		summary, ok := byID[record.DuplicateOf]
+		if !ok {
+			first := records[record.DuplicateOf-1]
+			summary = &DuplicateSummary{FirstID: first.ID, Provider: first.Provider, Tenant: first.Tenant,
+				APIKey: first.APIKey, Path: first.Path, MockName: first.MockName}
+			byID[record.DuplicateOf] = summary
+			order = append(order, record.DuplicateOf)
+		}
+		summary.DuplicateIDs = append(summary.DuplicateIDs, record.ID)
+		if record.Usage != nil {
+			summary.WastedTokens += record.Usage.InputTokens + record.Usage.OutputTokens
+		}
+	}
+
+	duplicates := make([]DuplicateSummary, 0, len(order))
+	for _, id := range order {
+		duplicates = append(duplicates, *byID[id])
+	}
+	return duplicates
+}
+
+// Duplicates returns the identical requests received within the duplicate window of each other,
+// which point at clients double-spending tokens. Every duplicate is also flagged with
+// duplicate_of in the request log.
+func (s *Server) Duplicates() []DuplicateSummary {
+	return s.requestLog.Duplicates()
+}
+
+func (s *Server) handleAdminDuplicates(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Duplicates())
+}
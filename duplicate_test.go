@@ -0,0 +1,78 @@
+package mockllm_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuplicateDetection(t *testing.T) {
+	mock := replyMock("hello", "Hi")
+	mock.Response.Usage = openai.CompletionUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI:          []mockllm.OpenAIMock{mock},
+		DuplicateWindow: mockllm.Duration(200 * time.Millisecond),
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+
+	post := func(content, apiKey string) {
+		resp := postJSON(t, baseURL+"/v1/chat/completions", openai.ChatCompletionNewParams{
+			Model:    "gpt-4o-mini",
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage(content)},
+		}, map[string]string{"Authorization": "Bearer " + apiKey})
+		resp.Body.Close() //nolint:errcheck
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	post("Hello", "key-a")
+	post("Hello", "key-a")
+	post("Hello", "key-a")
+	post("Hello", "key-b") // another client
+	post("Hello again", "key-a")
+	time.Sleep(300 * time.Millisecond)
+	post("Hello", "key-a") // outside the window
+
+	duplicates := server.Duplicates()
+	require.Len(t, duplicates, 1)
+	assert.Equal(t, mockllm.DuplicateSummary{
+		FirstID:      1,
+		DuplicateIDs: []int{2, 3},
+		Provider:     "openai",
+		APIKey:       "...-a",
+		Path:         "/v1/chat/completions",
+		MockName:     "hello",
+		WastedTokens: 30,
+	}, duplicates[0])
+
+	requests := server.Requests()
+	assert.Equal(t, []int{0, 1, 1, 0, 0, 0}, []int{requests[0].DuplicateOf, requests[1].DuplicateOf,
+		requests[2].DuplicateOf, requests[3].DuplicateOf, requests[4].DuplicateOf, requests[5].DuplicateOf})
+
+	resp, err := http.Get(baseURL + "/admin/duplicates")
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	var report []mockllm.DuplicateSummary
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	assert.Equal(t, duplicates, report)
+}
+
+func TestDuplicateDetectionDisabled(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI:          []mockllm.OpenAIMock{replyMock("hello", "Hi")},
+		DuplicateWindow: mockllm.Duration(-1),
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+
+	postChatCompletion(t, baseURL, helloParams)
+	postChatCompletion(t, baseURL, helloParams)
+	assert.Empty(t, server.Duplicates())
+}
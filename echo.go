@@ -0,0 +1,91 @@
+package mockllm
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+)
+
+// echoMockName is the mock name logged for requests answered by the global echo mode
+const echoMockName = "echo"
+
+// EchoTransform changes the echoed text
+type EchoTransform string
+
+const (
+	EchoUppercase EchoTransform = "uppercase"
+	EchoReverse   EchoTransform = "reverse"
+)
+
+// EchoConfig replies with the text of the request's last message instead of a configured response
+type EchoConfig struct {
+	// Transform is applied to the text before it is echoed
+	Transform EchoTransform `json:"transform,omitempty"`
+	// MaxLength truncates the echoed text to at most this many characters, unlimited when zero
+	MaxLength int `json:"max_length,omitempty"`
+}
+
+// echo returns the text transformed and truncated as configured
+func (e *EchoConfig) echo(text string) string {
+	switch e.Transform {
+	case EchoUppercase:
+		text = strings.ToUpper(text)
+	case EchoReverse:
+		runes := []rune(text)
+		slices.Reverse(runes)
+		text = string(runes)
+	}
+	if runes := []rune(text); e.MaxLength > 0 && len(runes) > e.MaxLength {
+		text = string(runes[:e.MaxLength])
+	}
+	return text
+}
+
+// openaiLastMessageText returns the text content of the request's last message
+func openaiLastMessageText(request openai.ChatCompletionNewParams) string {
+	if len(request.Messages) == 0 {
+		return ""
+	}
+	return openaiMessageText(request.Messages[len(request.Messages)-1])
+}
+
+// openaiMessageText returns the text content of a message
+func openaiMessageText(message openai.ChatCompletionMessageParamUnion) string {
+	var text string
+	switch content := message.GetContent().AsAny().(type) {
+	case *string:
+		text = *content
+	case *[]openai.ChatCompletionContentPartUnionParam:
+		for _, part := range *content {
+			if part.OfText != nil {
+				text += part.OfText.Text
+			}
+		}
+	case *[]openai.ChatCompletionContentPartTextParam:
+		for _, part := range *content {
+			text += part.Text
+		}
+	}
+	return text
+}
+
+// anthropicLastMessageText returns the text blocks of the request's last message
+func anthropicLastMessageText(request anthropic.MessageNewParams) string {
+	if len(request.Messages) == 0 {
+		return ""
+	}
+	return anthropicMessageText(request.Messages[len(request.Messages)-1])
+}
+
+// anthropicMessageText returns the text blocks of a message
+func anthropicMessageText(message anthropic.MessageParam) string {
+	var text string
+	for _, block := range message.Content {
+		if block.OfText != nil {
+			text += block.OfText.Text
+		}
+	}
+	return text
+}
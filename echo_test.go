@@ -0,0 +1,54 @@
+package mockllm_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEchoMode(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		Echo: &mockllm.EchoConfig{Transform: mockllm.EchoUppercase},
+		OpenAI: []mockllm.OpenAIMock{{
+			Name:  "reversed",
+			Match: mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Reverse")},
+			Echo:  &mockllm.EchoConfig{Transform: mockllm.EchoReverse, MaxLength: 5},
+		}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	completion := postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+		Model:    "gpt-4o-mini",
+		Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Reverse me")},
+	})
+	require.Len(t, completion.Choices, 1)
+	assert.Equal(t, "em es", completion.Choices[0].Message.Content)
+
+	completion = postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+		Model:    "gpt-4o-mini",
+		Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Anything else")},
+	})
+	assert.Equal(t, "ANYTHING ELSE", completion.Choices[0].Message.Content)
+
+	resp := postJSON(t, baseURL+"/v1/messages", anthropicHelloRequest, anthropicHeaders("2023-06-01"))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var message anthropic.Message
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&message))
+	require.Len(t, message.Content, 1)
+	assert.Equal(t, "HELLO", message.Content[0].Text)
+
+	requests := server.Requests()
+	require.Len(t, requests, 3)
+	assert.Equal(t, "reversed", requests[0].MockName)
+	assert.Equal(t, "echo", requests[1].MockName)
+	assert.True(t, requests[2].Matched)
+}
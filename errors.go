@@ -0,0 +1,87 @@
+package mockllm
+
+import (
+	"net/http"
+)
+
+// anthropicErrorTypes maps HTTP status codes to the error types documented for the Anthropic API
+var anthropicErrorTypes = map[int]string{
+	http.StatusBadRequest:            "invalid_request_error",
+	http.StatusUnauthorized:          "authentication_error",
+	http.StatusForbidden:             "permission_error",
+	http.StatusNotFound:              "not_found_error",
+	http.StatusRequestEntityTooLarge: "request_too_large",
+	http.StatusTooManyRequests:       "rate_limit_error",
+	http.StatusInternalServerError:   "api_error",
+	529:                              "overloaded_error",
+}
+
+// anthropicErrorType returns the Anthropic error type for an HTTP status code
+func anthropicErrorType(status int) string {
+	if errorType, ok := anthropicErrorTypes[status]; ok {
+		return errorType
+	}
+	if status >= http.StatusInternalServerError {
+		return "api_error"
+	}
+	return "invalid_request_error"
+}
+
+// writeAnthropicError writes an error using the Anthropic error envelope,
+// {"type":"error","error":{"type":"...","message":"..."}}, so the official SDKs can parse it
+func writeAnthropicError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{
+		"type": "error",
+		"error": map[string]any{
+			"type":    anthropicErrorType(status),
+			"message": message,
+		},
+	})
+}
+
+// openaiErrorType returns the OpenAI error type for an HTTP status code
+func openaiErrorType(status int) string {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return "requests"
+	case status >= http.StatusInternalServerError:
+		return "server_error"
+	default:
+		return "invalid_request_error"
+	}
+}
+
+// writeOpenAIError writes an error using the OpenAI error envelope,
+// {"error":{"message":"...","type":"...","param":...,"code":...}}. Empty param and code are sent as null.
+func writeOpenAIError(w http.ResponseWriter, status int, message, param, code string) {
+	writeJSON(w, status, map[string]any{
+		"error": map[string]any{
+			"message": message,
+			"type":    openaiErrorType(status),
+			"param":   nullableString(param),
+			"code":    nullableString(code),
+		},
+	})
+}
+
+func nullableString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// writeProviderError writes an error in the envelope of the given provider, falling back to a
+// plain {"error":"..."} body for custom providers
+func writeProviderError(w http.ResponseWriter, provider Provider, status int, message string) {
+	switch provider.(type) {
+	case *OpenAIProvider:
+		writeOpenAIError(w, status, message, "", "")
+	case *AnthropicProvider:
+		writeAnthropicError(w, status, message)
+	case *GeminiProvider:
+		writeGeminiError(w, status, message)
+	default:
+		writeJSON(w, status, map[string]any{"error": message})
+	}
+}
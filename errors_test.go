@@ -0,0 +1,90 @@
+package mockllm_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	openaioption "github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnthropicErrorFormat(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	client := anthropic.NewClient(
+		anthropicoption.WithBaseURL(baseURL),
+		anthropicoption.WithAPIKey("test-key"),
+		anthropicoption.WithMaxRetries(0),
+	)
+	_, err = client.Messages.New(t.Context(), anthropic.MessageNewParams{
+		Model:     "claude-3-5-sonnet-20240620",
+		MaxTokens: 100,
+		Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("Hello"))},
+	})
+	var apiErr *anthropic.Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+
+	var body struct {
+		Type  string `json:"type"`
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(apiErr.RawJSON()), &body))
+	assert.Equal(t, "error", body.Type)
+	assert.Equal(t, "not_found_error", body.Error.Type)
+	assert.Contains(t, body.Error.Message, "No matching mock found")
+
+	resp := postJSON(t, baseURL+"/v1/messages", map[string]any{}, map[string]string{"anthropic-version": "2023-06-01"})
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "authentication_error", body.Error.Type)
+}
+
+func TestOpenAIErrorFormat(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	client := openai.NewClient(
+		openaioption.WithBaseURL(baseURL+"/v1"),
+		openaioption.WithAPIKey("test-key"),
+		openaioption.WithMaxRetries(0),
+	)
+	_, err = client.Chat.Completions.New(t.Context(), openai.ChatCompletionNewParams{
+		Model:    "gpt-4o-mini",
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Hello")},
+	})
+	var apiErr *openai.Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "invalid_request_error", apiErr.Type)
+	assert.Equal(t, "mock_not_found", apiErr.Code)
+	assert.Contains(t, apiErr.Message, "No matching mock found")
+
+	resp := postJSON(t, baseURL+"/v1/chat/completions", map[string]any{}, nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	var body struct {
+		Error struct {
+			Type string  `json:"type"`
+			Code *string `json:"code"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "invalid_request_error", body.Error.Type)
+	assert.Nil(t, body.Error.Code)
+}
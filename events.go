@@ -0,0 +1,92 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// EventType classifies a request log entry for the live event stream
+type EventType string
+
+const (
+	// EventMatch is emitted when a request was served by a mock
+	EventMatch EventType = "match"
+	// EventUnmatch is emitted when a well-formed request did not match any mock
+	EventUnmatch EventType = "unmatch"
+	// EventError is emitted when a request was rejected or could not be served
+	EventError EventType = "error"
+	// EventDisconnect is emitted when the client went away in the middle of a streamed response
+	EventDisconnect EventType = "disconnect"
+	// EventReplay is emitted when the stored response of an earlier request with the same
+	// Idempotency-Key was replayed
+	EventReplay EventType = "replay"
+)
+
+// eventBufferSize is the number of events buffered per subscriber before new events are dropped
+const eventBufferSize = 64
+
+// Event is a single entry of the live activity stream
+type Event struct {
+	Type    EventType     `json:"type"`
+	Request RequestRecord `json:"request"`
+}
+
+func newEvent(record RequestRecord) Event {
+	eventType := EventUnmatch
+	switch {
+	case record.Stream != nil && record.Stream.Disconnected:
+		eventType = EventDisconnect
+	case record.ReplayOf != 0:
+		eventType = EventReplay
+	case record.Matched:
+		eventType = EventMatch
+	case record.Error != "":
+		eventType = EventError
+	}
+	return Event{Type: eventType, Request: record}
+}
+
+// Subscribe returns a channel receiving an event for every request added to the server's log
+// from now on, and a function that cancels the subscription. Events are dropped rather than
+// blocking request handling if the subscriber falls behind.
+func (s *Server) Subscribe() (<-chan Event, func()) {
+	return s.requestLog.Subscribe()
+}
+
+// handleAdminEvents streams request log events as server-sent events until the client disconnects
+// or the server shuts down
+func (s *Server) handleAdminEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events, cancel := s.Subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.shuttingDown:
+			return
+		case event := <-events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
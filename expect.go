@@ -0,0 +1,115 @@
+package mockllm
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+)
+
+// RequestExpectation is an assertion on the requests a mock serves, checked once the mock matched,
+// e.g. to catch prompt construction regressions such as tool results dropped from the
+// conversation. Failed expectations don't change the response, they are recorded in the request
+// log and reported by Server.Verify.
+type RequestExpectation struct {
+	// Contains requires the text of a message, system prompt or tool result to contain the text verbatim
+	Contains string `json:"contains,omitempty"`
+	// NotContains requires no message, system prompt or tool result to contain the text
+	NotContains string `json:"not_contains,omitempty"`
+	// ToolResult requires the request to pass back a non-empty result of a call of the named tool
+	ToolResult string `json:"tool_result,omitempty"`
+}
+
+// failures checks the expectation against the texts and tool results of a request, keyed by tool name
+func (e RequestExpectation) failures(texts []string, toolResults map[string][]string) []string {
+	var failures []string
+	contains := func(substr string) bool {
+		return slices.ContainsFunc(texts, func(text string) bool { return strings.Contains(text, substr) })
+	}
+	if e.Contains != "" && !contains(e.Contains) {
+		failures = append(failures, fmt.Sprintf("request does not include %q", e.Contains))
+	}
+	if e.NotContains != "" && contains(e.NotContains) {
+		failures = append(failures, fmt.Sprintf("request includes %q", e.NotContains))
+	}
+	if e.ToolResult != "" && !slices.ContainsFunc(toolResults[e.ToolResult], func(result string) bool { return result != "" }) {
+		failures = append(failures, fmt.Sprintf("request does not include a result of tool %q", e.ToolResult))
+	}
+	return failures
+}
+
+// expectationFailures checks every expectation of a mock
+func expectationFailures(expectations []RequestExpectation, texts []string, toolResults map[string][]string) []string {
+	var failures []string
+	for _, expectation := range expectations {
+		failures = append(failures, expectation.failures(texts, toolResults)...)
+	}
+	return failures
+}
+
+// openaiExpectationFailures checks the expectations of an OpenAI mock against a request
+func openaiExpectationFailures(expectations []RequestExpectation, request openai.ChatCompletionNewParams) []string {
+	if len(expectations) == 0 {
+		return nil
+	}
+	names := map[string]string{}
+	toolResults := map[string][]string{}
+	for _, message := range request.Messages {
+		if message.OfAssistant != nil {
+			for _, toolCall := range message.OfAssistant.ToolCalls {
+				names[toolCall.ID] = toolCall.Function.Name
+			}
+		}
+		if message.OfTool != nil {
+			name := names[message.OfTool.ToolCallID]
+			toolResults[name] = append(toolResults[name], openaiMessageText(message))
+		}
+	}
+	return expectationFailures(expectations, openaiMessageTexts(request), toolResults)
+}
+
+// anthropicExpectationFailures checks the expectations of an Anthropic mock against a request
+func anthropicExpectationFailures(expectations []RequestExpectation, request anthropic.MessageNewParams) []string {
+	if len(expectations) == 0 {
+		return nil
+	}
+	texts := anthropicMessageTexts(request)
+	names := map[string]string{}
+	toolResults := map[string][]string{}
+	for _, message := range request.Messages {
+		for _, block := range message.Content {
+			if block.OfToolUse != nil {
+				names[block.OfToolUse.ID] = block.OfToolUse.Name
+			}
+			if block.OfToolResult == nil {
+				continue
+			}
+			var result string
+			for _, content := range block.OfToolResult.Content {
+				if content.OfText != nil {
+					result += content.OfText.Text
+				}
+			}
+			name := names[block.OfToolResult.ToolUseID]
+			toolResults[name] = append(toolResults[name], result)
+			texts = append(texts, result)
+		}
+	}
+	return expectationFailures(expectations, texts, toolResults)
+}
+
+// Verify returns an error listing the failed expectations of every logged request and the failed
+// assertions of the config, or nil when all were met
+func (s *Server) Verify() error {
+	var errs []error
+	for _, record := range s.Requests() {
+		for _, failure := range record.ExpectationFailures {
+			errs = append(errs, fmt.Errorf("request #%d (%s %s): %s", record.ID, record.Provider, record.MockName, failure))
+		}
+	}
+	errs = append(errs, s.assertionFailures()...)
+	return errors.Join(errs...)
+}
@@ -0,0 +1,150 @@
+package mockllm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// FaultType is a connection level failure a mock can simulate
+type FaultType string
+
+const (
+	// FaultClose closes the connection without sending a response
+	FaultClose FaultType = "close"
+	// FaultHangAfterHeaders sends the status and headers, then hangs without a body until the
+	// client gives up or Duration elapses, after which the connection is closed
+	FaultHangAfterHeaders FaultType = "hang_after_headers"
+	// FaultStall pauses for Duration after AfterEvents events of a streamed response, or before
+	// a non-streamed response is sent, then completes the response normally
+	FaultStall FaultType = "stall"
+	// FaultTimeout never responds, until the client gives up or Duration elapses, after which the
+	// connection is closed
+	FaultTimeout FaultType = "timeout"
+	// FaultDrip writes the response Bytes at a time, one write every Duration, like a slow-loris
+	// peer, e.g. to test read timeouts that only cover the start of a response
+	FaultDrip FaultType = "drip"
+)
+
+// Fault simulates a misbehaving connection for testing client retry and cancellation logic
+type Fault struct {
+	Type FaultType `json:"type"`
+	// Duration is how long to stall or hang, and must be set for stalls. Hangs without a duration
+	// last until the client disconnects.
+	Duration Duration `json:"duration,omitempty"`
+	// AfterEvents is the number of stream events sent before a stall
+	AfterEvents int `json:"after_events,omitempty"`
+	// Bytes is the number of bytes dripped at a time, one by default
+	Bytes int `json:"bytes,omitempty"`
+}
+
+// stallsBefore reports whether a stream should stall before sending the event at index i
+func (f *Fault) stallsBefore(i int) bool {
+	return f != nil && f.Type == FaultStall && i == f.AfterEvents
+}
+
+// applyFault simulates the connection level faults that prevent a response from being written,
+// and reports whether the handler should go on to write the response. When it should not, status
+// is the status sent before the connection was dropped, 0 when none was.
+func applyFault(w http.ResponseWriter, r *http.Request, clock Clock, fault *Fault, contentType string) (status int, ok bool) {
+	if fault == nil {
+		return 0, true
+	}
+
+	switch fault.Type {
+	case FaultClose:
+		closeConnection(w)
+		return 0, false
+	case FaultHangAfterHeaders:
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		http.NewResponseController(w).Flush() //nolint:errcheck
+		if wait(r.Context(), clock, time.Duration(fault.Duration)) {
+			closeConnection(w)
+		}
+		return http.StatusOK, false
+	case FaultTimeout:
+		if wait(r.Context(), clock, time.Duration(fault.Duration)) {
+			closeConnection(w)
+		}
+		return 0, false
+	default:
+		return 0, true
+	}
+}
+
+// closeConnection abruptly closes the underlying connection of the response
+func closeConnection(w http.ResponseWriter) {
+	conn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return
+	}
+	conn.Close() //nolint:errcheck
+}
+
+// dripWriter writes the response a few bytes at a time for FaultDrip
+type dripWriter struct {
+	http.ResponseWriter
+	ctx      context.Context
+	clock    Clock
+	fault    *Fault
+	dripping bool
+}
+
+// dripResponse returns a writer dripping the response when the fault asks for it, and w otherwise
+func dripResponse(w http.ResponseWriter, r *http.Request, clock Clock, fault *Fault) http.ResponseWriter {
+	if fault == nil || fault.Type != FaultDrip {
+		return w
+	}
+	return &dripWriter{ResponseWriter: w, ctx: r.Context(), clock: clock, fault: fault}
+}
+
+// Write flushes every few bytes of p after waiting for the fault's duration, a second by default.
+// It stops with the context's error once the client disconnects.
+func (d *dripWriter) Write(p []byte) (int, error) {
+	size, interval := max(d.fault.Bytes, 1), time.Duration(d.fault.Duration)
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	written := 0
+	for written < len(p) {
+		if d.dripping && !wait(d.ctx, d.clock, interval) {
+			return written, d.ctx.Err()
+		}
+		d.dripping = true
+		n, err := d.ResponseWriter.Write(p[written:min(written+size, len(p))])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		if err := http.NewResponseController(d.ResponseWriter).Flush(); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (d *dripWriter) Unwrap() http.ResponseWriter {
+	return d.ResponseWriter
+}
+
+// faultIssues reports faults of an unknown type and stalls that would never complete the response
+func faultIssues(fault *Fault, path string) []configIssue {
+	if fault == nil {
+		return nil
+	}
+	switch fault.Type {
+	case FaultClose, FaultHangAfterHeaders, FaultTimeout, FaultDrip:
+		return nil
+	case FaultStall:
+		if fault.Duration <= 0 {
+			return []configIssue{{path + ".duration", "must be positive for a stall"}}
+		}
+		return nil
+	default:
+		return []configIssue{{path + ".type", fmt.Sprintf("unknown fault type %q", fault.Type)}}
+	}
+}
@@ -0,0 +1,174 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// mismatchedField checks fields of a request against the patterns of a mock's fields criteria,
+// returning why they do not match or an empty string
+func mismatchedField(request any, fields map[string]string) string {
+	_, reason := matchFields(request, fields)
+	return reason
+}
+
+// fieldCaptures returns the named groups captured by the regular expressions of a mock's fields
+// criteria, e.g. city for "/weather in (?P<city>\w+)/", from the first value each matched
+func fieldCaptures(request any, fields map[string]string) map[string]string {
+	captures, _ := matchFields(request, fields)
+	return captures
+}
+
+// matchFields checks fields of a request against the patterns of a mock's fields criteria,
+// returning the named groups they captured and why they do not match or an empty string
+func matchFields(request any, fields map[string]string) (map[string]string, string) {
+	if len(fields) == 0 {
+		return nil, ""
+	}
+	encoded, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Sprintf("failed to encode request: %v", err)
+	}
+	var document any
+	if err := json.Unmarshal(encoded, &document); err != nil {
+		return nil, fmt.Sprintf("failed to decode request: %v", err)
+	}
+
+	paths := make([]string, 0, len(fields))
+	for path := range fields {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	captures := map[string]string{}
+	for _, path := range paths {
+		values, err := lookupField(document, path)
+		if err != nil {
+			return nil, fmt.Sprintf("invalid field path %q: %v", path, err)
+		}
+		if len(values) == 0 {
+			return nil, fmt.Sprintf("field %s is missing", path)
+		}
+		pattern, err := compileFieldPattern(fields[path])
+		if err != nil {
+			return nil, fmt.Sprintf("invalid pattern for field %s: %v", path, err)
+		}
+		var match []string
+		for _, value := range values {
+			if match = pattern.FindStringSubmatch(fieldText(value)); match != nil {
+				break
+			}
+		}
+		if match == nil {
+			return nil, fmt.Sprintf("field %s is %s, want %q", path, fieldText(values[0]), fields[path])
+		}
+		for i, name := range pattern.SubexpNames() {
+			if name != "" && match[i] != "" {
+				captures[name] = match[i]
+			}
+		}
+	}
+	return captures, ""
+}
+
+// substituteCaptures replaces {name} references to captured groups in the text of a response, its
+// tool call arguments included. References to other names are left as they are, and so is a
+// response that does not encode.
+func substituteCaptures[T any](response *T, captures map[string]string) {
+	if len(captures) == 0 {
+		return
+	}
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		return
+	}
+	replacements := make([]string, 0, 2*len(captures))
+	for name, value := range captures {
+		// Escape the value as the content of a JSON string
+		quoted, _ := json.Marshal(value)
+		replacements = append(replacements, "{"+name+"}", string(quoted[1:len(quoted)-1]))
+	}
+	substituted := strings.NewReplacer(replacements...).Replace(string(encoded))
+	var result T
+	if err := json.Unmarshal([]byte(substituted), &result); err == nil {
+		*response = result
+	}
+}
+
+// lookupField returns the values at a path such as tools[0].function.name in a decoded JSON
+// document. [*] selects every element of an array and negative indexes count from the end.
+func lookupField(document any, path string) ([]any, error) {
+	values := []any{document}
+	for _, segment := range strings.Split(path, ".") {
+		name, indexes, _ := strings.Cut(segment, "[")
+		if name != "" {
+			var next []any
+			for _, value := range values {
+				if object, ok := value.(map[string]any); ok {
+					if field, ok := object[name]; ok {
+						next = append(next, field)
+					}
+				}
+			}
+			values = next
+		}
+		if indexes == "" {
+			continue
+		}
+
+		for _, index := range strings.Split(strings.TrimSuffix(indexes, "]"), "][") {
+			var next []any
+			for _, value := range values {
+				array, ok := value.([]any)
+				if !ok {
+					continue
+				}
+				if index == "*" {
+					next = append(next, array...)
+					continue
+				}
+				i, err := strconv.Atoi(index)
+				if err != nil {
+					return nil, fmt.Errorf("index %q is not a number or *", index)
+				}
+				if i < 0 {
+					i += len(array)
+				}
+				if i >= 0 && i < len(array) {
+					next = append(next, array[i])
+				}
+			}
+			values = next
+		}
+	}
+	return values, nil
+}
+
+// compileFieldPattern compiles a regular expression given between slashes, such as "/^search_/",
+// or else a wildcard pattern matching the whole value, where * matches any text and ? a single
+// character
+func compileFieldPattern(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		return regexp.Compile(pattern[1 : len(pattern)-1])
+	}
+	quoted := regexp.QuoteMeta(pattern)
+	quoted = strings.ReplaceAll(quoted, `\*`, ".*")
+	quoted = strings.ReplaceAll(quoted, `\?`, ".")
+	return regexp.Compile("^(?s:" + quoted + ")$")
+}
+
+// fieldText returns strings as is and any other JSON value encoded
+func fieldText(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,144 @@
+package mockllm_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldMatching(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{
+			{
+				Name: "search",
+				Match: mockllm.OpenAIRequestMatch{
+					MatchType: mockllm.MatchTypeFields,
+					Fields: map[string]string{
+						"tools[*].function.name": "search_*",
+						"messages[-1].role":      "user",
+						"model":                  "/^gpt-4o/",
+						"temperature":            "0.2",
+					},
+				},
+			},
+			{
+				Name: "hello",
+				Match: mockllm.OpenAIRequestMatch{
+					MatchType: mockllm.MatchTypeContains,
+					Message:   openaiUserMessage("Hello"),
+					Fields:    map[string]string{"messages[0].content": "You are *"},
+				},
+			},
+		},
+		Anthropic: []mockllm.AnthropicMock{{
+			Name: "weather",
+			Match: mockllm.AnthropicRequestMatch{
+				MatchType: mockllm.MatchTypeFields,
+				Fields:    map[string]string{"tools[0].name": "get_weather", "max_tokens": "1024"},
+			},
+		}},
+	}))
+
+	explain := func(body any) map[string]mockllm.MatchExplanation {
+		encoded, err := json.Marshal(body)
+		require.NoError(t, err)
+		explanations, err := server.WhichMockMatches(encoded, nil)
+		require.NoError(t, err)
+		byName := map[string]mockllm.MatchExplanation{}
+		for _, explanation := range explanations {
+			byName[explanation.MockName] = explanation
+		}
+		return byName
+	}
+
+	searchTool := openai.ChatCompletionToolParam{Function: openai.FunctionDefinitionParam{Name: "search_docs"}}
+	request := openai.ChatCompletionNewParams{
+		Model:       "gpt-4o-mini",
+		Temperature: openai.Float(0.2),
+		Tools:       []openai.ChatCompletionToolParam{{Function: openai.FunctionDefinitionParam{Name: "get_time"}}, searchTool},
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage("You are a helpful assistant"),
+			openaiUserMessage("Hello"),
+		},
+	}
+	explanations := explain(request)
+	assert.True(t, explanations["search"].Matched, explanations["search"].Reason)
+	assert.True(t, explanations["hello"].Matched, explanations["hello"].Reason)
+
+	request.Model = "o1-mini"
+	request.Messages[0] = openai.SystemMessage("Be brief")
+	explanations = explain(request)
+	assert.Equal(t, `field model is o1-mini, want "/^gpt-4o/"`, explanations["search"].Reason)
+	assert.Equal(t, `field messages[0].content is Be brief, want "You are *"`, explanations["hello"].Reason)
+
+	request.Model, request.Tools = "gpt-4o", nil
+	assert.Equal(t, "field tools[*].function.name is missing", explain(request)["search"].Reason)
+
+	anthropicRequest := anthropic.MessageNewParams{
+		Model:     anthropic.ModelClaude3_5SonnetLatest,
+		MaxTokens: 1024,
+		Messages:  anthropicHelloRequest.Messages,
+		Tools:     []anthropic.ToolUnionParam{{OfTool: &anthropic.ToolParam{Name: "get_weather", InputSchema: anthropic.ToolInputSchemaParam{}}}},
+	}
+	assert.True(t, explain(anthropicRequest)["weather"].Matched)
+}
+
+func TestFieldCaptures(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{{
+			Name: "weather",
+			Match: mockllm.OpenAIRequestMatch{
+				MatchType: mockllm.MatchTypeFields,
+				Fields:    map[string]string{"messages[-1].content": `/weather in (?P<city>\w+)/`},
+			},
+			Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{
+				Content: "Checking the weather in {city}, {country}.",
+				ToolCalls: []openai.ChatCompletionMessageToolCall{{
+					ID:       "call_1",
+					Function: openai.ChatCompletionMessageToolCallFunction{Name: "get_weather", Arguments: `{"city": "{city}"}`},
+				}},
+			}}}},
+		}},
+		Anthropic: []mockllm.AnthropicMock{{
+			Name: "weather",
+			Match: mockllm.AnthropicRequestMatch{
+				MatchType: mockllm.MatchTypeFields,
+				Fields:    map[string]string{"messages[-1].content[0].text": `/weather in (?P<city>\w+)/`},
+			},
+			Response: anthropic.Message{Content: []anthropic.ContentBlockUnion{
+				{Type: "tool_use", ID: "toolu_1", Name: "get_weather", Input: json.RawMessage(`{"city": "{city}"}`)},
+			}},
+		}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+
+	completion := postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+		Model:    "gpt-4o",
+		Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("What's the weather in Paris?")},
+	})
+	require.Len(t, completion.Choices, 1)
+	message := completion.Choices[0].Message
+	assert.Equal(t, "Checking the weather in Paris, {country}.", message.Content, "unknown references are kept")
+	require.Len(t, message.ToolCalls, 1)
+	assert.JSONEq(t, `{"city": "Paris"}`, message.ToolCalls[0].Function.Arguments)
+
+	resp := postJSON(t, baseURL+"/v1/messages", anthropic.MessageNewParams{
+		Model:     "claude-sonnet-4-0",
+		MaxTokens: 100,
+		Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("How is the weather in Oslo today?"))},
+	}, anthropicHeaders("2023-06-01"))
+	defer resp.Body.Close() //nolint:errcheck
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var response anthropic.Message
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	require.Len(t, response.Content, 1)
+	assert.JSONEq(t, `{"city": "Oslo"}`, string(response.Content[0].Input))
+}
@@ -0,0 +1,138 @@
+package mockllm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// FixtureFormat is the language fixtures are exported for
+type FixtureFormat string
+
+const (
+	// FixtureFormatJSON writes one JSON file per fixture, for Python, TypeScript or any test suite
+	FixtureFormatJSON FixtureFormat = "json"
+	// FixtureFormatGo writes a single Go source file declaring the fixtures as a slice of structs
+	FixtureFormatGo FixtureFormat = "go"
+)
+
+// Fixture is a request a mock served and the response it got, exported to seed the tests of
+// other repos with recorded agent interactions
+type Fixture struct {
+	Name     string          `json:"name"`
+	Provider string          `json:"provider"`
+	Path     string          `json:"path"`
+	MockName string          `json:"mock_name,omitempty"`
+	Model    string          `json:"model,omitempty"`
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response"`
+}
+
+// NewFixtures turns the records of the request log a mock served into fixtures, skipping the
+// others. Fixtures are named after the record's ID and mock, so names are unique within a log.
+func NewFixtures(records []RequestRecord) []Fixture {
+	var fixtures []Fixture
+	for _, record := range records {
+		if len(record.Response) == 0 || len(record.Body) == 0 {
+			continue
+		}
+		fixtures = append(fixtures, Fixture{
+			Name:     fmt.Sprintf("%04d_%s", record.ID, fixtureSlug(record.MockName)),
+			Provider: record.Provider,
+			Path:     record.Path,
+			MockName: record.MockName,
+			Model:    record.Model,
+			Request:  record.Body,
+			Response: record.Response,
+		})
+	}
+	return fixtures
+}
+
+// ExportFixtures renders the served requests of the request log as fixture files in the format,
+// keyed by file name. goPackage names the package of Go fixtures, "fixtures" when empty.
+func ExportFixtures(records []RequestRecord, fixtureFormat FixtureFormat, goPackage string) (map[string][]byte, error) {
+	fixtures := NewFixtures(records)
+	switch fixtureFormat {
+	case FixtureFormatJSON:
+		files := make(map[string][]byte, len(fixtures))
+		for _, fixture := range fixtures {
+			data, err := json.MarshalIndent(fixture, "", "  ")
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode fixture %s: %w", fixture.Name, err)
+			}
+			files[fixture.Name+".json"] = append(data, '\n')
+		}
+		return files, nil
+	case FixtureFormatGo:
+		if goPackage == "" {
+			goPackage = "fixtures"
+		}
+		source, err := goFixtures(fixtures, goPackage)
+		if err != nil {
+			return nil, err
+		}
+		return map[string][]byte{"fixtures.go": source}, nil
+	default:
+		return nil, fmt.Errorf("unknown fixture format %q, expected %s or %s", fixtureFormat, FixtureFormatJSON, FixtureFormatGo)
+	}
+}
+
+// goFixtures renders the fixtures as a gofmt'ed Go source file of the package
+func goFixtures(fixtures []Fixture, goPackage string) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by mockllm export. DO NOT EDIT.\n\npackage %s\n\n", goPackage)
+	b.WriteString("import \"encoding/json\"\n\n")
+	b.WriteString("// Fixture is a request a mock served and the response it got\n")
+	b.WriteString("type Fixture struct {\n\tName string\n\tProvider string\n\tPath string\n\tMockName string\n" +
+		"\tModel string\n\tRequest json.RawMessage\n\tResponse json.RawMessage\n}\n\n")
+	b.WriteString("// Fixtures are the recorded requests, in the order their handling ended\n")
+	b.WriteString("var Fixtures = []Fixture{\n")
+	for _, fixture := range fixtures {
+		fmt.Fprintf(&b, "{\nName: %q,\nProvider: %q,\nPath: %q,\nMockName: %q,\nModel: %q,\n",
+			fixture.Name, fixture.Provider, fixture.Path, fixture.MockName, fixture.Model)
+		fmt.Fprintf(&b, "Request: json.RawMessage(%s),\nResponse: json.RawMessage(%s),\n},\n",
+			goStringLiteral(fixture.Request), goStringLiteral(fixture.Response))
+	}
+	b.WriteString("}\n")
+
+	source, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format Go fixtures: %w", err)
+	}
+	return source, nil
+}
+
+// goStringLiteral returns indented JSON as a raw string literal, or an interpreted one when the
+// JSON contains a backquote
+func goStringLiteral(data json.RawMessage) string {
+	var indented bytes.Buffer
+	if json.Indent(&indented, data, "", "  ") == nil {
+		data = indented.Bytes()
+	}
+	if bytes.ContainsAny(data, "`\r") {
+		return strconv.Quote(string(data))
+	}
+	return "`" + string(data) + "`"
+}
+
+// fixtureSlug reduces a mock name to lowercase letters, digits and underscores, safe in file names
+func fixtureSlug(name string) string {
+	slug := strings.Map(func(r rune) rune {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			return unicode.ToLower(r)
+		default:
+			return '_'
+		}
+	}, name)
+	slug = strings.Trim(slug, "_")
+	if slug == "" {
+		return "request"
+	}
+	return slug
+}
@@ -0,0 +1,81 @@
+package mockllm_test
+
+import (
+	"context"
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportFixtures(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{{
+			Name:     "Say hello",
+			Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+			Response: openai.ChatCompletion{ID: "chatcmpl-1", Object: "chat.completion"},
+		}},
+		Anthropic: []mockllm.AnthropicMock{{
+			Name: "hello",
+			Match: mockllm.AnthropicRequestMatch{
+				MatchType: mockllm.MatchTypeContains,
+				Message:   anthropic.NewUserMessage(anthropic.NewTextBlock("Hello")),
+			},
+			Response: anthropic.Message{ID: "msg_1", Content: []anthropic.ContentBlockUnion{{Type: "text", Text: "Use `go test`"}}},
+		}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	headers := map[string]string{"Authorization": "Bearer test-key"}
+	for _, content := range []string{"Hello", "Goodbye"} {
+		postJSON(t, baseURL+"/v1/chat/completions", openai.ChatCompletionNewParams{
+			Model:    "gpt-4o",
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage(content)},
+		}, headers).Body.Close() //nolint:errcheck
+	}
+	postJSON(t, baseURL+"/v1/messages", anthropicHelloRequest, anthropicHeaders("2023-06-01")).Body.Close() //nolint:errcheck
+
+	t.Run("json", func(t *testing.T) {
+		files, err := mockllm.ExportFixtures(server.Requests(), mockllm.FixtureFormatJSON, "")
+		require.NoError(t, err)
+		require.Len(t, files, 2, "the unmatched request is left out")
+
+		var fixture mockllm.Fixture
+		require.NoError(t, json.Unmarshal(files["0001_say_hello.json"], &fixture))
+		assert.Equal(t, "openai", fixture.Provider)
+		assert.Equal(t, "/v1/chat/completions", fixture.Path)
+		assert.Equal(t, "gpt-4o", fixture.Model)
+		assert.Contains(t, string(fixture.Request), `"Hello"`)
+		assert.Contains(t, string(fixture.Response), `"chatcmpl-1"`)
+
+		require.NoError(t, json.Unmarshal(files["0003_hello.json"], &fixture))
+		assert.Equal(t, "anthropic", fixture.Provider)
+		assert.Contains(t, string(fixture.Response), `"msg_1"`)
+	})
+
+	t.Run("go", func(t *testing.T) {
+		files, err := mockllm.ExportFixtures(server.Requests(), mockllm.FixtureFormatGo, "agentfixtures")
+		require.NoError(t, err)
+		source := files["fixtures.go"]
+		require.NotEmpty(t, source)
+
+		file, err := parser.ParseFile(token.NewFileSet(), "fixtures.go", source, 0)
+		require.NoError(t, err)
+		assert.Equal(t, "agentfixtures", file.Name.Name)
+		assert.Contains(t, string(source), `Name:     "0001_say_hello"`)
+		assert.Contains(t, string(source), "\\\"Use `go test`\\\"", "JSON with backquotes is quoted")
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		_, err := mockllm.ExportFixtures(server.Requests(), "yaml", "")
+		assert.ErrorContains(t, err, `unknown fixture format "yaml"`)
+	})
+}
@@ -0,0 +1,30 @@
+package mockllm
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// ForceMockHeader names the mock serving a request, whether it matches or not, bypassing
+	// matching for that request alone
+	ForceMockHeader = "X-Mockllm-Force-Mock"
+	// ForceErrorHeader fails a request with the provider's error for the given status, e.g. 429 or
+	// 529, so client-side tests can inject faults without changing the state of the server
+	ForceErrorHeader = "X-Mockllm-Force-Error"
+)
+
+// forcedError returns the status the ForceErrorHeader of a request fails it with, zero when it has
+// none
+func forcedError(header http.Header) (int, error) {
+	value := header.Get(ForceErrorHeader)
+	if value == "" {
+		return 0, nil
+	}
+	status, err := strconv.Atoi(value)
+	if err != nil || status < 400 || status > 599 {
+		return 0, fmt.Errorf("%s must be an error status between 400 and 599, got %q", ForceErrorHeader, value)
+	}
+	return status, nil
+}
@@ -0,0 +1,94 @@
+package mockllm_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForceHeaders(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{
+			{
+				Name:     "hello",
+				Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+				Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "Hi"}}}},
+			},
+			{
+				Name:     "refusal",
+				Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Forbidden")},
+				Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Refusal: "I can't help with that."}}}},
+			},
+		},
+		Anthropic: []mockllm.AnthropicMock{{
+			Name:     "hello",
+			Match:    mockllm.AnthropicRequestMatch{MatchType: mockllm.MatchTypeExact, Message: anthropicHelloRequest.Messages[0]},
+			Response: anthropic.Message{Content: []anthropic.ContentBlockUnion{{Type: "text", Text: "Hi!"}}},
+		}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	hello := openai.ChatCompletionNewParams{
+		Model:    "gpt-4o-mini",
+		Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+	}
+	post := func(header, value string) *http.Response {
+		return postJSON(t, baseURL+"/v1/chat/completions", hello, map[string]string{
+			"Authorization": "Bearer test-key",
+			header:          value,
+		})
+	}
+
+	resp := post(mockllm.ForceMockHeader, "refusal")
+	defer resp.Body.Close() //nolint:errcheck
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var completion openai.ChatCompletion
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&completion))
+	assert.Equal(t, "I can't help with that.", completion.Choices[0].Message.Refusal, "the forced mock serves the request, matching or not")
+
+	resp = post(mockllm.ForceMockHeader, "missing")
+	defer resp.Body.Close() //nolint:errcheck
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = post(mockllm.ForceErrorHeader, "429")
+	defer resp.Body.Close() //nolint:errcheck
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	var openaiError struct {
+		Error struct {
+			Type string `json:"type"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&openaiError))
+	assert.Equal(t, "requests", openaiError.Error.Type)
+
+	resp = post(mockllm.ForceErrorHeader, "200")
+	defer resp.Body.Close() //nolint:errcheck
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "only error statuses can be forced")
+
+	headers := anthropicHeaders("2023-06-01")
+	headers[mockllm.ForceErrorHeader] = "529"
+	resp = postJSON(t, baseURL+"/v1/messages", anthropicHelloRequest, headers)
+	defer resp.Body.Close() //nolint:errcheck
+	require.Equal(t, 529, resp.StatusCode)
+	var anthropicError struct {
+		Error struct {
+			Type string `json:"type"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&anthropicError))
+	assert.Equal(t, "overloaded_error", anthropicError.Error.Type)
+
+	assert.Equal(t, "Hi", postChatCompletion(t, baseURL, hello).Choices[0].Message.Content, "the next requests match as usual")
+	records := server.Requests()
+	assert.Equal(t, "refusal", records[0].MockName)
+	assert.Equal(t, "forced error", records[2].Error)
+}
@@ -0,0 +1,246 @@
+package mockllm
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+)
+
+// FuzzCase is a kind of adversarial response served in fuzz mode
+type FuzzCase string
+
+const (
+	// FuzzUnicode replies with text full of unicode edge cases: NUL, BOM, line and paragraph
+	// separators, combining marks, right-to-left text, emoji sequences and noncharacters
+	FuzzUnicode FuzzCase = "unicode"
+	// FuzzHugeNumbers reports token counts and timestamps beyond the integers a float64 holds exactly
+	FuzzHugeNumbers FuzzCase = "huge_numbers"
+	// FuzzDeepNesting replies with a tool call whose arguments nest objects 512 levels deep
+	FuzzDeepNesting FuzzCase = "deep_nesting"
+	// FuzzEmptyChoices replies without any choices or content blocks
+	FuzzEmptyChoices FuzzCase = "empty_choices"
+	// FuzzEmptyContent replies with empty text
+	FuzzEmptyContent FuzzCase = "empty_content"
+	// FuzzLongContent replies with a single 1 MiB word, longer than the default line buffers of SSE readers
+	FuzzLongContent FuzzCase = "long_content"
+)
+
+// FuzzCases are all the fuzz cases, in the order they are drawn from
+var FuzzCases = []FuzzCase{FuzzUnicode, FuzzHugeNumbers, FuzzDeepNesting, FuzzEmptyChoices, FuzzEmptyContent, FuzzLongContent}
+
+// FuzzConfig turns a mock's response into a schema-valid but adversarial one, to stress the
+// parsing of clients. Every request draws a case from the seed and the number of requests the mock
+// served before, so a run with the same seed and request order serves the same cases.
+type FuzzConfig struct {
+	Seed uint64 `json:"seed,omitempty"`
+	// Cases limits the drawn cases, e.g. to replay a failing one. Defaults to FuzzCases.
+	Cases []FuzzCase `json:"cases,omitempty"`
+}
+
+// FuzzRecord is the fuzz case served for a request
+type FuzzRecord struct {
+	Seed uint64 `json:"seed"`
+	// Iteration is the number of requests the mock fuzzed before this one
+	Iteration int      `json:"iteration"`
+	Case      FuzzCase `json:"case"`
+}
+
+// fuzzIterations counts the fuzzed requests of every mock
+type fuzzIterations struct {
+	mu   sync.Mutex
+	next map[string]int
+}
+
+func newFuzzIterations() *fuzzIterations {
+	return &fuzzIterations{next: map[string]int{}}
+}
+
+// draw picks the case of the next request of a mock, returning it with a random source for its content
+func (f *fuzzIterations) draw(mockName string, config FuzzConfig) (*FuzzRecord, *rand.Rand) {
+	f.mu.Lock()
+	iteration := f.next[mockName]
+	f.next[mockName]++
+	f.mu.Unlock()
+
+	rng := rand.New(rand.NewPCG(config.Seed, uint64(iteration)))
+	cases := config.Cases
+	if len(cases) == 0 {
+		cases = FuzzCases
+	}
+	return &FuzzRecord{Seed: config.Seed, Iteration: iteration, Case: cases[rng.IntN(len(cases))]}, rng
+}
+
+// fuzzIssues reports the unknown cases of a fuzz config
+func fuzzIssues(config *FuzzConfig, path string) []configIssue {
+	if config == nil {
+		return nil
+	}
+	var issues []configIssue
+	for i, c := range config.Cases {
+		if !slices.Contains(FuzzCases, c) {
+			issues = append(issues, configIssue{fmt.Sprintf("%s.cases[%d]", path, i), fmt.Sprintf("unknown fuzz case %q", c)})
+		}
+	}
+	return issues
+}
+
+// fuzzUnicode are the unicode edge cases of FuzzUnicode replies
+var fuzzUnicode = []string{
+	"\x00", "\uFEFF", "\u2028", "\u2029", "e\u0301\u0302\u0303", "\u202Eevil\u202C",
+	"\u05E9\u05DC\u05D5\u05DD", "\U0001F469\u200D\U0001F469\u200D\U0001F467", "\U0001F1FA\U0001F1F3", "\uFFFE", "\U0010FFFF", "\u200B\u200D",
+	"\"\\/</script>", "\r\n\r\n", "data: [DONE]",
+}
+
+// fuzzHugeNumber is the first integer a float64 cannot represent, so JavaScript clients round it
+const fuzzHugeNumber = 1<<53 + 1
+
+// fuzzText returns the text of a text case
+func fuzzText(c FuzzCase, rng *rand.Rand) string {
+	switch c {
+	case FuzzUnicode:
+		parts := slices.Clone(fuzzUnicode)
+		rng.Shuffle(len(parts), func(i, j int) { parts[i], parts[j] = parts[j], parts[i] })
+		return strings.Join(parts, " ")
+	case FuzzLongContent:
+		return strings.Repeat("a", 1<<20)
+	}
+	return ""
+}
+
+// fuzzArguments returns a JSON object nesting another object under "a" 512 levels deep
+func fuzzArguments() string {
+	return strings.Repeat(`{"a":`, 512) + "{}" + strings.Repeat("}", 512)
+}
+
+// fuzzOpenAIResponse turns a response into the drawn fuzz case
+func fuzzOpenAIResponse(fuzz *FuzzRecord, rng *rand.Rand, mockName string, request openai.ChatCompletionNewParams,
+	response *openai.ChatCompletion) {
+	switch fuzz.Case {
+	case FuzzUnicode, FuzzEmptyContent, FuzzLongContent:
+		setOpenAIContent(response, fuzzText(fuzz.Case, rng))
+		for i := range response.Choices {
+			response.Choices[i].Message.ToolCalls = nil
+			response.Choices[i].FinishReason = "stop"
+		}
+	case FuzzHugeNumbers:
+		response.Created = fuzzHugeNumber
+		response.Usage.PromptTokens, response.Usage.CompletionTokens = fuzzHugeNumber, fuzzHugeNumber
+		response.Usage.TotalTokens = fuzzHugeNumber
+	case FuzzDeepNesting:
+		name := "fuzz"
+		if len(request.Tools) > 0 {
+			name = request.Tools[0].Function.Name
+		}
+		setOpenAIToolCall(response, openai.ChatCompletionMessageToolCall{
+			ID:       mockResponseID("call_", mockName+"/"+name),
+			Type:     "function",
+			Function: openai.ChatCompletionMessageToolCallFunction{Name: name, Arguments: fuzzArguments()},
+		})
+	case FuzzEmptyChoices:
+		response.Choices = []openai.ChatCompletionChoice{}
+	}
+}
+
+// fuzzAnthropicResponse turns a message into the drawn fuzz case
+func fuzzAnthropicResponse(fuzz *FuzzRecord, rng *rand.Rand, mockName string, request anthropic.MessageNewParams,
+	response *anthropic.Message) {
+	switch fuzz.Case {
+	case FuzzUnicode, FuzzEmptyContent, FuzzLongContent:
+		setAnthropicContent(response, fuzzText(fuzz.Case, rng))
+		response.StopReason = anthropic.StopReasonEndTurn
+	case FuzzHugeNumbers:
+		response.Usage.InputTokens, response.Usage.OutputTokens = fuzzHugeNumber, fuzzHugeNumber
+	case FuzzDeepNesting:
+		name := "fuzz"
+		if len(request.Tools) > 0 && request.Tools[0].GetName() != nil {
+			name = *request.Tools[0].GetName()
+		}
+		setAnthropicToolUse(response, anthropic.ContentBlockUnion{
+			Type:  "tool_use",
+			ID:    mockResponseID("toolu_", mockName+"/"+name),
+			Name:  name,
+			Input: json.RawMessage(fuzzArguments()),
+		})
+	case FuzzEmptyChoices:
+		response.Content = []anthropic.ContentBlockUnion{}
+	}
+}
+
+// fuzzResponse turns the response of a fuzzed mock into the case drawn for the request
+func (p *OpenAIProvider) fuzzResponse(mock *OpenAIMock, request openai.ChatCompletionNewParams,
+	response *openai.ChatCompletion) *FuzzRecord {
+	if mock.Fuzz == nil {
+		return nil
+	}
+	fuzz, rng := p.fuzz.draw(mock.Name, *mock.Fuzz)
+	fuzzOpenAIResponse(fuzz, rng, mock.Name, request, response)
+	return fuzz
+}
+
+// fuzzResponse turns the response of a fuzzed mock into the case drawn for the request
+func (p *AnthropicProvider) fuzzResponse(mock *AnthropicMock, request anthropic.MessageNewParams,
+	response *anthropic.Message) *FuzzRecord {
+	if mock.Fuzz == nil {
+		return nil
+	}
+	fuzz, rng := p.fuzz.draw(mock.Name, *mock.Fuzz)
+	fuzzAnthropicResponse(fuzz, rng, mock.Name, request, response)
+	return fuzz
+}
+
+// FuzzSummary counts the fuzz cases a mock served
+type FuzzSummary struct {
+	Provider string           `json:"provider"`
+	Tenant   string           `json:"tenant,omitempty"`
+	MockName string           `json:"mock_name"`
+	Seed     uint64           `json:"seed"`
+	Requests int              `json:"requests"`
+	Cases    map[FuzzCase]int `json:"cases"`
+}
+
+// Fuzz returns the fuzz cases served by every fuzzed mock, ordered by provider, tenant and mock
+func (l *RequestLog) Fuzz() []FuzzSummary {
+	type fuzzKey struct{ provider, tenant, mockName string }
+	totals := map[fuzzKey]*FuzzSummary{}
+	for _, record := range l.Records() {
+		if record.Fuzz == nil {
+			continue
+		}
+		key := fuzzKey{record.Provider, record.Tenant, record.MockName}
+		summary, ok := totals[key]
+		if !ok {
+			summary = &FuzzSummary{Provider: key.provider, Tenant: key.tenant, MockName: key.mockName,
+				Seed: record.Fuzz.Seed, Cases: map[FuzzCase]int{}}
+			totals[key] = summary
+		}
+		summary.Requests++
+		summary.Cases[record.Fuzz.Case]++
+	}
+
+	summaries := make([]FuzzSummary, 0, len(totals))
+	for _, summary := range totals {
+		summaries = append(summaries, *summary)
+	}
+	slices.SortFunc(summaries, func(a, b FuzzSummary) int {
+		return cmp.Or(cmp.Compare(a.Provider, b.Provider), cmp.Compare(a.Tenant, b.Tenant), cmp.Compare(a.MockName, b.MockName))
+	})
+	return summaries
+}
+
+// FuzzReport returns the fuzz cases served so far by every fuzzed mock. The request log has the
+// case of every request.
+func (s *Server) FuzzReport() []FuzzSummary {
+	return s.requestLog.Fuzz()
+}
+
+func (s *Server) handleAdminFuzz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.FuzzReport())
+}
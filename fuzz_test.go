@@ -0,0 +1,128 @@
+package mockllm_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzMode(t *testing.T) {
+	config := mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{{
+			Name:  "fuzz",
+			Match: mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+			Fuzz:  &mockllm.FuzzConfig{Seed: 42},
+		}},
+		Anthropic: []mockllm.AnthropicMock{{
+			Name:  "fuzz",
+			Match: mockllm.AnthropicRequestMatch{MatchType: mockllm.MatchTypeExact, Message: anthropicHelloRequest.Messages[0]},
+			Fuzz:  &mockllm.FuzzConfig{Seed: 42, Cases: []mockllm.FuzzCase{mockllm.FuzzDeepNesting}},
+		}},
+	}
+	serve := func(requests int) (*mockllm.Server, string, []openai.ChatCompletion) {
+		server := mockllm.NewServer(mockllm.WithConfig(config))
+		baseURL, err := server.Start(t.Context())
+		require.NoError(t, err)
+		t.Cleanup(func() { server.Stop(context.Background()) }) //nolint:errcheck
+
+		completions := make([]openai.ChatCompletion, requests)
+		for i := range completions {
+			completions[i] = postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+				Model:    "gpt-4o-mini",
+				Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+			})
+		}
+		return server, baseURL, completions
+	}
+
+	// Seed 42 draws every case within its first ten requests
+	server, _, completions := serve(10)
+	served := map[mockllm.FuzzCase]bool{}
+	for i, record := range server.Requests() {
+		require.NotNil(t, record.Fuzz)
+		assert.Equal(t, i, record.Fuzz.Iteration)
+		served[record.Fuzz.Case] = true
+
+		completion := completions[i]
+		switch record.Fuzz.Case {
+		case mockllm.FuzzEmptyChoices:
+			assert.Empty(t, completion.Choices)
+		case mockllm.FuzzEmptyContent:
+			assert.Empty(t, completion.Choices[0].Message.Content)
+		case mockllm.FuzzLongContent:
+			assert.Len(t, completion.Choices[0].Message.Content, 1<<20)
+		case mockllm.FuzzHugeNumbers:
+			assert.Equal(t, int64(1<<53+1), completion.Usage.TotalTokens)
+		case mockllm.FuzzUnicode:
+			assert.Contains(t, completion.Choices[0].Message.Content, "\u2028")
+			assert.Contains(t, completion.Choices[0].Message.Content, "\x00")
+		case mockllm.FuzzDeepNesting:
+			arguments := completion.Choices[0].Message.ToolCalls[0].Function.Arguments
+			assert.True(t, json.Valid([]byte(arguments)))
+			assert.Equal(t, 512, strings.Count(arguments, `{"a":`))
+		}
+	}
+	assert.Len(t, served, len(mockllm.FuzzCases), "every case is drawn")
+
+	report := server.FuzzReport()
+	require.Len(t, report, 1)
+	assert.Equal(t, "fuzz", report[0].MockName)
+	assert.Equal(t, uint64(42), report[0].Seed)
+	assert.Equal(t, 10, report[0].Requests)
+
+	// The same seed serves the same cases in the same order
+	replay, _, _ := serve(10)
+	for i, record := range replay.Requests() {
+		assert.Equal(t, server.Requests()[i].Fuzz, record.Fuzz)
+	}
+
+	// Every case streams
+	streams, baseURL, _ := serve(0)
+	for range 10 {
+		resp := postJSON(t, baseURL+"/v1/chat/completions", json.RawMessage(`{"model":"gpt-4o-mini","stream":true,"messages":[{"role":"user","content":"Hello"}]}`),
+			map[string]string{"Authorization": "Bearer test-key"})
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close() //nolint:errcheck
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.True(t, strings.HasSuffix(string(body), "data: [DONE]\n\n"))
+	}
+	streamed := map[mockllm.FuzzCase]bool{}
+	for _, record := range streams.Requests() {
+		streamed[record.Fuzz.Case] = true
+	}
+	assert.Len(t, streamed, len(mockllm.FuzzCases), "every case is streamed")
+
+	// Cases can be limited, here for Anthropic
+	resp := postJSON(t, baseURL+"/v1/messages", anthropicHelloRequest, anthropicHeaders("2023-06-01"))
+	defer resp.Body.Close() //nolint:errcheck
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var message anthropic.Message
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&message))
+	require.Len(t, message.Content, 1)
+	assert.Equal(t, "tool_use", message.Content[0].Type)
+	assert.Equal(t, anthropic.StopReasonToolUse, message.StopReason)
+}
+
+func TestFuzzConfigValidation(t *testing.T) {
+	filesys := fstest.MapFS{"mocks.json": {Data: []byte(`{
+  "openai": [{
+    "name": "fuzz",
+    "match": {"match_type": "contains", "message": {"role": "user", "content": "Hello"}},
+    "fuzz": {"seed": 1, "cases": ["unicode", "binary"]}
+  }]
+}`)}}
+	_, err := mockllm.LoadConfigFromFile("mocks.json", filesys)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `openai[0].fuzz.cases[1]: unknown fuzz case "binary"`)
+}
@@ -0,0 +1,301 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// GeminiPart is a part of the content of a Gemini message: text, a function call the model
+// makes, or the response to one sent back by the client
+type GeminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	FunctionCall     *GeminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+// GeminiFunctionCall is a call of a declared function, made by the model
+type GeminiFunctionCall struct {
+	Name string         `json:"name"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+// GeminiFunctionResponse is the result of a function call, sent by the client
+type GeminiFunctionResponse struct {
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response,omitempty"`
+}
+
+// GeminiContent is a message of a Gemini conversation
+type GeminiContent struct {
+	// Role is user or model
+	Role  string       `json:"role,omitempty"`
+	Parts []GeminiPart `json:"parts"`
+}
+
+// GeminiSafetyRating is the probability of a response or prompt being harmful in a category,
+// e.g. HARM_CATEGORY_DANGEROUS_CONTENT with a probability of HIGH
+type GeminiSafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked,omitempty"`
+}
+
+// GeminiCandidate is a candidate response of the model
+type GeminiCandidate struct {
+	Content GeminiContent `json:"content"`
+	// FinishReason defaults to STOP. Set it to SAFETY along with blocked SafetyRatings to simulate
+	// a response withheld by the safety filters.
+	FinishReason  string               `json:"finishReason,omitempty"`
+	Index         int                  `json:"index"`
+	SafetyRatings []GeminiSafetyRating `json:"safetyRatings,omitempty"`
+}
+
+// GeminiPromptFeedback is the safety feedback on the prompt. With a BlockReason, e.g. SAFETY,
+// the prompt was blocked and the response has no candidates.
+type GeminiPromptFeedback struct {
+	BlockReason   string               `json:"blockReason,omitempty"`
+	SafetyRatings []GeminiSafetyRating `json:"safetyRatings,omitempty"`
+}
+
+// GeminiUsageMetadata is the token usage of a Gemini response
+type GeminiUsageMetadata struct {
+	PromptTokenCount     int64 `json:"promptTokenCount"`
+	CandidatesTokenCount int64 `json:"candidatesTokenCount"`
+	TotalTokenCount      int64 `json:"totalTokenCount"`
+}
+
+// GeminiResponse is the response to a generateContent request
+type GeminiResponse struct {
+	Candidates     []GeminiCandidate     `json:"candidates,omitempty"`
+	PromptFeedback *GeminiPromptFeedback `json:"promptFeedback,omitempty"`
+	UsageMetadata  *GeminiUsageMetadata  `json:"usageMetadata,omitempty"`
+	// ModelVersion defaults to the model of the request
+	ModelVersion string `json:"modelVersion,omitempty"`
+}
+
+// GeminiRequest holds the parts of a generateContent request mocks are matched against
+type GeminiRequest struct {
+	Contents          []GeminiContent `json:"contents"`
+	SystemInstruction *GeminiContent  `json:"systemInstruction,omitempty"`
+}
+
+// GeminiFunctionResponseMatch matches a functionResponse part of the last content of a request
+type GeminiFunctionResponseMatch struct {
+	// Name is the name of the function the response is for
+	Name string `json:"name"`
+	// Fields are patterns for fields of the function's response, as for the fields match type,
+	// e.g. {"temperature": "/^2\\d$/"}
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// GeminiRequestMatch matches the last content of a generateContent request
+type GeminiRequestMatch struct {
+	// MatchType is exact (the text of the last content equals Text) or contains (it contains Text)
+	MatchType MatchType `json:"match_type,omitempty"`
+	Text      string    `json:"text,omitempty"`
+	// FunctionResponse requires the last content to carry the response to a function call
+	FunctionResponse *GeminiFunctionResponseMatch `json:"function_response,omitempty"`
+}
+
+// GeminiMock maps a Gemini generateContent request to a response
+type GeminiMock struct {
+	Name     string             `json:"name"`
+	Match    GeminiRequestMatch `json:"match"`
+	Response GeminiResponse     `json:"response"`
+}
+
+// geminiIssues reports Gemini mocks with invalid match criteria
+func geminiIssues(mocks []GeminiMock) []configIssue {
+	var issues []configIssue
+	for i, mock := range mocks {
+		path := fmt.Sprintf("gemini[%d].match", i)
+		switch mock.Match.MatchType {
+		case MatchTypeExact, MatchTypeContains:
+		case "":
+			if mock.Match.FunctionResponse == nil {
+				issues = append(issues, configIssue{path + ".match_type", "must be set without function_response"})
+			}
+		default:
+			issues = append(issues, configIssue{path + ".match_type", fmt.Sprintf("must be exact or contains, got %q", mock.Match.MatchType)})
+		}
+		if mock.Match.FunctionResponse != nil && mock.Match.FunctionResponse.Name == "" {
+			issues = append(issues, configIssue{path + ".function_response.name", "must not be empty"})
+		}
+	}
+	return issues
+}
+
+// GeminiProvider emulates the generateContent and streamGenerateContent methods of the Gemini API
+type GeminiProvider struct {
+	mocks []GeminiMock
+	log   *RequestLog
+	clock Clock
+}
+
+// NewGeminiProvider creates a new GeminiProvider with the given mocks
+func NewGeminiProvider(mocks []GeminiMock) *GeminiProvider {
+	return &GeminiProvider{mocks: mocks, clock: systemClock{}}
+}
+
+// Routes returns the model method endpoints, e.g. /v1beta/models/gemini-2.0-flash:generateContent.
+// The model and method share a path segment, so they are told apart by the handler.
+func (p *GeminiProvider) Routes() []Route {
+	return []Route{
+		{Method: http.MethodPost, Path: "/v1beta/models/{action}"},
+		{Method: http.MethodPost, Path: "/v1/models/{action}"},
+	}
+}
+
+// geminiInterruptedEvent ends streams cut short by a shutdown
+var geminiInterruptedEvent = newSSEEvent("", map[string]any{
+	"error": map[string]any{"code": http.StatusServiceUnavailable, "message": "The server is shutting down", "status": "UNAVAILABLE"},
+})
+
+// Handle serves generateContent and streamGenerateContent requests
+func (p *GeminiProvider) Handle(w http.ResponseWriter, r *http.Request) {
+	model, method, _ := strings.Cut(r.PathValue("action"), ":")
+	if method != "generateContent" && method != "streamGenerateContent" {
+		writeGeminiError(w, http.StatusNotFound, fmt.Sprintf("Method %q is not supported by the mock.", method))
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeGeminiError(w, http.StatusBadRequest, fmt.Sprintf("Failed to read request body: %v", err))
+		return
+	}
+	record := p.log.newRecord(r, providerGemini, body)
+	record.Model = model
+	defer func() { p.log.Add(&record) }()
+
+	var request GeminiRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		record.Status, record.Error = http.StatusBadRequest, err.Error()
+		writeGeminiError(w, record.Status, fmt.Sprintf("Invalid JSON payload received: %v", err))
+		return
+	}
+	if len(request.Contents) == 0 {
+		record.Status, record.Error = http.StatusBadRequest, "contents is not specified"
+		writeGeminiError(w, record.Status, "* GenerateContentRequest.contents: contents is not specified")
+		return
+	}
+
+	mock := p.findMatchingMock(request)
+	if mock == nil {
+		record.Status, record.Error = http.StatusNotFound, "no matching mock"
+		writeGeminiError(w, record.Status, "No matching mock found")
+		return
+	}
+	record.Matched, record.MockName = true, mock.Name
+	done := p.log.serving(providerGemini, "", mock.Name)
+	defer done()
+
+	response := geminiResponseFor(*mock, model)
+	record.Response, _ = json.Marshal(response)
+	if usage := response.UsageMetadata; usage != nil {
+		record.Usage = &TokenUsage{InputTokens: usage.PromptTokenCount, OutputTokens: usage.CandidatesTokenCount}
+	}
+	record.Status = http.StatusOK
+	if method == "streamGenerateContent" {
+		// Streams are sent as server-sent events with ?alt=sse, and as a JSON array of chunks
+		// otherwise. The mock sends the whole response as a single chunk.
+		if r.URL.Query().Get("alt") == "sse" {
+			record.Stream = writeSSE(w, r, p.clock, []sseEvent{{Data: record.Response}}, ssePacing{}, geminiInterruptedEvent)
+			return
+		}
+		writeJSON(w, http.StatusOK, []GeminiResponse{response})
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// findMatchingMock finds the first mock that matches the last content of the request
+func (p *GeminiProvider) findMatchingMock(request GeminiRequest) *GeminiMock {
+	last := request.Contents[len(request.Contents)-1]
+	for _, mock := range p.mocks {
+		if mock.Match.matches(last) {
+			return &mock
+		}
+	}
+	return nil
+}
+
+// matches reports whether a content meets the criteria
+func (m GeminiRequestMatch) matches(content GeminiContent) bool {
+	if m.FunctionResponse != nil && !m.FunctionResponse.matches(content) {
+		return false
+	}
+	text := geminiContentText(content)
+	switch m.MatchType {
+	case MatchTypeExact:
+		return text == m.Text
+	case MatchTypeContains:
+		return strings.Contains(text, m.Text)
+	case "":
+		// Function response criteria alone match any text
+		return m.FunctionResponse != nil
+	default:
+		return false
+	}
+}
+
+// matches reports whether a content carries a response to the function with matching fields
+func (m GeminiFunctionResponseMatch) matches(content GeminiContent) bool {
+	for _, part := range content.Parts {
+		if part.FunctionResponse == nil || part.FunctionResponse.Name != m.Name {
+			continue
+		}
+		if mismatchedField(part.FunctionResponse.Response, m.Fields) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// geminiContentText joins the text parts of a content
+func geminiContentText(content GeminiContent) string {
+	var text strings.Builder
+	for _, part := range content.Parts {
+		text.WriteString(part.Text)
+	}
+	return text.String()
+}
+
+// geminiResponseFor fills in the fields of a mock's response real responses always carry
+func geminiResponseFor(mock GeminiMock, model string) GeminiResponse {
+	response := mock.Response
+	response.Candidates = make([]GeminiCandidate, len(mock.Response.Candidates))
+	for i, candidate := range mock.Response.Candidates {
+		candidate.Index = i
+		if candidate.Content.Role == "" {
+			candidate.Content.Role = "model"
+		}
+		if candidate.FinishReason == "" {
+			candidate.FinishReason = "STOP"
+		}
+		response.Candidates[i] = candidate
+	}
+	if response.ModelVersion == "" {
+		response.ModelVersion = model
+	}
+	return response
+}
+
+// geminiStatuses maps HTTP statuses to the status names of Gemini errors
+var geminiStatuses = map[int]string{
+	http.StatusBadRequest:          "INVALID_ARGUMENT",
+	http.StatusNotFound:            "NOT_FOUND",
+	http.StatusTooManyRequests:     "RESOURCE_EXHAUSTED",
+	http.StatusServiceUnavailable:  "UNAVAILABLE",
+	http.StatusInternalServerError: "INTERNAL",
+}
+
+// writeGeminiError writes an error in the Gemini API error envelope
+func writeGeminiError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{
+		"error": map[string]any{"code": status, "message": message, "status": geminiStatuses[status]},
+	})
+}
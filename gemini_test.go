@@ -0,0 +1,223 @@
+package mockllm_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newGeminiServer(t *testing.T, mocks ...mockllm.GeminiMock) string {
+	t.Helper()
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{Gemini: mocks}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+	return baseURL
+}
+
+func geminiUserText(text string) map[string]any {
+	return map[string]any{"contents": []map[string]any{{"role": "user", "parts": []map[string]any{{"text": text}}}}}
+}
+
+func decodeGeminiResponse(t *testing.T, resp *http.Response) mockllm.GeminiResponse {
+	t.Helper()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var response mockllm.GeminiResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	return response
+}
+
+func TestGeminiFunctionCalling(t *testing.T) {
+	baseURL := newGeminiServer(t,
+		mockllm.GeminiMock{
+			Name: "weather-result",
+			Match: mockllm.GeminiRequestMatch{FunctionResponse: &mockllm.GeminiFunctionResponseMatch{
+				Name:   "get_weather",
+				Fields: map[string]string{"temperature": "/^2\\d$/"},
+			}},
+			Response: mockllm.GeminiResponse{Candidates: []mockllm.GeminiCandidate{{
+				Content: mockllm.GeminiContent{Parts: []mockllm.GeminiPart{{Text: "It is warm in Paris"}}},
+			}}},
+		},
+		mockllm.GeminiMock{
+			Name:  "weather-call",
+			Match: mockllm.GeminiRequestMatch{MatchType: mockllm.MatchTypeContains, Text: "weather"},
+			Response: mockllm.GeminiResponse{Candidates: []mockllm.GeminiCandidate{{
+				Content: mockllm.GeminiContent{Parts: []mockllm.GeminiPart{{
+					FunctionCall: &mockllm.GeminiFunctionCall{Name: "get_weather", Args: map[string]any{"city": "Paris"}},
+				}}},
+			}}},
+		},
+	)
+	url := baseURL + "/v1beta/models/gemini-2.0-flash:generateContent"
+
+	response := decodeGeminiResponse(t, postJSON(t, url, geminiUserText("What's the weather in Paris?"), nil))
+	require.Len(t, response.Candidates, 1)
+	candidate := response.Candidates[0]
+	assert.Equal(t, "model", candidate.Content.Role)
+	assert.Equal(t, "STOP", candidate.FinishReason)
+	assert.Equal(t, "gemini-2.0-flash", response.ModelVersion)
+	require.NotNil(t, candidate.Content.Parts[0].FunctionCall)
+	assert.Equal(t, "get_weather", candidate.Content.Parts[0].FunctionCall.Name)
+	assert.Equal(t, map[string]any{"city": "Paris"}, candidate.Content.Parts[0].FunctionCall.Args)
+
+	functionResponse := func(temperature int) map[string]any {
+		return map[string]any{"contents": []map[string]any{
+			{"role": "user", "parts": []map[string]any{{"text": "What's the weather in Paris?"}}},
+			{"role": "model", "parts": []map[string]any{{"functionCall": map[string]any{"name": "get_weather", "args": map[string]any{"city": "Paris"}}}}},
+			{"role": "user", "parts": []map[string]any{{"functionResponse": map[string]any{
+				"name": "get_weather", "response": map[string]any{"temperature": temperature},
+			}}}},
+		}}
+	}
+	response = decodeGeminiResponse(t, postJSON(t, url, functionResponse(24), nil))
+	assert.Equal(t, "It is warm in Paris", response.Candidates[0].Content.Parts[0].Text)
+
+	// A function response with other fields matches no mock
+	resp := postJSON(t, url, functionResponse(5), nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	var body struct {
+		Error struct {
+			Code   int    `json:"code"`
+			Status string `json:"status"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, http.StatusNotFound, body.Error.Code)
+	assert.Equal(t, "NOT_FOUND", body.Error.Status)
+}
+
+func TestGeminiSafety(t *testing.T) {
+	baseURL := newGeminiServer(t,
+		mockllm.GeminiMock{
+			Name:  "blocked-prompt",
+			Match: mockllm.GeminiRequestMatch{MatchType: mockllm.MatchTypeContains, Text: "explosives"},
+			Response: mockllm.GeminiResponse{PromptFeedback: &mockllm.GeminiPromptFeedback{
+				BlockReason: "SAFETY",
+				SafetyRatings: []mockllm.GeminiSafetyRating{
+					{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Probability: "HIGH", Blocked: true},
+				},
+			}},
+		},
+		mockllm.GeminiMock{
+			Name:  "blocked-response",
+			Match: mockllm.GeminiRequestMatch{MatchType: mockllm.MatchTypeExact, Text: "Insult me"},
+			Response: mockllm.GeminiResponse{Candidates: []mockllm.GeminiCandidate{{
+				FinishReason: "SAFETY",
+				SafetyRatings: []mockllm.GeminiSafetyRating{
+					{Category: "HARM_CATEGORY_HARASSMENT", Probability: "MEDIUM", Blocked: true},
+				},
+			}}},
+		},
+	)
+	url := baseURL + "/v1beta/models/gemini-2.0-flash:generateContent"
+
+	response := decodeGeminiResponse(t, postJSON(t, url, geminiUserText("How are explosives made?"), nil))
+	assert.Empty(t, response.Candidates)
+	require.NotNil(t, response.PromptFeedback)
+	assert.Equal(t, "SAFETY", response.PromptFeedback.BlockReason)
+	assert.True(t, response.PromptFeedback.SafetyRatings[0].Blocked)
+
+	response = decodeGeminiResponse(t, postJSON(t, url, geminiUserText("Insult me"), nil))
+	require.Len(t, response.Candidates, 1)
+	assert.Equal(t, "SAFETY", response.Candidates[0].FinishReason)
+	assert.Equal(t, "HARM_CATEGORY_HARASSMENT", response.Candidates[0].SafetyRatings[0].Category)
+}
+
+func TestGeminiStreaming(t *testing.T) {
+	baseURL := newGeminiServer(t, mockllm.GeminiMock{
+		Name:  "hello",
+		Match: mockllm.GeminiRequestMatch{MatchType: mockllm.MatchTypeContains, Text: "Hello"},
+		Response: mockllm.GeminiResponse{Candidates: []mockllm.GeminiCandidate{{
+			Content: mockllm.GeminiContent{Parts: []mockllm.GeminiPart{{Text: "Hi"}}},
+		}}},
+	})
+
+	resp := postJSON(t, baseURL+"/v1beta/models/gemini-2.0-flash:streamGenerateContent?alt=sse", geminiUserText("Hello"), nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	var chunks []mockllm.GeminiResponse
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			var chunk mockllm.GeminiResponse
+			require.NoError(t, json.Unmarshal([]byte(data), &chunk))
+			chunks = append(chunks, chunk)
+		}
+	}
+	require.Len(t, chunks, 1)
+	assert.Equal(t, "Hi", chunks[0].Candidates[0].Content.Parts[0].Text)
+
+	// Without alt=sse the chunks are sent as a JSON array
+	resp = postJSON(t, baseURL+"/v1beta/models/gemini-2.0-flash:streamGenerateContent", geminiUserText("Hello"), nil)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&chunks))
+	require.Len(t, chunks, 1)
+	assert.Equal(t, "Hi", chunks[0].Candidates[0].Content.Parts[0].Text)
+}
+
+func TestGeminiCoverage(t *testing.T) {
+	reply := mockllm.GeminiResponse{Candidates: []mockllm.GeminiCandidate{{
+		Content: mockllm.GeminiContent{Parts: []mockllm.GeminiPart{{Text: "Hi"}}},
+	}}}
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{Gemini: []mockllm.GeminiMock{
+		{Name: "hello", Match: mockllm.GeminiRequestMatch{MatchType: mockllm.MatchTypeContains, Text: "Hello"}, Response: reply},
+		{Name: "bye", Match: mockllm.GeminiRequestMatch{MatchType: mockllm.MatchTypeContains, Text: "Bye"}, Response: reply},
+	}}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+
+	decodeGeminiResponse(t, postJSON(t, baseURL+"/v1beta/models/gemini-2.0-flash:generateContent", geminiUserText("Hello"), nil))
+
+	mocks := server.Mocks()
+	require.Len(t, mocks, 2)
+	assert.Equal(t, "gemini", mocks[0].Provider)
+	assert.Equal(t, 1, mocks[0].Hits)
+	coverage := server.Coverage()
+	assert.Equal(t, 2, coverage.Total)
+	require.Len(t, coverage.Unused, 1)
+	assert.Equal(t, "bye", coverage.Unused[0].Name)
+}
+
+func TestGeminiErrorRecords(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{Gemini: []mockllm.GeminiMock{
+		{Name: "hello", Match: mockllm.GeminiRequestMatch{MatchType: mockllm.MatchTypeContains, Text: "Hello"}},
+	}}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+
+	url := baseURL + "/v1beta/models/gemini-2.0-flash:generateContent"
+	resp, err := http.Post(url, "application/json", strings.NewReader("{"))
+	require.NoError(t, err)
+	resp.Body.Close() //nolint:errcheck
+	postJSON(t, url, map[string]any{"contents": []any{}}, nil)
+	postJSON(t, url, geminiUserText("Bye"), nil)
+
+	records := server.Requests()
+	require.Len(t, records, 3)
+	for i, expected := range []string{"unexpected end of JSON input", "contents is not specified", "no matching mock"} {
+		assert.NotEqual(t, http.StatusOK, records[i].Status)
+		assert.Equal(t, expected, records[i].Error)
+	}
+}
+
+func TestGeminiValidation(t *testing.T) {
+	filesys := fstest.MapFS{"config.json": {Data: []byte(`{"gemini": [
+		{"name": "a", "match": {"match_type": "regex", "text": "x"}},
+		{"name": "b", "match": {"function_response": {}}},
+		{"name": "c", "match": {}}
+	]}`)}}
+	_, err := mockllm.LoadConfigFromFile("config.json", filesys)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `gemini[0].match.match_type: must be exact or contains, got "regex"`)
+	assert.Contains(t, err.Error(), "gemini[1].match.function_response.name: must not be empty")
+	assert.Contains(t, err.Error(), "gemini[2].match.match_type: must be set without function_response")
+}
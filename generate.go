@@ -0,0 +1,131 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/kagent-dev/mockllm/openapi"
+	"github.com/kagent-dev/mockllm/tokenizer"
+	"github.com/openai/openai-go"
+)
+
+// loremWords are the words of generated text
+var loremWords = strings.Fields(`lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod
+	tempor incididunt ut labore et dolore magna aliqua enim ad minim veniam quis nostrud exercitation
+	ullamco laboris nisi aliquip ex ea commodo consequat duis aute irure in reprehenderit voluptate
+	velit esse cillum fugiat nulla pariatur excepteur sint occaecat cupidatat non proident sunt culpa
+	qui officia deserunt mollit anim id est laborum`)
+
+// GenerateConfig generates the content of a response instead of configuring it, e.g. to load test
+// streaming consumers with large outputs. Exactly one of Paragraphs, Tokens, Bytes, JSONSchema
+// and ToolCall is set.
+type GenerateConfig struct {
+	// Paragraphs of lorem ipsum text
+	Paragraphs int `json:"paragraphs,omitempty"`
+	// Tokens of lorem ipsum text, as counted by the configured tokenizer
+	Tokens int `json:"tokens,omitempty"`
+	// Bytes of lorem ipsum text, e.g. 5000000 for a multi-megabyte completion
+	Bytes int `json:"bytes,omitempty"`
+	// JSONSchema generates random JSON valid against the schema
+	JSONSchema *openapi.Schema `json:"json_schema,omitempty"`
+	// ToolCall calls the named tool declared in the request, or a random one for "*", with
+	// random arguments valid against the tool's schema, e.g. to fuzz tool executors
+	ToolCall string `json:"tool_call,omitempty"`
+	// Seed varies the generated content, which is the same for every request with the same seed
+	Seed uint64 `json:"seed,omitempty"`
+}
+
+// generate returns the configured content
+func (g *GenerateConfig) generate(tok tokenizer.Tokenizer) string {
+	rng := rand.New(rand.NewPCG(g.Seed, 0))
+	switch {
+	case g.JSONSchema != nil:
+		data, err := json.Marshal(openapi.Random(g.JSONSchema, rng))
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	case g.Tokens > 0:
+		var b strings.Builder
+		for tokenizer.Count(tok, b.String()) < g.Tokens {
+			b.WriteString(loremParagraph(rng))
+			b.WriteString("\n\n")
+		}
+		text, _ := tokenizer.Truncate(tok, b.String(), g.Tokens)
+		return text
+	case g.Bytes > 0:
+		var b strings.Builder
+		b.Grow(g.Bytes)
+		for b.Len() < g.Bytes {
+			b.WriteString(loremParagraph(rng))
+			b.WriteString("\n\n")
+		}
+		return b.String()[:g.Bytes]
+	default:
+		paragraphs := make([]string, g.Paragraphs)
+		for i := range paragraphs {
+			paragraphs[i] = loremParagraph(rng)
+		}
+		return strings.Join(paragraphs, "\n\n")
+	}
+}
+
+// generateOpenAIToolCall replaces the messages of a response with a call of the configured tool.
+// A response is left as is when the request declares no such tool.
+func (g *GenerateConfig) generateOpenAIToolCall(mockName string, request openai.ChatCompletionNewParams,
+	response *openai.ChatCompletion) {
+	rng := newToolRand(g.Seed)
+	tools := slices.DeleteFunc(slices.Clone(request.Tools), func(tool openai.ChatCompletionToolParam) bool {
+		return g.ToolCall != "*" && tool.Function.Name != g.ToolCall
+	})
+	if len(tools) > 0 {
+		setOpenAIToolCall(response, openaiToolCall(mockName, tools[rng.IntN(len(tools))].Function, rng))
+	}
+}
+
+// generateAnthropicToolUse replaces the content of a message with a call of the configured tool.
+// A message is left as is when the request declares no such tool.
+func (g *GenerateConfig) generateAnthropicToolUse(mockName string, request anthropic.MessageNewParams,
+	response *anthropic.Message) {
+	rng := newToolRand(g.Seed)
+	tools := slices.DeleteFunc(slices.Clone(request.Tools), func(tool anthropic.ToolUnionParam) bool {
+		name := tool.GetName()
+		return name == nil || g.ToolCall != "*" && *name != g.ToolCall
+	})
+	if len(tools) > 0 {
+		setAnthropicToolUse(response, anthropicToolUse(mockName, tools[rng.IntN(len(tools))], rng))
+	}
+}
+
+// loremParagraph generates four to six sentences of six to twelve words
+func loremParagraph(rng *rand.Rand) string {
+	sentences := make([]string, 4+rng.IntN(3))
+	for i := range sentences {
+		words := make([]string, 6+rng.IntN(7))
+		for j := range words {
+			words[j] = loremWords[rng.IntN(len(loremWords))]
+		}
+		sentences[i] = strings.ToUpper(words[0][:1]) + words[0][1:] + " " + strings.Join(words[1:], " ") + "."
+	}
+	return strings.Join(sentences, " ")
+}
+
+// setOpenAIContent replaces the content of every choice, adding a choice when there is none
+func setOpenAIContent(response *openai.ChatCompletion, content string) {
+	if len(response.Choices) == 0 {
+		response.Choices = make([]openai.ChatCompletionChoice, 1)
+	} else {
+		response.Choices = slices.Clone(response.Choices)
+	}
+	for i := range response.Choices {
+		response.Choices[i].Message.Content = content
+	}
+}
+
+// setAnthropicContent replaces the content of a message with a single text block
+func setAnthropicContent(response *anthropic.Message, content string) {
+	response.Content = []anthropic.ContentBlockUnion{{Type: "text", Text: content}}
+}
@@ -0,0 +1,109 @@
+package mockllm_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/kagent-dev/mockllm/openapi"
+	"github.com/kagent-dev/mockllm/tokenizer"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratedResponses(t *testing.T) {
+	schema, err := openapi.ParseSchema([]byte(`{"type": "object", "properties": {"city": {"type": "string"}, "temperature": {"type": "number"}}}`))
+	require.NoError(t, err)
+
+	generated := func(name string) mockllm.OpenAIMock {
+		return mockllm.OpenAIMock{
+			Name:  name,
+			Match: mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage(name)},
+		}
+	}
+	paragraphs, tokens, bytes, jsonMock := generated("paragraphs"), generated("tokens"), generated("bytes"), generated("json")
+	paragraphs.Generate = &mockllm.GenerateConfig{Paragraphs: 3}
+	tokens.Generate = &mockllm.GenerateConfig{Tokens: 500}
+	bytes.Generate = &mockllm.GenerateConfig{Bytes: 5 << 20}
+	jsonMock.Generate = &mockllm.GenerateConfig{JSONSchema: schema, Seed: 7}
+	baseURL := newOpenAIServer(t, paragraphs, tokens, bytes, jsonMock)
+
+	content := func(prompt string) string {
+		completion := postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+			Model:    "gpt-4o-mini",
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage(prompt)},
+		})
+		require.Len(t, completion.Choices, 1)
+		return completion.Choices[0].Message.Content
+	}
+
+	text := content("paragraphs")
+	assert.Len(t, strings.Split(text, "\n\n"), 3)
+	assert.Equal(t, text, content("paragraphs"), "generated content is reproducible")
+
+	assert.Equal(t, 500, tokenizer.Count(tokenizer.Default, content("tokens")))
+	assert.Len(t, content("bytes"), 5<<20)
+
+	var object map[string]any
+	require.NoError(t, json.Unmarshal([]byte(content("json")), &object))
+	assert.IsType(t, "", object["city"])
+	assert.IsType(t, float64(0), object["temperature"])
+}
+
+func TestGeneratedToolCalls(t *testing.T) {
+	mock := func(name, toolCall string, seed uint64) mockllm.OpenAIMock {
+		return mockllm.OpenAIMock{
+			Name:     name,
+			Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage(name)},
+			Generate: &mockllm.GenerateConfig{ToolCall: toolCall, Seed: seed},
+		}
+	}
+	missing := mock("missing", "delete", 1)
+	missing.Response = openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "No tool"}}}}
+	baseURL := newOpenAIServer(t, mock("search", "search", 1), mock("other", "search", 2), mock("any", "*", 1), missing)
+
+	tools := []openai.ChatCompletionToolParam{
+		{Function: openai.FunctionDefinitionParam{Name: "search", Parameters: openai.FunctionParameters{
+			"type":     "object",
+			"required": []string{"query", "limit"},
+			"properties": map[string]any{
+				"query": map[string]any{"type": "string"},
+				"limit": map[string]any{"type": "integer", "minimum": 1},
+				"sort":  map[string]any{"enum": []string{"relevance", "date"}},
+			},
+		}}},
+		{Function: openai.FunctionDefinitionParam{Name: "noop"}},
+	}
+	message := func(prompt string) openai.ChatCompletionMessage {
+		completion := postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+			Model:    "gpt-4o-mini",
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage(prompt)},
+			Tools:    tools,
+		})
+		require.Len(t, completion.Choices, 1)
+		if len(completion.Choices[0].Message.ToolCalls) > 0 {
+			assert.Equal(t, "tool_calls", completion.Choices[0].FinishReason)
+		}
+		return completion.Choices[0].Message
+	}
+
+	search := message("search")
+	require.Len(t, search.ToolCalls, 1)
+	assert.Equal(t, "search", search.ToolCalls[0].Function.Name)
+	var arguments map[string]any
+	require.NoError(t, json.Unmarshal([]byte(search.ToolCalls[0].Function.Arguments), &arguments))
+	assert.IsType(t, "", arguments["query"])
+	assert.GreaterOrEqual(t, arguments["limit"], float64(1))
+	assert.Contains(t, []any{"relevance", "date"}, arguments["sort"])
+	assert.Equal(t, search, message("search"), "generated calls are reproducible")
+	assert.NotEqual(t, search.ToolCalls[0].Function.Arguments, message("other").ToolCalls[0].Function.Arguments,
+		"seeds vary the arguments")
+
+	anyCall := message("any")
+	require.Len(t, anyCall.ToolCalls, 1)
+	assert.Contains(t, []string{"search", "noop"}, anyCall.ToolCalls[0].Function.Name)
+
+	assert.Equal(t, "No tool", message("missing").Content, "a tool the request doesn't declare is not called")
+}
@@ -0,0 +1,200 @@
+package mockllm
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+)
+
+// UpdateGoldenEnvVar is the environment variable that, set to a true value, makes the server
+// rewrite its golden transcript and metrics baseline instead of comparing the run against them
+const UpdateGoldenEnvVar = "MOCKLLM_UPDATE_GOLDEN"
+
+// GoldenConfig compares the transcript of a run, the requests it made and the mocks that served
+// them, against a golden transcript stored by an earlier run when the server stops, failing on
+// drift so unintended changes to the prompts of agents surface in review
+type GoldenConfig struct {
+	// Path is the file of the golden transcript, written by the first run
+	Path string `json:"path"`
+	// Update rewrites the golden transcript with the transcript of the run instead of comparing
+	// them, like setting UpdateGoldenEnvVar
+	Update bool `json:"update,omitempty"`
+	// IgnoreFields are top-level request body fields left out of transcripts, such as ones
+	// carrying IDs or timestamps that change between runs
+	IgnoreFields []string `json:"ignore_fields,omitempty"`
+}
+
+// update reports whether the golden transcript is rewritten rather than compared against
+func (c GoldenConfig) update() bool {
+	return c.Update || updateGoldenEnv()
+}
+
+// updateGoldenEnv reports whether UpdateGoldenEnvVar is set to a true value
+func updateGoldenEnv() bool {
+	update, _ := strconv.ParseBool(os.Getenv(UpdateGoldenEnvVar))
+	return update
+}
+
+// goldenIssues reports golden transcripts without a path
+func goldenIssues(golden *GoldenConfig) []configIssue {
+	if golden == nil || golden.Path != "" {
+		return nil
+	}
+	return []configIssue{{"golden.path", "must not be empty"}}
+}
+
+// TranscriptEntry is a request of a run and the mock that served it
+type TranscriptEntry struct {
+	Provider string `json:"provider"`
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	// Mock is the name of the mock that served the request, empty when none did
+	Mock   string          `json:"mock,omitempty"`
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// Transcript returns the requests served so far in the order they were received, with the
+// mocks that served them and their bodies, without the fields the golden config ignores
+func (s *Server) Transcript() []TranscriptEntry {
+	var ignored []string
+	if s.config.Golden != nil {
+		ignored = s.config.Golden.IgnoreFields
+	}
+	// The log is in the order requests were handled, which concurrent requests finish in any order
+	records := s.Requests()
+	slices.SortStableFunc(records, func(a, b RequestRecord) int { return cmp.Compare(a.received, b.received) })
+	transcript := []TranscriptEntry{}
+	for _, record := range records {
+		entry := TranscriptEntry{
+			Provider: record.Provider,
+			Method:   record.Method,
+			Path:     record.Path,
+			Status:   record.Status,
+			Body:     transcriptBody(record.Body, ignored),
+		}
+		if record.Matched {
+			entry.Mock = record.MockName
+		}
+		transcript = append(transcript, entry)
+	}
+	return transcript
+}
+
+// transcriptBody drops the ignored fields of a JSON object body, leaving other bodies as they are
+func transcriptBody(body json.RawMessage, ignored []string) json.RawMessage {
+	var fields map[string]json.RawMessage
+	if len(ignored) == 0 || json.Unmarshal(body, &fields) != nil || fields == nil {
+		return body
+	}
+	for _, field := range ignored {
+		delete(fields, field)
+	}
+	trimmed, err := json.Marshal(fields)
+	if err != nil {
+		return body
+	}
+	return trimmed
+}
+
+// marshalTranscript writes a transcript as indented JSON, with the keys of the bodies sorted so
+// transcripts diff line by line
+func marshalTranscript(transcript []TranscriptEntry) ([]byte, error) {
+	var normalized []any
+	for _, entry := range transcript {
+		var body any
+		if len(entry.Body) > 0 && json.Unmarshal(entry.Body, &body) != nil {
+			body = string(entry.Body)
+		}
+		normalized = append(normalized, struct {
+			TranscriptEntry
+			Body any `json:"body,omitempty"`
+		}{entry, body})
+	}
+	if normalized == nil {
+		normalized = []any{}
+	}
+	data, err := json.MarshalIndent(normalized, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// GoldenDiff diffs the golden transcript against the transcript of the run so far, returning the
+// changed hunks of a line diff, "- " lines only in the golden transcript and "+ " ones only in the
+// run with a few unchanged lines around them, or an empty string when they are the same
+func (s *Server) GoldenDiff() (string, error) {
+	if s.config.Golden == nil {
+		return "", errors.New("no golden transcript configured")
+	}
+	golden, err := os.ReadFile(s.config.Golden.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read golden transcript: %w", err)
+	}
+	actual, err := marshalTranscript(s.Transcript())
+	if err != nil {
+		return "", err
+	}
+	if bytes.Equal(golden, actual) {
+		return "", nil
+	}
+	return hunkDiff(string(golden), string(actual)), nil
+}
+
+// checkGolden writes the golden transcript when it is missing or being updated, and otherwise
+// returns an error with the diff when the run drifted from it
+func (s *Server) checkGolden() error {
+	golden := s.config.Golden
+	if golden == nil {
+		return nil
+	}
+	if _, err := os.Stat(golden.Path); golden.update() || errors.Is(err, fs.ErrNotExist) {
+		transcript, err := marshalTranscript(s.Transcript())
+		if err == nil {
+			err = os.WriteFile(golden.Path, transcript, 0o644)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write golden transcript: %w", err)
+		}
+		return nil
+	}
+	diff, err := s.GoldenDiff()
+	if err != nil {
+		return err
+	}
+	if diff != "" {
+		return fmt.Errorf("transcript drifted from golden transcript %s, set %s=1 to update it:\n%s", golden.Path, UpdateGoldenEnvVar, diff)
+	}
+	return nil
+}
+
+// handleAdminGolden returns the transcript of the run, and its diff against the golden transcript
+// with a 417 status when they differ
+func (s *Server) handleAdminGolden(w http.ResponseWriter, r *http.Request) {
+	if s.config.Golden == nil {
+		writeJSON(w, http.StatusOK, map[string]any{"transcript": s.Transcript()})
+		return
+	}
+	diff, err := s.GoldenDiff()
+	if errors.Is(err, fs.ErrNotExist) {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": err.Error(), "transcript": s.Transcript()})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	status := http.StatusOK
+	if diff != "" {
+		status = http.StatusExpectationFailed
+	}
+	writeJSON(w, status, map[string]any{"passed": diff == "", "diff": diff, "transcript": s.Transcript()})
+}
@@ -0,0 +1,178 @@
+package mockllm_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoldenTranscript(t *testing.T) {
+	goldenPath := filepath.Join(t.TempDir(), "golden.json")
+	runs := 0
+	run := func(prompt string) error {
+		t.Helper()
+		server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+			OpenAI: []mockllm.OpenAIMock{{
+				Name:     "hello",
+				Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+				Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "Hi"}}}},
+			}},
+			Golden: &mockllm.GoldenConfig{Path: goldenPath, IgnoreFields: []string{"user"}},
+		}))
+		baseURL, err := server.Start(t.Context())
+		require.NoError(t, err)
+		runs++
+		postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+			Model:    "gpt-4o",
+			Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage(prompt)},
+			// IDs that change between runs are ignored
+			User: openai.String(fmt.Sprintf("run-%d", runs)),
+		})
+		return server.Stop(t.Context())
+	}
+
+	// The first run writes the golden transcript
+	require.NoError(t, run("Hello there"))
+	data, err := os.ReadFile(goldenPath)
+	require.NoError(t, err)
+	var golden []map[string]any
+	require.NoError(t, json.Unmarshal(data, &golden))
+	require.Len(t, golden, 1)
+	assert.Equal(t, "hello", golden[0]["mock"])
+	assert.NotContains(t, golden[0]["body"], "user")
+
+	require.NoError(t, run("Hello there"))
+
+	// A changed prompt drifts from it
+	err = run("Hello there, be concise")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "transcript drifted from golden transcript")
+	assert.Contains(t, err.Error(), `"content": "Hello there, be concise"`)
+
+	// Updating accepts the drift
+	t.Setenv(mockllm.UpdateGoldenEnvVar, "1")
+	require.NoError(t, run("Hello there, be concise"))
+	t.Setenv(mockllm.UpdateGoldenEnvVar, "")
+	require.NoError(t, run("Hello there, be concise"))
+}
+
+func TestTranscriptOrder(t *testing.T) {
+	slow := replyMock("slow", "Slowly")
+	slow.Match.Message = openaiUserMessage("Slow")
+	slow.Fault = &mockllm.Fault{Type: mockllm.FaultStall, Duration: mockllm.Duration(200 * time.Millisecond)}
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{OpenAI: []mockllm.OpenAIMock{replyMock("hello", "Hi"), slow}}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+
+	post := func(content string) {
+		postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+			Model:    "gpt-4o",
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage(content)},
+		})
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		post("Slow")
+	}()
+	time.Sleep(50 * time.Millisecond)
+	post("Hello")
+	<-done
+
+	// The slow request finished last, but was received first
+	assert.Equal(t, "hello", server.Requests()[0].MockName)
+	transcript := server.Transcript()
+	require.Len(t, transcript, 2)
+	assert.Equal(t, "slow", transcript[0].Mock)
+	assert.Equal(t, "hello", transcript[1].Mock)
+}
+
+func TestGoldenDiffHunks(t *testing.T) {
+	goldenPath := filepath.Join(t.TempDir(), "golden.json")
+	run := func(prompts ...string) *mockllm.Server {
+		t.Helper()
+		server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{Golden: &mockllm.GoldenConfig{Path: goldenPath}}))
+		baseURL, err := server.Start(t.Context())
+		require.NoError(t, err)
+		for _, prompt := range prompts {
+			postJSON(t, baseURL+"/v1/chat/completions", openai.ChatCompletionNewParams{
+				Model:    "gpt-4o",
+				Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage(prompt)},
+			}, openaiHeaders)
+		}
+		return server
+	}
+	prompts := make([]string, 50)
+	for i := range prompts {
+		prompts[i] = fmt.Sprintf("Prompt %d", i)
+	}
+	require.NoError(t, run(prompts...).Stop(t.Context()))
+
+	prompts[25] = "Prompt 25, be concise"
+	server := run(prompts...)
+	diff, err := server.GoldenDiff()
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(goldenPath))
+	require.NoError(t, server.Stop(t.Context()))
+
+	// Only the changed request is shown, with a few lines around it
+	assert.Contains(t, diff, `-           "content": "Prompt 25",`)
+	assert.Contains(t, diff, `+           "content": "Prompt 25, be concise",`)
+	assert.Equal(t, 1, strings.Count(diff, "@@ -"))
+	assert.NotContains(t, diff, "Prompt 24")
+	assert.NotContains(t, diff, "Prompt 26")
+	assert.Less(t, strings.Count(diff, "\n"), 10)
+}
+
+func TestAdminGolden(t *testing.T) {
+	goldenPath := filepath.Join(t.TempDir(), "golden.json")
+	require.NoError(t, os.WriteFile(goldenPath, []byte("[]\n"), 0o644))
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{Golden: &mockllm.GoldenConfig{Path: goldenPath}}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+
+	get := func() (int, map[string]any) {
+		t.Helper()
+		resp, err := http.Get(baseURL + "/admin/golden")
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint:errcheck
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return resp.StatusCode, body
+	}
+
+	status, body := get()
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, true, body["passed"])
+
+	resp := postJSON(t, baseURL+"/v1/chat/completions", openai.ChatCompletionNewParams{
+		Model:    "gpt-4o",
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Hello")},
+	}, openaiHeaders)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	status, body = get()
+	assert.Equal(t, http.StatusExpectationFailed, status)
+	assert.Equal(t, false, body["passed"])
+	assert.Contains(t, body["diff"], `+     "path": "/v1/chat/completions",`)
+}
+
+func TestGoldenValidation(t *testing.T) {
+	filesys := fstest.MapFS{"config.json": {Data: []byte(`{"golden": {"update": true}}`)}}
+	_, err := mockllm.LoadConfigFromFile("config.json", filesys)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "golden.path: must not be empty")
+}
@@ -0,0 +1,93 @@
+package mockllm
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+)
+
+// grammarMaxDepth bounds the expansion of recursive symbols
+const grammarMaxDepth = 10
+
+// grammarMockName is the mock name logged for requests answered by the global grammar
+const grammarMockName = "grammar"
+
+// grammarSymbolPattern matches a {symbol} reference in a template
+var grammarSymbolPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+// Grammar synthesizes varied but reproducible replies from templates, so exploratory tests do not
+// need a mock per prompt. The reply to a prompt is always the same, while different prompts pick
+// different alternatives.
+type Grammar struct {
+	// Rules are tried in order, the first whose keywords match the prompt gives the reply template
+	Rules []GrammarRule `json:"rules"`
+	// Symbols are the alternatives a {symbol} in a template expands to. They can reference other
+	// symbols, and {input} expands to the prompt itself.
+	Symbols map[string][]string `json:"symbols,omitempty"`
+	// Seed varies the alternatives picked for every prompt
+	Seed uint64 `json:"seed,omitempty"`
+}
+
+// GrammarRule is a reply template conditioned on keywords of the prompt
+type GrammarRule struct {
+	// When lists keywords of which at least one must be in the prompt, ignoring case. A rule
+	// without keywords matches any prompt.
+	When []string `json:"when,omitempty"`
+	// Reply is the template of the reply
+	Reply string `json:"reply"`
+}
+
+// rule returns the first rule matching the prompt, nil when none does
+func (g *Grammar) rule(prompt string) *GrammarRule {
+	lower := strings.ToLower(prompt)
+	for i, rule := range g.Rules {
+		if len(rule.When) == 0 || containsAny(lower, rule.When) {
+			return &g.Rules[i]
+		}
+	}
+	return nil
+}
+
+// answers reports whether the grammar, if any, has a rule for the prompt
+func (g *Grammar) answers(prompt string) bool {
+	return g != nil && g.rule(prompt) != nil
+}
+
+// reply synthesizes the reply to a prompt, reporting whether a rule matched it
+func (g *Grammar) reply(prompt string) (string, bool) {
+	rule := g.rule(prompt)
+	if rule == nil {
+		return "", false
+	}
+	hash := fnv.New64a()
+	hash.Write([]byte(prompt)) //nolint:errcheck
+	rng := rand.New(rand.NewPCG(hash.Sum64(), g.Seed))
+	return g.expand(rule.Reply, prompt, rng, 0), true
+}
+
+// expand replaces the symbols of a template with one of their alternatives. Unknown symbols and
+// symbols nested deeper than grammarMaxDepth are left as they are.
+func (g *Grammar) expand(template, prompt string, rng *rand.Rand, depth int) string {
+	return grammarSymbolPattern.ReplaceAllStringFunc(template, func(reference string) string {
+		name := reference[1 : len(reference)-1]
+		if name == "input" {
+			return prompt
+		}
+		alternatives := g.Symbols[name]
+		if len(alternatives) == 0 || depth >= grammarMaxDepth {
+			return reference
+		}
+		return g.expand(alternatives[rng.IntN(len(alternatives))], prompt, rng, depth+1)
+	})
+}
+
+// containsAny reports whether the lower case text contains any of the keywords, ignoring case
+func containsAny(text string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(text, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
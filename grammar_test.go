@@ -0,0 +1,80 @@
+package mockllm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrammar(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{Grammar: &mockllm.Grammar{
+		Rules: []mockllm.GrammarRule{
+			{When: []string{"weather", "Forecast"}, Reply: "{greeting} It is {condition} today."},
+			{Reply: "{greeting} You said: {input}"},
+		},
+		Symbols: map[string][]string{
+			"greeting":  {"Sure!", "Of course."},
+			"condition": {"sunny", "{adjective} and rainy"},
+			"adjective": {"cold", "warm"},
+		},
+	}}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	reply := func(prompt string) string {
+		completion := postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+			Model:    "gpt-4o-mini",
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage(prompt)},
+		})
+		require.Len(t, completion.Choices, 1)
+		return completion.Choices[0].Message.Content
+	}
+
+	weather := reply("What is the FORECAST for Paris?")
+	assert.Regexp(t, `^(Sure!|Of course\.) It is (sunny|(cold|warm) and rainy) today\.$`, weather)
+	assert.Equal(t, weather, reply("What is the FORECAST for Paris?"), "replies are reproducible")
+	assert.Regexp(t, `^(Sure!|Of course\.) You said: Hello$`, reply("Hello"))
+
+	requests := server.Requests()
+	require.NotEmpty(t, requests)
+	assert.Equal(t, "grammar", requests[0].MockName)
+}
+
+func TestGrammarFallsThroughToEcho(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		Grammar: &mockllm.Grammar{Rules: []mockllm.GrammarRule{{When: []string{"weather"}, Reply: "It is sunny."}}},
+		Echo:    &mockllm.EchoConfig{},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	reply := func(prompt string) string {
+		completion := postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+			Model:    "gpt-4o-mini",
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage(prompt)},
+		})
+		require.Len(t, completion.Choices, 1)
+		return completion.Choices[0].Message.Content
+	}
+
+	assert.Equal(t, "It is sunny.", reply("How is the weather?"))
+	// No rule covers the prompt, so echo mode answers it instead of an empty grammar reply
+	assert.Equal(t, "Hello", reply("Hello"))
+
+	requests := server.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, "grammar", requests[0].MockName)
+	assert.Equal(t, "echo", requests[1].MockName)
+
+	explanations, err := server.WhichMockMatches([]byte(`{"model": "gpt-4o-mini", "messages": [{"role": "user", "content": "Hello"}]}`), nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, explanations)
+	assert.Equal(t, "echo", explanations[0].MockName)
+	assert.True(t, explanations[0].Selected)
+}
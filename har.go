@@ -0,0 +1,253 @@
+package mockllm
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HAR is an HTTP archive, as saved by browsers' developer tools and HTTP proxies. Only the fields
+// mockllm reads or writes are declared, see http://www.softwareishard.com/blog/har-12-spec/.
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog is the root of an archive, with its entries in the order the requests were sent
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator names the application that saved an archive
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry is an exchanged request and response
+type HAREntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+// HARRequest is the request of an entry
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	Cookies     []HARNameValue `json:"cookies"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARResponse is the response of an entry
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []HARNameValue `json:"headers"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARNameValue is a header, query parameter or cookie
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData is the body of a request
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// HARContent is the body of a response
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	// Encoding is "base64" when Text is base64 encoded
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// HARTimings are the durations, in milliseconds, of the phases of an exchange
+type HARTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// ImportHAR turns the chat completion and Messages API calls of an HTTP archive of real traffic
+// into a config of recorded mocks, see RecordOpenAIMock and RecordAnthropicMock. The other entries,
+// failed calls and streamed responses are skipped, each with the reason returned.
+func ImportHAR(data []byte) ([]byte, []string, error) {
+	var har HAR
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse HAR: %w", err)
+	}
+	config := struct {
+		OpenAI    []json.RawMessage `json:"openai,omitempty"`
+		Anthropic []json.RawMessage `json:"anthropic,omitempty"`
+	}{}
+	var skipped []string
+	for i, entry := range har.Log.Entries {
+		skip := func(reason string) {
+			skipped = append(skipped, fmt.Sprintf("entry %d (%s %s): %s", i, entry.Request.Method, entry.Request.URL, reason))
+		}
+		provider := harProvider(entry.Request)
+		if provider == "" {
+			skip("not a chat completion or Messages API call")
+			continue
+		}
+		if entry.Response.Status != http.StatusOK {
+			skip(fmt.Sprintf("status %d", entry.Response.Status))
+			continue
+		}
+		if mediaType, _, _ := mime.ParseMediaType(entry.Response.Content.MimeType); mediaType == "text/event-stream" {
+			skip("streamed response")
+			continue
+		}
+		if entry.Request.PostData == nil {
+			skip("no request body")
+			continue
+		}
+		response, err := entry.Response.Content.body()
+		if err != nil {
+			skip(err.Error())
+			continue
+		}
+
+		name := "har-" + strconv.Itoa(i)
+		record, mocks := RecordOpenAIMock, &config.OpenAI
+		if provider == "anthropic" {
+			record, mocks = RecordAnthropicMock, &config.Anthropic
+		}
+		mock, err := record(name, []byte(entry.Request.PostData.Text), response)
+		if err != nil {
+			skip(err.Error())
+			continue
+		}
+		*mocks = append(*mocks, mock)
+	}
+	out, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, skipped, nil
+}
+
+// harProvider returns the provider whose chat endpoint a request calls, or the empty string
+func harProvider(request HARRequest) string {
+	if request.Method != http.MethodPost {
+		return ""
+	}
+	u, err := url.Parse(request.URL)
+	if err != nil {
+		return ""
+	}
+	return chatEndpointProvider(u.Path)
+}
+
+// chatEndpointProvider returns the provider whose chat endpoint a URL path is, or the empty string
+func chatEndpointProvider(urlPath string) string {
+	switch {
+	case strings.HasSuffix(urlPath, "/chat/completions"):
+		return "openai"
+	case strings.HasSuffix(urlPath, "/v1/messages"):
+		return "anthropic"
+	}
+	return ""
+}
+
+// body returns the decoded text of the content
+func (c HARContent) body() ([]byte, error) {
+	if c.Encoding == "base64" {
+		decoded, err := base64.StdEncoding.DecodeString(c.Text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return decoded, nil
+	}
+	if c.Text == "" {
+		return nil, fmt.Errorf("response content was not captured")
+	}
+	return []byte(c.Text), nil
+}
+
+// ExportHAR renders records of the request log as an HTTP archive, with URLs on baseURL, so the
+// traffic can be inspected with existing HAR tooling. Streamed responses are archived in their
+// non-streamed form, and the responses of requests no mock served are left empty, the reason
+// they failed being the entry's comment.
+func ExportHAR(records []RequestRecord, baseURL string) HAR {
+	har := HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "mockllm", Version: "1"},
+		Entries: make([]HAREntry, 0, len(records)),
+	}}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	for _, record := range records {
+		entry := HAREntry{
+			StartedDateTime: record.Time.Format(time.RFC3339Nano),
+			Request: HARRequest{
+				Method:      record.Method,
+				URL:         baseURL + record.Path,
+				HTTPVersion: "HTTP/1.1",
+				Headers:     []HARNameValue{},
+				QueryString: []HARNameValue{},
+				Cookies:     []HARNameValue{},
+				HeadersSize: -1,
+				BodySize:    len(record.Body),
+			},
+			Response: HARResponse{
+				Status:      record.Status,
+				StatusText:  http.StatusText(record.Status),
+				HTTPVersion: "HTTP/1.1",
+				Headers:     []HARNameValue{},
+				Cookies:     []HARNameValue{},
+				Content:     HARContent{Size: len(record.Response), MimeType: "application/json", Text: string(record.Response)},
+				HeadersSize: -1,
+				BodySize:    len(record.Response),
+			},
+			Comment: record.Error,
+		}
+		if len(record.Body) > 0 {
+			entry.Request.Headers = []HARNameValue{{Name: "Content-Type", Value: "application/json"}}
+			entry.Request.PostData = &HARPostData{MimeType: "application/json", Text: string(record.Body)}
+		}
+		if record.Stream != nil {
+			entry.Comment = strings.TrimPrefix(entry.Comment+"; streamed response archived in its non-streamed form", "; ")
+		}
+		har.Log.Entries = append(har.Log.Entries, entry)
+	}
+	return har
+}
+
+func (s *Server) handleAdminHAR(w http.ResponseWriter, r *http.Request) {
+	query, err := parseRequestQuery(r.URL.Query())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	records, total := s.QueryRequests(query)
+	w.Header().Set(totalCountHeader, strconv.Itoa(total))
+	writeJSON(w, http.StatusOK, ExportHAR(records, "http://"+r.Host))
+}
@@ -0,0 +1,136 @@
+package mockllm_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	openaioption "github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportHAR(t *testing.T) {
+	entry := func(method, url, request, mimeType, response string, status int) mockllm.HAREntry {
+		entry := mockllm.HAREntry{
+			Request:  mockllm.HARRequest{Method: method, URL: url},
+			Response: mockllm.HARResponse{Status: status, Content: mockllm.HARContent{MimeType: mimeType, Text: response}},
+		}
+		if request != "" {
+			entry.Request.PostData = &mockllm.HARPostData{MimeType: "application/json", Text: request}
+		}
+		return entry
+	}
+	anthropicEntry := entry(http.MethodPost, "https://api.anthropic.com/v1/messages",
+		`{"model":"claude-3-5-sonnet-20240620","max_tokens":100,"messages":[{"role":"user","content":"Hello"}]}`,
+		"application/json", base64.StdEncoding.EncodeToString([]byte(
+			`{"id":"msg_har","type":"message","role":"assistant","model":"claude-3-5-sonnet-20240620","content":[{"type":"text","text":"Hi from HAR"}]}`)),
+		http.StatusOK)
+	anthropicEntry.Response.Content.Encoding = "base64"
+	har, err := json.Marshal(mockllm.HAR{Log: mockllm.HARLog{Entries: []mockllm.HAREntry{
+		entry(http.MethodGet, "https://api.openai.com/v1/models", "", "application/json", `{"data":[]}`, http.StatusOK),
+		entry(http.MethodPost, "https://api.openai.com/v1/chat/completions",
+			`{"model":"gpt-4o","messages":[{"role":"user","content":"Hello"}]}`, "application/json; charset=utf-8",
+			`{"id":"chatcmpl-har","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"Hi from HAR"}}]}`,
+			http.StatusOK),
+		entry(http.MethodPost, "https://api.openai.com/v1/chat/completions",
+			`{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"Hello"}]}`, "text/event-stream",
+			"data: [DONE]\n\n", http.StatusOK),
+		entry(http.MethodPost, "https://api.openai.com/v1/chat/completions",
+			`{"model":"gpt-4o","messages":[{"role":"user","content":"Hello"}]}`, "application/json",
+			`{"error":{"message":"Rate limit reached"}}`, http.StatusTooManyRequests),
+		anthropicEntry,
+	}}})
+	require.NoError(t, err)
+
+	configJSON, skipped, err := mockllm.ImportHAR(har)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"entry 0 (GET https://api.openai.com/v1/models): not a chat completion or Messages API call",
+		"entry 2 (POST https://api.openai.com/v1/chat/completions): streamed response",
+		"entry 3 (POST https://api.openai.com/v1/chat/completions): status 429",
+	}, skipped)
+
+	config, err := mockllm.LoadConfigFromFile("mocks.json", fstest.MapFS{"mocks.json": {Data: configJSON}})
+	require.NoError(t, err)
+	require.Len(t, config.OpenAI, 1)
+	require.Len(t, config.Anthropic, 1)
+	assert.Equal(t, "har-1", config.OpenAI[0].Name)
+	assert.Equal(t, "har-4", config.Anthropic[0].Name)
+
+	server := mockllm.NewServer(mockllm.WithConfig(config))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	openaiClient := openai.NewClient(openaioption.WithBaseURL(baseURL+"/v1/"), openaioption.WithAPIKey("test-key"))
+	completion, err := openaiClient.Chat.Completions.New(t.Context(), openai.ChatCompletionNewParams{
+		Model:    "gpt-4o",
+		Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Hi from HAR", completion.Choices[0].Message.Content)
+
+	anthropicClient := anthropic.NewClient(anthropicoption.WithBaseURL(baseURL), anthropicoption.WithAPIKey("test-key"))
+	message, err := anthropicClient.Messages.New(t.Context(), anthropicHelloRequest)
+	require.NoError(t, err)
+	assert.Equal(t, "msg_har", message.ID)
+}
+
+func TestExportHAR(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{OpenAI: []mockllm.OpenAIMock{{
+		Name:  "hello",
+		Match: mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+		Response: openai.ChatCompletion{
+			ID:      "chatcmpl-1",
+			Object:  "chat.completion",
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: "assistant", Content: "Hi"}}},
+		},
+	}}}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	headers := map[string]string{"Authorization": "Bearer test-key"}
+	for _, content := range []string{"Hello", "Goodbye"} {
+		postJSON(t, baseURL+"/v1/chat/completions", openai.ChatCompletionNewParams{
+			Model:    "gpt-4o",
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage(content)},
+		}, headers).Body.Close() //nolint:errcheck
+	}
+
+	resp, err := http.Get(baseURL + "/admin/har?matched=true")
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var har mockllm.HAR
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&har))
+
+	assert.Equal(t, "1.2", har.Log.Version)
+	require.Len(t, har.Log.Entries, 1)
+	entry := har.Log.Entries[0]
+	assert.Equal(t, http.MethodPost, entry.Request.Method)
+	assert.Equal(t, baseURL+"/v1/chat/completions", entry.Request.URL)
+	require.NotNil(t, entry.Request.PostData)
+	assert.Contains(t, entry.Request.PostData.Text, `"Hello"`)
+	assert.Equal(t, http.StatusOK, entry.Response.Status)
+	assert.Contains(t, entry.Response.Content.Text, `"chatcmpl-1"`)
+
+	unmatched := mockllm.ExportHAR(server.Requests(), baseURL).Log.Entries[1]
+	assert.Equal(t, http.StatusNotFound, unmatched.Response.Status)
+	assert.Empty(t, unmatched.Response.Content.Text)
+
+	data, err := json.Marshal(har)
+	require.NoError(t, err)
+	config, skipped, err := mockllm.ImportHAR(data)
+	require.NoError(t, err)
+	assert.Empty(t, skipped, "exported traffic imports back")
+	assert.Contains(t, string(config), `"chatcmpl-1"`)
+}
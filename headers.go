@@ -0,0 +1,58 @@
+package mockllm
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	anthropicBetaHeader = "anthropic-beta"
+	openaiBetaHeader    = "OpenAI-Beta"
+)
+
+// mismatchedHeader checks request headers against the values a mock requires, given as
+// path.Match patterns, returning why they do not match or an empty string
+func mismatchedHeader(header http.Header, required map[string]string) string {
+	names := make([]string, 0, len(required))
+	for name := range required {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		values := header.Values(name)
+		if len(values) == 0 {
+			return fmt.Sprintf("header %s is missing", name)
+		}
+		if !slices.ContainsFunc(values, func(value string) bool {
+			ok, err := path.Match(required[name], value)
+			return err == nil && ok
+		}) {
+			return fmt.Sprintf("header %s is %q, want %q", name, strings.Join(values, ", "), required[name])
+		}
+	}
+	return ""
+}
+
+// missingBetas returns the required beta features that are not listed in the given header.
+// Beta headers carry a comma separated list of feature names.
+func missingBetas(header http.Header, name string, required []string) []string {
+	enabled := map[string]bool{}
+	for _, value := range header.Values(name) {
+		for _, beta := range strings.Split(value, ",") {
+			enabled[strings.TrimSpace(beta)] = true
+		}
+	}
+
+	var missing []string
+	for _, beta := range required {
+		if !enabled[beta] {
+			missing = append(missing, beta)
+		}
+	}
+	return missing
+}
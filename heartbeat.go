@@ -0,0 +1,94 @@
+package mockllm
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// HeartbeatConfig keeps streams alive while they wait on a simulated delay, such as a stall fault
+// or the stream interval of a model profile, the way proxies and the providers do
+type HeartbeatConfig struct {
+	// Interval is how often a heartbeat is sent while a stream waits
+	Interval Duration `json:"interval"`
+	// Comment is the text of the SSE comment sent as a heartbeat, "ping" by default
+	Comment string `json:"comment,omitempty"`
+	// PingEvents sends Anthropic streams ping events, {"type": "ping"}, instead of comments.
+	// OpenAI streams, which have no ping event, still get comments.
+	PingEvents bool `json:"ping_events,omitempty"`
+}
+
+// anthropicPingEvent is the keep-alive event of the Messages API
+var anthropicPingEvent = newSSEEvent("ping", map[string]any{"type": "ping"})
+
+// heartbeatEvent returns the heartbeat of a stream, with the given ping event if the config asks
+// for ping events and the provider has one
+func (c *HeartbeatConfig) heartbeatEvent(ping *sseEvent) *sseEvent {
+	if c == nil || c.Interval <= 0 {
+		return nil
+	}
+	if c.PingEvents && ping != nil {
+		return ping
+	}
+	comment := c.Comment
+	if comment == "" {
+		comment = "ping"
+	}
+	return &sseEvent{Comment: comment}
+}
+
+// interval returns how often heartbeats are sent, zero when they are not
+func (c *HeartbeatConfig) interval() time.Duration {
+	if c == nil {
+		return 0
+	}
+	return time.Duration(c.Interval)
+}
+
+// ssePacing is how a stream is paced between its events
+type ssePacing struct {
+	// fault stalls the stream before one of its events
+	fault *Fault
+	// interval is the pause between events
+	interval time.Duration
+	// heartbeat is sent every heartbeatInterval while the stream waits
+	heartbeat         *sseEvent
+	heartbeatInterval time.Duration
+}
+
+// pace waits before the event at index i, sending heartbeats along the way. It reports whether
+// the stream should go on.
+func (p ssePacing) pace(ctx context.Context, w http.ResponseWriter, clock Clock, i int, stats *StreamStats) bool {
+	if i > 0 && p.interval > 0 && !p.wait(ctx, w, clock, p.interval, stats) {
+		return false
+	}
+	if p.fault.stallsBefore(i) && !p.wait(ctx, w, clock, time.Duration(p.fault.Duration), stats) {
+		return false
+	}
+	return true
+}
+
+// wait blocks like wait, sending a heartbeat every heartbeat interval until d elapses. It reports
+// whether d elapsed without the context being cancelled or a heartbeat failing.
+func (p ssePacing) wait(ctx context.Context, w http.ResponseWriter, clock Clock, d time.Duration, stats *StreamStats) bool {
+	if p.heartbeat == nil || p.heartbeatInterval <= 0 {
+		return wait(ctx, clock, d)
+	}
+	rc := http.NewResponseController(w)
+	for {
+		step := p.heartbeatInterval
+		if d > 0 && d <= step {
+			return wait(ctx, clock, d)
+		}
+		if !wait(ctx, clock, step) {
+			return false
+		}
+		if writeEvent(w, *p.heartbeat) != nil || rc.Flush() != nil {
+			return false
+		}
+		stats.Heartbeats++
+		if d > 0 {
+			d -= step
+		}
+	}
+}
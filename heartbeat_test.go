@@ -0,0 +1,98 @@
+package mockllm_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamHeartbeats(t *testing.T) {
+	stall := &mockllm.Fault{Type: mockllm.FaultStall, Duration: mockllm.Duration(60 * time.Millisecond), AfterEvents: 1}
+	newServer := func(t *testing.T, heartbeat *mockllm.HeartbeatConfig) (*mockllm.Server, string) {
+		server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+			Heartbeat: heartbeat,
+			OpenAI: []mockllm.OpenAIMock{{
+				Name:     "stalled",
+				Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+				Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "Hello there"}}}},
+				Fault:    stall,
+			}},
+			Anthropic: []mockllm.AnthropicMock{{
+				Name:     "stalled",
+				Match:    mockllm.AnthropicRequestMatch{MatchType: mockllm.MatchTypeExact, Message: anthropicHelloRequest.Messages[0]},
+				Response: anthropic.Message{Content: []anthropic.ContentBlockUnion{{Type: "text", Text: "Hello there"}}},
+				Fault:    stall,
+			}},
+		}))
+		baseURL, err := server.Start(t.Context())
+		require.NoError(t, err)
+		t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+		return server, baseURL
+	}
+	streamAnthropic := func(t *testing.T, baseURL string) string {
+		request := anthropicHelloRequest
+		resp := postJSON(t, baseURL+"/v1/messages", map[string]any{
+			"model": request.Model, "max_tokens": request.MaxTokens, "messages": request.Messages, "stream": true,
+		}, anthropicHeaders("2023-06-01"))
+		defer resp.Body.Close() //nolint:errcheck
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	t.Run("ping events", func(t *testing.T) {
+		server, baseURL := newServer(t, &mockllm.HeartbeatConfig{Interval: mockllm.Duration(10 * time.Millisecond), PingEvents: true})
+		body := streamAnthropic(t, baseURL)
+		assert.GreaterOrEqual(t, strings.Count(body, "event: ping\ndata: {\"type\":\"ping\"}\n\n"), 3)
+		assert.Less(t, strings.Index(body, "event: message_start"), strings.Index(body, "event: ping"), "heartbeats are sent during the stall")
+
+		client := anthropic.NewClient(anthropicoption.WithBaseURL(baseURL), anthropicoption.WithAPIKey("test-key"))
+		stream := client.Messages.NewStreaming(t.Context(), anthropicHelloRequest)
+		var message anthropic.Message
+		for stream.Next() {
+			require.NoError(t, message.Accumulate(stream.Current()))
+		}
+		require.NoError(t, stream.Err())
+		assert.Equal(t, "Hello there", message.Content[0].Text)
+
+		requests := server.Requests()
+		require.Len(t, requests, 2)
+		require.NotNil(t, requests[0].Stream)
+		assert.GreaterOrEqual(t, requests[0].Stream.Heartbeats, 3)
+	})
+
+	t.Run("comments", func(t *testing.T) {
+		_, baseURL := newServer(t, &mockllm.HeartbeatConfig{Interval: mockllm.Duration(10 * time.Millisecond), Comment: "keep-alive"})
+		assert.Contains(t, streamAnthropic(t, baseURL), ": keep-alive\n\n")
+
+		resp := postJSON(t, baseURL+"/v1/chat/completions", map[string]any{
+			"model": "gpt-4o", "messages": []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")}, "stream": true,
+		}, map[string]string{"Authorization": "Bearer test-key"})
+		defer resp.Body.Close() //nolint:errcheck
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), ": keep-alive\n\n")
+		assert.True(t, strings.HasSuffix(string(body), "data: [DONE]\n\n"))
+
+		client := anthropic.NewClient(anthropicoption.WithBaseURL(baseURL), anthropicoption.WithAPIKey("test-key"))
+		stream := client.Messages.NewStreaming(t.Context(), anthropicHelloRequest)
+		for stream.Next() {
+		}
+		require.NoError(t, stream.Err(), "the Anthropic SDK skips comments")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		_, baseURL := newServer(t, nil)
+		body := streamAnthropic(t, baseURL)
+		assert.NotContains(t, body, "event: ping")
+		assert.NotContains(t, body, "\n: ")
+	})
+}
@@ -0,0 +1,164 @@
+package mockllm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+)
+
+const providerHTTP = "http"
+
+// HTTPBodyMatch matches the raw body of a request
+type HTTPBodyMatch struct {
+	// MatchType is exact (JSON bodies are compared semantically) or contains (substring)
+	MatchType MatchType `json:"match_type"`
+	Value     string    `json:"value"`
+}
+
+// HTTPResponse is an arbitrary HTTP response
+type HTTPResponse struct {
+	// Status is the response status code. Defaults to 200.
+	Status  int               `json:"status,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// JSON is written as the response body with an application/json content type
+	JSON json.RawMessage `json:"json,omitempty"`
+	// BodyBase64 is decoded and written as the body when JSON is not set, for binary or
+	// pre-compressed bodies
+	BodyBase64 string `json:"body_base64,omitempty"`
+	// BodyFile is read and written as the body when neither JSON nor BodyBase64 is set, e.g. for
+	// audio or images. Config files resolve it relative to the config file, otherwise it is
+	// relative to the working directory.
+	BodyFile string `json:"body_file,omitempty"`
+	// Body is written verbatim when no other body is set
+	Body string `json:"body,omitempty"`
+	// ContentType is the Content-Type header of the response, taking precedence over Headers and
+	// the application/json type of JSON bodies. Without one, the type of other bodies is sniffed
+	// from their first bytes.
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// HTTPMock maps an arbitrary HTTP request, such as an auth token or vector database call made by
+// an agent, to a canned response
+type HTTPMock struct {
+	Name string `json:"name"`
+	// Method is the request method to match. Empty matches any method.
+	Method string `json:"method,omitempty"`
+	// Path is a path.Match pattern for the request path, e.g. /collections/*/points
+	Path     string         `json:"path"`
+	Body     *HTTPBodyMatch `json:"body,omitempty"`
+	Response HTTPResponse   `json:"response"`
+}
+
+// HTTPProvider serves the custom HTTP mocks for requests no other route handles
+type HTTPProvider struct {
+	mocks []HTTPMock
+	log   *RequestLog
+}
+
+// NewHTTPProvider creates a new HTTPProvider with the given mocks
+func NewHTTPProvider(mocks []HTTPMock) *HTTPProvider {
+	return &HTTPProvider{mocks: mocks}
+}
+
+// Fallback returns a handler serving the first matching mock, or delegating to next if none match
+func (p *HTTPProvider) Fallback(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to read request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		mock := p.findMatchingMock(r, body)
+		if mock == nil {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		record := p.log.newRecord(r, providerHTTP, body)
+		record.Matched, record.MockName = true, mock.Name
+		done := p.log.serving(providerHTTP, "", mock.Name)
+		record.Status = writeHTTPResponse(w, mock.Response)
+		done()
+		p.log.Add(&record)
+	})
+}
+
+// findMatchingMock finds the first mock that matches the request
+func (p *HTTPProvider) findMatchingMock(r *http.Request, body []byte) *HTTPMock {
+	for _, mock := range p.mocks {
+		if mock.Method != "" && !strings.EqualFold(mock.Method, r.Method) {
+			continue
+		}
+		if ok, err := path.Match(mock.Path, r.URL.Path); err != nil || !ok {
+			continue
+		}
+		if mock.Body != nil && !mock.Body.matches(body) {
+			continue
+		}
+		return &mock
+	}
+	return nil
+}
+
+func (m HTTPBodyMatch) matches(body []byte) bool {
+	switch m.MatchType {
+	case MatchTypeExact:
+		var expected, actual any
+		if json.Unmarshal([]byte(m.Value), &expected) == nil && json.Unmarshal(body, &actual) == nil {
+			return reflect.DeepEqual(expected, actual)
+		}
+		return string(body) == m.Value
+	case MatchTypeContains:
+		return strings.Contains(string(body), m.Value)
+	default:
+		return false
+	}
+}
+
+// writeHTTPResponse writes the response and returns the status code that was sent
+func writeHTTPResponse(w http.ResponseWriter, response HTTPResponse) int {
+	status := response.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	body := []byte(response.Body)
+	switch {
+	case response.JSON != nil:
+		body = response.JSON
+		w.Header().Set("Content-Type", "application/json")
+	case response.BodyBase64 != "":
+		decoded, err := base64.StdEncoding.DecodeString(response.BodyBase64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid body_base64 in mock response: %v", err), http.StatusInternalServerError)
+			return http.StatusInternalServerError
+		}
+		body = decoded
+	case response.BodyFile != "":
+		data, err := os.ReadFile(response.BodyFile)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid body_file in mock response: %v", err), http.StatusInternalServerError)
+			return http.StatusInternalServerError
+		}
+		body = data
+	}
+	for k, v := range response.Headers {
+		w.Header().Set(k, v)
+	}
+	if response.ContentType != "" {
+		w.Header().Set("Content-Type", response.ContentType)
+	}
+
+	w.WriteHeader(status)
+	w.Write(body) //nolint:errcheck
+	return status
+}
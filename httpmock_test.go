@@ -0,0 +1,128 @@
+package mockllm_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPMock(t *testing.T) {
+	config := mockllm.Config{
+		HTTP: []mockllm.HTTPMock{
+			{
+				Name:   "token",
+				Method: "POST",
+				Path:   "/oauth/token",
+				Body:   &mockllm.HTTPBodyMatch{MatchType: mockllm.MatchTypeContains, Value: "grant_type=client_credentials"},
+				Response: mockllm.HTTPResponse{
+					JSON: json.RawMessage(`{"access_token":"abc","expires_in":3600}`),
+				},
+			},
+			{
+				Name: "vector-search",
+				Path: "/collections/*/points/search",
+				Body: &mockllm.HTTPBodyMatch{MatchType: mockllm.MatchTypeExact, Value: `{"limit": 1}`},
+				Response: mockllm.HTTPResponse{
+					Status:  http.StatusAccepted,
+					Headers: map[string]string{"Content-Type": "text/plain"},
+					Body:    "ok",
+				},
+			},
+		},
+	}
+
+	server := mockllm.NewServer(mockllm.WithConfig(config))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	resp, err := http.Post(baseURL+"/oauth/token", "application/x-www-form-urlencoded",
+		strings.NewReader("grant_type=client_credentials&client_id=x"))
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var token map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&token))
+	assert.Equal(t, "abc", token["access_token"])
+
+	resp, err = http.Post(baseURL+"/collections/docs/points/search", "application/json",
+		strings.NewReader(`{"limit":1}`))
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(body))
+
+	// A body that does not match falls through to the not found handler
+	resp, err = http.Post(baseURL+"/collections/docs/points/search", "application/json",
+		strings.NewReader(`{"limit":2}`))
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	requests := server.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, "token", requests[0].MockName)
+	assert.Equal(t, "vector-search", requests[1].MockName)
+}
+
+func TestHTTPMockBinaryBodies(t *testing.T) {
+	// An MP3 frame header and a PNG signature
+	speech := []byte{0xff, 0xfb, 0x90, 0x64, 0x00}
+	image := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "image.png"), image, 0o600))
+
+	config, err := mockllm.LoadConfigFromFile("suite/mocks.json", fstest.MapFS{
+		"suite/mocks.json": {Data: []byte(`{"http": [
+  {"name": "speech", "method": "POST", "path": "/v1/audio/speech", "response": {"body_file": "audio/speech.mp3", "content_type": "audio/mpeg"}}
+]}`)},
+		"suite/audio/speech.mp3": {Data: speech},
+	})
+	require.NoError(t, err)
+	config.HTTP = append(config.HTTP,
+		mockllm.HTTPMock{Name: "image", Path: "/images/cat", Response: mockllm.HTTPResponse{BodyFile: filepath.Join(dir, "image.png")}},
+		mockllm.HTTPMock{Name: "mislabeled", Path: "/images/dog", Response: mockllm.HTTPResponse{
+			BodyBase64:  base64.StdEncoding.EncodeToString(image),
+			ContentType: "application/octet-stream",
+		}},
+	)
+	server := mockllm.NewServer(mockllm.WithConfig(config))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(context.Background()) }) //nolint:errcheck
+
+	get := func(method, path string) (string, []byte) {
+		req, err := http.NewRequest(method, baseURL+path, nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint:errcheck
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.Header.Get("Content-Type"), body
+	}
+
+	contentType, body := get(http.MethodPost, "/v1/audio/speech")
+	assert.Equal(t, "audio/mpeg", contentType)
+	assert.Equal(t, speech, body)
+	contentType, body = get(http.MethodGet, "/images/cat")
+	assert.Equal(t, "image/png", contentType, "the type of bodies without a content type is sniffed")
+	assert.Equal(t, image, body)
+	contentType, body = get(http.MethodGet, "/images/dog")
+	assert.Equal(t, "application/octet-stream", contentType)
+	assert.Equal(t, image, body)
+}
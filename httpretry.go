@@ -0,0 +1,100 @@
+package mockllm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DoHTTP sends the request built by newRequest with client, retrying connection errors and the
+// statuses the provider SDKs retry: 408, 409, 429 and 5xx, unless the response sets
+// x-should-retry: false. Instead of the backoff it waits as long as the upstream asks, through
+// retry-after-ms, Retry-After, or the reset time of an exhausted OpenAI or Anthropic rate limit,
+// capped at MaxDelay. newRequest is called for every attempt, so request bodies can be replayed.
+// Once the attempts run out the last response is returned for the caller to inspect.
+func (p RetryPolicy) DoHTTP(ctx context.Context, client *http.Client,
+	newRequest func(ctx context.Context) (*http.Request, error)) (*http.Response, error) {
+	clock := p.Clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+
+	var response *http.Response
+	err := p.retry(ctx, func(last bool) (time.Duration, bool, error) {
+		request, err := newRequest(ctx)
+		if err != nil {
+			return 0, false, err
+		}
+		if response, err = client.Do(request); err != nil {
+			return 0, ctx.Err() == nil, err
+		}
+		if last || !retryableResponse(response) {
+			return 0, false, nil
+		}
+
+		delay, _ := retryAfter(response.Header, clock.Now())
+		if p.MaxDelay > 0 {
+			delay = min(delay, p.MaxDelay)
+		}
+		// Drain the body so the connection can be reused
+		io.Copy(io.Discard, response.Body) //nolint:errcheck
+		response.Body.Close()              //nolint:errcheck
+		return delay, true, fmt.Errorf("upstream responded %s", response.Status)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// retryableResponse reports whether a response is worth retrying
+func retryableResponse(response *http.Response) bool {
+	switch response.Header.Get("x-should-retry") {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	status := response.StatusCode
+	return status == http.StatusRequestTimeout || status == http.StatusConflict ||
+		status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// retryAfter returns how long an upstream asked clients to wait before retrying
+func retryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	if ms, err := strconv.ParseFloat(header.Get("retry-after-ms"), 64); err == nil && ms >= 0 {
+		return time.Duration(ms * float64(time.Millisecond)), true
+	}
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+			return time.Duration(seconds * float64(time.Second)), true
+		}
+		if at, err := http.ParseTime(value); err == nil {
+			return max(at.Sub(now), 0), true
+		}
+	}
+
+	// Rate limit headers, waiting for the latest reset among the exhausted limits
+	var delay time.Duration
+	found := false
+	for _, limit := range []string{"requests", "tokens"} {
+		if header.Get("x-ratelimit-remaining-"+limit) != "0" {
+			continue
+		}
+		if reset, err := time.ParseDuration(header.Get("x-ratelimit-reset-" + limit)); err == nil {
+			delay, found = max(delay, reset), true
+		}
+	}
+	for _, limit := range []string{"requests", "tokens", "input-tokens", "output-tokens"} {
+		if header.Get("anthropic-ratelimit-"+limit+"-remaining") != "0" {
+			continue
+		}
+		if reset, err := time.Parse(time.RFC3339, header.Get("anthropic-ratelimit-"+limit+"-reset")); err == nil {
+			delay, found = max(delay, reset.Sub(now)), true
+		}
+	}
+	return delay, found
+}
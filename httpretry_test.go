@@ -0,0 +1,98 @@
+package mockllm_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicyDoHTTP(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := mockllm.NewFakeClock(start)
+
+	responses := []func(w http.ResponseWriter){
+		func(w http.ResponseWriter) {
+			w.Header().Set("anthropic-ratelimit-requests-remaining", "0")
+			w.Header().Set("anthropic-ratelimit-requests-reset", start.Add(30*time.Second).Format(time.RFC3339))
+			w.Header().Set("anthropic-ratelimit-tokens-remaining", "1000")
+			w.Header().Set("anthropic-ratelimit-tokens-reset", start.Add(time.Hour).Format(time.RFC3339))
+			w.WriteHeader(http.StatusTooManyRequests)
+		},
+		func(w http.ResponseWriter) {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		},
+		func(w http.ResponseWriter) {
+			w.Header().Set("x-ratelimit-remaining-tokens", "0")
+			w.Header().Set("x-ratelimit-reset-tokens", "6m0s")
+			w.WriteHeader(http.StatusTooManyRequests)
+		},
+		func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusInternalServerError)
+		},
+		func(w http.ResponseWriter) {
+			w.Write([]byte("ok")) //nolint:errcheck
+		},
+	}
+	requests := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "hello", string(body))
+		responses[requests](w)
+		requests++
+	}))
+	defer upstream.Close()
+
+	var delays []time.Duration
+	policy := mockllm.RetryPolicy{
+		Attempts:  5,
+		BaseDelay: time.Second,
+		MaxDelay:  time.Minute,
+		Clock:     clock,
+		OnRetry: func(_ int, _ error, delay time.Duration) {
+			delays = append(delays, delay)
+			go func() {
+				require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+				clock.Advance(delay)
+			}()
+		},
+	}
+	newRequest := func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodPost, upstream.URL, strings.NewReader("hello"))
+	}
+	resp, err := policy.DoHTTP(t.Context(), upstream.Client(), newRequest)
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []time.Duration{30 * time.Second, 2 * time.Second, time.Minute, 8 * time.Second}, delays)
+
+	// Once the attempts run out the last response is returned as is
+	requests, delays = 0, nil
+	policy.Attempts = 1
+	resp, err = policy.DoHTTP(t.Context(), upstream.Client(), newRequest)
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Empty(t, delays)
+
+	// Upstreams can veto retries
+	requests = 0
+	responses[0] = func(w http.ResponseWriter) {
+		w.Header().Set("x-should-retry", "false")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	policy.Attempts = 5
+	resp, err = policy.DoHTTP(t.Context(), upstream.Client(), newRequest)
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, 1, requests)
+}
@@ -0,0 +1,169 @@
+package mockllm
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+const (
+	// idempotencyKeyHeader names the request header carrying an idempotency key
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayedHeader is set on responses replayed for a repeated idempotency key
+	idempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// idempotencyKey identifies the requests sharing an idempotency key, which is scoped to the API key
+type idempotencyKey struct{ provider, apiKey, key string }
+
+// idempotentResponse is the outcome of the first request with an idempotency key
+type idempotentResponse struct {
+	method, path string
+	body         []byte
+	// done is closed once the first request completed, after which status, header and response are
+	// set, and original is the logged request whose response they are
+	done     chan struct{}
+	status   int
+	header   http.Header
+	response []byte
+	original RequestRecord
+}
+
+// idempotencyStore keeps the responses of requests with an idempotency key for the lifetime of the server
+type idempotencyStore struct {
+	mu        sync.Mutex
+	responses map[idempotencyKey]*idempotentResponse
+}
+
+func newIdempotencyStore() *idempotencyStore {
+	return &idempotencyStore{responses: map[idempotencyKey]*idempotentResponse{}}
+}
+
+// idempotencyRecorder passes a response through to the client while keeping a copy of it
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (r *idempotencyRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status, r.header = status, r.ResponseWriter.Header().Clone()
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *idempotencyRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// idempotent replays the stored response of POST requests repeating the Idempotency-Key of an
+// earlier successful request, without handling them again. Requests reusing a key that is still
+// being handled get a 409, and those reusing a key with another endpoint or body a 400. Failed
+// requests, those logged with an error included, are not stored, so their retries are handled again.
+func (s *Server) idempotent(provider Provider, next http.HandlerFunc) http.HandlerFunc {
+	providerName := builtinProviderName(provider)
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" || r.Method != http.MethodPost || providerName == "" {
+			next(w, r)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeProviderError(w, provider, http.StatusBadRequest, fmt.Sprintf("Failed to read request body: %v", err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		storeKey := idempotencyKey{providerName, requestAPIKey(r), key}
+		s.idempotency.mu.Lock()
+		stored, ok := s.idempotency.responses[storeKey]
+		if !ok {
+			stored = &idempotentResponse{method: r.Method, path: r.URL.Path, body: body, done: make(chan struct{})}
+			s.idempotency.responses[storeKey] = stored
+		}
+		s.idempotency.mu.Unlock()
+
+		if !ok {
+			recorder := &idempotencyRecorder{ResponseWriter: w}
+			served := false
+			// Resolve the key even when the handler panics, so its retries aren't rejected for good
+			defer func() {
+				var original RequestRecord
+				succeeded := false
+				if served && recorder.status >= 200 && recorder.status < 300 {
+					original, succeeded = s.requestLog.lastWithIdempotencyKey(providerName, requestAPIKey(r), key)
+				}
+				s.idempotency.mu.Lock()
+				defer s.idempotency.mu.Unlock()
+				// Requests that failed after sending a success status, such as dropped connections and
+				// mid-stream errors, are handled again too
+				if succeeded && original.Error == "" {
+					stored.status, stored.header, stored.response = recorder.status, recorder.header, recorder.body.Bytes()
+					stored.original = original
+					close(stored.done)
+					return
+				}
+				delete(s.idempotency.responses, storeKey)
+			}()
+			next(recorder, r)
+			served = true
+			return
+		}
+
+		record := s.requestLog.newRecord(r, providerName, body)
+		if t := s.tenants[record.apiKey]; t != nil {
+			record.Tenant = t.config.Name
+		}
+		defer s.requestLog.Add(&record)
+		select {
+		case <-stored.done:
+		default:
+			record.Status, record.Error = http.StatusConflict, "idempotency key in use"
+			writeProviderError(w, provider, http.StatusConflict,
+				"Another request with the same Idempotency-Key is still being processed.")
+			return
+		}
+		if stored.method != r.Method || stored.path != r.URL.Path || !bytes.Equal(stored.body, body) {
+			record.Status, record.Error = http.StatusBadRequest, "idempotency key reused with another request"
+			writeProviderError(w, provider, http.StatusBadRequest,
+				"Keys for idempotent requests can only be used with the same parameters they were first used with.")
+			return
+		}
+
+		record.Status, record.ReplayOf, record.MockName = stored.status, stored.original.ID, stored.original.MockName
+		for name, values := range stored.header {
+			w.Header()[name] = values
+		}
+		w.Header().Set(idempotentReplayedHeader, "true")
+		w.WriteHeader(stored.status)
+		w.Write(stored.response) //nolint:errcheck
+	}
+}
+
+// lastWithIdempotencyKey returns the latest logged request with an idempotency key that got a
+// success status without being a replay, the one whose response is stored for the key
+func (l *RequestLog) lastWithIdempotencyKey(provider, apiKey, key string) (RequestRecord, bool) {
+	records := l.Records()
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		if record.Provider == provider && record.apiKey == apiKey && record.IdempotencyKey == key &&
+			record.ReplayOf == 0 && record.Status >= 200 && record.Status < 300 {
+			return record, true
+		}
+	}
+	return RequestRecord{}, false
+}
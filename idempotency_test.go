@@ -0,0 +1,92 @@
+package mockllm_test
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	slow := replyMock("slow", "Slowly")
+	slow.Match.Message = openaiUserMessage("Slow")
+	slow.Fault = &mockllm.Fault{Type: mockllm.FaultStall, Duration: mockllm.Duration(300 * time.Millisecond)}
+	cut := replyMock("cut", "The quick brown fox jumps over the lazy dog")
+	cut.Match.Message = openaiUserMessage("Cut")
+	cut.StreamError = &mockllm.StreamError{AfterEvents: 3}
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{replyMock("hello", "Hi"), slow, cut},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+
+	post := func(content, key string) (*http.Response, string) {
+		resp := postJSON(t, baseURL+"/v1/chat/completions", openai.ChatCompletionNewParams{
+			Model:    "gpt-4o-mini",
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage(content)},
+		}, map[string]string{"Authorization": "Bearer test-key", "Idempotency-Key": key})
+		defer resp.Body.Close() //nolint:errcheck
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(body)
+	}
+
+	first, firstBody := post("Hello", "key-1")
+	require.Equal(t, http.StatusOK, first.StatusCode)
+	replay, replayBody := post("Hello", "key-1")
+	assert.Equal(t, http.StatusOK, replay.StatusCode)
+	assert.Equal(t, "true", replay.Header.Get("Idempotent-Replayed"))
+	assert.Empty(t, first.Header.Get("Idempotent-Replayed"))
+	assert.Equal(t, firstBody, replayBody)
+
+	// Keys are only replayed for the same request
+	mismatch, _ := post("Hello there", "key-1")
+	assert.Equal(t, http.StatusBadRequest, mismatch.StatusCode)
+
+	// Failed requests are handled again
+	unmatched, _ := post("Goodbye", "key-2")
+	assert.Equal(t, http.StatusNotFound, unmatched.StatusCode)
+	unmatched, _ = post("Goodbye", "key-2")
+	assert.Equal(t, http.StatusNotFound, unmatched.StatusCode)
+	assert.Empty(t, unmatched.Header.Get("Idempotent-Replayed"))
+
+	// A key in use by a request still being handled is rejected
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		post("Slow", "key-3")
+	}()
+	time.Sleep(100 * time.Millisecond)
+	conflict, _ := post("Slow", "key-3")
+	assert.Equal(t, http.StatusConflict, conflict.StatusCode)
+	<-done
+
+	requests := server.Requests()
+	require.Len(t, requests, 7)
+	assert.Equal(t, "key-1", requests[0].IdempotencyKey)
+	assert.Equal(t, 1, requests[1].ReplayOf)
+	assert.Equal(t, "hello", requests[1].MockName)
+	assert.False(t, requests[1].Matched)
+	assert.Zero(t, requests[1].DuplicateOf, "replays are not duplicates")
+	assert.Zero(t, requests[4].ReplayOf)
+	assert.Equal(t, 1, server.Mocks()[0].Hits)
+
+	// Streams failing after a success status are handled again
+	for range 2 {
+		resp := postJSON(t, baseURL+"/v1/chat/completions", map[string]any{
+			"model":    "gpt-4o-mini",
+			"messages": []map[string]string{{"role": "user", "content": "Cut"}},
+			"stream":   true,
+		}, map[string]string{"Authorization": "Bearer test-key", "Idempotency-Key": "key-4"})
+		io.Copy(io.Discard, resp.Body) //nolint:errcheck
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Idempotent-Replayed"))
+	}
+	assert.Equal(t, 2, server.Mocks()[2].Hits)
+}
@@ -0,0 +1,73 @@
+package mockllm
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// IDConfig controls the IDs of the objects created through the stateful emulations: files,
+// fine-tuning jobs, vector stores and message batches
+type IDConfig struct {
+	// Seed derives IDs from the seed and the number of objects of their kind created before,
+	// e.g. file-3f9a..., instead of numbering them as file-000001. IDs stay reproducible from run to
+	// run, while servers with different seeds don't hand out the same IDs to services persisting
+	// them.
+	Seed uint64 `json:"seed"`
+}
+
+// IssuedID is an ID a server generated for an object created through a stateful emulation
+type IssuedID struct {
+	ID string `json:"id"`
+	// Kind is the object type of the API, e.g. file, fine_tuning.job, vector_store or message_batch
+	Kind string `json:"kind"`
+	// Tenant is the virtual tenant the object was created by, empty for the default mocks
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// idSource generates the IDs of a server, numbering the objects of each kind across its tenants
+type idSource struct {
+	mu     sync.Mutex
+	config *IDConfig
+	counts map[string]int
+	issued []IssuedID
+}
+
+func newIDSource(config *IDConfig) *idSource {
+	return &idSource{config: config, counts: map[string]int{}}
+}
+
+// next returns a new ID with the given prefix for an object of a kind
+func (s *idSource) next(kind, prefix, tenant string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counts[kind]++
+	id := fmt.Sprintf("%s%06d", prefix, s.counts[kind])
+	if s.config != nil {
+		seed := binary.BigEndian.AppendUint64(nil, s.config.Seed)
+		sum := sha256.Sum256(fmt.Appendf(seed, "%s/%d", kind, s.counts[kind]))
+		id = prefix + hex.EncodeToString(sum[:12])
+	}
+	s.issued = append(s.issued, IssuedID{ID: id, Kind: kind, Tenant: tenant})
+	return id
+}
+
+func (s *idSource) list() []IssuedID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]IssuedID{}, s.issued...)
+}
+
+// IDs returns the IDs generated so far for the objects created through the stateful emulations,
+// in the order they were created, e.g. to check the IDs a service under test persisted
+func (s *Server) IDs() []IssuedID {
+	return s.ids.list()
+}
+
+func (s *Server) handleAdminIDs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.IDs())
+}
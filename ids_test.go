@@ -0,0 +1,65 @@
+package mockllm_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIDs(t *testing.T) {
+	upload := func(baseURL string) string {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		require.NoError(t, form.WriteField("purpose", "assistants"))
+		part, err := form.CreateFormFile("file", "notes.txt")
+		require.NoError(t, err)
+		_, err = part.Write([]byte("hello"))
+		require.NoError(t, err)
+		require.NoError(t, form.Close())
+
+		req, err := http.NewRequest(http.MethodPost, baseURL+"/v1/files", &body)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer test-key")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint:errcheck
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var file struct {
+			ID string `json:"id"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&file))
+		return file.ID
+	}
+	uploads := func(config mockllm.Config) []string {
+		server := mockllm.NewServer(mockllm.WithConfig(config))
+		baseURL, err := server.Start(t.Context())
+		require.NoError(t, err)
+		defer server.Stop(context.Background()) //nolint:errcheck
+
+		ids := []string{upload(baseURL), upload(baseURL)}
+		assert.Equal(t, []mockllm.IssuedID{{ID: ids[0], Kind: "file"}, {ID: ids[1], Kind: "file"}}, server.IDs())
+		resp, err := http.Get(baseURL + "/admin/ids")
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint:errcheck
+		var issued []mockllm.IssuedID
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&issued))
+		assert.Equal(t, server.IDs(), issued)
+		return ids
+	}
+
+	assert.Equal(t, []string{"file-000001", "file-000002"}, uploads(mockllm.Config{}))
+
+	seeded := uploads(mockllm.Config{IDs: &mockllm.IDConfig{Seed: 7}})
+	assert.Regexp(t, `^file-[0-9a-f]{24}$`, seeded[0])
+	assert.NotEqual(t, seeded[0], seeded[1])
+	assert.Equal(t, seeded, uploads(mockllm.Config{IDs: &mockllm.IDConfig{Seed: 7}}), "IDs are reproducible")
+	assert.NotEqual(t, seeded, uploads(mockllm.Config{IDs: &mockllm.IDConfig{Seed: 8}}))
+}
@@ -0,0 +1,701 @@
+package mockllm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"mime"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+)
+
+// importedStub is a stub of another mocking tool for an OpenAI or Anthropic chat endpoint,
+// reduced to what mockllm mocks express
+type importedStub struct {
+	name     string
+	provider string
+	// fields and headers are the match criteria, as the patterns of OpenAIRequestMatch.Fields and
+	// OpenAIRequestMatch.Headers
+	fields  map[string]string
+	headers map[string]string
+
+	status          int
+	responseHeaders map[string]string
+	body            []byte
+	bodyFile        string
+	delay           time.Duration
+}
+
+// importedMock is the config entry of an imported stub. Responses are kept verbatim, since the
+// SDK response types serialize every zero valued field.
+type importedMock struct {
+	Name  string `json:"name"`
+	Match struct {
+		MatchType MatchType         `json:"match_type"`
+		Headers   map[string]string `json:"headers,omitempty"`
+		Fields    map[string]string `json:"fields,omitempty"`
+	} `json:"match"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Raw      *HTTPResponse   `json:"raw,omitempty"`
+	Fault    *Fault          `json:"fault,omitempty"`
+}
+
+// marshalImportedStubs renders stubs as a config. The stubs whose response is a well formed
+// response of their provider become regular mocks, the others raw ones.
+func marshalImportedStubs(stubs []importedStub, skipped []string) ([]byte, []string, error) {
+	config := struct {
+		OpenAI    []importedMock `json:"openai,omitempty"`
+		Anthropic []importedMock `json:"anthropic,omitempty"`
+	}{}
+	for _, stub := range stubs {
+		mock := importedMock{Name: stub.name}
+		mock.Match.MatchType = MatchTypeFields
+		mock.Match.Headers, mock.Match.Fields = stub.headers, stub.fields
+
+		if response := stub.providerResponse(); response != nil {
+			mock.Response = response
+			if stub.delay > 0 {
+				mock.Fault = &Fault{Type: FaultStall, Duration: Duration(stub.delay)}
+			}
+		} else {
+			mock.Raw = stub.rawResponse()
+			if stub.delay > 0 {
+				skipped = append(skipped, fmt.Sprintf("%s: delay dropped, raw responses are served at once", stub.name))
+			}
+		}
+		if stub.provider == "openai" {
+			config.OpenAI = append(config.OpenAI, mock)
+		} else {
+			config.Anthropic = append(config.Anthropic, mock)
+		}
+	}
+	out, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, skipped, nil
+}
+
+// providerResponse returns the compacted body of a stub when it is a successful, plain JSON
+// response of its provider, or nil
+func (s importedStub) providerResponse() json.RawMessage {
+	if s.status != http.StatusOK || s.bodyFile != "" {
+		return nil
+	}
+	for name, value := range s.responseHeaders {
+		mediaType, _, _ := mime.ParseMediaType(value)
+		if !strings.EqualFold(name, "Content-Type") || mediaType != "application/json" {
+			return nil
+		}
+	}
+	var ok bool
+	if s.provider == "openai" {
+		var completion openai.ChatCompletion
+		ok = json.Unmarshal(s.body, &completion) == nil && len(completion.Choices) > 0
+	} else {
+		var message anthropic.Message
+		ok = json.Unmarshal(s.body, &message) == nil && message.Type == "message"
+	}
+	var compact bytes.Buffer
+	if !ok || json.Compact(&compact, s.body) != nil {
+		return nil
+	}
+	return compact.Bytes()
+}
+
+// rawResponse returns the response of a stub served as is
+func (s importedStub) rawResponse() *HTTPResponse {
+	raw := &HTTPResponse{Status: s.status, Headers: maps.Clone(s.responseHeaders), BodyFile: s.bodyFile}
+	switch {
+	case s.bodyFile != "":
+	case json.Valid(s.body):
+		var compact bytes.Buffer
+		json.Compact(&compact, s.body) //nolint:errcheck
+		raw.JSON = compact.Bytes()
+		delete(raw.Headers, "Content-Type")
+	case utf8.Valid(s.body):
+		raw.Body = string(s.body)
+	default:
+		raw.BodyBase64 = base64.StdEncoding.EncodeToString(s.body)
+	}
+	if len(raw.Headers) == 0 {
+		raw.Headers = nil
+	}
+	return raw
+}
+
+// exactFieldPattern returns a field pattern matching exactly the value
+func exactFieldPattern(value string) string {
+	if strings.ContainsAny(value, "*?") || (len(value) >= 2 && strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/")) {
+		return "/^(?s:" + regexp.QuoteMeta(value) + ")$/"
+	}
+	return value
+}
+
+// containsFieldPattern returns a field pattern matching JSON encoded values containing the text
+func containsFieldPattern(text string) string {
+	encoded, _ := json.Marshal(text)
+	return "/" + regexp.QuoteMeta(string(encoded[1:len(encoded)-1])) + "/"
+}
+
+// exactHeaderPattern returns a header pattern, a path.Match pattern, matching exactly the value
+func exactHeaderPattern(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`).Replace(value)
+}
+
+// flattenFields adds the field patterns requiring the leaves of a JSON document to equal those of
+// value, e.g. messages[0].content for {"messages":[{"content":"Hello"}]}. WireMock's JSON unit
+// placeholders match any value, or a regular expression.
+func flattenFields(fields map[string]string, path string, value any) {
+	join := func(name string) string {
+		if path == "" {
+			return name
+		}
+		return path + "." + name
+	}
+	switch value := value.(type) {
+	case map[string]any:
+		if len(value) == 0 && path != "" {
+			fields[path] = "{}"
+		}
+		for name, child := range value {
+			flattenFields(fields, join(name), child)
+		}
+	case []any:
+		if len(value) == 0 {
+			fields[path] = "[]"
+		}
+		for i, child := range value {
+			flattenFields(fields, path+"["+strconv.Itoa(i)+"]", child)
+		}
+	case string:
+		switch {
+		case value == "${json-unit.any-string}" || value == "${json-unit.ignore}":
+			fields[path] = "*"
+		case strings.HasPrefix(value, "${json-unit.regex}"):
+			fields[path] = "/" + strings.TrimPrefix(value, "${json-unit.regex}") + "/"
+		default:
+			fields[path] = exactFieldPattern(value)
+		}
+	default:
+		fields[path] = exactFieldPattern(fieldText(value))
+	}
+}
+
+// anyJSONString matches a JSON encoded string
+const anyJSONString = `"(?:[^"\\]|\\.)*"`
+
+// shapeFields adds the field patterns requiring the objects of a JSON document to have exactly the
+// keys of those of value, and its arrays exactly their elements, as WireMock's equalToJson does
+// unless extraKeys (ignoreExtraElements) or extraElements (ignoreExtraInArrayElements) allow more.
+// The patterns match the encoded containers as a whole, so it returns why it cannot when a
+// container that must be exact holds one that need not be, or a ${json-unit.ignore} placeholder.
+func shapeFields(fields map[string]string, path string, value any, extraKeys, extraElements bool) string {
+	join := func(name string) string {
+		if path == "" {
+			return name
+		}
+		return path + "." + name
+	}
+	var children map[string]any
+	switch value := value.(type) {
+	case map[string]any:
+		if !extraKeys {
+			return exactShape(fields, path, value, extraKeys, extraElements)
+		}
+		children = map[string]any{}
+		for name, child := range value {
+			children[join(name)] = child
+		}
+	case []any:
+		if !extraElements {
+			return exactShape(fields, path, value, extraKeys, extraElements)
+		}
+		children = map[string]any{}
+		for i, child := range value {
+			children[path+"["+strconv.Itoa(i)+"]"] = child
+		}
+	}
+	for _, childPath := range sortedKeys(children) {
+		if reason := shapeFields(fields, childPath, children[childPath], extraKeys, extraElements); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// exactShape adds the pattern matching the encoded container at path, or returns why it cannot
+func exactShape(fields map[string]string, path string, value any, extraKeys, extraElements bool) string {
+	pattern, reason := shapePattern(value, extraKeys, extraElements)
+	if reason == "" {
+		fields[path] = "/^" + pattern + "$/"
+	}
+	return reason
+}
+
+// shapePattern returns a regular expression matching the JSON encoding of values equal to value,
+// with any string in place of placeholders, whose own criteria are added by flattenFields, or why
+// value cannot be matched exactly
+func shapePattern(value any, extraKeys, extraElements bool) (string, string) {
+	var parts []string
+	switch value := value.(type) {
+	case map[string]any:
+		if extraKeys {
+			return "", "equalToJson objects allowing extra keys cannot be nested in arrays that must match exactly, " +
+				"set ignoreExtraInArrayElements too"
+		}
+		for _, name := range sortedKeys(value) {
+			child, reason := shapePattern(value[name], extraKeys, extraElements)
+			if reason != "" {
+				return "", reason
+			}
+			key, _ := json.Marshal(name)
+			parts = append(parts, regexp.QuoteMeta(string(key)+":")+child)
+		}
+		return `\{` + strings.Join(parts, ",") + `\}`, ""
+	case []any:
+		if extraElements {
+			return "", "equalToJson arrays allowing extra elements cannot be nested in objects that must match exactly, " +
+				"set ignoreExtraElements too"
+		}
+		for _, element := range value {
+			child, reason := shapePattern(element, extraKeys, extraElements)
+			if reason != "" {
+				return "", reason
+			}
+			parts = append(parts, child)
+		}
+		return `\[` + strings.Join(parts, ",") + `\]`, ""
+	case string:
+		switch {
+		case value == "${json-unit.ignore}":
+			return "", "${json-unit.ignore} cannot be matched in equalToJson documents that must match exactly, " +
+				"set ignoreExtraElements and ignoreExtraInArrayElements"
+		case value == "${json-unit.any-string}" || strings.HasPrefix(value, "${json-unit.regex}"):
+			return anyJSONString, ""
+		}
+	}
+	encoded, _ := json.Marshal(value)
+	return regexp.QuoteMeta(string(encoded)), ""
+}
+
+// simpleJSONPath matches the JSONPath expressions that are plain field paths, such as
+// $.messages[0].content
+var simpleJSONPath = regexp.MustCompile(`^\$((\.[A-Za-z_][A-Za-z0-9_-]*)|(\[(\d+|\*)\]))+$`)
+
+// jsonPathField returns the field path of a plain JSONPath expression, or false
+func jsonPathField(expression string) (string, bool) {
+	if !simpleJSONPath.MatchString(expression) {
+		return "", false
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(expression, "$"), "."), true
+}
+
+// wireMockMapping is a WireMock stub mapping, see https://wiremock.org/docs/stubbing/
+type wireMockMapping struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Priority int    `json:"priority"`
+	Request  struct {
+		Method         string                     `json:"method"`
+		URL            string                     `json:"url"`
+		URLPath        string                     `json:"urlPath"`
+		URLPattern     string                     `json:"urlPattern"`
+		URLPathPattern string                     `json:"urlPathPattern"`
+		Headers        map[string]wireMockPattern `json:"headers"`
+		BodyPatterns   []wireMockPattern          `json:"bodyPatterns"`
+	} `json:"request"`
+	Response struct {
+		Status                 int                        `json:"status"`
+		Headers                map[string]json.RawMessage `json:"headers"`
+		Body                   string                     `json:"body"`
+		JSONBody               json.RawMessage            `json:"jsonBody"`
+		Base64Body             string                     `json:"base64Body"`
+		BodyFileName           string                     `json:"bodyFileName"`
+		FixedDelayMilliseconds int                        `json:"fixedDelayMilliseconds"`
+	} `json:"response"`
+}
+
+// wireMockPattern is a WireMock matcher, e.g. {"equalTo": "value"}, with its modifiers
+type wireMockPattern map[string]json.RawMessage
+
+// ImportWireMock converts the WireMock stub mappings of OpenAI chat completion and Anthropic
+// Messages API paths into a config of mocks, tried in the order of their priority. The documents
+// are mapping files, holding either a "mappings" array or a single mapping. Other stubs, and those
+// whose matchers mockllm cannot express, are skipped, each with the reason returned.
+//
+// equalToJson body patterns become fields criteria on the leaves of the expected document, along
+// with criteria requiring its objects to have the same keys and its arrays the same elements unless
+// ignoreExtraElements or ignoreExtraInArrayElements is set, and contains patterns criteria on the
+// text of the conversation.
+func ImportWireMock(documents ...[]byte) ([]byte, []string, error) {
+	var mappings []wireMockMapping
+	for i, data := range documents {
+		var document struct {
+			Mappings []wireMockMapping `json:"mappings"`
+		}
+		if err := json.Unmarshal(data, &document); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse WireMock mappings %d: %w", i, err)
+		}
+		if document.Mappings == nil {
+			var mapping wireMockMapping
+			if err := json.Unmarshal(data, &mapping); err != nil {
+				return nil, nil, fmt.Errorf("failed to parse WireMock mapping %d: %w", i, err)
+			}
+			document.Mappings = []wireMockMapping{mapping}
+		}
+		mappings = append(mappings, document.Mappings...)
+	}
+	for i := range mappings {
+		if mappings[i].Name == "" {
+			mappings[i].Name = mappings[i].ID
+		}
+		if mappings[i].Name == "" {
+			mappings[i].Name = "wiremock-" + strconv.Itoa(i)
+		}
+	}
+	// WireMock serves the stub with the lowest priority number, the stubs without one last
+	sort.SliceStable(mappings, func(i, j int) bool {
+		pi, pj := mappings[i].Priority, mappings[j].Priority
+		return pi != 0 && (pj == 0 || pi < pj)
+	})
+
+	var stubs []importedStub
+	var skipped []string
+	for _, mapping := range mappings {
+		stub, reason := mapping.stub()
+		if reason != "" {
+			skipped = append(skipped, mapping.Name+": "+reason)
+			continue
+		}
+		stubs = append(stubs, stub)
+	}
+	return marshalImportedStubs(stubs, skipped)
+}
+
+// stub converts the mapping, or returns why it cannot be
+func (m wireMockMapping) stub() (importedStub, string) {
+	request, response := m.Request, m.Response
+	if request.Method != "" && request.Method != http.MethodPost && request.Method != "ANY" {
+		return importedStub{}, fmt.Sprintf("method %s is not POST", request.Method)
+	}
+	urlPath, _, _ := strings.Cut(request.URL+request.URLPath, "?")
+	if urlPath == "" {
+		// A pattern whose tail is literal, such as .*/v1/messages, still names the endpoint
+		urlPath = strings.TrimSuffix(request.URLPattern+request.URLPathPattern, "$")
+	}
+	stub := importedStub{
+		name:            m.Name,
+		provider:        chatEndpointProvider(urlPath),
+		fields:          map[string]string{},
+		headers:         map[string]string{},
+		status:          response.Status,
+		responseHeaders: map[string]string{},
+		bodyFile:        response.BodyFileName,
+		delay:           time.Duration(response.FixedDelayMilliseconds) * time.Millisecond,
+	}
+	if stub.provider == "" {
+		return importedStub{}, fmt.Sprintf("URL %q is not a chat completion or Messages API path", urlPath)
+	}
+
+	for _, name := range sortedKeys(request.Headers) {
+		pattern := request.Headers[name]
+		var value string
+		switch {
+		case pattern.decode("equalTo", &value) && !pattern.has("caseInsensitive"):
+			stub.headers[name] = exactHeaderPattern(value)
+		case pattern.decode("contains", &value):
+			stub.headers[name] = "*" + exactHeaderPattern(value) + "*"
+		default:
+			return importedStub{}, fmt.Sprintf("unsupported matcher of header %s", name)
+		}
+	}
+	for _, pattern := range request.BodyPatterns {
+		if reason := pattern.addFields(stub.fields); reason != "" {
+			return importedStub{}, reason
+		}
+	}
+
+	switch {
+	case response.JSONBody != nil:
+		stub.body = response.JSONBody
+	case response.Base64Body != "":
+		body, err := base64.StdEncoding.DecodeString(response.Base64Body)
+		if err != nil {
+			return importedStub{}, fmt.Sprintf("invalid base64Body: %v", err)
+		}
+		stub.body = body
+	default:
+		stub.body = []byte(response.Body)
+	}
+	if stub.status == 0 {
+		stub.status = http.StatusOK
+	}
+	for _, name := range sortedKeys(response.Headers) {
+		// Headers are strings, or arrays of them for repeated headers
+		var value string
+		var values []string
+		if json.Unmarshal(response.Headers[name], &value) != nil {
+			if json.Unmarshal(response.Headers[name], &values) != nil || len(values) == 0 {
+				return importedStub{}, fmt.Sprintf("invalid value of response header %s", name)
+			}
+			value = values[0]
+		}
+		stub.responseHeaders[http.CanonicalHeaderKey(name)] = value
+	}
+	return stub, ""
+}
+
+// has reports whether the pattern sets the matcher or modifier to anything but false
+func (p wireMockPattern) has(name string) bool {
+	value, ok := p[name]
+	return ok && string(value) != "false"
+}
+
+// decode decodes the value of the matcher, reporting whether it is set
+func (p wireMockPattern) decode(name string, value any) bool {
+	raw, ok := p[name]
+	return ok && json.Unmarshal(raw, value) == nil
+}
+
+// addFields adds the fields criteria of a body pattern, or returns why it cannot be expressed
+func (p wireMockPattern) addFields(fields map[string]string) string {
+	var text string
+	var document any
+	switch {
+	case p.decode("equalToJson", &document), p.decode("equalTo", &text) && json.Unmarshal([]byte(text), &document) == nil:
+		// equalToJson is given as a document, or as a string holding one
+		if s, ok := document.(string); ok && json.Unmarshal([]byte(s), &document) != nil {
+			return "invalid equalToJson document"
+		}
+		flattenFields(fields, "", document)
+		return shapeFields(fields, "", document, p.has("ignoreExtraElements"), p.has("ignoreExtraInArrayElements"))
+	case p.decode("contains", &text):
+		fields["messages"] = containsFieldPattern(text)
+	case p.decode("matchesJsonPath", &text):
+		path, ok := jsonPathField(text)
+		if !ok {
+			return fmt.Sprintf("unsupported JSONPath %q", text)
+		}
+		fields[path] = "*"
+	default:
+		var expression struct {
+			Expression string  `json:"expression"`
+			EqualTo    *string `json:"equalTo"`
+			Contains   *string `json:"contains"`
+		}
+		if !p.decode("matchesJsonPath", &expression) {
+			return fmt.Sprintf("unsupported body pattern with %s", strings.Join(sortedKeys(p), ", "))
+		}
+		path, ok := jsonPathField(expression.Expression)
+		switch {
+		case !ok:
+			return fmt.Sprintf("unsupported JSONPath %q", expression.Expression)
+		case expression.EqualTo != nil:
+			fields[path] = exactFieldPattern(*expression.EqualTo)
+		case expression.Contains != nil:
+			fields[path] = containsFieldPattern(*expression.Contains)
+		default:
+			return fmt.Sprintf("unsupported matcher of JSONPath %q", expression.Expression)
+		}
+	}
+	return ""
+}
+
+// mockoonEnvironment is a Mockoon environment file, see https://mockoon.com/docs/latest/
+type mockoonEnvironment struct {
+	Routes []struct {
+		Method    string            `json:"method"`
+		Endpoint  string            `json:"endpoint"`
+		Responses []mockoonResponse `json:"responses"`
+	} `json:"routes"`
+}
+
+// mockoonResponse is one of the responses of a Mockoon route, served when its rules match
+type mockoonResponse struct {
+	Label      string `json:"label"`
+	StatusCode int    `json:"statusCode"`
+	Body       string `json:"body"`
+	Headers    []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	} `json:"headers"`
+	Latency       int           `json:"latency"`
+	BodyType      string        `json:"bodyType"`
+	FilePath      string        `json:"filePath"`
+	Rules         []mockoonRule `json:"rules"`
+	RulesOperator string        `json:"rulesOperator"`
+	Default       bool          `json:"default"`
+}
+
+// mockoonRule is a condition of a Mockoon response on the request
+type mockoonRule struct {
+	Target   string `json:"target"`
+	Modifier string `json:"modifier"`
+	Value    string `json:"value"`
+	Invert   bool   `json:"invert"`
+	Operator string `json:"operator"`
+}
+
+// ImportMockoon converts the responses of the routes of a Mockoon environment for OpenAI chat
+// completion and Anthropic Messages API paths into a config of mocks. The responses whose rules
+// match come first, in order, and the default response of a route last. Responses whose rules
+// mockllm cannot express, or that use Mockoon templating, are skipped, each with the reason
+// returned. A response whose rules are ORed becomes a mock per rule.
+func ImportMockoon(data []byte) ([]byte, []string, error) {
+	var environment mockoonEnvironment
+	if err := json.Unmarshal(data, &environment); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse Mockoon environment: %w", err)
+	}
+	var stubs, defaults []importedStub
+	var skipped []string
+	for i, route := range environment.Routes {
+		endpoint := "/" + strings.TrimPrefix(route.Endpoint, "/")
+		method := strings.ToUpper(route.Method)
+		defaultIndex := slices.IndexFunc(route.Responses, func(response mockoonResponse) bool { return response.Default })
+		if defaultIndex < 0 {
+			defaultIndex = 0
+		}
+		for j, response := range route.Responses {
+			name := response.Label
+			if name == "" {
+				name = fmt.Sprintf("mockoon-%d-%d", i, j)
+			}
+			skip := func(reason string) {
+				skipped = append(skipped, name+": "+reason)
+			}
+			switch {
+			case method != http.MethodPost && method != "ALL":
+				skip(fmt.Sprintf("method %s is not POST", method))
+				continue
+			case chatEndpointProvider(endpoint) == "":
+				skip(fmt.Sprintf("endpoint %q is not a chat completion or Messages API path", endpoint))
+				continue
+			case len(response.Rules) == 0 && j != defaultIndex:
+				skip("has no rules and is not the default response")
+				continue
+			case strings.Contains(response.Body, "{{"):
+				skip("body uses Mockoon templating")
+				continue
+			case response.BodyType != "" && response.BodyType != "INLINE" && response.BodyType != "FILE":
+				skip(fmt.Sprintf("unsupported body type %s", response.BodyType))
+				continue
+			}
+
+			base := importedStub{
+				name:            name,
+				provider:        chatEndpointProvider(endpoint),
+				status:          response.StatusCode,
+				responseHeaders: map[string]string{},
+				body:            []byte(response.Body),
+				delay:           time.Duration(response.Latency) * time.Millisecond,
+			}
+			if response.BodyType == "FILE" {
+				base.body, base.bodyFile = nil, response.FilePath
+			}
+			if base.status == 0 {
+				base.status = http.StatusOK
+			}
+			for _, header := range response.Headers {
+				base.responseHeaders[http.CanonicalHeaderKey(header.Key)] = header.Value
+			}
+
+			// Rules ORed together become a mock each, rules ANDed a single one
+			ruleSets := [][]mockoonRule{response.Rules}
+			if response.RulesOperator == "OR" && len(response.Rules) > 1 {
+				ruleSets = nil
+				for _, rule := range response.Rules {
+					ruleSets = append(ruleSets, []mockoonRule{rule})
+				}
+			}
+			var converted []importedStub
+			var reason string
+			for k, rules := range ruleSets {
+				stub := base
+				stub.fields, stub.headers = map[string]string{}, map[string]string{}
+				if len(ruleSets) > 1 {
+					stub.name = fmt.Sprintf("%s-%d", name, k)
+				}
+				for _, rule := range rules {
+					if reason = rule.addCriteria(stub.fields, stub.headers); reason != "" {
+						break
+					}
+				}
+				if reason != "" {
+					break
+				}
+				converted = append(converted, stub)
+			}
+			switch {
+			case reason != "":
+				skip(reason)
+			case len(response.Rules) == 0:
+				defaults = append(defaults, converted...)
+			default:
+				stubs = append(stubs, converted...)
+			}
+		}
+	}
+	return marshalImportedStubs(append(stubs, defaults...), skipped)
+}
+
+// addCriteria adds the fields or headers criteria of a rule, or returns why it cannot be expressed
+func (r mockoonRule) addCriteria(fields, headers map[string]string) string {
+	if r.Invert {
+		return "inverted rules are not supported"
+	}
+	switch {
+	case r.Target == "header" && r.Operator == "equals":
+		headers[r.Modifier] = exactHeaderPattern(r.Value)
+		return ""
+	case r.Target != "body" || r.Modifier == "":
+		return fmt.Sprintf("unsupported rule on %s %q", r.Target, r.Modifier)
+	}
+
+	path := mockoonFieldPath(r.Modifier)
+	switch r.Operator {
+	case "equals":
+		fields[path] = exactFieldPattern(r.Value)
+	case "regex":
+		fields[path] = "/" + r.Value + "/"
+	case "regex_i":
+		fields[path] = "/(?i)" + r.Value + "/"
+	case "array_includes":
+		fields[path+"[*]"] = exactFieldPattern(r.Value)
+	default:
+		return fmt.Sprintf("unsupported rule operator %s", r.Operator)
+	}
+	return ""
+}
+
+// mockoonFieldPath converts the path of a body rule, in object-path form such as messages.0.content
+// or JSONPath form such as $.messages[0].content, into a field path
+func mockoonFieldPath(modifier string) string {
+	if path, ok := jsonPathField(modifier); ok {
+		return path
+	}
+	segments := strings.Split(modifier, ".")
+	var b strings.Builder
+	for i, segment := range segments {
+		if _, err := strconv.Atoi(segment); err == nil && i > 0 {
+			b.WriteString("[" + segment + "]")
+			continue
+		}
+		if i > 0 {
+			b.WriteString(".")
+		}
+		b.WriteString(segment)
+	}
+	return b.String()
+}
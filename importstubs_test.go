@@ -0,0 +1,229 @@
+package mockllm_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	openaioption "github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveImportedConfig loads an imported config and serves it
+func serveImportedConfig(t *testing.T, configJSON []byte) string {
+	t.Helper()
+	config, err := mockllm.LoadConfigFromFile("mocks.json", fstest.MapFS{"mocks.json": {Data: configJSON}})
+	require.NoError(t, err)
+	server := mockllm.NewServer(mockllm.WithConfig(config))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(context.Background()) }) //nolint:errcheck
+	return baseURL
+}
+
+func TestImportWireMock(t *testing.T) {
+	mappings := []byte(`{"mappings": [
+		{
+			"name": "hello",
+			"priority": 2,
+			"request": {
+				"method": "POST",
+				"url": "/v1/chat/completions",
+				"bodyPatterns": [{"equalToJson": {"model": "${json-unit.any-string}", "messages": [{"role": "user", "content": "Hello"}]}}]
+			},
+			"response": {
+				"status": 200,
+				"headers": {"Content-Type": "application/json"},
+				"jsonBody": {"id": "chatcmpl-wiremock", "object": "chat.completion", "created": 1, "model": "gpt-4o",
+					"choices": [{"index": 0, "finish_reason": "stop", "logprobs": null, "message": {"role": "assistant", "content": "Hi from WireMock", "refusal": null}}]}
+			}
+		},
+		{
+			"name": "rate limited",
+			"priority": 1,
+			"request": {
+				"method": "POST",
+				"urlPath": "/v1/chat/completions",
+				"headers": {"X-Test": {"equalTo": "throttle"}}
+			},
+			"response": {"status": 429, "headers": {"Retry-After": "7"}, "jsonBody": {"error": {"message": "Rate limit reached", "type": "rate_limit_error"}}}
+		},
+		{
+			"id": "weather",
+			"request": {
+				"method": "POST",
+				"urlPattern": ".*/v1/messages",
+				"bodyPatterns": [{"contains": "weather"}]
+			},
+			"response": {
+				"status": 200,
+				"body": "{\"id\":\"msg_wiremock\",\"type\":\"message\",\"role\":\"assistant\",\"model\":\"claude-3-5-sonnet-20240620\",\"content\":[{\"type\":\"text\",\"text\":\"Sunny\"}],\"stop_reason\":\"end_turn\",\"stop_sequence\":null,\"usage\":{\"input_tokens\":1,\"output_tokens\":1}}"
+			}
+		},
+		{"request": {"method": "POST", "url": "/v1/embeddings"}, "response": {"status": 200}},
+		{"name": "regex header", "request": {"method": "POST", "url": "/v1/chat/completions", "headers": {"X-Test": {"matches": "a.*"}}}, "response": {"status": 200}}
+	]}`)
+
+	configJSON, skipped, err := mockllm.ImportWireMock(mappings)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`wiremock-3: URL "/v1/embeddings" is not a chat completion or Messages API path`,
+		"regex header: unsupported matcher of header X-Test",
+	}, skipped)
+	baseURL := serveImportedConfig(t, configJSON)
+
+	openaiClient := openai.NewClient(openaioption.WithBaseURL(baseURL+"/v1/"), openaioption.WithAPIKey("test-key"),
+		openaioption.WithMaxRetries(0))
+	request := openai.ChatCompletionNewParams{
+		Model:    "gpt-4o",
+		Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+	}
+	completion, err := openaiClient.Chat.Completions.New(t.Context(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi from WireMock", completion.Choices[0].Message.Content)
+
+	_, err = openaiClient.Chat.Completions.New(t.Context(), request, openaioption.WithHeader("X-Test", "throttle"))
+	var apiErr *openai.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusTooManyRequests, apiErr.StatusCode, "the stub of lower priority number comes first")
+	assert.Equal(t, "7", apiErr.Response.Header.Get("Retry-After"))
+
+	anthropicClient := anthropic.NewClient(anthropicoption.WithBaseURL(baseURL), anthropicoption.WithAPIKey("test-key"))
+	message, err := anthropicClient.Messages.New(t.Context(), anthropic.MessageNewParams{
+		Model:     "claude-3-5-sonnet-20240620",
+		MaxTokens: 100,
+		Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("What is the weather?"))},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "msg_wiremock", message.ID)
+}
+
+func TestImportWireMockEqualToJSON(t *testing.T) {
+	mapping := func(name, pattern string) string {
+		return `{"name": "` + name + `", "request": {"method": "POST", "url": "/v1/chat/completions", "bodyPatterns": [` + pattern + `]},
+			"response": {"status": 200, "jsonBody": {"id": "chatcmpl-` + name + `", "object": "chat.completion", "created": 1, "model": "gpt-4o",
+				"choices": [{"index": 0, "finish_reason": "stop", "logprobs": null, "message": {"role": "assistant", "content": "` + name + `", "refusal": null}}]}}}`
+	}
+	mappings := []byte(`{"mappings": [` +
+		mapping("exact", `{"equalToJson": {"model": "gpt-4o", "messages": [{"role": "user", "content": "Hello"}]}}`) + `,` +
+		mapping("extra keys", `{"equalToJson": {"model": "gpt-4o-mini"}, "ignoreExtraElements": true}`) + `,` +
+		mapping("extra elements", `{"equalToJson": {"messages": [{"role": "user", "content": "Hey"}]}, "ignoreExtraElements": true, "ignoreExtraInArrayElements": true}`) + `,` +
+		mapping("ignored", `{"equalToJson": {"model": "${json-unit.ignore}", "messages": []}}`) + `,` +
+		mapping("nested", `{"equalToJson": {"messages": [{"role": "user"}]}, "ignoreExtraElements": true}`) +
+		`]}`)
+
+	configJSON, skipped, err := mockllm.ImportWireMock(mappings)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"ignored: ${json-unit.ignore} cannot be matched in equalToJson documents that must match exactly, " +
+			"set ignoreExtraElements and ignoreExtraInArrayElements",
+		"nested: equalToJson objects allowing extra keys cannot be nested in arrays that must match exactly, " +
+			"set ignoreExtraInArrayElements too",
+	}, skipped)
+	baseURL := serveImportedConfig(t, configJSON)
+
+	send := func(request openai.ChatCompletionNewParams) int {
+		t.Helper()
+		return postJSON(t, baseURL+"/v1/chat/completions", request, openaiHeaders).StatusCode
+	}
+	messages := func(contents ...string) []openai.ChatCompletionMessageParamUnion {
+		var messages []openai.ChatCompletionMessageParamUnion
+		for _, content := range contents {
+			messages = append(messages, openaiUserMessage(content))
+		}
+		return messages
+	}
+
+	// Without the ignore flags, documents must have the same keys and array lengths
+	assert.Equal(t, http.StatusOK, send(openai.ChatCompletionNewParams{Model: "gpt-4o", Messages: messages("Hello")}))
+	assert.Equal(t, http.StatusNotFound, send(openai.ChatCompletionNewParams{Model: "gpt-4o", Messages: messages("Hello"),
+		Temperature: openai.Float(0.5)}))
+	assert.Equal(t, http.StatusNotFound, send(openai.ChatCompletionNewParams{Model: "gpt-4o", Messages: messages("Hello", "Hello")}))
+
+	// ignoreExtraElements allows extra keys
+	assert.Equal(t, http.StatusOK, send(openai.ChatCompletionNewParams{Model: "gpt-4o-mini", Messages: messages("Hi"),
+		Temperature: openai.Float(0.5)}))
+
+	// ignoreExtraInArrayElements allows both
+	assert.Equal(t, http.StatusOK, send(openai.ChatCompletionNewParams{Model: "gpt-4o", Messages: messages("Hey", "there")}))
+}
+
+func TestImportMockoon(t *testing.T) {
+	environment := []byte(`{
+		"uuid": "env",
+		"name": "LLM",
+		"routes": [
+			{
+				"method": "post",
+				"endpoint": "v1/chat/completions",
+				"responses": [
+					{
+						"label": "server error",
+						"statusCode": 500,
+						"body": "{\"error\": {\"message\": \"boom\"}}",
+						"headers": [{"key": "Content-Type", "value": "application/json"}],
+						"rules": [],
+						"default": true
+					},
+					{
+						"label": "greeting",
+						"statusCode": 200,
+						"latency": 10,
+						"body": "{\"id\": \"chatcmpl-mockoon\", \"object\": \"chat.completion\", \"created\": 1, \"model\": \"gpt-4o\", \"choices\": [{\"index\": 0, \"finish_reason\": \"stop\", \"logprobs\": null, \"message\": {\"role\": \"assistant\", \"content\": \"Hi from Mockoon\", \"refusal\": null}}]}",
+						"headers": [{"key": "Content-Type", "value": "application/json"}],
+						"rules": [
+							{"target": "body", "modifier": "messages.0.content", "value": "Hello", "operator": "equals", "invert": false},
+							{"target": "body", "modifier": "$.messages[0].content", "value": "^Hi", "operator": "regex", "invert": false}
+						],
+						"rulesOperator": "OR"
+					},
+					{
+						"label": "templated",
+						"body": "{{body 'model'}}",
+						"rules": [{"target": "body", "modifier": "model", "value": "gpt-4o", "operator": "equals"}]
+					},
+					{
+						"label": "not a user",
+						"body": "{}",
+						"rules": [{"target": "header", "modifier": "X-User", "value": "", "operator": "null"}]
+					}
+				]
+			},
+			{"method": "get", "endpoint": "v1/models", "responses": [{"label": "models", "body": "{}"}]}
+		]
+	}`)
+
+	configJSON, skipped, err := mockllm.ImportMockoon(environment)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"templated: body uses Mockoon templating",
+		`not a user: unsupported rule on header "X-User"`,
+		"models: method GET is not POST",
+	}, skipped)
+	baseURL := serveImportedConfig(t, configJSON)
+
+	openaiClient := openai.NewClient(openaioption.WithBaseURL(baseURL+"/v1/"), openaioption.WithAPIKey("test-key"),
+		openaioption.WithMaxRetries(0))
+	for _, content := range []string{"Hello", "Hi there"} {
+		completion, err := openaiClient.Chat.Completions.New(t.Context(), openai.ChatCompletionNewParams{
+			Model:    "gpt-4o",
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage(content)},
+		})
+		require.NoError(t, err, content)
+		assert.Equal(t, "Hi from Mockoon", completion.Choices[0].Message.Content, "ORed rules match alone")
+	}
+
+	_, err = openaiClient.Chat.Completions.New(t.Context(), openai.ChatCompletionNewParams{
+		Model:    "gpt-4o",
+		Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Goodbye")},
+	})
+	var apiErr *openai.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode, "the default response comes last")
+}
@@ -0,0 +1,155 @@
+package mockllm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// configFile is a config file read while resolving includes
+type configFile struct {
+	name      string
+	data      []byte
+	positions map[string]int64
+}
+
+// configOrigin locates an element of a top level array of the combined config in its file
+type configOrigin struct {
+	file *configFile
+	path string
+}
+
+// configOrigins maps the top level array elements of a combined config, such as "openai[3]", to
+// the files and paths they came from
+type configOrigins map[string]configOrigin
+
+// locate returns the file, line and column of the value at a path of the combined config
+func (o configOrigins) locate(p string) string {
+	end := strings.Index(p, "]") + 1
+	origin, ok := o[p[:end]]
+	if !ok {
+		return p
+	}
+	line, column := positionOf(origin.file.data, origin.file.positions, origin.path+p[end:])
+	return fmt.Sprintf("%s:%d:%d", origin.file.name, line, column)
+}
+
+// loadConfigDocument reads a JSON config and the configs it includes, combining them into a single
+// document. Included files are resolved relative to the including file, and their mocks, tenants
+// and HTTP mocks follow those of the including file. Objects such as templates are merged, with
+// the including file taking precedence. Files included several times, e.g. by two files
+// including the same shared file, are only loaded where they are first included; loaded records
+// the files loaded so far.
+func loadConfigDocument(filesys fs.FS, name string, chain []string, loaded map[string]bool) (map[string]any,
+	configOrigins, error) {
+	for _, seen := range chain {
+		if seen == name {
+			return nil, nil, fmt.Errorf("include cycle: %s -> %s", strings.Join(chain, " -> "), name)
+		}
+	}
+	if loaded[name] {
+		return map[string]any{}, configOrigins{}, nil
+	}
+	loaded[name] = true
+	data, err := fs.ReadFile(filesys, name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	var document map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse config JSON %s: %w", name, err)
+	}
+	if _, err := migrateConfigDocument(document); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	if err := resolveResponseFiles(filesys, name, document); err != nil {
+		return nil, nil, err
+	}
+
+	file := &configFile{name: name, data: data, positions: jsonPositions(data)}
+	origins := configOrigins{}
+	for key, value := range document {
+		list, _ := value.([]any)
+		for i := range list {
+			elementPath := fmt.Sprintf("%s[%d]", key, i)
+			origins[elementPath] = configOrigin{file: file, path: elementPath}
+		}
+	}
+
+	includes, err := includePatterns(document["include"])
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", name, err)
+	}
+	delete(document, "include")
+	for _, pattern := range includes {
+		matches, err := fs.Glob(filesys, path.Join(path.Dir(name), pattern))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: invalid include %q: %w", name, pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, nil, fmt.Errorf("%s: include %q matches no files", name, pattern)
+		}
+		for _, match := range matches {
+			included, includedOrigins, err := loadConfigDocument(filesys, match, append(chain, name), loaded)
+			if err != nil {
+				return nil, nil, err
+			}
+			includeDocument(document, origins, included, includedOrigins)
+		}
+	}
+	return document, origins, nil
+}
+
+// includeDocument appends the arrays of an included document to those of the document and adds
+// the members it does not set itself
+func includeDocument(document map[string]any, origins configOrigins, included map[string]any,
+	includedOrigins configOrigins) {
+	addOrigins := func(key string, offset int, value any) {
+		list, _ := value.([]any)
+		for i := range list {
+			origins[fmt.Sprintf("%s[%d]", key, offset+i)] = includedOrigins[fmt.Sprintf("%s[%d]", key, i)]
+		}
+	}
+	for key, value := range included {
+		switch existing := document[key].(type) {
+		case nil:
+			document[key] = value
+			addOrigins(key, 0, value)
+		case []any:
+			if list, ok := value.([]any); ok {
+				addOrigins(key, len(existing), list)
+				document[key] = append(existing, list...)
+			}
+		case map[string]any:
+			object, _ := value.(map[string]any)
+			for member, memberValue := range object {
+				if _, ok := existing[member]; !ok {
+					existing[member] = memberValue
+				}
+			}
+		}
+	}
+}
+
+// includePatterns returns the glob patterns of an "include" member
+func includePatterns(include any) ([]string, error) {
+	list, ok := include.([]any)
+	if include != nil && !ok {
+		return nil, fmt.Errorf("include must be a list of file patterns")
+	}
+	patterns := make([]string, 0, len(list))
+	for _, pattern := range list {
+		s, ok := pattern.(string)
+		if !ok {
+			return nil, fmt.Errorf("include must be a list of file patterns, got %v", pattern)
+		}
+		patterns = append(patterns, s)
+	}
+	return patterns, nil
+}
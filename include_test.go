@@ -0,0 +1,78 @@
+package mockllm_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigIncludes(t *testing.T) {
+	filesys := fstest.MapFS{
+		"suite/main.json": {Data: []byte(`{
+  "include": ["common/*.json"],
+  "openai": [
+    {"name": "main", "extends": "gpt", "response": {"choices": [{"message": {"content": "Main"}}]}}
+  ]
+}`)},
+		"suite/common/a.json": {Data: []byte(`{
+  "templates": {"gpt": {"response": {"model": "gpt-4o"}}},
+  "openai": [{"name": "a", "response": {"choices": [{"message": {"content": "A"}}]}}]
+}`)},
+		"suite/common/b.json": {Data: []byte(`{
+  "include": ["../shared.json"],
+  "anthropic": [{"name": "b", "response": {"content": [{"type": "text", "text": "B"}]}}]
+}`)},
+		"suite/shared.json": {Data: []byte(`{"openai": [{"name": "shared", "response": {"choices": []}}]}`)},
+	}
+	config, err := mockllm.LoadConfigFromFile("suite/main.json", filesys)
+	require.NoError(t, err)
+
+	var names []string
+	for _, mock := range config.OpenAI {
+		names = append(names, mock.Name)
+	}
+	assert.Equal(t, []string{"main", "a", "shared"}, names)
+	assert.Equal(t, "gpt-4o", config.OpenAI[0].Response.Model)
+	require.Len(t, config.Anthropic, 1)
+	assert.Equal(t, "b", config.Anthropic[0].Name)
+
+	filesys["suite/common/a.json"] = &fstest.MapFile{Data: []byte(`{
+  "openai": [{"name": "a", "response": {"choices": [{"message": {"content": "A"}, "role": "assistant"}]}}]
+}`)}
+	_, err = mockllm.LoadConfigFromFile("suite/main.json", filesys)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "suite/common/a.json:2:83: openai[1].response.choices[0].role: unknown field")
+}
+
+func TestConfigIncludeErrors(t *testing.T) {
+	_, err := mockllm.LoadConfigFromFile("a.json", fstest.MapFS{
+		"a.json": {Data: []byte(`{"include": ["b.json"]}`)},
+		"b.json": {Data: []byte(`{"include": ["a.json"]}`)},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "include cycle: a.json -> b.json -> a.json")
+
+	_, err = mockllm.LoadConfigFromFile("a.json", fstest.MapFS{"a.json": {Data: []byte(`{"include": ["missing/*.json"]}`)}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `a.json: include "missing/*.json" matches no files`)
+}
+
+func TestConfigIncludeDiamond(t *testing.T) {
+	config, err := mockllm.LoadConfigFromFile("main.json", fstest.MapFS{
+		"main.json":   {Data: []byte(`{"include": ["b.json", "c.json"]}`)},
+		"b.json":      {Data: []byte(`{"include": ["shared.json"], "openai": [{"name": "b", "response": {"choices": []}}]}`)},
+		"c.json":      {Data: []byte(`{"include": ["./shared.json"], "openai": [{"name": "c", "response": {"choices": []}}]}`)},
+		"shared.json": {Data: []byte(`{"openai": [{"name": "shared", "response": {"choices": []}}]}`)},
+	})
+	require.NoError(t, err)
+
+	// shared.json is loaded once, where b.json includes it
+	var names []string
+	for _, mock := range config.OpenAI {
+		names = append(names, mock.Name)
+	}
+	assert.Equal(t, []string{"b", "shared", "c"}, names)
+}
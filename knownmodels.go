@@ -0,0 +1,45 @@
+package mockllm
+
+import "fmt"
+
+// KnownModels lists the models each provider serves. Requests for other models are rejected with
+// the provider's model not found error, e.g. to test client fallback chains across models.
+type KnownModels struct {
+	// OpenAI and Anthropic are model names or patterns like those of fields criteria. Models whose
+	// alias is known are known too, and a provider without models serves any model.
+	OpenAI    []string `json:"openai,omitempty"`
+	Anthropic []string `json:"anthropic,omitempty"`
+}
+
+// knownModel reports whether the model, or its canonical name, is one of the known models
+func knownModel[M ~string](models []string, aliases map[string]string, model M) bool {
+	if len(models) == 0 {
+		return true
+	}
+	for _, name := range []string{string(model), string(canonicalModel(aliases, model))} {
+		for _, pattern := range models {
+			if re, err := compileFieldPattern(pattern); err == nil && re.MatchString(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// knownModelIssues reports the known model patterns that don't compile
+func knownModelIssues(models *KnownModels) []configIssue {
+	if models == nil {
+		return nil
+	}
+	var issues []configIssue
+	check := func(provider string, patterns []string) {
+		for i, pattern := range patterns {
+			if _, err := compileFieldPattern(pattern); err != nil {
+				issues = append(issues, configIssue{fmt.Sprintf("known_models.%s[%d]", provider, i), err.Error()})
+			}
+		}
+	}
+	check(providerOpenAI, models.OpenAI)
+	check(providerAnthropic, models.Anthropic)
+	return issues
+}
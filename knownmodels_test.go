@@ -0,0 +1,81 @@
+package mockllm_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKnownModels(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		ModelAliases: map[string]string{"gpt-4o-2024-08-06": "gpt-4o"},
+		KnownModels: &mockllm.KnownModels{
+			OpenAI:    []string{"gpt-4o", "gpt-4o-mini-*"},
+			Anthropic: []string{"claude-3-5-haiku-*"},
+		},
+		OpenAI: []mockllm.OpenAIMock{{
+			Name:     "any",
+			Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+			Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "Hi"}}}},
+		}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+
+	for _, model := range []string{"gpt-4o", "gpt-4o-2024-08-06", "gpt-4o-mini-2024-07-18"} {
+		completion := postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+			Model:    model,
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+		})
+		require.Len(t, completion.Choices, 1, model)
+	}
+
+	resp := postJSON(t, baseURL+"/v1/chat/completions", openai.ChatCompletionNewParams{
+		Model:    "gpt-5",
+		Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+	}, map[string]string{"Authorization": "Bearer test-key"})
+	defer resp.Body.Close() //nolint:errcheck
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	var openaiError struct {
+		Error struct {
+			Message string `json:"message"`
+			Code    string `json:"code"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&openaiError))
+	assert.Equal(t, "model_not_found", openaiError.Error.Code)
+	assert.Contains(t, openaiError.Error.Message, "`gpt-5`")
+
+	resp = postJSON(t, baseURL+"/v1/messages", anthropicHelloRequest, anthropicHeaders("2023-06-01"))
+	defer resp.Body.Close() //nolint:errcheck
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	var anthropicError struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&anthropicError))
+	assert.Equal(t, "not_found_error", anthropicError.Error.Type)
+	assert.Equal(t, "model: "+string(anthropicHelloRequest.Model), anthropicError.Error.Message)
+
+	requests := server.Requests()
+	require.Len(t, requests, 5)
+	assert.Equal(t, http.StatusNotFound, requests[3].Status)
+	assert.Equal(t, "unknown model gpt-5", requests[3].Error)
+}
+
+func TestKnownModelValidation(t *testing.T) {
+	_, err := mockllm.LoadConfigFromFile("mocks.json", fstest.MapFS{"mocks.json": {Data: []byte(`{
+  "known_models": {"anthropic": ["/claude-(/"]}
+}`)}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "known_models.anthropic[0]: error parsing regexp")
+}
@@ -0,0 +1,67 @@
+package mockllm
+
+import (
+	"hash/fnv"
+
+	"github.com/kagent-dev/mockllm/tokenizer"
+	"github.com/openai/openai-go"
+)
+
+// alternativeTokens are the candidates used to fill top_logprobs beyond the sampled token
+var alternativeTokens = []string{" the", " a", " and", " to", " of", " is", " in", " it", ",", ".",
+	" that", " for", " on", " with", " as", " was", " be", " this", " at", " by"}
+
+// generateLogprobs deterministically fabricates token logprobs for content. The same content
+// always yields the same tokens and values, with topLogprobs alternatives per token.
+func generateLogprobs(content string, topLogprobs int, tok tokenizer.Tokenizer) openai.ChatCompletionChoiceLogprobs {
+	tokens := tok.Tokenize(content)
+	logprobs := openai.ChatCompletionChoiceLogprobs{
+		Content: make([]openai.ChatCompletionTokenLogprob, 0, len(tokens)),
+		Refusal: []openai.ChatCompletionTokenLogprob{},
+	}
+
+	for i, token := range tokens {
+		logprob := tokenLogprob(token, i)
+		top := make([]openai.ChatCompletionTokenLogprobTopLogprob, 0, topLogprobs)
+		if topLogprobs > 0 {
+			top = append(top, openai.ChatCompletionTokenLogprobTopLogprob{
+				Token: token, Bytes: tokenBytes(token), Logprob: logprob,
+			})
+		}
+		for _, alternative := range alternativeTokens {
+			if len(top) >= topLogprobs {
+				break
+			}
+			if alternative == token {
+				continue
+			}
+			top = append(top, openai.ChatCompletionTokenLogprobTopLogprob{
+				Token: alternative, Bytes: tokenBytes(alternative), Logprob: logprob - float64(len(top)),
+			})
+		}
+
+		logprobs.Content = append(logprobs.Content, openai.ChatCompletionTokenLogprob{
+			Token:       token,
+			Bytes:       tokenBytes(token),
+			Logprob:     logprob,
+			TopLogprobs: top,
+		})
+	}
+	return logprobs
+}
+
+// tokenLogprob derives a logprob in (-2, 0] from the token and its position
+func tokenLogprob(token string, position int) float64 {
+	h := fnv.New32a()
+	h.Write([]byte{byte(position), byte(position >> 8)}) //nolint:errcheck
+	h.Write([]byte(token))                               //nolint:errcheck
+	return -float64(h.Sum32()%2000) / 1000
+}
+
+func tokenBytes(token string) []int64 {
+	bytes := make([]int64, len(token))
+	for i := 0; i < len(token); i++ {
+		bytes[i] = int64(token[i])
+	}
+	return bytes
+}
@@ -0,0 +1,146 @@
+package mockllm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// MalformedKind is a way a mock can break its response, to test the robustness of clients
+type MalformedKind string
+
+const (
+	// MalformedInvalidJSON cuts the JSON body, or the data of the first stream event, in half
+	MalformedInvalidJSON MalformedKind = "invalid_json"
+	// MalformedWrongEnum replaces every role, finish_reason and stop_reason with a value outside
+	// the enum
+	MalformedWrongEnum MalformedKind = "wrong_enum"
+	// MalformedWrongType encodes every number as a string, e.g. token counts and timestamps
+	MalformedWrongType MalformedKind = "wrong_type"
+	// MalformedMissingID removes every id, of responses, stream chunks and tool calls
+	MalformedMissingID MalformedKind = "missing_id"
+	// MalformedOutOfOrder streams the events in reverse order, except for the final one. Non-streamed
+	// responses are served unchanged.
+	MalformedOutOfOrder MalformedKind = "out_of_order"
+	// MalformedTruncated ends streams halfway without their final events, and sends half of a
+	// non-streamed body with the Content-Length of all of it
+	MalformedTruncated MalformedKind = "truncated"
+)
+
+// MalformedKinds are all the ways a mock can break its response
+var MalformedKinds = []MalformedKind{MalformedInvalidJSON, MalformedWrongEnum, MalformedWrongType,
+	MalformedMissingID, MalformedOutOfOrder, MalformedTruncated}
+
+// malformedEnum replaces enum values for MalformedWrongEnum
+const malformedEnum = "not_a_valid_value"
+
+// malformedIssues reports an unknown malformed kind
+func malformedIssues(kind MalformedKind, path string) []configIssue {
+	if kind == "" || slices.Contains(MalformedKinds, kind) {
+		return nil
+	}
+	return []configIssue{{path, fmt.Sprintf("unknown malformed kind %q", kind)}}
+}
+
+// malformJSON breaks an encoded response or event. Data that isn't a JSON value, such as OpenAI's
+// [DONE], is only ever cut.
+func malformJSON(kind MalformedKind, data []byte) []byte {
+	if kind == MalformedInvalidJSON {
+		return data[:len(data)/2]
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if decoder.Decode(&value) != nil {
+		return data
+	}
+	encoded, err := json.Marshal(malformValue(kind, value))
+	if err != nil {
+		return data
+	}
+	return encoded
+}
+
+// malformValue breaks a decoded JSON value, recursing into objects and arrays
+func malformValue(kind MalformedKind, value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			switch {
+			case kind == MalformedMissingID && key == "id":
+				delete(v, key)
+			case kind == MalformedWrongEnum && (key == "role" || key == "finish_reason" || key == "stop_reason"):
+				if _, ok := field.(string); ok {
+					v[key] = malformedEnum
+				}
+			default:
+				v[key] = malformValue(kind, field)
+			}
+		}
+	case []any:
+		for i := range v {
+			v[i] = malformValue(kind, v[i])
+		}
+	case json.Number:
+		if kind == MalformedWrongType {
+			return v.String()
+		}
+	}
+	return value
+}
+
+// malformEvents breaks a streamed response, leaving it unchanged when kind is empty
+func malformEvents(kind MalformedKind, events []sseEvent) []sseEvent {
+	switch kind {
+	case "":
+		return events
+	case MalformedOutOfOrder:
+		if len(events) < 2 {
+			return events
+		}
+		reordered := slices.Clone(events[:len(events)-1])
+		slices.Reverse(reordered)
+		return append(reordered, events[len(events)-1])
+	case MalformedTruncated:
+		return events[:len(events)/2]
+	case MalformedInvalidJSON:
+		malformed := slices.Clone(events)
+		if len(malformed) > 0 {
+			malformed[0].Data = malformJSON(kind, malformed[0].Data)
+		}
+		return malformed
+	}
+
+	malformed := make([]sseEvent, len(events))
+	for i, event := range events {
+		malformed[i] = sseEvent{Event: event.Event, Data: malformJSON(kind, event.Data)}
+	}
+	return malformed
+}
+
+// writeMalformedResponse writes a non-streamed response broken in the given way
+func writeMalformedResponse(w http.ResponseWriter, kind MalformedKind, response any) {
+	body, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	body = append(body, '\n')
+
+	w.Header().Set("Content-Type", "application/json")
+	switch kind {
+	case MalformedTruncated:
+		// The server closes the connection when the handler writes less than the Content-Length
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		body = body[:len(body)/2]
+	case MalformedOutOfOrder:
+	default:
+		body = malformJSON(kind, body)
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(body) //nolint:errcheck
+}
@@ -0,0 +1,145 @@
+package mockllm_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMalformedResponses(t *testing.T) {
+	reply := openai.ChatCompletion{
+		ID:      "chatcmpl-malformed",
+		Created: 1700000000,
+		Model:   "gpt-4o-mini",
+		Choices: []openai.ChatCompletionChoice{{
+			Message:      openai.ChatCompletionMessage{Role: "assistant", Content: "Hello there"},
+			FinishReason: "stop",
+		}},
+		Usage: openai.CompletionUsage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5},
+	}
+	var mocks []mockllm.OpenAIMock
+	for _, kind := range mockllm.MalformedKinds {
+		mocks = append(mocks, mockllm.OpenAIMock{
+			Name:      string(kind),
+			Match:     mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeExact, Message: openaiUserMessage(string(kind))},
+			Response:  reply,
+			Malformed: kind,
+		})
+	}
+	baseURL := newOpenAIServer(t, mocks...)
+
+	post := func(kind mockllm.MalformedKind, stream bool) (string, error) {
+		resp := postJSON(t, baseURL+"/v1/chat/completions", map[string]any{
+			"model":    "gpt-4o-mini",
+			"stream":   stream,
+			"messages": []map[string]any{{"role": "user", "content": string(kind)}},
+		}, openaiHeaders)
+		defer resp.Body.Close() //nolint:errcheck
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+	decode := func(body string) map[string]any {
+		var decoded map[string]any
+		require.NoError(t, json.Unmarshal([]byte(body), &decoded))
+		return decoded
+	}
+
+	body, err := post(mockllm.MalformedInvalidJSON, false)
+	require.NoError(t, err)
+	assert.False(t, json.Valid([]byte(body)))
+
+	body, err = post(mockllm.MalformedWrongEnum, false)
+	require.NoError(t, err)
+	choice := decode(body)["choices"].([]any)[0].(map[string]any)
+	assert.Equal(t, "not_a_valid_value", choice["finish_reason"])
+	assert.Equal(t, "not_a_valid_value", choice["message"].(map[string]any)["role"])
+
+	body, err = post(mockllm.MalformedWrongType, false)
+	require.NoError(t, err)
+	decoded := decode(body)
+	assert.Equal(t, "1700000000", decoded["created"])
+	assert.Equal(t, "5", decoded["usage"].(map[string]any)["total_tokens"])
+
+	body, err = post(mockllm.MalformedMissingID, false)
+	require.NoError(t, err)
+	assert.NotContains(t, decode(body), "id")
+
+	body, err = post(mockllm.MalformedOutOfOrder, false)
+	require.NoError(t, err)
+	assert.Equal(t, "chatcmpl-malformed", decode(body)["id"])
+
+	_, err = post(mockllm.MalformedTruncated, false)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	// Streams
+	body, err = post(mockllm.MalformedOutOfOrder, true)
+	require.NoError(t, err)
+	events := strings.Split(strings.TrimSpace(body), "\n\n")
+	assert.Contains(t, events[0], `"finish_reason":"stop"`)
+	assert.Contains(t, events[len(events)-2], `"role":"assistant"`)
+	assert.Equal(t, "data: [DONE]", events[len(events)-1])
+
+	body, err = post(mockllm.MalformedTruncated, true)
+	require.NoError(t, err)
+	assert.NotContains(t, body, "[DONE]")
+	assert.NotContains(t, body, `"finish_reason":"stop"`)
+
+	body, err = post(mockllm.MalformedMissingID, true)
+	require.NoError(t, err)
+	assert.NotContains(t, body, "chatcmpl-malformed")
+	assert.True(t, strings.HasSuffix(body, "data: [DONE]\n\n"))
+}
+
+func TestMalformedAnthropicStream(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{Anthropic: []mockllm.AnthropicMock{{
+		Name:      "out-of-order",
+		Match:     mockllm.AnthropicRequestMatch{MatchType: mockllm.MatchTypeExact, Message: anthropicHelloRequest.Messages[0]},
+		Response:  anthropic.Message{ID: "msg_malformed", Content: []anthropic.ContentBlockUnion{{Type: "text", Text: "Hello there"}}},
+		Malformed: mockllm.MalformedOutOfOrder,
+	}}}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+
+	request := anthropicHelloRequest
+	resp := postJSON(t, baseURL+"/v1/messages", map[string]any{
+		"model": request.Model, "max_tokens": request.MaxTokens, "messages": request.Messages, "stream": true,
+	}, anthropicHeaders("2023-06-01"))
+	defer resp.Body.Close() //nolint:errcheck
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	var names []string
+	for _, line := range strings.Split(string(body), "\n") {
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			names = append(names, name)
+		}
+	}
+	require.NotEmpty(t, names)
+	assert.Equal(t, "message_delta", names[0])
+	assert.Equal(t, []string{"message_start", "message_stop"}, names[len(names)-2:])
+}
+
+func TestMalformedConfigValidation(t *testing.T) {
+	filesys := fstest.MapFS{"mocks.json": {Data: []byte(`{
+  "anthropic": [{
+    "name": "broken",
+    "match": {"match_type": "contains", "message": {"role": "user", "content": "Hello"}},
+    "response": {"id": "msg_broken", "content": [{"type": "text", "text": "Hi"}]},
+    "malformed": "binary"
+  }]
+}`)}}
+	_, err := mockllm.LoadConfigFromFile("mocks.json", filesys)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `anthropic[0].malformed: unknown malformed kind "binary"`)
+}
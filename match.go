@@ -0,0 +1,182 @@
+package mockllm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+)
+
+// MatchExplanation explains whether a configured mock matches a request
+type MatchExplanation struct {
+	Provider  string    `json:"provider"`
+	MockName  string    `json:"mock_name"`
+	MatchType MatchType `json:"match_type,omitempty"`
+	Matched   bool      `json:"matched"`
+	// Selected marks the mock that would serve the request: the first matching mock, preferring
+	// one calling the tool the request forces, or else the grammar or echo fallback
+	Selected bool   `json:"selected"`
+	Reason   string `json:"reason"`
+	// Diff compares the expected message of a mock that does not match with the request's last message
+	Diff string `json:"diff,omitempty"`
+}
+
+// WhichMockMatches explains, for every default OpenAI and Anthropic mock, whether it would match
+// a request body and headers and why, without serving the request. Bodies are explained against
+// the mocks of every provider whose request type they decode as.
+func (s *Server) WhichMockMatches(body []byte, header http.Header) ([]MatchExplanation, error) {
+	return s.WhichMockMatchesContext(context.Background(), body, header)
+}
+
+// WhichMockMatchesContext is WhichMockMatches, with a context cancelling the match hooks of the
+// plugins of the mocks
+func (s *Server) WhichMockMatchesContext(ctx context.Context, body []byte, header http.Header) ([]MatchExplanation, error) {
+	var explanations []MatchExplanation
+
+	var openaiRequest openai.ChatCompletionNewParams
+	openaiErr := json.Unmarshal(body, &openaiRequest)
+	if openaiErr == nil {
+		explanations = append(explanations, s.openaiProvider.explain(ctx, openaiRequest, header)...)
+	}
+	var anthropicRequest anthropic.MessageNewParams
+	anthropicBody, err := s.anthropicProvider.resolveFileReferences(body)
+	if err != nil {
+		// References to unknown files are left as they are
+		anthropicBody = body
+	}
+	anthropicErr := json.Unmarshal(anthropicBody, &anthropicRequest)
+	if anthropicErr == nil {
+		explanations = append(explanations, s.anthropicProvider.explain(ctx, anthropicRequest, header)...)
+	}
+	if openaiErr != nil && anthropicErr != nil {
+		return nil, fmt.Errorf("invalid request: %w", errors.Join(openaiErr, anthropicErr))
+	}
+	return explanations, nil
+}
+
+// explain explains whether each mock matches the request
+func (p *OpenAIProvider) explain(ctx context.Context, request openai.ChatCompletionNewParams, header http.Header) []MatchExplanation {
+	request.Model = canonicalModel(p.modelAliases, request.Model)
+	explanations := make([]MatchExplanation, 0, len(p.mocks)+1)
+	// A request forcing a tool call selects the first matching mock calling the tool, if any
+	forced := openaiForcedTool(request)
+	preferred := -1
+	for i, mock := range p.mocks {
+		if forced != nil && openaiCallsTool(mock.Response, forced) && p.mockMatches(ctx, mock, request, header) {
+			preferred = i
+			break
+		}
+	}
+	selected := false
+	for i, mock := range p.mocks {
+		matched, reason := p.explainMockMatch(ctx, mock, request, header)
+		explanation := MatchExplanation{
+			Provider:  providerOpenAI,
+			MockName:  mock.Name,
+			MatchType: mock.Match.MatchType,
+			Matched:   matched,
+			Selected:  matched && (i == preferred || preferred < 0 && !selected),
+			Reason:    reason,
+		}
+		if !matched && len(request.Messages) > 0 {
+			explanation.Diff = jsonDiff(mock.Match.Message, request.Messages[len(request.Messages)-1])
+		}
+		selected = selected || matched
+		explanations = append(explanations, explanation)
+	}
+	if !selected {
+		if fallback := fallbackExplanation(providerOpenAI, p.grammar.answers(openaiLastMessageText(request)), p.echo); fallback != nil {
+			explanations = append(explanations, *fallback)
+		}
+	}
+	return explanations
+}
+
+// explain explains whether each mock matches the request
+func (p *AnthropicProvider) explain(ctx context.Context, request anthropic.MessageNewParams, header http.Header) []MatchExplanation {
+	request.Model = canonicalModel(p.modelAliases, request.Model)
+	explanations := make([]MatchExplanation, 0, len(p.mocks)+1)
+	// A request forcing a tool call selects the first matching mock calling the tool, if any
+	forced := anthropicForcedTool(request)
+	preferred := -1
+	for i, mock := range p.mocks {
+		if forced != nil && anthropicCallsTool(mock.Response, forced) && p.mockMatches(ctx, mock, request, header) {
+			preferred = i
+			break
+		}
+	}
+	selected := false
+	for i, mock := range p.mocks {
+		matched, reason := p.explainMockMatch(ctx, mock, request, header)
+		explanation := MatchExplanation{
+			Provider:  providerAnthropic,
+			MockName:  mock.Name,
+			MatchType: mock.Match.MatchType,
+			Matched:   matched,
+			Selected:  matched && (i == preferred || preferred < 0 && !selected),
+			Reason:    reason,
+		}
+		if !matched && len(request.Messages) > 0 {
+			explanation.Diff = jsonDiff(mock.Match.Message, request.Messages[len(request.Messages)-1])
+		}
+		selected = selected || matched
+		explanations = append(explanations, explanation)
+	}
+	if !selected {
+		if fallback := fallbackExplanation(providerAnthropic, p.grammar.answers(anthropicLastMessageText(request)), p.echo); fallback != nil {
+			explanations = append(explanations, *fallback)
+		}
+	}
+	return explanations
+}
+
+// fallbackExplanation explains the grammar, when one of its rules matches, or echo mode answering
+// a request no mock matches
+func fallbackExplanation(provider string, grammar bool, echo *EchoConfig) *MatchExplanation {
+	switch {
+	case grammar:
+		return &MatchExplanation{Provider: provider, MockName: grammarMockName, Matched: true, Selected: true,
+			Reason: "no mock matches, the grammar replies"}
+	case echo != nil:
+		return &MatchExplanation{Provider: provider, MockName: echoMockName, Matched: true, Selected: true,
+			Reason: "no mock matches, echo mode replies"}
+	}
+	return nil
+}
+
+// containsReason checks whether text contains substr, describing the text as what
+func containsReason(what, text, substr string) (bool, string) {
+	if strings.Contains(text, substr) {
+		return true, fmt.Sprintf("%s contains %q", what, substr)
+	}
+	return false, fmt.Sprintf("%s does not contain %q", what, substr)
+}
+
+// pluginMatchReason describes the result of a plugin's match hook
+func pluginMatchReason(matched bool) string {
+	if matched {
+		return "plugin match hook accepted the request"
+	}
+	return "plugin match hook rejected the request"
+}
+
+func (s *Server) handleAdminMatch(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": fmt.Sprintf("Failed to read request body: %v", err)})
+		return
+	}
+	// The headers of the admin request stand in for those of the explained request
+	explanations, err := s.WhichMockMatchesContext(r.Context(), body, r.Header)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, explanations)
+}
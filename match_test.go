@@ -0,0 +1,145 @@
+package mockllm_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWhichMockMatches(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{
+			{
+				Name:  "bye",
+				Match: mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Bye")},
+			},
+			{
+				Name: "after-search",
+				Match: mockllm.OpenAIRequestMatch{
+					MatchType:   mockllm.MatchTypeUserContains,
+					Message:     openaiUserMessage("Hello"),
+					ToolsCalled: []string{"search"},
+				},
+			},
+			{
+				Name:  "hello",
+				Match: mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+			},
+			{
+				Name:  "hello-again",
+				Match: mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+			},
+		},
+	}))
+
+	body, err := json.Marshal(openai.ChatCompletionNewParams{
+		Model:    "gpt-4o",
+		Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello there")},
+	})
+	require.NoError(t, err)
+	explanations, err := server.WhichMockMatches(body, nil)
+	require.NoError(t, err)
+
+	var openaiExplanations []mockllm.MatchExplanation
+	for _, explanation := range explanations {
+		if explanation.Provider == "openai" {
+			openaiExplanations = append(openaiExplanations, explanation)
+		}
+	}
+	require.Len(t, openaiExplanations, 4)
+	assert.False(t, openaiExplanations[0].Matched)
+	assert.Equal(t, `last message does not contain "Bye"`, openaiExplanations[0].Reason)
+	assert.Contains(t, openaiExplanations[0].Diff, `-   "content": "Bye"`)
+	assert.Equal(t, `tool "search" has not been called`, openaiExplanations[1].Reason)
+	assert.True(t, openaiExplanations[2].Matched)
+	assert.True(t, openaiExplanations[2].Selected)
+	assert.Equal(t, `last message contains "Hello"`, openaiExplanations[2].Reason)
+	assert.True(t, openaiExplanations[3].Matched)
+	assert.False(t, openaiExplanations[3].Selected)
+
+	_, err = server.WhichMockMatches([]byte("not json"), nil)
+	assert.Error(t, err)
+
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	resp := postJSON(t, baseURL+"/admin/match", json.RawMessage(body), nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var served []mockllm.MatchExplanation
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&served))
+	assert.Equal(t, explanations, served)
+	assert.Empty(t, server.Requests(), "explaining a request does not serve it")
+}
+
+func TestHeaderMatching(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{
+			{
+				Name: "traced",
+				Match: mockllm.OpenAIRequestMatch{
+					MatchType: mockllm.MatchTypeContains,
+					Message:   openaiUserMessage("Hello"),
+					Headers:   map[string]string{"traceparent": "00-*", "OpenAI-Organization": "org-kagent"},
+				},
+				Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "traced"}}}},
+			},
+			{
+				Name:     "untraced",
+				Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+				Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "untraced"}}}},
+			},
+		},
+		Anthropic: []mockllm.AnthropicMock{{
+			Name: "beta",
+			Match: mockllm.AnthropicRequestMatch{
+				MatchType: mockllm.MatchTypeExact,
+				Message:   anthropicHelloRequest.Messages[0],
+				Headers:   map[string]string{"anthropic-beta": "*"},
+			},
+			Response: anthropic.Message{Content: []anthropic.ContentBlockUnion{{Type: "text", Text: "Hi!"}}},
+		}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	params := openai.ChatCompletionNewParams{
+		Model:    "gpt-4o-mini",
+		Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+	}
+	headers := map[string]string{
+		"Authorization":       "Bearer test-key",
+		"traceparent":         "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"OpenAI-Organization": "org-kagent",
+	}
+	reply := func(headers map[string]string) string {
+		var completion openai.ChatCompletion
+		require.NoError(t, json.NewDecoder(postJSON(t, baseURL+"/v1/chat/completions", params, headers).Body).Decode(&completion))
+		return completion.Choices[0].Message.Content
+	}
+	assert.Equal(t, "traced", reply(headers))
+	delete(headers, "traceparent")
+	assert.Equal(t, "untraced", reply(headers))
+
+	resp := postJSON(t, baseURL+"/v1/messages", anthropicHelloRequest, anthropicHeaders("2023-06-01"))
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	betaHeaders := anthropicHeaders("2023-06-01")
+	betaHeaders["anthropic-beta"] = "prompt-caching-2024-07-31"
+	resp = postJSON(t, baseURL+"/v1/messages", anthropicHelloRequest, betaHeaders)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := json.Marshal(params)
+	require.NoError(t, err)
+	explanations, err := server.WhichMockMatches(body, http.Header{"Openai-Organization": {"org-other"}})
+	require.NoError(t, err)
+	assert.Equal(t, "header OpenAI-Organization is \"org-other\", want \"org-kagent\"", explanations[0].Reason)
+	assert.True(t, explanations[1].Selected)
+}
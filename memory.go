@@ -0,0 +1,230 @@
+package mockllm
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+)
+
+// DefaultMemoryFacts extract facts such as "my name is Ada" or "remember that the deadline is Friday"
+var DefaultMemoryFacts = []string{
+	`(?i)\bremember that (?P<key>[\w ]+?) (?:is|are) (?P<value>[^.,;!?\n]+)`,
+	`(?i)\bmy (?P<key>[\w ]+?) (?:is|are) (?P<value>[^.,;!?\n]+)`,
+}
+
+// DefaultMemoryQuestions recognize questions such as "what is my name?" or "do you remember the deadline?"
+var DefaultMemoryQuestions = []string{
+	`(?i)\bwhat(?:'s| is| are) (?P<key>[\w ]+?)\s*\?`,
+	`(?i)\bdo you (?:know|remember) (?P<key>[\w ]+?)\s*\?`,
+}
+
+// MemoryConfig answers questions about facts asserted earlier in the conversation, e.g. "what is
+// my name?" after "my name is Ada", to test agents that keep memories in their prompts. Facts are
+// extracted from every message of the request, later assertions overriding earlier ones. Keys are
+// compared ignoring case and a leading "my" or "the".
+type MemoryConfig struct {
+	// Facts are regular expressions with "key" and "value" groups extracting facts from messages.
+	// Defaults to DefaultMemoryFacts.
+	Facts []string `json:"facts,omitempty"`
+	// Questions are regular expressions with a "key" group recognizing questions in the last
+	// message. Defaults to DefaultMemoryQuestions.
+	Questions []string `json:"questions,omitempty"`
+	// Answer is the reply to a question about a known fact, with {key} and {value} replaced.
+	// Defaults to "Your {key} is {value}."
+	Answer string `json:"answer,omitempty"`
+	// Unknown is the reply to a question about an unknown fact, with {key} replaced. Defaults to
+	// "I don't know your {key}."
+	Unknown string `json:"unknown,omitempty"`
+	// Acknowledge is the reply to a last message asserting a fact, with {key} and {value} replaced.
+	// Defaults to "Got it, your {key} is {value}." Other messages get the configured response.
+	Acknowledge string `json:"acknowledge,omitempty"`
+	// Persist remembers facts across the requests the mock serves, so later conversations can
+	// ask about them too
+	Persist bool `json:"persist,omitempty"`
+}
+
+// memoryFact is a fact extracted from a message, at the given offset
+type memoryFact struct {
+	key, value string
+	offset     int
+}
+
+// memories keeps the facts persisted by every mock
+type memories struct {
+	mu    sync.Mutex
+	facts map[string]map[string]string
+}
+
+func newMemories() *memories {
+	return &memories{facts: map[string]map[string]string{}}
+}
+
+// recall returns a copy of the facts persisted by a mock
+func (m *memories) recall(mockName string) map[string]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	facts := map[string]string{}
+	for key, value := range m.facts[mockName] {
+		facts[key] = value
+	}
+	return facts
+}
+
+// remember persists facts of a mock
+func (m *memories) remember(mockName string, facts map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.facts[mockName] == nil {
+		m.facts[mockName] = map[string]string{}
+	}
+	for key, value := range facts {
+		m.facts[mockName][key] = value
+	}
+}
+
+// memoryIssues reports the patterns of a memory config that don't compile or lack their groups
+func memoryIssues(config *MemoryConfig, path string) []configIssue {
+	if config == nil {
+		return nil
+	}
+	var issues []configIssue
+	check := func(field string, patterns []string, groups ...string) {
+		for i, pattern := range patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				issues = append(issues, configIssue{fmt.Sprintf("%s.%s[%d]", path, field, i), err.Error()})
+				continue
+			}
+			for _, group := range groups {
+				if re.SubexpIndex(group) < 0 {
+					issues = append(issues, configIssue{fmt.Sprintf("%s.%s[%d]", path, field, i),
+						fmt.Sprintf("missing named group %q", group)})
+				}
+			}
+		}
+	}
+	check("facts", config.Facts, "key", "value")
+	check("questions", config.Questions, "key")
+	return issues
+}
+
+// compileMemoryPatterns compiles the patterns, skipping those that don't compile
+func compileMemoryPatterns(patterns, defaults []string) []*regexp.Regexp {
+	if len(patterns) == 0 {
+		patterns = defaults
+	}
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		if re, err := regexp.Compile(pattern); err == nil {
+			compiled = append(compiled, re)
+		}
+	}
+	return compiled
+}
+
+// memoryKey normalizes a fact key, so "My Name" and "name" are the same fact
+func memoryKey(key string) string {
+	key = strings.ToLower(strings.Join(strings.Fields(key), " "))
+	for _, prefix := range []string{"my ", "the "} {
+		key = strings.TrimPrefix(key, prefix)
+	}
+	return key
+}
+
+// extractFacts returns the facts asserted in a text, in the order they appear
+func extractFacts(patterns []*regexp.Regexp, text string) []memoryFact {
+	var facts []memoryFact
+	for _, re := range patterns {
+		key, value := re.SubexpIndex("key"), re.SubexpIndex("value")
+		for _, match := range re.FindAllStringSubmatchIndex(text, -1) {
+			facts = append(facts, memoryFact{
+				key:    memoryKey(text[match[2*key]:match[2*key+1]]),
+				value:  strings.TrimSpace(text[match[2*value]:match[2*value+1]]),
+				offset: match[0],
+			})
+		}
+	}
+	slices.SortStableFunc(facts, func(a, b memoryFact) int { return cmp.Compare(a.offset, b.offset) })
+	return facts
+}
+
+// reply answers the last message of a conversation given as the texts of its messages, reporting
+// whether it asked about or asserted a fact. New facts are persisted when configured.
+func (c *MemoryConfig) reply(store *memories, mockName string, messages []string) (string, bool) {
+	if len(messages) == 0 {
+		return "", false
+	}
+	factPatterns := compileMemoryPatterns(c.Facts, DefaultMemoryFacts)
+
+	known := map[string]string{}
+	if c.Persist {
+		known = store.recall(mockName)
+	}
+	asserted := map[string]string{}
+	var last []memoryFact
+	for i, text := range messages {
+		facts := extractFacts(factPatterns, text)
+		for _, fact := range facts {
+			known[fact.key], asserted[fact.key] = fact.value, fact.value
+		}
+		if i == len(messages)-1 {
+			last = facts
+		}
+	}
+	if c.Persist {
+		store.remember(mockName, asserted)
+	}
+
+	question := messages[len(messages)-1]
+	for _, re := range compileMemoryPatterns(c.Questions, DefaultMemoryQuestions) {
+		match := re.FindStringSubmatch(question)
+		if match == nil {
+			continue
+		}
+		key := memoryKey(match[re.SubexpIndex("key")])
+		if value, ok := known[key]; ok {
+			return strings.NewReplacer("{key}", key, "{value}", value).Replace(cmp.Or(c.Answer, "Your {key} is {value}.")), true
+		}
+		return strings.NewReplacer("{key}", key).Replace(cmp.Or(c.Unknown, "I don't know your {key}.")), true
+	}
+	if len(last) > 0 {
+		fact := last[len(last)-1]
+		return strings.NewReplacer("{key}", fact.key, "{value}", fact.value).
+			Replace(cmp.Or(c.Acknowledge, "Got it, your {key} is {value}.")), true
+	}
+	return "", false
+}
+
+// openaiMessageTexts returns the text content of every message of the request
+func openaiMessageTexts(request openai.ChatCompletionNewParams) []string {
+	texts := make([]string, len(request.Messages))
+	for i, message := range request.Messages {
+		texts[i] = openaiMessageText(message)
+	}
+	return texts
+}
+
+// anthropicMessageTexts returns the text blocks of the system prompt, when there is one, followed
+// by those of every message of the request
+func anthropicMessageTexts(request anthropic.MessageNewParams) []string {
+	var texts []string
+	var system string
+	for _, block := range request.System {
+		system += block.Text
+	}
+	if system != "" {
+		texts = append(texts, system)
+	}
+	for _, message := range request.Messages {
+		texts = append(texts, anthropicMessageText(message))
+	}
+	return texts
+}
@@ -0,0 +1,90 @@
+package mockllm_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryResponder(t *testing.T) {
+	baseURL := newOpenAIServer(t, mockllm.OpenAIMock{
+		Name:     "memory",
+		Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("")},
+		Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "Hmm."}}}},
+		Memory:   &mockllm.MemoryConfig{},
+	})
+	reply := func(messages ...openai.ChatCompletionMessageParamUnion) string {
+		completion := postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{Model: "gpt-4o-mini", Messages: messages})
+		require.Len(t, completion.Choices, 1)
+		return completion.Choices[0].Message.Content
+	}
+
+	assert.Equal(t, "Got it, your name is Ada.", reply(openaiUserMessage("Hi, my name is Ada.")))
+	assert.Equal(t, "Your name is Ada.", reply(
+		openaiUserMessage("Hi, my name is Ada."),
+		openai.AssistantMessage("Got it, your name is Ada."),
+		openaiUserMessage("What is my name?"),
+	))
+	assert.Equal(t, "Your deadline is Friday.", reply(
+		openai.SystemMessage("Memories: remember that the deadline is Thursday. Remember that the deadline is Friday."),
+		openaiUserMessage("Do you remember the deadline?"),
+	), "later facts override earlier ones")
+	assert.Equal(t, "I don't know your favorite color.", reply(openaiUserMessage("What's my favorite color?")))
+	assert.Equal(t, "Hmm.", reply(openaiUserMessage("Tell me a joke")))
+}
+
+func TestPersistedMemory(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{Anthropic: []mockllm.AnthropicMock{{
+		Name: "memory",
+		Match: mockllm.AnthropicRequestMatch{
+			MatchType: mockllm.MatchTypeContains,
+			Message:   anthropic.NewUserMessage(anthropic.NewTextBlock("")),
+		},
+		Memory: &mockllm.MemoryConfig{
+			Facts:   []string{`(?P<key>\w+) = (?P<value>\w+)`},
+			Answer:  "{key} was {value}",
+			Persist: true,
+		},
+	}}}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+
+	reply := func(text string) string {
+		resp := postJSON(t, baseURL+"/v1/messages", anthropic.MessageNewParams{
+			Model:     "claude-3-5-sonnet-20240620",
+			MaxTokens: 100,
+			Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock(text))},
+		}, anthropicHeaders("2023-06-01"))
+		defer resp.Body.Close() //nolint:errcheck
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var message anthropic.Message
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&message))
+		require.Len(t, message.Content, 1)
+		return message.Content[0].Text
+	}
+
+	assert.Equal(t, "Got it, your color is teal.", reply("color = teal"))
+	assert.Equal(t, "color was teal", reply("What is the color?"), "facts of earlier conversations are remembered")
+}
+
+func TestMemoryConfigValidation(t *testing.T) {
+	filesys := fstest.MapFS{"mocks.json": {Data: []byte(`{
+  "openai": [{
+    "name": "memory",
+    "match": {"match_type": "contains", "message": {"role": "user", "content": ""}},
+    "memory": {"facts": ["(?P<key>\\w+) is (\\w+)"], "questions": ["what("]}
+  }]
+}`)}}
+	_, err := mockllm.LoadConfigFromFile("mocks.json", filesys)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `openai[0].memory.facts[0]: missing named group "value"`)
+	assert.Contains(t, err.Error(), `openai[0].memory.questions[0]: error parsing regexp`)
+}
@@ -0,0 +1,323 @@
+package mockllm
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"math"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMetricsThreshold is the relative change of a metric from its baseline flagged as a delta
+const DefaultMetricsThreshold = 0.2
+
+// MetricsConfig compares the prompt metrics of a run against a baseline stored by an earlier run
+// when the server stops, failing on significant deltas, so the mock doubles as a lightweight
+// prompt regression detector
+type MetricsConfig struct {
+	// Baseline is the file of the baseline metrics, written by the first run
+	Baseline string `json:"baseline"`
+	// Threshold is the relative change of a metric flagged as a delta, DefaultMetricsThreshold
+	// when unset
+	Threshold float64 `json:"threshold,omitempty"`
+	// Update rewrites the baseline with the metrics of the run instead of comparing them, like
+	// setting UpdateGoldenEnvVar
+	Update bool `json:"update,omitempty"`
+}
+
+// threshold returns the configured threshold, or the default one
+func (c MetricsConfig) threshold() float64 {
+	if c.Threshold == 0 {
+		return DefaultMetricsThreshold
+	}
+	return c.Threshold
+}
+
+// metricsIssues reports metric baselines without a path or with negative thresholds
+func metricsIssues(metrics *MetricsConfig) []configIssue {
+	if metrics == nil {
+		return nil
+	}
+	var issues []configIssue
+	if metrics.Baseline == "" {
+		issues = append(issues, configIssue{"metrics.baseline", "must not be empty"})
+	}
+	if metrics.Threshold < 0 {
+		issues = append(issues, configIssue{"metrics.threshold", "must not be negative"})
+	}
+	return issues
+}
+
+// PromptMetrics summarizes the OpenAI chat completion and Anthropic message requests of a run
+type PromptMetrics struct {
+	Requests int `json:"requests"`
+	// AvgPromptChars is the average length, in characters, of the text of the messages and
+	// system prompts of the requests
+	AvgPromptChars float64 `json:"avg_prompt_chars"`
+	AvgMessages    float64 `json:"avg_messages"`
+	// MessageCounts is the distribution of the number of messages of the requests, the number of
+	// requests keyed by their number of messages
+	MessageCounts map[int]int `json:"message_counts"`
+	// ToolsDeclared counts the requests declaring each tool, and ToolCalls the responses served
+	// calling each tool
+	ToolsDeclared map[string]int `json:"tools_declared"`
+	ToolCalls     map[string]int `json:"tool_calls"`
+}
+
+// metricsRequest holds the parts of OpenAI and Anthropic requests the metrics are computed from
+type metricsRequest struct {
+	System   json.RawMessage `json:"system"`
+	Messages []struct {
+		Content json.RawMessage `json:"content"`
+	} `json:"messages"`
+	Tools []struct {
+		Name     string `json:"name"`
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	} `json:"tools"`
+}
+
+// metricsResponse holds the tool calls of OpenAI and Anthropic responses
+type metricsResponse struct {
+	Choices []struct {
+		Message struct {
+			ToolCalls []struct {
+				Function struct {
+					Name string `json:"name"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"message"`
+	} `json:"choices"`
+	Content []struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+	} `json:"content"`
+}
+
+// PromptMetrics computes the prompt metrics of the requests served so far
+func (s *Server) PromptMetrics() PromptMetrics {
+	metrics := PromptMetrics{MessageCounts: map[int]int{}, ToolsDeclared: map[string]int{}, ToolCalls: map[string]int{}}
+	var promptChars, messages int
+	for _, record := range s.Requests() {
+		if !strings.HasSuffix(record.Path, "/chat/completions") && !strings.HasSuffix(record.Path, "/messages") {
+			continue
+		}
+		var request metricsRequest
+		if json.Unmarshal(record.Body, &request) != nil || len(request.Messages) == 0 {
+			continue
+		}
+		metrics.Requests++
+		messages += len(request.Messages)
+		metrics.MessageCounts[len(request.Messages)]++
+		promptChars += textLength(request.System)
+		for _, message := range request.Messages {
+			promptChars += textLength(message.Content)
+		}
+		for _, tool := range request.Tools {
+			metrics.ToolsDeclared[cmp.Or(tool.Name, tool.Function.Name)]++
+		}
+
+		var response metricsResponse
+		if json.Unmarshal(record.Response, &response) != nil {
+			continue
+		}
+		for _, choice := range response.Choices {
+			for _, call := range choice.Message.ToolCalls {
+				metrics.ToolCalls[call.Function.Name]++
+			}
+		}
+		for _, block := range response.Content {
+			if block.Type == "tool_use" || block.Type == "server_tool_use" {
+				metrics.ToolCalls[block.Name]++
+			}
+		}
+	}
+	if metrics.Requests > 0 {
+		metrics.AvgPromptChars = float64(promptChars) / float64(metrics.Requests)
+		metrics.AvgMessages = float64(messages) / float64(metrics.Requests)
+	}
+	return metrics
+}
+
+// textLength returns the number of characters of a string, or of the text of an array of
+// content blocks
+func textLength(content json.RawMessage) int {
+	var text string
+	if json.Unmarshal(content, &text) == nil {
+		return utf8.RuneCountInString(text)
+	}
+	var blocks []struct {
+		Text string `json:"text"`
+	}
+	if json.Unmarshal(content, &blocks) != nil {
+		return 0
+	}
+	length := 0
+	for _, block := range blocks {
+		length += utf8.RuneCountInString(block.Text)
+	}
+	return length
+}
+
+// MetricDelta is a metric that changed from its baseline by more than the threshold
+type MetricDelta struct {
+	// Metric is the name of the metric, e.g. avg_prompt_chars or tool_calls.search
+	Metric   string  `json:"metric"`
+	Baseline float64 `json:"baseline"`
+	Current  float64 `json:"current"`
+	// Change is the change relative to the baseline, +Inf for metrics the baseline lacks
+	Change float64 `json:"change"`
+}
+
+// String describes the delta, e.g. avg_prompt_chars: 120 -> 180 (+50%)
+func (d MetricDelta) String() string {
+	if math.IsInf(d.Change, 0) {
+		return fmt.Sprintf("%s: %g -> %g (new)", d.Metric, d.Baseline, d.Current)
+	}
+	return fmt.Sprintf("%s: %g -> %g (%+.0f%%)", d.Metric, d.Baseline, d.Current, d.Change*100)
+}
+
+// MarshalJSON writes the change of metrics the baseline lacks as null, JSON having no infinity
+func (d MetricDelta) MarshalJSON() ([]byte, error) {
+	type delta MetricDelta
+	var change *float64
+	if !math.IsInf(d.Change, 0) {
+		change = &d.Change
+	}
+	return json.Marshal(struct {
+		delta
+		Change *float64 `json:"change"`
+	}{delta(d), change})
+}
+
+// CompareMetrics returns the metrics of current that changed from baseline by more than the
+// threshold, relative to the baseline. Tool counts are compared per request, so runs of
+// different lengths compare.
+func CompareMetrics(baseline, current PromptMetrics, threshold float64) []MetricDelta {
+	values := func(metrics PromptMetrics) map[string]float64 {
+		values := map[string]float64{
+			"requests":         float64(metrics.Requests),
+			"avg_prompt_chars": metrics.AvgPromptChars,
+			"avg_messages":     metrics.AvgMessages,
+		}
+		if metrics.Requests == 0 {
+			return values
+		}
+		for name, count := range metrics.ToolsDeclared {
+			values["tools_declared."+name] = float64(count) / float64(metrics.Requests)
+		}
+		for name, count := range metrics.ToolCalls {
+			values["tool_calls."+name] = float64(count) / float64(metrics.Requests)
+		}
+		return values
+	}
+	baselineValues, currentValues := values(baseline), values(current)
+	names := slices.Sorted(maps.Keys(baselineValues))
+	for name := range currentValues {
+		if _, ok := baselineValues[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	deltas := []MetricDelta{}
+	for _, name := range names {
+		was, is := baselineValues[name], currentValues[name]
+		change := math.Inf(1)
+		if was != 0 {
+			change = (is - was) / was
+		} else if is == 0 {
+			change = 0
+		}
+		if math.Abs(change) > threshold {
+			deltas = append(deltas, MetricDelta{Metric: name, Baseline: round(was), Current: round(is), Change: change})
+		}
+	}
+	return deltas
+}
+
+// round rounds a metric to two decimals for reports
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// readBaseline reads the baseline metrics of the config
+func (s *Server) readBaseline() (PromptMetrics, error) {
+	var baseline PromptMetrics
+	if s.config.Metrics == nil {
+		return baseline, errors.New("no metrics baseline configured")
+	}
+	data, err := os.ReadFile(s.config.Metrics.Baseline)
+	if err == nil {
+		err = json.Unmarshal(data, &baseline)
+	}
+	if err != nil {
+		return baseline, fmt.Errorf("failed to read metrics baseline: %w", err)
+	}
+	return baseline, nil
+}
+
+// checkMetrics writes the metrics baseline when it is missing or being updated, and otherwise
+// returns an error listing the deltas of the run from it
+func (s *Server) checkMetrics() error {
+	config := s.config.Metrics
+	if config == nil {
+		return nil
+	}
+	if _, err := os.Stat(config.Baseline); config.Update || updateGoldenEnv() || errors.Is(err, fs.ErrNotExist) {
+		data, err := json.MarshalIndent(s.PromptMetrics(), "", "  ")
+		if err == nil {
+			err = os.WriteFile(config.Baseline, append(data, '\n'), 0o644)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write metrics baseline: %w", err)
+		}
+		return nil
+	}
+	baseline, err := s.readBaseline()
+	if err != nil {
+		return err
+	}
+	deltas := CompareMetrics(baseline, s.PromptMetrics(), config.threshold())
+	if len(deltas) == 0 {
+		return nil
+	}
+	lines := make([]string, len(deltas))
+	for i, delta := range deltas {
+		lines[i] = "  " + delta.String()
+	}
+	return fmt.Errorf("prompt metrics changed from baseline %s by more than %g%%:\n%s", config.Baseline, config.threshold()*100, strings.Join(lines, "\n"))
+}
+
+// handleAdminMetrics returns the prompt metrics of the run and, with a baseline, the baseline and
+// the deltas from it, with a 417 status when there are any
+func (s *Server) handleAdminMetrics(w http.ResponseWriter, r *http.Request) {
+	metrics := s.PromptMetrics()
+	if s.config.Metrics == nil {
+		writeJSON(w, http.StatusOK, map[string]any{"metrics": metrics})
+		return
+	}
+	baseline, err := s.readBaseline()
+	if errors.Is(err, fs.ErrNotExist) {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": err.Error(), "metrics": metrics})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	deltas := CompareMetrics(baseline, metrics, s.config.Metrics.threshold())
+	status := http.StatusOK
+	if len(deltas) > 0 {
+		status = http.StatusExpectationFailed
+	}
+	writeJSON(w, status, map[string]any{"metrics": metrics, "baseline": baseline, "deltas": deltas})
+}
@@ -0,0 +1,112 @@
+package mockllm_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptMetrics(t *testing.T) {
+	baselinePath := filepath.Join(t.TempDir(), "metrics.json")
+	config := mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{{
+			Name:  "search",
+			Match: mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Find")},
+			Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{
+				Message: openai.ChatCompletionMessage{ToolCalls: []openai.ChatCompletionMessageToolCall{{
+					ID:       "call_1",
+					Function: openai.ChatCompletionMessageToolCallFunction{Name: "search", Arguments: "{}"},
+				}}},
+			}}},
+		}},
+		Anthropic: []mockllm.AnthropicMock{{
+			Name:     "hello",
+			Match:    mockllm.AnthropicRequestMatch{MatchType: mockllm.MatchTypeContains, Message: anthropic.NewUserMessage(anthropic.NewTextBlock("Hello"))},
+			Response: anthropic.Message{Content: []anthropic.ContentBlockUnion{{Type: "text", Text: "Hi"}}},
+		}},
+		Metrics: &mockllm.MetricsConfig{Baseline: baselinePath},
+	}
+	tools := []openai.ChatCompletionToolParam{{Function: openai.FunctionDefinitionParam{Name: "search"}}}
+	run := func(prompt string) (*mockllm.Server, string) {
+		t.Helper()
+		server := mockllm.NewServer(mockllm.WithConfig(config))
+		baseURL, err := server.Start(t.Context())
+		require.NoError(t, err)
+		postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+			Model: "gpt-4o",
+			Messages: []openai.ChatCompletionMessageParamUnion{
+				openai.SystemMessage("You are a search agent"),
+				openai.UserMessage(prompt),
+			},
+			Tools: tools,
+		})
+		resp := postJSON(t, baseURL+"/v1/messages", anthropicHelloRequest, anthropicHeaders("2023-06-01"))
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return server, baseURL
+	}
+
+	server, _ := run("Find cats")
+	metrics := server.PromptMetrics()
+	assert.Equal(t, 2, metrics.Requests)
+	assert.Equal(t, 1.5, metrics.AvgMessages)
+	assert.Equal(t, map[int]int{1: 1, 2: 1}, metrics.MessageCounts)
+	assert.Equal(t, map[string]int{"search": 1}, metrics.ToolsDeclared)
+	assert.Equal(t, map[string]int{"search": 1}, metrics.ToolCalls)
+	assert.Equal(t, float64(len("You are a search agent")+len("Find cats")+len("Hello"))/2, metrics.AvgPromptChars)
+	// The first run writes the baseline
+	require.NoError(t, server.Stop(t.Context()))
+
+	server, _ = run("Find cats")
+	require.NoError(t, server.Stop(t.Context()))
+
+	// A much longer prompt is flagged
+	server, baseURL := run("Find cats " + strings.Repeat("and be thorough ", 10))
+	resp, err := http.Get(baseURL + "/admin/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	assert.Equal(t, http.StatusExpectationFailed, resp.StatusCode)
+	var body struct {
+		Deltas []mockllm.MetricDelta `json:"deltas"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.Len(t, body.Deltas, 1)
+	assert.Equal(t, "avg_prompt_chars", body.Deltas[0].Metric)
+	assert.Equal(t, 18.0, body.Deltas[0].Baseline)
+	assert.Equal(t, 98.5, body.Deltas[0].Current)
+
+	err = server.Stop(t.Context())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "prompt metrics changed from baseline")
+	assert.Contains(t, err.Error(), "avg_prompt_chars: 18 -> 98.5 (+447%)")
+}
+
+func TestCompareMetrics(t *testing.T) {
+	baseline := mockllm.PromptMetrics{Requests: 4, AvgPromptChars: 100, AvgMessages: 3, ToolCalls: map[string]int{"search": 4}}
+	current := mockllm.PromptMetrics{Requests: 8, AvgPromptChars: 110, AvgMessages: 3, ToolCalls: map[string]int{"search": 8, "delete": 1}}
+
+	deltas := mockllm.CompareMetrics(baseline, current, 0.2)
+	require.Len(t, deltas, 2)
+	assert.Equal(t, "requests: 4 -> 8 (+100%)", deltas[0].String())
+	assert.Equal(t, "tool_calls.delete: 0 -> 0.13 (new)", deltas[1].String())
+
+	data, err := json.Marshal(deltas[1])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"metric": "tool_calls.delete", "baseline": 0, "current": 0.13, "change": null}`, string(data))
+}
+
+func TestMetricsValidation(t *testing.T) {
+	filesys := fstest.MapFS{"config.json": {Data: []byte(`{"metrics": {"threshold": -1}}`)}}
+	_, err := mockllm.LoadConfigFromFile("config.json", filesys)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "metrics.baseline: must not be empty")
+	assert.Contains(t, err.Error(), "metrics.threshold: must not be negative")
+}
@@ -0,0 +1,75 @@
+package mockllmtest
+
+import (
+	"fmt"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/onsi/gomega/format"
+	"github.com/onsi/gomega/types"
+)
+
+// HaveMatchedMock succeeds when the *mockllm.Server has served at least one request with the named mock
+//
+//	Expect(server).To(mockllmtest.HaveMatchedMock("initial_request"))
+func HaveMatchedMock(mockName string) types.GomegaMatcher {
+	return &haveMatchedMockMatcher{mockName: mockName}
+}
+
+type haveMatchedMockMatcher struct {
+	mockName string
+	requests []mockllm.RequestRecord
+}
+
+func (m *haveMatchedMockMatcher) Match(actual any) (bool, error) {
+	server, err := toServer("HaveMatchedMock", actual)
+	if err != nil {
+		return false, err
+	}
+	m.requests = server.Requests()
+	return matches(server, m.mockName) > 0, nil
+}
+
+func (m *haveMatchedMockMatcher) FailureMessage(any) string {
+	return fmt.Sprintf("Expected mock %q to be matched, but it was not. Requests:\n%s", m.mockName, summarize(m.requests))
+}
+
+func (m *haveMatchedMockMatcher) NegatedFailureMessage(any) string {
+	return fmt.Sprintf("Expected mock %q not to be matched, but it was. Requests:\n%s", m.mockName, summarize(m.requests))
+}
+
+// HaveReceivedRequests succeeds when the *mockllm.Server has logged exactly count requests
+//
+//	Eventually(server).Should(mockllmtest.HaveReceivedRequests(3))
+func HaveReceivedRequests(count int) types.GomegaMatcher {
+	return &haveReceivedRequestsMatcher{count: count}
+}
+
+type haveReceivedRequestsMatcher struct {
+	count    int
+	requests []mockllm.RequestRecord
+}
+
+func (m *haveReceivedRequestsMatcher) Match(actual any) (bool, error) {
+	server, err := toServer("HaveReceivedRequests", actual)
+	if err != nil {
+		return false, err
+	}
+	m.requests = server.Requests()
+	return len(m.requests) == m.count, nil
+}
+
+func (m *haveReceivedRequestsMatcher) FailureMessage(any) string {
+	return fmt.Sprintf("Expected %d requests, but received %d:\n%s", m.count, len(m.requests), summarize(m.requests))
+}
+
+func (m *haveReceivedRequestsMatcher) NegatedFailureMessage(any) string {
+	return fmt.Sprintf("Expected not to receive %d requests, but did:\n%s", m.count, summarize(m.requests))
+}
+
+func toServer(matcher string, actual any) (*mockllm.Server, error) {
+	server, ok := actual.(*mockllm.Server)
+	if !ok || server == nil {
+		return nil, fmt.Errorf("%s matcher expects a *mockllm.Server. Got:\n%s", matcher, format.Object(actual, 1))
+	}
+	return server, nil
+}
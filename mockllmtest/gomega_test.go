@@ -0,0 +1,31 @@
+package mockllmtest_test
+
+import (
+	"testing"
+
+	"github.com/kagent-dev/mockllm/mockllmtest"
+	"github.com/onsi/gomega"
+)
+
+func TestGomegaMatchers(t *testing.T) {
+	g := gomega.NewWithT(t)
+	server, post := newHelloServer(t)
+
+	g.Expect(server).NotTo(mockllmtest.HaveMatchedMock("hello"))
+	g.Expect(server).To(mockllmtest.HaveReceivedRequests(0))
+
+	post("Hello there")
+	post("Goodbye")
+	g.Expect(server).To(mockllmtest.HaveMatchedMock("hello"))
+	g.Expect(server).NotTo(mockllmtest.HaveMatchedMock("other"))
+	g.Expect(server).To(mockllmtest.HaveReceivedRequests(2))
+
+	failure := mockllmtest.HaveReceivedRequests(3)
+	ok, err := failure.Match(server)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(ok).To(gomega.BeFalse())
+	g.Expect(failure.FailureMessage(server)).To(gomega.ContainSubstring("matched hello"))
+
+	_, err = mockllmtest.HaveMatchedMock("hello").Match("not a server")
+	g.Expect(err).To(gomega.HaveOccurred())
+}
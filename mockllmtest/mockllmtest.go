@@ -0,0 +1,200 @@
+// Package mockllmtest provides assertions on the requests a mockllm.Server has handled, to cut
+// down on boilerplate in test suites using the mock server.
+package mockllmtest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kagent-dev/mockllm"
+)
+
+// TestingT is the subset of testing.TB used by the assertions, also implemented by GinkgoT()
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+	FailNow()
+}
+
+// AssertMatched asserts that the named mock served at least one request
+func AssertMatched(t TestingT, server *mockllm.Server, mockName string) bool {
+	t.Helper()
+	if matches(server, mockName) == 0 {
+		t.Errorf("expected mock %q to be matched, but it was not. Requests:\n%s", mockName, summarize(server.Requests()))
+		return false
+	}
+	return true
+}
+
+// AssertMatchedTimes asserts that the named mock served exactly n requests
+func AssertMatchedTimes(t TestingT, server *mockllm.Server, mockName string, n int) bool {
+	t.Helper()
+	if got := matches(server, mockName); got != n {
+		t.Errorf("expected mock %q to be matched %d times, but it was matched %d times. Requests:\n%s",
+			mockName, n, got, summarize(server.Requests()))
+		return false
+	}
+	return true
+}
+
+// AssertNotMatched asserts that the named mock did not serve any request
+func AssertNotMatched(t TestingT, server *mockllm.Server, mockName string) bool {
+	t.Helper()
+	return AssertMatchedTimes(t, server, mockName, 0)
+}
+
+// AssertNoUnmatched asserts that every well-formed request the server received matched a mock
+func AssertNoUnmatched(t TestingT, server *mockllm.Server) bool {
+	t.Helper()
+	var unmatched []mockllm.RequestRecord
+	for _, record := range server.Requests() {
+		if Unmatched(record) {
+			unmatched = append(unmatched, record)
+		}
+	}
+	if len(unmatched) > 0 {
+		t.Errorf("expected every request to match a mock, but %d did not:\n%s", len(unmatched), describeUnmatched(unmatched))
+		return false
+	}
+	return true
+}
+
+// AssertAllMocksUsed asserts that every configured mock served at least one request, reporting
+// the mocks the test never reached
+func AssertAllMocksUsed(t TestingT, server *mockllm.Server) bool {
+	t.Helper()
+	unused := server.UnusedMocks()
+	if len(unused) == 0 {
+		return true
+	}
+	var sb strings.Builder
+	for _, mock := range unused {
+		sb.WriteString("  " + mock.Provider + " " + mock.Name)
+		if mock.Tenant != "" {
+			sb.WriteString(" (tenant " + mock.Tenant + ")")
+		}
+		sb.WriteString("\n")
+	}
+	t.Errorf("expected every mock to be matched, but %d were not:\n%s", len(unused), sb.String())
+	return false
+}
+
+// AssertClientDisconnected asserts that a client went away before a streamed response of the
+// named mock completed. The request must already be logged, see mockllm.Server.DisconnectedMidStream.
+func AssertClientDisconnected(t TestingT, server *mockllm.Server, mockName string) bool {
+	t.Helper()
+	if !server.DisconnectedMidStream(mockName) {
+		t.Errorf("expected a client to disconnect from a stream of mock %q, but none did", mockName)
+		return false
+	}
+	return true
+}
+
+// AssertMaxConcurrency asserts that the named mock never served more than limit requests at once,
+// e.g. to check the parallelism budget of an agent
+func AssertMaxConcurrency(t TestingT, server *mockllm.Server, mockName string, limit int) bool {
+	t.Helper()
+	if got := server.MaxConcurrency(mockName); got > limit {
+		t.Errorf("expected mock %q to serve at most %d requests at once, but it served %d. Requests:\n%s",
+			mockName, limit, got, summarize(server.Requests()))
+		return false
+	}
+	return true
+}
+
+// AssertNoDuplicates asserts that the server received no identical requests within its duplicate
+// window, which point at retry loops double-spending tokens
+func AssertNoDuplicates(t TestingT, server *mockllm.Server) bool {
+	t.Helper()
+	duplicates := server.Duplicates()
+	if len(duplicates) == 0 {
+		return true
+	}
+	var b strings.Builder
+	for _, duplicate := range duplicates {
+		fmt.Fprintf(&b, "  #%d %s %s sent again as %v\n", duplicate.FirstID, duplicate.Provider, duplicate.Path,
+			duplicate.DuplicateIDs)
+	}
+	t.Errorf("expected no duplicate requests, but %d were repeated:\n%s", len(duplicates), b.String())
+	return false
+}
+
+// AssertExpectationsMet asserts that every request met the expectations of the mock that served it
+func AssertExpectationsMet(t TestingT, server *mockllm.Server) bool {
+	t.Helper()
+	if err := server.Verify(); err != nil {
+		t.Errorf("expected requests to meet the expectations of their mocks:\n%v", err)
+		return false
+	}
+	return true
+}
+
+// RequireLastRequestContains requires the body of the most recent request to contain substr,
+// stopping the test otherwise
+func RequireLastRequestContains(t TestingT, server *mockllm.Server, substr string) {
+	t.Helper()
+	requests := server.Requests()
+	if len(requests) == 0 {
+		t.Errorf("expected the last request to contain %q, but no requests were received", substr)
+		t.FailNow()
+		return
+	}
+	last := requests[len(requests)-1]
+	if !strings.Contains(string(last.Body), substr) {
+		t.Errorf("expected the last request to contain %q, but its body was:\n%s", substr, last.Body)
+		t.FailNow()
+	}
+}
+
+// Unmatched reports whether a logged request was well-formed but did not match any mock, and was
+// not an idempotent replay either
+func Unmatched(record mockllm.RequestRecord) bool {
+	return !record.Matched && record.Error == "" && record.ReplayOf == 0
+}
+
+func matches(server *mockllm.Server, mockName string) int {
+	n := 0
+	for _, record := range server.Requests() {
+		if record.Matched && record.MockName == mockName {
+			n++
+		}
+	}
+	return n
+}
+
+// summarize lists the requests one per line with the mock that served them
+func summarize(records []mockllm.RequestRecord) string {
+	if len(records) == 0 {
+		return "  (none)"
+	}
+	var b strings.Builder
+	for _, record := range records {
+		outcome := "unmatched"
+		switch {
+		case record.Matched:
+			outcome = "matched " + record.MockName
+		case record.ReplayOf != 0:
+			outcome = fmt.Sprintf("replayed #%d", record.ReplayOf)
+		case record.Error != "":
+			outcome = "error: " + record.Error
+		}
+		fmt.Fprintf(&b, "  #%d %s %s %s -> %d %s\n", record.ID, record.Provider, record.Method, record.Path,
+			record.Status, outcome)
+	}
+	return b.String()
+}
+
+// describeUnmatched lists unmatched requests along with their diffs against every mock
+func describeUnmatched(records []mockllm.RequestRecord) string {
+	var b strings.Builder
+	for _, record := range records {
+		fmt.Fprintf(&b, "  #%d %s %s %s\n", record.ID, record.Provider, record.Method, record.Path)
+		for _, diff := range record.Diffs {
+			fmt.Fprintf(&b, "    vs %s (%s):\n", diff.MockName, diff.MatchType)
+			for _, line := range strings.Split(strings.TrimRight(diff.Diff, "\n"), "\n") {
+				fmt.Fprintf(&b, "      %s\n", line)
+			}
+		}
+	}
+	return b.String()
+}
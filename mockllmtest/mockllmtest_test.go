@@ -0,0 +1,112 @@
+package mockllmtest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/kagent-dev/mockllm/mockllmtest"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingT records failures instead of failing the test
+type recordingT struct {
+	errors []string
+	failed bool
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingT) FailNow() {
+	r.failed = true
+}
+
+// newHelloServer starts a server with a single mock answering "Hello" and returns it along with a
+// function posting a user message to it
+func newHelloServer(t *testing.T) (*mockllm.Server, func(content string)) {
+	t.Helper()
+
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{{
+			Name: "hello",
+			Match: mockllm.OpenAIRequestMatch{
+				MatchType: mockllm.MatchTypeContains,
+				Message: openai.ChatCompletionMessageParamUnion{OfUser: &openai.ChatCompletionUserMessageParam{
+					Role:    "user",
+					Content: openai.ChatCompletionUserMessageParamContentUnion{OfString: openai.String("Hello")},
+				}},
+			},
+			Response: openai.ChatCompletion{ID: "chatcmpl-hello"},
+			Expect:   []mockllm.RequestExpectation{{NotContains: "password"}},
+		}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(context.Background()) }) //nolint:errcheck
+
+	return server, func(content string) {
+		body, err := json.Marshal(map[string]any{
+			"model":    "gpt-4o-mini",
+			"messages": []map[string]any{{"role": "user", "content": content}},
+		})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, baseURL+"/v1/chat/completions", bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer test-key")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close() //nolint:errcheck
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	server, post := newHelloServer(t)
+
+	rt := &recordingT{}
+	assert.False(t, mockllmtest.AssertMatched(rt, server, "hello"))
+	mockllmtest.RequireLastRequestContains(rt, server, "Hello")
+	assert.True(t, rt.failed)
+	assert.False(t, mockllmtest.AssertAllMocksUsed(rt, server))
+	assert.Contains(t, rt.errors[len(rt.errors)-1], "openai hello")
+
+	post("Hello there")
+	assert.True(t, mockllmtest.AssertAllMocksUsed(t, server))
+	assert.True(t, mockllmtest.AssertMatched(t, server, "hello"))
+	assert.True(t, mockllmtest.AssertMatchedTimes(t, server, "hello", 1))
+	assert.True(t, mockllmtest.AssertNotMatched(t, server, "other"))
+	assert.True(t, mockllmtest.AssertNoUnmatched(t, server))
+	mockllmtest.RequireLastRequestContains(t, server, "Hello there")
+
+	post("Goodbye")
+	rt = &recordingT{}
+	assert.False(t, mockllmtest.AssertNoUnmatched(rt, server))
+	require.Len(t, rt.errors, 1)
+	assert.Contains(t, rt.errors[0], "vs hello (contains)")
+	assert.Contains(t, rt.errors[0], "Goodbye")
+
+	mockllmtest.RequireLastRequestContains(rt, server, "Hello")
+	assert.True(t, rt.failed)
+	assert.False(t, mockllmtest.AssertClientDisconnected(rt, server, "hello"))
+	assert.True(t, mockllmtest.AssertMaxConcurrency(t, server, "hello", 1))
+	assert.False(t, mockllmtest.AssertMaxConcurrency(rt, server, "hello", 0))
+
+	assert.True(t, mockllmtest.AssertNoDuplicates(t, server))
+	post("Hello there")
+	assert.False(t, mockllmtest.AssertNoDuplicates(rt, server))
+	assert.Contains(t, rt.errors[len(rt.errors)-1], "#1 openai /v1/chat/completions sent again as [3]")
+
+	assert.True(t, mockllmtest.AssertExpectationsMet(t, server))
+	post("Hello, my password is hunter2")
+	assert.False(t, mockllmtest.AssertExpectationsMet(rt, server))
+	assert.Contains(t, rt.errors[len(rt.errors)-1], `request #4 (openai hello): request includes "password"`)
+}
@@ -0,0 +1,114 @@
+package mockllm
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// mockSwitches enable and disable the OpenAI and Anthropic mocks of a server at runtime, by name
+// and by tag. They are shared by the providers of the server and of its tenants.
+type mockSwitches struct {
+	mu        sync.RWMutex
+	selection TagSelection
+	// disabled are the names of the mocks disabled with Server.DisableMock
+	disabled map[string]bool
+}
+
+// newMockSwitches starts with the tag selection of the TagsEnvVar environment variable when set, or
+// else with that of the config
+func newMockSwitches(selection *TagSelection) *mockSwitches {
+	switches := &mockSwitches{disabled: map[string]bool{}}
+	if env, ok := os.LookupEnv(TagsEnvVar); ok {
+		switches.selection = ParseTagSelection(env)
+	} else if selection != nil {
+		switches.selection = *selection
+	}
+	return switches
+}
+
+func (m *mockSwitches) tags() TagSelection {
+	if m == nil {
+		return TagSelection{}
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.selection
+}
+
+func (m *mockSwitches) setTags(selection TagSelection) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.selection = selection
+}
+
+func (m *mockSwitches) setDisabled(name string, disabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if disabled {
+		m.disabled[name] = true
+	} else {
+		delete(m.disabled, name)
+	}
+}
+
+// disabledReason tells why a mock is not served, empty when it is
+func (m *mockSwitches) disabledReason(name string, tags []string) string {
+	if m == nil {
+		return ""
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[name] {
+		return "mock is disabled"
+	}
+	return m.selection.disabledReason(tags)
+}
+
+// DisableMock stops serving the OpenAI and Anthropic mocks of the given name, those of tenants
+// included, until EnableMock is called, e.g. to simulate a capability disappearing mid-run and force
+// an agent onto its fallback path. Requests then match the next mocks as if it wasn't configured.
+func (s *Server) DisableMock(name string) error {
+	return s.switchMock(name, true)
+}
+
+// EnableMock serves the mocks of the given name disabled by DisableMock again
+func (s *Server) EnableMock(name string) error {
+	return s.switchMock(name, false)
+}
+
+func (s *Server) switchMock(name string, disabled bool) error {
+	if err := s.checkMockName(name); err != nil {
+		return err
+	}
+	s.switches.setDisabled(name, disabled)
+	return nil
+}
+
+// checkMockName fails unless the server has OpenAI or Anthropic mocks of the given name
+func (s *Server) checkMockName(name string) error {
+	summaries := s.providerMockSummaries("", s.config.OpenAI, s.config.Anthropic)
+	summaries = append(summaries, s.organizationMockSummaries()...)
+	for _, tenant := range s.config.Tenants {
+		summaries = append(summaries, s.providerMockSummaries(tenant.Name, tenant.OpenAI, tenant.Anthropic)...)
+	}
+	for _, mock := range summaries {
+		if mock.Name == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("no OpenAI or Anthropic mock named %q", name)
+}
+
+// handleAdminMockSwitch disables or enables the named mock, depending on the last element of the path
+func (s *Server) handleAdminMockSwitch(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	disabled := strings.HasSuffix(r.URL.Path, "/disable")
+	if err := s.switchMock(name, disabled); err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"name": name, "disabled": disabled})
+}
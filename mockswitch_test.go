@@ -0,0 +1,59 @@
+package mockllm_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisableMock(t *testing.T) {
+	reply := func(content string) openai.ChatCompletion {
+		return openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: content}}}}
+	}
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{
+			{
+				Name:     "search",
+				Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+				Response: reply("searched"),
+			},
+			{
+				Name:     "fallback",
+				Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+				Response: reply("fell back"),
+			},
+		},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	hello := func() string {
+		return postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+			Model:    "gpt-4o-mini",
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+		}).Choices[0].Message.Content
+	}
+	assert.Equal(t, "searched", hello())
+
+	resp, err := http.Post(baseURL+"/admin/mocks/search/disable", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close() //nolint:errcheck
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "fell back", hello(), "disabled mocks are skipped")
+	assert.True(t, server.Mocks()[0].Disabled)
+
+	require.NoError(t, server.EnableMock("search"))
+	assert.Equal(t, "searched", hello())
+
+	assert.EqualError(t, server.DisableMock("missing"), `no OpenAI or Anthropic mock named "missing"`)
+	resp, err = http.Post(baseURL+"/admin/mocks/missing/enable", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close() //nolint:errcheck
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,27 @@
+package mockllm
+
+// canonicalModel returns the canonical name of a requested model: the alias of the model itself,
+// or else that of the first pattern, in sorted order, matching it. Models without an alias are
+// their own canonical name.
+func canonicalModel[M ~string](aliases map[string]string, model M) M {
+	if canonical, ok := aliases[string(model)]; ok {
+		return M(canonical)
+	}
+	for _, pattern := range sortedKeys(aliases) {
+		if re, err := compileFieldPattern(pattern); err == nil && re.MatchString(string(model)) {
+			return M(aliases[pattern])
+		}
+	}
+	return model
+}
+
+// modelAliasIssues reports the model alias patterns that don't compile
+func modelAliasIssues(aliases map[string]string) []configIssue {
+	var issues []configIssue
+	for _, pattern := range sortedKeys(aliases) {
+		if _, err := compileFieldPattern(pattern); err != nil {
+			issues = append(issues, configIssue{"model_aliases." + pattern, err.Error()})
+		}
+	}
+	return issues
+}
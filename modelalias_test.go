@@ -0,0 +1,78 @@
+package mockllm_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelAliases(t *testing.T) {
+	reply := func(content string) openai.ChatCompletion {
+		return openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: content}}}}
+	}
+	byModel := func(name, model string) mockllm.OpenAIMock {
+		return mockllm.OpenAIMock{
+			Name:     name,
+			Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeFields, Fields: map[string]string{"model": model}},
+			Response: reply(name),
+		}
+	}
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		ModelAliases: map[string]string{
+			"gpt-4o-2024-08-06":  "gpt-4o",
+			"gpt-4o-mini-*":      "gpt-4o-mini",
+			"/^claude-sonnet-4/": "claude-sonnet-4",
+		},
+		OpenAI: []mockllm.OpenAIMock{byModel("4o", "gpt-4o"), byModel("mini", "gpt-4o-mini")},
+		Anthropic: []mockllm.AnthropicMock{{
+			Name:     "sonnet",
+			Match:    mockllm.AnthropicRequestMatch{MatchType: mockllm.MatchTypeFields, Fields: map[string]string{"model": "claude-sonnet-4"}},
+			Response: anthropic.Message{Content: []anthropic.ContentBlockUnion{{Type: "text", Text: "sonnet"}}},
+		}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+
+	for _, test := range []struct{ model, mockName string }{
+		{"gpt-4o", "4o"},
+		{"gpt-4o-2024-08-06", "4o"},
+		{"gpt-4o-mini-2024-07-18", "mini"},
+	} {
+		model := test.model
+		completion := postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+			Model:    model,
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+		})
+		require.Len(t, completion.Choices, 1)
+		assert.Equal(t, test.mockName, completion.Choices[0].Message.Content, model)
+		assert.Equal(t, model, completion.Model, "responses echo the requested model")
+	}
+
+	request := anthropicHelloRequest
+	request.Model = "claude-sonnet-4-20250514"
+	resp := postJSON(t, baseURL+"/v1/messages", request, anthropicHeaders("2023-06-01"))
+	defer resp.Body.Close() //nolint:errcheck
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var message anthropic.Message
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&message))
+	assert.Equal(t, "sonnet", message.Content[0].Text)
+	assert.Equal(t, anthropic.Model("claude-sonnet-4-20250514"), message.Model)
+
+	assert.Equal(t, "gpt-4o-2024-08-06", server.Requests()[1].Model, "the log has the requested model")
+}
+
+func TestModelAliasValidation(t *testing.T) {
+	_, err := mockllm.LoadConfigFromFile("mocks.json", fstest.MapFS{"mocks.json": {Data: []byte(`{
+  "model_aliases": {"/gpt-(/": "gpt-4o"}
+}`)}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "model_aliases./gpt-(/: error parsing regexp")
+}
@@ -0,0 +1,192 @@
+package mockllm
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+)
+
+// ModelProfile makes every mock served for a model behave like a kind of model, e.g. a fast small
+// model or a slow frontier one, so comparative tests can reuse the same mock content
+type ModelProfile struct {
+	// Latency is how long the model takes to start responding
+	Latency *LatencyRange `json:"latency,omitempty"`
+	// MaxContextTokens is the context window: requests whose estimated prompt tokens, plus the
+	// completion tokens they ask for, exceed it get the provider's context length error.
+	// Unlimited when zero.
+	MaxContextTokens int64 `json:"max_context_tokens,omitempty"`
+	// StreamInterval is the pause between the events of streamed responses
+	StreamInterval Duration `json:"stream_interval,omitempty"`
+	// ErrorRate is the fraction of requests, between 0 and 1, failing with ErrorStatus
+	ErrorRate float64 `json:"error_rate,omitempty"`
+	// ErrorStatus is the status of failed requests. Defaults to 500 for OpenAI and 529, overloaded,
+	// for Anthropic.
+	ErrorStatus int `json:"error_status,omitempty"`
+	// Seed seeds the latencies and failures drawn for the profile, so runs are reproducible
+	Seed uint64 `json:"seed,omitempty"`
+}
+
+// LatencyRange is a latency drawn uniformly between Min and Max, Min when Max is not above it
+type LatencyRange struct {
+	Min Duration `json:"min,omitempty"`
+	Max Duration `json:"max,omitempty"`
+}
+
+// modelProfile is a profile with the random source its latencies and failures are drawn from
+type modelProfile struct {
+	ModelProfile
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// modelProfiles are the profiles of a provider, keyed by model name or pattern
+type modelProfiles map[string]*modelProfile
+
+func newModelProfiles(profiles map[string]ModelProfile) modelProfiles {
+	if len(profiles) == 0 {
+		return nil
+	}
+	m := make(modelProfiles, len(profiles))
+	for key, profile := range profiles {
+		m[key] = &modelProfile{ModelProfile: profile, rng: rand.New(rand.NewPCG(profile.Seed, 0))}
+	}
+	return m
+}
+
+// lookupModelProfile returns the profile of the requested model or of its canonical name: that of
+// either name itself, or else that of the first pattern, in sorted order, matching one. Models
+// without a profile get nil.
+func lookupModelProfile[M ~string](m modelProfiles, aliases map[string]string, model M) *modelProfile {
+	if len(m) == 0 {
+		return nil
+	}
+	names := []string{string(model), string(canonicalModel(aliases, model))}
+	for _, name := range names {
+		if profile, ok := m[name]; ok {
+			return profile
+		}
+	}
+	for _, pattern := range sortedKeys(m) {
+		re, err := compileFieldPattern(pattern)
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			if re.MatchString(name) {
+				return m[pattern]
+			}
+		}
+	}
+	return nil
+}
+
+// contextLimit returns the maximum prompt tokens of the profile, zero when unlimited
+func (p *modelProfile) contextLimit() int64 {
+	if p == nil {
+		return 0
+	}
+	return p.MaxContextTokens
+}
+
+// streamInterval returns the pause between stream events
+func (p *modelProfile) streamInterval() time.Duration {
+	if p == nil {
+		return 0
+	}
+	return time.Duration(p.StreamInterval)
+}
+
+// draw returns the latency of a request and the status it fails with, zero when it doesn't fail
+func (p *modelProfile) draw(defaultErrorStatus int) (time.Duration, int) {
+	if p == nil {
+		return 0, 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var latency time.Duration
+	if p.Latency != nil {
+		latency = time.Duration(p.Latency.Min)
+		if spread := int64(p.Latency.Max - p.Latency.Min); spread > 0 {
+			latency += time.Duration(p.rng.Int64N(spread + 1))
+		}
+	}
+	if p.ErrorRate > 0 && p.rng.Float64() < p.ErrorRate {
+		if p.ErrorStatus != 0 {
+			return latency, p.ErrorStatus
+		}
+		return latency, defaultErrorStatus
+	}
+	return latency, 0
+}
+
+// modelProfileIssues reports the model profiles whose pattern doesn't compile or whose settings
+// are out of range
+func modelProfileIssues(profiles map[string]ModelProfile) []configIssue {
+	var issues []configIssue
+	for _, key := range sortedKeys(profiles) {
+		profile, path := profiles[key], "model_profiles."+key
+		if _, err := compileFieldPattern(key); err != nil {
+			issues = append(issues, configIssue{path, err.Error()})
+		}
+		if profile.Latency != nil && (profile.Latency.Min < 0 || profile.Latency.Max < 0) {
+			issues = append(issues, configIssue{path + ".latency", "must not be negative"})
+		}
+		if profile.MaxContextTokens < 0 {
+			issues = append(issues, configIssue{path + ".max_context_tokens", "must not be negative"})
+		}
+		if profile.StreamInterval < 0 {
+			issues = append(issues, configIssue{path + ".stream_interval", "must not be negative"})
+		}
+		if profile.ErrorRate < 0 || profile.ErrorRate > 1 {
+			issues = append(issues, configIssue{path + ".error_rate", fmt.Sprintf("must be between 0 and 1, got %v", profile.ErrorRate)})
+		}
+		if profile.ErrorStatus != 0 && (profile.ErrorStatus < 400 || profile.ErrorStatus > 599) {
+			issues = append(issues, configIssue{path + ".error_status", fmt.Sprintf("must be an error status, got %d", profile.ErrorStatus)})
+		}
+	}
+	return issues
+}
+
+// profileError returns the status, log reason, message, param and code of the error an OpenAI
+// request gets for exceeding the context window of its model's profile or for failing, or a zero
+// status
+func (p *OpenAIProvider) profileError(profile *modelProfile, request openai.ChatCompletionNewParams,
+	failure int) (status int, reason, message, param, code string) {
+	if limit := profile.contextLimit(); limit > 0 {
+		if reason, message := openaiContextOverflow(p.tokenizer, limit, request); reason != "" {
+			return http.StatusBadRequest, reason, message, "messages", "context_length_exceeded"
+		}
+	}
+	if failure == 0 {
+		return 0, "", "", "", ""
+	}
+	message = http.StatusText(failure)
+	if failure >= http.StatusInternalServerError {
+		message = "The server had an error while processing your request. Sorry about that!"
+	}
+	return failure, "simulated model error", message, "", ""
+}
+
+// profileError returns the status, log reason and message of the error an Anthropic request gets
+// for exceeding the context window of its model's profile or for failing, or a zero status
+func (p *AnthropicProvider) profileError(profile *modelProfile, request anthropic.MessageNewParams,
+	failure int) (status int, reason, message string) {
+	if limit := profile.contextLimit(); limit > 0 {
+		if reason, message := anthropicContextOverflow(p.tokenizer, limit, request); reason != "" {
+			return http.StatusBadRequest, reason, message
+		}
+	}
+	if failure == 0 {
+		return 0, "", ""
+	}
+	if failure == 529 {
+		return failure, "simulated model error", "Overloaded"
+	}
+	return failure, "simulated model error", http.StatusText(failure)
+}
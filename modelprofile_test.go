@@ -0,0 +1,136 @@
+package mockllm_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	openaioption "github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelProfiles(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		ModelProfiles: map[string]mockllm.ModelProfile{
+			"slow-*": {
+				Latency:        &mockllm.LatencyRange{Min: mockllm.Duration(50 * time.Millisecond), Max: mockllm.Duration(60 * time.Millisecond)},
+				StreamInterval: mockllm.Duration(10 * time.Millisecond),
+			},
+			"small": {MaxContextTokens: 20},
+			"flaky": {ErrorRate: 1},
+			"/^cl/": {ErrorRate: 1},
+		},
+		OpenAI: []mockllm.OpenAIMock{{
+			Name:     "hello",
+			Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+			Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "Hi there, how can I help?"}}}},
+		}},
+		Anthropic: []mockllm.AnthropicMock{{
+			Name:     "hello",
+			Match:    mockllm.AnthropicRequestMatch{MatchType: mockllm.MatchTypeContains, Message: anthropic.NewUserMessage(anthropic.NewTextBlock("Hello"))},
+			Response: anthropic.Message{Content: []anthropic.ContentBlockUnion{{Type: "text", Text: "Hi"}}},
+		}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+
+	client := openai.NewClient(openaioption.WithBaseURL(baseURL+"/v1"), openaioption.WithAPIKey("test-key"), openaioption.WithMaxRetries(0))
+	request := func(model, content string) openai.ChatCompletionNewParams {
+		return openai.ChatCompletionNewParams{Model: model, Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage(content)}}
+	}
+
+	started := time.Now()
+	completion, err := client.Chat.Completions.New(t.Context(), request("slow-frontier", "Hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "Hi there, how can I help?", completion.Choices[0].Message.Content)
+	assert.GreaterOrEqual(t, time.Since(started), 50*time.Millisecond)
+
+	started = time.Now()
+	stream := client.Chat.Completions.NewStreaming(t.Context(), request("slow-frontier", "Hello"))
+	chunks := 0
+	for stream.Next() {
+		chunks++
+	}
+	require.NoError(t, stream.Err())
+	require.Greater(t, chunks, 2)
+	assert.GreaterOrEqual(t, time.Since(started), 50*time.Millisecond+time.Duration(chunks-1)*10*time.Millisecond)
+
+	_, err = client.Chat.Completions.New(t.Context(), request("small", "Hello"))
+	require.NoError(t, err, "prompts within the context window are served")
+	_, err = client.Chat.Completions.New(t.Context(), request("small", "Hello"+strings.Repeat(" again", 30)))
+	var openaiErr *openai.Error
+	require.ErrorAs(t, err, &openaiErr)
+	assert.Equal(t, 400, openaiErr.StatusCode)
+	assert.Equal(t, "context_length_exceeded", openaiErr.Code)
+	assert.Equal(t, "messages", openaiErr.Param)
+
+	_, err = client.Chat.Completions.New(t.Context(), request("flaky", "Hello"))
+	require.ErrorAs(t, err, &openaiErr)
+	assert.Equal(t, 500, openaiErr.StatusCode)
+	assert.Equal(t, "server_error", openaiErr.Type)
+
+	_, err = client.Chat.Completions.New(t.Context(), request("gpt-4o", "Hello"+strings.Repeat(" again", 30)))
+	require.NoError(t, err, "models without a profile are unaffected")
+
+	anthropicClient := anthropic.NewClient(anthropicoption.WithBaseURL(baseURL), anthropicoption.WithAPIKey("test-key"),
+		anthropicoption.WithMaxRetries(0))
+	_, err = anthropicClient.Messages.New(t.Context(), anthropicHelloRequest)
+	var anthropicErr *anthropic.Error
+	require.ErrorAs(t, err, &anthropicErr)
+	assert.Equal(t, 529, anthropicErr.StatusCode)
+	assert.Contains(t, anthropicErr.Error(), "overloaded_error")
+
+	requests := server.Requests()
+	assert.Equal(t, "context length exceeded: 35 tokens > 20", requests[3].Error)
+	assert.Equal(t, "simulated model error", requests[4].Error)
+}
+
+func TestModelProfileLatencyDisconnect(t *testing.T) {
+	clock := mockllm.NewFakeClock(time.Now())
+	server := mockllm.NewServer(mockllm.WithClock(clock), mockllm.WithConfig(mockllm.Config{
+		ModelProfiles: map[string]mockllm.ModelProfile{
+			"slow": {Latency: &mockllm.LatencyRange{Min: mockllm.Duration(time.Hour)}},
+		},
+		OpenAI: []mockllm.OpenAIMock{replyMock("hello", "Hi")},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+
+	client := openai.NewClient(openaioption.WithBaseURL(baseURL+"/v1"), openaioption.WithAPIKey("test-key"), openaioption.WithMaxRetries(0))
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+	go func() {
+		_, err := client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+			Model:    "slow",
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+		})
+		done <- err
+	}()
+	require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, 5*time.Millisecond)
+	cancel()
+	require.Error(t, <-done)
+
+	require.Eventually(t, func() bool { return len(server.Requests()) == 1 }, time.Second, 5*time.Millisecond)
+	record := server.Requests()[0]
+	assert.Zero(t, record.Status)
+	assert.Equal(t, "client disconnected", record.Error)
+}
+
+func TestModelProfileValidation(t *testing.T) {
+	_, err := mockllm.LoadConfigFromFile("mocks.json", fstest.MapFS{"mocks.json": {Data: []byte(`{
+  "model_profiles": {"gpt-4o": {"error_rate": 1.5}, "/gpt-(/": {"error_status": 200}}
+}`)}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "model_profiles.gpt-4o.error_rate: must be between 0 and 1, got 1.5")
+	assert.Contains(t, err.Error(), "model_profiles./gpt-(/: error parsing regexp")
+	assert.Contains(t, err.Error(), "model_profiles./gpt-(/.error_status: must be an error status, got 200")
+}
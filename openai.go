@@ -2,104 +2,561 @@ package mockllm
 
 import (
 	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/kagent-dev/mockllm/tokenizer"
 	"github.com/openai/openai-go"
 )
 
 // Provider handles OpenAI request/response mocking
 type OpenAIProvider struct {
-	mocks []OpenAIMock
+	mocks      []OpenAIMock
+	cache      *promptCache
+	log        *RequestLog
+	files      *fileStore
+	fineTuning *fineTuningStore
+	// vectorStores are searched by plain term matching over the content of their files
+	vectorStores *vectorStoreStore
+	// responsesMocks serve the Responses API
+	responsesMocks []OpenAIResponsesMock
+	// tenant is the name of the virtual tenant the provider serves, empty for the default mocks
+	tenant string
+	// tokenizer paces streams and generates logprobs, and with estimateUsage and enforceMaxTokens
+	// also fills in missing usage and truncates responses to the requested max tokens
+	tokenizer        tokenizer.Tokenizer
+	estimateUsage    bool
+	enforceMaxTokens bool
+	// grammar, or else echo, answers requests that match no mock when set, the grammar only
+	// those one of its rules matches
+	grammar *Grammar
+	echo    *EchoConfig
+	// plugins runs the WebAssembly plugins of mocks
+	plugins *pluginHost
+	// clock times simulated delays and timestamps responses
+	clock Clock
+	// toolValidation checks the tools declared in requests when set
+	toolValidation *ToolValidationConfig
+	// fuzz counts the requests of fuzzed mocks
+	fuzz *fuzzIterations
+	// draws count the draws of mocks matching with a probability
+	draws *matchDraws
+	// memories are the facts persisted by mocks with a memory
+	memories *memories
+	// modelAliases map requested models to the canonical names mocks are matched with
+	modelAliases map[string]string
+	// knownModels are the models served, any model when empty
+	knownModels []string
+	// profiles give models a latency, context window, streaming speed and error rate
+	profiles modelProfiles
+	// heartbeat keeps streams alive while they wait
+	heartbeat *HeartbeatConfig
+	// serviceTiers emulate the processing tiers requests select, when set
+	serviceTiers *ServiceTierConfig
+	// streamAttempts counts the cut attempts of requests for stream retry mocks
+	streamAttempts *streamAttempts
+	// switches enable and disable mocks by name and by tag, all of them being served when nil
+	switches *mockSwitches
+	// patches change the responses of mocks at runtime
+	patches *responsePatches
+	// ids generates the IDs of the objects created through the stateful emulations
+	ids *idSource
 }
 
 // NewOpenAIProvider creates a new OpenAI OpenAIProvider with the given mocks
 func NewOpenAIProvider(mocks []OpenAIMock) *OpenAIProvider {
-	return &OpenAIProvider{mocks: mocks}
+	return &OpenAIProvider{
+		mocks:          mocks,
+		cache:          newPromptCache(),
+		files:          newFileStore(),
+		fineTuning:     newFineTuningStore(),
+		vectorStores:   newVectorStoreStore(),
+		tokenizer:      tokenizer.Default,
+		plugins:        newPluginHost(),
+		clock:          systemClock{},
+		fuzz:           newFuzzIterations(),
+		draws:          newMatchDraws(),
+		memories:       newMemories(),
+		streamAttempts: newStreamAttempts(),
+		ids:            newIDSource(nil),
+	}
+}
+
+// Routes returns the OpenAI endpoints served by the provider
+func (p *OpenAIProvider) Routes() []Route {
+	routes := []Route{{Method: http.MethodPost, Path: "/v1/chat/completions"}, {Method: http.MethodPost, Path: openaiResponsesPath}}
+	routes = append(routes, openaiFileRoutes...)
+	routes = append(routes, openaiFineTuningRoutes...)
+	return append(routes, openaiVectorStoreRoutes...)
 }
 
-// Handle processes an OpenAI chat completion request
+// Handle processes an OpenAI chat completion, Responses API, files, fine-tuning or vector store request
 func (p *OpenAIProvider) Handle(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasPrefix(r.URL.Path, openaiFilesPath):
+		p.handleFiles(w, r)
+	case strings.HasPrefix(r.URL.Path, openaiFineTuningJobsPath):
+		p.handleFineTuning(w, r)
+	case strings.HasPrefix(r.URL.Path, openaiVectorStoresPath):
+		p.handleVectorStores(w, r)
+	case r.URL.Path == openaiResponsesPath:
+		p.handleResponses(w, r)
+	default:
+		p.handleChatCompletion(w, r)
+	}
+}
+
+// handleChatCompletion processes an OpenAI chat completion request
+func (p *OpenAIProvider) handleChatCompletion(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, fmt.Sprintf("Failed to read request body: %v", err), "", "")
+		return
+	}
+
+	record := p.log.newRecord(r, providerOpenAI, body)
+	record.Tenant = p.tenant
+	defer p.log.Add(&record)
+
+	if reason, message, code := checkOpenAIAuth(r); reason != "" {
+		record.Status, record.Error = http.StatusUnauthorized, reason
+		writeOpenAIError(w, http.StatusUnauthorized, message, "", code)
+		return
+	}
+
 	// Parse the incoming request into SDK type
 	var requestBody openai.ChatCompletionNewParams
-	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+	if err := json.Unmarshal(body, &requestBody); err != nil {
+		record.Status, record.Error = http.StatusBadRequest, err.Error()
+		writeOpenAIError(w, http.StatusBadRequest,
+			fmt.Sprintf("We could not parse the JSON body of your request: %v", err), "", "")
+		return
+	}
+
+	if !knownModel(p.knownModels, p.modelAliases, requestBody.Model) {
+		record.Status, record.Error = http.StatusNotFound, "unknown model "+requestBody.Model
+		writeOpenAIError(w, http.StatusNotFound, fmt.Sprintf(
+			"The model `%s` does not exist or you do not have access to it.", requestBody.Model), "", "model_not_found")
+		return
+	}
+
+	forcedStatus, err := forcedError(r.Header)
+	if err != nil {
+		record.Status, record.Error = http.StatusBadRequest, err.Error()
+		writeOpenAIError(w, http.StatusBadRequest, err.Error(), "", "")
+		return
+	}
+	if forcedStatus != 0 {
+		_, _, message, param, code := p.profileError(nil, requestBody, forcedStatus)
+		record.Status, record.Error = forcedStatus, "forced error"
+		writeOpenAIError(w, forcedStatus, message, param, code)
+		return
+	}
+
+	_, tier, tierErr := p.serviceTiers.resolve(string(requestBody.ServiceTier), requestBody.Model)
+	if tierErr != nil {
+		record.Status, record.Error = tierErr.status, tierErr.reason
+		tierErr.write(w)
+		return
+	}
+
+	profile := lookupModelProfile(p.profiles, p.modelAliases, requestBody.Model)
+	latency, failure := profile.draw(http.StatusInternalServerError)
+	latency += time.Duration(tier.Latency)
+	if latency > 0 && !wait(r.Context(), p.clock, latency) {
+		// No status was sent before the client went away
+		record.Status, record.Error = 0, "client disconnected"
+		return
+	}
+	if status, reason, message, param, code := p.profileError(profile, requestBody, failure); status != 0 {
+		record.Status, record.Error = status, reason
+		writeOpenAIError(w, status, message, param, code)
+		return
+	}
+
+	if violation := checkTools(p.toolValidation, &record, openaiToolViolations, body); violation != nil {
+		writeOpenAIError(w, http.StatusBadRequest, violation.Message, violation.Param, "invalid_value")
 		return
 	}
 
 	// Find a matching mock
-	mock := p.findMatchingMock(requestBody)
+	mock := p.findMatchingMock(r.Context(), requestBody, r.Header)
+	if name := r.Header.Get(ForceMockHeader); mock == nil && name != "" {
+		record.Status, record.Error = http.StatusNotFound, "no mock named "+name
+		writeOpenAIError(w, http.StatusNotFound, fmt.Sprintf("No mock named %q to force.", name), "", "mock_not_found")
+		return
+	}
 	if mock == nil {
+		record.Status, record.Diffs = http.StatusNotFound, p.diffs(requestBody)
 		requestBodyBytes, err := json.MarshalIndent(requestBody, "", "  ")
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to encode request body: %v", err),
-				http.StatusInternalServerError)
+			record.Status, record.Error = http.StatusInternalServerError, err.Error()
+			writeOpenAIError(w, http.StatusInternalServerError,
+				fmt.Sprintf("Failed to encode request body: %v", err), "", "")
+			return
+		}
+
+		writeOpenAIError(w, http.StatusNotFound, fmt.Sprintf("No matching mock found. Request: %s",
+			string(requestBodyBytes)), "messages", "mock_not_found")
+		return
+	}
+
+	if missing := missingBetas(r.Header, openaiBetaHeader, mock.Betas); len(missing) > 0 {
+		record.Status, record.MockName = http.StatusBadRequest, mock.Name
+		record.Error = "missing OpenAI-Beta " + strings.Join(missing, ",")
+		message := fmt.Sprintf("You must provide the '%s' header with %s to access this feature.",
+			openaiBetaHeader, strings.Join(missing, ", "))
+		writeOpenAIError(w, http.StatusBadRequest, message, "", "invalid_beta")
+		return
+	}
+
+	record.Status, record.Matched, record.MockName = http.StatusOK, true, mock.Name
+	defer p.log.serving(providerOpenAI, p.tenant, mock.Name)()
+	record.ExpectationFailures = openaiExpectationFailures(mock.Expect, requestBody)
+	if status, ok := applyFault(w, r, p.clock, mock.Fault, "application/json"); !ok {
+		// The mock matched, but the connection was dropped instead of serving it
+		record.Status, record.Error = status, "simulated fault: "+string(mock.Fault.Type)
+		return
+	}
+	w = dripResponse(w, r, p.clock, mock.Fault)
+	if mock.Raw != nil {
+		record.Status = writeHTTPResponse(w, *mock.Raw)
+		return
+	}
+	if mock.Script != "" {
+		if mock, err = scriptedOpenAIMock(mock, body); err != nil {
+			record.Status, record.Error = http.StatusInternalServerError, err.Error()
+			writeOpenAIError(w, http.StatusInternalServerError, err.Error(), "", "")
+			return
+		}
+	}
+	if mock.Plugin != "" {
+		if mock, err = p.plugins.pluginOpenAIMock(r.Context(), mock, body); err != nil {
+			record.Status, record.Error = http.StatusInternalServerError, err.Error()
+			writeOpenAIError(w, http.StatusInternalServerError, err.Error(), "", "")
 			return
 		}
+	}
 
-		http.Error(w, fmt.Sprintf("No matching mock found. Request: %s",
-			string(requestBodyBytes)), http.StatusNotFound)
+	response := p.buildResponse(mock, requestBody)
+	record.Fuzz = p.fuzzResponse(mock, requestBody, &response)
+	record.Model, record.Usage = requestBody.Model, openaiTokenUsage(response)
+	record.Response, _ = json.Marshal(response)
+	if isStreamingRequest(body) {
+		events := openaiStreamEvents(response, requestBody.StreamOptions.IncludeUsage.Value, p.tokenizer)
+		events = mock.StreamError.openaiEvents(malformEvents(mock.Malformed, events))
+		events, cut, attempt := p.streamAttempts.attempt(mock.StreamRetry, mock.Name, body, events)
+		record.Stream = writeSSE(w, r, p.clock, events, ssePacing{
+			fault:             mock.Fault,
+			interval:          profile.streamInterval(),
+			heartbeat:         p.heartbeat.heartbeatEvent(nil),
+			heartbeatInterval: p.heartbeat.interval(),
+		}, openaiInterruptedEvent)
+		record.Stream.Attempt = attempt
+		switch {
+		case cut:
+			closeConnection(w)
+			record.Error = fmt.Sprintf("simulated disconnect of attempt %d", attempt)
+		case mock.StreamError != nil:
+			closeConnection(w)
+			record.Error = mock.StreamError.reason()
+		}
+		return
+	}
+	if mock.Fault.stallsBefore(0) && !wait(r.Context(), p.clock, time.Duration(mock.Fault.Duration)) {
+		return
+	}
+	if mock.Malformed != "" {
+		writeMalformedResponse(w, mock.Malformed, response)
 		return
 	}
 
 	// Return the response
-	p.handleNonStreamingResponse(w, mock.Response)
+	p.handleNonStreamingResponse(w, response)
+}
+
+// checkOpenAIAuth checks for the API key of a request, only its presence is validated. It returns
+// the log reason, client message and error code of a failed check.
+func checkOpenAIAuth(r *http.Request) (reason, message, code string) {
+	authorization := r.Header.Get("Authorization")
+	if authorization == "" {
+		return "missing Authorization header",
+			"You didn't provide an API key. You need to provide your API key in an Authorization header " +
+				"using Bearer auth (i.e. Authorization: Bearer YOUR_KEY).", ""
+	}
+	if !strings.HasPrefix(authorization, "Bearer ") || strings.TrimPrefix(authorization, "Bearer ") == "" {
+		return "malformed Authorization header", "Incorrect API key provided.", "invalid_api_key"
+	}
+	return "", "", ""
 }
 
-// findMatchingMock finds the first mock that matches the request
-func (p *OpenAIProvider) findMatchingMock(request openai.ChatCompletionNewParams) *OpenAIMock {
+// buildResponse derives the response to a request from the matched mock
+func (p *OpenAIProvider) buildResponse(mock *OpenAIMock, requestBody openai.ChatCompletionNewParams) openai.ChatCompletion {
+	response := mock.Response
+	if requestBody.Seed.Valid() {
+		if seedResponse, ok := mock.SeedResponses[requestBody.Seed.Value]; ok {
+			response = seedResponse
+		}
+		if response.SystemFingerprint == "" {
+			response.SystemFingerprint = systemFingerprint(mock.Name)
+		}
+	}
+	matched := requestBody
+	matched.Model = canonicalModel(p.modelAliases, matched.Model)
+	substituteCaptures(&response, fieldCaptures(matched, mock.Match.Fields))
+	if mock.Echo != nil {
+		setOpenAIContent(&response, mock.Echo.echo(openaiLastMessageText(requestBody)))
+	}
+	if mock.Generate != nil && mock.Generate.ToolCall != "" {
+		mock.Generate.generateOpenAIToolCall(mock.Name, requestBody, &response)
+	} else if mock.Generate != nil {
+		setOpenAIContent(&response, mock.Generate.generate(p.tokenizer))
+	}
+	if mock.Grammar != nil {
+		reply, _ := mock.Grammar.reply(openaiLastMessageText(requestBody))
+		setOpenAIContent(&response, reply)
+	}
+	if mock.Memory != nil {
+		if reply, ok := mock.Memory.reply(p.memories, mock.Name, openaiMessageTexts(requestBody)); ok {
+			setOpenAIContent(&response, reply)
+		}
+	}
+	forceOpenAIToolCall(mock.Name, requestBody, &response)
+	fillOpenAIDefaults(mock.Name, requestBody, &response, p.clock.Now())
+	p.serviceTiers.fillServiceTier(mock.Name, requestBody, &response)
+	if mock.SimulatePromptCache {
+		p.cache.simulateOpenAIPromptCache(requestBody, &response)
+	}
+	if n := int(requestBody.N.Value); n > 1 {
+		response.Choices = expandChoices(response.Choices, n)
+	}
+	maxTokens := cmp.Or(requestBody.MaxCompletionTokens.Value, requestBody.MaxTokens.Value)
+	if p.enforceMaxTokens && maxTokens > 0 {
+		response.Choices = truncateOpenAIChoices(p.tokenizer, response.Choices, int(maxTokens))
+	}
+	if requestBody.Logprobs.Value {
+		response.Choices = slices.Clone(response.Choices)
+		for i, choice := range response.Choices {
+			if len(choice.Logprobs.Content) == 0 {
+				response.Choices[i].Logprobs = generateLogprobs(choice.Message.Content, int(requestBody.TopLogprobs.Value), p.tokenizer)
+			}
+		}
+	}
+	if p.estimateUsage {
+		estimateOpenAIUsage(p.tokenizer, requestBody, &response)
+	}
+	return response
+}
+
+// findMatchingMock finds the first mock that matches the request, falling back to the grammar
+// and echo mode. A request forcing a tool call prefers the first matching mock calling the tool,
+// and one naming a mock in the ForceMockHeader gets that mock, if any.
+func (p *OpenAIProvider) findMatchingMock(ctx context.Context, request openai.ChatCompletionNewParams, header http.Header) *OpenAIMock {
+	if name := header.Get(ForceMockHeader); name != "" {
+		for _, mock := range p.mocks {
+			if mock.Name == name {
+				applyResponsePatches(p.patches, mock.Name, &mock.Response)
+				return &mock
+			}
+		}
+		return nil
+	}
+	request.Model = canonicalModel(p.modelAliases, request.Model)
+	forced := openaiForcedTool(request)
+	var first *OpenAIMock
 	for _, mock := range p.mocks {
-		if p.requestsMatch(mock.Match, request) {
+		if !p.mockMatches(ctx, mock, request, header) || !p.draws.draw(mock.Name, mock.Probability) {
+			continue
+		}
+		applyResponsePatches(p.patches, mock.Name, &mock.Response)
+		if forced == nil || openaiCallsTool(mock.Response, forced) {
 			return &mock
 		}
+		if first == nil {
+			first = &mock
+		}
+	}
+	if first != nil {
+		return first
+	}
+	if p.grammar.answers(openaiLastMessageText(request)) {
+		return &OpenAIMock{Name: grammarMockName, Grammar: p.grammar}
+	}
+	if p.echo != nil {
+		return &OpenAIMock{Name: echoMockName, Echo: p.echo}
 	}
 	return nil
 }
 
+// mockMatches asks the match hook of the mock's plugin, or else checks the mock's match criteria
+func (p *OpenAIProvider) mockMatches(ctx context.Context, mock OpenAIMock, request openai.ChatCompletionNewParams, header http.Header) bool {
+	matched, _ := p.explainMockMatch(ctx, mock, request, header)
+	return matched
+}
+
+// explainMockMatch is mockMatches, also returning the reason the mock matches or not
+func (p *OpenAIProvider) explainMockMatch(ctx context.Context, mock OpenAIMock, request openai.ChatCompletionNewParams, header http.Header) (bool, string) {
+	if reason := p.switches.disabledReason(mock.Name, mock.Tags); reason != "" {
+		return false, reason
+	}
+	if reason := mismatchedHeader(header, mock.Match.Headers); reason != "" {
+		return false, reason
+	}
+	if mock.Plugin != "" {
+		body, err := json.Marshal(request)
+		if err != nil {
+			return false, fmt.Sprintf("failed to encode request: %v", err)
+		}
+		// A failing plugin never matches, the request then shows up as unmatched
+		matched, ok, err := p.plugins.matches(ctx, mock.Plugin, body)
+		if err != nil {
+			return false, fmt.Sprintf("plugin failed: %v", err)
+		}
+		if ok {
+			return matched, pluginMatchReason(matched)
+		}
+	}
+	return p.explainRequestMatch(mock.Match, request)
+}
+
+// diffs compares the last message of an unmatched request against every configured mock
+func (p *OpenAIProvider) diffs(request openai.ChatCompletionNewParams) []MockDiff {
+	if len(request.Messages) == 0 {
+		return nil
+	}
+	lastMessage := request.Messages[len(request.Messages)-1]
+
+	diffs := make([]MockDiff, 0, len(p.mocks))
+	for _, mock := range p.mocks {
+		diffs = append(diffs, MockDiff{
+			MockName:  mock.Name,
+			MatchType: mock.Match.MatchType,
+			Diff:      jsonDiff(mock.Match.Message, lastMessage),
+		})
+	}
+	return diffs
+}
+
 // requestsMatch checks if two requests are equivalent
 func (p *OpenAIProvider) requestsMatch(expected OpenAIRequestMatch, actual openai.ChatCompletionNewParams) bool {
-	// Simple deep equal comparison for now
-	// In the future, we could add more sophisticated matching
+	matched, _ := p.explainRequestMatch(expected, actual)
+	return matched
+}
+
+// explainRequestMatch is requestsMatch, also returning the reason the request matches or not
+func (p *OpenAIProvider) explainRequestMatch(expected OpenAIRequestMatch, actual openai.ChatCompletionNewParams) (bool, string) {
+	if tool := openaiMissingTool(expected.ToolsCalled, actual); tool != "" {
+		return false, fmt.Sprintf("tool %q has not been called", tool)
+	}
+	if reason := mismatchedField(actual, expected.Fields); reason != "" {
+		return false, reason
+	}
+	if reason := expected.Document.mismatch(openaiDocuments(actual, p.files)); reason != "" {
+		return false, reason
+	}
+	if reason := expected.Audio.mismatch(openaiInputAudio(actual)); reason != "" {
+		return false, reason
+	}
 	switch expected.MatchType {
 	case MatchTypeExact:
 		// get Last message from actual
 		if len(actual.Messages) == 0 {
-			return false
+			return false, "request has no messages"
 		}
 		lastMessage := actual.Messages[len(actual.Messages)-1]
 		// Check json is equal
 		jsonExpected, err := json.Marshal(expected.Message)
 		if err != nil {
-			return false
+			return false, fmt.Sprintf("failed to encode expected message: %v", err)
 		}
 		jsonActual, err := json.Marshal(lastMessage)
 		if err != nil {
-			return false
+			return false, fmt.Sprintf("failed to encode last message: %v", err)
+		}
+		if !bytes.Equal(jsonExpected, jsonActual) {
+			return false, "last message differs from the expected message"
 		}
-		return bytes.Equal(jsonExpected, jsonActual)
+		return true, "last message equals the expected message"
 	case MatchTypeContains:
 		// Check if the last message contains the expected message
 		if len(actual.Messages) == 0 {
-			return false
+			return false, "request has no messages"
 		}
 		lastMessage := actual.Messages[len(actual.Messages)-1]
 		if *lastMessage.GetRole() != *expected.Message.GetRole() {
-			return false
+			return false, fmt.Sprintf("last message has role %q, expected %q", *lastMessage.GetRole(), *expected.Message.GetRole())
 		}
 		strExpected, ok := expected.Message.GetContent().AsAny().(*string)
 		if !ok {
-			return false
+			return false, "expected message content is not a string"
 		}
 		strActual, ok := lastMessage.GetContent().AsAny().(*string)
 		if !ok {
-			return false
+			return false, "last message content is not a string"
 		}
-		return strings.Contains(*strActual, *strExpected)
+		return containsReason("last message", *strActual, *strExpected)
+	case MatchTypeUserContains:
+		for i := len(actual.Messages) - 1; i >= 0; i-- {
+			if actual.Messages[i].OfUser != nil {
+				return containsReason("latest user message", openaiMessageText(actual.Messages[i]),
+					openaiMessageText(expected.Message))
+			}
+		}
+		return false, "request has no user message"
+	case MatchTypeFields:
+		return true, "fields match"
 	default:
-		return false
+		return false, fmt.Sprintf("unknown match type %q", expected.MatchType)
+	}
+}
+
+// openaiMissingTool returns the first named tool the assistant did not call in the request's
+// conversation, or "" when it called them all
+func openaiMissingTool(tools []string, request openai.ChatCompletionNewParams) string {
+	var called []string
+	for _, message := range request.Messages {
+		if message.OfAssistant != nil {
+			for _, toolCall := range message.OfAssistant.ToolCalls {
+				called = append(called, toolCall.Function.Name)
+			}
+		}
+	}
+	for _, tool := range tools {
+		if !slices.Contains(called, tool) {
+			return tool
+		}
+	}
+	return ""
+}
+
+// systemFingerprint derives a stable fingerprint for a mock, standing in for the backend
+// configuration identifier OpenAI returns alongside seeded completions
+func systemFingerprint(mockName string) string {
+	sum := sha256.Sum256([]byte(mockName))
+	return "fp_" + hex.EncodeToString(sum[:5])
+}
+
+// expandChoices returns exactly n choices with sequential indices. Configured choices are used in
+// order and cycled through when fewer than n are configured, so a single choice is duplicated.
+func expandChoices(choices []openai.ChatCompletionChoice, n int) []openai.ChatCompletionChoice {
+	if len(choices) == 0 {
+		return choices
+	}
+	expanded := make([]openai.ChatCompletionChoice, n)
+	for i := range expanded {
+		expanded[i] = choices[i%len(choices)]
+		expanded[i].Index = int64(i)
 	}
+	return expanded
 }
 
 // handleNonStreamingResponse sends a JSON response
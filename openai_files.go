@@ -0,0 +1,180 @@
+package mockllm
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// openaiFilesPath is the root of the OpenAI Files API
+const openaiFilesPath = "/v1/files"
+
+// openaiFileRoutes are the Files API endpoints served by the OpenAI provider
+var openaiFileRoutes = []Route{
+	{Method: http.MethodPost, Path: openaiFilesPath},
+	{Method: http.MethodGet, Path: openaiFilesPath},
+	{Method: http.MethodGet, Path: openaiFilesPath + "/{id}"},
+	{Method: http.MethodDelete, Path: openaiFilesPath + "/{id}"},
+	{Method: http.MethodGet, Path: openaiFilesPath + "/{id}/content"},
+}
+
+// maxUploadSize is the largest file accepted by the Files API emulation
+const maxUploadSize = 512 << 20
+
+// openaiFile is an uploaded file, encoded as an OpenAI FileObject
+type openaiFile struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"`
+	Bytes     int    `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+	Status    string `json:"status"`
+	content   []byte
+}
+
+// fileStore keeps uploaded files in memory, in upload order
+type fileStore struct {
+	mu    sync.Mutex
+	files []*openaiFile
+}
+
+func newFileStore() *fileStore {
+	return &fileStore{}
+}
+
+// add stores the content under the given file ID, created at the given time
+func (s *fileStore) add(id, filename, purpose string, content []byte, createdAt time.Time) *openaiFile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file := &openaiFile{
+		ID:        id,
+		Object:    "file",
+		Bytes:     len(content),
+		CreatedAt: createdAt.Unix(),
+		Filename:  filename,
+		Purpose:   purpose,
+		Status:    "processed",
+		content:   content,
+	}
+	s.files = append(s.files, file)
+	return file
+}
+
+// get returns the file with the given ID, or nil
+func (s *fileStore) get(id string) *openaiFile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, file := range s.files {
+		if file.ID == id {
+			return file
+		}
+	}
+	return nil
+}
+
+// list returns the files with the given purpose, or all files when purpose is empty
+func (s *fileStore) list(purpose string) []*openaiFile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files := []*openaiFile{}
+	for _, file := range s.files {
+		if purpose == "" || file.Purpose == purpose {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// remove deletes the file with the given ID and reports whether it existed
+func (s *fileStore) remove(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, file := range s.files {
+		if file.ID == id {
+			s.files = slices.Delete(s.files, i, i+1)
+			return true
+		}
+	}
+	return false
+}
+
+// handleFiles serves the upload, list, retrieve, delete and content endpoints of the Files API
+func (p *OpenAIProvider) handleFiles(w http.ResponseWriter, r *http.Request) {
+	if _, message, code := checkOpenAIAuth(r); message != "" {
+		writeOpenAIError(w, http.StatusUnauthorized, message, "", code)
+		return
+	}
+
+	id, content := strings.CutSuffix(strings.Trim(strings.TrimPrefix(r.URL.Path, openaiFilesPath), "/"), "/content")
+	if id == "" {
+		switch r.Method {
+		case http.MethodPost:
+			p.uploadFile(w, r)
+		default:
+			p.listFiles(w, r)
+		}
+		return
+	}
+
+	file := p.files.get(id)
+	if file == nil {
+		writeOpenAIError(w, http.StatusNotFound, fmt.Sprintf("No such File object: %s", id), "id", "")
+		return
+	}
+	switch {
+	case content:
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.WriteHeader(http.StatusOK)
+		w.Write(file.content) //nolint:errcheck
+	case r.Method == http.MethodDelete:
+		p.files.remove(id)
+		writeJSON(w, http.StatusOK, map[string]any{"id": id, "object": "file", "deleted": true})
+	default:
+		writeJSON(w, http.StatusOK, file)
+	}
+}
+
+// uploadFile stores the file of a multipart upload
+func (p *OpenAIProvider) uploadFile(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, fmt.Sprintf("Failed to parse multipart form: %v", err), "", "")
+		return
+	}
+	purpose := r.FormValue("purpose")
+	if purpose == "" {
+		writeOpenAIError(w, http.StatusBadRequest, "Missing required parameter: 'purpose'.", "purpose", "")
+		return
+	}
+	upload, header, err := r.FormFile("file")
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "Missing required parameter: 'file'.", "file", "")
+		return
+	}
+	defer upload.Close() //nolint:errcheck
+
+	content, err := io.ReadAll(upload)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, fmt.Sprintf("Failed to read file: %v", err), "file", "")
+		return
+	}
+	file := p.files.add(p.ids.next("file", "file-", p.tenant), header.Filename, purpose, content, p.clock.Now())
+	writeJSON(w, http.StatusOK, file)
+}
+
+// listFiles lists the uploaded files, newest first unless order=asc
+func (p *OpenAIProvider) listFiles(w http.ResponseWriter, r *http.Request) {
+	files := p.files.list(r.URL.Query().Get("purpose"))
+	if r.URL.Query().Get("order") != "asc" {
+		slices.Reverse(files)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"object": "list", "data": files, "has_more": false})
+}
@@ -0,0 +1,66 @@
+package mockllm_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+	openaioption "github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIFiles(t *testing.T) {
+	baseURL := newOpenAIServer(t)
+	client := openai.NewClient(
+		openaioption.WithBaseURL(baseURL+"/v1"),
+		openaioption.WithAPIKey("test-key"),
+		openaioption.WithMaxRetries(0),
+	)
+
+	batchFile, err := client.Files.New(t.Context(), openai.FileNewParams{
+		File:    openai.File(strings.NewReader(`{"custom_id":"1"}`+"\n"), "batch.jsonl", "application/jsonl"),
+		Purpose: openai.FilePurposeBatch,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "batch.jsonl", batchFile.Filename)
+	assert.Equal(t, int64(18), batchFile.Bytes)
+	assert.Equal(t, openai.FileObjectPurposeBatch, batchFile.Purpose)
+
+	_, err = client.Files.New(t.Context(), openai.FileNewParams{
+		File:    openai.File(strings.NewReader("notes"), "notes.txt", "text/plain"),
+		Purpose: openai.FilePurposeAssistants,
+	})
+	require.NoError(t, err)
+
+	page, err := client.Files.List(t.Context(), openai.FileListParams{})
+	require.NoError(t, err)
+	require.Len(t, page.Data, 2)
+	assert.Equal(t, "notes.txt", page.Data[0].Filename)
+
+	page, err = client.Files.List(t.Context(), openai.FileListParams{Purpose: openai.String("batch")})
+	require.NoError(t, err)
+	require.Len(t, page.Data, 1)
+	assert.Equal(t, batchFile.ID, page.Data[0].ID)
+
+	retrieved, err := client.Files.Get(t.Context(), batchFile.ID)
+	require.NoError(t, err)
+	assert.Equal(t, batchFile.ID, retrieved.ID)
+
+	resp, err := client.Files.Content(t.Context(), batchFile.ID)
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	content, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `{"custom_id":"1"}`+"\n", string(content))
+
+	deleted, err := client.Files.Delete(t.Context(), batchFile.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted.Deleted)
+
+	_, err = client.Files.Get(t.Context(), batchFile.ID)
+	var apiErr *openai.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, 404, apiErr.StatusCode)
+}
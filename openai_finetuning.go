@@ -0,0 +1,360 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// openaiFineTuningJobsPath is the root of the OpenAI fine-tuning jobs API
+const openaiFineTuningJobsPath = "/v1/fine_tuning/jobs"
+
+// openaiFineTuningRoutes are the fine-tuning endpoints served by the OpenAI provider
+var openaiFineTuningRoutes = []Route{
+	{Method: http.MethodPost, Path: openaiFineTuningJobsPath},
+	{Method: http.MethodGet, Path: openaiFineTuningJobsPath},
+	{Method: http.MethodGet, Path: openaiFineTuningJobsPath + "/{id}"},
+	{Method: http.MethodPost, Path: openaiFineTuningJobsPath + "/{id}/cancel"},
+	{Method: http.MethodGet, Path: openaiFineTuningJobsPath + "/{id}/events"},
+}
+
+// Fine-tuning job statuses
+const (
+	fineTuningValidating = "validating_files"
+	fineTuningRunning    = "running"
+	fineTuningSucceeded  = "succeeded"
+	fineTuningFailed     = "failed"
+	fineTuningCancelled  = "cancelled"
+)
+
+// FineTuningConfig scripts the lifecycle of emulated fine-tuning jobs. Jobs are validated, run and
+// then end with Outcome, spending StepDuration in each of the first two states.
+type FineTuningConfig struct {
+	// StepDuration is how long a job is validating_files and then running, jobs end immediately when unset
+	StepDuration Duration `json:"step_duration,omitempty"`
+	// Outcome is the final status of a job, succeeded (the default) or failed
+	Outcome string `json:"outcome,omitempty"`
+	// Manual disables the timer, jobs then only advance through POST /admin/fine_tuning/jobs/{id}
+	Manual bool `json:"manual,omitempty"`
+}
+
+// fineTuningJob is an emulated fine-tuning job and its events
+type fineTuningJob struct {
+	id             string
+	model          string
+	trainingFile   string
+	validationFile string
+	suffix         string
+	createdAt      time.Time
+	finishedAt     time.Time
+	status         string
+	events         []map[string]any
+}
+
+// fineTuningStore holds the fine-tuning jobs created through a provider
+type fineTuningStore struct {
+	mu     sync.Mutex
+	config FineTuningConfig
+	clock  Clock
+	jobs   []*fineTuningJob
+}
+
+func newFineTuningStore() *fineTuningStore {
+	return &fineTuningStore{clock: systemClock{}}
+}
+
+// create adds a new job in the validating_files state
+func (s *fineTuningStore) create(job *fineTuningJob) map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job.createdAt = s.clock.Now()
+	s.jobs = append(s.jobs, job)
+	job.transition(fineTuningValidating, job.createdAt)
+	s.advance(job, job.createdAt)
+	return job.object()
+}
+
+// get returns the job with the given ID, advanced to the current time, or nil
+func (s *fineTuningStore) get(id string) map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := s.find(id)
+	if job == nil {
+		return nil
+	}
+	s.advance(job, s.clock.Now())
+	return job.object()
+}
+
+// list returns every job, newest first
+func (s *fineTuningStore) list() []map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	objects := make([]map[string]any, 0, len(s.jobs))
+	for _, job := range slices.Backward(s.jobs) {
+		s.advance(job, s.clock.Now())
+		objects = append(objects, job.object())
+	}
+	return objects
+}
+
+// events returns the events of a job, newest first, and whether the job exists
+func (s *fineTuningStore) events(id string) ([]map[string]any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := s.find(id)
+	if job == nil {
+		return nil, false
+	}
+	s.advance(job, s.clock.Now())
+	events := slices.Clone(job.events)
+	slices.Reverse(events)
+	return events, true
+}
+
+// setStatus moves a job to the given status. Terminal jobs cannot change status.
+func (s *fineTuningStore) setStatus(id, status string) (map[string]any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := s.find(id)
+	if job == nil {
+		return nil, fmt.Errorf("no such fine-tuning job: %s", id)
+	}
+	now := s.clock.Now()
+	s.advance(job, now)
+	if job.terminal() {
+		return nil, fmt.Errorf("fine-tuning job %s has already %s", id, job.status)
+	}
+	switch status {
+	case fineTuningValidating, fineTuningRunning, fineTuningSucceeded, fineTuningFailed, fineTuningCancelled:
+		job.transition(status, now)
+		return job.object(), nil
+	default:
+		return nil, fmt.Errorf("invalid fine-tuning job status: %s", status)
+	}
+}
+
+func (s *fineTuningStore) find(id string) *fineTuningJob {
+	for _, job := range s.jobs {
+		if job.id == id {
+			return job
+		}
+	}
+	return nil
+}
+
+// advance moves a job through the scripted lifecycle according to the time elapsed since it was
+// created, unless the lifecycle is controlled manually
+func (s *fineTuningStore) advance(job *fineTuningJob, now time.Time) {
+	if s.config.Manual {
+		return
+	}
+	outcome := fineTuningSucceeded
+	if s.config.Outcome == fineTuningFailed {
+		outcome = fineTuningFailed
+	}
+	lifecycle := []string{fineTuningValidating, fineTuningRunning, outcome}
+
+	step := time.Duration(s.config.StepDuration)
+	for i := slices.Index(lifecycle, job.status) + 1; i > 0 && i < len(lifecycle); i++ {
+		at := job.createdAt.Add(time.Duration(i) * step)
+		if at.After(now) {
+			return
+		}
+		job.transition(lifecycle[i], at)
+	}
+}
+
+// transition moves the job to status at the given time and emits the matching event
+func (job *fineTuningJob) transition(status string, at time.Time) {
+	job.status = status
+	level, message := "info", ""
+	switch status {
+	case fineTuningValidating:
+		message = "Validating training file: " + job.trainingFile
+	case fineTuningRunning:
+		message = "Fine-tuning job started"
+	case fineTuningSucceeded:
+		message = "The job has successfully completed"
+	case fineTuningFailed:
+		level, message = "error", "The job failed"
+	case fineTuningCancelled:
+		message = "Fine-tuning job cancelled"
+	}
+	if job.terminal() {
+		job.finishedAt = at
+	}
+	job.events = append(job.events, map[string]any{
+		"object":     "fine_tuning.job.event",
+		"id":         fmt.Sprintf("ftevent-%s-%d", strings.TrimPrefix(job.id, "ftjob-"), len(job.events)+1),
+		"created_at": at.Unix(),
+		"level":      level,
+		"message":    message,
+		"type":       "message",
+	})
+}
+
+func (job *fineTuningJob) terminal() bool {
+	return job.status == fineTuningSucceeded || job.status == fineTuningFailed || job.status == fineTuningCancelled
+}
+
+// object returns the FineTuningJob representation of the job
+func (job *fineTuningJob) object() map[string]any {
+	object := map[string]any{
+		"object":           "fine_tuning.job",
+		"id":               job.id,
+		"model":            job.model,
+		"created_at":       job.createdAt.Unix(),
+		"finished_at":      nil,
+		"fine_tuned_model": nil,
+		"organization_id":  "org-mockllm",
+		"result_files":     []string{},
+		"status":           job.status,
+		"training_file":    job.trainingFile,
+		"validation_file":  nil,
+		"hyperparameters":  map[string]any{"n_epochs": "auto", "batch_size": "auto", "learning_rate_multiplier": "auto"},
+		"trained_tokens":   nil,
+		"error":            nil,
+		"seed":             0,
+	}
+	if job.validationFile != "" {
+		object["validation_file"] = job.validationFile
+	}
+	if job.terminal() {
+		object["finished_at"] = job.finishedAt.Unix()
+	}
+	switch job.status {
+	case fineTuningSucceeded:
+		suffix := job.suffix
+		if suffix == "" {
+			suffix = "mockllm"
+		}
+		object["fine_tuned_model"] = fmt.Sprintf("ft:%s:%s:%s", job.model, suffix, job.id)
+		object["trained_tokens"] = 0
+	case fineTuningFailed:
+		object["error"] = map[string]any{"code": "job_failed", "message": "The job failed", "param": nil}
+	}
+	return object
+}
+
+// handleFineTuning serves the create, list, retrieve, cancel and events endpoints of the
+// fine-tuning jobs API
+func (p *OpenAIProvider) handleFineTuning(w http.ResponseWriter, r *http.Request) {
+	if _, message, code := checkOpenAIAuth(r); message != "" {
+		writeOpenAIError(w, http.StatusUnauthorized, message, "", code)
+		return
+	}
+
+	id, action, _ := strings.Cut(strings.Trim(strings.TrimPrefix(r.URL.Path, openaiFineTuningJobsPath), "/"), "/")
+	switch {
+	case id == "" && r.Method == http.MethodPost:
+		p.createFineTuningJob(w, r)
+	case id == "":
+		writeJSON(w, http.StatusOK, map[string]any{"object": "list", "data": p.fineTuning.list(), "has_more": false})
+	case action == "events":
+		events, ok := p.fineTuning.events(id)
+		if !ok {
+			writeOpenAIError(w, http.StatusNotFound, fmt.Sprintf("No such fine-tuning job: %s", id), "fine_tuning_job_id", "")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"object": "list", "data": events, "has_more": false})
+	case action == "cancel":
+		if p.fineTuning.get(id) == nil {
+			writeOpenAIError(w, http.StatusNotFound, fmt.Sprintf("No such fine-tuning job: %s", id), "fine_tuning_job_id", "")
+			return
+		}
+		// Cancelling a finished job is rejected like the real API does
+		job, err := p.fineTuning.setStatus(id, fineTuningCancelled)
+		if err != nil {
+			writeOpenAIError(w, http.StatusBadRequest, err.Error(), "", "")
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+	default:
+		job := p.fineTuning.get(id)
+		if job == nil {
+			writeOpenAIError(w, http.StatusNotFound, fmt.Sprintf("No such fine-tuning job: %s", id), "fine_tuning_job_id", "")
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+	}
+}
+
+// createFineTuningJob starts a job for a previously uploaded training file
+func (p *OpenAIProvider) createFineTuningJob(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, fmt.Sprintf("Failed to read request body: %v", err), "", "")
+		return
+	}
+	var request struct {
+		Model          string `json:"model"`
+		TrainingFile   string `json:"training_file"`
+		ValidationFile string `json:"validation_file"`
+		Suffix         string `json:"suffix"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest,
+			fmt.Sprintf("We could not parse the JSON body of your request: %v", err), "", "")
+		return
+	}
+	if request.Model == "" {
+		writeOpenAIError(w, http.StatusBadRequest, "Missing required parameter: 'model'.", "model", "")
+		return
+	}
+	if p.files.get(request.TrainingFile) == nil {
+		writeOpenAIError(w, http.StatusBadRequest,
+			fmt.Sprintf("invalid training_file: %s", request.TrainingFile), "training_file", "")
+		return
+	}
+	if request.ValidationFile != "" && p.files.get(request.ValidationFile) == nil {
+		writeOpenAIError(w, http.StatusBadRequest,
+			fmt.Sprintf("invalid validation_file: %s", request.ValidationFile), "validation_file", "")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, p.fineTuning.create(&fineTuningJob{
+		id:             p.ids.next("fine_tuning.job", "ftjob-", p.tenant),
+		model:          request.Model,
+		trainingFile:   request.TrainingFile,
+		validationFile: request.ValidationFile,
+		suffix:         request.Suffix,
+	}))
+}
+
+// handleAdminFineTuningJob moves a fine-tuning job to the status in the request body, e.g.
+// {"status":"failed"}, for tests that control the lifecycle manually
+func (s *Server) handleAdminFineTuningJob(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": fmt.Sprintf("Invalid JSON: %v", err)})
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/admin/fine_tuning/jobs/")
+	for _, provider := range s.openaiProviders() {
+		if provider.fineTuning.get(id) == nil {
+			continue
+		}
+		job, err := provider.fineTuning.setStatus(id, request.Status)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+		return
+	}
+	writeJSON(w, http.StatusNotFound, map[string]any{"error": "no such fine-tuning job: " + id})
+}
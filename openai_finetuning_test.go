@@ -0,0 +1,104 @@
+package mockllm_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	openaioption "github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFineTuningClient starts a server with the given fine-tuning config and uploads a training file
+func newFineTuningClient(t *testing.T, config mockllm.FineTuningConfig) (openai.Client, string, string) {
+	t.Helper()
+
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{FineTuning: &config}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(context.Background()) }) //nolint:errcheck
+
+	client := openai.NewClient(
+		openaioption.WithBaseURL(baseURL+"/v1"),
+		openaioption.WithAPIKey("test-key"),
+		openaioption.WithMaxRetries(0),
+	)
+	file, err := client.Files.New(t.Context(), openai.FileNewParams{
+		File:    openai.File(strings.NewReader(`{"messages":[]}`+"\n"), "train.jsonl", "application/jsonl"),
+		Purpose: openai.FilePurposeFineTune,
+	})
+	require.NoError(t, err)
+	return client, baseURL, file.ID
+}
+
+func TestOpenAIFineTuningTimer(t *testing.T) {
+	client, _, fileID := newFineTuningClient(t, mockllm.FineTuningConfig{
+		StepDuration: mockllm.Duration(100 * time.Millisecond),
+	})
+
+	job, err := client.FineTuning.Jobs.New(t.Context(), openai.FineTuningJobNewParams{
+		Model:        "gpt-4o-mini-2024-07-18",
+		TrainingFile: fileID,
+		Suffix:       openai.String("support"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, openai.FineTuningJobStatusValidatingFiles, job.Status)
+
+	require.Eventually(t, func() bool {
+		job, err = client.FineTuning.Jobs.Get(t.Context(), job.ID)
+		return err == nil && job.Status == openai.FineTuningJobStatusSucceeded
+	}, 2*time.Second, 20*time.Millisecond)
+	assert.Equal(t, "ft:gpt-4o-mini-2024-07-18:support:"+job.ID, job.FineTunedModel)
+	assert.NotZero(t, job.FinishedAt)
+
+	events, err := client.FineTuning.Jobs.ListEvents(t.Context(), job.ID, openai.FineTuningJobListEventsParams{})
+	require.NoError(t, err)
+	require.Len(t, events.Data, 3)
+	assert.Equal(t, "The job has successfully completed", events.Data[0].Message)
+
+	_, err = client.FineTuning.Jobs.New(t.Context(), openai.FineTuningJobNewParams{
+		Model:        "gpt-4o-mini-2024-07-18",
+		TrainingFile: "file-missing",
+	})
+	var apiErr *openai.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+}
+
+func TestOpenAIFineTuningManual(t *testing.T) {
+	client, baseURL, fileID := newFineTuningClient(t, mockllm.FineTuningConfig{Manual: true})
+
+	job, err := client.FineTuning.Jobs.New(t.Context(), openai.FineTuningJobNewParams{
+		Model:        "gpt-4o-mini-2024-07-18",
+		TrainingFile: fileID,
+	})
+	require.NoError(t, err)
+
+	resp := postJSON(t, baseURL+"/admin/fine_tuning/jobs/"+job.ID, map[string]string{"status": "running"}, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	job, err = client.FineTuning.Jobs.Get(t.Context(), job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, openai.FineTuningJobStatusRunning, job.Status)
+
+	resp = postJSON(t, baseURL+"/admin/fine_tuning/jobs/"+job.ID, map[string]string{"status": "failed"}, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	job, err = client.FineTuning.Jobs.Get(t.Context(), job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, openai.FineTuningJobStatusFailed, job.Status)
+	assert.Equal(t, "job_failed", job.Error.Code)
+
+	_, err = client.FineTuning.Jobs.Cancel(t.Context(), job.ID)
+	var apiErr *openai.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+
+	page, err := client.FineTuning.Jobs.List(t.Context(), openai.FineTuningJobListParams{})
+	require.NoError(t, err)
+	require.Len(t, page.Data, 1)
+	assert.Equal(t, job.ID, page.Data[0].ID)
+}
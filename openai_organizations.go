@@ -0,0 +1,162 @@
+package mockllm
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+)
+
+const (
+	openaiOrganizationHeader = "OpenAI-Organization"
+	openaiProjectHeader      = "OpenAI-Project"
+)
+
+// OpenAIOrganization is an OpenAI organization that requests select with the OpenAI-Organization
+// header, with mocks answering only the requests of the organization
+type OpenAIOrganization struct {
+	ID string `json:"id"`
+	// Projects are the projects of the organization, selected with the OpenAI-Project header.
+	// Requests may name any project when none are configured.
+	Projects []OpenAIProject `json:"projects,omitempty"`
+	// OpenAI mocks are tried before the default mocks for requests of the organization
+	OpenAI []OpenAIMock `json:"openai,omitempty"`
+}
+
+// OpenAIProject is a project of an OpenAI organization
+type OpenAIProject struct {
+	ID string `json:"id"`
+	// OpenAI mocks are tried before those of the organization for requests of the project
+	OpenAI []OpenAIMock `json:"openai,omitempty"`
+}
+
+// organizationMocks returns the mocks of the organizations and their projects, most specific
+// first, each requiring the headers that select its organization and project
+func organizationMocks(organizations []OpenAIOrganization) []OpenAIMock {
+	restrict := func(mocks []OpenAIMock, headers map[string]string) []OpenAIMock {
+		restricted := make([]OpenAIMock, 0, len(mocks))
+		for _, mock := range mocks {
+			required := maps.Clone(mock.Match.Headers)
+			if required == nil {
+				required = map[string]string{}
+			}
+			maps.Copy(required, headers)
+			mock.Match.Headers = required
+			restricted = append(restricted, mock)
+		}
+		return restricted
+	}
+
+	var mocks []OpenAIMock
+	for _, organization := range organizations {
+		for _, project := range organization.Projects {
+			mocks = append(mocks, restrict(project.OpenAI, map[string]string{
+				openaiOrganizationHeader: organization.ID,
+				openaiProjectHeader:      project.ID,
+			})...)
+		}
+	}
+	for _, organization := range organizations {
+		mocks = append(mocks, restrict(organization.OpenAI, map[string]string{openaiOrganizationHeader: organization.ID})...)
+	}
+	return mocks
+}
+
+// organizationRouted echoes the OpenAI-Organization and OpenAI-Project headers of requests in the
+// response, as OpenAI does, and rejects organizations and projects that are not configured
+func (s *Server) organizationRouted(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		organization, project := r.Header.Get(openaiOrganizationHeader), r.Header.Get(openaiProjectHeader)
+		if organization != "" {
+			w.Header().Set("openai-organization", organization)
+		}
+		if project != "" {
+			w.Header().Set("openai-project", project)
+		}
+
+		if message, code := s.checkOrganization(organization, project); message != "" {
+			record := s.requestLog.newRecord(r, providerOpenAI, nil)
+			record.Status, record.Error = http.StatusUnauthorized, message
+			s.requestLog.Add(&record)
+			writeOpenAIError(w, http.StatusUnauthorized, message, "", code)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// checkOrganization validates the organization and project of a request against the configured
+// organizations, returning an error message and code when they do not exist
+func (s *Server) checkOrganization(organization, project string) (message, code string) {
+	organizations := s.config.OpenAIOrganizations
+	if len(organizations) == 0 {
+		return "", ""
+	}
+
+	index := slices.IndexFunc(organizations, func(o OpenAIOrganization) bool { return o.ID == organization })
+	if organization != "" && index < 0 {
+		return fmt.Sprintf("OpenAI-Organization header should match organization for API key. No such organization: %s.", organization),
+			"mismatched_organization"
+	}
+	if project == "" {
+		return "", ""
+	}
+
+	candidates := organizations
+	if index >= 0 {
+		candidates = organizations[index : index+1]
+	}
+	for _, candidate := range candidates {
+		if len(candidate.Projects) == 0 ||
+			slices.ContainsFunc(candidate.Projects, func(p OpenAIProject) bool { return p.ID == project }) {
+			return "", ""
+		}
+	}
+	return fmt.Sprintf("OpenAI-Project header should match project for API key. No such project: %s.", project),
+		"mismatched_project"
+}
+
+// organizationMockSummaries summarizes the mocks of every organization, followed by those of its projects
+func (s *Server) organizationMockSummaries() []MockSummary {
+	concurrency := s.requestLog.TenantMaxConcurrency("", providerOpenAI)
+	summarize := func(mocks []OpenAIMock, organization, project string) []MockSummary {
+		hits := s.requestLog.organizationHits(organization, project)
+		summaries := make([]MockSummary, 0, len(mocks))
+		for _, mock := range mocks {
+			summaries = append(summaries, MockSummary{
+				Provider:       providerOpenAI,
+				Organization:   organization,
+				Project:        project,
+				Name:           mock.Name,
+				MatchType:      mock.Match.MatchType,
+				Tags:           mock.Tags,
+				Disabled:       s.switches.disabledReason(mock.Name, mock.Tags) != "",
+				Hits:           hits[mock.Name],
+				MaxConcurrency: concurrency[mock.Name],
+			})
+		}
+		return summaries
+	}
+
+	var summaries []MockSummary
+	for _, organization := range s.config.OpenAIOrganizations {
+		summaries = append(summaries, summarize(organization.OpenAI, organization.ID, "")...)
+		for _, project := range organization.Projects {
+			summaries = append(summaries, summarize(project.OpenAI, organization.ID, project.ID)...)
+		}
+	}
+	return summaries
+}
+
+// organizationHits returns the number of requests of the organization, and of the project unless
+// it is empty, each OpenAI mock has served, keyed by mock name
+func (l *RequestLog) organizationHits(organization, project string) map[string]int {
+	hits := map[string]int{}
+	for _, record := range l.Records() {
+		if record.Tenant == "" && record.Provider == providerOpenAI && record.Matched &&
+			record.Organization == organization && (project == "" || record.Project == project) {
+			hits[record.MockName]++
+		}
+	}
+	return hits
+}
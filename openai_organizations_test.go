@@ -0,0 +1,115 @@
+package mockllm_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replyMock returns an OpenAI mock answering "Hello" with the given content
+func replyMock(name, content string) mockllm.OpenAIMock {
+	return mockllm.OpenAIMock{
+		Name:     name,
+		Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+		Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: content}}}},
+	}
+}
+
+func TestOpenAIOrganizations(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{replyMock("default", "default")},
+		OpenAIOrganizations: []mockllm.OpenAIOrganization{
+			{
+				ID:       "org-a",
+				Projects: []mockllm.OpenAIProject{{ID: "proj-1", OpenAI: []mockllm.OpenAIMock{replyMock("proj-1", "project one")}}, {ID: "proj-2"}},
+				OpenAI:   []mockllm.OpenAIMock{replyMock("org-a", "organization a")},
+			},
+			{ID: "org-b"},
+		},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	post := func(organization, project string) *http.Response {
+		headers := map[string]string{"Authorization": "Bearer test-key"}
+		if organization != "" {
+			headers["OpenAI-Organization"] = organization
+		}
+		if project != "" {
+			headers["OpenAI-Project"] = project
+		}
+		return postJSON(t, baseURL+"/v1/chat/completions", openai.ChatCompletionNewParams{
+			Model:    "gpt-4o-mini",
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+		}, headers)
+	}
+
+	tests := []struct {
+		name         string
+		organization string
+		project      string
+		expected     string
+	}{
+		{name: "no organization", expected: "default"},
+		{name: "organization", organization: "org-a", expected: "organization a"},
+		{name: "project", organization: "org-a", project: "proj-1", expected: "project one"},
+		{name: "project without mocks", organization: "org-a", project: "proj-2", expected: "organization a"},
+		{name: "organization without mocks", organization: "org-b", project: "proj-anything", expected: "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := post(tt.organization, tt.project)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, tt.organization, resp.Header.Get("openai-organization"))
+			assert.Equal(t, tt.project, resp.Header.Get("openai-project"))
+
+			var completion openai.ChatCompletion
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&completion))
+			assert.Equal(t, tt.expected, completion.Choices[0].Message.Content)
+		})
+	}
+
+	rejected := []struct {
+		organization string
+		project      string
+		code         string
+	}{
+		{organization: "org-unknown", code: "mismatched_organization"},
+		{organization: "org-a", project: "proj-3", code: "mismatched_project"},
+	}
+	for _, tt := range rejected {
+		resp := post(tt.organization, tt.project)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		var body struct {
+			Error struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, tt.code, body.Error.Code)
+	}
+
+	records := server.Requests()
+	require.Len(t, records, len(tests)+len(rejected))
+	assert.Equal(t, "org-a", records[2].Organization)
+	assert.Equal(t, "proj-1", records[2].Project)
+	assert.Equal(t, "proj-1", records[2].MockName)
+
+	// Organization and project mocks are listed with their hits
+	mocks := server.Mocks()
+	require.Len(t, mocks, 3)
+	assert.Equal(t, mockllm.MockSummary{Provider: "openai", Organization: "org-a", Name: "org-a",
+		MatchType: mockllm.MatchTypeContains, Hits: 2, MaxConcurrency: 1}, mocks[1])
+	assert.Equal(t, mockllm.MockSummary{Provider: "openai", Organization: "org-a", Project: "proj-1", Name: "proj-1",
+		MatchType: mockllm.MatchTypeContains, Hits: 1, MaxConcurrency: 1}, mocks[2])
+	assert.Empty(t, server.UnusedMocks())
+	require.NoError(t, server.DisableMock("proj-1"))
+	assert.True(t, server.Mocks()[2].Disabled)
+}
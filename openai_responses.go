@@ -0,0 +1,312 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/kagent-dev/mockllm/tokenizer"
+)
+
+// openaiResponsesPath is the endpoint of the OpenAI Responses API
+const openaiResponsesPath = "/v1/responses"
+
+// Output item types of the Responses API served by the mocks
+const (
+	responsesItemMessage        = "message"
+	responsesItemFunctionCall   = "function_call"
+	responsesItemWebSearchCall  = "web_search_call"
+	responsesItemFileSearchCall = "file_search_call"
+)
+
+// Includes of a Responses API request asking for the results of built-in tools
+const (
+	responsesIncludeWebSearchSources = "web_search_call.action.sources"
+	responsesIncludeFileSearchResult = "file_search_call.results"
+)
+
+// OpenAIResponsesMock maps an OpenAI Responses API request to the output items of its response
+type OpenAIResponsesMock struct {
+	Name     string                  `json:"name"`
+	Match    OpenAIResponsesMatch    `json:"match"`
+	Response OpenAIResponsesResponse `json:"response"`
+}
+
+// OpenAIResponsesMatch matches the text of the last user input of a Responses API request
+type OpenAIResponsesMatch struct {
+	// MatchType is exact (the text equals Text) or contains (it contains Text)
+	MatchType MatchType `json:"match_type"`
+	Text      string    `json:"text"`
+}
+
+// OpenAIResponsesResponse is the response of a Responses API mock. The ID, model, status and
+// usage of the response are filled in when serving.
+type OpenAIResponsesResponse struct {
+	Output []OpenAIResponsesOutputItem `json:"output"`
+}
+
+// OpenAIResponsesOutputItem is an output item of a response: a message, a function call, or the
+// call of a built-in tool the model ran itself, such as web_search_call or file_search_call
+type OpenAIResponsesOutputItem struct {
+	// Type is message, function_call, web_search_call or file_search_call
+	Type string `json:"type"`
+	// Status defaults to completed, set it to failed to simulate a failed built-in tool call
+	Status string `json:"status,omitempty"`
+	// Text is the text of message items
+	Text string `json:"text,omitempty"`
+	// Name and Arguments are the function and its JSON arguments of function_call items
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+	// Query is the search of web_search_call items, and Sources the URLs of the pages it found,
+	// sent when the request includes web_search_call.action.sources
+	Query   string   `json:"query,omitempty"`
+	Sources []string `json:"sources,omitempty"`
+	// Queries are the searches of file_search_call items, and Results the chunks they found,
+	// sent when the request includes file_search_call.results
+	Queries []string                 `json:"queries,omitempty"`
+	Results []OpenAIFileSearchResult `json:"results,omitempty"`
+}
+
+// OpenAIFileSearchResult is a chunk of a file found by a file_search_call
+type OpenAIFileSearchResult struct {
+	FileID     string         `json:"file_id"`
+	Filename   string         `json:"filename"`
+	Score      float64        `json:"score"`
+	Text       string         `json:"text"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// openaiResponsesRequest holds the parts of a Responses API request the mocks use
+type openaiResponsesRequest struct {
+	Model string `json:"model"`
+	// Input is either a string or a list of input items
+	Input   json.RawMessage `json:"input"`
+	Include []string        `json:"include"`
+	Stream  bool            `json:"stream"`
+}
+
+// lastUserText returns the text of the last user input of the request
+func (r openaiResponsesRequest) lastUserText() string {
+	var text string
+	if json.Unmarshal(r.Input, &text) == nil {
+		return text
+	}
+	var items []struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	}
+	if json.Unmarshal(r.Input, &items) != nil {
+		return ""
+	}
+	for i := len(items) - 1; i >= 0; i-- {
+		if items[i].Role != "user" {
+			continue
+		}
+		if json.Unmarshal(items[i].Content, &text) == nil {
+			return text
+		}
+		var parts []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}
+		json.Unmarshal(items[i].Content, &parts) //nolint:errcheck
+		var joined strings.Builder
+		for _, part := range parts {
+			if part.Type == "input_text" {
+				joined.WriteString(part.Text)
+			}
+		}
+		return joined.String()
+	}
+	return ""
+}
+
+// responsesIssues reports Responses API mocks with invalid match types or output items
+func responsesIssues(mocks []OpenAIResponsesMock) []configIssue {
+	var issues []configIssue
+	for i, mock := range mocks {
+		path := fmt.Sprintf("openai_responses[%d]", i)
+		if mock.Match.MatchType != MatchTypeExact && mock.Match.MatchType != MatchTypeContains {
+			issues = append(issues, configIssue{path + ".match.match_type",
+				fmt.Sprintf("must be exact or contains, got %q", mock.Match.MatchType)})
+		}
+		for j, item := range mock.Response.Output {
+			switch item.Type {
+			case responsesItemMessage, responsesItemFunctionCall, responsesItemWebSearchCall, responsesItemFileSearchCall:
+			default:
+				issues = append(issues, configIssue{fmt.Sprintf("%s.response.output[%d].type", path, j), fmt.Sprintf(
+					"must be message, function_call, web_search_call or file_search_call, got %q", item.Type)})
+			}
+		}
+	}
+	return issues
+}
+
+// handleResponses serves a Responses API request from the first matching mock
+func (p *OpenAIProvider) handleResponses(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, fmt.Sprintf("Failed to read request body: %v", err), "", "")
+		return
+	}
+
+	record := p.log.newRecord(r, providerOpenAI, body)
+	record.Tenant = p.tenant
+	defer p.log.Add(&record)
+
+	if reason, message, code := checkOpenAIAuth(r); reason != "" {
+		record.Status, record.Error = http.StatusUnauthorized, reason
+		writeOpenAIError(w, http.StatusUnauthorized, message, "", code)
+		return
+	}
+
+	var request openaiResponsesRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		record.Status, record.Error = http.StatusBadRequest, err.Error()
+		writeOpenAIError(w, http.StatusBadRequest,
+			fmt.Sprintf("We could not parse the JSON body of your request: %v", err), "", "")
+		return
+	}
+	record.Model = request.Model
+
+	mock := p.findMatchingResponsesMock(request)
+	if mock == nil {
+		record.Status = http.StatusNotFound
+		writeOpenAIError(w, http.StatusNotFound, fmt.Sprintf("No matching mock found. Request: %s", body), "input", "mock_not_found")
+		return
+	}
+	record.Matched, record.MockName = true, mock.Name
+	defer p.log.serving(providerOpenAI, p.tenant, mock.Name)()
+
+	response, usage := p.buildResponsesResponse(*mock, request)
+	record.Status, record.Usage = http.StatusOK, usage
+	record.Response, _ = json.Marshal(response)
+	if request.Stream {
+		record.Stream = writeSSE(w, r, p.clock, responsesStreamEvents(response), ssePacing{
+			heartbeat:         p.heartbeat.heartbeatEvent(nil),
+			heartbeatInterval: p.heartbeat.interval(),
+		}, openaiInterruptedEvent)
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// findMatchingResponsesMock finds the first mock matching the last user input of the request
+func (p *OpenAIProvider) findMatchingResponsesMock(request openaiResponsesRequest) *OpenAIResponsesMock {
+	text := request.lastUserText()
+	for _, mock := range p.responsesMocks {
+		switch mock.Match.MatchType {
+		case MatchTypeExact:
+			if text == mock.Match.Text {
+				return &mock
+			}
+		case MatchTypeContains:
+			if strings.Contains(text, mock.Match.Text) {
+				return &mock
+			}
+		}
+	}
+	return nil
+}
+
+// buildResponsesResponse builds the response object of a mock, in the shape of the Responses API,
+// along with its estimated usage
+func (p *OpenAIProvider) buildResponsesResponse(mock OpenAIResponsesMock, request openaiResponsesRequest) (map[string]any, *TokenUsage) {
+	id := p.ids.next("response", "resp_", p.tenant)
+	suffix := strings.TrimPrefix(id, "resp_")
+	output := make([]map[string]any, 0, len(mock.Response.Output))
+	var outputTokens int
+	for i, item := range mock.Response.Output {
+		itemID := func(prefix string) string { return fmt.Sprintf("%s_%s_%d", prefix, suffix, i) }
+		status := item.Status
+		if status == "" {
+			status = "completed"
+		}
+		switch item.Type {
+		case responsesItemMessage:
+			outputTokens += tokenizer.Count(p.tokenizer, item.Text)
+			output = append(output, map[string]any{
+				"type": item.Type, "id": itemID("msg"), "status": status, "role": "assistant",
+				"content": []map[string]any{{"type": "output_text", "text": item.Text, "annotations": []any{}}},
+			})
+		case responsesItemFunctionCall:
+			outputTokens += tokenizer.Count(p.tokenizer, item.Arguments)
+			output = append(output, map[string]any{
+				"type": item.Type, "id": itemID("fc"), "call_id": itemID("call"), "status": status,
+				"name": item.Name, "arguments": item.Arguments,
+			})
+		case responsesItemWebSearchCall:
+			action := map[string]any{"type": "search", "query": item.Query}
+			if slices.Contains(request.Include, responsesIncludeWebSearchSources) {
+				sources := make([]map[string]any, len(item.Sources))
+				for j, url := range item.Sources {
+					sources[j] = map[string]any{"type": "url", "url": url}
+				}
+				action["sources"] = sources
+			}
+			output = append(output, map[string]any{"type": item.Type, "id": itemID("ws"), "status": status, "action": action})
+		case responsesItemFileSearchCall:
+			var results []OpenAIFileSearchResult
+			if slices.Contains(request.Include, responsesIncludeFileSearchResult) {
+				results = item.Results
+				if results == nil {
+					results = []OpenAIFileSearchResult{}
+				}
+			}
+			queries := item.Queries
+			if queries == nil {
+				queries = []string{}
+			}
+			output = append(output, map[string]any{
+				"type": item.Type, "id": itemID("fs"), "status": status, "queries": queries, "results": results,
+			})
+		}
+	}
+	usage := &TokenUsage{InputTokens: promptTokens(p.tokenizer, request.Input, 0), OutputTokens: int64(outputTokens)}
+	return map[string]any{
+		"id":                  id,
+		"object":              "response",
+		"created_at":          p.clock.Now().Unix(),
+		"status":              "completed",
+		"model":               request.Model,
+		"output":              output,
+		"parallel_tool_calls": true,
+		"tool_choice":         "auto",
+		"tools":               []any{},
+		"error":               nil,
+		"incomplete_details":  nil,
+		"usage": map[string]any{
+			"input_tokens":          usage.InputTokens,
+			"input_tokens_details":  map[string]any{"cached_tokens": 0},
+			"output_tokens":         usage.OutputTokens,
+			"output_tokens_details": map[string]any{"reasoning_tokens": 0},
+			"total_tokens":          usage.InputTokens + usage.OutputTokens,
+		},
+	}, usage
+}
+
+// responsesStreamEvents streams a response as the Responses API does: the response being
+// created, each output item being added and done, then the completed response
+func responsesStreamEvents(response map[string]any) []sseEvent {
+	var events []sseEvent
+	add := func(name string, data map[string]any) {
+		data["type"], data["sequence_number"] = name, len(events)
+		events = append(events, newSSEEvent(name, data))
+	}
+	output := response["output"].([]map[string]any)
+	created := maps.Clone(response)
+	created["status"], created["output"] = "in_progress", []any{}
+	add("response.created", map[string]any{"response": created})
+	for i, item := range output {
+		added := maps.Clone(item)
+		added["status"] = "in_progress"
+		add("response.output_item.added", map[string]any{"output_index": i, "item": added})
+		add("response.output_item.done", map[string]any{"output_index": i, "item": item})
+	}
+	add("response.completed", map[string]any{"response": response})
+	return events
+}
@@ -0,0 +1,155 @@
+package mockllm_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	openaioption "github.com/openai/openai-go/option"
+	"github.com/openai/openai-go/responses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newResponsesClient starts a server with the Responses API mocks and returns a client of it
+func newResponsesClient(t *testing.T, mocks ...mockllm.OpenAIResponsesMock) openai.Client {
+	t.Helper()
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{OpenAIResponses: mocks}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+	return openai.NewClient(
+		openaioption.WithBaseURL(baseURL+"/v1"),
+		openaioption.WithAPIKey("test-key"),
+		openaioption.WithMaxRetries(0),
+	)
+}
+
+var researchMock = mockllm.OpenAIResponsesMock{
+	Name:  "research",
+	Match: mockllm.OpenAIResponsesMatch{MatchType: mockllm.MatchTypeContains, Text: "Research"},
+	Response: mockllm.OpenAIResponsesResponse{Output: []mockllm.OpenAIResponsesOutputItem{
+		{Type: "web_search_call", Query: "mock llm servers", Sources: []string{"https://example.com/mocks"}},
+		{Type: "file_search_call", Queries: []string{"mock servers"}, Results: []mockllm.OpenAIFileSearchResult{
+			{FileID: "file-1", Filename: "notes.md", Score: 0.9, Text: "Mock servers make tests deterministic"},
+		}},
+		{Type: "message", Text: "Mock servers make tests deterministic."},
+	}},
+}
+
+func TestResponsesBuiltinTools(t *testing.T) {
+	client := newResponsesClient(t, researchMock)
+
+	params := responses.ResponseNewParams{
+		Model: "gpt-4o",
+		Input: responses.ResponseNewParamsInputUnion{OfString: openai.String("Research mock servers")},
+	}
+	response, err := client.Responses.New(t.Context(), params)
+	require.NoError(t, err)
+	assert.Equal(t, "completed", string(response.Status))
+	assert.Equal(t, "Mock servers make tests deterministic.", response.OutputText())
+	require.Len(t, response.Output, 3)
+
+	webSearch := response.Output[0].AsWebSearchCall()
+	assert.Equal(t, "completed", string(webSearch.Status))
+	assert.Equal(t, "mock llm servers", webSearch.Action.Query)
+	assert.NotContains(t, webSearch.Action.RawJSON(), "sources")
+
+	// Results are only sent when the request includes them
+	fileSearch := response.Output[1].AsFileSearchCall()
+	assert.Equal(t, []string{"mock servers"}, fileSearch.Queries)
+	assert.Empty(t, fileSearch.Results)
+
+	params.Include = []responses.ResponseIncludable{"web_search_call.action.sources", responses.ResponseIncludableFileSearchCallResults}
+	response, err = client.Responses.New(t.Context(), params)
+	require.NoError(t, err)
+	assert.Contains(t, response.Output[0].AsWebSearchCall().Action.RawJSON(), "https://example.com/mocks")
+	results := response.Output[1].AsFileSearchCall().Results
+	require.Len(t, results, 1)
+	assert.Equal(t, "notes.md", results[0].Filename)
+	assert.Equal(t, 0.9, results[0].Score)
+	assert.Equal(t, "Mock servers make tests deterministic", results[0].Text)
+
+	// Input item lists are matched on their last user message
+	_, err = client.Responses.New(t.Context(), responses.ResponseNewParams{
+		Model: "gpt-4o",
+		Input: responses.ResponseNewParamsInputUnion{OfInputItemList: responses.ResponseInputParam{
+			responses.ResponseInputItemParamOfMessage("Research mock servers", responses.EasyInputMessageRoleUser),
+			responses.ResponseInputItemParamOfMessage("Hello", responses.EasyInputMessageRoleAssistant),
+		}},
+	})
+	require.NoError(t, err)
+
+	_, err = client.Responses.New(t.Context(), responses.ResponseNewParams{
+		Model: "gpt-4o",
+		Input: responses.ResponseNewParamsInputUnion{OfString: openai.String("Hello")},
+	})
+	var apiErr *openai.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, 404, apiErr.StatusCode)
+}
+
+func TestResponsesStreaming(t *testing.T) {
+	client := newResponsesClient(t, researchMock)
+
+	stream := client.Responses.NewStreaming(t.Context(), responses.ResponseNewParams{
+		Model: "gpt-4o",
+		Input: responses.ResponseNewParamsInputUnion{OfString: openai.String("Research mock servers")},
+	})
+	var types []string
+	var completed responses.Response
+	for stream.Next() {
+		event := stream.Current()
+		types = append(types, event.Type)
+		if event.Type == "response.completed" {
+			completed = event.AsResponseCompleted().Response
+		}
+	}
+	require.NoError(t, stream.Err())
+	assert.Equal(t, []string{
+		"response.created",
+		"response.output_item.added", "response.output_item.done",
+		"response.output_item.added", "response.output_item.done",
+		"response.output_item.added", "response.output_item.done",
+		"response.completed",
+	}, types)
+	assert.Equal(t, "web_search_call", completed.Output[0].Type)
+	assert.Equal(t, "Mock servers make tests deterministic.", completed.OutputText())
+}
+
+func TestResponsesCoverage(t *testing.T) {
+	unused := researchMock
+	unused.Name, unused.Match.Text = "unused", "Summarize"
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{OpenAIResponses: []mockllm.OpenAIResponsesMock{researchMock, unused}}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+	client := openai.NewClient(openaioption.WithBaseURL(baseURL+"/v1"), openaioption.WithAPIKey("test-key"))
+
+	_, err = client.Responses.New(t.Context(), responses.ResponseNewParams{
+		Model: "gpt-4o",
+		Input: responses.ResponseNewParamsInputUnion{OfString: openai.String("Research mock servers")},
+	})
+	require.NoError(t, err)
+
+	mocks := server.Mocks()
+	require.Len(t, mocks, 2)
+	assert.Equal(t, "research", mocks[0].Name)
+	assert.Equal(t, 1, mocks[0].Hits)
+	coverage := server.Coverage()
+	assert.Equal(t, 2, coverage.Total)
+	require.Len(t, coverage.Unused, 1)
+	assert.Equal(t, "unused", coverage.Unused[0].Name)
+	assert.Error(t, server.DisableMock("research"), "only chat mocks can be disabled")
+}
+
+func TestResponsesValidation(t *testing.T) {
+	filesys := fstest.MapFS{"config.json": {Data: []byte(`{"openai_responses": [
+		{"name": "a", "match": {"match_type": "user_contains", "text": "x"}, "response": {"output": [{"type": "code_interpreter_call"}]}}
+	]}`)}}
+	_, err := mockllm.LoadConfigFromFile("config.json", filesys)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `openai_responses[0].match.match_type: must be exact or contains, got "user_contains"`)
+	assert.Contains(t, err.Error(), `openai_responses[0].response.output[0].type: must be message, function_call, web_search_call or file_search_call, got "code_interpreter_call"`)
+}
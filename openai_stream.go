@@ -0,0 +1,168 @@
+package mockllm
+
+import (
+	"github.com/kagent-dev/mockllm/tokenizer"
+	"github.com/openai/openai-go"
+)
+
+// openaiDoneEvent terminates an OpenAI stream
+var openaiDoneEvent = sseEvent{Data: []byte("[DONE]")}
+
+// openaiInterruptedEvent ends an OpenAI stream cut short by the server stopping, as an error
+// object the SDKs raise mid-stream
+var openaiInterruptedEvent = newSSEEvent("", map[string]any{
+	"error": map[string]any{
+		"message": "The server is shutting down",
+		"type":    "server_error",
+		"param":   nil,
+		"code":    nil,
+	},
+})
+
+// openaiChunk is a chat.completion.chunk object. The SDK response types are not used for
+// streaming because they serialize every zero valued field, which real chunks omit.
+type openaiChunk struct {
+	ID                string                  `json:"id"`
+	Object            string                  `json:"object"`
+	Created           int64                   `json:"created"`
+	Model             string                  `json:"model"`
+	SystemFingerprint string                  `json:"system_fingerprint,omitempty"`
+	ServiceTier       string                  `json:"service_tier,omitempty"`
+	Choices           []openaiChunkChoice     `json:"choices"`
+	Usage             *openai.CompletionUsage `json:"usage,omitempty"`
+}
+
+type openaiChunkChoice struct {
+	Index        int64            `json:"index"`
+	Delta        openaiChunkDelta `json:"delta"`
+	FinishReason *string          `json:"finish_reason"`
+}
+
+type openaiChunkDelta struct {
+	Role      string                `json:"role,omitempty"`
+	Content   *string               `json:"content,omitempty"`
+	ToolCalls []openaiChunkToolCall `json:"tool_calls,omitempty"`
+	Audio     *openaiChunkAudio     `json:"audio,omitempty"`
+}
+
+// openaiChunkAudio is a delta of the audio of a message: its ID and expiry, then pieces of its
+// transcript and of its base64 data
+type openaiChunkAudio struct {
+	ID         string `json:"id,omitempty"`
+	ExpiresAt  int64  `json:"expires_at,omitempty"`
+	Transcript string `json:"transcript,omitempty"`
+	Data       string `json:"data,omitempty"`
+}
+
+// audioDataChunkSize is how much base64 audio data a chunk carries, a multiple of 4 so every piece
+// decodes on its own
+const audioDataChunkSize = 16384
+
+type openaiChunkToolCall struct {
+	Index    int                 `json:"index"`
+	ID       string              `json:"id,omitempty"`
+	Type     string              `json:"type,omitempty"`
+	Function openaiChunkFunction `json:"function"`
+}
+
+type openaiChunkFunction struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+// openaiStreamEvents converts a chat completion into the chunks OpenAI streams for it: a role
+// chunk, content, audio and tool call argument deltas, a finish chunk per choice, an optional
+// usage chunk and the final [DONE] marker. Deltas carry a single token each.
+func openaiStreamEvents(response openai.ChatCompletion, includeUsage bool, tok tokenizer.Tokenizer) []sseEvent {
+	chunk := func(choice openaiChunkChoice) sseEvent {
+		return newSSEEvent("", openaiChunk{
+			ID:                response.ID,
+			Object:            "chat.completion.chunk",
+			Created:           response.Created,
+			Model:             response.Model,
+			SystemFingerprint: response.SystemFingerprint,
+			ServiceTier:       string(response.ServiceTier),
+			Choices:           []openaiChunkChoice{choice},
+		})
+	}
+
+	var events []sseEvent
+	for _, choice := range response.Choices {
+		empty := ""
+		events = append(events, chunk(openaiChunkChoice{
+			Index: choice.Index,
+			Delta: openaiChunkDelta{Role: "assistant", Content: &empty},
+		}))
+
+		for _, piece := range tok.Tokenize(choice.Message.Content) {
+			events = append(events, chunk(openaiChunkChoice{
+				Index: choice.Index,
+				Delta: openaiChunkDelta{Content: &piece},
+			}))
+		}
+
+		if audio := choice.Message.Audio; audio.ID != "" || audio.Data != "" {
+			events = append(events, chunk(openaiChunkChoice{
+				Index: choice.Index,
+				Delta: openaiChunkDelta{Audio: &openaiChunkAudio{ID: audio.ID, ExpiresAt: audio.ExpiresAt}},
+			}))
+			for _, piece := range tok.Tokenize(audio.Transcript) {
+				events = append(events, chunk(openaiChunkChoice{
+					Index: choice.Index,
+					Delta: openaiChunkDelta{Audio: &openaiChunkAudio{Transcript: piece}},
+				}))
+			}
+			for data := audio.Data; data != ""; {
+				piece := data[:min(len(data), audioDataChunkSize)]
+				data = data[len(piece):]
+				events = append(events, chunk(openaiChunkChoice{
+					Index: choice.Index,
+					Delta: openaiChunkDelta{Audio: &openaiChunkAudio{Data: piece}},
+				}))
+			}
+		}
+
+		for i, toolCall := range choice.Message.ToolCalls {
+			events = append(events, chunk(openaiChunkChoice{
+				Index: choice.Index,
+				Delta: openaiChunkDelta{ToolCalls: []openaiChunkToolCall{{
+					Index:    i,
+					ID:       toolCall.ID,
+					Type:     "function",
+					Function: openaiChunkFunction{Name: toolCall.Function.Name},
+				}}},
+			}))
+			for _, piece := range tok.Tokenize(toolCall.Function.Arguments) {
+				events = append(events, chunk(openaiChunkChoice{
+					Index: choice.Index,
+					Delta: openaiChunkDelta{ToolCalls: []openaiChunkToolCall{{
+						Index:    i,
+						Function: openaiChunkFunction{Arguments: piece},
+					}}},
+				}))
+			}
+		}
+
+		finishReason := choice.FinishReason
+		if finishReason == "" {
+			finishReason = "stop"
+		}
+		events = append(events, chunk(openaiChunkChoice{Index: choice.Index, FinishReason: &finishReason}))
+	}
+
+	if includeUsage {
+		usage := response.Usage
+		events = append(events, newSSEEvent("", openaiChunk{
+			ID:                response.ID,
+			Object:            "chat.completion.chunk",
+			Created:           response.Created,
+			Model:             response.Model,
+			SystemFingerprint: response.SystemFingerprint,
+			ServiceTier:       string(response.ServiceTier),
+			Choices:           []openaiChunkChoice{},
+			Usage:             &usage,
+		}))
+	}
+
+	return append(events, openaiDoneEvent)
+}
@@ -0,0 +1,240 @@
+package mockllm_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openaiHeaders are the headers required by the OpenAI handler
+var openaiHeaders = map[string]string{"Authorization": "Bearer test-key"}
+
+// newOpenAIServer starts a server with the given OpenAI mocks and stops it when the test ends
+func newOpenAIServer(t *testing.T, mocks ...mockllm.OpenAIMock) string {
+	t.Helper()
+
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{OpenAI: mocks}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(context.Background()) }) //nolint:errcheck
+	return baseURL
+}
+
+// postChatCompletion sends the request and decodes a successful response
+func postChatCompletion(t *testing.T, baseURL string, request openai.ChatCompletionNewParams) openai.ChatCompletion {
+	t.Helper()
+
+	resp := postJSON(t, baseURL+"/v1/chat/completions", request, openaiHeaders)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var completion openai.ChatCompletion
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&completion))
+	return completion
+}
+
+func TestOpenAIPromptCacheSimulation(t *testing.T) {
+	baseURL := newOpenAIServer(t, mockllm.OpenAIMock{
+		Name: "cached",
+		Match: mockllm.OpenAIRequestMatch{
+			MatchType: mockllm.MatchTypeContains,
+			Message:   openaiUserMessage("Hello"),
+		},
+		Response:            openai.ChatCompletion{ID: "chatcmpl-cached"},
+		SimulatePromptCache: true,
+	})
+
+	request := openai.ChatCompletionNewParams{
+		Model: "gpt-4o-mini",
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage(strings.Repeat("You are a helpful assistant. ", 300)),
+			openaiUserMessage("Hello"),
+		},
+	}
+
+	first := postChatCompletion(t, baseURL, request)
+	assert.Zero(t, first.Usage.PromptTokensDetails.CachedTokens)
+	assert.Positive(t, first.Usage.PromptTokens)
+
+	request.Messages[1] = openaiUserMessage("Hello again")
+	second := postChatCompletion(t, baseURL, request)
+	cached := second.Usage.PromptTokensDetails.CachedTokens
+	assert.GreaterOrEqual(t, cached, int64(1024))
+	assert.Zero(t, (cached-1024)%128)
+	assert.Less(t, cached, second.Usage.PromptTokens)
+}
+
+func TestOpenAIGeneratedLogprobs(t *testing.T) {
+	baseURL := newOpenAIServer(t, mockllm.OpenAIMock{
+		Name: "hello",
+		Match: mockllm.OpenAIRequestMatch{
+			MatchType: mockllm.MatchTypeContains,
+			Message:   openaiUserMessage("Hello"),
+		},
+		Response: openai.ChatCompletion{
+			ID: "chatcmpl-logprobs",
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{Role: "assistant", Content: "Hi there, friend"}},
+			},
+		},
+	})
+
+	request := openai.ChatCompletionNewParams{
+		Model:       "gpt-4o-mini",
+		Messages:    []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+		Logprobs:    openai.Bool(true),
+		TopLogprobs: openai.Int(3),
+	}
+
+	first := postChatCompletion(t, baseURL, request)
+	require.Len(t, first.Choices, 1)
+	content := first.Choices[0].Logprobs.Content
+	require.Len(t, content, 3)
+
+	var text string
+	for _, token := range content {
+		text += token.Token
+		assert.LessOrEqual(t, token.Logprob, 0.0)
+		require.Len(t, token.TopLogprobs, 3)
+		assert.Equal(t, token.Token, token.TopLogprobs[0].Token)
+	}
+	assert.Equal(t, "Hi there, friend", text)
+
+	second := postChatCompletion(t, baseURL, request)
+	assert.Equal(t, content, second.Choices[0].Logprobs.Content)
+}
+
+func TestOpenAIMultipleChoices(t *testing.T) {
+	baseURL := newOpenAIServer(t, mockllm.OpenAIMock{
+		Name: "hello",
+		Match: mockllm.OpenAIRequestMatch{
+			MatchType: mockllm.MatchTypeContains,
+			Message:   openaiUserMessage("Hello"),
+		},
+		Response: openai.ChatCompletion{
+			ID: "chatcmpl-n",
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{Content: "first"}},
+				{Message: openai.ChatCompletionMessage{Content: "second"}},
+			},
+		},
+	})
+
+	completion := postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+		Model:    "gpt-4o-mini",
+		Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+		N:        openai.Int(3),
+	})
+	require.Len(t, completion.Choices, 3)
+	for i, expected := range []string{"first", "second", "first"} {
+		assert.Equal(t, int64(i), completion.Choices[i].Index)
+		assert.Equal(t, expected, completion.Choices[i].Message.Content)
+	}
+}
+
+func TestOpenAISeedResponses(t *testing.T) {
+	baseURL := newOpenAIServer(t, mockllm.OpenAIMock{
+		Name: "hello",
+		Match: mockllm.OpenAIRequestMatch{
+			MatchType: mockllm.MatchTypeContains,
+			Message:   openaiUserMessage("Hello"),
+		},
+		Response: openai.ChatCompletion{ID: "chatcmpl-default"},
+		SeedResponses: map[int64]openai.ChatCompletion{
+			42: {ID: "chatcmpl-42", SystemFingerprint: "fp_configured"},
+		},
+	})
+
+	request := openai.ChatCompletionNewParams{
+		Model:    "gpt-4o-mini",
+		Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+	}
+	unseeded := postChatCompletion(t, baseURL, request)
+	assert.Equal(t, "chatcmpl-default", unseeded.ID)
+	assert.Empty(t, unseeded.SystemFingerprint)
+
+	request.Seed = openai.Int(42)
+	seeded := postChatCompletion(t, baseURL, request)
+	assert.Equal(t, "chatcmpl-42", seeded.ID)
+	assert.Equal(t, "fp_configured", seeded.SystemFingerprint)
+
+	request.Seed = openai.Int(7)
+	other := postChatCompletion(t, baseURL, request)
+	assert.Equal(t, "chatcmpl-default", other.ID)
+	assert.Regexp(t, `^fp_[0-9a-f]{10}$`, other.SystemFingerprint)
+}
+
+func TestOpenAIRawResponse(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, err := gz.Write([]byte(`{"id":"chatcmpl-gzip"}`))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	baseURL := newOpenAIServer(t, mockllm.OpenAIMock{
+		Name: "gzip",
+		Match: mockllm.OpenAIRequestMatch{
+			MatchType: mockllm.MatchTypeContains,
+			Message:   openaiUserMessage("Hello"),
+		},
+		Raw: &mockllm.HTTPResponse{
+			Status: http.StatusOK,
+			Headers: map[string]string{
+				"Content-Type":     "application/json",
+				"Content-Encoding": "gzip",
+			},
+			BodyBase64: base64.StdEncoding.EncodeToString(compressed.Bytes()),
+		},
+	})
+
+	// Asking for gzip explicitly stops the transport from transparently decompressing the body
+	req, err := http.NewRequest(http.MethodPost, baseURL+"/v1/chat/completions", strings.NewReader(
+		`{"model":"gpt-4o-mini","messages":[{"role":"user","content":"Hello"}]}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer test-key")
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+
+	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+	reader, err := gzip.NewReader(resp.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"chatcmpl-gzip"}`, string(body))
+}
+
+func TestOpenAIPartialResponseDefaults(t *testing.T) {
+	baseURL := newOpenAIServer(t, mockllm.OpenAIMock{
+		Name:  "partial",
+		Match: mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+		Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Content: "Hi"}},
+			{Message: openai.ChatCompletionMessage{ToolCalls: []openai.ChatCompletionMessageToolCall{{ID: "call_1"}}}},
+		}},
+	})
+
+	completion := postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+		Model:    "gpt-4.1-mini",
+		Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+	})
+	assert.True(t, strings.HasPrefix(completion.ID, "chatcmpl-"))
+	assert.Equal(t, "chat.completion", string(completion.Object))
+	assert.NotZero(t, completion.Created)
+	assert.Equal(t, "gpt-4.1-mini", completion.Model)
+	require.Len(t, completion.Choices, 2)
+	assert.Equal(t, "assistant", string(completion.Choices[0].Message.Role))
+	assert.Equal(t, "stop", completion.Choices[0].FinishReason)
+	assert.Equal(t, int64(1), completion.Choices[1].Index)
+	assert.Equal(t, "tool_calls", completion.Choices[1].FinishReason)
+}
@@ -0,0 +1,355 @@
+package mockllm
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// openaiVectorStoresPath is the root of the OpenAI vector stores API
+const openaiVectorStoresPath = "/v1/vector_stores"
+
+// openaiVectorStoreRoutes are the vector store endpoints served by the OpenAI provider
+var openaiVectorStoreRoutes = []Route{
+	{Method: http.MethodPost, Path: openaiVectorStoresPath},
+	{Method: http.MethodGet, Path: openaiVectorStoresPath},
+	{Method: http.MethodGet, Path: openaiVectorStoresPath + "/{id}"},
+	{Method: http.MethodDelete, Path: openaiVectorStoresPath + "/{id}"},
+	{Method: http.MethodPost, Path: openaiVectorStoresPath + "/{id}/search"},
+	{Method: http.MethodPost, Path: openaiVectorStoresPath + "/{id}/files"},
+	{Method: http.MethodGet, Path: openaiVectorStoresPath + "/{id}/files"},
+	{Method: http.MethodGet, Path: openaiVectorStoresPath + "/{id}/files/{file_id}"},
+	{Method: http.MethodDelete, Path: openaiVectorStoresPath + "/{id}/files/{file_id}"},
+}
+
+// vectorStoreChunkSize is the approximate size in bytes of the chunks files are split into for search
+const vectorStoreChunkSize = 800
+
+// vectorStore is an emulated vector store holding the chunked content of its files
+type vectorStore struct {
+	id        string
+	name      string
+	createdAt time.Time
+	files     []*vectorStoreFile
+}
+
+// vectorStoreFile is a file attached to a vector store
+type vectorStoreFile struct {
+	file      *openaiFile
+	createdAt time.Time
+	chunks    []string
+}
+
+// vectorStoreStore holds the vector stores created through a provider
+type vectorStoreStore struct {
+	mu     sync.Mutex
+	stores []*vectorStore
+}
+
+func newVectorStoreStore() *vectorStoreStore {
+	return &vectorStoreStore{}
+}
+
+func (s *vectorStoreStore) find(id string) *vectorStore {
+	for _, store := range s.stores {
+		if store.id == id {
+			return store
+		}
+	}
+	return nil
+}
+
+// attach adds a file to the store at the given time, replacing an earlier attachment of the same file
+func (store *vectorStore) attach(file *openaiFile, createdAt time.Time) *vectorStoreFile {
+	attached := &vectorStoreFile{file: file, createdAt: createdAt, chunks: chunkText(string(file.content))}
+	store.files = slices.DeleteFunc(store.files, func(f *vectorStoreFile) bool { return f.file.ID == file.ID })
+	store.files = append(store.files, attached)
+	return attached
+}
+
+func (store *vectorStore) object() map[string]any {
+	usageBytes := 0
+	for _, file := range store.files {
+		usageBytes += file.file.Bytes
+	}
+	return map[string]any{
+		"id":             store.id,
+		"object":         "vector_store",
+		"created_at":     store.createdAt.Unix(),
+		"last_active_at": store.createdAt.Unix(),
+		"name":           store.name,
+		"usage_bytes":    usageBytes,
+		"status":         "completed",
+		"file_counts": map[string]int{
+			"in_progress": 0, "completed": len(store.files), "failed": 0, "cancelled": 0, "total": len(store.files),
+		},
+		"metadata":      map[string]string{},
+		"expires_after": nil,
+		"expires_at":    nil,
+	}
+}
+
+func (file *vectorStoreFile) object(storeID string) map[string]any {
+	return map[string]any{
+		"id":              file.file.ID,
+		"object":          "vector_store.file",
+		"created_at":      file.createdAt.Unix(),
+		"vector_store_id": storeID,
+		"usage_bytes":     file.file.Bytes,
+		"status":          "completed",
+		"last_error":      nil,
+		"chunking_strategy": map[string]any{
+			"type":   "static",
+			"static": map[string]int{"max_chunk_size_tokens": vectorStoreChunkSize / 4, "chunk_overlap_tokens": 0},
+		},
+	}
+}
+
+// search scores every chunk by the fraction of query terms it contains and returns the best
+// scoring chunks, at most maxResults
+func (store *vectorStore) search(queries []string, maxResults int) []map[string]any {
+	var terms []string
+	for _, query := range queries {
+		terms = append(terms, searchTerms(query)...)
+	}
+	if len(terms) == 0 {
+		return []map[string]any{}
+	}
+
+	type hit struct {
+		file  *vectorStoreFile
+		chunk string
+		score float64
+	}
+	var hits []hit
+	for _, file := range store.files {
+		for _, chunk := range file.chunks {
+			chunkTerms := searchTerms(chunk)
+			matched := 0
+			for _, term := range terms {
+				if slices.Contains(chunkTerms, term) {
+					matched++
+				}
+			}
+			if matched > 0 {
+				hits = append(hits, hit{file: file, chunk: chunk, score: float64(matched) / float64(len(terms))})
+			}
+		}
+	}
+	slices.SortStableFunc(hits, func(a, b hit) int { return cmp.Compare(b.score, a.score) })
+
+	results := []map[string]any{}
+	for _, hit := range hits[:min(len(hits), maxResults)] {
+		results = append(results, map[string]any{
+			"file_id":    hit.file.file.ID,
+			"filename":   hit.file.file.Filename,
+			"score":      hit.score,
+			"attributes": map[string]any{},
+			"content":    []map[string]string{{"type": "text", "text": hit.chunk}},
+		})
+	}
+	return results
+}
+
+// chunkText splits text into chunks of about vectorStoreChunkSize bytes at paragraph boundaries,
+// splitting paragraphs that are too long on their own at whitespace
+func chunkText(text string) []string {
+	var chunks []string
+	var current strings.Builder
+	flush := func() {
+		if chunk := strings.TrimSpace(current.String()); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		current.Reset()
+	}
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		for _, word := range strings.Fields(paragraph) {
+			if current.Len()+len(word) >= vectorStoreChunkSize {
+				flush()
+			}
+			current.WriteString(word + " ")
+		}
+		if current.Len() >= vectorStoreChunkSize/2 {
+			flush()
+		}
+	}
+	flush()
+	return chunks
+}
+
+// searchTerms returns the lower cased words of text
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// handleVectorStores serves the vector store, vector store file and search endpoints
+func (p *OpenAIProvider) handleVectorStores(w http.ResponseWriter, r *http.Request) {
+	if _, message, code := checkOpenAIAuth(r); message != "" {
+		writeOpenAIError(w, http.StatusUnauthorized, message, "", code)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, openaiVectorStoresPath), "/"), "/")
+	id, action, fileID := parts[0], "", ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+	if len(parts) > 2 {
+		fileID = parts[2]
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, fmt.Sprintf("Failed to read request body: %v", err), "", "")
+		return
+	}
+
+	p.vectorStores.mu.Lock()
+	defer p.vectorStores.mu.Unlock()
+
+	if id == "" {
+		if r.Method == http.MethodPost {
+			p.createVectorStore(w, body)
+			return
+		}
+		objects := []map[string]any{}
+		for _, store := range slices.Backward(p.vectorStores.stores) {
+			objects = append(objects, store.object())
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"object": "list", "data": objects, "has_more": false})
+		return
+	}
+
+	store := p.vectorStores.find(id)
+	if store == nil {
+		writeOpenAIError(w, http.StatusNotFound, fmt.Sprintf("No vector store found with id '%s'.", id), "", "")
+		return
+	}
+	switch {
+	case action == "search":
+		p.searchVectorStore(w, store, body)
+	case action == "files":
+		p.handleVectorStoreFiles(w, r, store, fileID, body)
+	case r.Method == http.MethodDelete:
+		p.vectorStores.stores = slices.DeleteFunc(p.vectorStores.stores, func(s *vectorStore) bool { return s == store })
+		writeJSON(w, http.StatusOK, map[string]any{"id": id, "object": "vector_store.deleted", "deleted": true})
+	default:
+		writeJSON(w, http.StatusOK, store.object())
+	}
+}
+
+// createVectorStore creates a vector store, attaching the files listed in the request
+func (p *OpenAIProvider) createVectorStore(w http.ResponseWriter, body []byte) {
+	var request struct {
+		Name    string   `json:"name"`
+		FileIDs []string `json:"file_ids"`
+	}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &request); err != nil {
+			writeOpenAIError(w, http.StatusBadRequest,
+				fmt.Sprintf("We could not parse the JSON body of your request: %v", err), "", "")
+			return
+		}
+	}
+
+	files := make([]*openaiFile, 0, len(request.FileIDs))
+	for _, fileID := range request.FileIDs {
+		file := p.files.get(fileID)
+		if file == nil {
+			writeOpenAIError(w, http.StatusNotFound, fmt.Sprintf("No file found with id '%s'.", fileID), "file_ids", "")
+			return
+		}
+		files = append(files, file)
+	}
+
+	store := &vectorStore{id: p.ids.next("vector_store", "vs_", p.tenant), name: request.Name, createdAt: p.clock.Now()}
+	for _, file := range files {
+		store.attach(file, store.createdAt)
+	}
+	p.vectorStores.stores = append(p.vectorStores.stores, store)
+	writeJSON(w, http.StatusOK, store.object())
+}
+
+// searchVectorStore runs a search request against the store
+func (p *OpenAIProvider) searchVectorStore(w http.ResponseWriter, store *vectorStore, body []byte) {
+	var request struct {
+		Query         json.RawMessage `json:"query"`
+		MaxNumResults int             `json:"max_num_results"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest,
+			fmt.Sprintf("We could not parse the JSON body of your request: %v", err), "", "")
+		return
+	}
+	var queries []string
+	if err := json.Unmarshal(request.Query, &queries); err != nil {
+		var query string
+		if err := json.Unmarshal(request.Query, &query); err != nil {
+			writeOpenAIError(w, http.StatusBadRequest, "query must be a string or an array of strings", "query", "")
+			return
+		}
+		queries = []string{query}
+	}
+	maxResults := request.MaxNumResults
+	if maxResults <= 0 {
+		maxResults = 10
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"object":       "vector_store.search_results.page",
+		"search_query": queries,
+		"data":         store.search(queries, maxResults),
+		"has_more":     false,
+		"next_page":    nil,
+	})
+}
+
+// handleVectorStoreFiles attaches, lists, retrieves and detaches the files of a vector store
+func (p *OpenAIProvider) handleVectorStoreFiles(w http.ResponseWriter, r *http.Request, store *vectorStore,
+	fileID string, body []byte) {
+	switch {
+	case fileID == "" && r.Method == http.MethodPost:
+		var request struct {
+			FileID string `json:"file_id"`
+		}
+		if err := json.Unmarshal(body, &request); err != nil {
+			writeOpenAIError(w, http.StatusBadRequest,
+				fmt.Sprintf("We could not parse the JSON body of your request: %v", err), "", "")
+			return
+		}
+		file := p.files.get(request.FileID)
+		if file == nil {
+			writeOpenAIError(w, http.StatusNotFound, fmt.Sprintf("No file found with id '%s'.", request.FileID), "file_id", "")
+			return
+		}
+		writeJSON(w, http.StatusOK, store.attach(file, p.clock.Now()).object(store.id))
+	case fileID == "":
+		objects := []map[string]any{}
+		for _, file := range slices.Backward(store.files) {
+			objects = append(objects, file.object(store.id))
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"object": "list", "data": objects, "has_more": false})
+	default:
+		index := slices.IndexFunc(store.files, func(f *vectorStoreFile) bool { return f.file.ID == fileID })
+		if index < 0 {
+			writeOpenAIError(w, http.StatusNotFound,
+				fmt.Sprintf("No file found with id '%s' in vector store '%s'.", fileID, store.id), "", "")
+			return
+		}
+		if r.Method == http.MethodDelete {
+			store.files = slices.Delete(store.files, index, index+1)
+			writeJSON(w, http.StatusOK, map[string]any{"id": fileID, "object": "vector_store.file.deleted", "deleted": true})
+			return
+		}
+		writeJSON(w, http.StatusOK, store.files[index].object(store.id))
+	}
+}
@@ -0,0 +1,65 @@
+package mockllm_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+	openaioption "github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIVectorStores(t *testing.T) {
+	baseURL := newOpenAIServer(t)
+	client := openai.NewClient(
+		openaioption.WithBaseURL(baseURL+"/v1"),
+		openaioption.WithAPIKey("test-key"),
+		openaioption.WithMaxRetries(0),
+	)
+
+	upload := func(name, content string) string {
+		file, err := client.Files.New(t.Context(), openai.FileNewParams{
+			File:    openai.File(strings.NewReader(content), name, "text/plain"),
+			Purpose: openai.FilePurposeAssistants,
+		})
+		require.NoError(t, err)
+		return file.ID
+	}
+	pods := upload("pods.md", "Pods are the smallest deployable units in Kubernetes.\n\nA pod runs one or more containers.")
+	services := upload("services.md", "A Service exposes pods on the network.")
+
+	store, err := client.VectorStores.New(t.Context(), openai.VectorStoreNewParams{
+		Name:    openai.String("docs"),
+		FileIDs: []string{pods},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), store.FileCounts.Completed)
+
+	_, err = client.VectorStores.Files.New(t.Context(), store.ID, openai.VectorStoreFileNewParams{FileID: services})
+	require.NoError(t, err)
+	files, err := client.VectorStores.Files.List(t.Context(), store.ID, openai.VectorStoreFileListParams{})
+	require.NoError(t, err)
+	assert.Len(t, files.Data, 2)
+
+	results, err := client.VectorStores.Search(t.Context(), store.ID, openai.VectorStoreSearchParams{
+		Query: openai.VectorStoreSearchParamsQueryUnion{OfString: openai.String("how are pods exposed on the network")},
+	})
+	require.NoError(t, err)
+	require.Len(t, results.Data, 2)
+	assert.Equal(t, "services.md", results.Data[0].Filename)
+	assert.Greater(t, results.Data[0].Score, results.Data[1].Score)
+	assert.Contains(t, results.Data[0].Content[0].Text, "Service exposes pods")
+
+	results, err = client.VectorStores.Search(t.Context(), store.ID, openai.VectorStoreSearchParams{
+		Query: openai.VectorStoreSearchParamsQueryUnion{OfString: openai.String("ingress")},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, results.Data)
+
+	deleted, err := client.VectorStores.Delete(t.Context(), store.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted.Deleted)
+	_, err = client.VectorStores.Get(t.Context(), store.ID)
+	assert.Error(t, err)
+}
@@ -0,0 +1,199 @@
+package openapi
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// DriftKind is how a value drifted from the schema it was written against
+type DriftKind string
+
+const (
+	// DriftDeprecated is a field or value the schema deprecates
+	DriftDeprecated DriftKind = "deprecated"
+	// DriftUnknown is a field the schema does not define, typically because it was removed
+	DriftUnknown DriftKind = "unknown"
+	// DriftEnum is a value outside the values the schema allows, typically a removed enum value
+	DriftEnum DriftKind = "enum"
+)
+
+// Drift is a part of a value that the current version of a schema deprecates or no longer allows
+type Drift struct {
+	// Path locates the value, e.g. choices[0].message.function_call, empty for the value itself
+	Path    string    `json:"path"`
+	Kind    DriftKind `json:"kind"`
+	Message string    `json:"message"`
+}
+
+// Drift reports the parts of a decoded JSON value that the named component schema deprecates or
+// no longer allows, ordered by path. Objects without declared properties, or allowing additional
+// ones, accept any field, and values of the wrong type are left to validation. For anyOf and oneOf
+// schemas, the value is checked against the alternative it drifts least from.
+func (s *Spec) Drift(name string, value any) ([]Drift, error) {
+	schema, ok := s.Components.Schemas[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown schema %q", name)
+	}
+	return s.drift(schema, value, ""), nil
+}
+
+func (s *Spec) drift(schema *Schema, value any, path string) []Drift {
+	schema, err := s.Resolve(schema)
+	if schema == nil || err != nil || value == nil {
+		return nil
+	}
+
+	var drifts []Drift
+	if schema.Deprecated {
+		drifts = append(drifts, Drift{Path: path, Kind: DriftDeprecated, Message: "deprecated"})
+	}
+	allowed := schema.Enum
+	if schema.Const != nil {
+		allowed = []any{schema.Const}
+	}
+	if len(allowed) > 0 && !slices.ContainsFunc(allowed, func(v any) bool { return sameValue(v, value) }) {
+		drifts = append(drifts, Drift{Path: path, Kind: DriftEnum, Message: fmt.Sprintf("value %v is not one of %v", value, allowed)})
+	}
+	if alternatives := append(slices.Clone(schema.AnyOf), schema.OneOf...); len(alternatives) > 0 {
+		var best []Drift
+		found := false
+		for _, alternative := range alternatives {
+			resolved, err := s.Resolve(alternative)
+			if err != nil || !s.acceptsType(resolved, value) {
+				continue
+			}
+			if alternativeDrifts := s.drift(resolved, value, path); !found || len(alternativeDrifts) < len(best) {
+				best, found = alternativeDrifts, true
+			}
+		}
+		drifts = append(drifts, best...)
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		properties := s.properties(schema)
+		closed := len(properties) > 0 && (schema.AdditionalProperties == nil || schema.AdditionalProperties == false)
+		for _, name := range sortedKeys(value) {
+			property, ok := properties[name]
+			if !ok {
+				if !closed {
+					continue
+				}
+				drifts = append(drifts, Drift{Path: joinPath(path, name), Kind: DriftUnknown, Message: "not defined by the schema"})
+				continue
+			}
+			drifts = append(drifts, s.drift(property, value[name], joinPath(path, name))...)
+		}
+	case []any:
+		for i, item := range value {
+			drifts = append(drifts, s.drift(schema.Items, item, path+"["+strconv.Itoa(i)+"]")...)
+		}
+	}
+	return drifts
+}
+
+// properties returns the properties of an object schema, merged with those of its allOf parts
+func (s *Spec) properties(schema *Schema) map[string]*Schema {
+	properties := map[string]*Schema{}
+	for name, property := range schema.Properties {
+		properties[name] = property
+	}
+	for _, part := range schema.AllOf {
+		if resolved, err := s.Resolve(part); err == nil && resolved != nil {
+			for name, property := range s.properties(resolved) {
+				properties[name] = property
+			}
+		}
+	}
+	return properties
+}
+
+// acceptsType reports whether a schema allows values of the JSON type of value, schemas that do
+// not constrain the type accepting any
+func (s *Spec) acceptsType(schema *Schema, value any) bool {
+	if schema == nil {
+		return true
+	}
+	if len(schema.Type) == 0 {
+		if len(schema.Properties) > 0 || len(schema.AllOf) > 0 {
+			_, ok := value.(map[string]any)
+			return ok || value == nil
+		}
+		return true
+	}
+	for _, t := range schema.Type {
+		switch value.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case map[string]any:
+			if t == "object" {
+				return true
+			}
+		case []any:
+			if t == "array" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case float64, int, int64:
+			if t == "number" || t == "integer" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sameValue compares an enum value of a spec, decoded from YAML, with a value decoded from JSON,
+// whose numbers are float64
+func sameValue(allowed, value any) bool {
+	if reflect.DeepEqual(allowed, value) {
+		return true
+	}
+	a, aOK := number(allowed)
+	v, vOK := number(value)
+	return aOK && vOK && a == v
+}
+
+// number returns a numeric value as a float64
+func number(value any) (float64, bool) {
+	switch value := value.(type) {
+	case int:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case uint64:
+		return float64(value), true
+	case float64:
+		return value, true
+	}
+	return 0, false
+}
+
+// joinPath appends a field name to a path
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// sortedKeys returns the keys of an object in order, so drifts are reported deterministically
+func sortedKeys(object map[string]any) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,239 @@
+// Package openapi reads the OpenAPI specs the providers publish, to keep mocks in line with the
+// real response schemas, and generates values from schemas. Only the parts of OpenAPI 3.0 and 3.1
+// schemas (and JSON Schema) that affect the shape of a value are understood.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is an OpenAPI document, reduced to its component schemas
+type Spec struct {
+	Components struct {
+		Schemas map[string]*Schema `yaml:"schemas"`
+	} `yaml:"components"`
+}
+
+// Schema is an OpenAPI schema object
+type Schema struct {
+	Ref        string             `yaml:"$ref" json:"$ref,omitempty"`
+	Type       Types              `yaml:"type" json:"type,omitempty"`
+	Nullable   bool               `yaml:"nullable" json:"nullable,omitempty"`
+	Enum       []any              `yaml:"enum" json:"enum,omitempty"`
+	Const      any                `yaml:"const" json:"const,omitempty"`
+	Default    any                `yaml:"default" json:"default,omitempty"`
+	Example    any                `yaml:"example" json:"example,omitempty"`
+	Minimum    *float64           `yaml:"minimum" json:"minimum,omitempty"`
+	Properties map[string]*Schema `yaml:"properties" json:"properties,omitempty"`
+	// AdditionalProperties is false, true or the schema of the properties not declared
+	AdditionalProperties any       `yaml:"additionalProperties" json:"additionalProperties,omitempty"`
+	Required             []string  `yaml:"required" json:"required,omitempty"`
+	Items                *Schema   `yaml:"items" json:"items,omitempty"`
+	AllOf                []*Schema `yaml:"allOf" json:"allOf,omitempty"`
+	AnyOf                []*Schema `yaml:"anyOf" json:"anyOf,omitempty"`
+	OneOf                []*Schema `yaml:"oneOf" json:"oneOf,omitempty"`
+	Deprecated           bool      `yaml:"deprecated" json:"deprecated,omitempty"`
+}
+
+// Types are the types allowed by a schema, given as a single type or, in OpenAPI 3.1, a list
+type Types []string
+
+// UnmarshalYAML accepts a single type or a list of types
+func (t *Types) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*t = Types{node.Value}
+		return nil
+	}
+	var types []string
+	if err := node.Decode(&types); err != nil {
+		return err
+	}
+	*t = types
+	return nil
+}
+
+// UnmarshalJSON accepts a single type or a list of types
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = Types{single}
+		return nil
+	}
+	var types []string
+	if err := json.Unmarshal(data, &types); err != nil {
+		return err
+	}
+	*t = types
+	return nil
+}
+
+// Parse parses an OpenAPI document in YAML or JSON
+func Parse(data []byte) (*Spec, error) {
+	var spec Spec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI spec: %w", err)
+	}
+	if len(spec.Components.Schemas) == 0 {
+		return nil, fmt.Errorf("OpenAPI spec has no component schemas")
+	}
+	return &spec, nil
+}
+
+// Resolve follows the $ref of a schema to the component schema it points to
+func (s *Spec) Resolve(schema *Schema) (*Schema, error) {
+	for schema != nil && schema.Ref != "" {
+		name, ok := strings.CutPrefix(schema.Ref, "#/components/schemas/")
+		if !ok {
+			return nil, fmt.Errorf("unsupported reference %q", schema.Ref)
+		}
+		if schema, ok = s.Components.Schemas[name]; !ok {
+			return nil, fmt.Errorf("unknown schema %q", name)
+		}
+	}
+	return schema, nil
+}
+
+// Skeleton generates the smallest valid value of the named component schema: objects carry their
+// required properties only, arrays a single item, and scalars their default, example, first enum
+// value or zero value. Null is only used when a schema allows nothing else.
+func (s *Spec) Skeleton(name string) (any, error) {
+	schema, ok := s.Components.Schemas[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown schema %q", name)
+	}
+	return s.skeleton(schema, nil)
+}
+
+func (s *Spec) skeleton(schema *Schema, refs []string) (any, error) {
+	if schema == nil {
+		return nil, nil
+	}
+	if schema.Ref != "" {
+		// Recursive schemas end in null rather than recursing forever
+		if slices.Contains(refs, schema.Ref) {
+			return nil, nil
+		}
+		resolved, err := s.Resolve(schema)
+		if err != nil {
+			return nil, err
+		}
+		return s.skeleton(resolved, append(refs, schema.Ref))
+	}
+
+	switch {
+	case schema.Const != nil:
+		return schema.Const, nil
+	case schema.Default != nil:
+		return schema.Default, nil
+	case len(schema.Enum) > 0:
+		return schema.Enum[0], nil
+	case len(schema.AllOf) > 0:
+		return s.allOfSkeleton(schema, refs)
+	case len(schema.AnyOf) > 0:
+		return s.firstSkeleton(schema.AnyOf, refs)
+	case len(schema.OneOf) > 0:
+		return s.firstSkeleton(schema.OneOf, refs)
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		object := map[string]any{}
+		for _, name := range schema.Required {
+			value, err := s.skeleton(schema.Properties[name], refs)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			object[name] = value
+		}
+		return object, nil
+	case "array":
+		item, err := s.skeleton(schema.Items, refs)
+		if err != nil {
+			return nil, err
+		}
+		return []any{item}, nil
+	case "string":
+		if example, ok := schema.Example.(string); ok {
+			return example, nil
+		}
+		return "", nil
+	case "integer", "number":
+		if schema.Minimum != nil {
+			return *schema.Minimum, nil
+		}
+		return 0, nil
+	case "boolean":
+		return false, nil
+	default:
+		return nil, nil
+	}
+}
+
+// schemaType returns the non-null type of a schema, inferring objects from their properties
+func schemaType(schema *Schema) string {
+	for _, t := range schema.Type {
+		if t != "null" {
+			return t
+		}
+	}
+	if len(schema.Properties) > 0 {
+		return "object"
+	}
+	return ""
+}
+
+// sortedNames returns the property names of a schema in order, so random values are reproducible
+func sortedNames(properties map[string]*Schema) []string {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// allOfSkeleton merges the skeletons of the schemas of an allOf
+func (s *Spec) allOfSkeleton(schema *Schema, refs []string) (any, error) {
+	merged := map[string]any{}
+	for _, part := range schema.AllOf {
+		value, err := s.skeleton(part, refs)
+		if err != nil {
+			return nil, err
+		}
+		object, ok := value.(map[string]any)
+		if !ok {
+			return value, nil
+		}
+		for k, v := range object {
+			merged[k] = v
+		}
+	}
+	rest := *schema
+	rest.AllOf = nil
+	value, err := s.skeleton(&rest, refs)
+	if err != nil {
+		return nil, err
+	}
+	if object, ok := value.(map[string]any); ok {
+		for k, v := range object {
+			merged[k] = v
+		}
+	}
+	return merged, nil
+}
+
+// firstSkeleton returns the skeleton of the first alternative that is not null
+func (s *Spec) firstSkeleton(alternatives []*Schema, refs []string) (any, error) {
+	for _, alternative := range alternatives {
+		if slices.Equal(alternative.Type, Types{"null"}) {
+			continue
+		}
+		return s.skeleton(alternative, refs)
+	}
+	return nil, nil
+}
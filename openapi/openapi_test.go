@@ -0,0 +1,149 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/kagent-dev/mockllm/openapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const spec = `
+openapi: 3.1.0
+components:
+  schemas:
+    CreateChatCompletionResponse:
+      type: object
+      required: [id, object, created, choices, usage]
+      properties:
+        id:
+          type: string
+          example: chatcmpl-123
+        object:
+          type: string
+          enum: [chat.completion]
+        created:
+          type: integer
+        service_tier:
+          type: string
+        choices:
+          type: array
+          items:
+            type: object
+            required: [index, message, finish_reason]
+            properties:
+              index:
+                type: integer
+              finish_reason:
+                type: string
+                enum: [stop, length]
+              message:
+                $ref: '#/components/schemas/Message'
+        usage:
+          allOf:
+            - $ref: '#/components/schemas/Usage'
+          deprecated: true
+    Message:
+      type: object
+      required: [role, content, parent]
+      properties:
+        role:
+          const: assistant
+        content:
+          anyOf:
+            - type: 'null'
+            - type: string
+        parent:
+          $ref: '#/components/schemas/Message'
+    Usage:
+      type: object
+      required: [total_tokens]
+      properties:
+        total_tokens:
+          type: [integer, 'null']
+          minimum: 1
+`
+
+func TestSkeleton(t *testing.T) {
+	parsed, err := openapi.Parse([]byte(spec))
+	require.NoError(t, err)
+
+	skeleton, err := parsed.Skeleton("CreateChatCompletionResponse")
+	require.NoError(t, err)
+	data, err := json.Marshal(skeleton)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"id": "chatcmpl-123",
+		"object": "chat.completion",
+		"created": 0,
+		"choices": [{
+			"index": 0,
+			"finish_reason": "stop",
+			"message": {"role": "assistant", "content": "", "parent": null}
+		}],
+		"usage": {"total_tokens": 1}
+	}`, string(data))
+
+	_, err = parsed.Skeleton("Missing")
+	assert.Error(t, err)
+}
+
+func TestParseRejectsSpecWithoutSchemas(t *testing.T) {
+	_, err := openapi.Parse([]byte(`{"openapi": "3.0.0"}`))
+	assert.Error(t, err)
+}
+
+func TestRandom(t *testing.T) {
+	schema, err := openapi.ParseSchema([]byte(`{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string"},
+			"age": {"type": "integer", "minimum": 18},
+			"tags": {"type": "array", "items": {"enum": ["a", "b"]}}
+		}
+	}`))
+	require.NoError(t, err)
+
+	value := openapi.Random(schema, rand.New(rand.NewPCG(1, 2)))
+	object, ok := value.(map[string]any)
+	require.True(t, ok)
+	assert.IsType(t, "", object["name"])
+	assert.GreaterOrEqual(t, object["age"], int64(18))
+	require.NotEmpty(t, object["tags"])
+	for _, tag := range object["tags"].([]any) {
+		assert.Contains(t, []any{"a", "b"}, tag)
+	}
+
+	assert.Equal(t, value, openapi.Random(schema, rand.New(rand.NewPCG(1, 2))), "values are reproducible")
+}
+
+func TestDrift(t *testing.T) {
+	parsed, err := openapi.Parse([]byte(spec))
+	require.NoError(t, err)
+
+	var value any
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"id": "chatcmpl-1",
+		"object": "chat.completion",
+		"created": 1,
+		"system_fingerprint": "fp_1",
+		"choices": [{
+			"index": 0,
+			"finish_reason": "function_call",
+			"message": {"role": "assistant", "content": "Hi"}
+		}],
+		"usage": {"total_tokens": 3}
+	}`), &value))
+	drifts, err := parsed.Drift("CreateChatCompletionResponse", value)
+	require.NoError(t, err)
+	assert.Equal(t, []openapi.Drift{
+		{Path: "choices[0].finish_reason", Kind: openapi.DriftEnum, Message: "value function_call is not one of [stop length]"},
+		{Path: "system_fingerprint", Kind: openapi.DriftUnknown, Message: "not defined by the schema"},
+		{Path: "usage", Kind: openapi.DriftDeprecated, Message: "deprecated"},
+	}, drifts)
+
+	_, err = parsed.Drift("Missing", value)
+	assert.Error(t, err)
+}
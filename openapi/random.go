@@ -0,0 +1,79 @@
+package openapi
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"gopkg.in/yaml.v3"
+)
+
+// words are used for generated strings
+var words = []string{"lorem", "ipsum", "dolor", "sit", "amet", "consectetur", "adipiscing", "elit", "sed",
+	"do", "eiusmod", "tempor", "incididunt", "ut", "labore", "et", "dolore", "magna", "aliqua"}
+
+// ParseSchema parses a standalone JSON Schema or OpenAPI schema object in YAML or JSON
+func ParseSchema(data []byte) (*Schema, error) {
+	var schema Schema
+	if err := yaml.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	return &schema, nil
+}
+
+// Random generates a random value valid against a schema, reproducible for a given rng seed.
+// Unlike skeletons, random values carry every property and one to three array items. References
+// are not followed and generate null.
+func Random(schema *Schema, rng *rand.Rand) any {
+	if schema == nil || schema.Ref != "" {
+		return nil
+	}
+	switch {
+	case schema.Const != nil:
+		return schema.Const
+	case len(schema.Enum) > 0:
+		return schema.Enum[rng.IntN(len(schema.Enum))]
+	case len(schema.AllOf) > 0:
+		merged := map[string]any{}
+		for _, part := range schema.AllOf {
+			if object, ok := Random(part, rng).(map[string]any); ok {
+				for k, v := range object {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	case len(schema.AnyOf) > 0:
+		return Random(schema.AnyOf[rng.IntN(len(schema.AnyOf))], rng)
+	case len(schema.OneOf) > 0:
+		return Random(schema.OneOf[rng.IntN(len(schema.OneOf))], rng)
+	}
+
+	minimum := 0.0
+	if schema.Minimum != nil {
+		minimum = *schema.Minimum
+	}
+	switch schemaType(schema) {
+	case "object":
+		object := map[string]any{}
+		for _, name := range sortedNames(schema.Properties) {
+			object[name] = Random(schema.Properties[name], rng)
+		}
+		return object
+	case "array":
+		items := make([]any, 1+rng.IntN(3))
+		for i := range items {
+			items[i] = Random(schema.Items, rng)
+		}
+		return items
+	case "string":
+		return words[rng.IntN(len(words))] + " " + words[rng.IntN(len(words))]
+	case "integer":
+		return int64(minimum) + rng.Int64N(100)
+	case "number":
+		return minimum + rng.Float64()*100
+	case "boolean":
+		return rng.IntN(2) == 1
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,47 @@
+package mockllm
+
+import (
+	"log/slog"
+	"net"
+)
+
+// ServerOption configures a server created by NewServer
+type ServerOption func(*serverOptions)
+
+// serverOptions are the settings NewServer collects from its options
+type serverOptions struct {
+	config   Config
+	clock    Clock
+	logger   *slog.Logger
+	listener net.Listener
+}
+
+// WithConfig serves the mocks and settings of a config. Servers without one serve no mocks.
+func WithConfig(config Config) ServerOption {
+	return func(o *serverOptions) {
+		o.config = config
+	}
+}
+
+// WithLogger logs the server starting and stopping, and every request it handles: those served
+// by a mock at info level, the others at warn level. Servers log nothing by default.
+func WithLogger(logger *slog.Logger) ServerOption {
+	return func(o *serverOptions) {
+		o.logger = logger
+	}
+}
+
+// WithClock times the server's simulated delays with the clock, in place of Config.Clock
+func WithClock(clock Clock) ServerOption {
+	return func(o *serverOptions) {
+		o.clock = clock
+	}
+}
+
+// WithListener serves requests from a TCP listener, e.g. one a test harness bound beforehand, in
+// place of listening on Config.ListenAddr. Stopping the server closes it.
+func WithListener(listener net.Listener) ServerOption {
+	return func(o *serverOptions) {
+		o.listener = listener
+	}
+}
@@ -0,0 +1,73 @@
+package mockllm_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerOptions(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	}))
+	clock := mockllm.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	server := mockllm.NewServer(
+		mockllm.WithConfig(mockllm.Config{
+			Clock: mockllm.NewFakeClock(time.Now()),
+			OpenAI: []mockllm.OpenAIMock{{
+				Name:  "hello",
+				Match: mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+				Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{
+					Message: openai.ChatCompletionMessage{Content: "Hi"},
+				}}},
+			}},
+		}),
+		mockllm.WithLogger(logger),
+		mockllm.WithClock(clock),
+		mockllm.WithListener(listener),
+	)
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "http://"+listener.Addr().String(), baseURL, "the server listens on the listener given")
+
+	completion := postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+		Model:    "gpt-4o-mini",
+		Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+	})
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).Unix(), completion.Created, "WithClock overrides Config.Clock")
+
+	resp := postJSON(t, baseURL+"/v1/chat/completions", openai.ChatCompletionNewParams{
+		Model:    "gpt-4o-mini",
+		Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Goodbye")},
+	}, map[string]string{"Authorization": "Bearer test-key"})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	require.NoError(t, server.Stop(context.Background()))
+	_, err = net.Dial("tcp", listener.Addr().String())
+	assert.Error(t, err, "stopping the server closes the listener")
+
+	output := logs.String()
+	assert.Contains(t, output, `level=INFO msg="mockllm listening" url=`+baseURL)
+	assert.Contains(t, output, `level=INFO msg="request served" id=1 provider=openai method=POST path=/v1/chat/completions status=200 mock=hello`)
+	assert.Contains(t, output, `level=WARN msg="request not served" id=2 provider=openai method=POST path=/v1/chat/completions status=404`)
+	assert.Contains(t, output, `level=INFO msg="mockllm stopped" drained=0`)
+}
@@ -0,0 +1,240 @@
+package mockllm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// fieldPatch replaces the value at a path of a mock response
+type fieldPatch struct {
+	path  string
+	value any
+}
+
+// responsePatches are the patches applied to the responses of mocks at runtime, keyed by mock name.
+// They are shared by the providers of a server and of its tenants.
+type responsePatches struct {
+	mu      sync.RWMutex
+	patches map[string][]fieldPatch
+}
+
+func newResponsePatches() *responsePatches {
+	return &responsePatches{patches: map[string][]fieldPatch{}}
+}
+
+func (r *responsePatches) get(name string) []fieldPatch {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.patches[name]
+}
+
+// applyResponsePatches patches the response of the named mock, which is left as it is when it has
+// no patches
+func applyResponsePatches[T any](r *responsePatches, name string, response *T) {
+	if patches := r.get(name); len(patches) > 0 {
+		patchResponse(response, patches) //nolint:errcheck // patches are checked when they are added
+	}
+}
+
+// patchResponse replaces the values at the paths of the patches in a response, in order
+func patchResponse[T any](response *T, patches []fieldPatch) error {
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var document any
+	if err := decoder.Decode(&document); err != nil {
+		return err
+	}
+	for _, patch := range patches {
+		set, err := setField(document, patch.path, patch.value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", patch.path, err)
+		}
+		if set == 0 {
+			return fmt.Errorf("%s: no such field in the response", patch.path)
+		}
+	}
+	if encoded, err = json.Marshal(document); err != nil {
+		return err
+	}
+	var patched T
+	if err := json.Unmarshal(encoded, &patched); err != nil {
+		return err
+	}
+	*response = patched
+	return nil
+}
+
+// setField replaces the values at a path such as choices[0].message.content in a decoded JSON
+// document, as addressed by lookupField, and returns how many it replaced. The last field of the
+// path is added to objects that lack it.
+func setField(document any, path string, value any) (int, error) {
+	type step struct {
+		name  string
+		index string
+	}
+	var steps []step
+	for _, segment := range strings.Split(path, ".") {
+		name, indexes, _ := strings.Cut(segment, "[")
+		if name != "" {
+			steps = append(steps, step{name: name})
+		}
+		if indexes != "" {
+			for _, index := range strings.Split(strings.TrimSuffix(indexes, "]"), "][") {
+				steps = append(steps, step{index: index})
+			}
+		}
+	}
+
+	var set func(node any, steps []step) (int, error)
+	set = func(node any, steps []step) (int, error) {
+		last := len(steps) == 1
+		current := steps[0]
+		if current.name != "" {
+			object, ok := node.(map[string]any)
+			if !ok {
+				return 0, nil
+			}
+			if last {
+				object[current.name] = value
+				return 1, nil
+			}
+			field, ok := object[current.name]
+			if !ok {
+				return 0, nil
+			}
+			return set(field, steps[1:])
+		}
+
+		array, ok := node.([]any)
+		if !ok {
+			return 0, nil
+		}
+		indexes := []int{}
+		if current.index == "*" {
+			for i := range array {
+				indexes = append(indexes, i)
+			}
+		} else {
+			i, err := strconv.Atoi(current.index)
+			if err != nil {
+				return 0, fmt.Errorf("index %q is not a number or *", current.index)
+			}
+			if i < 0 {
+				i += len(array)
+			}
+			if i >= 0 && i < len(array) {
+				indexes = append(indexes, i)
+			}
+		}
+		count := 0
+		for _, i := range indexes {
+			if last {
+				array[i] = value
+				count++
+				continue
+			}
+			n, err := set(array[i], steps[1:])
+			if err != nil {
+				return 0, err
+			}
+			count += n
+		}
+		return count, nil
+	}
+	if len(steps) == 0 {
+		return 0, fmt.Errorf("empty path")
+	}
+	return set(document, steps)
+}
+
+// PatchMockResponse replaces values in the responses of the OpenAI and Anthropic mocks of the given
+// name, those of tenants included, e.g. to change the arguments of a tool call between the phases of
+// a test without configuring the whole mock again. Keys are paths such as
+// choices[0].message.tool_calls[0].function.arguments, addressing the response as fields criteria
+// address requests, and values are the JSON values to put there. Patches add up, in sorted path
+// order for a single call, until ResetMockResponse. Patches that don't apply to every response of
+// the name, e.g. paths that address nothing, are rejected.
+func (s *Server) PatchMockResponse(name string, patch map[string]any) error {
+	patches := make([]fieldPatch, 0, len(patch))
+	for _, path := range sortedKeys(patch) {
+		patches = append(patches, fieldPatch{path: path, value: patch[path]})
+	}
+
+	if err := s.checkMockName(name); err != nil {
+		return err
+	}
+	s.patches.mu.Lock()
+	defer s.patches.mu.Unlock()
+	patches = append(slices.Clone(s.patches.patches[name]), patches...)
+	for _, provider := range s.openaiProviders() {
+		for _, mock := range provider.mocks {
+			if mock.Name != name {
+				continue
+			}
+			if err := patchResponse(&mock.Response, patches); err != nil {
+				return fmt.Errorf("failed to patch the response of OpenAI mock %q: %w", name, err)
+			}
+		}
+	}
+	for _, provider := range s.anthropicProviders() {
+		for _, mock := range provider.mocks {
+			if mock.Name != name {
+				continue
+			}
+			if err := patchResponse(&mock.Response, patches); err != nil {
+				return fmt.Errorf("failed to patch the response of Anthropic mock %q: %w", name, err)
+			}
+		}
+	}
+	s.patches.patches[name] = patches
+	return nil
+}
+
+// ResetMockResponse drops the patches of the responses of the named mocks, serving them as
+// configured again
+func (s *Server) ResetMockResponse(name string) {
+	s.patches.mu.Lock()
+	defer s.patches.mu.Unlock()
+	delete(s.patches.patches, name)
+}
+
+// handleAdminMockResponse patches the response of the named mock with the paths and values of the
+// body on PATCH, and drops its patches on DELETE
+func (s *Server) handleAdminMockResponse(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := s.checkMockName(name); err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": err.Error()})
+		return
+	}
+	if r.Method == http.MethodDelete {
+		s.ResetMockResponse(name)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var patch map[string]any
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&patch); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": fmt.Sprintf("Invalid JSON: %v", err)})
+		return
+	}
+	if err := s.PatchMockResponse(name, patch); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"name": name, "patched": len(patch)})
+}
@@ -0,0 +1,78 @@
+package mockllm_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatchMockResponse(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{{
+			Name:  "weather",
+			Match: mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Weather")},
+			Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{
+				FinishReason: "tool_calls",
+				Message: openai.ChatCompletionMessage{ToolCalls: []openai.ChatCompletionMessageToolCall{{
+					ID:       "call_1",
+					Type:     "function",
+					Function: openai.ChatCompletionMessageToolCallFunction{Name: "get_weather", Arguments: `{"city":"Berlin"}`},
+				}}},
+			}}},
+		}},
+		Anthropic: []mockllm.AnthropicMock{{
+			Name:     "hello",
+			Match:    mockllm.AnthropicRequestMatch{MatchType: mockllm.MatchTypeExact, Message: anthropicHelloRequest.Messages[0]},
+			Response: anthropic.Message{Content: []anthropic.ContentBlockUnion{{Type: "text", Text: "Hi!"}}},
+		}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	arguments := func() string {
+		completion := postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+			Model:    "gpt-4o-mini",
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Weather")},
+		})
+		return completion.Choices[0].Message.ToolCalls[0].Function.Arguments
+	}
+	assert.Equal(t, `{"city":"Berlin"}`, arguments())
+
+	patch := func(method, body string) int {
+		req, err := http.NewRequest(method, baseURL+"/admin/mocks/weather/response", strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close() //nolint:errcheck
+		return resp.StatusCode
+	}
+	require.Equal(t, http.StatusOK, patch(http.MethodPatch, `{"choices[0].message.tool_calls[0].function.arguments": "{\"city\":\"Paris\"}"}`))
+	assert.Equal(t, `{"city":"Paris"}`, arguments())
+	assert.Equal(t, http.StatusBadRequest, patch(http.MethodPatch, `{"choices[3].message.content": "Hi"}`),
+		"paths addressing nothing are rejected")
+	assert.Equal(t, `{"city":"Paris"}`, arguments(), "rejected patches are not applied")
+
+	require.Equal(t, http.StatusNoContent, patch(http.MethodDelete, ""))
+	assert.Equal(t, `{"city":"Berlin"}`, arguments())
+
+	require.NoError(t, server.PatchMockResponse("hello", map[string]any{"content[0].text": "Bonjour!"}))
+	client := anthropic.NewClient(
+		anthropicoption.WithBaseURL(baseURL),
+		anthropicoption.WithAPIKey("test-key"),
+		anthropicoption.WithMaxRetries(0),
+	)
+	message, err := client.Messages.New(t.Context(), anthropicHelloRequest)
+	require.NoError(t, err)
+	assert.Equal(t, "Bonjour!", message.Content[0].Text)
+
+	assert.EqualError(t, server.PatchMockResponse("missing", map[string]any{"id": "x"}), `no OpenAI or Anthropic mock named "missing"`)
+}
@@ -0,0 +1,185 @@
+package mockllm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// pluginHost runs the WebAssembly plugins of mocks. A plugin exports its memory, an
+// alloc(size i32) i32 function for the request body, and either or both hooks:
+//
+//	match(ptr, len i32) i32    nonzero when the mock matches the request body
+//	respond(ptr, len i32) i64  the JSON reply, as ptr<<32 | len
+//
+// respond returns a JSON string for a reply text, or any other JSON value for the whole response.
+// Modules are compiled once and instantiated afresh for every call, so plugins keep no state
+// between requests. WASI is available, for modules built by toolchains that require it.
+// Every call is bounded by pluginCallTimeout and the request it serves, so a runaway plugin
+// fails the request instead of hanging it.
+type pluginHost struct {
+	mu       sync.Mutex
+	runtime  wazero.Runtime
+	compiled map[string]wazero.CompiledModule
+}
+
+// pluginCallTimeout bounds a call of a plugin hook
+const pluginCallTimeout = 5 * time.Second
+
+func newPluginHost() *pluginHost {
+	return &pluginHost{compiled: map[string]wazero.CompiledModule{}}
+}
+
+// module compiles the plugin at path on first use, returning it with the runtime it runs in
+func (h *pluginHost) module(ctx context.Context, path string) (wazero.Runtime, wazero.CompiledModule, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if compiled, ok := h.compiled[path]; ok {
+		return h.runtime, compiled, nil
+	}
+	if h.runtime == nil {
+		// Closing on context done interrupts plugins stuck in loops once their call times out
+		h.runtime = wazero.NewRuntimeWithConfig(context.Background(), wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+		wasi_snapshot_preview1.MustInstantiate(context.Background(), h.runtime)
+	}
+	wasm, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read plugin: %w", err)
+	}
+	compiled, err := h.runtime.CompileModule(ctx, wasm)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compile plugin %s: %w", path, err)
+	}
+	h.compiled[path] = compiled
+	return h.runtime, compiled, nil
+}
+
+// close closes the runtime and the compiled plugins, failing calls still running
+func (h *pluginHost) close(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.runtime == nil {
+		return nil
+	}
+	err := h.runtime.Close(ctx)
+	h.runtime, h.compiled = nil, map[string]wazero.CompiledModule{}
+	return err
+}
+
+// exports reports whether the plugin at path exports the named hook
+func (h *pluginHost) exports(ctx context.Context, path, hook string) (bool, error) {
+	_, compiled, err := h.module(ctx, path)
+	if err != nil {
+		return false, err
+	}
+	_, ok := compiled.ExportedFunctions()[hook]
+	return ok, nil
+}
+
+// call passes the request body to a hook of the plugin at path and returns its results
+func (h *pluginHost) call(ctx context.Context, path, hook string, body []byte) ([]uint64, api.Memory, func(), error) {
+	runtime, compiled, err := h.module(ctx, path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	instance, err := runtime.InstantiateModule(ctx, compiled,
+		wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize"))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to instantiate plugin %s: %w", path, err)
+	}
+	closeInstance := func() { instance.Close(context.Background()) } //nolint:errcheck
+
+	alloc, fn := instance.ExportedFunction("alloc"), instance.ExportedFunction(hook)
+	if alloc == nil || fn == nil || instance.Memory() == nil {
+		closeInstance()
+		return nil, nil, nil, fmt.Errorf("plugin %s must export memory, alloc and %s", path, hook)
+	}
+	allocated, err := alloc.Call(ctx, uint64(len(body)))
+	if err != nil {
+		closeInstance()
+		return nil, nil, nil, fmt.Errorf("plugin %s alloc failed: %w", path, err)
+	}
+	ptr := uint32(allocated[0])
+	if !instance.Memory().Write(ptr, body) {
+		closeInstance()
+		return nil, nil, nil, fmt.Errorf("plugin %s allocated memory out of range", path)
+	}
+	results, err := fn.Call(ctx, uint64(ptr), uint64(len(body)))
+	if err != nil {
+		closeInstance()
+		return nil, nil, nil, fmt.Errorf("plugin %s %s failed: %w", path, hook, err)
+	}
+	return results, instance.Memory(), closeInstance, nil
+}
+
+// matches calls the match hook of the plugin at path. ok is false when the plugin has no match
+// hook, leaving the mock's match criteria to decide.
+func (h *pluginHost) matches(ctx context.Context, path string, body []byte) (matched, ok bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, pluginCallTimeout)
+	defer cancel()
+	if ok, err := h.exports(ctx, path, "match"); !ok || err != nil {
+		return false, false, err
+	}
+	results, _, closeInstance, err := h.call(ctx, path, "match", body)
+	if err != nil {
+		return false, true, err
+	}
+	defer closeInstance()
+	return uint32(results[0]) != 0, true, nil
+}
+
+// respond calls the respond hook of the plugin at path, returning the reply text or the JSON
+// response. ok is false when the plugin has no respond hook, leaving the configured response.
+func (h *pluginHost) respond(ctx context.Context, path string, body []byte) (text string, response []byte, ok bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, pluginCallTimeout)
+	defer cancel()
+	if ok, err := h.exports(ctx, path, "respond"); !ok || err != nil {
+		return "", nil, false, err
+	}
+	results, memory, closeInstance, err := h.call(ctx, path, "respond", body)
+	if err != nil {
+		return "", nil, true, err
+	}
+	defer closeInstance()
+
+	reply, inRange := memory.Read(uint32(results[0]>>32), uint32(results[0]))
+	if !inRange {
+		return "", nil, true, fmt.Errorf("plugin %s returned a reply out of memory range", path)
+	}
+	if !json.Valid(reply) {
+		return "", nil, true, fmt.Errorf("plugin %s returned invalid JSON: %q", path, reply)
+	}
+	if err := json.Unmarshal(reply, &text); err == nil {
+		return text, nil, true, nil
+	}
+	return "", append([]byte(nil), reply...), true, nil
+}
+
+// pluginOpenAIMock returns a copy of the mock with the response computed by its plugin, or the
+// mock itself when the plugin has no respond hook
+func (h *pluginHost) pluginOpenAIMock(ctx context.Context, mock *OpenAIMock, body []byte) (*OpenAIMock, error) {
+	text, response, ok, err := h.respond(ctx, mock.Plugin, body)
+	if err != nil || !ok {
+		return mock, err
+	}
+	return computedOpenAIMock(mock, text, response)
+}
+
+// pluginAnthropicMock returns a copy of the mock with the response computed by its plugin, or
+// the mock itself when the plugin has no respond hook
+func (h *pluginHost) pluginAnthropicMock(ctx context.Context, mock *AnthropicMock, body []byte) (*AnthropicMock, error) {
+	text, response, ok, err := h.respond(ctx, mock.Plugin, body)
+	if err != nil || !ok {
+		return mock, err
+	}
+	return computedAnthropicMock(mock, text, response)
+}
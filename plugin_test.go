@@ -0,0 +1,110 @@
+package mockllm_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pluginModule assembles a WebAssembly plugin whose match hook accepts request bodies longer than
+// minLength bytes and whose respond hook returns reply, stored at the start of its memory. The
+// encoding is kept minimal, so the reply must be shorter than 64 bytes.
+func pluginModule(minLength int, reply string) []byte {
+	// minLength as a signed LEB128 i32.const immediate, for lengths below 8192
+	length := []byte{byte(minLength)}
+	if minLength >= 64 {
+		length = []byte{byte(minLength&0x7f | 0x80), byte(minLength >> 7)}
+	}
+	// local.get 1, i32.const minLength, i32.gt_u
+	return pluginModuleMatching(append(append([]byte{0x20, 1, 0x41}, length...), 0x4b), reply)
+}
+
+// pluginModuleMatching is pluginModule with the instructions of its match hook, leaving the
+// result on the stack
+func pluginModuleMatching(match []byte, reply string) []byte {
+	vec := func(items ...[]byte) []byte {
+		out := []byte{byte(len(items))}
+		for _, item := range items {
+			out = append(out, item...)
+		}
+		return out
+	}
+	section := func(id byte, content []byte) []byte {
+		return append([]byte{id, byte(len(content))}, content...)
+	}
+	name := func(s string) []byte { return append([]byte{byte(len(s))}, s...) }
+	body := func(code ...byte) []byte { return append([]byte{byte(len(code) + 1), 0}, code...) }
+	const i32, i64, end = 0x7f, 0x7e, 0x0b
+
+	module := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	module = append(module, section(1, vec(
+		[]byte{0x60, 1, i32, 1, i32},      // alloc
+		[]byte{0x60, 2, i32, i32, 1, i32}, // match
+		[]byte{0x60, 2, i32, i32, 1, i64}, // respond
+	))...)
+	module = append(module, section(3, []byte{3, 0, 1, 2})...)
+	module = append(module, section(5, []byte{1, 0, 1})...)
+	module = append(module, section(7, vec(
+		append(name("memory"), 2, 0),
+		append(name("alloc"), 0, 0),
+		append(name("match"), 0, 1),
+		append(name("respond"), 0, 2),
+	))...)
+	module = append(module, section(10, vec(
+		body(0x41, 0x80, 0x08, end), // i32.const 1024
+		body(append(match, end)...),
+		body(0x42, byte(len(reply)), end), // i64.const len(reply), at offset 0
+	))...)
+	module = append(module, section(11, vec(
+		append([]byte{0, 0x41, 0, end, byte(len(reply))}, reply...),
+	))...)
+	return module
+}
+
+func TestPlugins(t *testing.T) {
+	dir := t.TempDir()
+	textPlugin, responsePlugin := filepath.Join(dir, "text.wasm"), filepath.Join(dir, "response.wasm")
+	require.NoError(t, os.WriteFile(textPlugin, pluginModule(100, `"long prompt"`), 0o600))
+	require.NoError(t, os.WriteFile(responsePlugin, pluginModule(0, `{"choices":[{"message":{"content":"whole response"}}]}`), 0o600))
+
+	baseURL := newOpenAIServer(t,
+		mockllm.OpenAIMock{Name: "text", Plugin: textPlugin},
+		mockllm.OpenAIMock{Name: "response", Plugin: responsePlugin},
+	)
+	reply := func(prompt string) string {
+		completion := postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+			Model:    "gpt-4o-mini",
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage(prompt)},
+		})
+		require.Len(t, completion.Choices, 1)
+		return completion.Choices[0].Message.Content
+	}
+
+	assert.Equal(t, "long prompt", reply(strings.Repeat("long ", 20)))
+	assert.Equal(t, "whole response", reply("short"))
+}
+
+func TestRunawayPluginIsCancelled(t *testing.T) {
+	loopingPlugin := filepath.Join(t.TempDir(), "loop.wasm")
+	// loop br 0 end, i32.const 0: a match hook that never returns
+	require.NoError(t, os.WriteFile(loopingPlugin, pluginModuleMatching([]byte{0x03, 0x40, 0x0c, 0, 0x0b, 0x41, 0}, `"never"`), 0o600))
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{{Name: "loop", Plugin: loopingPlugin}},
+	}))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+	explanations, err := server.WhichMockMatchesContext(ctx, []byte(`{"model": "gpt-4o", "messages": [{"role": "user", "content": "Hello"}]}`), nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, explanations)
+	assert.False(t, explanations[0].Matched)
+	assert.Contains(t, explanations[0].Reason, "plugin failed")
+}
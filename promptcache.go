@@ -0,0 +1,131 @@
+package mockllm
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"sync"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+)
+
+const (
+	// openaiMinCachedTokens is the shortest prompt prefix OpenAI caches automatically
+	openaiMinCachedTokens = 1024
+	// openaiCacheIncrement is the granularity of OpenAI cache hits beyond the minimum
+	openaiCacheIncrement = 128
+)
+
+// promptPrefix is a cacheable prompt prefix and its estimated size in tokens
+type promptPrefix struct {
+	key    [sha256.Size]byte
+	tokens int
+}
+
+// promptCache remembers the prompt prefixes seen by a provider to simulate prompt caching
+type promptCache struct {
+	mu       sync.Mutex
+	prefixes map[[sha256.Size]byte]bool
+}
+
+func newPromptCache() *promptCache {
+	return &promptCache{prefixes: map[[sha256.Size]byte]bool{}}
+}
+
+// use looks up the prefixes, ordered from shortest to longest, and stores them in the cache.
+// It returns the tokens of the longest prefix that was already cached and the tokens of the
+// longest prefix that had to be written to the cache beyond that.
+func (c *promptCache) use(prefixes []promptPrefix) (read, created int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, prefix := range prefixes {
+		if c.prefixes[prefix.key] {
+			read = prefix.tokens
+		}
+	}
+	if len(prefixes) > 0 {
+		if longest := prefixes[len(prefixes)-1]; !c.prefixes[longest.key] {
+			created = longest.tokens - read
+		}
+	}
+	for _, prefix := range prefixes {
+		c.prefixes[prefix.key] = true
+	}
+	return read, created
+}
+
+// prefixBuilder accumulates serialized prompt segments and snapshots prefixes at cache breakpoints
+type prefixBuilder struct {
+	hash     []byte
+	size     int
+	prefixes []promptPrefix
+}
+
+func (b *prefixBuilder) add(segment any, breakpoint bool) {
+	data, err := json.Marshal(segment)
+	if err != nil {
+		return
+	}
+	sum := sha256.Sum256(append(b.hash, data...))
+	b.hash = sum[:]
+	b.size += len(data)
+	if breakpoint {
+		b.prefixes = append(b.prefixes, promptPrefix{key: sum, tokens: b.tokens()})
+	}
+}
+
+// tokens approximates the token count of everything added so far using the common four
+// characters per token rule
+func (b *prefixBuilder) tokens() int {
+	return (b.size + 3) / 4
+}
+
+// simulateAnthropicPromptCache fills in the cache usage of the response for a request, treating
+// every block with cache_control as a cache breakpoint the way the Messages API does
+func (c *promptCache) simulateAnthropicPromptCache(request anthropic.MessageNewParams, response *anthropic.Message) {
+	var b prefixBuilder
+	for _, tool := range request.Tools {
+		b.add(tool, hasCacheControl(tool.GetCacheControl()))
+	}
+	for _, block := range request.System {
+		b.add(block, hasCacheControl(&block.CacheControl))
+	}
+	for _, message := range request.Messages {
+		b.add(message.Role, false)
+		for _, block := range message.Content {
+			b.add(block, hasCacheControl(block.GetCacheControl()))
+		}
+	}
+
+	read, created := c.use(b.prefixes)
+	response.Usage.CacheReadInputTokens = int64(read)
+	response.Usage.CacheCreationInputTokens = int64(created)
+	response.Usage.InputTokens = int64(b.tokens() - read - created)
+}
+
+func hasCacheControl(cacheControl *anthropic.CacheControlEphemeralParam) bool {
+	return cacheControl != nil && cacheControl.Type != ""
+}
+
+// simulateOpenAIPromptCache fills in the cached prompt tokens of the response for a request.
+// Like OpenAI's automatic caching, prefixes are cached at message boundaries and only hits of
+// at least openaiMinCachedTokens count, in increments of openaiCacheIncrement tokens.
+func (c *promptCache) simulateOpenAIPromptCache(request openai.ChatCompletionNewParams, response *openai.ChatCompletion) {
+	var b prefixBuilder
+	for _, tool := range request.Tools {
+		b.add(tool, false)
+	}
+	for _, message := range request.Messages {
+		b.add(message, true)
+	}
+
+	read, _ := c.use(b.prefixes)
+	cached := 0
+	if read >= openaiMinCachedTokens {
+		cached = read - (read-openaiMinCachedTokens)%openaiCacheIncrement
+	}
+	response.Usage.PromptTokens = int64(b.tokens())
+	response.Usage.PromptTokensDetails.CachedTokens = int64(cached)
+	response.Usage.TotalTokens = response.Usage.PromptTokens + response.Usage.CompletionTokens
+}
@@ -0,0 +1,100 @@
+package mockllm
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Route is an HTTP endpoint served by a provider
+type Route struct {
+	Method string
+	// Path is a net/http ServeMux path pattern, e.g. /v1/files/{id}
+	Path string
+}
+
+// Provider serves one or more mocked API endpoints
+type Provider interface {
+	// Routes returns the endpoints the provider serves
+	Routes() []Route
+	// Handle serves a request for one of the provider's routes
+	Handle(w http.ResponseWriter, r *http.Request)
+}
+
+// routeClaimer is implemented by providers serving routes other providers serve too, such as
+// /v1/files, to claim the requests meant for them. Requests no provider claims go to the first
+// provider serving the route that isn't a claimer.
+type routeClaimer interface {
+	claims(r *http.Request) bool
+}
+
+// claimedHandler is the handler of a provider for a route, along with the provider as a claimer
+type claimedHandler struct {
+	claimer routeClaimer
+	handle  http.HandlerFunc
+}
+
+// dispatchClaimed returns the handler of a route served by the given providers, which hands
+// requests to the provider claiming them
+func dispatchClaimed(handlers []claimedHandler) http.HandlerFunc {
+	if len(handlers) == 1 {
+		return handlers[0].handle
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		fallback := handlers[0].handle
+		for i := len(handlers) - 1; i >= 0; i-- {
+			switch handler := handlers[i]; {
+			case handler.claimer == nil:
+				fallback = handler.handle
+			case handler.claimer.claims(r):
+				handler.handle(w, r)
+				return
+			}
+		}
+		fallback(w, r)
+	}
+}
+
+// ProviderFactory creates a provider for a server with the given config. Provider specific
+// settings can be read from Config.Providers under the name the provider was registered with.
+type ProviderFactory func(config Config) Provider
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]ProviderFactory{}
+)
+
+// RegisterProvider makes a custom provider available to every server created afterwards.
+// It is meant to be called from the init function of the package implementing the provider,
+// and panics if the name is already taken or the factory is nil.
+func RegisterProvider(name string, factory ProviderFactory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	if factory == nil {
+		panic("mockllm: RegisterProvider factory is nil")
+	}
+	if _, dup := providers[name]; dup || name == providerOpenAI || name == providerAnthropic || name == providerGemini {
+		panic(fmt.Sprintf("mockllm: RegisterProvider called twice for provider %q", name))
+	}
+	providers[name] = factory
+}
+
+// registeredProviders creates an instance of every registered provider, ordered by name
+func registeredProviders(config Config) []Provider {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	instances := make([]Provider, 0, len(names))
+	for _, name := range names {
+		instances = append(instances, providers[name](config))
+	}
+	return instances
+}
@@ -0,0 +1,70 @@
+package mockllm_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pingProvider is a minimal custom provider replying with a configured string
+type pingProvider struct {
+	Reply string `json:"reply"`
+}
+
+func (p *pingProvider) Routes() []mockllm.Route {
+	return []mockllm.Route{{Method: http.MethodGet, Path: "/v1/ping"}}
+}
+
+func (p *pingProvider) Handle(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(p.Reply)) //nolint:errcheck
+}
+
+func init() {
+	mockllm.RegisterProvider("ping", func(config mockllm.Config) mockllm.Provider {
+		provider := &pingProvider{Reply: "pong"}
+		if raw, ok := config.Providers["ping"]; ok {
+			json.Unmarshal(raw, provider) //nolint:errcheck
+		}
+		return provider
+	})
+}
+
+func TestCustomProvider(t *testing.T) {
+	config := mockllm.Config{
+		Providers: map[string]json.RawMessage{"ping": json.RawMessage(`{"reply":"custom pong"}`)},
+	}
+	server := mockllm.NewServer(mockllm.WithConfig(config))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	resp, err := http.Get(baseURL + "/v1/ping")
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "custom pong", string(body))
+
+	resp, err = http.Get(baseURL + "/v1/unknown")
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	var notFound map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&notFound))
+	assert.Contains(t, notFound["hint"], "GET /v1/ping")
+	assert.Contains(t, notFound["hint"], "POST /v1/messages")
+}
+
+func TestRegisterProviderPanicsOnDuplicate(t *testing.T) {
+	assert.Panics(t, func() {
+		mockllm.RegisterProvider("openai", func(mockllm.Config) mockllm.Provider { return nil })
+	})
+	assert.Panics(t, func() {
+		mockllm.RegisterProvider("ping", func(mockllm.Config) mockllm.Provider { return nil })
+	})
+}
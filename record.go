@@ -0,0 +1,87 @@
+package mockllm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+)
+
+// recordedMock is a mock config entry whose response is kept verbatim, since the SDK response
+// types serialize every zero valued field
+type recordedMock struct {
+	Name     string          `json:"name"`
+	Match    any             `json:"match"`
+	Response json.RawMessage `json:"response"`
+}
+
+// RecordOpenAIMock turns a real chat completion request and response into a mock config entry,
+// ready to be added to the "openai" section of a config. The mock contains-matches the text of the
+// request's last message, or exactly matches the message when it has no plain text content.
+func RecordOpenAIMock(name string, request, response []byte) ([]byte, error) {
+	var params openai.ChatCompletionNewParams
+	if err := json.Unmarshal(request, &params); err != nil {
+		return nil, fmt.Errorf("failed to parse request: %w", err)
+	}
+	if len(params.Messages) == 0 {
+		return nil, fmt.Errorf("request has no messages")
+	}
+	var completion openai.ChatCompletion
+	if err := json.Unmarshal(response, &completion); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("response has no choices, is it a chat completion?")
+	}
+
+	lastMessage := params.Messages[len(params.Messages)-1]
+	match := OpenAIRequestMatch{MatchType: MatchTypeExact, Message: lastMessage}
+	if _, ok := lastMessage.GetContent().AsAny().(*string); ok {
+		match.MatchType = MatchTypeContains
+	}
+	return marshalRecordedMock(name, match, response)
+}
+
+// RecordAnthropicMock turns a real Messages API request and response into a mock config entry,
+// ready to be added to the "anthropic" section of a config. The mock contains-matches the first
+// text block of the request's last message, or exactly matches the message when it has none.
+func RecordAnthropicMock(name string, request, response []byte) ([]byte, error) {
+	var params anthropic.MessageNewParams
+	if err := json.Unmarshal(request, &params); err != nil {
+		return nil, fmt.Errorf("failed to parse request: %w", err)
+	}
+	if len(params.Messages) == 0 {
+		return nil, fmt.Errorf("request has no messages")
+	}
+	var message anthropic.Message
+	if err := json.Unmarshal(response, &message); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if message.Type != "message" {
+		return nil, fmt.Errorf("response type is %q, expected a message", message.Type)
+	}
+
+	lastMessage := params.Messages[len(params.Messages)-1]
+	match := AnthropicRequestMatch{MatchType: MatchTypeExact, Message: lastMessage}
+	for _, part := range lastMessage.Content {
+		if part.OfText != nil {
+			match.MatchType = MatchTypeContains
+			match.Message = anthropic.MessageParam{
+				Role:    lastMessage.Role,
+				Content: []anthropic.ContentBlockParamUnion{anthropic.NewTextBlock(part.OfText.Text)},
+			}
+			break
+		}
+	}
+	return marshalRecordedMock(name, match, response)
+}
+
+func marshalRecordedMock(name string, match any, response []byte) ([]byte, error) {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, response); err != nil {
+		return nil, fmt.Errorf("failed to compact response: %w", err)
+	}
+	return json.MarshalIndent(recordedMock{Name: name, Match: match, Response: compact.Bytes()}, "", "  ")
+}
@@ -0,0 +1,61 @@
+package mockllm_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordOpenAIMock(t *testing.T) {
+	request := `{"model":"gpt-4o-mini","messages":[{"role":"system","content":"Be brief"},{"role":"user","content":"List all nodes"}]}`
+	response := `{"id":"chatcmpl-rec","object":"chat.completion","created":1,"model":"gpt-4o-mini",
+		"choices":[{"index":0,"message":{"role":"assistant","content":"node-1"},"finish_reason":"stop"}]}`
+
+	entry, err := mockllm.RecordOpenAIMock("nodes", []byte(request), []byte(response))
+	require.NoError(t, err)
+	assert.Contains(t, string(entry), `"match_type": "contains"`)
+	assert.NotContains(t, string(entry), "service_tier", "the response should be kept verbatim")
+
+	var mock mockllm.OpenAIMock
+	require.NoError(t, json.Unmarshal(entry, &mock))
+	baseURL := newOpenAIServer(t, mock)
+
+	resp := postJSON(t, baseURL+"/v1/chat/completions", json.RawMessage(request), openaiHeaders)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "chatcmpl-rec")
+
+	_, err = mockllm.RecordOpenAIMock("nodes", []byte(request), []byte(`{"id":"msg_1","type":"message"}`))
+	assert.ErrorContains(t, err, "no choices")
+}
+
+func TestRecordAnthropicMock(t *testing.T) {
+	request, err := json.Marshal(anthropicHelloRequest)
+	require.NoError(t, err)
+	response := `{"id":"msg_rec","type":"message","role":"assistant","model":"claude-3-5-sonnet-20240620",
+		"content":[{"type":"text","text":"Hi!"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":2}}`
+
+	entry, err := mockllm.RecordAnthropicMock("hello", request, []byte(response))
+	require.NoError(t, err)
+	assert.Contains(t, string(entry), `"match_type": "contains"`)
+
+	var mock mockllm.AnthropicMock
+	require.NoError(t, json.Unmarshal(entry, &mock))
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{Anthropic: []mockllm.AnthropicMock{mock}}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	resp := postJSON(t, baseURL+"/v1/messages", anthropicHelloRequest, anthropicHeaders("2023-06-01"))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "msg_rec")
+}
@@ -0,0 +1,211 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ReportFormat is the format of a verification report
+type ReportFormat string
+
+const (
+	// ReportFormatJUnit is the JUnit XML format CI systems show as test results
+	ReportFormatJUnit ReportFormat = "junit"
+	// ReportFormatJSON is the VerificationReport as JSON
+	ReportFormatJSON ReportFormat = "json"
+)
+
+// ReportConfig writes a verification report when the server stops, so CI systems can surface
+// unmet expectations and failed assertions as test failures
+type ReportConfig struct {
+	// Path is the file the report is written to
+	Path string `json:"path"`
+	// Format is junit or json, by default junit for .xml paths and json otherwise
+	Format ReportFormat `json:"format,omitempty"`
+}
+
+// format returns the configured format, or the one the extension of the path implies
+func (c ReportConfig) format() ReportFormat {
+	if c.Format != "" {
+		return c.Format
+	}
+	if strings.EqualFold(filepath.Ext(c.Path), ".xml") {
+		return ReportFormatJUnit
+	}
+	return ReportFormatJSON
+}
+
+// reportIssues reports unknown report formats
+func reportIssues(report *ReportConfig) []configIssue {
+	if report == nil || report.format() == ReportFormatJUnit || report.format() == ReportFormatJSON {
+		return nil
+	}
+	return []configIssue{{"report.format", fmt.Sprintf("unknown report format %q", report.Format)}}
+}
+
+// VerificationReport is the outcome of every check Verify makes
+type VerificationReport struct {
+	Passed bool `json:"passed"`
+	// Cases are the requests served by mocks with expectations, in the order their handling ended,
+	// followed by the assertions of the config
+	Cases []VerificationCase `json:"cases"`
+}
+
+// VerificationCase is the outcome of the expectations of a mock for a request, or of an assertion
+type VerificationCase struct {
+	// Suite is "expectations" or "assertions"
+	Suite    string   `json:"suite"`
+	Name     string   `json:"name"`
+	Failures []string `json:"failures,omitempty"`
+}
+
+// VerificationReport checks the expectations of the mocks and the assertions of the config
+// against the requests served so far, like Verify, reporting the checks that passed too
+func (s *Server) VerificationReport() VerificationReport {
+	expecting := map[string]bool{}
+	for _, provider := range s.openaiProviders() {
+		for _, mock := range provider.mocks {
+			expecting[providerOpenAI+"/"+mock.Name] = expecting[providerOpenAI+"/"+mock.Name] || len(mock.Expect) > 0
+		}
+	}
+	for _, provider := range s.anthropicProviders() {
+		for _, mock := range provider.mocks {
+			expecting[providerAnthropic+"/"+mock.Name] = expecting[providerAnthropic+"/"+mock.Name] || len(mock.Expect) > 0
+		}
+	}
+
+	report := VerificationReport{Passed: true, Cases: []VerificationCase{}}
+	for _, record := range s.Requests() {
+		if !record.Matched || !expecting[record.Provider+"/"+record.MockName] && len(record.ExpectationFailures) == 0 {
+			continue
+		}
+		report.Cases = append(report.Cases, VerificationCase{
+			Suite:    "expectations",
+			Name:     fmt.Sprintf("request #%d (%s %s)", record.ID, record.Provider, record.MockName),
+			Failures: record.ExpectationFailures,
+		})
+	}
+	for _, result := range s.Assertions() {
+		verificationCase := VerificationCase{Suite: "assertions", Name: result.Description}
+		if !result.Passed {
+			verificationCase.Failures = []string{fmt.Sprintf("was called %s", times(result.Calls))}
+		}
+		report.Cases = append(report.Cases, verificationCase)
+	}
+	for _, verificationCase := range report.Cases {
+		report.Passed = report.Passed && len(verificationCase.Failures) == 0
+	}
+	return report
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as JUnit XML, with a test suite of the expectations and one of the
+// assertions, each failed case carrying its failures
+func (r VerificationReport) WriteJUnit(w io.Writer) error {
+	suites := junitTestSuites{Name: "mockllm"}
+	for _, name := range []string{"expectations", "assertions"} {
+		suite := junitTestSuite{Name: name, Cases: []junitTestCase{}}
+		for _, verificationCase := range r.Cases {
+			if verificationCase.Suite != name {
+				continue
+			}
+			testCase := junitTestCase{Name: verificationCase.Name, ClassName: "mockllm." + name}
+			if len(verificationCase.Failures) > 0 {
+				testCase.Failure = &junitFailure{
+					Message: verificationCase.Failures[0],
+					Text:    strings.Join(verificationCase.Failures, "\n"),
+				}
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, testCase)
+		}
+		suite.Tests = len(suite.Cases)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// writeReport writes the verification report of the config, if any
+func (s *Server) writeReport() error {
+	if s.config.Report == nil {
+		return nil
+	}
+	file, err := os.Create(s.config.Report.Path)
+	if err != nil {
+		return fmt.Errorf("failed to write verification report: %w", err)
+	}
+	defer file.Close() //nolint:errcheck
+
+	report := s.VerificationReport()
+	switch format := s.config.Report.format(); format {
+	case ReportFormatJUnit:
+		err = report.WriteJUnit(file)
+	case ReportFormatJSON:
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write verification report: %w", err)
+	}
+	return file.Close()
+}
+
+// handleAdminReport returns the verification report as JSON, or as JUnit XML with format=junit
+func (s *Server) handleAdminReport(w http.ResponseWriter, r *http.Request) {
+	report := s.VerificationReport()
+	switch format := ReportFormat(r.URL.Query().Get("format")); format {
+	case "", ReportFormatJSON:
+		writeJSON(w, http.StatusOK, report)
+	case ReportFormatJUnit:
+		w.Header().Set("Content-Type", "application/xml")
+		report.WriteJUnit(w) //nolint:errcheck
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": fmt.Sprintf("unknown report format %q", format)})
+	}
+}
@@ -0,0 +1,84 @@
+package mockllm_test
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerificationReport(t *testing.T) {
+	reportPath := filepath.Join(t.TempDir(), "report.xml")
+	reply := func(name, message string, expect mockllm.RequestExpectation) mockllm.OpenAIMock {
+		return mockllm.OpenAIMock{
+			Name:     name,
+			Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage(message)},
+			Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "Hi"}}}},
+			Expect:   []mockllm.RequestExpectation{expect},
+		}
+	}
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{
+			reply("hello", "Hello", mockllm.RequestExpectation{Contains: "Hello"}),
+			reply("leak", "Bye", mockllm.RequestExpectation{NotContains: "password"}),
+		},
+		Assertions: []mockllm.Assertion{{Mock: "hello", MinCalls: 1}, {Mock: "leak", MinCalls: 2}},
+		Report:     &mockllm.ReportConfig{Path: reportPath},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+
+	for _, message := range []string{"Hello", "Bye, my password is hunter2"} {
+		postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+			Model:    "gpt-4o",
+			Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage(message)},
+		})
+	}
+
+	resp, err := http.Get(baseURL + "/admin/report")
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	var report mockllm.VerificationReport
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	assert.False(t, report.Passed)
+	assert.Equal(t, []mockllm.VerificationCase{
+		{Suite: "expectations", Name: "request #1 (openai hello)"},
+		{Suite: "expectations", Name: "request #2 (openai leak)", Failures: []string{`request includes "password"`}},
+		{Suite: "assertions", Name: `mock "hello" called at least once`},
+		{Suite: "assertions", Name: `mock "leak" called at least 2 times`, Failures: []string{"was called once"}},
+	}, report.Cases)
+
+	// Stopping writes the report as JUnit XML for CI systems
+	require.Error(t, server.Stop(t.Context()))
+	data, err := os.ReadFile(reportPath)
+	require.NoError(t, err)
+	var suites struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Suites   []struct {
+			Name  string `xml:"name,attr"`
+			Cases []struct {
+				Name    string `xml:"name,attr"`
+				Failure *struct {
+					Message string `xml:"message,attr"`
+				} `xml:"failure"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	require.NoError(t, xml.Unmarshal(data, &suites))
+	assert.Equal(t, 4, suites.Tests)
+	assert.Equal(t, 2, suites.Failures)
+	require.Len(t, suites.Suites, 2)
+	assert.Equal(t, "expectations", suites.Suites[0].Name)
+	require.Len(t, suites.Suites[0].Cases, 2)
+	assert.Nil(t, suites.Suites[0].Cases[0].Failure)
+	require.NotNil(t, suites.Suites[0].Cases[1].Failure)
+	assert.Equal(t, `request includes "password"`, suites.Suites[0].Cases[1].Failure.Message)
+}
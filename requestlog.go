@@ -0,0 +1,262 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	providerOpenAI    = "openai"
+	providerAnthropic = "anthropic"
+	providerGemini    = "gemini"
+)
+
+// RequestRecord describes a single request handled by one of the providers
+type RequestRecord struct {
+	ID       int       `json:"id"`
+	Time     time.Time `json:"time"`
+	Provider string    `json:"provider"`
+	Tenant   string    `json:"tenant,omitempty"`
+	// APIKey is the redacted API key of the request, e.g. sk-...abcd
+	APIKey   string          `json:"api_key,omitempty"`
+	Method   string          `json:"method"`
+	Path     string          `json:"path"`
+	Status   int             `json:"status"`
+	Matched  bool            `json:"matched"`
+	MockName string          `json:"mock_name,omitempty"`
+	Body     json.RawMessage `json:"body,omitempty"`
+	// Response is the response a mock served, the one streamed responses were built from included
+	Response json.RawMessage `json:"response,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	// Organization and Project are the OpenAI-Organization and OpenAI-Project headers of the request
+	Organization string `json:"organization,omitempty"`
+	Project      string `json:"project,omitempty"`
+	// ToolViolations are the problems with the tools declared in the request, when tool validation is enabled
+	ToolViolations []string `json:"tool_violations,omitempty"`
+	// Diffs explains why each configured mock did not match an unmatched request
+	Diffs []MockDiff `json:"diffs,omitempty"`
+	// Model and Usage are the requested model and the token usage reported to the client, set
+	// once a mock served the request
+	Model string      `json:"model,omitempty"`
+	Usage *TokenUsage `json:"usage,omitempty"`
+	// Stream describes how much of a streamed response reached the client
+	Stream *StreamStats `json:"stream,omitempty"`
+	// Fuzz is the fuzz case served, for mocks in fuzz mode
+	Fuzz *FuzzRecord `json:"fuzz,omitempty"`
+	// IdempotencyKey is the Idempotency-Key header of the request, and ReplayOf the ID of the
+	// request whose response was replayed for it. Replays are not matched against the mocks, so
+	// they are not counted as hits of MockName.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	ReplayOf       int    `json:"replay_of,omitempty"`
+	// ExpectationFailures are the expectations of the mock the request did not meet
+	ExpectationFailures []string `json:"expectation_failures,omitempty"`
+	// DuplicateOf is the ID of an earlier identical request when this one was sent within the
+	// duplicate window of it, see Server.Duplicates
+	DuplicateOf int `json:"duplicate_of,omitempty"`
+	// apiKey is the API key of the request, for looking up its tenant, idempotency keys and usage
+	apiKey string
+	// received numbers the requests in the order they were received, while IDs follow the order
+	// in which their handling ended
+	received int64
+}
+
+// StreamStats records the delivery of a streamed response
+type StreamStats struct {
+	EventsSent  int `json:"events_sent"`
+	EventsTotal int `json:"events_total"`
+	// Disconnected is set when the client went away before the stream completed
+	Disconnected bool `json:"disconnected"`
+	// Interrupted is set when the server stopped before the stream completed and ended it with an error event
+	Interrupted bool `json:"interrupted"`
+	// Heartbeats is the number of heartbeats sent while the stream waited
+	Heartbeats int `json:"heartbeats,omitempty"`
+	// Attempt is the number of the attempt of a request served by a mock with a stream retry
+	Attempt int `json:"attempt,omitempty"`
+}
+
+// MockDiff is a line diff between the message a mock expects and the message that was received
+type MockDiff struct {
+	MockName  string    `json:"mock_name"`
+	MatchType MatchType `json:"match_type"`
+	Diff      string    `json:"diff"`
+}
+
+// newRecord returns the record of a request received now, by the log's clock
+func (l *RequestLog) newRecord(r *http.Request, provider string, body []byte) RequestRecord {
+	record := RequestRecord{
+		Time:     l.clock.Now(),
+		received: l.received.Add(1),
+		Provider: provider,
+		Method:   r.Method,
+		Path:     r.URL.Path,
+	}
+	record.apiKey = requestAPIKey(r)
+	record.APIKey = redactAPIKey(record.apiKey)
+	record.Organization, record.Project = r.Header.Get(openaiOrganizationHeader), r.Header.Get(openaiProjectHeader)
+	record.IdempotencyKey = r.Header.Get(idempotencyKeyHeader)
+	if json.Valid(body) {
+		record.Body = json.RawMessage(body)
+	}
+	return record
+}
+
+// RequestLog is an in-memory, concurrency safe log of the requests handled by the server
+type RequestLog struct {
+	mu          sync.Mutex
+	records     []RequestRecord
+	subscribers map[chan Event]struct{}
+	// inFlight counts the requests every mock is serving, and maxInFlight the most it served at once
+	inFlight    map[mockKey]int
+	maxInFlight map[mockKey]int
+	// duplicateWindow is how close identical requests are flagged as duplicates, negative to disable
+	duplicateWindow time.Duration
+	// logger logs the records added, nil to log nothing
+	logger *slog.Logger
+	// clock stamps the time of the records
+	clock Clock
+	// received counts the records created, to number them in the order requests were received
+	received atomic.Int64
+}
+
+// mockKey identifies a mock across providers and tenants
+type mockKey struct{ provider, tenant, name string }
+
+// NewRequestLog creates an empty request log
+func NewRequestLog() *RequestLog {
+	return &RequestLog{
+		subscribers: map[chan Event]struct{}{},
+		inFlight:    map[mockKey]int{},
+		maxInFlight: map[mockKey]int{},
+		clock:       systemClock{},
+	}
+}
+
+// Add appends a copy of the record to the log and assigns it an ID. Add is a no-op on a nil log.
+func (l *RequestLog) Add(record *RequestRecord) {
+	if l == nil {
+		return
+	}
+	defer l.logRecord(record)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	record.ID = len(l.records) + 1
+	record.DuplicateOf = l.duplicateOf(record)
+	l.records = append(l.records, *record)
+
+	event := newEvent(*record)
+	for subscriber := range l.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
+
+// logRecord logs an added record, at warn level when no mock served it
+func (l *RequestLog) logRecord(record *RequestRecord) {
+	if l.logger == nil {
+		return
+	}
+	attrs := []any{"id", record.ID, "provider", record.Provider, "method", record.Method, "path", record.Path,
+		"status", record.Status}
+	if record.Tenant != "" {
+		attrs = append(attrs, "tenant", record.Tenant)
+	}
+	if record.Matched {
+		l.logger.Info("request served", append(attrs, "mock", record.MockName)...)
+		return
+	}
+	if record.Error != "" {
+		attrs = append(attrs, "error", record.Error)
+	}
+	l.logger.Warn("request not served", attrs...)
+}
+
+// Subscribe returns a channel receiving an event for every record added from now on and a
+// function that cancels the subscription
+func (l *RequestLog) Subscribe() (<-chan Event, func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	subscriber := make(chan Event, eventBufferSize)
+	l.subscribers[subscriber] = struct{}{}
+
+	return subscriber, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.subscribers, subscriber)
+	}
+}
+
+// serving counts a request served by a mock until the returned function is called. It is a no-op
+// on a nil log.
+func (l *RequestLog) serving(provider, tenant, mockName string) func() {
+	if l == nil {
+		return func() {}
+	}
+	key := mockKey{provider, tenant, mockName}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight[key]++
+	l.maxInFlight[key] = max(l.maxInFlight[key], l.inFlight[key])
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.inFlight[key]--
+	}
+}
+
+// Records returns a snapshot of all logged requests in the order their handling ended, which is
+// the order of their IDs. Concurrent requests may end in another order than they were received.
+func (l *RequestLog) Records() []RequestRecord {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	records := make([]RequestRecord, len(l.records))
+	copy(records, l.records)
+	return records
+}
+
+// Hits returns the number of requests each mock of the given provider has served, keyed by mock name
+func (l *RequestLog) Hits(provider string) map[string]int {
+	return l.TenantHits("", provider)
+}
+
+// TenantHits returns the number of requests each mock of the given tenant and provider has served,
+// keyed by mock name
+func (l *RequestLog) TenantHits(tenant, provider string) map[string]int {
+	hits := map[string]int{}
+	for _, record := range l.Records() {
+		if record.Tenant == tenant && record.Provider == provider && record.Matched {
+			hits[record.MockName]++
+		}
+	}
+	return hits
+}
+
+// TenantMaxConcurrency returns the most requests each mock of the given tenant and provider has
+// served at once, keyed by mock name
+func (l *RequestLog) TenantMaxConcurrency(tenant, provider string) map[string]int {
+	concurrency := map[string]int{}
+	if l == nil {
+		return concurrency
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, n := range l.maxInFlight {
+		if key.tenant == tenant && key.provider == provider {
+			concurrency[key.name] = n
+		}
+	}
+	return concurrency
+}
@@ -0,0 +1,156 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// totalCountHeader reports how many requests a query of the request log selected before paging
+const totalCountHeader = "X-Total-Count"
+
+// RequestQuery selects and pages the records of the request log. Zero fields select every record.
+type RequestQuery struct {
+	Provider string
+	// Tenant selects the requests of a tenant, the empty name selecting those of the default mocks
+	Tenant   *string
+	MockName string
+	// Model is the model requested, also for requests no mock served
+	Model string
+	// Since and Until bound the time the requests were received, Since included and Until excluded
+	Since time.Time
+	Until time.Time
+	// Matched selects the requests a mock served, or those none did
+	Matched *bool
+	// Offset skips the first selected requests and Limit caps how many are returned
+	Offset int
+	Limit  int
+	// OmitBodies drops the request and response bodies from the records, which can dwarf the rest
+	// of the log
+	OmitBodies bool
+}
+
+// selects reports whether the query selects the record
+func (q RequestQuery) selects(record RequestRecord) bool {
+	switch {
+	case q.Provider != "" && record.Provider != q.Provider,
+		q.Tenant != nil && record.Tenant != *q.Tenant,
+		q.MockName != "" && record.MockName != q.MockName,
+		q.Model != "" && requestedModel(record) != q.Model,
+		!q.Since.IsZero() && record.Time.Before(q.Since),
+		!q.Until.IsZero() && !record.Time.Before(q.Until),
+		q.Matched != nil && record.Matched != *q.Matched:
+		return false
+	}
+	return true
+}
+
+// requestedModel returns the model of a logged request, read from its body when no mock served it
+func requestedModel(record RequestRecord) string {
+	if record.Model != "" {
+		return record.Model
+	}
+	var body struct {
+		Model string `json:"model"`
+	}
+	json.Unmarshal(record.Body, &body) //nolint:errcheck
+	return body.Model
+}
+
+// QueryRequests returns a page of the requests the query selects, in the order their handling ended,
+// and how many requests it selects in all
+func (s *Server) QueryRequests(query RequestQuery) ([]RequestRecord, int) {
+	records := []RequestRecord{}
+	total := 0
+	for _, record := range s.Requests() {
+		if !query.selects(record) {
+			continue
+		}
+		total++
+		if total <= query.Offset || (query.Limit > 0 && len(records) == query.Limit) {
+			continue
+		}
+		if query.OmitBodies {
+			record.Body, record.Response = nil, nil
+		}
+		records = append(records, record)
+	}
+	return records, total
+}
+
+// parseRequestQuery reads a request log query from the query parameters of /admin/requests
+func parseRequestQuery(values url.Values) (RequestQuery, error) {
+	query := RequestQuery{
+		Provider: values.Get("provider"),
+		MockName: values.Get("mock"),
+		Model:    values.Get("model"),
+	}
+	if values.Has("tenant") {
+		tenant := values.Get("tenant")
+		query.Tenant = &tenant
+	}
+	parseTime := func(name string, bound *time.Time) error {
+		if !values.Has(name) {
+			return nil
+		}
+		parsed, err := time.Parse(time.RFC3339, values.Get(name))
+		if err != nil {
+			return fmt.Errorf("%s must be an RFC 3339 time, got %q", name, values.Get(name))
+		}
+		*bound = parsed
+		return nil
+	}
+	parseCount := func(name string, n *int) error {
+		if !values.Has(name) {
+			return nil
+		}
+		parsed, err := strconv.Atoi(values.Get(name))
+		if err != nil || parsed < 0 {
+			return fmt.Errorf("%s must be a non-negative integer, got %q", name, values.Get(name))
+		}
+		*n = parsed
+		return nil
+	}
+	parseBool := func(name string) (*bool, error) {
+		if !values.Has(name) {
+			return nil, nil
+		}
+		parsed, err := strconv.ParseBool(values.Get(name))
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false, got %q", name, values.Get(name))
+		}
+		return &parsed, nil
+	}
+
+	bodies, err := parseBool("bodies")
+	if err != nil {
+		return RequestQuery{}, err
+	}
+	query.OmitBodies = bodies != nil && !*bodies
+	if query.Matched, err = parseBool("matched"); err != nil {
+		return RequestQuery{}, err
+	}
+	for _, err := range []error{
+		parseTime("since", &query.Since), parseTime("until", &query.Until),
+		parseCount("offset", &query.Offset), parseCount("limit", &query.Limit),
+	} {
+		if err != nil {
+			return RequestQuery{}, err
+		}
+	}
+	return query, nil
+}
+
+func (s *Server) handleAdminRequests(w http.ResponseWriter, r *http.Request) {
+	query, err := parseRequestQuery(r.URL.Query())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	records, total := s.QueryRequests(query)
+	w.Header().Set(totalCountHeader, strconv.Itoa(total))
+	writeJSON(w, http.StatusOK, records)
+}
@@ -0,0 +1,141 @@
+package mockllm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+)
+
+// resolveResponseFiles replaces the response_file member of the mocks of a config document with
+// the response read from that file, resolved relative to the config file. JSON files hold the
+// response itself, and other files the text of a reply, so large canned outputs stay out of the
+// config and can be shared by several mocks. The body_file of raw and HTTP mock responses is read
+// the same way, becoming their body_base64.
+func resolveResponseFiles(filesys fs.FS, name string, document map[string]any) error {
+	resolve := func(section, kind string, mocks any) error {
+		list, _ := mocks.([]any)
+		for i, mock := range list {
+			object, ok := mock.(map[string]any)
+			if !ok {
+				continue
+			}
+			raw, _ := object["raw"].(map[string]any)
+			if err := resolveBodyFile(filesys, name, fmt.Sprintf("%s[%d].raw", section, i), raw); err != nil {
+				return err
+			}
+			if object["response_file"] == nil {
+				continue
+			}
+			where := fmt.Sprintf("%s: %s[%d].response_file", name, section, i)
+			file, ok := object["response_file"].(string)
+			if !ok || file == "" {
+				return fmt.Errorf("%s: must be a file path", where)
+			}
+			if object["response"] != nil {
+				return fmt.Errorf("%s: response and response_file are mutually exclusive", where)
+			}
+			response, err := readResponseFile(filesys, path.Join(path.Dir(name), file), kind)
+			if err != nil {
+				return fmt.Errorf("%s: %w", where, err)
+			}
+			object["response"] = response
+			delete(object, "response_file")
+		}
+		return nil
+	}
+
+	for _, section := range []string{"openai", "anthropic", "gemini", "openai_responses"} {
+		if err := resolve(section, section, document[section]); err != nil {
+			return err
+		}
+	}
+	httpMocks, _ := document["http"].([]any)
+	for i, mock := range httpMocks {
+		object, _ := mock.(map[string]any)
+		response, _ := object["response"].(map[string]any)
+		if err := resolveBodyFile(filesys, name, fmt.Sprintf("http[%d].response", i), response); err != nil {
+			return err
+		}
+	}
+	tenants, _ := document["tenants"].([]any)
+	for i, tenant := range tenants {
+		object, _ := tenant.(map[string]any)
+		for _, section := range []string{"openai", "anthropic"} {
+			if err := resolve(fmt.Sprintf("tenants[%d].%s", i, section), section, object[section]); err != nil {
+				return err
+			}
+		}
+	}
+	organizations, _ := document["openai_organizations"].([]any)
+	for i, organization := range organizations {
+		object, _ := organization.(map[string]any)
+		section := fmt.Sprintf("openai_organizations[%d]", i)
+		if err := resolve(section+".openai", providerOpenAI, object["openai"]); err != nil {
+			return err
+		}
+		projects, _ := object["projects"].([]any)
+		for j, project := range projects {
+			object, _ := project.(map[string]any)
+			if err := resolve(fmt.Sprintf("%s.projects[%d].openai", section, j), providerOpenAI, object["openai"]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readResponseFile reads the response of a mock of the kind, the config section of the mock such as
+// openai or openai_responses, from a file, a JSON response when the file has the .json extension
+// and otherwise the text of a reply
+func readResponseFile(filesys fs.FS, name, kind string) (any, error) {
+	data, err := fs.ReadFile(filesys, name)
+	if err != nil {
+		return nil, err
+	}
+	if path.Ext(name) == ".json" {
+		var response any
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&response); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		return response, nil
+	}
+
+	text := string(data)
+	switch kind {
+	case providerAnthropic:
+		return map[string]any{"content": []any{map[string]any{"type": "text", "text": text}}}, nil
+	case providerGemini:
+		return map[string]any{"candidates": []any{map[string]any{"content": map[string]any{
+			"role": "model", "parts": []any{map[string]any{"text": text}}}}}}, nil
+	case "openai_responses":
+		return map[string]any{"output": []any{map[string]any{"type": "message", "text": text}}}, nil
+	}
+	return map[string]any{"choices": []any{map[string]any{"message": map[string]any{"role": "assistant", "content": text}}}}, nil
+}
+
+// resolveBodyFile replaces the body_file member of a raw or HTTP mock response with the
+// body_base64 encoded content of the file, resolved relative to the config file
+func resolveBodyFile(filesys fs.FS, name, where string, response map[string]any) error {
+	if response == nil || response["body_file"] == nil {
+		return nil
+	}
+	file, ok := response["body_file"].(string)
+	if !ok || file == "" {
+		return fmt.Errorf("%s: %s.body_file: must be a file path", name, where)
+	}
+	if response["body_base64"] != nil {
+		return fmt.Errorf("%s: %s.body_file: body_base64 and body_file are mutually exclusive", name, where)
+	}
+	data, err := fs.ReadFile(filesys, path.Join(path.Dir(name), file))
+	if err != nil {
+		return fmt.Errorf("%s: %s.body_file: %w", name, where, err)
+	}
+	response["body_base64"] = base64.StdEncoding.EncodeToString(data)
+	delete(response, "body_file")
+	return nil
+}
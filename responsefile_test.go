@@ -0,0 +1,74 @@
+package mockllm_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseFiles(t *testing.T) {
+	filesys := fstest.MapFS{
+		"suite/main.json": {Data: []byte(`{
+  "include": ["common/*.json"],
+  "openai": [
+    {"name": "report", "response_file": "responses/report.txt"},
+    {"name": "json", "response_file": "responses/completion.json"}
+  ],
+  "anthropic": [{"name": "report", "response_file": "responses/report.txt"}],
+  "gemini": [{"name": "report", "match": {"match_type": "contains", "text": "report"}, "response_file": "responses/report.txt"}],
+  "openai_responses": [{"name": "report", "match": {"match_type": "contains", "text": "report"}, "response_file": "responses/report.txt"}],
+  "openai_organizations": [{"id": "org-a", "openai": [{"name": "org", "response_file": "responses/report.txt"}],
+    "projects": [{"id": "proj-1", "openai": [{"name": "project", "response_file": "responses/report.txt"}]}]}]
+}`)},
+		"suite/common/shared.json": {Data: []byte(`{
+  "tenants": [{"name": "team-a", "api_keys": ["key-a"], "openai": [{"name": "shared", "response_file": "../responses/report.txt"}]}]
+}`)},
+		"suite/responses/report.txt":      {Data: []byte("A very long report.\n")},
+		"suite/responses/completion.json": {Data: []byte(`{"model": "gpt-4o", "choices": [{"message": {"content": "From JSON"}}]}`)},
+	}
+	config, err := mockllm.LoadConfigFromFile("suite/main.json", filesys)
+	require.NoError(t, err)
+
+	require.Len(t, config.OpenAI, 2)
+	require.Len(t, config.OpenAI[0].Response.Choices, 1)
+	assert.Equal(t, "A very long report.\n", config.OpenAI[0].Response.Choices[0].Message.Content)
+	assert.Equal(t, "gpt-4o", config.OpenAI[1].Response.Model)
+	assert.Equal(t, "From JSON", config.OpenAI[1].Response.Choices[0].Message.Content)
+	require.Len(t, config.Anthropic, 1)
+	require.Len(t, config.Anthropic[0].Response.Content, 1)
+	assert.Equal(t, "A very long report.\n", config.Anthropic[0].Response.Content[0].Text)
+	require.Len(t, config.Gemini, 1)
+	require.Len(t, config.Gemini[0].Response.Candidates, 1)
+	assert.Equal(t, "A very long report.\n", config.Gemini[0].Response.Candidates[0].Content.Parts[0].Text)
+	require.Len(t, config.OpenAIResponses, 1)
+	require.Len(t, config.OpenAIResponses[0].Response.Output, 1)
+	assert.Equal(t, "A very long report.\n", config.OpenAIResponses[0].Response.Output[0].Text)
+	require.Len(t, config.OpenAIOrganizations, 1)
+	organization := config.OpenAIOrganizations[0]
+	assert.Equal(t, "A very long report.\n", organization.OpenAI[0].Response.Choices[0].Message.Content)
+	assert.Equal(t, "A very long report.\n", organization.Projects[0].OpenAI[0].Response.Choices[0].Message.Content)
+	require.Len(t, config.Tenants, 1)
+	require.Len(t, config.Tenants[0].OpenAI, 1)
+	assert.Equal(t, "A very long report.\n", config.Tenants[0].OpenAI[0].Response.Choices[0].Message.Content,
+		"response files are resolved relative to the config file naming them")
+}
+
+func TestResponseFileErrors(t *testing.T) {
+	for message, mock := range map[string]string{
+		"a.json: openai[0].response_file: open missing.txt: file does not exist":             `{"name": "a", "response_file": "missing.txt"}`,
+		"a.json: openai[0].response_file: response and response_file are mutually exclusive": `{"name": "a", "response_file": "b.txt", "response": {}}`,
+		"a.json: openai[0].response_file: failed to parse broken.json":                       `{"name": "a", "response_file": "broken.json"}`,
+		"a.json: openai[0].raw.body_file: open missing.bin: file does not exist":             `{"name": "a", "raw": {"body_file": "missing.bin"}}`,
+	} {
+		_, err := mockllm.LoadConfigFromFile("a.json", fstest.MapFS{
+			"a.json":      {Data: []byte(`{"openai": [` + mock + `]}`)},
+			"b.txt":       {Data: []byte("B")},
+			"broken.json": {Data: []byte("{")},
+		})
+		require.Error(t, err, message)
+		assert.Contains(t, err.Error(), message)
+	}
+}
@@ -2,11 +2,85 @@ package mockllm
 
 import (
 	"context"
+	"math/rand/v2"
 	"time"
 )
 
+// RetryPolicy retries a function with exponential backoff, BaseDelay*(2^attempt) up to MaxDelay
+type RetryPolicy struct {
+	// Attempts is the maximum number of calls, including the first one
+	Attempts  int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Jitter randomizes every delay by up to this fraction of it, e.g. 0.2 for ±20%
+	Jitter float64
+	// OnRetry is called after every failed attempt that is retried, with the number of the
+	// attempt starting at 1, its error and the delay before the next attempt
+	OnRetry func(attempt int, err error, delay time.Duration)
+	// Clock waits between attempts. Defaults to the system clock.
+	Clock Clock
+}
+
+// Delay returns the backoff after the failed attempt with the given zero-based index, before jitter
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	if attempt >= 62 {
+		return p.MaxDelay
+	}
+	delay := p.BaseDelay * (1 << uint(attempt))
+	if delay > p.MaxDelay || delay < 0 {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// Do calls f until it succeeds or the attempts run out, returning the last error. Waits between
+// attempts end as soon as ctx is done, returning the context's error.
+func (p RetryPolicy) Do(ctx context.Context, f func() error) error {
+	return p.retry(ctx, func(bool) (time.Duration, bool, error) {
+		err := f()
+		return 0, err != nil, err
+	})
+}
+
+// retry calls f until it reports no retry or the attempts run out, telling f whether it makes the
+// last attempt. f returns the delay the callee asked for, used instead of the backoff when
+// positive, whether to retry, and its error.
+func (p RetryPolicy) retry(ctx context.Context, f func(last bool) (time.Duration, bool, error)) error {
+	clock := p.Clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+
+	var err error
+	for i := 0; i < p.Attempts; i++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		requested, retry, attemptErr := f(i == p.Attempts-1)
+		if err = attemptErr; !retry || i == p.Attempts-1 {
+			break
+		}
+
+		delay := requested
+		if delay <= 0 {
+			delay = p.Delay(i)
+			if p.Jitter > 0 {
+				delay += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(delay))
+			}
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(i+1, err, delay)
+		}
+		if !clock.Sleep(ctx, delay) {
+			return ctx.Err()
+		}
+	}
+	return err
+}
+
 // RetryWithBackoff executes the given function with retries on an error, up to the given number
-// of attempts with exponential backoff baseDelay*(2^attempt) up to maxDelay
+// of attempts with exponential backoff baseDelay*(2^attempt) up to maxDelay. See RetryPolicy for
+// jitter, retry callbacks and a fake clock.
 func RetryWithBackoff(
 	ctx context.Context,
 	attempts int,
@@ -14,22 +88,5 @@ func RetryWithBackoff(
 	maxDelay time.Duration,
 	f func() error,
 ) error {
-	var err error
-	for i := 0; i < attempts; i++ {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			if err = f(); err == nil {
-				return nil
-			}
-			// exponential backoff
-			delay := baseDelay * (1 << uint(i))
-			if delay > maxDelay {
-				delay = maxDelay
-			}
-			time.Sleep(delay)
-		}
-	}
-	return err
+	return RetryPolicy{Attempts: attempts, BaseDelay: baseDelay, MaxDelay: maxDelay}.Do(ctx, f)
 }
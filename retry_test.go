@@ -0,0 +1,84 @@
+package mockllm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	clock := mockllm.NewFakeClock(time.Now())
+	var delays []time.Duration
+	policy := mockllm.RetryPolicy{
+		Attempts:  4,
+		BaseDelay: time.Second,
+		MaxDelay:  3 * time.Second,
+		Clock:     clock,
+		OnRetry: func(attempt int, err error, delay time.Duration) {
+			delays = append(delays, delay)
+			// Let the next attempt run right away
+			go func() {
+				require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+				clock.Advance(delay)
+			}()
+		},
+	}
+
+	calls := 0
+	err := policy.Do(t.Context(), func() error {
+		calls++
+		return errors.New("unavailable")
+	})
+	assert.EqualError(t, err, "unavailable")
+	assert.Equal(t, 4, calls)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}, delays)
+
+	policy = mockllm.RetryPolicy{Attempts: 1000, BaseDelay: time.Second, MaxDelay: time.Minute, Jitter: 0.5}
+	for attempt := range 10 {
+		delay := policy.Delay(attempt)
+		assert.LessOrEqual(t, delay, time.Minute)
+		assert.Positive(t, delay)
+	}
+	assert.Equal(t, time.Minute, policy.Delay(100))
+}
+
+func TestRetryPolicyJitter(t *testing.T) {
+	clock := mockllm.NewFakeClock(time.Now())
+	var delays []time.Duration
+	policy := mockllm.RetryPolicy{
+		Attempts:  2,
+		BaseDelay: time.Second,
+		MaxDelay:  time.Second,
+		Jitter:    0.2,
+		Clock:     clock,
+		OnRetry: func(_ int, _ error, delay time.Duration) {
+			delays = append(delays, delay)
+			go func() {
+				require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+				clock.Advance(delay)
+			}()
+		},
+	}
+	for range 20 {
+		policy.Do(t.Context(), func() error { return errors.New("unavailable") }) //nolint:errcheck
+	}
+	require.Len(t, delays, 20)
+	for _, delay := range delays {
+		assert.InDelta(t, float64(time.Second), float64(delay), float64(200*time.Millisecond))
+	}
+}
+
+func TestRetryWithBackoffHonorsContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := mockllm.RetryWithBackoff(ctx, 3, time.Hour, time.Hour, func() error { return errors.New("unavailable") })
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
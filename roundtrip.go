@@ -0,0 +1,153 @@
+package mockllm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// RoundTripper answers requests with a mock server in process, without any network I/O. Inject it
+// into an SDK through its HTTP client option, with any base URL:
+//
+//	transport := mockllm.NewRoundTripper(config)
+//	client := openai.NewClient(option.WithBaseURL("http://mockllm/v1"), option.WithHTTPClient(transport.Client()))
+type RoundTripper struct {
+	// Server is the mock server answering the requests, for inspecting its request log
+	Server *Server
+}
+
+// NewRoundTripper creates a RoundTripper answering requests with a new server for the config
+func NewRoundTripper(config Config) *RoundTripper {
+	return &RoundTripper{Server: NewServer(WithConfig(config))}
+}
+
+// Client returns an HTTP client sending its requests to the mock server
+func (t *RoundTripper) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+// errConnectionClosed is returned for responses that a fault cut off before their headers
+var errConnectionClosed = errors.New("mockllm: connection closed by the server")
+
+// RoundTrip serves the request with the server's handler. The response body streams what the
+// handler writes as it writes it, so streamed responses and faults behave as over a connection.
+func (t *RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	serverReq := req.Clone(req.Context())
+	serverReq.RequestURI = req.URL.RequestURI()
+	serverReq.RemoteAddr = "127.0.0.1:0"
+	if serverReq.Host == "" {
+		serverReq.Host = req.URL.Host
+	}
+	if serverReq.Body == nil {
+		serverReq.Body = http.NoBody
+	}
+
+	body, bodyWriter := io.Pipe()
+	w := &roundTripWriter{header: http.Header{}, body: bodyWriter, headers: make(chan struct{})}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer serverReq.Body.Close() //nolint:errcheck
+		t.Server.Handler().ServeHTTP(w, serverReq)
+		w.finish()
+	}()
+
+	select {
+	case <-w.headers:
+	case <-done:
+	case <-req.Context().Done():
+		body.CloseWithError(req.Context().Err()) //nolint:errcheck
+		return nil, req.Context().Err()
+	}
+	status, header, ok := w.response()
+	if !ok {
+		return nil, errConnectionClosed
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          body,
+		ContentLength: -1,
+		Request:       req,
+	}, nil
+}
+
+// roundTripWriter is the response writer of an in-process request, piping the body to the client
+type roundTripWriter struct {
+	header http.Header
+	body   *io.PipeWriter
+
+	mu          sync.Mutex
+	status      int
+	sent        http.Header
+	wroteHeader bool
+	closed      bool
+	// headers is closed once the status and headers are sent
+	headers chan struct{}
+}
+
+func (w *roundTripWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *roundTripWriter) WriteHeader(status int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.wroteHeader || w.closed || status < http.StatusOK {
+		return
+	}
+	w.wroteHeader, w.status, w.sent = true, status, w.header.Clone()
+	close(w.headers)
+}
+
+func (w *roundTripWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(p)
+}
+
+// Flush sends the headers, the body is unbuffered
+func (w *roundTripWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+}
+
+// Hijack hands out a connection whose Close cuts the response off, as closing a real one would
+func (w *roundTripWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return abortConn{abort: func() {
+		w.mu.Lock()
+		w.closed = true
+		w.mu.Unlock()
+		w.body.CloseWithError(io.ErrUnexpectedEOF) //nolint:errcheck
+	}}, nil, nil
+}
+
+// finish completes the response once the handler returned
+func (w *roundTripWriter) finish() {
+	w.WriteHeader(http.StatusOK)
+	w.body.Close() //nolint:errcheck
+}
+
+// response returns the status and headers sent, or false when the connection was closed first
+func (w *roundTripWriter) response() (int, http.Header, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status, w.sent, w.wroteHeader
+}
+
+// abortConn is the connection of a hijacked in-process response, only supporting Close
+type abortConn struct {
+	net.Conn
+	abort func()
+}
+
+func (c abortConn) Close() error {
+	c.abort()
+	return nil
+}
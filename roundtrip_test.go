@@ -0,0 +1,92 @@
+package mockllm_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	openaioption "github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTripper(t *testing.T) {
+	transport := mockllm.NewRoundTripper(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{
+			{
+				Name:     "hello",
+				Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+				Response: helloCompletion,
+			},
+			{
+				Name:     "closed",
+				Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Close")},
+				Response: helloCompletion,
+				Fault:    &mockllm.Fault{Type: mockllm.FaultClose},
+			},
+			{
+				Name:     "stalled",
+				Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Stall")},
+				Response: helloCompletion,
+				Fault:    &mockllm.Fault{Type: mockllm.FaultStall, Duration: mockllm.Duration(time.Hour), AfterEvents: 1},
+			},
+		},
+		Anthropic: []mockllm.AnthropicMock{{
+			Name:     "hello",
+			Match:    mockllm.AnthropicRequestMatch{MatchType: mockllm.MatchTypeExact, Message: anthropicHelloRequest.Messages[0]},
+			Response: anthropic.Message{Content: []anthropic.ContentBlockUnion{{Type: "text", Text: "Hi!"}}},
+		}},
+	})
+	openaiClient := openai.NewClient(
+		openaioption.WithBaseURL("http://mockllm/v1"),
+		openaioption.WithAPIKey("test-key"),
+		openaioption.WithMaxRetries(0),
+		openaioption.WithHTTPClient(transport.Client()),
+	)
+
+	completion, err := openaiClient.Chat.Completions.New(t.Context(), openai.ChatCompletionNewParams{
+		Model:    "gpt-4o-mini",
+		Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello there, how can I help?", completion.Choices[0].Message.Content)
+
+	stream := openaiClient.Chat.Completions.NewStreaming(t.Context(), helloParams)
+	var acc openai.ChatCompletionAccumulator
+	for stream.Next() {
+		acc.AddChunk(stream.Current())
+	}
+	require.NoError(t, stream.Err())
+	assert.Equal(t, "Hello there, how can I help?", acc.Choices[0].Message.Content)
+
+	anthropicClient := anthropic.NewClient(
+		anthropicoption.WithBaseURL("http://mockllm"),
+		anthropicoption.WithAPIKey("test-key"),
+		anthropicoption.WithMaxRetries(0),
+		anthropicoption.WithHTTPClient(transport.Client()),
+	)
+	message, err := anthropicClient.Messages.New(t.Context(), anthropicHelloRequest)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi!", message.Content[0].Text)
+
+	_, err = openaiClient.Chat.Completions.New(t.Context(), openai.ChatCompletionNewParams{
+		Model:    "gpt-4o-mini",
+		Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Close")},
+	})
+	assert.Error(t, err, "closing the connection fails the request")
+
+	// Cancelling a stream reaches the server as a disconnect
+	ctx, cancel := context.WithCancel(t.Context())
+	stream = openaiClient.Chat.Completions.NewStreaming(ctx, openai.ChatCompletionNewParams{
+		Model:    "gpt-4o-mini",
+		Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Stall")},
+	})
+	require.True(t, stream.Next())
+	cancel()
+	assert.False(t, stream.Next())
+	assert.Eventually(t, func() bool { return transport.Server.DisconnectedMidStream("stalled") }, time.Second, 5*time.Millisecond)
+}
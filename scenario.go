@@ -0,0 +1,250 @@
+package mockllm
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+)
+
+// scenario is one scenario of a scenario file, before it is compiled into mocks
+type scenario struct {
+	name      string
+	says      *string
+	tools     []string
+	reply     string
+	toolCalls []scenarioToolCall
+	expect    []RequestExpectation
+	retry     *StreamRetry
+}
+
+// scenarioToolCall is a tool call a scenario replies with
+type scenarioToolCall struct {
+	name      string
+	arguments json.RawMessage
+}
+
+// ParseScenarios compiles a scenario file into OpenAI and Anthropic mocks. Scenario files are a
+// human readable alternative to JSON configs:
+//
+//	Feature: cluster assistant
+//
+//	  Scenario: list nodes
+//	    When the user says "list the nodes"
+//	    Then call tool "get_resources" with {"kind": "node"}
+//
+//	  Scenario: summarize nodes
+//	    When the user says "list the nodes"
+//	    And tool "get_resources" has been called
+//	    Then reply "There are 3 nodes."
+//
+// Steps start with Given, When, Then, And or But. The conditions are `the user says "text"`,
+// matched against the latest user message, and `tool "name" has been called`. The actions are
+// `reply "text"` and `call tool "name"`, optionally followed by `with` and JSON arguments.
+// `cut the stream after N events and restart it`, or `resume it`, cuts the first streamed attempt
+// of the reply and serves its retry as a StreamRetry does.
+// Scenarios with more tool conditions are tried first, so a conversation moves on to the next
+// scenario once its tools were called. The assertions `the request includes "text"`, `the request
+// does not include "text"` and `the request includes the result of tool "name"` don't affect
+// matching, they become expectations reported by Server.Verify. Lines starting with # are comments.
+func ParseScenarios(filename string, data []byte) (Config, error) {
+	var scenarios []*scenario
+	var current *scenario
+	lineNumber := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "Feature:") {
+			continue
+		}
+		if name, ok := strings.CutPrefix(line, "Scenario:"); ok {
+			current = &scenario{name: strings.TrimSpace(name)}
+			if current.name == "" {
+				return Config{}, fmt.Errorf("%s:%d: scenario has no name", filename, lineNumber)
+			}
+			scenarios = append(scenarios, current)
+			continue
+		}
+		if current == nil {
+			return Config{}, fmt.Errorf("%s:%d: step outside of a scenario", filename, lineNumber)
+		}
+		if err := current.parseStep(line); err != nil {
+			return Config{}, fmt.Errorf("%s:%d: %w", filename, lineNumber, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Config{}, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+
+	for _, s := range scenarios {
+		if s.says == nil && len(s.tools) == 0 {
+			return Config{}, fmt.Errorf("%s: scenario %q has no condition", filename, s.name)
+		}
+		if s.reply == "" && len(s.toolCalls) == 0 {
+			return Config{}, fmt.Errorf("%s: scenario %q has no reply", filename, s.name)
+		}
+	}
+	slices.SortStableFunc(scenarios, func(a, b *scenario) int {
+		return cmp.Compare(len(b.tools), len(a.tools))
+	})
+
+	var config Config
+	for _, s := range scenarios {
+		config.OpenAI = append(config.OpenAI, s.openaiMock())
+		config.Anthropic = append(config.Anthropic, s.anthropicMock())
+	}
+	return config, nil
+}
+
+// parseStep adds a condition or an action to the scenario
+func (s *scenario) parseStep(line string) error {
+	keyword, step, _ := strings.Cut(line, " ")
+	if !slices.Contains([]string{"Given", "When", "Then", "And", "But"}, keyword) {
+		return fmt.Errorf("step must start with Given, When, Then, And or But: %q", line)
+	}
+	step = strings.TrimSpace(step)
+
+	switch {
+	case strings.HasPrefix(step, "the user says "):
+		text, rest, err := quoted(strings.TrimPrefix(step, "the user says "))
+		if err != nil || rest != "" {
+			return fmt.Errorf("expected the user says \"text\": %q", line)
+		}
+		s.says = &text
+	case strings.HasPrefix(step, "tool "):
+		name, rest, err := quoted(strings.TrimPrefix(step, "tool "))
+		if err != nil || rest != "has been called" {
+			return fmt.Errorf("expected tool \"name\" has been called: %q", line)
+		}
+		s.tools = append(s.tools, name)
+	case strings.HasPrefix(step, "reply "):
+		text, rest, err := quoted(strings.TrimPrefix(step, "reply "))
+		if err != nil || rest != "" {
+			return fmt.Errorf("expected reply \"text\": %q", line)
+		}
+		s.reply += text
+	case strings.HasPrefix(step, "call tool "):
+		name, rest, err := quoted(strings.TrimPrefix(step, "call tool "))
+		if err != nil {
+			return fmt.Errorf("expected call tool \"name\": %q", line)
+		}
+		arguments := json.RawMessage("{}")
+		if rest != "" {
+			raw, ok := strings.CutPrefix(rest, "with ")
+			if !ok || !json.Valid([]byte(raw)) {
+				return fmt.Errorf("expected call tool \"name\" with JSON arguments: %q", line)
+			}
+			arguments = json.RawMessage(raw)
+		}
+		s.toolCalls = append(s.toolCalls, scenarioToolCall{name: name, arguments: arguments})
+	case strings.HasPrefix(step, "cut the stream after "):
+		count, rest, _ := strings.Cut(strings.TrimPrefix(step, "cut the stream after "), " events and ")
+		cutAfter, err := strconv.Atoi(count)
+		modes := map[string]StreamRetryMode{"restart it": StreamRetryRestart, "resume it": StreamRetryResume}
+		mode, ok := modes[rest]
+		if err != nil || cutAfter < 0 || !ok {
+			return fmt.Errorf("expected cut the stream after N events and restart it, or resume it: %q", line)
+		}
+		s.retry = &StreamRetry{CutAfter: cutAfter, Mode: mode}
+	case strings.HasPrefix(step, "the request includes the result of tool "):
+		name, rest, err := quoted(strings.TrimPrefix(step, "the request includes the result of tool "))
+		if err != nil || rest != "" {
+			return fmt.Errorf("expected the request includes the result of tool \"name\": %q", line)
+		}
+		s.expect = append(s.expect, RequestExpectation{ToolResult: name})
+	case strings.HasPrefix(step, "the request includes "):
+		text, rest, err := quoted(strings.TrimPrefix(step, "the request includes "))
+		if err != nil || rest != "" {
+			return fmt.Errorf("expected the request includes \"text\": %q", line)
+		}
+		s.expect = append(s.expect, RequestExpectation{Contains: text})
+	case strings.HasPrefix(step, "the request does not include "):
+		text, rest, err := quoted(strings.TrimPrefix(step, "the request does not include "))
+		if err != nil || rest != "" {
+			return fmt.Errorf("expected the request does not include \"text\": %q", line)
+		}
+		s.expect = append(s.expect, RequestExpectation{NotContains: text})
+	default:
+		return fmt.Errorf("unknown step %q", line)
+	}
+	return nil
+}
+
+// quoted parses the Go quoted string at the start of s, returning it and the trimmed rest of s
+func quoted(s string) (string, string, error) {
+	prefix, err := strconv.QuotedPrefix(s)
+	if err != nil {
+		return "", "", err
+	}
+	text, err := strconv.Unquote(prefix)
+	return text, strings.TrimSpace(s[len(prefix):]), err
+}
+
+// said returns the text the latest user message must contain, matching any user message when the
+// scenario only has tool conditions
+func (s *scenario) said() string {
+	if s.says == nil {
+		return ""
+	}
+	return *s.says
+}
+
+// openaiMock compiles the scenario into an OpenAI mock
+func (s *scenario) openaiMock() OpenAIMock {
+	message := openai.ChatCompletionMessage{Content: s.reply}
+	for i, call := range s.toolCalls {
+		message.ToolCalls = append(message.ToolCalls, openai.ChatCompletionMessageToolCall{
+			ID: mockResponseID("call_", fmt.Sprintf("%s/%d", s.name, i)),
+			Function: openai.ChatCompletionMessageToolCallFunction{
+				Name:      call.name,
+				Arguments: string(call.arguments),
+			},
+		})
+	}
+	return OpenAIMock{
+		Name: s.name,
+		Match: OpenAIRequestMatch{
+			MatchType:   MatchTypeUserContains,
+			Message:     openai.UserMessage(s.said()),
+			ToolsCalled: s.tools,
+		},
+		Response:    openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: message}}},
+		Expect:      s.expect,
+		StreamRetry: s.retry,
+	}
+}
+
+// anthropicMock compiles the scenario into an Anthropic mock
+func (s *scenario) anthropicMock() AnthropicMock {
+	content := []anthropic.ContentBlockUnion{}
+	if s.reply != "" {
+		content = append(content, anthropic.ContentBlockUnion{Type: "text", Text: s.reply})
+	}
+	for i, call := range s.toolCalls {
+		content = append(content, anthropic.ContentBlockUnion{
+			Type:  "tool_use",
+			ID:    mockResponseID("toolu_", fmt.Sprintf("%s/%d", s.name, i)),
+			Name:  call.name,
+			Input: call.arguments,
+		})
+	}
+	return AnthropicMock{
+		Name: s.name,
+		Match: AnthropicRequestMatch{
+			MatchType:   MatchTypeUserContains,
+			Message:     anthropic.NewUserMessage(anthropic.NewTextBlock(s.said())),
+			ToolsCalled: s.tools,
+		},
+		Response:    anthropic.Message{Content: content},
+		Expect:      s.expect,
+		StreamRetry: s.retry,
+	}
+}
@@ -0,0 +1,140 @@
+package mockllm_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"testing/fstest"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const nodesFeature = `Feature: cluster assistant
+
+  # The agent looks the nodes up before answering
+  Scenario: list nodes
+    When the user says "list the nodes"
+    Then call tool "get_resources" with {"kind": "node"}
+
+  Scenario: summarize nodes
+    When the user says "list the nodes"
+    And tool "get_resources" has been called
+    Then reply "There are 3 nodes."
+`
+
+func TestScenarios(t *testing.T) {
+	config, err := mockllm.LoadConfigFromFile("nodes.feature", fstest.MapFS{
+		"nodes.feature": {Data: []byte(nodesFeature)},
+	})
+	require.NoError(t, err)
+	require.Len(t, config.OpenAI, 2)
+	assert.Equal(t, "summarize nodes", config.OpenAI[0].Name)
+
+	server := mockllm.NewServer(mockllm.WithConfig(config))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	messages := []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Please list the nodes")}
+	completion := postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{Model: "gpt-4o", Messages: messages})
+	require.Len(t, completion.Choices, 1)
+	require.Len(t, completion.Choices[0].Message.ToolCalls, 1)
+	toolCall := completion.Choices[0].Message.ToolCalls[0]
+	assert.Equal(t, "get_resources", toolCall.Function.Name)
+	assert.JSONEq(t, `{"kind": "node"}`, toolCall.Function.Arguments)
+	assert.Equal(t, "tool_calls", completion.Choices[0].FinishReason)
+
+	messages = append(messages, completion.Choices[0].Message.ToParam(), openai.ToolMessage("node-a node-b node-c", toolCall.ID))
+	completion = postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{Model: "gpt-4o", Messages: messages})
+	require.Len(t, completion.Choices, 1)
+	assert.Equal(t, "There are 3 nodes.", completion.Choices[0].Message.Content)
+	assert.Empty(t, completion.Choices[0].Message.ToolCalls)
+
+	resp := postJSON(t, baseURL+"/v1/messages", json.RawMessage(`{
+		"model": "claude-sonnet-4-0",
+		"max_tokens": 100,
+		"messages": [
+			{"role": "user", "content": [{"type": "text", "text": "list the nodes"}]},
+			{"role": "assistant", "content": [{"type": "tool_use", "id": "toolu_1", "name": "get_resources", "input": {}}]},
+			{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "toolu_1", "content": "node-a"}]}
+		]
+	}`), anthropicHeaders("2023-06-01"))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var message anthropic.Message
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&message))
+	require.Len(t, message.Content, 1)
+	assert.Equal(t, "There are 3 nodes.", message.Content[0].Text)
+	assert.Equal(t, anthropic.StopReasonEndTurn, message.StopReason)
+}
+
+func TestScenarioErrors(t *testing.T) {
+	for name, feature := range map[string]string{
+		"nodes.feature:2: unknown step":                  "Scenario: a\n  When the moon is full\n",
+		"nodes.feature:1: step outside":                  "When the user says \"hi\"\n",
+		"nodes.feature:3: expected call tool":            "Scenario: a\n  When the user says \"hi\"\n  Then call tool \"x\" with {oops\n",
+		`scenario "a" has no reply`:                      "Scenario: a\n  When the user says \"hi\"\n",
+		`nodes.feature: scenario "a" has no condition`:   "Scenario: a\n  Then reply \"hi\"\n",
+		"nodes.feature:3: expected the request includes": "Scenario: a\n  When the user says \"hi\"\n  Then the request includes hi\n",
+		"nodes.feature:3: expected cut the stream":       "Scenario: a\n  When the user says \"hi\"\n  Then cut the stream after 2 events and retry\n",
+	} {
+		_, err := mockllm.ParseScenarios("nodes.feature", []byte(feature))
+		require.Error(t, err, name)
+		assert.Contains(t, err.Error(), name)
+	}
+}
+
+func TestScenarioExpectations(t *testing.T) {
+	config, err := mockllm.ParseScenarios("nodes.feature", []byte(`Scenario: summarize nodes
+  When the user says "list the nodes"
+  And tool "get_resources" has been called
+  Then the request includes the result of tool "get_resources"
+  And the request includes "node-a"
+  But the request does not include "secret"
+  Then reply "There are 3 nodes."
+`))
+	require.NoError(t, err)
+	server := mockllm.NewServer(mockllm.WithConfig(config))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	summarize := func(result string) {
+		resp := postJSON(t, baseURL+"/v1/messages", json.RawMessage(`{
+			"model": "claude-sonnet-4-0",
+			"max_tokens": 100,
+			"messages": [
+				{"role": "user", "content": [{"type": "text", "text": "list the nodes"}]},
+				{"role": "assistant", "content": [{"type": "tool_use", "id": "toolu_1", "name": "get_resources", "input": {}}]},
+				{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "toolu_1", "content": [{"type": "text", "text": `+strconv.Quote(result)+`}]}]}
+			]
+		}`), anthropicHeaders("2023-06-01"))
+		resp.Body.Close() //nolint:errcheck
+		require.Equal(t, http.StatusOK, resp.StatusCode, "expectations don't affect the response")
+	}
+
+	summarize("node-a node-b")
+	require.NoError(t, server.Verify())
+	summarize("")
+	err = server.Verify()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `request #2 (anthropic summarize nodes): request does not include a result of tool "get_resources"`)
+	assert.Contains(t, err.Error(), `request does not include "node-a"`)
+	assert.Len(t, server.Requests()[1].ExpectationFailures, 2)
+
+	messages := []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage("The secret is 42."),
+		openaiUserMessage("list the nodes"),
+		{OfAssistant: &openai.ChatCompletionAssistantMessageParam{ToolCalls: []openai.ChatCompletionMessageToolCallParam{{
+			ID: "call_1", Function: openai.ChatCompletionMessageToolCallFunctionParam{Name: "get_resources", Arguments: "{}"},
+		}}}},
+		openai.ToolMessage("node-a", "call_1"),
+	}
+	postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{Model: "gpt-4o", Messages: messages})
+	assert.Equal(t, []string{`request includes "secret"`}, server.Requests()[2].ExpectationFailures)
+}
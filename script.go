@@ -0,0 +1,93 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"fmt"
+
+	starlarkjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// scriptMaxSteps bounds the execution of a script, so a runaway loop fails the request instead of
+// hanging it
+const scriptMaxSteps = 10_000_000
+
+// runScript executes a Starlark script defining respond(request), called with the decoded JSON
+// request body. It returns the reply text when respond returns a string, otherwise the JSON
+// encoding of the returned value.
+func runScript(name, source string, body []byte) (text string, response []byte, err error) {
+	thread := &starlark.Thread{Name: name}
+	thread.SetMaxExecutionSteps(scriptMaxSteps)
+	predeclared := starlark.StringDict{"json": starlarkjson.Module}
+
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, name, source, predeclared)
+	if err != nil {
+		return "", nil, err
+	}
+	respond, ok := globals["respond"].(starlark.Callable)
+	if !ok {
+		return "", nil, fmt.Errorf("script does not define a respond(request) function")
+	}
+
+	decode := starlarkjson.Module.Members["decode"]
+	request, err := starlark.Call(thread, decode, starlark.Tuple{starlark.String(body)}, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	result, err := starlark.Call(thread, respond, starlark.Tuple{request}, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	if s, ok := result.(starlark.String); ok {
+		return string(s), nil, nil
+	}
+
+	encoded, err := starlark.Call(thread, starlarkjson.Module.Members["encode"], starlark.Tuple{result}, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	return "", []byte(encoded.(starlark.String)), nil
+}
+
+// scriptedOpenAIMock returns a copy of the mock with the response computed by its script
+func scriptedOpenAIMock(mock *OpenAIMock, body []byte) (*OpenAIMock, error) {
+	text, response, err := runScript(mock.Name, mock.Script, body)
+	if err != nil {
+		return nil, fmt.Errorf("script of mock %q failed: %w", mock.Name, err)
+	}
+	return computedOpenAIMock(mock, text, response)
+}
+
+// scriptedAnthropicMock returns a copy of the mock with the response computed by its script
+func scriptedAnthropicMock(mock *AnthropicMock, body []byte) (*AnthropicMock, error) {
+	text, response, err := runScript(mock.Name, mock.Script, body)
+	if err != nil {
+		return nil, fmt.Errorf("script of mock %q failed: %w", mock.Name, err)
+	}
+	return computedAnthropicMock(mock, text, response)
+}
+
+// computedOpenAIMock returns a copy of the mock with a computed reply text replacing the content
+// of every choice or, when response is set, a computed JSON response replacing the whole response
+func computedOpenAIMock(mock *OpenAIMock, text string, response []byte) (*OpenAIMock, error) {
+	computed := *mock
+	if response == nil {
+		setOpenAIContent(&computed.Response, text)
+	} else if err := json.Unmarshal(response, &computed.Response); err != nil {
+		return nil, fmt.Errorf("mock %q computed an invalid response: %w", mock.Name, err)
+	}
+	return &computed, nil
+}
+
+// computedAnthropicMock returns a copy of the mock with a computed reply text replacing its
+// content or, when response is set, a computed JSON response replacing the whole response
+func computedAnthropicMock(mock *AnthropicMock, text string, response []byte) (*AnthropicMock, error) {
+	computed := *mock
+	if response == nil {
+		setAnthropicContent(&computed.Response, text)
+	} else if err := json.Unmarshal(response, &computed.Response); err != nil {
+		return nil, fmt.Errorf("mock %q computed an invalid response: %w", mock.Name, err)
+	}
+	return &computed, nil
+}
@@ -0,0 +1,63 @@
+package mockllm_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScriptedResponses(t *testing.T) {
+	scripted := func(name, script string) mockllm.OpenAIMock {
+		return mockllm.OpenAIMock{
+			Name:   name,
+			Match:  mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage(name)},
+			Script: script,
+		}
+	}
+	baseURL := newOpenAIServer(t,
+		scripted("text", `
+def respond(request):
+    return "%d messages, last: %s" % (len(request["messages"]), request["messages"][-1]["content"])
+`),
+		scripted("response", `
+def respond(request):
+    return {
+        "model": "scripted-" + request["model"],
+        "choices": [{"message": {"content": "from a dict"}, "finish_reason": "length"}],
+    }
+`),
+		scripted("broken", `
+def respond(request):
+    return 1 // 0
+`),
+	)
+
+	request := func(prompt string) openai.ChatCompletionNewParams {
+		return openai.ChatCompletionNewParams{
+			Model:    "gpt-4o-mini",
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage(prompt)},
+		}
+	}
+
+	completion := postChatCompletion(t, baseURL, request("text"))
+	assert.Equal(t, "1 messages, last: text", completion.Choices[0].Message.Content)
+
+	completion = postChatCompletion(t, baseURL, request("response"))
+	assert.Equal(t, "scripted-gpt-4o-mini", completion.Model)
+	assert.Equal(t, "from a dict", completion.Choices[0].Message.Content)
+	assert.Equal(t, "length", completion.Choices[0].FinishReason)
+
+	resp := postJSON(t, baseURL+"/v1/chat/completions", request("broken"), openaiHeaders)
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	var envelope struct {
+		Error struct{ Message string } `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&envelope))
+	assert.Contains(t, envelope.Error.Message, `script of mock "broken" failed`)
+	assert.Contains(t, envelope.Error.Message, "division by zero")
+}
@@ -3,13 +3,20 @@ package mockllm
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
+	"log/slog"
+	"maps"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
-
-	"github.com/gorilla/mux"
 )
 
 // Server is the main mock LLM server
@@ -17,79 +24,217 @@ type Server struct {
 	config            Config
 	openaiProvider    *OpenAIProvider
 	anthropicProvider *AnthropicProvider
-	router            *mux.Router
+	geminiProvider    *GeminiProvider
+	httpProvider      *HTTPProvider
+	customProviders   []Provider
+	tenants           map[string]*tenant
+	requestLog        *RequestLog
+	router            *http.ServeMux
+	routesOnce        sync.Once
 	listener          net.Listener
 	httpServer        *http.Server
+	clock             Clock
+	logger            *slog.Logger
+	switches          *mockSwitches
+	patches           *responsePatches
+	ids               *idSource
+	streams           *streamTracker
+	idempotency       *idempotencyStore
+	// methods are the request methods the routes and HTTP mocks answer, sorted, for CORS preflights
+	methods []string
+	// serveErr receives the error the server failed with, if any
+	serveErr chan error
+	// cancelRequests cancels the context of every request, cutting them short on shutdown
+	cancelRequests context.CancelFunc
+	// shuttingDown is closed once the server starts shutting down, ending the event streams
+	shuttingDown chan struct{}
 }
 
-// NewServer creates a new mock LLM server with the given config
-func NewServer(config Config) *Server {
-	// Convert config to provider mocks
-	var openaiMocks []OpenAIMock
-	for _, mock := range config.OpenAI {
-		openaiMocks = append(openaiMocks, OpenAIMock{
-			Name:     mock.Name,
-			Match:    mock.Match,
-			Response: mock.Response,
-		})
+// NewServer creates a new mock LLM server configured by the options, e.g.
+// NewServer(WithConfig(config), WithLogger(logger))
+func NewServer(options ...ServerOption) *Server {
+	var o serverOptions
+	for _, option := range options {
+		option(&o)
+	}
+	config := o.config
+	if o.clock != nil {
+		config.Clock = o.clock
+	}
+	if config.Clock == nil {
+		config.Clock = systemClock{}
 	}
+	logger := o.logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	requestLog := NewRequestLog()
+	requestLog.logger, requestLog.clock = logger, config.Clock
+	requestLog.duplicateWindow = time.Duration(config.DuplicateWindow)
+	switches, patches, ids := newMockSwitches(config.Tags), newResponsePatches(), newIDSource(config.IDs)
+	openaiMocks := append(organizationMocks(config.OpenAIOrganizations), config.OpenAI...)
+	openaiProvider, anthropicProvider := newBuiltinProviders(config, openaiMocks, config.Anthropic, requestLog, "")
+	openaiProvider.switches, anthropicProvider.switches = switches, switches
+	openaiProvider.patches, anthropicProvider.patches = patches, patches
+	openaiProvider.ids, anthropicProvider.ids = ids, ids
+	geminiProvider := NewGeminiProvider(config.Gemini)
+	geminiProvider.log, geminiProvider.clock = requestLog, config.Clock
+	httpProvider := NewHTTPProvider(config.HTTP)
+	httpProvider.log = requestLog
 
-	var anthropicMocks []AnthropicMock
-	for _, mock := range config.Anthropic {
-		anthropicMocks = append(anthropicMocks, AnthropicMock{
-			Name:     mock.Name,
-			Match:    mock.Match,
-			Response: mock.Response,
-		})
+	tenants := map[string]*tenant{}
+	for _, tenantConfig := range config.Tenants {
+		t := &tenant{config: tenantConfig, limiter: newRateLimiter(tenantConfig.RateLimit)}
+		t.openai, t.anthropic = newBuiltinProviders(config, tenantConfig.OpenAI, tenantConfig.Anthropic,
+			requestLog, tenantConfig.Name)
+		t.openai.switches, t.anthropic.switches = switches, switches
+		t.openai.patches, t.anthropic.patches = patches, patches
+		t.openai.ids, t.anthropic.ids = ids, ids
+		for _, key := range tenantConfig.APIKeys {
+			tenants[key] = t
+		}
 	}
 
 	return &Server{
 		config:            config,
-		openaiProvider:    NewOpenAIProvider(openaiMocks),
-		anthropicProvider: NewAnthropicProvider(anthropicMocks),
+		openaiProvider:    openaiProvider,
+		anthropicProvider: anthropicProvider,
+		geminiProvider:    geminiProvider,
+		httpProvider:      httpProvider,
+		customProviders:   registeredProviders(config),
+		tenants:           tenants,
+		requestLog:        requestLog,
+		clock:             config.Clock,
+		logger:            logger,
+		switches:          switches,
+		patches:           patches,
+		ids:               ids,
+		listener:          o.listener,
+		streams:           &streamTracker{},
+		idempotency:       newIdempotencyStore(),
+		serveErr:          make(chan error, 1),
+		shuttingDown:      make(chan struct{}),
 	}
 }
 
-// LoadConfigFromFile loads configuration from a JSON file
+// newBuiltinProviders creates the OpenAI and Anthropic providers for a set of mocks, logging
+// their requests under the given tenant
+func newBuiltinProviders(config Config, openaiMocks []OpenAIMock, anthropicMocks []AnthropicMock,
+	requestLog *RequestLog, tenant string) (*OpenAIProvider, *AnthropicProvider) {
+	openaiProvider := NewOpenAIProvider(openaiMocks)
+	openaiProvider.log, openaiProvider.tenant = requestLog, tenant
+	if config.FineTuning != nil {
+		openaiProvider.fineTuning.config = *config.FineTuning
+	}
+	anthropicProvider := NewAnthropicProvider(anthropicMocks)
+	anthropicProvider.log, anthropicProvider.tenant = requestLog, tenant
+	if len(config.AnthropicVersions) > 0 {
+		anthropicProvider.versions = config.AnthropicVersions
+	}
+	if config.Tokenizer != nil {
+		openaiProvider.tokenizer, anthropicProvider.tokenizer = config.Tokenizer, config.Tokenizer
+	}
+	openaiProvider.estimateUsage, openaiProvider.enforceMaxTokens = config.EstimateUsage, config.EnforceMaxTokens
+	anthropicProvider.estimateUsage, anthropicProvider.enforceMaxTokens = config.EstimateUsage, config.EnforceMaxTokens
+	openaiProvider.grammar, anthropicProvider.grammar = config.Grammar, config.Grammar
+	openaiProvider.echo, anthropicProvider.echo = config.Echo, config.Echo
+	openaiProvider.toolValidation, anthropicProvider.toolValidation = config.ToolValidation, config.ToolValidation
+	openaiProvider.modelAliases, anthropicProvider.modelAliases = config.ModelAliases, config.ModelAliases
+	openaiProvider.heartbeat, anthropicProvider.heartbeat = config.Heartbeat, config.Heartbeat
+	openaiProvider.serviceTiers = config.ServiceTiers
+	openaiProvider.responsesMocks = config.OpenAIResponses
+	profiles := newModelProfiles(config.ModelProfiles)
+	openaiProvider.profiles, anthropicProvider.profiles = profiles, profiles
+	if config.KnownModels != nil {
+		openaiProvider.knownModels, anthropicProvider.knownModels = config.KnownModels.OpenAI, config.KnownModels.Anthropic
+	}
+	if config.Clock != nil {
+		openaiProvider.clock, openaiProvider.fineTuning.clock = config.Clock, config.Clock
+		anthropicProvider.clock = config.Clock
+	}
+	if config.Batches != nil {
+		anthropicProvider.batchDelay = time.Duration(config.Batches.ProcessingDelay)
+	}
+	return openaiProvider, anthropicProvider
+}
+
+// LoadConfigFromFile loads configuration from a JSON file, along with the files it includes. Mock
+// responses are checked against the SDK response types, and unknown fields, invalid values or
+// missing required fields are reported with their file, line and column. Files with the .feature
+// extension are scenario files, see ParseScenarios.
 func LoadConfigFromFile(path string, filesys fs.ReadFileFS) (Config, error) {
-	data, err := filesys.ReadFile(path)
+	if strings.HasSuffix(path, ".feature") {
+		data, err := filesys.ReadFile(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to read config file: %w", err)
+		}
+		return ParseScenarios(path, data)
+	}
+
+	document, origins, err := loadConfigDocument(filesys, path, nil, map[string]bool{})
+	if err != nil {
+		return Config{}, err
+	}
+	data, err := json.Marshal(document)
 	if err != nil {
-		return Config{}, fmt.Errorf("failed to read config file: %w", err)
+		return Config{}, fmt.Errorf("failed to encode config: %w", err)
 	}
 
 	var config Config
 	if err := json.Unmarshal(data, &config); err != nil {
 		return Config{}, fmt.Errorf("failed to parse config JSON: %w", err)
 	}
+	if err := validateConfig(config, origins.locate); err != nil {
+		return Config{}, fmt.Errorf("invalid mock responses:\n%w", err)
+	}
 
 	return config, nil
 }
 
-// Start starts the server on a random available port and returns the base URL
+// Start starts the server on a random available port, or the listener it was given, and returns
+// the base URL
 func (s *Server) Start(ctx context.Context) (string, error) {
-	s.setupRoutes()
-
-	listenAddr := s.config.ListenAddr
-	if listenAddr == "" {
-		listenAddr = "0.0.0.0:0"
-	}
-
-	listener, err := net.Listen("tcp", listenAddr)
-	if err != nil {
-		return "", fmt.Errorf("failed to create listener: %w", err)
+	listener := s.listener
+	if listener == nil {
+		listenAddr := s.config.ListenAddr
+		if listenAddr == "" {
+			listenAddr = "0.0.0.0:0"
+		}
+		var err error
+		if listener, err = net.Listen("tcp", listenAddr); err != nil {
+			return "", fmt.Errorf("failed to create listener: %w", err)
+		}
 	}
 
 	s.listener = listener
-	s.httpServer = &http.Server{Handler: s.router}
+	requestCtx, cancelRequests := context.WithCancel(context.WithValue(context.Background(), streamTrackerKey{}, s.streams))
+	s.cancelRequests = cancelRequests
+	s.httpServer = &http.Server{
+		Handler:     s.Handler(),
+		BaseContext: func(net.Listener) context.Context { return requestCtx },
+	}
+	// Event streams never end on their own, so they would keep Shutdown waiting. Shutdown runs the
+	// hook every time it is called.
+	s.httpServer.RegisterOnShutdown(sync.OnceFunc(func() { close(s.shuttingDown) }))
 
 	go func() {
 		if err := s.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
-			fmt.Printf("Server error: %v\n", err)
+			s.serveErr <- err
 		}
 	}()
 
+	// Stop health checking as soon as the server fails
+	healthCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var serveErr error
 	if err := RetryWithBackoff(
-		ctx, 5, 500*time.Millisecond, 5*time.Second, func() error {
+		healthCtx, 5, 500*time.Millisecond, 5*time.Second, func() error {
+			select {
+			case serveErr = <-s.serveErr:
+				cancel()
+				return serveErr
+			default:
+			}
 			resp, err := http.Get(fmt.Sprintf("http://%s/health", listener.Addr().String()))
 			if err != nil {
 				return err
@@ -100,37 +245,210 @@ func (s *Server) Start(ctx context.Context) (string, error) {
 			}
 			return nil
 		}); err != nil {
+		if serveErr != nil {
+			return "", fmt.Errorf("server failed: %w", serveErr)
+		}
 		return "", fmt.Errorf("failed to health check server: %w", err)
 	}
 
 	baseURL := fmt.Sprintf("http://%s", listener.Addr().String())
+	s.logger.Info("mockllm listening", "url", baseURL)
 	return baseURL, nil
 }
 
-// Stop stops the server
+// Stop stops the server, see Shutdown, then evaluates the assertions of the config, writes the
+// verification report it asks for and compares the run against its golden transcript and metrics
+// baseline. The error lists the failed assertions and the drift from the baselines, if any.
 func (s *Server) Stop(ctx context.Context) error {
-	if s.httpServer != nil {
-		return s.httpServer.Shutdown(ctx)
+	_, err := s.Shutdown(ctx)
+	return errors.Join(err, s.writeReport(), errors.Join(s.assertionFailures()...), s.checkGolden(), s.checkMetrics())
+}
+
+// Err returns a channel receiving the error the server fails with while serving requests after
+// Start returned. Nothing is sent when the server is stopped. Run receives from the same channel,
+// so callers of Run get the error from it instead.
+func (s *Server) Err() <-chan error {
+	return s.serveErr
+}
+
+// runStopTimeout bounds how long Run waits for the server to stop
+const runStopTimeout = 30 * time.Second
+
+// Run starts the server, unless Start was called already, and blocks until ctx is done, the
+// process receives SIGINT or SIGTERM, or the server fails. It then stops the server, waiting for
+// in-flight requests, for up to Config.ShutdownGracePeriod when it is set and at most 30 seconds,
+// and returns the error the server failed with, if any, or the error stopping it. A second signal
+// while stopping kills the process.
+func (s *Server) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if s.httpServer == nil {
+		if _, err := s.Start(ctx); err != nil {
+			return err
+		}
+	}
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+	case serveErr = <-s.serveErr:
+	}
+	// Restore the default handling of the signals, so a second one isn't swallowed while stopping
+	stop()
+	stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), runStopTimeout)
+	defer cancel()
+	if err := s.Stop(stopCtx); serveErr == nil {
+		return err
 	}
-	return nil
+	return serveErr
+}
+
+// Requests returns a snapshot of every request the providers have handled so far
+func (s *Server) Requests() []RequestRecord {
+	return s.requestLog.Records()
+}
+
+// TenantRequests returns a snapshot of the requests made with the API keys of the named tenant
+func (s *Server) TenantRequests(tenant string) []RequestRecord {
+	records, _ := s.QueryRequests(RequestQuery{Tenant: &tenant})
+	return records
+}
+
+// DisconnectedMidStream reports whether a client went away before a streamed response of the
+// named mock completed. Requests are logged once their handler returns, so tests cancelling a
+// stream should poll, e.g. with assert.Eventually.
+func (s *Server) DisconnectedMidStream(mockName string) bool {
+	for _, record := range s.Requests() {
+		if record.MockName == mockName && record.Stream != nil && record.Stream.Disconnected {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *Server) setupRoutes() {
-	r := mux.NewRouter()
+	r := http.NewServeMux()
+	methods := map[string]bool{http.MethodOptions: true}
+	handleFunc := func(pattern string, handler http.HandlerFunc) {
+		method, _, _ := strings.Cut(pattern, " ")
+		methods[method] = true
+		r.HandleFunc(pattern, handler)
+	}
 
 	// Health check
-	r.HandleFunc("/health", s.handleHealth).Methods("GET")
+	handleFunc("GET /health", s.handleHealth)
 
-	// OpenAI Chat Completions API
-	r.HandleFunc("/v1/chat/completions", s.openaiProvider.Handle).Methods("POST")
+	// Provider APIs, sharing a single concurrency limit
+	var limiter *concurrencyLimiter
+	if s.config.Concurrency != nil && s.config.Concurrency.MaxConcurrent > 0 {
+		limiter = newConcurrencyLimiter(*s.config.Concurrency, s.clock, s.requestLog)
+	}
+	var patterns []string
+	handlers := map[string][]claimedHandler{}
+	for _, provider := range s.providers() {
+		handle := provider.Handle
+		if len(s.tenants) > 0 {
+			handle = s.tenantRouted(provider, handle)
+		}
+		if provider == Provider(s.openaiProvider) {
+			handle = s.organizationRouted(handle)
+		}
+		if limiter != nil {
+			handle = limiter.limit(provider, handle)
+		}
+		handle = s.idempotent(provider, handle)
+		claimer, _ := provider.(routeClaimer)
+		for _, route := range provider.Routes() {
+			pattern := route.Method + " " + route.Path
+			if _, ok := handlers[pattern]; !ok {
+				patterns = append(patterns, pattern)
+			}
+			handlers[pattern] = append(handlers[pattern], claimedHandler{claimer: claimer, handle: handle})
+		}
+	}
+	for _, pattern := range patterns {
+		handleFunc(pattern, dispatchClaimed(handlers[pattern]))
+	}
 
-	// Anthropic Messages API
-	r.HandleFunc("/v1/messages", s.anthropicProvider.Handle).Methods("POST")
+	// Dashboard and the admin API backing it
+	handleFunc("GET /ui", s.handleUI)
+	handleFunc("GET /admin/mocks", s.handleAdminMocks)
+	handleFunc("POST /admin/mocks/{name}/disable", s.handleAdminMockSwitch)
+	handleFunc("POST /admin/mocks/{name}/enable", s.handleAdminMockSwitch)
+	handleFunc("PATCH /admin/mocks/{name}/response", s.handleAdminMockResponse)
+	handleFunc("DELETE /admin/mocks/{name}/response", s.handleAdminMockResponse)
+	handleFunc("GET /admin/coverage", s.handleAdminCoverage)
+	handleFunc("GET /admin/requests", s.handleAdminRequests)
+	handleFunc("GET /admin/har", s.handleAdminHAR)
+	handleFunc("GET /admin/events", s.handleAdminEvents)
+	handleFunc("GET /admin/usage", s.handleAdminUsage)
+	handleFunc("GET /admin/fuzz", s.handleAdminFuzz)
+	handleFunc("GET /admin/duplicates", s.handleAdminDuplicates)
+	handleFunc("POST /admin/match", s.handleAdminMatch)
+	handleFunc("GET /admin/ids", s.handleAdminIDs)
+	handleFunc("GET /admin/verify", s.handleAdminVerify)
+	handleFunc("GET /admin/report", s.handleAdminReport)
+	handleFunc("GET /admin/golden", s.handleAdminGolden)
+	handleFunc("GET /admin/metrics", s.handleAdminMetrics)
+	handleFunc("GET /admin/tags", s.handleAdminTags)
+	handleFunc("PUT /admin/tags", s.handleAdminTags)
+	handleFunc("POST /admin/fine_tuning/jobs/{id}", s.handleAdminFineTuningJob)
+	handleFunc("GET /v1/usage", s.handleOpenAIUsage)
 
-	// Debug route
-	r.NotFoundHandler = http.HandlerFunc(s.handleNotFound)
+	// Custom HTTP mocks, then the debug route, for everything else
+	r.Handle("/", s.httpProvider.Fallback(http.HandlerFunc(s.handleNotFound)))
+	for _, mock := range s.config.HTTP {
+		if mock.Method != "" {
+			methods[strings.ToUpper(mock.Method)] = true
+		}
+	}
 
 	s.router = r
+	s.methods = slices.Sorted(maps.Keys(methods))
+}
+
+// Handler returns the server's routes wrapped with the middleware enabled in the config, for
+// serving the mocks from an existing HTTP server instead of calling Start. Mount it with
+// http.StripPrefix to serve it under a path prefix. It needs no listener, so it can also serve
+// requests in process, e.g. from a serverless function or with httptest.NewRecorder. Stop and
+// Run only apply to servers started with Start.
+func (s *Server) Handler() http.Handler {
+	s.routesOnce.Do(s.setupRoutes)
+	var h http.Handler = s.router
+	if s.config.Compression != nil {
+		h = compressionMiddleware(*s.config.Compression, h)
+	}
+	if s.config.CORS != nil {
+		h = corsMiddleware(*s.config.CORS, s.methods, h)
+	}
+	return h
+}
+
+// providers returns the built-in providers followed by the registered custom providers
+func (s *Server) providers() []Provider {
+	return append([]Provider{s.openaiProvider, s.anthropicProvider, s.geminiProvider}, s.customProviders...)
+}
+
+// openaiProviders returns the default OpenAI provider followed by those of the tenants
+func (s *Server) openaiProviders() []*OpenAIProvider {
+	providers := []*OpenAIProvider{s.openaiProvider}
+	for _, tenant := range s.tenants {
+		if !slices.Contains(providers, tenant.openai) {
+			providers = append(providers, tenant.openai)
+		}
+	}
+	return providers
+}
+
+// anthropicProviders returns the default Anthropic provider followed by those of the tenants
+func (s *Server) anthropicProviders() []*AnthropicProvider {
+	providers := []*AnthropicProvider{s.anthropicProvider}
+	for _, tenant := range s.tenants {
+		if !slices.Contains(providers, tenant.anthropic) {
+			providers = append(providers, tenant.anthropic)
+		}
+	}
+	return providers
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -155,8 +473,19 @@ func (s *Server) handleNotFound(w http.ResponseWriter, r *http.Request) {
 		"error":  "Endpoint not found",
 		"path":   r.URL.Path,
 		"method": r.Method,
-		"hint":   "Supported: /v1/chat/completions (OpenAI), /v1/messages (Anthropic)",
+		"hint":   "Supported: " + strings.Join(s.supportedRoutes(), ", "),
 	}); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
 	}
 }
+
+// supportedRoutes lists the provider endpoints for the not found hint
+func (s *Server) supportedRoutes() []string {
+	var routes []string
+	for _, provider := range s.providers() {
+		for _, route := range provider.Routes() {
+			routes = append(routes, route.Method+" "+route.Path)
+		}
+	}
+	return routes
+}
@@ -5,6 +5,8 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/anthropics/anthropic-sdk-go"
@@ -71,7 +73,7 @@ func TestSimpleOpenAIMock(t *testing.T) {
 	}
 
 	// Start server
-	server := mockllm.NewServer(config)
+	server := mockllm.NewServer(mockllm.WithConfig(config))
 	baseURL, err := server.Start(t.Context())
 	require.NoError(t, err)
 	defer server.Stop(context.Background()) //nolint:errcheck
@@ -150,7 +152,7 @@ func TestSimpleAnthropicMock(t *testing.T) {
 	}
 
 	// Start server
-	server := mockllm.NewServer(config)
+	server := mockllm.NewServer(mockllm.WithConfig(config))
 	baseURL, err := server.Start(t.Context())
 	require.NoError(t, err)
 	defer server.Stop(context.Background()) //nolint:errcheck
@@ -180,7 +182,7 @@ func TestSimpleAnthropicMock(t *testing.T) {
 
 func TestHealthCheck(t *testing.T) {
 	config := mockllm.Config{}
-	server := mockllm.NewServer(config)
+	server := mockllm.NewServer(mockllm.WithConfig(config))
 	baseURL, err := server.Start(t.Context())
 	require.NoError(t, err)
 	defer server.Stop(context.Background()) //nolint:errcheck
@@ -198,3 +200,114 @@ func TestHealthCheck(t *testing.T) {
 	assert.Equal(t, "healthy", responseBody["status"])
 	assert.Equal(t, "mock-llm", responseBody["service"])
 }
+
+func TestRun(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- server.Run(ctx) }()
+
+	resp, err := http.Get(baseURL + "/health")
+	require.NoError(t, err)
+	resp.Body.Close() //nolint:errcheck
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	cancel()
+	require.NoError(t, <-done)
+	_, err = http.Get(baseURL + "/health")
+	assert.Error(t, err, "the server is stopped once Run returns")
+}
+
+func TestStartFailsWhenPortTaken(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{ListenAddr: "127.0.0.1:0"}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+
+	_, err = mockllm.NewServer(mockllm.WithConfig(mockllm.Config{ListenAddr: strings.TrimPrefix(baseURL, "http://")})).Start(t.Context())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "address already in use")
+
+	require.NoError(t, server.Stop(context.Background()))
+	select {
+	case err := <-server.Err():
+		t.Fatalf("stopping the server is not an error, got %v", err)
+	default:
+	}
+}
+
+func TestHandlerMountedUnderPrefix(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{}))
+	mux := http.NewServeMux()
+	mux.Handle("/llm/", http.StripPrefix("/llm", server.Handler()))
+	embedder := httptest.NewServer(mux)
+	defer embedder.Close()
+
+	resp, err := http.Get(embedder.URL + "/llm/health")
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = postJSON(t, embedder.URL+"/llm/v1/chat/completions", openai.ChatCompletionNewParams{
+		Model:    "gpt-4o-mini",
+		Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+	}, map[string]string{"Authorization": "Bearer test-key"})
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Len(t, server.Requests(), 1)
+	assert.Equal(t, "/v1/chat/completions", server.Requests()[0].Path)
+}
+
+func TestHandlerInProcess(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{{
+			Name:     "hello",
+			Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+			Response: helloCompletion,
+		}},
+	}))
+
+	body := `{"model":"gpt-4o-mini","stream":true,"messages":[{"role":"user","content":"Hello"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer test-key")
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/event-stream", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "data: [DONE]")
+	require.Len(t, server.Requests(), 1)
+	assert.Equal(t, "hello", server.Requests()[0].MockName)
+}
+
+// postJSON sends body as JSON to url with the given headers and returns the response
+func postJSON(t *testing.T, url string, body any, headers map[string]string) *http.Response {
+	t.Helper()
+
+	reqBytes, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(reqBytes))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() }) //nolint:errcheck
+	return resp
+}
+
+// openaiUserMessage returns a chat completion user message with plain string content
+func openaiUserMessage(content string) openai.ChatCompletionMessageParamUnion {
+	return openai.ChatCompletionMessageParamUnion{
+		OfUser: &openai.ChatCompletionUserMessageParam{
+			Role:    "user",
+			Content: openai.ChatCompletionUserMessageParamContentUnion{OfString: openai.String(content)},
+		},
+	}
+}
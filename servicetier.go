@@ -0,0 +1,122 @@
+package mockllm
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// openaiServiceTiers are the values of the service_tier field of OpenAI requests
+var openaiServiceTiers = []string{"auto", "default", "flex", "scale", "priority"}
+
+// ServiceTierConfig emulates the processing tiers OpenAI requests select with service_tier, to test
+// tier selection logic: responses report the tier serving them, and tiers can be slower, as flex
+// processing is, or out of capacity
+type ServiceTierConfig struct {
+	// Default is the tier serving requests for the "auto" tier or none, "default" unless set
+	Default string `json:"default,omitempty"`
+	// Tiers configure the tiers by name: "default", "flex", "scale" or "priority". Tiers that
+	// aren't configured serve every model without extra latency.
+	Tiers map[string]ServiceTier `json:"tiers,omitempty"`
+}
+
+// ServiceTier is the behavior of a processing tier
+type ServiceTier struct {
+	// Latency is added to the latency of the model profile, e.g. "5s" for flex
+	Latency Duration `json:"latency,omitempty"`
+	// Models are wildcard patterns, or regular expressions between slashes, of the models the tier
+	// serves, every model when empty. Requests for other models get a 400 error, as flex requests
+	// for models without flex processing do.
+	Models []string `json:"models,omitempty"`
+	// Unavailable rejects the requests for the tier with a 429 resource_unavailable error, as flex
+	// processing does when it lacks capacity, so clients can fall back to another tier
+	Unavailable bool `json:"unavailable,omitempty"`
+	// RetryAfter is sent in the Retry-After and retry-after-ms headers of those errors
+	RetryAfter Duration `json:"retry_after,omitempty"`
+	// SystemFingerprint is reported by the responses of the tier, instead of one derived from the
+	// name of the mock, unless the mock's response sets its own
+	SystemFingerprint string `json:"system_fingerprint,omitempty"`
+}
+
+// serviceTierError is why a request for a tier is rejected
+type serviceTierError struct {
+	status     int
+	reason     string
+	message    string
+	code       string
+	retryAfter time.Duration
+}
+
+// write sends the error, with the retry headers of tiers out of capacity
+func (e *serviceTierError) write(w http.ResponseWriter) {
+	if e.retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(e.retryAfter.Seconds()))))
+		w.Header().Set("retry-after-ms", strconv.FormatInt(e.retryAfter.Milliseconds(), 10))
+	}
+	writeOpenAIError(w, e.status, e.message, "service_tier", e.code)
+}
+
+// resolve returns the name and behavior of the tier serving a request for the given tier and
+// model, or the error rejecting the request
+func (c *ServiceTierConfig) resolve(requested, model string) (string, ServiceTier, *serviceTierError) {
+	if c == nil {
+		return "", ServiceTier{}, nil
+	}
+	if requested != "" && !slices.Contains(openaiServiceTiers, requested) {
+		return "", ServiceTier{}, &serviceTierError{
+			status: http.StatusBadRequest,
+			reason: "unsupported service_tier " + requested,
+			message: fmt.Sprintf("Invalid value: '%s'. Supported values are: 'auto', 'default', 'flex', 'scale', and 'priority'.",
+				requested),
+			code: "invalid_value",
+		}
+	}
+	name := requested
+	if name == "" || name == "auto" {
+		name = cmp.Or(c.Default, "default")
+	}
+	tier := c.Tiers[name]
+	if len(tier.Models) > 0 && !slices.ContainsFunc(tier.Models, func(pattern string) bool {
+		re, err := compileFieldPattern(pattern)
+		return err == nil && re.MatchString(model)
+	}) {
+		return "", ServiceTier{}, &serviceTierError{
+			status:  http.StatusBadRequest,
+			reason:  fmt.Sprintf("service_tier %s unavailable for model %s", name, model),
+			message: fmt.Sprintf("The %s service tier is not available for model %s.", name, model),
+			code:    "invalid_value",
+		}
+	}
+	if tier.Unavailable {
+		return "", ServiceTier{}, &serviceTierError{
+			status:     http.StatusTooManyRequests,
+			reason:     fmt.Sprintf("service_tier %s unavailable", name),
+			message:    fmt.Sprintf("Resource unavailable: the %s service tier lacks capacity. Please retry later.", name),
+			code:       "resource_unavailable",
+			retryAfter: time.Duration(tier.RetryAfter),
+		}
+	}
+	return name, tier, nil
+}
+
+// fillServiceTier reports the tier serving a request, and its system fingerprint, in the response
+// unless the mock sets them
+func (c *ServiceTierConfig) fillServiceTier(mockName string, request openai.ChatCompletionNewParams,
+	response *openai.ChatCompletion) {
+	name, tier, failure := c.resolve(string(request.ServiceTier), request.Model)
+	if name == "" || failure != nil {
+		return
+	}
+	if response.ServiceTier == "" {
+		response.ServiceTier = openai.ChatCompletionServiceTier(name)
+	}
+	if response.SystemFingerprint == "" {
+		response.SystemFingerprint = cmp.Or(tier.SystemFingerprint, systemFingerprint(mockName))
+	}
+}
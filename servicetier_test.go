@@ -0,0 +1,90 @@
+package mockllm_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	openaioption "github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceTiers(t *testing.T) {
+	config := mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{{
+			Name:     "hello",
+			Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+			Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "Hi"}}}},
+		}},
+		ServiceTiers: &mockllm.ServiceTierConfig{
+			Tiers: map[string]mockllm.ServiceTier{
+				"flex":     {Latency: mockllm.Duration(100 * time.Millisecond), Models: []string{"o3*"}, SystemFingerprint: "fp_flex"},
+				"priority": {Unavailable: true, RetryAfter: mockllm.Duration(1500 * time.Millisecond)},
+			},
+		},
+	}
+	server := mockllm.NewServer(mockllm.WithConfig(config))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+
+	client := openai.NewClient(openaioption.WithBaseURL(baseURL+"/v1"), openaioption.WithAPIKey("test-key"),
+		openaioption.WithMaxRetries(0))
+	request := func(model string, tier openai.ChatCompletionNewParamsServiceTier) openai.ChatCompletionNewParams {
+		return openai.ChatCompletionNewParams{
+			Model:       model,
+			Messages:    []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Hello")},
+			ServiceTier: tier,
+		}
+	}
+
+	// Requests for auto or no tier are served by the default tier
+	completion, err := client.Chat.Completions.New(t.Context(), request("gpt-4o", openai.ChatCompletionNewParamsServiceTierAuto))
+	require.NoError(t, err)
+	assert.Equal(t, openai.ChatCompletionServiceTierDefault, completion.ServiceTier)
+	assert.NotEmpty(t, completion.SystemFingerprint)
+
+	start := time.Now()
+	completion, err = client.Chat.Completions.New(t.Context(), request("o3-mini", openai.ChatCompletionNewParamsServiceTierFlex))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	assert.Equal(t, openai.ChatCompletionServiceTierFlex, completion.ServiceTier)
+	assert.Equal(t, "fp_flex", completion.SystemFingerprint)
+
+	stream := client.Chat.Completions.NewStreaming(t.Context(), request("o3-mini", openai.ChatCompletionNewParamsServiceTierFlex))
+	var accumulated openai.ChatCompletionAccumulator
+	for stream.Next() {
+		accumulated.AddChunk(stream.Current())
+	}
+	require.NoError(t, stream.Err())
+	assert.Equal(t, openai.ChatCompletionServiceTierFlex, accumulated.ServiceTier)
+	assert.Equal(t, "fp_flex", accumulated.SystemFingerprint)
+
+	_, err = client.Chat.Completions.New(t.Context(), request("gpt-4o", openai.ChatCompletionNewParamsServiceTierFlex))
+	var apiErr *openai.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, "service_tier", apiErr.Param)
+
+	// Tiers out of capacity tell clients when to retry
+	resp := postJSON(t, baseURL+"/v1/chat/completions", request("gpt-4o", openai.ChatCompletionNewParamsServiceTierPriority), openaiHeaders)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "2", resp.Header.Get("Retry-After"))
+	assert.Equal(t, "1500", resp.Header.Get("retry-after-ms"))
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "resource_unavailable", body.Error.Code)
+
+	resp = postJSON(t, baseURL+"/v1/chat/completions", map[string]any{
+		"model": "gpt-4o", "service_tier": "turbo", "messages": []any{map[string]any{"role": "user", "content": "Hello"}},
+	}, openaiHeaders)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
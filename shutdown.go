@@ -0,0 +1,114 @@
+package mockllm
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StopReport counts the streamed responses that were in flight when the server stopped
+type StopReport struct {
+	// Drained streams completed within the grace period
+	Drained int `json:"drained"`
+	// Cut streams were still running after the grace period and were ended with an error event
+	Cut int `json:"cut"`
+}
+
+// cutStreamTimeout is how long streams cut short on shutdown get to send their interrupted event
+// before their connection is closed
+const cutStreamTimeout = time.Second
+
+// streamTracker keeps track of the streamed responses in flight, so stopping the server can wait
+// for them or cut them short
+type streamTracker struct {
+	wg          sync.WaitGroup
+	active      atomic.Int64
+	cut         atomic.Int64
+	interrupted atomic.Bool
+}
+
+// streamTrackerKey is the request context key of the server's streamTracker
+type streamTrackerKey struct{}
+
+// streamTrackerFrom returns the tracker of the server handling a request, nil outside a server
+func streamTrackerFrom(ctx context.Context) *streamTracker {
+	tracker, _ := ctx.Value(streamTrackerKey{}).(*streamTracker)
+	return tracker
+}
+
+func (t *streamTracker) begin() {
+	if t != nil {
+		t.wg.Add(1)
+		t.active.Add(1)
+	}
+}
+
+func (t *streamTracker) end() {
+	if t != nil {
+		t.active.Add(-1)
+		t.wg.Done()
+	}
+}
+
+// waitFor waits for the streams in flight to end, for up to d
+func (t *streamTracker) waitFor(d time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(d):
+	}
+}
+
+// stopping reports whether the server is cutting streams short
+func (t *streamTracker) stopping() bool {
+	return t != nil && t.interrupted.Load()
+}
+
+// Shutdown stops accepting requests and waits for in-flight requests to finish, until ctx is done
+// or for up to Config.ShutdownGracePeriod when it is set. Streams still running then are ended
+// with an error event in the provider's format, so clients fail with an error instead of a
+// truncated stream, and requests waiting on a fault are abandoned. Connections of streams that
+// can't send the error event within a second, because their client stopped reading, are closed.
+func (s *Server) Shutdown(ctx context.Context) (StopReport, error) {
+	if s.httpServer == nil {
+		return StopReport{}, nil
+	}
+	defer s.closePlugins(context.WithoutCancel(ctx))
+
+	inFlight := int(s.streams.active.Load())
+	graceCtx, cancel := ctx, context.CancelFunc(func() {})
+	if s.config.ShutdownGracePeriod > 0 {
+		graceCtx, cancel = context.WithTimeout(ctx, time.Duration(s.config.ShutdownGracePeriod))
+	}
+	defer cancel()
+	if err := s.httpServer.Shutdown(graceCtx); err == nil {
+		s.logger.Info("mockllm stopped", "drained", inFlight)
+		return StopReport{Drained: inFlight}, nil
+	}
+
+	s.streams.interrupted.Store(true)
+	s.cancelRequests()
+	// Closing the connections unblocks the streams still writing to clients that stopped reading,
+	// which the cancelled context doesn't
+	s.streams.waitFor(cutStreamTimeout)
+	s.httpServer.Close() //nolint:errcheck
+	s.streams.wg.Wait()
+	cut := int(s.streams.cut.Load())
+	s.logger.Info("mockllm stopped", "drained", max(inFlight-cut, 0), "cut", cut)
+	return StopReport{Drained: max(inFlight-cut, 0), Cut: cut}, ctx.Err()
+}
+
+// closePlugins closes the WebAssembly runtimes of the providers once no request uses them
+func (s *Server) closePlugins(ctx context.Context) {
+	for _, provider := range s.openaiProviders() {
+		provider.plugins.close(ctx) //nolint:errcheck
+	}
+	for _, provider := range s.anthropicProviders() {
+		provider.plugins.close(ctx) //nolint:errcheck
+	}
+}
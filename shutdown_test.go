@@ -0,0 +1,225 @@
+package mockllm_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	openaioption "github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startStalledStreamServer starts a server whose streams stall for an hour after two events
+func startStalledStreamServer(t *testing.T, gracePeriod time.Duration) (*mockllm.Server, *mockllm.FakeClock, string) {
+	t.Helper()
+
+	clock := mockllm.NewFakeClock(time.Now())
+	stall := &mockllm.Fault{Type: mockllm.FaultStall, Duration: mockllm.Duration(time.Hour), AfterEvents: 2}
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		Clock:               clock,
+		ShutdownGracePeriod: mockllm.Duration(gracePeriod),
+		OpenAI: []mockllm.OpenAIMock{{
+			Name:     "hello",
+			Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+			Response: helloCompletion,
+			Fault:    stall,
+		}},
+		Anthropic: []mockllm.AnthropicMock{{
+			Name:  "hello",
+			Match: mockllm.AnthropicRequestMatch{MatchType: mockllm.MatchTypeExact, Message: anthropicHelloRequest.Messages[0]},
+			Response: anthropic.Message{
+				Content: []anthropic.ContentBlockUnion{{Type: "text", Text: "Hello there, how can I help?"}},
+			},
+			Fault: stall,
+		}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(context.Background()) }) //nolint:errcheck
+	return server, clock, baseURL
+}
+
+func TestShutdownCutsStreams(t *testing.T) {
+	server, clock, baseURL := startStalledStreamServer(t, 10*time.Millisecond)
+
+	openaiClient := openai.NewClient(
+		openaioption.WithBaseURL(baseURL+"/v1"),
+		openaioption.WithAPIKey("test-key"),
+		openaioption.WithMaxRetries(0),
+	)
+	anthropicClient := anthropic.NewClient(
+		anthropicoption.WithBaseURL(baseURL),
+		anthropicoption.WithAPIKey("test-key"),
+		anthropicoption.WithMaxRetries(0),
+	)
+	errs := make(chan error, 2)
+	go func() {
+		stream := openaiClient.Chat.Completions.NewStreaming(context.Background(), helloParams)
+		for stream.Next() {
+		}
+		errs <- stream.Err()
+	}()
+	go func() {
+		stream := anthropicClient.Messages.NewStreaming(context.Background(), anthropicHelloRequest)
+		for stream.Next() {
+		}
+		errs <- stream.Err()
+	}()
+	require.Eventually(t, func() bool { return clock.Waiters() == 2 }, time.Second, 5*time.Millisecond)
+
+	report, err := server.Shutdown(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, mockllm.StopReport{Cut: 2}, report)
+	for range 2 {
+		err := <-errs
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "The server is shutting down")
+	}
+
+	records := server.Requests()
+	require.Len(t, records, 2)
+	for _, record := range records {
+		require.NotNil(t, record.Stream)
+		assert.True(t, record.Stream.Interrupted)
+		assert.False(t, record.Stream.Disconnected)
+		assert.Equal(t, 2, record.Stream.EventsSent)
+	}
+}
+
+func TestShutdownDrainsStreams(t *testing.T) {
+	server, clock, baseURL := startStalledStreamServer(t, 5*time.Second)
+
+	client := openai.NewClient(
+		openaioption.WithBaseURL(baseURL+"/v1"),
+		openaioption.WithAPIKey("test-key"),
+		openaioption.WithMaxRetries(0),
+	)
+	content := make(chan string, 1)
+	go func() {
+		stream := client.Chat.Completions.NewStreaming(context.Background(), helloParams)
+		var acc openai.ChatCompletionAccumulator
+		for stream.Next() {
+			acc.AddChunk(stream.Current())
+		}
+		assert.NoError(t, stream.Err())
+		content <- acc.Choices[0].Message.Content
+	}()
+	require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, 5*time.Millisecond)
+
+	reports := make(chan mockllm.StopReport, 1)
+	go func() {
+		report, err := server.Shutdown(context.Background())
+		assert.NoError(t, err)
+		reports <- report
+	}()
+	// Once the server stops accepting connections, the stream completes within the grace period
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(baseURL + "/health")
+		if err == nil {
+			resp.Body.Close() //nolint:errcheck
+		}
+		return err != nil
+	}, time.Second, 5*time.Millisecond)
+	server.AdvanceTime(time.Hour)
+
+	assert.Equal(t, "Hello there, how can I help?", <-content)
+	assert.Equal(t, mockllm.StopReport{Drained: 1}, <-reports)
+}
+
+func TestStopWaitsForRequests(t *testing.T) {
+	clock := mockllm.NewFakeClock(time.Now())
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		Clock: clock,
+		OpenAI: []mockllm.OpenAIMock{{
+			Name:     "hello",
+			Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+			Response: helloCompletion,
+			Fault:    &mockllm.Fault{Type: mockllm.FaultStall, Duration: mockllm.Duration(time.Hour)},
+		}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+
+	statuses := make(chan int, 1)
+	go func() {
+		resp := postJSON(t, baseURL+"/v1/chat/completions", openai.ChatCompletionNewParams{
+			Model:    "gpt-4o-mini",
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+		}, openaiHeaders)
+		resp.Body.Close() //nolint:errcheck
+		statuses <- resp.StatusCode
+	}()
+	require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, 5*time.Millisecond)
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- server.Stop(context.Background()) }()
+	// Without a grace period, stopping waits for the stalled request however long it takes
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(baseURL + "/health")
+		if err == nil {
+			resp.Body.Close() //nolint:errcheck
+		}
+		return err != nil
+	}, time.Second, 5*time.Millisecond)
+	select {
+	case <-stopped:
+		t.Fatal("Stop returned before the in-flight request completed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	server.AdvanceTime(time.Hour)
+
+	assert.Equal(t, http.StatusOK, <-statuses)
+	require.NoError(t, <-stopped)
+}
+
+func TestShutdownClosesStreamsOfClientsThatStoppedReading(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		ShutdownGracePeriod: mockllm.Duration(10 * time.Millisecond),
+		OpenAI: []mockllm.OpenAIMock{{
+			Name:  "long",
+			Match: mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+			Response: openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{
+				Message: openai.ChatCompletionMessage{Content: strings.Repeat("word ", 200_000)},
+			}}},
+		}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+
+	// The client sends a streamed request, then never reads the response, so the server's writes
+	// block once the socket buffers are full
+	body := `{"model":"gpt-4o-mini","stream":true,"messages":[{"role":"user","content":"Hello"}]}`
+	conn, err := net.Dial("tcp", strings.TrimPrefix(baseURL, "http://"))
+	require.NoError(t, err)
+	defer conn.Close() //nolint:errcheck
+	_, err = fmt.Fprintf(conn, "POST /v1/chat/completions HTTP/1.1\r\nHost: mockllm\r\nAuthorization: Bearer test-key\r\n"+
+		"Content-Type: application/json\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+	require.NoError(t, err)
+	status := make([]byte, len("HTTP/1.1 200"))
+	_, err = io.ReadFull(conn, status)
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1 200", string(status))
+	time.Sleep(200 * time.Millisecond)
+
+	stopped := make(chan mockllm.StopReport)
+	go func() {
+		report, _ := server.Shutdown(context.Background())
+		stopped <- report
+	}()
+	select {
+	case report := <-stopped:
+		assert.Equal(t, 1, report.Cut)
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown is blocked by a stream writing to a client that stopped reading")
+	}
+}
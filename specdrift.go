@@ -0,0 +1,152 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/kagent-dev/mockllm/openapi"
+)
+
+const (
+	// OpenAIResponseSchema is the schema of chat completions in OpenAI's OpenAPI spec
+	OpenAIResponseSchema = "CreateChatCompletionResponse"
+	// AnthropicResponseSchema is the schema of messages in Anthropic's OpenAPI spec
+	AnthropicResponseSchema = "Message"
+)
+
+// DriftSpecs are the OpenAPI specs of the providers that mock responses are checked against. A
+// provider without a spec is not checked, and empty schema names default to OpenAIResponseSchema
+// and AnthropicResponseSchema.
+type DriftSpecs struct {
+	OpenAI          *openapi.Spec
+	OpenAISchema    string
+	Anthropic       *openapi.Spec
+	AnthropicSchema string
+}
+
+// SpecDrift is a part of a mock response that the provider's current spec deprecates or no longer
+// allows, such as a removed field or enum value
+type SpecDrift struct {
+	Provider string `json:"provider"`
+	MockName string `json:"mock_name"`
+	// Path locates the value in the config, e.g. openai[0].response.choices[0].message.function_call
+	Path    string            `json:"path"`
+	Kind    openapi.DriftKind `json:"kind"`
+	Message string            `json:"message"`
+}
+
+// CheckSpecDrift checks the responses of the mocks of a config against the providers' specs, so
+// large mock suites can keep up as the provider APIs evolve. Responses are checked as they were
+// written in the config file, or as the SDK encodes them for configs built in code. Raw responses,
+// and those whose content is computed, are not checked.
+func CheckSpecDrift(config Config, specs DriftSpecs) ([]SpecDrift, error) {
+	openaiSchema, anthropicSchema := specs.OpenAISchema, specs.AnthropicSchema
+	if openaiSchema == "" {
+		openaiSchema = OpenAIResponseSchema
+	}
+	if anthropicSchema == "" {
+		anthropicSchema = AnthropicResponseSchema
+	}
+
+	var drifts []SpecDrift
+	check := func(spec *openapi.Spec, schema, provider, mockName, path, raw string, response any) error {
+		data := []byte(raw)
+		if raw == "" {
+			var err error
+			if data, err = json.Marshal(response); err != nil {
+				return fmt.Errorf("%s: failed to encode response: %w", path, err)
+			}
+		}
+		var value any
+		if err := json.Unmarshal(data, &value); err != nil {
+			return fmt.Errorf("%s: failed to decode response: %w", path, err)
+		}
+		found, err := spec.Drift(schema, value)
+		if err != nil {
+			return err
+		}
+		for _, drift := range found {
+			drifts = append(drifts, SpecDrift{
+				Provider: provider,
+				MockName: mockName,
+				Path:     joinFieldPath(path, drift.Path),
+				Kind:     drift.Kind,
+				Message:  drift.Message,
+			})
+		}
+		return nil
+	}
+	checkOpenAI := func(prefix string, mocks []OpenAIMock) error {
+		if specs.OpenAI == nil {
+			return nil
+		}
+		for i, mock := range mocks {
+			if mock.Raw != nil || mock.computesContent() {
+				continue
+			}
+			path := fmt.Sprintf("%s[%d]", prefix, i)
+			if err := check(specs.OpenAI, openaiSchema, "openai", mock.Name, path+".response",
+				mock.Response.RawJSON(), mock.Response); err != nil {
+				return err
+			}
+			for _, seed := range sortedKeys(mock.SeedResponses) {
+				response := mock.SeedResponses[seed]
+				if err := check(specs.OpenAI, openaiSchema, "openai", mock.Name,
+					fmt.Sprintf("%s.seed_responses.%d", path, seed), response.RawJSON(), response); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	checkAnthropic := func(prefix string, mocks []AnthropicMock) error {
+		if specs.Anthropic == nil {
+			return nil
+		}
+		for i, mock := range mocks {
+			if mock.Raw != nil || mock.computesContent() {
+				continue
+			}
+			path := fmt.Sprintf("%s[%d]", prefix, i)
+			if err := check(specs.Anthropic, anthropicSchema, "anthropic", mock.Name, path+".response",
+				mock.Response.RawJSON(), mock.Response); err != nil {
+				return err
+			}
+			for _, version := range sortedKeys(mock.VersionResponses) {
+				response := mock.VersionResponses[version]
+				if err := check(specs.Anthropic, anthropicSchema, "anthropic", mock.Name,
+					path+".version_responses."+version, response.RawJSON(), response); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := checkOpenAI("openai", config.OpenAI); err != nil {
+		return nil, err
+	}
+	if err := checkAnthropic("anthropic", config.Anthropic); err != nil {
+		return nil, err
+	}
+	for i, tenant := range config.Tenants {
+		if err := checkOpenAI(fmt.Sprintf("tenants[%d].openai", i), tenant.OpenAI); err != nil {
+			return nil, err
+		}
+		if err := checkAnthropic(fmt.Sprintf("tenants[%d].anthropic", i), tenant.Anthropic); err != nil {
+			return nil, err
+		}
+	}
+	return drifts, nil
+}
+
+// joinFieldPath appends the path of a value within a response to the path of the response
+func joinFieldPath(path, field string) string {
+	switch {
+	case field == "":
+		return path
+	case field[0] == '[':
+		return path + field
+	}
+	return path + "." + field
+}
@@ -0,0 +1,91 @@
+package mockllm_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/kagent-dev/mockllm/openapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSpecDrift(t *testing.T) {
+	spec, err := openapi.Parse([]byte(`
+components:
+  schemas:
+    CreateChatCompletionResponse:
+      type: object
+      properties:
+        id: {type: string}
+        object: {type: string}
+        created: {type: integer}
+        model: {type: string}
+        choices:
+          type: array
+          items:
+            type: object
+            properties:
+              index: {type: integer}
+              finish_reason:
+                type: string
+                enum: [stop, length, tool_calls]
+              message:
+                type: object
+                properties:
+                  role: {type: string}
+                  content: {type: [string, 'null']}
+                  function_call:
+                    type: object
+                    deprecated: true
+`))
+	require.NoError(t, err)
+
+	config, err := mockllm.LoadConfigFromFile("mocks.json", fstest.MapFS{"mocks.json": {Data: []byte(`{
+		"openai": [
+			{
+				"name": "current",
+				"match": {"match_type": "contains", "message": {"role": "user", "content": "Hello"}},
+				"response": {"id": "chatcmpl-1", "object": "chat.completion", "created": 1, "model": "gpt-4o",
+					"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "Hi"}}]}
+			},
+			{
+				"name": "legacy",
+				"match": {"match_type": "contains", "message": {"role": "user", "content": "Weather"}},
+				"response": {"id": "chatcmpl-2", "object": "chat.completion", "created": 1, "model": "gpt-4o",
+					"choices": [{"index": 0, "finish_reason": "function_call", "message": {"role": "assistant", "content": null,
+						"function_call": {"name": "get_weather", "arguments": "{}"}}}]},
+				"seed_responses": {"7": {"id": "chatcmpl-3", "object": "chat.completion", "created": 1, "model": "gpt-4o",
+					"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "Hi"}}], "service_tier": "default"}}
+			}
+		],
+		"anthropic": [
+			{
+				"name": "unchecked",
+				"match": {"match_type": "contains", "message": {"role": "user", "content": "Hello"}},
+				"response": {"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-3-5-sonnet-20240620", "content": []}
+			}
+		]
+	}`)}})
+	require.NoError(t, err)
+
+	drifts, err := mockllm.CheckSpecDrift(config, mockllm.DriftSpecs{OpenAI: spec})
+	require.NoError(t, err)
+	assert.Equal(t, []mockllm.SpecDrift{
+		{
+			Provider: "openai", MockName: "legacy", Path: "openai[1].response.choices[0].finish_reason",
+			Kind: openapi.DriftEnum, Message: "value function_call is not one of [stop length tool_calls]",
+		},
+		{
+			Provider: "openai", MockName: "legacy", Path: "openai[1].response.choices[0].message.function_call",
+			Kind: openapi.DriftDeprecated, Message: "deprecated",
+		},
+		{
+			Provider: "openai", MockName: "legacy", Path: "openai[1].seed_responses.7.service_tier",
+			Kind: openapi.DriftUnknown, Message: "not defined by the schema",
+		},
+	}, drifts)
+
+	_, err = mockllm.CheckSpecDrift(config, mockllm.DriftSpecs{Anthropic: spec})
+	assert.ErrorContains(t, err, `unknown schema "Message"`)
+}
@@ -0,0 +1,103 @@
+package mockllm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// sseEvent is a single server-sent event of a streamed response
+type sseEvent struct {
+	// Event is the optional event name, Anthropic names every event while OpenAI sends data only
+	Event string
+	Data  []byte
+	// Comment makes the event a comment line, ignored by clients, instead
+	Comment string
+}
+
+// newSSEEvent marshals data into an event with the given name
+func newSSEEvent(name string, data any) sseEvent {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		encoded = []byte(fmt.Sprintf(`{"error":%q}`, err.Error()))
+	}
+	return sseEvent{Event: name, Data: encoded}
+}
+
+// isStreamingRequest reports whether a raw request body asks for a streamed response
+func isStreamingRequest(body []byte) bool {
+	var request struct {
+		Stream bool `json:"stream"`
+	}
+	return json.Unmarshal(body, &request) == nil && request.Stream
+}
+
+// writeSSE streams the events to the client, paced as the pacing asks. It stops as soon as the
+// client disconnects and reports how many events were delivered. When the server cuts the stream
+// short on shutdown, the interrupted event is sent to end it.
+func writeSSE(w http.ResponseWriter, r *http.Request, clock Clock, events []sseEvent, pacing ssePacing,
+	interrupted sseEvent) *StreamStats {
+	tracker := streamTrackerFrom(r.Context())
+	tracker.begin()
+	defer tracker.end()
+
+	stats := &StreamStats{EventsTotal: len(events)}
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush() //nolint:errcheck
+
+	stopped := func() *StreamStats {
+		if !tracker.stopping() {
+			stats.Disconnected = true
+			return stats
+		}
+		// Don't let a client that stopped reading hold up the shutdown
+		rc.SetWriteDeadline(time.Now().Add(cutStreamTimeout)) //nolint:errcheck
+		if writeEvent(w, interrupted) == nil {
+			rc.Flush() //nolint:errcheck
+		}
+		tracker.cut.Add(1)
+		stats.Interrupted = true
+		return stats
+	}
+	for i, event := range events {
+		if !pacing.pace(r.Context(), w, clock, i, stats) {
+			return stopped()
+		}
+		if r.Context().Err() != nil || writeEvent(w, event) != nil || rc.Flush() != nil {
+			return stopped()
+		}
+		stats.EventsSent++
+	}
+	return stats
+}
+
+// writeEvent writes a single event in the server-sent events wire format
+func writeEvent(w http.ResponseWriter, event sseEvent) error {
+	if event.Comment != "" {
+		_, err := fmt.Fprintf(w, ": %s\n\n", event.Comment)
+		return err
+	}
+	if event.Event != "" {
+		if _, err := fmt.Fprintf(w, "event: %s\n", event.Event); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "data: %s\n\n", event.Data)
+	return err
+}
+
+// wait blocks for d on the clock, or until ctx is done when d is not positive. It reports whether
+// the full duration elapsed without ctx being cancelled.
+func wait(ctx context.Context, clock Clock, d time.Duration) bool {
+	if d <= 0 {
+		<-ctx.Done()
+		return false
+	}
+	return clock.Sleep(ctx, d)
+}
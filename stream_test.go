@@ -0,0 +1,281 @@
+package mockllm_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	openaioption "github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// helloCompletion is an OpenAI response used by the streaming tests
+var helloCompletion = openai.ChatCompletion{
+	ID:    "chatcmpl-stream",
+	Model: "gpt-4o-mini",
+	Choices: []openai.ChatCompletionChoice{{
+		Message:      openai.ChatCompletionMessage{Role: "assistant", Content: "Hello there, how can I help?"},
+		FinishReason: "stop",
+	}},
+	Usage: openai.CompletionUsage{PromptTokens: 5, CompletionTokens: 7, TotalTokens: 12},
+}
+
+// newStreamingOpenAIClient starts a server with a single mock answering "Hello" and returns a client for it
+func newStreamingOpenAIClient(t *testing.T, fault *mockllm.Fault) openai.Client {
+	t.Helper()
+
+	baseURL := newOpenAIServer(t, mockllm.OpenAIMock{
+		Name: "hello",
+		Match: mockllm.OpenAIRequestMatch{
+			MatchType: mockllm.MatchTypeContains,
+			Message:   openaiUserMessage("Hello"),
+		},
+		Response: helloCompletion,
+		Fault:    fault,
+	})
+	return openai.NewClient(
+		openaioption.WithBaseURL(baseURL+"/v1"),
+		openaioption.WithAPIKey("test-key"),
+		openaioption.WithMaxRetries(0),
+	)
+}
+
+// helloParams is a streaming friendly OpenAI request whose last message says "Hello"
+var helloParams = openai.ChatCompletionNewParams{
+	Model:    "gpt-4o-mini",
+	Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+	StreamOptions: openai.ChatCompletionStreamOptionsParam{
+		IncludeUsage: openai.Bool(true),
+	},
+}
+
+func TestOpenAIStreaming(t *testing.T) {
+	client := newStreamingOpenAIClient(t, nil)
+
+	stream := client.Chat.Completions.NewStreaming(t.Context(), helloParams)
+	var acc openai.ChatCompletionAccumulator
+	chunks := 0
+	for stream.Next() {
+		acc.AddChunk(stream.Current())
+		chunks++
+	}
+	require.NoError(t, stream.Err())
+
+	assert.Greater(t, chunks, 3)
+	require.Len(t, acc.Choices, 1)
+	assert.Equal(t, "Hello there, how can I help?", acc.Choices[0].Message.Content)
+	assert.Equal(t, "stop", acc.Choices[0].FinishReason)
+	assert.Equal(t, int64(12), acc.Usage.TotalTokens)
+}
+
+func TestAnthropicStreaming(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		Anthropic: []mockllm.AnthropicMock{{
+			Name: "hello",
+			Match: mockllm.AnthropicRequestMatch{
+				MatchType: mockllm.MatchTypeExact,
+				Message:   anthropicHelloRequest.Messages[0],
+			},
+			Response: anthropic.Message{
+				ID:    "msg_stream",
+				Model: "claude-3-5-sonnet-20240620",
+				Role:  "assistant",
+				Content: []anthropic.ContentBlockUnion{
+					{Type: "text", Text: "Hello there, how can I help?"},
+				},
+				StopReason: anthropic.StopReasonEndTurn,
+				Usage:      anthropic.Usage{InputTokens: 5, OutputTokens: 7},
+			},
+		}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	client := anthropic.NewClient(
+		anthropicoption.WithBaseURL(baseURL),
+		anthropicoption.WithAPIKey("test-key"),
+		anthropicoption.WithMaxRetries(0),
+	)
+	stream := client.Messages.NewStreaming(t.Context(), anthropicHelloRequest)
+	var message anthropic.Message
+	for stream.Next() {
+		require.NoError(t, message.Accumulate(stream.Current()))
+	}
+	require.NoError(t, stream.Err())
+
+	assert.Equal(t, "msg_stream", message.ID)
+	require.Len(t, message.Content, 1)
+	assert.Equal(t, "Hello there, how can I help?", message.Content[0].Text)
+	assert.Equal(t, anthropic.StopReasonEndTurn, message.StopReason)
+	assert.Equal(t, int64(7), message.Usage.OutputTokens)
+}
+
+func TestFaults(t *testing.T) {
+	t.Run("close", func(t *testing.T) {
+		client := newStreamingOpenAIClient(t, &mockllm.Fault{Type: mockllm.FaultClose})
+		_, err := client.Chat.Completions.New(t.Context(), helloParams)
+		assert.Error(t, err)
+	})
+
+	t.Run("hang after headers", func(t *testing.T) {
+		client := newStreamingOpenAIClient(t, &mockllm.Fault{Type: mockllm.FaultHangAfterHeaders})
+		ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+		defer cancel()
+		_, err := client.Chat.Completions.New(ctx, helloParams)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		client := newStreamingOpenAIClient(t, &mockllm.Fault{
+			Type:     mockllm.FaultTimeout,
+			Duration: mockllm.Duration(100 * time.Millisecond),
+		})
+		_, err := client.Chat.Completions.New(t.Context(), helloParams)
+		assert.Error(t, err)
+	})
+
+	t.Run("stall", func(t *testing.T) {
+		client := newStreamingOpenAIClient(t, &mockllm.Fault{
+			Type:        mockllm.FaultStall,
+			Duration:    mockllm.Duration(300 * time.Millisecond),
+			AfterEvents: 2,
+		})
+		start := time.Now()
+		stream := client.Chat.Completions.NewStreaming(t.Context(), helloParams)
+		var acc openai.ChatCompletionAccumulator
+		for stream.Next() {
+			acc.AddChunk(stream.Current())
+		}
+		require.NoError(t, stream.Err())
+		assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+		assert.Equal(t, "Hello there, how can I help?", acc.Choices[0].Message.Content)
+	})
+
+	t.Run("stall non-streaming", func(t *testing.T) {
+		baseURL := newOpenAIServer(t, mockllm.OpenAIMock{
+			Name: "hello",
+			Match: mockllm.OpenAIRequestMatch{
+				MatchType: mockllm.MatchTypeContains,
+				Message:   openaiUserMessage("Hello"),
+			},
+			Response: helloCompletion,
+			Fault:    &mockllm.Fault{Type: mockllm.FaultStall, Duration: mockllm.Duration(200 * time.Millisecond)},
+		})
+		start := time.Now()
+		completion := postChatCompletion(t, baseURL, helloParams)
+		assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+		assert.Equal(t, "chatcmpl-stream", completion.ID)
+	})
+
+	t.Run("drip", func(t *testing.T) {
+		client := newStreamingOpenAIClient(t, &mockllm.Fault{
+			Type:     mockllm.FaultDrip,
+			Duration: mockllm.Duration(10 * time.Millisecond),
+			Bytes:    100,
+		})
+		start := time.Now()
+		completion, err := client.Chat.Completions.New(t.Context(), helloParams)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond, "every 100 bytes waits 10ms")
+		assert.Equal(t, "Hello there, how can I help?", completion.Choices[0].Message.Content)
+
+		stream := client.Chat.Completions.NewStreaming(t.Context(), helloParams)
+		var acc openai.ChatCompletionAccumulator
+		for stream.Next() {
+			acc.AddChunk(stream.Current())
+		}
+		require.NoError(t, stream.Err())
+		assert.Equal(t, "Hello there, how can I help?", acc.Choices[0].Message.Content)
+	})
+}
+
+func TestFaultsAreLoggedAsDropped(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{{
+			Name:     "hello",
+			Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+			Response: helloCompletion,
+			Fault:    &mockllm.Fault{Type: mockllm.FaultClose},
+		}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(context.Background()) }) //nolint:errcheck
+
+	client := openai.NewClient(
+		openaioption.WithBaseURL(baseURL+"/v1"),
+		openaioption.WithAPIKey("test-key"),
+		openaioption.WithMaxRetries(0),
+	)
+	_, err = client.Chat.Completions.New(t.Context(), helloParams)
+	require.Error(t, err)
+
+	// The mock matched, but the dropped connection is not logged as served
+	records := server.Requests()
+	require.Len(t, records, 1)
+	assert.True(t, records[0].Matched)
+	assert.Equal(t, "hello", records[0].MockName)
+	assert.Equal(t, 0, records[0].Status)
+	assert.Equal(t, "simulated fault: close", records[0].Error)
+}
+
+func TestStreamClientDisconnect(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{OpenAI: []mockllm.OpenAIMock{{
+		Name: "hello",
+		Match: mockllm.OpenAIRequestMatch{
+			MatchType: mockllm.MatchTypeContains,
+			Message:   openaiUserMessage("Hello"),
+		},
+		Response: helloCompletion,
+		Fault:    &mockllm.Fault{Type: mockllm.FaultStall, Duration: mockllm.Duration(time.Hour), AfterEvents: 2},
+	}}}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	events, unsubscribe := server.Subscribe()
+	defer unsubscribe()
+
+	client := openai.NewClient(
+		openaioption.WithBaseURL(baseURL+"/v1"),
+		openaioption.WithAPIKey("test-key"),
+		openaioption.WithMaxRetries(0),
+	)
+	ctx, cancel := context.WithCancel(t.Context())
+	stream := client.Chat.Completions.NewStreaming(ctx, helloParams)
+	for range 2 {
+		require.True(t, stream.Next())
+	}
+	cancel()
+	assert.False(t, stream.Next())
+
+	assert.Eventually(t, func() bool { return server.DisconnectedMidStream("hello") }, time.Second, 10*time.Millisecond)
+	event := <-events
+	assert.Equal(t, mockllm.EventDisconnect, event.Type)
+	require.NotNil(t, event.Request.Stream)
+	assert.Equal(t, 2, event.Request.Stream.EventsSent)
+	assert.Greater(t, event.Request.Stream.EventsTotal, 2)
+
+	assert.False(t, server.DisconnectedMidStream("other"))
+}
+
+func TestFaultValidation(t *testing.T) {
+	_, err := mockllm.LoadConfigFromFile("mocks.json", fstest.MapFS{"mocks.json": {Data: []byte(`{
+  "openai": [
+    {"name": "typo", "match": {"match_type": "contains", "message": {"role": "user", "content": "Hello"}},
+      "response": {"choices": [{"message": {"role": "assistant", "content": "Hi"}}]}, "fault": {"type": "stal"}},
+    {"name": "stall", "match": {"match_type": "contains", "message": {"role": "user", "content": "Hello"}},
+      "response": {"choices": [{"message": {"role": "assistant", "content": "Hi"}}]}, "fault": {"type": "stall"}}
+  ]
+}`)}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `openai[0].fault.type: unknown fault type "stal"`)
+	assert.Contains(t, err.Error(), "openai[1].fault.duration: must be positive for a stall")
+}
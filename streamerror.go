@@ -0,0 +1,67 @@
+package mockllm
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// StreamError fails a streamed response partway through, after some of its content was sent, to
+// test how clients recover partial output. Non-streamed responses are served unchanged.
+type StreamError struct {
+	// AfterEvents is the number of events sent before the error
+	AfterEvents int `json:"after_events,omitempty"`
+	// Type is the error type, overloaded_error for Anthropic and server_error for OpenAI by default
+	Type string `json:"type,omitempty"`
+	// Message is the message of the Anthropic error event, "Overloaded" by default. OpenAI streams,
+	// which are otherwise cut without [DONE], send it as an error object before they are cut.
+	Message string `json:"message,omitempty"`
+}
+
+// anthropicEvents returns the events sent before the error, followed by the error event the
+// Messages API sends when it fails mid-stream
+func (e *StreamError) anthropicEvents(events []sseEvent) []sseEvent {
+	if e == nil {
+		return events
+	}
+	return append(slices.Clip(events[:min(e.AfterEvents, len(events))]), newSSEEvent("error", map[string]any{
+		"type": "error",
+		"error": map[string]any{
+			"type":    cmp.Or(e.Type, "overloaded_error"),
+			"message": cmp.Or(e.Message, "Overloaded"),
+		},
+	}))
+}
+
+// openaiEvents returns the events sent before the connection is cut without [DONE], followed by
+// an error object when the error has a message
+func (e *StreamError) openaiEvents(events []sseEvent) []sseEvent {
+	if e == nil {
+		return events
+	}
+	events = slices.Clip(events[:min(e.AfterEvents, len(events))])
+	if e.Message == "" {
+		return events
+	}
+	return append(events, newSSEEvent("", map[string]any{
+		"error": map[string]any{
+			"message": e.Message,
+			"type":    cmp.Or(e.Type, "server_error"),
+			"param":   nil,
+			"code":    nil,
+		},
+	}))
+}
+
+// reason returns the log reason of a stream failed by the error
+func (e *StreamError) reason() string {
+	return fmt.Sprintf("simulated stream error after %d events", e.AfterEvents)
+}
+
+// streamErrorIssues reports a stream error failing before the start of the stream
+func streamErrorIssues(e *StreamError, path string) []configIssue {
+	if e == nil || e.AfterEvents >= 0 {
+		return nil
+	}
+	return []configIssue{{path + ".after_events", "must not be negative"}}
+}
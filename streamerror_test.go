@@ -0,0 +1,98 @@
+package mockllm_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	openaioption "github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamErrors(t *testing.T) {
+	reply := "The quick brown fox jumps over the lazy dog"
+	openaiMock := func(name string, streamError *mockllm.StreamError) mockllm.OpenAIMock {
+		return mockllm.OpenAIMock{
+			Name:        name,
+			Match:       mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage(name)},
+			Response:    openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: reply}}}},
+			StreamError: streamError,
+		}
+	}
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{
+			openaiMock("cut", &mockllm.StreamError{AfterEvents: 3}),
+			openaiMock("error", &mockllm.StreamError{AfterEvents: 3, Message: "The server had an error"}),
+		},
+		Anthropic: []mockllm.AnthropicMock{{
+			Name:        "overloaded",
+			Match:       mockllm.AnthropicRequestMatch{MatchType: mockllm.MatchTypeExact, Message: anthropicHelloRequest.Messages[0]},
+			Response:    anthropic.Message{Content: []anthropic.ContentBlockUnion{{Type: "text", Text: reply}}},
+			StreamError: &mockllm.StreamError{AfterEvents: 4},
+		}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+
+	t.Run("anthropic", func(t *testing.T) {
+		client := anthropic.NewClient(anthropicoption.WithBaseURL(baseURL), anthropicoption.WithAPIKey("test-key"))
+		stream := client.Messages.NewStreaming(t.Context(), anthropicHelloRequest)
+		var message anthropic.Message
+		for stream.Next() {
+			require.NoError(t, message.Accumulate(stream.Current()))
+		}
+		require.Error(t, stream.Err())
+		assert.Contains(t, stream.Err().Error(), "overloaded_error")
+		require.Len(t, message.Content, 1)
+		assert.NotEmpty(t, message.Content[0].Text, "the partial output was sent")
+		assert.NotEqual(t, reply, message.Content[0].Text)
+	})
+
+	client := openai.NewClient(openaioption.WithBaseURL(baseURL+"/v1"), openaioption.WithAPIKey("test-key"))
+	stream := func(t *testing.T, content string) (string, error) {
+		stream := client.Chat.Completions.NewStreaming(t.Context(), openai.ChatCompletionNewParams{
+			Model:    "gpt-4o",
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage(content)},
+		})
+		var completion openai.ChatCompletionAccumulator
+		for stream.Next() {
+			completion.AddChunk(stream.Current())
+		}
+		require.NotEmpty(t, completion.Choices)
+		return completion.Choices[0].Message.Content, stream.Err()
+	}
+
+	t.Run("openai cut", func(t *testing.T) {
+		content, err := stream(t, "cut")
+		require.Error(t, err, "the stream ends without [DONE]")
+		assert.NotEmpty(t, content)
+		assert.NotEqual(t, reply, content)
+	})
+
+	t.Run("openai error", func(t *testing.T) {
+		content, err := stream(t, "error")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "The server had an error")
+		assert.NotEmpty(t, content)
+	})
+
+	requests := server.Requests()
+	require.Len(t, requests, 3)
+	for _, record := range requests {
+		assert.Contains(t, record.Error, "simulated stream error after")
+	}
+}
+
+func TestStreamErrorValidation(t *testing.T) {
+	_, err := mockllm.LoadConfigFromFile("mocks.json", fstest.MapFS{"mocks.json": {Data: []byte(`{
+  "anthropic": [{"name": "overloaded", "match": {"match_type": "contains", "message": {"role": "user", "content": "Hello"}},
+    "response": {"content": [{"type": "text", "text": "Hi"}]}, "stream_error": {"after_events": -1}}]
+}`)}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "anthropic[0].stream_error.after_events: must not be negative")
+}
@@ -0,0 +1,89 @@
+package mockllm
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// StreamRetryMode is how the retries of a cut stream are served
+type StreamRetryMode string
+
+const (
+	// StreamRetryRestart streams the whole response again
+	StreamRetryRestart StreamRetryMode = "restart"
+	// StreamRetryResume streams the events after those the cut attempts sent
+	StreamRetryResume StreamRetryMode = "resume"
+)
+
+// StreamRetry cuts the first streamed attempts of a request partway through, to validate the
+// stream retry logic of clients. Identical requests served by the mock are attempts of the same
+// request, until one of them streams the rest of the response.
+type StreamRetry struct {
+	// CutAfter is the number of events an attempt sends before its connection is closed
+	CutAfter int `json:"cut_after"`
+	// Mode is how the retries are served, StreamRetryRestart by default
+	Mode StreamRetryMode `json:"mode,omitempty"`
+	// Attempts is the number of attempts cut, one by default
+	Attempts int `json:"attempts,omitempty"`
+}
+
+// streamAttemptKey identifies the attempts of a request
+type streamAttemptKey struct {
+	mockName string
+	body     [sha256.Size]byte
+}
+
+// streamAttempts counts the cut attempts of the requests served by stream retry mocks
+type streamAttempts struct {
+	mu   sync.Mutex
+	next map[streamAttemptKey]int
+}
+
+func newStreamAttempts() *streamAttempts {
+	return &streamAttempts{next: map[streamAttemptKey]int{}}
+}
+
+// attempt returns the events the next attempt of a request streams, whether its connection is
+// closed after them, and the attempt's one-based number, zero without a stream retry. The attempts
+// start over once one of them streams the rest of the response.
+func (a *streamAttempts) attempt(retry *StreamRetry, mockName string, body []byte,
+	events []sseEvent) ([]sseEvent, bool, int) {
+	if retry == nil {
+		return events, false, 0
+	}
+	key := streamAttemptKey{mockName: mockName, body: sha256.Sum256(body)}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	attempt := a.next[key]
+	start := 0
+	if retry.Mode == StreamRetryResume {
+		start = min(attempt*retry.CutAfter, len(events))
+	}
+	if attempt < max(retry.Attempts, 1) {
+		a.next[key] = attempt + 1
+		return slices.Clip(events[start:min(start+retry.CutAfter, len(events))]), true, attempt + 1
+	}
+	delete(a.next, key)
+	return events[start:], false, attempt + 1
+}
+
+// streamRetryIssues reports the invalid settings of a stream retry
+func streamRetryIssues(retry *StreamRetry, path string) []configIssue {
+	if retry == nil {
+		return nil
+	}
+	var issues []configIssue
+	if retry.CutAfter < 0 {
+		issues = append(issues, configIssue{path + ".cut_after", "must not be negative"})
+	}
+	if retry.Attempts < 0 {
+		issues = append(issues, configIssue{path + ".attempts", "must not be negative"})
+	}
+	if retry.Mode != "" && retry.Mode != StreamRetryRestart && retry.Mode != StreamRetryResume {
+		issues = append(issues, configIssue{path + ".mode", fmt.Sprintf("unknown stream retry mode %q", retry.Mode)})
+	}
+	return issues
+}
@@ -0,0 +1,108 @@
+package mockllm_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamRetries(t *testing.T) {
+	config, err := mockllm.ParseScenarios("retry.feature", []byte(`Scenario: restart
+    When the user says "Hello"
+    Then reply "The quick brown fox jumps over the lazy dog"
+    And cut the stream after 3 events and restart it
+
+  Scenario: resume
+    When the user says "Resume"
+    Then reply "The quick brown fox jumps over the lazy dog"
+    And cut the stream after 3 events and resume it
+`))
+	require.NoError(t, err)
+	require.Equal(t, &mockllm.StreamRetry{CutAfter: 3, Mode: mockllm.StreamRetryResume}, config.OpenAI[1].StreamRetry)
+	config.Anthropic[0].StreamRetry.Attempts = 2
+	server := mockllm.NewServer(mockllm.WithConfig(config))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Stop(t.Context()) }) //nolint:errcheck
+
+	t.Run("restart", func(t *testing.T) {
+		client := anthropic.NewClient(anthropicoption.WithBaseURL(baseURL), anthropicoption.WithAPIKey("test-key"))
+		stream := func() (anthropic.Message, error) {
+			stream := client.Messages.NewStreaming(t.Context(), anthropicHelloRequest)
+			var message anthropic.Message
+			for stream.Next() {
+				require.NoError(t, message.Accumulate(stream.Current()))
+			}
+			return message, stream.Err()
+		}
+
+		for range 2 {
+			_, err := stream()
+			require.Error(t, err, "the first attempts are cut")
+		}
+		message, err := stream()
+		require.NoError(t, err)
+		assert.Equal(t, "The quick brown fox jumps over the lazy dog", message.Content[0].Text)
+		_, err = stream()
+		require.Error(t, err, "the attempts start over once one is served")
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		stream := func() string {
+			resp := postJSON(t, baseURL+"/v1/chat/completions", map[string]any{
+				"model": "gpt-4o", "messages": []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Resume")}, "stream": true,
+			}, map[string]string{"Authorization": "Bearer test-key"})
+			defer resp.Body.Close() //nolint:errcheck
+			body, _ := io.ReadAll(resp.Body)
+			return string(body)
+		}
+
+		first := stream()
+		assert.Equal(t, 3, strings.Count(first, "data: "))
+		assert.NotContains(t, first, "[DONE]")
+		second := stream()
+		assert.NotContains(t, second, `"role":"assistant"`, "the retry resumes after the events already sent")
+		assert.True(t, strings.HasSuffix(second, "data: [DONE]\n\n"))
+
+		var content strings.Builder
+		for _, line := range strings.Split(first+second, "\n") {
+			data, ok := strings.CutPrefix(line, "data: ")
+			if !ok || data == "[DONE]" {
+				continue
+			}
+			var chunk openai.ChatCompletionChunk
+			require.NoError(t, chunk.UnmarshalJSON([]byte(data)))
+			if len(chunk.Choices) > 0 {
+				content.WriteString(chunk.Choices[0].Delta.Content)
+			}
+		}
+		assert.Equal(t, "The quick brown fox jumps over the lazy dog", content.String())
+	})
+
+	var attempts []int
+	for _, record := range server.Requests() {
+		require.NotNil(t, record.Stream)
+		attempts = append(attempts, record.Stream.Attempt)
+		if record.Stream.Attempt == 1 {
+			assert.Equal(t, "simulated disconnect of attempt 1", record.Error)
+		}
+	}
+	assert.Equal(t, []int{1, 2, 3, 1, 1, 2}, attempts)
+}
+
+func TestStreamRetryValidation(t *testing.T) {
+	_, err := mockllm.LoadConfigFromFile("mocks.json", fstest.MapFS{"mocks.json": {Data: []byte(`{
+  "openai": [{"name": "retry", "match": {"match_type": "contains", "message": {"role": "user", "content": "Hello"}},
+    "response": {"choices": [{"message": {"content": "Hi"}}]}, "stream_retry": {"cut_after": 2, "mode": "rewind"}}]
+}`)}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `openai[0].stream_retry.mode: unknown stream retry mode "rewind"`)
+}
@@ -0,0 +1,87 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// TagsEnvVar is the environment variable selecting the tags of the mocks served, overriding
+// Config.Tags, see ParseTagSelection
+const TagsEnvVar = "MOCKLLM_TAGS"
+
+// TagSelection picks the mocks served by their tags, so one shared config can drive different test
+// suites. Mocks without tags are always served. Tagged mocks are served unless one of their tags is
+// disabled and, when any tags are enabled, only if one of their tags is.
+type TagSelection struct {
+	Enabled  []string `json:"enabled,omitempty"`
+	Disabled []string `json:"disabled,omitempty"`
+}
+
+// ParseTagSelection parses a selection written as comma separated tags, those prefixed with "-"
+// being disabled and the others enabled, e.g. "smoke,billing,-slow"
+func ParseTagSelection(s string) TagSelection {
+	var selection TagSelection
+	for _, tag := range strings.Split(s, ",") {
+		tag = strings.TrimSpace(tag)
+		switch {
+		case strings.HasPrefix(tag, "-") && len(tag) > 1:
+			selection.Disabled = append(selection.Disabled, tag[1:])
+		case tag != "" && tag != "-":
+			selection.Enabled = append(selection.Enabled, tag)
+		}
+	}
+	return selection
+}
+
+// String writes the selection as ParseTagSelection parses it
+func (t TagSelection) String() string {
+	tags := slices.Clone(t.Enabled)
+	for _, tag := range t.Disabled {
+		tags = append(tags, "-"+tag)
+	}
+	return strings.Join(tags, ",")
+}
+
+// disabledReason tells why the selection keeps mocks with the given tags from being served, empty
+// when it serves them
+func (t TagSelection) disabledReason(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	for _, tag := range tags {
+		if slices.Contains(t.Disabled, tag) {
+			return fmt.Sprintf("tag %q is disabled", tag)
+		}
+	}
+	if len(t.Enabled) == 0 || slices.ContainsFunc(tags, func(tag string) bool { return slices.Contains(t.Enabled, tag) }) {
+		return ""
+	}
+	return fmt.Sprintf("none of the tags %v is enabled", tags)
+}
+
+// Tags returns the tag selection picking the mocks served
+func (s *Server) Tags() TagSelection {
+	return s.switches.tags()
+}
+
+// SetTags changes the tag selection picking the mocks served, e.g. between test suites sharing a
+// server. It applies to the requests received from then on.
+func (s *Server) SetTags(selection TagSelection) {
+	s.switches.setTags(selection)
+}
+
+// handleAdminTags returns the tag selection on GET, and replaces it with the one in the body on PUT
+func (s *Server) handleAdminTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPut {
+		var selection TagSelection
+		if err := json.NewDecoder(r.Body).Decode(&selection); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": fmt.Sprintf("Invalid JSON: %v", err)})
+			return
+		}
+		s.SetTags(selection)
+	}
+	writeJSON(w, http.StatusOK, s.Tags())
+}
@@ -0,0 +1,87 @@
+package mockllm_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockTags(t *testing.T) {
+	reply := func(content string) openai.ChatCompletion {
+		return openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: content}}}}
+	}
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{
+			{
+				Name:     "slow",
+				Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+				Response: reply("slow"),
+				Tags:     []string{"smoke", "slow"},
+			},
+			{
+				Name:     "billing",
+				Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+				Response: reply("billing"),
+				Tags:     []string{"billing"},
+			},
+			{
+				Name:     "untagged",
+				Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+				Response: reply("untagged"),
+			},
+		},
+		Tags: &mockllm.TagSelection{Disabled: []string{"slow"}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	hello := func() string {
+		return postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+			Model:    "gpt-4o-mini",
+			Messages: []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+		}).Choices[0].Message.Content
+	}
+	assert.Equal(t, "billing", hello(), "mocks with a disabled tag are not served")
+
+	req, err := http.NewRequest(http.MethodPut, baseURL+"/admin/tags", strings.NewReader(`{"enabled": ["smoke"]}`))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, mockllm.TagSelection{Enabled: []string{"smoke"}}, server.Tags())
+	assert.Equal(t, "slow", hello())
+
+	server.SetTags(mockllm.TagSelection{Enabled: []string{"nightly"}})
+	assert.Equal(t, "untagged", hello(), "untagged mocks are always served")
+
+	resp, err = http.Get(baseURL + "/admin/mocks")
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+	var mocks []mockllm.MockSummary
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&mocks))
+	require.Len(t, mocks, 3)
+	assert.True(t, mocks[0].Disabled)
+	assert.Equal(t, []string{"smoke", "slow"}, mocks[0].Tags)
+	assert.False(t, mocks[2].Disabled)
+
+	explanations, err := server.WhichMockMatches([]byte(`{"model": "gpt-4o-mini", "messages": [{"role": "user", "content": "Hello"}]}`), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "none of the tags [billing] is enabled", explanations[1].Reason)
+}
+
+func TestMockTagsEnvVar(t *testing.T) {
+	t.Setenv(mockllm.TagsEnvVar, "smoke, -slow")
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{Tags: &mockllm.TagSelection{Enabled: []string{"billing"}}}))
+	assert.Equal(t, mockllm.TagSelection{Enabled: []string{"smoke"}, Disabled: []string{"slow"}}, server.Tags(),
+		"the environment overrides the config")
+	assert.Equal(t, "smoke,-slow", server.Tags().String())
+}
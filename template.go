@@ -0,0 +1,164 @@
+package mockllm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// UnmarshalJSON decodes a config, first expanding the mocks that extend templates
+func (c *Config) UnmarshalJSON(data []byte) error {
+	expanded, err := expandTemplates(data)
+	if err != nil {
+		return err
+	}
+	type plain Config
+	return json.Unmarshal(expanded, (*plain)(c))
+}
+
+// expandTemplates merges the templates named by the "extends" member of every mock into the mock.
+// Objects are merged recursively with the mock's own members taking precedence, other values
+// replace those of the template. Templates can extend other templates in turn.
+func expandTemplates(data []byte) ([]byte, error) {
+	var document map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil || document["templates"] == nil {
+		// Leave reporting malformed configs to the decoder of the config itself
+		return data, nil
+	}
+	templates, ok := document["templates"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("templates must be an object of partial mocks")
+	}
+
+	resolved := map[string]map[string]any{}
+	var resolve func(name string, chain []string) (map[string]any, error)
+	extend := func(object map[string]any, chain []string) (map[string]any, error) {
+		names, err := extendedTemplates(object["extends"])
+		if err != nil {
+			return nil, err
+		}
+		var merged any = map[string]any{}
+		for _, name := range names {
+			template, err := resolve(name, chain)
+			if err != nil {
+				return nil, err
+			}
+			merged = mergeJSON(merged, template)
+		}
+		own := make(map[string]any, len(object))
+		for key, value := range object {
+			if key != "extends" {
+				own[key] = value
+			}
+		}
+		return mergeJSON(merged, own).(map[string]any), nil
+	}
+	resolve = func(name string, chain []string) (map[string]any, error) {
+		if template, ok := resolved[name]; ok {
+			return template, nil
+		}
+		for _, seen := range chain {
+			if seen == name {
+				return nil, fmt.Errorf("template cycle: %s -> %s", strings.Join(chain, " -> "), name)
+			}
+		}
+		template, ok := templates[name].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unknown template %q", name)
+		}
+		template, err := extend(template, append(chain, name))
+		if err != nil {
+			return nil, err
+		}
+		resolved[name] = template
+		return template, nil
+	}
+
+	extendMocks := func(section string, mocks any) error {
+		list, _ := mocks.([]any)
+		for i, mock := range list {
+			object, ok := mock.(map[string]any)
+			if !ok || object["extends"] == nil {
+				continue
+			}
+			extended, err := extend(object, nil)
+			if err != nil {
+				return fmt.Errorf("%s[%d]: %w", section, i, err)
+			}
+			list[i] = extended
+		}
+		return nil
+	}
+	for _, section := range []string{"openai", "anthropic", "gemini", "openai_responses", "http"} {
+		if err := extendMocks(section, document[section]); err != nil {
+			return nil, err
+		}
+	}
+	tenants, _ := document["tenants"].([]any)
+	for i, tenant := range tenants {
+		object, _ := tenant.(map[string]any)
+		for _, section := range []string{"openai", "anthropic"} {
+			if err := extendMocks(fmt.Sprintf("tenants[%d].%s", i, section), object[section]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	organizations, _ := document["openai_organizations"].([]any)
+	for i, organization := range organizations {
+		object, _ := organization.(map[string]any)
+		section := fmt.Sprintf("openai_organizations[%d]", i)
+		if err := extendMocks(section+".openai", object["openai"]); err != nil {
+			return nil, err
+		}
+		projects, _ := object["projects"].([]any)
+		for j, project := range projects {
+			object, _ := project.(map[string]any)
+			if err := extendMocks(fmt.Sprintf("%s.projects[%d].openai", section, j), object["openai"]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return json.Marshal(document)
+}
+
+// extendedTemplates returns the template names of an "extends" member, a name or a list of names
+func extendedTemplates(extends any) ([]string, error) {
+	switch extends := extends.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{extends}, nil
+	case []any:
+		names := make([]string, 0, len(extends))
+		for _, name := range extends {
+			s, ok := name.(string)
+			if !ok {
+				return nil, fmt.Errorf("extends must list template names, got %v", name)
+			}
+			names = append(names, s)
+		}
+		return names, nil
+	default:
+		return nil, fmt.Errorf("extends must be a template name or a list of names, got %v", extends)
+	}
+}
+
+// mergeJSON merges override into base without modifying either
+func mergeJSON(base, override any) any {
+	baseObject, ok := base.(map[string]any)
+	overrideObject, ok2 := override.(map[string]any)
+	if !ok || !ok2 {
+		return override
+	}
+	merged := make(map[string]any, len(baseObject)+len(overrideObject))
+	for key, value := range baseObject {
+		merged[key] = value
+	}
+	for key, value := range overrideObject {
+		merged[key] = mergeJSON(baseObject[key], value)
+	}
+	return merged
+}
@@ -0,0 +1,70 @@
+package mockllm_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigTemplates(t *testing.T) {
+	config, err := mockllm.LoadConfigFromFile("mocks.json", fstest.MapFS{"mocks.json": {Data: []byte(`{
+  "templates": {
+    "gpt": {
+      "response": {"model": "gpt-4o", "usage": {"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15}}
+    },
+    "slow-gpt": {"extends": "gpt", "fault": {"type": "stall", "duration": "1s"}},
+    "contains-report": {"match": {"match_type": "contains", "text": "report"}}
+  },
+  "openai": [
+    {
+      "name": "hello",
+      "extends": "slow-gpt",
+      "match": {"match_type": "contains", "message": {"role": "user", "content": "Hello"}},
+      "response": {"usage": {"total_tokens": 16}, "choices": [{"message": {"content": "Hi"}}]}
+    }
+  ],
+  "tenants": [
+    {"name": "acme", "api_keys": ["acme"], "openai": [{"name": "bye", "extends": ["gpt"], "response": {"choices": [{"message": {"content": "Bye"}}]}}]}
+  ],
+  "openai_organizations": [
+    {"id": "org-a", "projects": [{"id": "proj-1", "openai": [{"name": "project", "extends": "gpt"}]}]}
+  ],
+  "gemini": [{"name": "gemini", "extends": "contains-report", "response": {"modelVersion": "gemini-2.0-flash"}}],
+  "openai_responses": [{"name": "responses", "extends": "contains-report", "response": {"output": [{"type": "message", "text": "Report"}]}}]
+}`)}})
+	require.NoError(t, err)
+
+	require.Len(t, config.OpenAI, 1)
+	hello := config.OpenAI[0]
+	assert.Equal(t, "gpt-4o", hello.Response.Model)
+	assert.EqualValues(t, 10, hello.Response.Usage.PromptTokens)
+	assert.EqualValues(t, 16, hello.Response.Usage.TotalTokens)
+	require.Len(t, hello.Response.Choices, 1)
+	assert.Equal(t, "Hi", hello.Response.Choices[0].Message.Content)
+	require.NotNil(t, hello.Fault)
+	assert.Equal(t, mockllm.FaultStall, hello.Fault.Type)
+
+	bye := config.Tenants[0].OpenAI[0]
+	assert.Equal(t, "gpt-4o", bye.Response.Model)
+	assert.Nil(t, bye.Fault)
+
+	// Templates apply to every kind of mock
+	assert.Equal(t, "gpt-4o", config.OpenAIOrganizations[0].Projects[0].OpenAI[0].Response.Model)
+	assert.Equal(t, mockllm.MatchTypeContains, config.Gemini[0].Match.MatchType)
+	assert.Equal(t, "gemini-2.0-flash", config.Gemini[0].Response.ModelVersion)
+	assert.Equal(t, "report", config.OpenAIResponses[0].Match.Text)
+}
+
+func TestConfigTemplateErrors(t *testing.T) {
+	for message, config := range map[string]string{
+		`openai[0]: unknown template "missing"`:  `{"templates": {}, "openai": [{"extends": "missing"}]}`,
+		"openai[0]: template cycle: a -> b -> a": `{"templates": {"a": {"extends": "b"}, "b": {"extends": "a"}}, "openai": [{"extends": "a"}]}`,
+	} {
+		_, err := mockllm.LoadConfigFromFile("mocks.json", fstest.MapFS{"mocks.json": {Data: []byte(config)}})
+		require.Error(t, err, message)
+		assert.Contains(t, err.Error(), message)
+	}
+}
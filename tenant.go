@@ -0,0 +1,147 @@
+package mockllm
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TenantConfig is a virtual tenant of the server, selected by the API key of a request. Requests
+// of a tenant are matched against its own mocks only, and are subject to its own rate limit.
+type TenantConfig struct {
+	Name string `json:"name"`
+	// APIKeys are the keys identifying the tenant, sent as a Bearer token or in x-api-key
+	APIKeys   []string        `json:"api_keys"`
+	OpenAI    []OpenAIMock    `json:"openai,omitempty"`
+	Anthropic []AnthropicMock `json:"anthropic,omitempty"`
+	// RateLimit rejects requests of the tenant with a 429 once it is exceeded
+	RateLimit *RateLimitConfig `json:"rate_limit,omitempty"`
+}
+
+// RateLimitConfig allows a number of requests per fixed time window
+type RateLimitConfig struct {
+	Requests int `json:"requests"`
+	// Window is the length of the rate limit window, one minute when unset
+	Window Duration `json:"window,omitempty"`
+}
+
+// tenant holds the providers and rate limit state of a configured tenant
+type tenant struct {
+	config    TenantConfig
+	openai    *OpenAIProvider
+	anthropic *AnthropicProvider
+	limiter   *rateLimiter
+}
+
+// providerFor returns the tenant's counterpart of a built-in provider, or nil for custom providers
+func (t *tenant) providerFor(provider Provider) Provider {
+	switch provider.(type) {
+	case *OpenAIProvider:
+		return t.openai
+	case *AnthropicProvider:
+		return t.anthropic
+	default:
+		return nil
+	}
+}
+
+// tenantRouted dispatches requests carrying the API key of a tenant to the tenant's provider,
+// and all other requests to next
+func (s *Server) tenantRouted(provider Provider, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t := s.tenants[requestAPIKey(r)]
+		if t == nil || t.providerFor(provider) == nil {
+			next(w, r)
+			return
+		}
+
+		if retryAfter, ok := t.limiter.allow(s.clock.Now()); !ok {
+			record := s.requestLog.newRecord(r, builtinProviderName(provider), nil)
+			record.Tenant, record.Status, record.Error = t.config.Name, http.StatusTooManyRequests, "rate limit exceeded"
+			s.requestLog.Add(&record)
+
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			writeProviderError(w, provider, http.StatusTooManyRequests,
+				fmt.Sprintf("Rate limit of %d requests exceeded for tenant %s.", t.config.RateLimit.Requests, t.config.Name))
+			return
+		}
+		t.providerFor(provider).Handle(w, r)
+	}
+}
+
+// requestAPIKey returns the API key of a request, sent either as a Bearer token or in x-api-key
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get("x-api-key"); key != "" {
+		return key
+	}
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// redactAPIKey keeps enough of an API key to tell keys apart in the request log without revealing
+// them: the first three and last four characters, or only the last half of short keys
+func redactAPIKey(key string) string {
+	if key == "" {
+		return ""
+	}
+	if len(key) < 12 {
+		return "..." + key[len(key)-min(4, len(key)/2):]
+	}
+	return key[:3] + "..." + key[len(key)-4:]
+}
+
+// builtinProviderName returns the request log name of a built-in provider
+func builtinProviderName(provider Provider) string {
+	switch provider.(type) {
+	case *OpenAIProvider:
+		return providerOpenAI
+	case *AnthropicProvider:
+		return providerAnthropic
+	case *GeminiProvider:
+		return providerGemini
+	default:
+		return ""
+	}
+}
+
+// rateLimiter is a fixed window request counter
+type rateLimiter struct {
+	mu          sync.Mutex
+	limit       int
+	window      time.Duration
+	windowStart time.Time
+	count       int
+}
+
+func newRateLimiter(config *RateLimitConfig) *rateLimiter {
+	if config == nil || config.Requests <= 0 {
+		return nil
+	}
+	window := time.Duration(config.Window)
+	if window <= 0 {
+		window = time.Minute
+	}
+	return &rateLimiter{limit: config.Requests, window: window}
+}
+
+// allow counts a request made at now and reports whether it is within the limit, along with the
+// time until the current window ends. A nil limiter allows every request.
+func (l *rateLimiter) allow(now time.Time) (time.Duration, bool) {
+	if l == nil {
+		return 0, true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.windowStart) >= l.window {
+		l.windowStart, l.count = now, 0
+	}
+	if l.count >= l.limit {
+		return l.windowStart.Add(l.window).Sub(now), false
+	}
+	l.count++
+	return 0, true
+}
@@ -0,0 +1,75 @@
+package mockllm_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenants(t *testing.T) {
+	helloMock := func(name, id string) mockllm.OpenAIMock {
+		return mockllm.OpenAIMock{
+			Name: name,
+			Match: mockllm.OpenAIRequestMatch{
+				MatchType: mockllm.MatchTypeContains,
+				Message:   openaiUserMessage("Hello"),
+			},
+			Response: openai.ChatCompletion{ID: id},
+		}
+	}
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{helloMock("default", "chatcmpl-default")},
+		Tenants: []mockllm.TenantConfig{
+			{
+				Name:    "team-a",
+				APIKeys: []string{"key-a", "key-a2"},
+				OpenAI:  []mockllm.OpenAIMock{helloMock("team-a", "chatcmpl-a")},
+			},
+			{
+				Name:      "team-b",
+				APIKeys:   []string{"key-b"},
+				OpenAI:    []mockllm.OpenAIMock{helloMock("team-b", "chatcmpl-b")},
+				RateLimit: &mockllm.RateLimitConfig{Requests: 1},
+			},
+		},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	post := func(key string) *http.Response {
+		return postJSON(t, baseURL+"/v1/chat/completions", helloParams, map[string]string{"Authorization": "Bearer " + key})
+	}
+	completionID := func(resp *http.Response) string {
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var completion openai.ChatCompletion
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&completion))
+		return completion.ID
+	}
+
+	assert.Equal(t, "chatcmpl-default", completionID(post("unknown")))
+	assert.Equal(t, "chatcmpl-a", completionID(post("key-a")))
+	assert.Equal(t, "chatcmpl-a", completionID(post("key-a2")))
+	assert.Equal(t, "chatcmpl-b", completionID(post("key-b")))
+
+	limited := post("key-b")
+	assert.Equal(t, http.StatusTooManyRequests, limited.StatusCode)
+	assert.NotEmpty(t, limited.Header.Get("Retry-After"))
+
+	assert.Len(t, server.TenantRequests("team-a"), 2)
+	teamB := server.TenantRequests("team-b")
+	require.Len(t, teamB, 2)
+	assert.Equal(t, http.StatusTooManyRequests, teamB[1].Status)
+
+	hits := map[string]int{}
+	for _, mock := range server.Mocks() {
+		hits[mock.Tenant+"/"+mock.Name] = mock.Hits
+	}
+	assert.Equal(t, map[string]int{"/default": 1, "team-a/team-a": 2, "team-b/team-b": 1}, hits)
+}
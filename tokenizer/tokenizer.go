@@ -0,0 +1,96 @@
+// Package tokenizer provides the tokenizer mockllm uses to estimate token usage, enforce
+// max_tokens and pace streamed responses.
+//
+// The default Fake tokenizer is deterministic and dependency free but only approximates the
+// tokenizers of real models. Tests that need accurate counts can plug in a real tokenizer such
+// as tiktoken through the Tokenizer interface, e.g. with Func:
+//
+//	enc, _ := tiktoken.GetEncoding("o200k_base")
+//	config.Tokenizer = tokenizer.Func(func(text string) []string {
+//		var tokens []string
+//		for _, id := range enc.Encode(text, nil, nil) {
+//			tokens = append(tokens, enc.Decode([]int{id}))
+//		}
+//		return tokens
+//	})
+package tokenizer
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Tokenizer splits text into tokens. Implementations must be deterministic and return tokens that
+// concatenate back to the original text.
+type Tokenizer interface {
+	Tokenize(text string) []string
+}
+
+// Func adapts a function to the Tokenizer interface
+type Func func(text string) []string
+
+// Tokenize calls f
+func (f Func) Tokenize(text string) []string {
+	return f(text)
+}
+
+// DefaultMaxTokenLength is the token length of a zero Fake tokenizer
+const DefaultMaxTokenLength = 8
+
+// wordPattern splits text into words that keep their leading whitespace, and trailing whitespace
+var wordPattern = regexp.MustCompile(`\s*\S+|\s+`)
+
+// Fake is a deterministic tokenizer that splits text into words, keeping leading whitespace and
+// punctuation attached, and splits long words into pieces of at most MaxTokenLength runes
+type Fake struct {
+	// MaxTokenLength is the largest number of non-whitespace runes in a token,
+	// DefaultMaxTokenLength when zero
+	MaxTokenLength int
+}
+
+// Tokenize splits text into tokens
+func (f Fake) Tokenize(text string) []string {
+	maxLength := f.MaxTokenLength
+	if maxLength <= 0 {
+		maxLength = DefaultMaxTokenLength
+	}
+
+	var tokens []string
+	for _, word := range wordPattern.FindAllString(text, -1) {
+		trimmed := strings.TrimLeft(word, " \t\r\n")
+		leading := word[:len(word)-len(trimmed)]
+		// Cut maxLength runes at a time, walking the word once
+		for {
+			end := 0
+			for n := 0; n < maxLength && end < len(trimmed); n++ {
+				_, size := utf8.DecodeRuneInString(trimmed[end:])
+				end += size
+			}
+			if end == len(trimmed) {
+				tokens = append(tokens, leading+trimmed)
+				break
+			}
+			tokens = append(tokens, leading+trimmed[:end])
+			leading, trimmed = "", trimmed[end:]
+		}
+	}
+	return tokens
+}
+
+// Default is the tokenizer used when none is configured
+var Default Tokenizer = Fake{}
+
+// Count returns the number of tokens in text
+func Count(t Tokenizer, text string) int {
+	return len(t.Tokenize(text))
+}
+
+// Truncate shortens text to at most maxTokens tokens and reports whether it was truncated
+func Truncate(t Tokenizer, text string, maxTokens int) (string, bool) {
+	tokens := t.Tokenize(text)
+	if len(tokens) <= maxTokens {
+		return text, false
+	}
+	return strings.Join(tokens[:max(maxTokens, 0)], ""), true
+}
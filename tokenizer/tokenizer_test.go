@@ -0,0 +1,58 @@
+package tokenizer_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kagent-dev/mockllm/tokenizer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFake(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxTokenLength int
+		text           string
+		want           []string
+	}{
+		{name: "words", text: "Hi there, friend", want: []string{"Hi", " there,", " friend"}},
+		{name: "whitespace", text: "  a\n\nb ", want: []string{"  a", "\n\nb", " "}},
+		{name: "long word", text: "Hello supercalifragilistic", want: []string{"Hello", " supercal", "ifragili", "stic"}},
+		{name: "custom length", maxTokenLength: 2, text: "héllo", want: []string{"hé", "ll", "o"}},
+		{name: "empty", text: "", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := tokenizer.Fake{MaxTokenLength: tt.maxTokenLength}.Tokenize(tt.text)
+			assert.Equal(t, tt.want, tokens)
+			assert.Equal(t, tt.text, strings.Join(tokens, ""))
+		})
+	}
+}
+
+func TestFakeLongWord(t *testing.T) {
+	// Words are split in a single pass, so a word of a mebibyte takes milliseconds rather than seconds
+	word := strings.Repeat("aé", 1<<19)
+	started := time.Now()
+	tokens := tokenizer.Default.Tokenize(" " + word)
+	assert.Less(t, time.Since(started), 5*time.Second)
+	assert.Len(t, tokens, 1<<20/tokenizer.DefaultMaxTokenLength)
+	assert.Equal(t, " aéaéaéaé", tokens[0])
+	assert.Equal(t, " "+word, strings.Join(tokens, ""))
+}
+
+func TestCountAndTruncate(t *testing.T) {
+	assert.Equal(t, 4, tokenizer.Count(tokenizer.Default, "one two three four"))
+
+	truncated, ok := tokenizer.Truncate(tokenizer.Default, "one two three four", 2)
+	assert.True(t, ok)
+	assert.Equal(t, "one two", truncated)
+
+	text, ok := tokenizer.Truncate(tokenizer.Default, "one two", 2)
+	assert.False(t, ok)
+	assert.Equal(t, "one two", text)
+
+	chars := tokenizer.Func(func(text string) []string { return strings.Split(text, "") })
+	assert.Equal(t, 3, tokenizer.Count(chars, "abc"))
+}
@@ -0,0 +1,116 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/kagent-dev/mockllm/tokenizer"
+	"github.com/openai/openai-go"
+)
+
+// messageTokenOverhead is the number of tokens counted per message for the role and delimiters
+const messageTokenOverhead = 3
+
+// promptTokens estimates the tokens of a request by counting the tokens of every string it contains
+func promptTokens(tok tokenizer.Tokenizer, request any, messages int) int64 {
+	encoded, err := json.Marshal(request)
+	if err != nil {
+		return 0
+	}
+	var decoded any
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return 0
+	}
+	return int64(tokenizer.Count(tok, strings.Join(jsonStrings(decoded), " ")) + messages*messageTokenOverhead)
+}
+
+// jsonStrings returns the string values nested in a decoded JSON value, in a stable order
+func jsonStrings(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var strs []string
+		for _, item := range v {
+			strs = append(strs, jsonStrings(item)...)
+		}
+		return strs
+	case map[string]any:
+		var strs []string
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			strs = append(strs, jsonStrings(v[key])...)
+		}
+		return strs
+	default:
+		return nil
+	}
+}
+
+// truncateOpenAIChoices cuts the content of every choice down to maxTokens, finishing truncated
+// choices with the "length" finish reason
+func truncateOpenAIChoices(tok tokenizer.Tokenizer, choices []openai.ChatCompletionChoice,
+	maxTokens int) []openai.ChatCompletionChoice {
+	choices = slices.Clone(choices)
+	for i, choice := range choices {
+		if content, truncated := tokenizer.Truncate(tok, choice.Message.Content, maxTokens); truncated {
+			choices[i].Message.Content = content
+			choices[i].Message.ToolCalls = nil
+			choices[i].FinishReason = "length"
+		}
+	}
+	return choices
+}
+
+// estimateOpenAIUsage fills in the usage of a response that has none configured
+func estimateOpenAIUsage(tok tokenizer.Tokenizer, request openai.ChatCompletionNewParams, response *openai.ChatCompletion) {
+	usage := &response.Usage
+	if usage.PromptTokens == 0 {
+		usage.PromptTokens = promptTokens(tok, request.Messages, len(request.Messages))
+	}
+	if usage.CompletionTokens == 0 {
+		for _, choice := range response.Choices {
+			usage.CompletionTokens += int64(tokenizer.Count(tok, choice.Message.Content))
+			for _, toolCall := range choice.Message.ToolCalls {
+				usage.CompletionTokens += int64(tokenizer.Count(tok, toolCall.Function.Name+" "+toolCall.Function.Arguments))
+			}
+		}
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+}
+
+// truncateAnthropicContent cuts the text of a message down to maxTokens, dropping the blocks
+// after the limit and stopping with the "max_tokens" stop reason
+func truncateAnthropicContent(tok tokenizer.Tokenizer, response *anthropic.Message, maxTokens int) {
+	remaining := maxTokens
+	for i, block := range response.Content {
+		if block.Type != "text" {
+			continue
+		}
+		text, truncated := tokenizer.Truncate(tok, block.Text, remaining)
+		if !truncated {
+			remaining -= tokenizer.Count(tok, block.Text)
+			continue
+		}
+		content := slices.Clone(response.Content[:i+1])
+		content[i] = anthropic.ContentBlockUnion{Type: "text", Text: text}
+		response.Content = content
+		response.StopReason = anthropic.StopReasonMaxTokens
+		return
+	}
+}
+
+// estimateAnthropicUsage fills in the usage of a response that has none configured
+func estimateAnthropicUsage(tok tokenizer.Tokenizer, request anthropic.MessageNewParams, response *anthropic.Message) {
+	usage := &response.Usage
+	if usage.InputTokens == 0 && usage.CacheReadInputTokens == 0 && usage.CacheCreationInputTokens == 0 {
+		usage.InputTokens = promptTokens(tok, []any{request.System, request.Tools, request.Messages}, len(request.Messages))
+	}
+	if usage.OutputTokens == 0 {
+		for _, block := range response.Content {
+			usage.OutputTokens += int64(tokenizer.Count(tok, block.Text+block.Thinking+string(block.Input)))
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package mockllm_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/kagent-dev/mockllm"
+	"github.com/kagent-dev/mockllm/tokenizer"
+	"github.com/openai/openai-go"
+	openaioption "github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenizerSettings(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{{
+			Name: "hello",
+			Match: mockllm.OpenAIRequestMatch{
+				MatchType: mockllm.MatchTypeContains,
+				Message:   openaiUserMessage("Hello"),
+			},
+			Response: openai.ChatCompletion{
+				ID: "chatcmpl-tokens",
+				Choices: []openai.ChatCompletionChoice{{
+					Message:      openai.ChatCompletionMessage{Role: "assistant", Content: "one two three four five"},
+					FinishReason: "stop",
+				}},
+			},
+		}},
+		Anthropic: []mockllm.AnthropicMock{{
+			Name: "hello",
+			Match: mockllm.AnthropicRequestMatch{
+				MatchType: mockllm.MatchTypeExact,
+				Message:   anthropicHelloRequest.Messages[0],
+			},
+			Response: anthropic.Message{
+				ID:         "msg_tokens",
+				Content:    []anthropic.ContentBlockUnion{{Type: "text", Text: "one two three four five"}},
+				StopReason: anthropic.StopReasonEndTurn,
+			},
+		}},
+		EstimateUsage:    true,
+		EnforceMaxTokens: true,
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	completion := postChatCompletion(t, baseURL, openai.ChatCompletionNewParams{
+		Model:     "gpt-4o-mini",
+		Messages:  []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+		MaxTokens: openai.Int(3),
+	})
+	assert.Equal(t, "one two three", completion.Choices[0].Message.Content)
+	assert.Equal(t, "length", completion.Choices[0].FinishReason)
+	assert.Equal(t, int64(3), completion.Usage.CompletionTokens)
+	assert.Positive(t, completion.Usage.PromptTokens)
+	assert.Equal(t, completion.Usage.PromptTokens+3, completion.Usage.TotalTokens)
+
+	completion = postChatCompletion(t, baseURL, helloParams)
+	assert.Equal(t, "one two three four five", completion.Choices[0].Message.Content)
+	assert.Equal(t, "stop", completion.Choices[0].FinishReason)
+	assert.Equal(t, int64(5), completion.Usage.CompletionTokens)
+
+	request := anthropicHelloRequest
+	request.MaxTokens = 2
+	resp := postJSON(t, baseURL+"/v1/messages", request, anthropicHeaders("2023-06-01"))
+	var message anthropic.Message
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&message))
+	assert.Equal(t, "one two", message.Content[0].Text)
+	assert.Equal(t, anthropic.StopReasonMaxTokens, message.StopReason)
+	assert.Equal(t, int64(2), message.Usage.OutputTokens)
+	assert.Positive(t, message.Usage.InputTokens)
+}
+
+func TestCustomTokenizer(t *testing.T) {
+	// A character tokenizer streams one character per chunk
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{{
+			Name: "hello",
+			Match: mockllm.OpenAIRequestMatch{
+				MatchType: mockllm.MatchTypeContains,
+				Message:   openaiUserMessage("Hello"),
+			},
+			Response: helloCompletion,
+		}},
+		Tokenizer: tokenizer.Func(func(text string) []string { return strings.Split(text, "") }),
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	client := openai.NewClient(
+		openaioption.WithBaseURL(baseURL+"/v1"),
+		openaioption.WithAPIKey("test-key"),
+		openaioption.WithMaxRetries(0),
+	)
+	stream := client.Chat.Completions.NewStreaming(t.Context(), helloParams)
+	var contentChunks int
+	for stream.Next() {
+		chunk := stream.Current()
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			assert.Len(t, chunk.Choices[0].Delta.Content, 1)
+			contentChunks++
+		}
+	}
+	require.NoError(t, stream.Err())
+	assert.Equal(t, len(helloCompletion.Choices[0].Message.Content), contentChunks)
+}
@@ -0,0 +1,162 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/kagent-dev/mockllm/openapi"
+	"github.com/openai/openai-go"
+)
+
+// forcedTool is the tool a request forces the model to call through its tool choice. An empty
+// name forces a call to any of the declared tools.
+type forcedTool struct {
+	name string
+}
+
+// calls reports whether a call of the named tool satisfies the forced choice
+func (f *forcedTool) calls(name string) bool {
+	return f.name == "" || f.name == name
+}
+
+// openaiForcedTool returns the tool forced by tool_choice "required" or a named function, or nil
+func openaiForcedTool(request openai.ChatCompletionNewParams) *forcedTool {
+	if len(request.Tools) == 0 {
+		return nil
+	}
+	if named := request.ToolChoice.OfChatCompletionNamedToolChoice; named != nil {
+		return &forcedTool{name: named.Function.Name}
+	}
+	if request.ToolChoice.OfAuto.Value == "required" {
+		return &forcedTool{}
+	}
+	return nil
+}
+
+// anthropicForcedTool returns the tool forced by tool_choice "any" or "tool", or nil
+func anthropicForcedTool(request anthropic.MessageNewParams) *forcedTool {
+	if len(request.Tools) == 0 {
+		return nil
+	}
+	switch {
+	case request.ToolChoice.OfTool != nil:
+		return &forcedTool{name: request.ToolChoice.OfTool.Name}
+	case request.ToolChoice.OfAny != nil:
+		return &forcedTool{}
+	}
+	return nil
+}
+
+// openaiCallsTool reports whether every choice of a response calls the forced tool
+func openaiCallsTool(response openai.ChatCompletion, forced *forcedTool) bool {
+	return len(response.Choices) > 0 && !slices.ContainsFunc(response.Choices, func(choice openai.ChatCompletionChoice) bool {
+		return !slices.ContainsFunc(choice.Message.ToolCalls, func(toolCall openai.ChatCompletionMessageToolCall) bool {
+			return forced.calls(toolCall.Function.Name)
+		})
+	})
+}
+
+// anthropicCallsTool reports whether a message calls the forced tool
+func anthropicCallsTool(response anthropic.Message, forced *forcedTool) bool {
+	return slices.ContainsFunc(response.Content, func(block anthropic.ContentBlockUnion) bool {
+		return block.Type == "tool_use" && forced.calls(block.Name)
+	})
+}
+
+// forceOpenAIToolCall replaces the messages of a response that doesn't call the forced tool with a
+// call of it, with arguments generated from the tool's declared parameters
+func forceOpenAIToolCall(mockName string, request openai.ChatCompletionNewParams, response *openai.ChatCompletion) {
+	forced := openaiForcedTool(request)
+	if forced == nil || openaiCallsTool(*response, forced) {
+		return
+	}
+	index := slices.IndexFunc(request.Tools, func(tool openai.ChatCompletionToolParam) bool {
+		return forced.calls(tool.Function.Name)
+	})
+	if index >= 0 {
+		setOpenAIToolCall(response, openaiToolCall(mockName, request.Tools[index].Function, newToolRand(0)))
+	}
+}
+
+// forceAnthropicToolCall replaces the content of a message that doesn't call the forced tool with
+// a call of it, with input generated from the tool's declared input schema
+func forceAnthropicToolCall(mockName string, request anthropic.MessageNewParams, response *anthropic.Message) {
+	forced := anthropicForcedTool(request)
+	if forced == nil || anthropicCallsTool(*response, forced) {
+		return
+	}
+	index := slices.IndexFunc(request.Tools, func(tool anthropic.ToolUnionParam) bool {
+		name := tool.GetName()
+		return name != nil && forced.calls(*name)
+	})
+	if index >= 0 {
+		setAnthropicToolUse(response, anthropicToolUse(mockName, request.Tools[index], newToolRand(0)))
+	}
+}
+
+// newToolRand returns the random source of generated tool arguments for a seed
+func newToolRand(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, 0))
+}
+
+// openaiToolCall builds a call of a declared function, with arguments valid against its parameters
+func openaiToolCall(mockName string, function openai.FunctionDefinitionParam, rng *rand.Rand) openai.ChatCompletionMessageToolCall {
+	parameters, _ := json.Marshal(function.Parameters)
+	return openai.ChatCompletionMessageToolCall{
+		ID:       mockResponseID("call_", mockName+"/"+function.Name),
+		Type:     "function",
+		Function: openai.ChatCompletionMessageToolCallFunction{Name: function.Name, Arguments: toolArguments(parameters, rng)},
+	}
+}
+
+// anthropicToolUse builds a call of a declared tool, with input valid against its input schema.
+// Server tools have no schema and get an empty input.
+func anthropicToolUse(mockName string, tool anthropic.ToolUnionParam, rng *rand.Rand) anthropic.ContentBlockUnion {
+	name := *tool.GetName()
+	var schema []byte
+	if tool.OfTool != nil {
+		schema, _ = json.Marshal(tool.OfTool.InputSchema)
+	}
+	return anthropic.ContentBlockUnion{
+		Type:  "tool_use",
+		ID:    mockResponseID("toolu_", mockName+"/"+name),
+		Name:  name,
+		Input: json.RawMessage(toolArguments(schema, rng)),
+	}
+}
+
+// setOpenAIToolCall replaces the message of every choice with a single tool call, adding a choice
+// when there is none
+func setOpenAIToolCall(response *openai.ChatCompletion, toolCall openai.ChatCompletionMessageToolCall) {
+	if len(response.Choices) == 0 {
+		response.Choices = make([]openai.ChatCompletionChoice, 1)
+	} else {
+		response.Choices = slices.Clone(response.Choices)
+	}
+	for i := range response.Choices {
+		response.Choices[i].Message.Content = ""
+		response.Choices[i].Message.ToolCalls = []openai.ChatCompletionMessageToolCall{toolCall}
+		response.Choices[i].FinishReason = "tool_calls"
+	}
+}
+
+// setAnthropicToolUse replaces the content of a message with a single tool use block
+func setAnthropicToolUse(response *anthropic.Message, toolUse anthropic.ContentBlockUnion) {
+	response.Content = []anthropic.ContentBlockUnion{toolUse}
+	response.StopReason = anthropic.StopReasonToolUse
+}
+
+// toolArguments generates a JSON object valid against a tool's parameter schema
+func toolArguments(schema []byte, rng *rand.Rand) string {
+	parsed, err := openapi.ParseSchema(schema)
+	if err != nil || len(schema) == 0 {
+		return "{}"
+	}
+	arguments, err := json.Marshal(openapi.Random(parsed, rng))
+	if err != nil || string(arguments) == "null" {
+		return "{}"
+	}
+	return string(arguments)
+}
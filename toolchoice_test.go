@@ -0,0 +1,127 @@
+package mockllm_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/kagent-dev/mockllm"
+	"github.com/openai/openai-go"
+	openaioption "github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForcedToolChoice(t *testing.T) {
+	weatherCall := openai.ChatCompletion{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{
+		ToolCalls: []openai.ChatCompletionMessageToolCall{{
+			ID:       "call_weather",
+			Type:     "function",
+			Function: openai.ChatCompletionMessageToolCallFunction{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+		}},
+	}}}}
+	mocks := []mockllm.OpenAIMock{replyMock("text", "Hi!"), {
+		Name:     "weather",
+		Match:    mockllm.OpenAIRequestMatch{MatchType: mockllm.MatchTypeContains, Message: openaiUserMessage("Hello")},
+		Response: weatherCall,
+	}}
+	baseURL := newOpenAIServer(t, mocks...)
+	tools := []openai.ChatCompletionToolParam{
+		{Function: openai.FunctionDefinitionParam{Name: "get_weather", Parameters: openai.FunctionParameters{
+			"type": "object", "properties": map[string]any{"city": map[string]any{"type": "string"}},
+		}}},
+		{Function: openai.FunctionDefinitionParam{Name: "get_time", Parameters: openai.FunctionParameters{
+			"type": "object", "properties": map[string]any{"zone": map[string]any{"enum": []string{"UTC"}}},
+		}}},
+	}
+	request := func(toolChoice openai.ChatCompletionToolChoiceOptionUnionParam) openai.ChatCompletionNewParams {
+		return openai.ChatCompletionNewParams{
+			Model:      "gpt-4o-mini",
+			Messages:   []openai.ChatCompletionMessageParamUnion{openaiUserMessage("Hello")},
+			Tools:      tools,
+			ToolChoice: toolChoice,
+		}
+	}
+
+	completion := postChatCompletion(t, baseURL, request(openai.ChatCompletionToolChoiceOptionUnionParam{}))
+	assert.Equal(t, "Hi!", completion.Choices[0].Message.Content, "without a forced tool the first match wins")
+
+	required := openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: openai.String("required")}
+	completion = postChatCompletion(t, baseURL, request(required))
+	assert.Equal(t, "call_weather", completion.Choices[0].Message.ToolCalls[0].ID, "the mock calling a tool is preferred")
+	assert.Equal(t, "tool_calls", completion.Choices[0].FinishReason)
+
+	body, err := json.Marshal(request(required))
+	require.NoError(t, err)
+	explanations, err := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{OpenAI: mocks})).WhichMockMatches(body, nil)
+	require.NoError(t, err)
+	require.Len(t, explanations, 2)
+	assert.False(t, explanations[0].Selected)
+	assert.True(t, explanations[1].Selected, "explanations select the mock calling the tool")
+
+	named := openai.ChatCompletionToolChoiceOptionParamOfChatCompletionNamedToolChoice(
+		openai.ChatCompletionNamedToolChoiceFunctionParam{Name: "get_time"})
+	completion = postChatCompletion(t, baseURL, request(named))
+	message := completion.Choices[0].Message
+	assert.Empty(t, message.Content)
+	require.Len(t, message.ToolCalls, 1)
+	assert.Equal(t, "get_time", message.ToolCalls[0].Function.Name, "a call of the named tool is synthesized")
+	assert.JSONEq(t, `{"zone":"UTC"}`, message.ToolCalls[0].Function.Arguments)
+	assert.NotEmpty(t, message.ToolCalls[0].ID)
+	assert.Equal(t, "tool_calls", completion.Choices[0].FinishReason)
+
+	// Streaming clients see the synthesized call
+	client := openai.NewClient(openaioption.WithBaseURL(baseURL+"/v1"), openaioption.WithAPIKey("test-key"))
+	stream := client.Chat.Completions.NewStreaming(t.Context(), request(named))
+	var acc openai.ChatCompletionAccumulator
+	for stream.Next() {
+		acc.AddChunk(stream.Current())
+	}
+	require.NoError(t, stream.Err())
+	assert.Equal(t, "get_time", acc.Choices[0].Message.ToolCalls[0].Function.Name)
+}
+
+func TestAnthropicForcedToolChoice(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{Anthropic: []mockllm.AnthropicMock{{
+		Name:     "hello",
+		Match:    mockllm.AnthropicRequestMatch{MatchType: mockllm.MatchTypeExact, Message: anthropicHelloRequest.Messages[0]},
+		Response: anthropic.Message{Content: []anthropic.ContentBlockUnion{{Type: "text", Text: "Hi!"}}},
+	}}}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+	client := anthropic.NewClient(anthropicoption.WithBaseURL(baseURL), anthropicoption.WithAPIKey("test-key"))
+
+	request := anthropicHelloRequest
+	request.Tools = []anthropic.ToolUnionParam{{OfTool: &anthropic.ToolParam{
+		Name: "get_weather",
+		InputSchema: anthropic.ToolInputSchemaParam{
+			Properties: map[string]any{"unit": map[string]any{"enum": []string{"celsius"}}},
+		},
+	}}}
+	message, err := client.Messages.New(t.Context(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi!", message.Content[0].Text, "without a forced tool the mock answers as configured")
+
+	request.ToolChoice = anthropic.ToolChoiceParamOfTool("get_weather")
+	message, err = client.Messages.New(t.Context(), request)
+	require.NoError(t, err)
+	require.Len(t, message.Content, 1)
+	assert.Equal(t, "tool_use", message.Content[0].Type)
+	assert.Equal(t, "get_weather", message.Content[0].Name)
+	assert.JSONEq(t, `{"unit":"celsius"}`, string(message.Content[0].Input))
+	assert.Equal(t, anthropic.StopReasonToolUse, message.StopReason)
+
+	request.ToolChoice = anthropic.ToolChoiceUnionParam{OfAny: &anthropic.ToolChoiceAnyParam{}}
+	stream := client.Messages.NewStreaming(t.Context(), request)
+	var streamed anthropic.Message
+	for stream.Next() {
+		require.NoError(t, streamed.Accumulate(stream.Current()))
+	}
+	require.NoError(t, stream.Err())
+	var input map[string]any
+	require.NoError(t, json.Unmarshal(streamed.Content[0].Input, &input))
+	assert.Equal(t, map[string]any{"unit": "celsius"}, input)
+}
@@ -0,0 +1,291 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+)
+
+// ToolValidationConfig checks the tools declared in requests the way the providers do: names,
+// duplicates, description lengths and the structure of their JSON Schemas
+type ToolValidationConfig struct {
+	// ReportOnly records violations in the request log without rejecting the request
+	ReportOnly bool `json:"report_only,omitempty"`
+}
+
+const (
+	// maxOpenAITools and maxToolDescription are the limits of the OpenAI API
+	maxOpenAITools     = 128
+	maxToolDescription = 1024
+)
+
+// toolNamePattern is the tool name pattern both providers enforce
+var toolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// schemaTypes are the JSON Schema types
+var schemaTypes = []string{"object", "array", "string", "number", "integer", "boolean", "null"}
+
+// toolViolation is a problem with a declared tool, with the request parameter at fault
+type toolViolation struct {
+	Param   string
+	Message string
+}
+
+// checkTools records the tool violations of a request body in its log entry, returning the
+// first one when the request is to be rejected
+func checkTools(config *ToolValidationConfig, record *RequestRecord,
+	violationsOf func(body []byte) []toolViolation, body []byte) *toolViolation {
+	if config == nil {
+		return nil
+	}
+	violations := violationsOf(body)
+	for _, violation := range violations {
+		record.ToolViolations = append(record.ToolViolations, violation.Message)
+	}
+	if len(violations) == 0 || config.ReportOnly {
+		return nil
+	}
+	record.Status, record.Error = http.StatusBadRequest, violations[0].Message
+	return &violations[0]
+}
+
+// openaiToolViolations checks the tools of a raw OpenAI chat completion request
+func openaiToolViolations(body []byte) []toolViolation {
+	var request struct {
+		Tools []struct {
+			Function struct {
+				Name        string          `json:"name"`
+				Description *string         `json:"description"`
+				Parameters  json.RawMessage `json:"parameters"`
+				Strict      bool            `json:"strict"`
+			} `json:"function"`
+		} `json:"tools"`
+	}
+	if json.Unmarshal(body, &request) != nil {
+		return nil
+	}
+
+	var violations []toolViolation
+	if len(request.Tools) > maxOpenAITools {
+		violations = append(violations, toolViolation{"tools", fmt.Sprintf(
+			"Invalid 'tools': array too long. Expected an array with maximum length %d, but got an array with length %d instead.",
+			maxOpenAITools, len(request.Tools))})
+	}
+	seen := map[string]bool{}
+	for i, tool := range request.Tools {
+		param := fmt.Sprintf("tools[%d].function", i)
+		function := tool.Function
+		if !toolNamePattern.MatchString(function.Name) {
+			violations = append(violations, toolViolation{param + ".name", fmt.Sprintf(
+				"Invalid '%s.name': string does not match pattern. Expected a string that matches the pattern '%s'.",
+				param, toolNamePattern)})
+		}
+		if seen[function.Name] {
+			violations = append(violations, toolViolation{param + ".name", fmt.Sprintf(
+				"Invalid 'tools': duplicate function name '%s'.", function.Name)})
+		}
+		seen[function.Name] = true
+		if function.Description != nil && len(*function.Description) > maxToolDescription {
+			violations = append(violations, toolViolation{param + ".description", fmt.Sprintf(
+				"Invalid '%s.description': string too long. Expected a string with maximum length %d, but got a string with length %d instead.",
+				param, maxToolDescription, len(*function.Description))})
+		}
+		if len(function.Parameters) == 0 {
+			continue
+		}
+		for _, problem := range toolSchemaProblems(function.Parameters, function.Strict) {
+			violations = append(violations, toolViolation{param + ".parameters", fmt.Sprintf(
+				"Invalid schema for function '%s': %s", function.Name, problem)})
+		}
+	}
+	return violations
+}
+
+// anthropicToolViolations checks the tools of a raw Anthropic messages request. Server tools,
+// which have a versioned type and no input schema, are only checked for duplicate names.
+func anthropicToolViolations(body []byte) []toolViolation {
+	var request struct {
+		Tools []struct {
+			Type        string          `json:"type"`
+			Name        string          `json:"name"`
+			InputSchema json.RawMessage `json:"input_schema"`
+		} `json:"tools"`
+	}
+	if json.Unmarshal(body, &request) != nil {
+		return nil
+	}
+
+	var violations []toolViolation
+	seen := map[string]bool{}
+	for i, tool := range request.Tools {
+		param := fmt.Sprintf("tools.%d", i)
+		if seen[tool.Name] {
+			violations = append(violations, toolViolation{"tools", "tools: Tool names must be unique."})
+		}
+		seen[tool.Name] = true
+		if tool.Type != "" && tool.Type != "custom" {
+			continue
+		}
+		if !toolNamePattern.MatchString(tool.Name) {
+			violations = append(violations, toolViolation{param + ".name", fmt.Sprintf(
+				"%s.custom.name: String should match pattern '%s'", param, toolNamePattern)})
+		}
+		if len(tool.InputSchema) == 0 {
+			violations = append(violations, toolViolation{param + ".input_schema", fmt.Sprintf(
+				"%s.custom.input_schema: Field required", param)})
+			continue
+		}
+		for _, problem := range toolSchemaProblems(tool.InputSchema, false) {
+			violations = append(violations, toolViolation{param + ".input_schema", fmt.Sprintf(
+				"%s.custom.input_schema: %s", param, problem)})
+		}
+	}
+	return violations
+}
+
+// toolSchemaProblems checks that a tool's schema is a JSON Schema of type object. Strict schemas
+// must also require every property and disallow additional ones, as OpenAI structured outputs do.
+func toolSchemaProblems(raw json.RawMessage, strict bool) []string {
+	var schema map[string]any
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return []string{"schema must be a JSON object"}
+	}
+	if schema["type"] != "object" {
+		return []string{fmt.Sprintf(`schema must be a JSON Schema of 'type: "object"', got 'type: %s'.`, schemaValue(schema["type"]))}
+	}
+	return schemaProblems(schema, "", strict)
+}
+
+// schemaProblems checks the structure of a JSON Schema and its subschemas, reporting problems
+// with their location in the schema
+func schemaProblems(schema map[string]any, at string, strict bool) []string {
+	var problems []string
+	report := func(format string, args ...any) {
+		location := "root"
+		if at != "" {
+			location = "'" + at + "'"
+		}
+		problems = append(problems, fmt.Sprintf("In context=%s, ", location)+fmt.Sprintf(format, args...))
+	}
+	subschema := func(value any, path string) {
+		if object, ok := value.(map[string]any); ok {
+			problems = append(problems, schemaProblems(object, path, strict)...)
+		} else {
+			report("'%s' must be a schema object", path)
+		}
+	}
+	child := func(path string) string {
+		if at == "" {
+			return path
+		}
+		return at + "." + path
+	}
+
+	if value, ok := schema["type"]; ok {
+		types, _ := value.([]any)
+		if name, ok := value.(string); ok {
+			types = []any{name}
+		}
+		if len(types) == 0 {
+			report("'type' must be a string or an array of strings")
+		}
+		for _, t := range types {
+			if name, ok := t.(string); !ok || !slices.Contains(schemaTypes, name) {
+				report("%s is not a valid type", schemaValue(t))
+			}
+		}
+	}
+
+	properties, hasProperties := schema["properties"].(map[string]any)
+	if value, ok := schema["properties"]; ok && !hasProperties {
+		report("'properties' must be an object, got %s", schemaValue(value))
+	}
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		subschema(properties[name], child("properties."+name))
+	}
+
+	var required []string
+	if value, ok := schema["required"]; ok {
+		list, isList := value.([]any)
+		if !isList {
+			report("'required' must be an array, got %s", schemaValue(value))
+		}
+		for _, entry := range list {
+			name, ok := entry.(string)
+			if !ok {
+				report("'required' entries must be strings, got %s", schemaValue(entry))
+				continue
+			}
+			required = append(required, name)
+			if hasProperties {
+				if _, defined := properties[name]; !defined {
+					report("'required' lists %q, which is not in 'properties'", name)
+				}
+			}
+		}
+	}
+	if strict && schema["type"] == "object" {
+		for _, name := range names {
+			if !slices.Contains(required, name) {
+				report("'required' is required to be supplied and to be an array including every key in properties. Missing '%s'.", name)
+			}
+		}
+		if schema["additionalProperties"] != false {
+			report("'additionalProperties' is required to be supplied and to be false.")
+		}
+	}
+
+	if items, ok := schema["items"]; ok {
+		subschema(items, child("items"))
+	}
+	if value, ok := schema["enum"]; ok {
+		if _, isList := value.([]any); !isList {
+			report("'enum' must be an array, got %s", schemaValue(value))
+		}
+	}
+	for _, keyword := range []string{"anyOf", "oneOf", "allOf"} {
+		value, ok := schema[keyword]
+		if !ok {
+			continue
+		}
+		list, isList := value.([]any)
+		if !isList {
+			report("'%s' must be an array, got %s", keyword, schemaValue(value))
+		}
+		for i, entry := range list {
+			subschema(entry, child(fmt.Sprintf("%s.%d", keyword, i)))
+		}
+	}
+	for _, keyword := range []string{"$defs", "definitions"} {
+		definitions, ok := schema[keyword].(map[string]any)
+		if !ok {
+			continue
+		}
+		names := make([]string, 0, len(definitions))
+		for name := range definitions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			subschema(definitions[name], child(keyword+"."+name))
+		}
+	}
+	return problems
+}
+
+// schemaValue encodes a value of a schema for error messages
+func schemaValue(value any) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
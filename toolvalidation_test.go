@@ -0,0 +1,114 @@
+package mockllm_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolValidation(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{ToolValidation: &mockllm.ToolValidationConfig{}}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	openaiRequest := func(tools string) json.RawMessage {
+		return json.RawMessage(`{"model":"gpt-4o-mini","messages":[{"role":"user","content":"Hello"}],"tools":` + tools + `}`)
+	}
+	longDescription := strings.Repeat("a", 1025)
+	tests := []struct {
+		name    string
+		tools   string
+		param   string
+		message string
+	}{
+		{
+			name:    "invalid name",
+			tools:   `[{"type":"function","function":{"name":"search docs"}}]`,
+			param:   "tools[0].function.name",
+			message: "Invalid 'tools[0].function.name': string does not match pattern. Expected a string that matches the pattern '^[a-zA-Z0-9_-]{1,64}$'.",
+		},
+		{
+			name:    "duplicate",
+			tools:   `[{"type":"function","function":{"name":"search"}},{"type":"function","function":{"name":"search"}}]`,
+			param:   "tools[1].function.name",
+			message: "Invalid 'tools': duplicate function name 'search'.",
+		},
+		{
+			name:    "long description",
+			tools:   `[{"type":"function","function":{"name":"search","description":"` + longDescription + `"}}]`,
+			param:   "tools[0].function.description",
+			message: "Invalid 'tools[0].function.description': string too long. Expected a string with maximum length 1024, but got a string with length 1025 instead.",
+		},
+		{
+			name:    "schema not an object",
+			tools:   `[{"type":"function","function":{"name":"search","parameters":{"type":"string"}}}]`,
+			param:   "tools[0].function.parameters",
+			message: `Invalid schema for function 'search': schema must be a JSON Schema of 'type: "object"', got 'type: "string"'.`,
+		},
+		{
+			name:    "invalid type",
+			tools:   `[{"type":"function","function":{"name":"search","parameters":{"type":"object","properties":{"query":{"type":"text"}},"required":["q"]}}}]`,
+			param:   "tools[0].function.parameters",
+			message: `Invalid schema for function 'search': In context='properties.query', "text" is not a valid type`,
+		},
+		{
+			name:    "strict",
+			tools:   `[{"type":"function","function":{"name":"search","strict":true,"parameters":{"type":"object","properties":{"query":{"type":"string"}}}}}]`,
+			param:   "tools[0].function.parameters",
+			message: "Invalid schema for function 'search': In context=root, 'required' is required to be supplied and to be an array including every key in properties. Missing 'query'.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := postJSON(t, baseURL+"/v1/chat/completions", openaiRequest(tt.tools),
+				map[string]string{"Authorization": "Bearer test-key"})
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			var body struct {
+				Error struct {
+					Message string `json:"message"`
+					Param   string `json:"param"`
+				} `json:"error"`
+			}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, tt.message, body.Error.Message)
+			assert.Equal(t, tt.param, body.Error.Param)
+		})
+	}
+
+	resp := postJSON(t, baseURL+"/v1/messages", json.RawMessage(`{
+		"model": "claude-3-5-sonnet-20240620",
+		"max_tokens": 1024,
+		"messages": [{"role": "user", "content": "Hello"}],
+		"tools": [
+			{"name": "get_weather", "input_schema": {"type": "string"}},
+			{"type": "web_search_20250305", "name": "get_weather"}
+		]
+	}`), anthropicHeaders("2023-06-01"))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	records := server.Requests()
+	assert.Equal(t, []string{
+		`tools.0.custom.input_schema: schema must be a JSON Schema of 'type: "object"', got 'type: "string"'.`,
+		"tools: Tool names must be unique.",
+	}, records[len(records)-1].ToolViolations)
+}
+
+func TestToolValidationReportOnly(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{ToolValidation: &mockllm.ToolValidationConfig{ReportOnly: true}}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	resp := postJSON(t, baseURL+"/v1/chat/completions", json.RawMessage(
+		`{"model":"gpt-4o-mini","messages":[{"role":"user","content":"Hello"}],"tools":[{"type":"function","function":{"name":"search docs"}}]}`),
+		map[string]string{"Authorization": "Bearer test-key"})
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "the request goes on to matching")
+	require.Len(t, server.Requests(), 1)
+	assert.Len(t, server.Requests()[0].ToolViolations, 1)
+}
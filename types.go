@@ -1,7 +1,12 @@
 package mockllm
 
 import (
+	"encoding/json"
+	"fmt"
+	"time"
+
 	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/kagent-dev/mockllm/tokenizer"
 	"github.com/openai/openai-go"
 )
 
@@ -9,10 +14,114 @@ import (
 
 // Config holds all the mock responses
 type Config struct {
+	// Version is the version of the config format. Files without one are of version 1, and older
+	// versions are migrated as they load, see MigrateConfig.
+	Version   int             `json:"version,omitempty"`
 	OpenAI    []OpenAIMock    `json:"openai,omitempty"`
 	Anthropic []AnthropicMock `json:"anthropic,omitempty"`
+	// OpenAIResponses mocks the OpenAI Responses API, with the output items of built-in tools
+	OpenAIResponses []OpenAIResponsesMock `json:"openai_responses,omitempty"`
+	// Gemini mocks the generateContent and streamGenerateContent methods of the Gemini API
+	Gemini []GeminiMock `json:"gemini,omitempty"`
+	// HTTP mocks arbitrary non-LLM endpoints, matched when no provider route handles a request
+	HTTP []HTTPMock `json:"http,omitempty"`
 	// ListenAddr is the address to listen on. Defaults to 0.0.0.0:0 (any IP address and ephemeral port)
 	ListenAddr string `json:"listen_addr,omitempty"`
+	// Compression enables gzip/deflate compression of responses
+	Compression *CompressionConfig `json:"compression,omitempty"`
+	// CORS lets browser based clients call the server from other origins
+	CORS *CORSConfig `json:"cors,omitempty"`
+	// Concurrency limits how many provider requests are handled at once
+	Concurrency *ConcurrencyConfig `json:"concurrency,omitempty"`
+	// Batches controls the batch API emulations
+	Batches *BatchConfig `json:"batches,omitempty"`
+	// FineTuning scripts the lifecycle of emulated fine-tuning jobs
+	FineTuning *FineTuningConfig `json:"fine_tuning,omitempty"`
+	// Tokenizer counts and splits tokens for usage estimation, max tokens enforcement, streaming
+	// and logprobs. Defaults to tokenizer.Default.
+	Tokenizer tokenizer.Tokenizer `json:"-"`
+	// Clock times simulated delays, rate limit windows and batch and fine-tuning jobs. Defaults to
+	// the system clock, tests can set a FakeClock and move it with Server.AdvanceTime.
+	Clock Clock `json:"-"`
+	// EstimateUsage fills in the token usage of responses that do not configure any
+	EstimateUsage bool `json:"estimate_usage,omitempty"`
+	// Grammar synthesizes replies to requests that match no mock but one of its rules, instead of
+	// a 404
+	Grammar *Grammar `json:"grammar,omitempty"`
+	// Echo answers requests that match no mock, nor the grammar, with their own last message
+	Echo *EchoConfig `json:"echo,omitempty"`
+	// ToolValidation rejects requests declaring invalid tools with a 400, as the providers do
+	ToolValidation *ToolValidationConfig `json:"tool_validation,omitempty"`
+	// EnforceMaxTokens truncates responses longer than the request's max tokens, as the providers do
+	EnforceMaxTokens bool `json:"enforce_max_tokens,omitempty"`
+	// ModelAliases map requested models, or patterns such as "gpt-4o-*", to the canonical model
+	// mocks are matched with, so date-pinned names don't need mocks of their own. Responses still
+	// echo the requested model.
+	ModelAliases map[string]string `json:"model_aliases,omitempty"`
+	// KnownModels reject requests for other models with the provider's model not found error
+	KnownModels *KnownModels `json:"known_models,omitempty"`
+	// ModelProfiles give the models, or patterns such as "gpt-4o-mini-*", a latency, context
+	// window, streaming speed and error rate, whichever mock serves them
+	ModelProfiles map[string]ModelProfile `json:"model_profiles,omitempty"`
+	// Heartbeat sends SSE comments or ping events while streams wait on a simulated delay
+	Heartbeat *HeartbeatConfig `json:"heartbeat,omitempty"`
+	// ServiceTiers emulate the processing tiers OpenAI requests select with service_tier
+	ServiceTiers *ServiceTierConfig `json:"service_tiers,omitempty"`
+	// OpenAIOrganizations are the organizations and projects OpenAI requests may select with the
+	// OpenAI-Organization and OpenAI-Project headers, each with its own mocks. Any organization and
+	// project are accepted when none are configured.
+	OpenAIOrganizations []OpenAIOrganization `json:"openai_organizations,omitempty"`
+	// Tenants are virtual tenants with their own mocks and rate limits, selected by API key
+	Tenants []TenantConfig `json:"tenants,omitempty"`
+	// AnthropicVersions are the accepted anthropic-version header values. Defaults to DefaultAnthropicVersions.
+	AnthropicVersions []string `json:"anthropic_versions,omitempty"`
+	// Providers holds the settings of custom providers, keyed by the name they were registered with
+	Providers map[string]json.RawMessage `json:"providers,omitempty"`
+	// DuplicateWindow is how close identical requests must be to be flagged as duplicates of each
+	// other. Defaults to DefaultDuplicateWindow, negative durations disable the detection.
+	DuplicateWindow Duration `json:"duplicate_window,omitempty"`
+	// ShutdownGracePeriod is how long stopping the server waits for in-flight requests to finish
+	// before cutting them. Unset, stopping waits for them until its context is done.
+	ShutdownGracePeriod Duration `json:"shutdown_grace_period,omitempty"`
+	// IDs makes the IDs of files, fine-tuning jobs, vector stores and message batches derive from
+	// a seed instead of numbering them
+	IDs *IDConfig `json:"ids,omitempty"`
+	// Tags selects the OpenAI and Anthropic mocks served by their tags. The MOCKLLM_TAGS
+	// environment variable overrides it, and Server.SetTags and PUT /admin/tags change it at runtime.
+	Tags *TagSelection `json:"tags,omitempty"`
+	// Assertions are checks on the calls of mocks evaluated when the server stops, see Assertion
+	Assertions []Assertion `json:"assertions,omitempty"`
+	// Report writes a JUnit XML or JSON report of the expectations and assertions when the server stops
+	Report *ReportConfig `json:"report,omitempty"`
+	// Golden compares the requests of the run against a golden transcript when the server stops
+	Golden *GoldenConfig `json:"golden,omitempty"`
+	// Metrics compares the prompt metrics of the run against a baseline when the server stops
+	Metrics *MetricsConfig `json:"metrics,omitempty"`
+	// Templates are partial mocks, keyed by name, that mocks in JSON configs extend by naming them
+	// in "extends". They are merged into the mocks when the config is decoded.
+	Templates map[string]json.RawMessage `json:"templates,omitempty"`
+}
+
+// Duration is a time.Duration configured as a string such as "1.5s" in JSON
+type Duration time.Duration
+
+// MarshalJSON encodes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON parses a duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"1.5s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
 }
 
 type MatchType string
@@ -20,11 +129,29 @@ type MatchType string
 const (
 	MatchTypeExact    MatchType = "exact"
 	MatchTypeContains MatchType = "contains"
+	// MatchTypeUserContains matches when the latest user message contains the text of the
+	// expected message, even when tool calls and results follow it
+	MatchTypeUserContains MatchType = "user_contains"
+	// MatchTypeFields matches on the fields criteria of the match alone, ignoring the message
+	MatchTypeFields MatchType = "fields"
 )
 
 type OpenAIRequestMatch struct {
 	MatchType MatchType                              `json:"match_type"`
 	Message   openai.ChatCompletionMessageParamUnion `json:"message"`
+	// ToolsCalled requires the assistant to have called each of these tools earlier in the conversation
+	ToolsCalled []string `json:"tools_called,omitempty"`
+	// Headers requires request headers to match these values, given as path.Match patterns, "*"
+	// only requiring the header to be present
+	Headers map[string]string `json:"headers,omitempty"`
+	// Fields requires fields of the request, addressed by paths such as tools[0].function.name, to
+	// match wildcard patterns such as "search_*", or regular expressions between slashes such as
+	// "/^search_/". [*] matches any element of an array and [-1] the last one.
+	Fields map[string]string `json:"fields,omitempty"`
+	// Document requires a file part of the conversation to match, e.g. a PDF by name or size
+	Document *DocumentMatch `json:"document,omitempty"`
+	// Audio requires an input_audio part of the conversation to match, e.g. by format or transcript
+	Audio *AudioMatch `json:"audio,omitempty"`
 }
 
 // OpenAIMock maps an OpenAI request to a response using official SDK types
@@ -32,11 +159,67 @@ type OpenAIMock struct {
 	Name     string                `json:"name"`     // identifier for this mock
 	Match    OpenAIRequestMatch    `json:"match"`    // Match type and value
 	Response openai.ChatCompletion `json:"response"` // OpenAI response to return (ChatCompletion or ChatCompletionChunk)
+	// Tags label the mock, e.g. smoke or slow, for the tag selection to enable or disable it
+	Tags []string `json:"tags,omitempty"`
+	// Probability makes the mock match only a share of the requests it otherwise matches, the others
+	// falling through to the next matching mock
+	Probability *MatchProbability `json:"probability,omitempty"`
+	// Betas are features that must be enabled in the OpenAI-Beta header for the mock to be served
+	Betas []string `json:"betas,omitempty"`
+	// Expect are assertions on the requests the mock serves, see Server.Verify
+	Expect []RequestExpectation `json:"expect,omitempty"`
+	// SeedResponses overrides Response for requests that set a specific seed
+	SeedResponses map[int64]openai.ChatCompletion `json:"seed_responses,omitempty"`
+	// Raw replaces the OpenAI response with an arbitrary status, headers and body, e.g. to
+	// emulate a proxy error page
+	Raw *HTTPResponse `json:"raw,omitempty"`
+	// Fault simulates a dropped, hanging, stalled or dripping connection instead of a well behaved response
+	Fault *Fault `json:"fault,omitempty"`
+	// Malformed breaks the response in the given way, e.g. with invalid JSON or out of order events
+	Malformed MalformedKind `json:"malformed,omitempty"`
+	// StreamError fails a streamed response partway through, after some of its content was sent
+	StreamError *StreamError `json:"stream_error,omitempty"`
+	// StreamRetry cuts the first streamed attempts of a request, then restarts or resumes the stream
+	StreamRetry *StreamRetry `json:"stream_retry,omitempty"`
+	// Echo replaces the content of every choice with the request's last message
+	Echo *EchoConfig `json:"echo,omitempty"`
+	// Generate replaces the content of every choice with generated text, JSON or a tool call
+	Generate *GenerateConfig `json:"generate,omitempty"`
+	// Fuzz replaces the response with a schema-valid but adversarial one, e.g. with empty choices
+	Fuzz *FuzzConfig `json:"fuzz,omitempty"`
+	// Grammar replaces the content of every choice with a reply synthesized from templates
+	Grammar *Grammar `json:"grammar,omitempty"`
+	// Memory replaces the content of every choice with answers about facts asserted earlier in
+	// the conversation
+	Memory *MemoryConfig `json:"memory,omitempty"`
+	// Script is Starlark source defining respond(request), which computes the reply text or the
+	// whole response from the decoded request
+	Script string `json:"script,omitempty"`
+	// Plugin is the path of a WebAssembly module whose match and respond hooks replace the match
+	// criteria and compute the response
+	Plugin string `json:"plugin,omitempty"`
+	// SimulatePromptCache computes usage.prompt_tokens_details.cached_tokens from the prompt
+	// prefixes seen in earlier requests instead of returning the configured value
+	SimulatePromptCache bool `json:"simulate_prompt_cache,omitempty"`
 }
 
 type AnthropicRequestMatch struct {
 	MatchType MatchType              `json:"match_type"`
 	Message   anthropic.MessageParam `json:"message"`
+	// ToolsCalled requires the assistant to have called each of these tools earlier in the conversation
+	ToolsCalled []string `json:"tools_called,omitempty"`
+	// Headers requires request headers to match these values, given as path.Match patterns, "*"
+	// only requiring the header to be present
+	Headers map[string]string `json:"headers,omitempty"`
+	// Fields requires fields of the request, addressed by paths such as tools[0].function.name, to
+	// match wildcard patterns such as "search_*", or regular expressions between slashes such as
+	// "/^search_/". [*] matches any element of an array and [-1] the last one.
+	Fields map[string]string `json:"fields,omitempty"`
+	// Document requires a document block of the conversation to match, e.g. a PDF by title or size
+	Document *DocumentMatch `json:"document,omitempty"`
+	// ComputerUse requires the request to be a turn of a computer use agent loop, e.g. one
+	// returning the screenshot of a screenshot action
+	ComputerUse *ComputerUseMatch `json:"computer_use,omitempty"`
 }
 
 // AnthropicMock maps an Anthropic request to a response using official SDK types
@@ -44,4 +227,46 @@ type AnthropicMock struct {
 	Name     string                `json:"name"`     // identifier for this mock
 	Match    AnthropicRequestMatch `json:"match"`    // Match type and value
 	Response anthropic.Message     `json:"response"` // Anthropic response to return (Message or streaming event)
+	// Tags label the mock, e.g. smoke or slow, for the tag selection to enable or disable it
+	Tags []string `json:"tags,omitempty"`
+	// Probability makes the mock match only a share of the requests it otherwise matches, the others
+	// falling through to the next matching mock
+	Probability *MatchProbability `json:"probability,omitempty"`
+	// VersionResponses overrides Response for requests sent with a specific anthropic-version
+	VersionResponses map[string]anthropic.Message `json:"version_responses,omitempty"`
+	// Betas are features that must be enabled in the anthropic-beta header for the mock to be served
+	Betas []string `json:"betas,omitempty"`
+	// Expect are assertions on the requests the mock serves, see Server.Verify
+	Expect []RequestExpectation `json:"expect,omitempty"`
+	// Raw replaces the Anthropic response with an arbitrary status, headers and body, e.g. to
+	// emulate a proxy error page
+	Raw *HTTPResponse `json:"raw,omitempty"`
+	// Fault simulates a dropped, hanging, stalled or dripping connection instead of a well behaved response
+	Fault *Fault `json:"fault,omitempty"`
+	// Malformed breaks the response in the given way, e.g. with invalid JSON or out of order events
+	Malformed MalformedKind `json:"malformed,omitempty"`
+	// StreamError fails a streamed response partway through, after some of its content was sent
+	StreamError *StreamError `json:"stream_error,omitempty"`
+	// StreamRetry cuts the first streamed attempts of a request, then restarts or resumes the stream
+	StreamRetry *StreamRetry `json:"stream_retry,omitempty"`
+	// Echo replaces the content of the response with the request's last message
+	Echo *EchoConfig `json:"echo,omitempty"`
+	// Generate replaces the content of the response with generated text, JSON or a tool call
+	Generate *GenerateConfig `json:"generate,omitempty"`
+	// Fuzz replaces the response with a schema-valid but adversarial one, e.g. with empty content
+	Fuzz *FuzzConfig `json:"fuzz,omitempty"`
+	// Grammar replaces the content of the response with a reply synthesized from templates
+	Grammar *Grammar `json:"grammar,omitempty"`
+	// Memory replaces the content of the response with answers about facts asserted earlier in
+	// the conversation
+	Memory *MemoryConfig `json:"memory,omitempty"`
+	// Script is Starlark source defining respond(request), which computes the reply text or the
+	// whole response from the decoded request
+	Script string `json:"script,omitempty"`
+	// Plugin is the path of a WebAssembly module whose match and respond hooks replace the match
+	// criteria and compute the response
+	Plugin string `json:"plugin,omitempty"`
+	// SimulatePromptCache computes the cache_creation_input_tokens and cache_read_input_tokens
+	// usage from the request's cache_control breakpoints instead of returning the configured values
+	SimulatePromptCache bool `json:"simulate_prompt_cache,omitempty"`
 }
@@ -0,0 +1,119 @@
+package mockllm
+
+import (
+	"cmp"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+)
+
+// TokenUsage is the token usage reported to the client for a single request. InputTokens
+// includes cached input tokens for both providers.
+type TokenUsage struct {
+	InputTokens       int64 `json:"input_tokens"`
+	OutputTokens      int64 `json:"output_tokens"`
+	CachedInputTokens int64 `json:"cached_input_tokens"`
+}
+
+func openaiTokenUsage(response openai.ChatCompletion) *TokenUsage {
+	return &TokenUsage{
+		InputTokens:       response.Usage.PromptTokens,
+		OutputTokens:      response.Usage.CompletionTokens,
+		CachedInputTokens: response.Usage.PromptTokensDetails.CachedTokens,
+	}
+}
+
+func anthropicTokenUsage(response anthropic.Message) *TokenUsage {
+	usage := response.Usage
+	return &TokenUsage{
+		InputTokens:       usage.InputTokens + usage.CacheReadInputTokens + usage.CacheCreationInputTokens,
+		OutputTokens:      usage.OutputTokens,
+		CachedInputTokens: usage.CacheReadInputTokens,
+	}
+}
+
+// UsageSummary is the cumulative token usage of an API key with a model
+type UsageSummary struct {
+	APIKey            string `json:"api_key"`
+	Tenant            string `json:"tenant,omitempty"`
+	Provider          string `json:"provider"`
+	Model             string `json:"model"`
+	Requests          int    `json:"requests"`
+	InputTokens       int64  `json:"input_tokens"`
+	OutputTokens      int64  `json:"output_tokens"`
+	CachedInputTokens int64  `json:"cached_input_tokens"`
+}
+
+// Usage returns the cumulative token usage of every API key and model served by a mock, ordered
+// by API key, provider and model. API keys are redacted like those of the records.
+func (l *RequestLog) Usage() []UsageSummary {
+	return l.usage(func(RequestRecord) bool { return true })
+}
+
+// usage is Usage, only counting the records keep returns true for
+func (l *RequestLog) usage(keep func(RequestRecord) bool) []UsageSummary {
+	type usageKey struct{ apiKey, provider, model string }
+	totals := map[usageKey]*UsageSummary{}
+	for _, record := range l.Records() {
+		if record.Usage == nil || !keep(record) {
+			continue
+		}
+		key := usageKey{record.apiKey, record.Provider, record.Model}
+		summary, ok := totals[key]
+		if !ok {
+			summary = &UsageSummary{APIKey: record.APIKey, Tenant: record.Tenant, Provider: key.provider, Model: key.model}
+			totals[key] = summary
+		}
+		summary.Requests++
+		summary.InputTokens += record.Usage.InputTokens
+		summary.OutputTokens += record.Usage.OutputTokens
+		summary.CachedInputTokens += record.Usage.CachedInputTokens
+	}
+
+	keys := slices.Collect(maps.Keys(totals))
+	slices.SortFunc(keys, func(a, b usageKey) int {
+		return cmp.Or(cmp.Compare(a.apiKey, b.apiKey), cmp.Compare(a.provider, b.provider), cmp.Compare(a.model, b.model))
+	})
+	summaries := make([]UsageSummary, 0, len(keys))
+	for _, key := range keys {
+		summaries = append(summaries, *totals[key])
+	}
+	return summaries
+}
+
+// Usage returns the cumulative simulated token usage per API key and model
+func (s *Server) Usage() []UsageSummary {
+	return s.requestLog.Usage()
+}
+
+func (s *Server) handleAdminUsage(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Usage())
+}
+
+// handleOpenAIUsage reports the OpenAI usage of the caller's API key in the shape of OpenAI's
+// legacy GET /v1/usage endpoint, with one entry per model
+func (s *Server) handleOpenAIUsage(w http.ResponseWriter, r *http.Request) {
+	apiKey := requestAPIKey(r)
+	if apiKey == "" {
+		writeOpenAIError(w, http.StatusUnauthorized, "You didn't provide an API key.", "", "")
+		return
+	}
+
+	data := []map[string]any{}
+	openaiUsage := func(record RequestRecord) bool { return record.apiKey == apiKey && record.Provider == providerOpenAI }
+	for _, summary := range s.requestLog.usage(openaiUsage) {
+		data = append(data, map[string]any{
+			"object":                        "usage",
+			"operation":                     "completion",
+			"snapshot_id":                   summary.Model,
+			"n_requests":                    summary.Requests,
+			"n_context_tokens_total":        summary.InputTokens,
+			"n_generated_tokens_total":      summary.OutputTokens,
+			"n_cached_context_tokens_total": summary.CachedInputTokens,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"object": "list", "data": data})
+}
@@ -0,0 +1,95 @@
+package mockllm_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/kagent-dev/mockllm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageAccounting(t *testing.T) {
+	server := mockllm.NewServer(mockllm.WithConfig(mockllm.Config{
+		OpenAI: []mockllm.OpenAIMock{{
+			Name: "hello",
+			Match: mockllm.OpenAIRequestMatch{
+				MatchType: mockllm.MatchTypeContains,
+				Message:   openaiUserMessage("Hello"),
+			},
+			Response: helloCompletion,
+		}},
+		Anthropic: []mockllm.AnthropicMock{{
+			Name: "hello",
+			Match: mockllm.AnthropicRequestMatch{
+				MatchType: mockllm.MatchTypeExact,
+				Message:   anthropicHelloRequest.Messages[0],
+			},
+			Response: anthropic.Message{
+				ID:    "msg_usage",
+				Usage: anthropic.Usage{InputTokens: 3, CacheReadInputTokens: 10, OutputTokens: 4},
+			},
+		}},
+	}))
+	baseURL, err := server.Start(t.Context())
+	require.NoError(t, err)
+	defer server.Stop(context.Background()) //nolint:errcheck
+
+	for _, key := range []string{"key-a", "key-a", "key-b"} {
+		resp := postJSON(t, baseURL+"/v1/chat/completions", helloParams, map[string]string{"Authorization": "Bearer " + key})
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	resp := postJSON(t, baseURL+"/v1/messages", anthropicHelloRequest, map[string]string{
+		"x-api-key":         "key-a",
+		"anthropic-version": "2023-06-01",
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	assert.Equal(t, []mockllm.UsageSummary{
+		{
+			APIKey: "...-a", Provider: "anthropic", Model: "claude-3-5-sonnet-20240620",
+			Requests: 1, InputTokens: 13, OutputTokens: 4, CachedInputTokens: 10,
+		},
+		{APIKey: "...-a", Provider: "openai", Model: "gpt-4o-mini", Requests: 2, InputTokens: 10, OutputTokens: 14},
+		{APIKey: "...-b", Provider: "openai", Model: "gpt-4o-mini", Requests: 1, InputTokens: 5, OutputTokens: 7},
+	}, server.Usage())
+
+	type openaiUsage struct {
+		Object string `json:"object"`
+		Data   []struct {
+			SnapshotID            string `json:"snapshot_id"`
+			NRequests             int    `json:"n_requests"`
+			NContextTokensTotal   int64  `json:"n_context_tokens_total"`
+			NGeneratedTokensTotal int64  `json:"n_generated_tokens_total"`
+		} `json:"data"`
+	}
+	getUsage := func(key string) openaiUsage {
+		req, err := http.NewRequest(http.MethodGet, baseURL+"/v1/usage", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+key)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint:errcheck
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var usage openaiUsage
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&usage))
+		return usage
+	}
+
+	usage := getUsage("key-b")
+	assert.Equal(t, "list", usage.Object)
+	require.Len(t, usage.Data, 1)
+	assert.Equal(t, "gpt-4o-mini", usage.Data[0].SnapshotID)
+	assert.Equal(t, 1, usage.Data[0].NRequests)
+	assert.Equal(t, int64(5), usage.Data[0].NContextTokensTotal)
+	assert.Equal(t, int64(7), usage.Data[0].NGeneratedTokensTotal)
+
+	// The usage of other providers is left out
+	usage = getUsage("key-a")
+	require.Len(t, usage.Data, 1)
+	assert.Equal(t, "gpt-4o-mini", usage.Data[0].SnapshotID)
+	assert.Equal(t, 2, usage.Data[0].NRequests)
+}
@@ -0,0 +1,261 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+)
+
+// requiredResponseFields lists, by Go field name, the fields a response must configure. Other
+// fields real responses carry, such as IDs and the model, are filled in when serving.
+var requiredResponseFields = map[reflect.Type][]string{
+	reflect.TypeFor[openai.ChatCompletion]():       {"Choices"},
+	reflect.TypeFor[openai.ChatCompletionChoice](): {"Message"},
+	reflect.TypeFor[anthropic.Message]():           {"Content"},
+	reflect.TypeFor[anthropic.ContentBlockUnion](): {"Type"},
+}
+
+// responseField is the presence metadata the SDKs keep for every field of a decoded response
+type responseField interface {
+	Valid() bool
+	Raw() string
+}
+
+// configIssue is a problem with the value at a JSON path of a config file
+type configIssue struct {
+	path    string
+	message string
+}
+
+// computesContent reports whether the mock's content is computed per request, leaving the
+// configured response incomplete
+func (m OpenAIMock) computesContent() bool {
+	return m.Echo != nil || m.Generate != nil || m.Fuzz != nil || m.Grammar != nil || m.Memory != nil ||
+		m.Script != "" || m.Plugin != ""
+}
+
+// computesContent reports whether the mock's content is computed per request, leaving the
+// configured response incomplete
+func (m AnthropicMock) computesContent() bool {
+	return m.Echo != nil || m.Generate != nil || m.Fuzz != nil || m.Grammar != nil || m.Memory != nil ||
+		m.Script != "" || m.Plugin != ""
+}
+
+// validateConfig checks the configured responses of a decoded config, reporting unknown fields,
+// values of the wrong type and missing required fields with the position locate finds for them
+func validateConfig(config Config, locate func(path string) string) error {
+	var issues []configIssue
+	addOpenAI := func(prefix string, mocks []OpenAIMock) {
+		for i, mock := range mocks {
+			path := fmt.Sprintf("%s[%d]", prefix, i)
+			issues = append(issues, faultIssues(mock.Fault, path+".fault")...)
+			issues = append(issues, fuzzIssues(mock.Fuzz, path+".fuzz")...)
+			issues = append(issues, malformedIssues(mock.Malformed, path+".malformed")...)
+			issues = append(issues, streamErrorIssues(mock.StreamError, path+".stream_error")...)
+			issues = append(issues, streamRetryIssues(mock.StreamRetry, path+".stream_retry")...)
+			issues = append(issues, memoryIssues(mock.Memory, path+".memory")...)
+			issues = append(issues, probabilityIssues(mock.Probability, path+".probability")...)
+			if mock.Raw != nil || mock.computesContent() {
+				continue
+			}
+			issues = append(issues, responseIssues(reflect.ValueOf(mock.Response), path+".response")...)
+			for _, seed := range sortedKeys(mock.SeedResponses) {
+				issues = append(issues, responseIssues(reflect.ValueOf(mock.SeedResponses[seed]),
+					fmt.Sprintf("%s.seed_responses.%d", path, seed))...)
+			}
+		}
+	}
+	addAnthropic := func(prefix string, mocks []AnthropicMock) {
+		for i, mock := range mocks {
+			path := fmt.Sprintf("%s[%d]", prefix, i)
+			issues = append(issues, faultIssues(mock.Fault, path+".fault")...)
+			issues = append(issues, fuzzIssues(mock.Fuzz, path+".fuzz")...)
+			issues = append(issues, malformedIssues(mock.Malformed, path+".malformed")...)
+			issues = append(issues, streamErrorIssues(mock.StreamError, path+".stream_error")...)
+			issues = append(issues, streamRetryIssues(mock.StreamRetry, path+".stream_retry")...)
+			issues = append(issues, memoryIssues(mock.Memory, path+".memory")...)
+			issues = append(issues, probabilityIssues(mock.Probability, path+".probability")...)
+			if mock.Raw != nil || mock.computesContent() {
+				continue
+			}
+			issues = append(issues, responseIssues(reflect.ValueOf(mock.Response), path+".response")...)
+			for _, version := range sortedKeys(mock.VersionResponses) {
+				issues = append(issues, responseIssues(reflect.ValueOf(mock.VersionResponses[version]),
+					path+".version_responses."+version)...)
+			}
+		}
+	}
+
+	addOpenAI("openai", config.OpenAI)
+	addAnthropic("anthropic", config.Anthropic)
+	for i, tenant := range config.Tenants {
+		addOpenAI(fmt.Sprintf("tenants[%d].openai", i), tenant.OpenAI)
+		addAnthropic(fmt.Sprintf("tenants[%d].anthropic", i), tenant.Anthropic)
+	}
+	issues = append(issues, responsesIssues(config.OpenAIResponses)...)
+	issues = append(issues, geminiIssues(config.Gemini)...)
+	issues = append(issues, modelAliasIssues(config.ModelAliases)...)
+	issues = append(issues, knownModelIssues(config.KnownModels)...)
+	issues = append(issues, modelProfileIssues(config.ModelProfiles)...)
+	issues = append(issues, assertionIssues(config)...)
+	issues = append(issues, reportIssues(config.Report)...)
+	issues = append(issues, goldenIssues(config.Golden)...)
+	issues = append(issues, metricsIssues(config.Metrics)...)
+	if len(issues) == 0 {
+		return nil
+	}
+
+	errs := make([]error, 0, len(issues))
+	for _, issue := range issues {
+		errs = append(errs, fmt.Errorf("%s: %s: %s", locate(issue.path), issue.path, issue.message))
+	}
+	return errors.Join(errs...)
+}
+
+// responseIssues walks a response decoded by one of the SDKs, using the metadata the SDKs record
+// while decoding to find unknown fields, invalid values and missing required fields
+func responseIssues(v reflect.Value, path string) []configIssue {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return responseIssues(v.Elem(), path)
+	case reflect.Slice:
+		var issues []configIssue
+		for i := range v.Len() {
+			issues = append(issues, responseIssues(v.Index(i), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return issues
+	case reflect.Struct:
+	default:
+		return nil
+	}
+
+	meta := v.FieldByName("JSON")
+	if !meta.IsValid() || meta.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var issues []configIssue
+	if extra := meta.FieldByName("ExtraFields"); extra.IsValid() {
+		keys := make([]string, 0, extra.Len())
+		for _, key := range extra.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			issues = append(issues, configIssue{path + "." + key, "unknown field"})
+		}
+	}
+
+	required := requiredResponseFields[v.Type()]
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		presence, ok := meta.FieldByName(field.Name).Interface().(responseField)
+		if !ok {
+			continue
+		}
+		fieldPath := path + "." + name
+		switch raw := presence.Raw(); {
+		case raw == "":
+			if slices.Contains(required, field.Name) {
+				issues = append(issues, configIssue{fieldPath, "missing required field"})
+			}
+		case raw != "null" && !presence.Valid():
+			issues = append(issues, configIssue{fieldPath, fmt.Sprintf("invalid value %s", raw)})
+		default:
+			issues = append(issues, responseIssues(v.Field(i), fieldPath)...)
+		}
+	}
+	return issues
+}
+
+// jsonPositions maps the path of every object member and array element of a JSON document, in
+// the form used by configIssue, to the byte offset of the member's key or of the element
+func jsonPositions(data []byte) map[string]int64 {
+	positions := map[string]int64{}
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	var walk func(path string) error
+	walk = func(path string) error {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'):
+			for decoder.More() {
+				offset := decoder.InputOffset()
+				key, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				memberPath := fmt.Sprint(key)
+				if path != "" {
+					memberPath = path + "." + memberPath
+				}
+				positions[memberPath] = offset
+				if err := walk(memberPath); err != nil {
+					return err
+				}
+			}
+			_, err = decoder.Token()
+		case json.Delim('['):
+			for i := 0; decoder.More(); i++ {
+				elementPath := fmt.Sprintf("%s[%d]", path, i)
+				positions[elementPath] = decoder.InputOffset()
+				if err := walk(elementPath); err != nil {
+					return err
+				}
+			}
+			_, err = decoder.Token()
+		}
+		return err
+	}
+	walk("") //nolint:errcheck // the document was already decoded successfully
+	return positions
+}
+
+// positionOf returns the line and column of the value at path, or of its closest ancestor
+// present in the document when the value is missing
+func positionOf(data []byte, positions map[string]int64, path string) (line, column int) {
+	offset, ok := positions[path]
+	for !ok && path != "" {
+		path = path[:max(strings.LastIndexAny(path, ".["), 0)]
+		offset, ok = positions[path]
+	}
+	// Offsets point just past the previous token, skip to the value itself
+	for offset < int64(len(data)) && strings.ContainsRune(" \t\r\n,:", rune(data[offset])) {
+		offset++
+	}
+
+	line, column = 1, 1
+	for _, b := range data[:offset] {
+		if b == '\n' {
+			line, column = line+1, 1
+		} else {
+			column++
+		}
+	}
+	return line, column
+}
+
+// sortedKeys returns the keys of a map in order, for stable error messages
+func sortedKeys[K interface{ ~int64 | ~string }, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
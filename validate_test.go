@@ -0,0 +1,63 @@
+package mockllm_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/kagent-dev/mockllm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigValidatesResponses(t *testing.T) {
+	valid := `{
+  "openai": [{
+    "name": "hello",
+    "match": {"match_type": "contains", "message": {"role": "user", "content": "Hello"}},
+    "response": {
+      "id": "chatcmpl-1", "object": "chat.completion", "created": 1, "model": "gpt-4o-mini",
+      "choices": [{"index": 0, "message": {"role": "assistant", "content": "Hi"}, "finish_reason": "stop"}]
+    }
+  }],
+  "anthropic": [{
+    "name": "raw",
+    "match": {"match_type": "contains", "message": {"role": "user", "content": [{"type": "text", "text": "Hello"}]}},
+    "raw": {"status": 502, "body": "bad gateway"}
+  }]
+}`
+	invalid := `{
+  "openai": [{
+    "name": "hello",
+    "match": {"match_type": "contains", "message": {"role": "user", "content": "Hello"}},
+    "response": {
+      "id": "chatcmpl-1", "object": "chat.completion", "created": "yesterday",
+      "choices": [{
+        "index": 0,
+        "role": "assistant",
+        "message": {"content": "Hi"},
+        "finish_reason": "stop"
+      }]
+    }
+  }],
+  "anthropic": [{
+    "name": "hello",
+    "match": {"match_type": "contains", "message": {"role": "user", "content": [{"type": "text", "text": "Hello"}]}},
+    "response": {"model": "claude-3-5-sonnet-20240620"}
+  }]
+}`
+	filesys := fstest.MapFS{
+		"valid.json":   {Data: []byte(valid)},
+		"invalid.json": {Data: []byte(invalid)},
+	}
+
+	config, err := mockllm.LoadConfigFromFile("valid.json", filesys)
+	require.NoError(t, err)
+	assert.Len(t, config.OpenAI, 1)
+
+	_, err = mockllm.LoadConfigFromFile("invalid.json", filesys)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid.json:6:56: openai[0].response.created: invalid value "yesterday"`)
+	assert.Contains(t, err.Error(), "invalid.json:9:9: openai[0].response.choices[0].role: unknown field")
+	assert.Contains(t, err.Error(), "invalid.json:18:5: anthropic[0].response.content: missing required field")
+	assert.NotContains(t, err.Error(), "response.model", "fields with defaults are optional")
+}